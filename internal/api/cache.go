@@ -0,0 +1,183 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/kunlu/git-keys/internal/logger"
+	"github.com/kunlu/git-keys/internal/profile"
+)
+
+// DefaultKeyCacheTTL is how long remote key listings are reused by default
+const DefaultKeyCacheTTL = 15 * time.Minute
+
+// KeyCache stores remote key listings on disk so read-only commands do not
+// hit the APIs on every run. Listings are keyed by a hash of the platform URL
+// and token, so no account names or tokens are written to the file.
+type KeyCache struct {
+	path string
+	ttl  time.Duration
+	mu   sync.Mutex
+}
+
+type keyCacheEntry struct {
+	FetchedAt time.Time `json:"fetched_at"`
+	Keys      []SSHKey  `json:"keys"`
+}
+
+var defaultKeyCache = NewKeyCache("", DefaultKeyCacheTTL)
+
+// NewKeyCache creates a key cache. An empty path uses
+// ~/.git-keys/cache/remote-keys.json; a negative ttl disables caching.
+func NewKeyCache(path string, ttl time.Duration) *KeyCache {
+	if path == "" {
+		path = DefaultKeyCachePath()
+	}
+	if ttl == 0 {
+		ttl = DefaultKeyCacheTTL
+	}
+	return &KeyCache{path: path, ttl: ttl}
+}
+
+// DefaultKeyCachePath returns the default cache file path
+func DefaultKeyCachePath() string {
+	return filepath.Join(profile.Dir(), "cache", "remote-keys.json")
+}
+
+// SetDefaultKeyCache replaces the cache used by ListKeysCached and
+// invalidated by AddKey/DeleteKey
+func SetDefaultKeyCache(cache *KeyCache) {
+	defaultKeyCache = cache
+}
+
+// Get returns a cached listing that is younger than the TTL
+func (c *KeyCache) Get(id string) ([]SSHKey, time.Time, bool) {
+	if c.ttl < 0 {
+		return nil, time.Time{}, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.load()[id]
+	if !ok || time.Since(entry.FetchedAt) > c.ttl {
+		return nil, time.Time{}, false
+	}
+	return entry.Keys, entry.FetchedAt, true
+}
+
+// Put stores a listing
+func (c *KeyCache) Put(id string, keys []SSHKey) error {
+	if c.ttl < 0 {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entries := c.load()
+	entries[id] = keyCacheEntry{FetchedAt: time.Now(), Keys: keys}
+	return c.save(entries)
+}
+
+// Invalidate drops a listing, e.g. after a key was added or deleted
+func (c *KeyCache) Invalidate(id string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entries := c.load()
+	if _, ok := entries[id]; !ok {
+		return nil
+	}
+	delete(entries, id)
+	return c.save(entries)
+}
+
+// load reads the cache file; a missing or unreadable cache is empty
+func (c *KeyCache) load() map[string]keyCacheEntry {
+	entries := make(map[string]keyCacheEntry)
+
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		return entries
+	}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		logger.Debug("Ignoring unreadable key cache %s: %v", c.path, err)
+		return make(map[string]keyCacheEntry)
+	}
+
+	// Drop listings that have expired
+	for id, entry := range entries {
+		if time.Since(entry.FetchedAt) > c.ttl {
+			delete(entries, id)
+		}
+	}
+	return entries
+}
+
+func (c *KeyCache) save(entries map[string]keyCacheEntry) error {
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode key cache: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0700); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	if err := os.WriteFile(c.path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write key cache: %w", err)
+	}
+	return nil
+}
+
+// cacheable is implemented by clients whose listings can be cached
+type cacheable interface {
+	cacheID() string
+}
+
+// keyCacheID derives a cache key from a platform URL and token
+func keyCacheID(baseURL, token string) string {
+	sum := sha256.Sum256([]byte(baseURL + "\x00" + token))
+	return hex.EncodeToString(sum[:16])
+}
+
+// invalidateKeyCache drops the cached listing of a client after a change
+func invalidateKeyCache(client cacheable) {
+	if err := defaultKeyCache.Invalidate(client.cacheID()); err != nil {
+		logger.Debug("Failed to invalidate key cache: %v", err)
+	}
+}
+
+// ListKeysCached lists a client's keys through the default cache. refresh
+// skips the cache and stores a fresh listing. The returned time is when the
+// listing was fetched.
+func ListKeysCached(ctx context.Context, client PlatformClient, refresh bool) ([]SSHKey, time.Time, error) {
+	c, ok := client.(cacheable)
+	if !ok {
+		keys, err := client.ListKeys(ctx)
+		return keys, time.Now(), err
+	}
+	id := c.cacheID()
+
+	if !refresh {
+		if keys, fetchedAt, ok := defaultKeyCache.Get(id); ok {
+			logger.Info("Using key listing cached at %s (--refresh to re-fetch)", fetchedAt.Format("15:04:05"))
+			return keys, fetchedAt, nil
+		}
+	}
+
+	keys, err := client.ListKeys(ctx)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	if err := defaultKeyCache.Put(id, keys); err != nil {
+		logger.Debug("Failed to cache key listing: %v", err)
+	}
+	return keys, time.Now(), nil
+}
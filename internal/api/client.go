@@ -5,16 +5,36 @@ import (
 	"fmt"
 	"os/exec"
 	"strings"
+	"time"
 
 	"github.com/kunlu/git-keys/internal/logger"
+	"github.com/kunlu/git-keys/internal/profile"
 )
 
 // PlatformClient defines the interface for interacting with git platforms
 type PlatformClient interface {
 	ListKeys(ctx context.Context) ([]SSHKey, error)
-	AddKey(ctx context.Context, title, publicKey string) (string, error)
+	// AddKey uploads a public key. Platforms that support it expire the key
+	// at expiresAt; the zero time means no expiry.
+	AddKey(ctx context.Context, title, publicKey string, expiresAt time.Time) (string, error)
 	DeleteKey(ctx context.Context, keyID string) error
 	GetKey(ctx context.Context, keyID string) (*SSHKey, error)
+
+	// Signing keys verify SSH-signed commits (gpg.format=ssh). They are
+	// registered apart from authentication keys and not returned by ListKeys.
+	ListSigningKeys(ctx context.Context) ([]SSHKey, error)
+	AddSigningKey(ctx context.Context, title, publicKey string, expiresAt time.Time) (string, error)
+	DeleteSigningKey(ctx context.Context, keyID string) error
+
+	// GPG keys verify GPG-signed commits; armoredPublicKey is the output of
+	// gpg --armor --export
+	AddGPGKey(ctx context.Context, armoredPublicKey string) (string, error)
+	DeleteGPGKey(ctx context.Context, keyID string) error
+
+	// Deploy keys give access to a single repository, given as owner/name
+	// (GitHub) or the project path (GitLab). readWrite allows pushes.
+	AddDeployKey(ctx context.Context, repo, title, publicKey string, readWrite bool, expiresAt time.Time) (string, error)
+	DeleteDeployKey(ctx context.Context, repo, keyID string) error
 }
 
 // SSHKey represents an SSH key on a platform
@@ -24,6 +44,7 @@ type SSHKey struct {
 	Key         string
 	Fingerprint string
 	CreatedAt   string
+	LastUsedAt  string // GitLab only; "" when never used or not reported
 }
 
 // TokenManager handles API token storage and retrieval
@@ -31,9 +52,11 @@ type TokenManager struct {
 	keychainService string
 }
 
-// NewTokenManager creates a new token manager
+// NewTokenManager creates a new token manager. Tokens of a profile other
+// than the default are kept under the service qualified with the profile, so
+// profiles never see each other's tokens.
 func NewTokenManager(service string) *TokenManager {
-	return &TokenManager{keychainService: service}
+	return &TokenManager{keychainService: profile.Qualify(service)}
 }
 
 // GetToken retrieves the API token from keychain
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/kunlu/git-keys/internal/logger"
+)
+
+// ConnectionOptions configures how a client reaches a self-hosted platform
+type ConnectionOptions struct {
+	CACertPath         string // PEM bundle trusted in addition to the system roots
+	InsecureSkipVerify bool   // Disable TLS certificate verification
+	Proxy              string // Proxy URL; empty uses HTTPS_PROXY/HTTP_PROXY/NO_PROXY
+}
+
+// IsZero reports whether no connection options are set
+func (o ConnectionOptions) IsZero() bool {
+	return o == ConnectionOptions{}
+}
+
+// newBaseTransport builds an HTTP transport honoring the connection options.
+// Without options the default transport is used, which already respects the
+// proxy environment variables.
+func newBaseTransport(opts ConnectionOptions) (http.RoundTripper, error) {
+	if opts.IsZero() {
+		return http.DefaultTransport, nil
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if opts.Proxy != "" {
+		proxyURL, err := url.Parse(opts.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL %q: %w", opts.Proxy, err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	if opts.CACertPath != "" || opts.InsecureSkipVerify {
+		tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+		if opts.CACertPath != "" {
+			pool, err := loadCertPool(opts.CACertPath)
+			if err != nil {
+				return nil, err
+			}
+			tlsConfig.RootCAs = pool
+		}
+
+		if opts.InsecureSkipVerify {
+			logger.Warn("TLS certificate verification is disabled (insecure_skip_verify)")
+			tlsConfig.InsecureSkipVerify = true
+		}
+
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	return transport, nil
+}
+
+// loadCertPool returns the system roots plus the certificates in path
+func loadCertPool(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA certificate: %w", err)
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no PEM certificates found in %s", path)
+	}
+
+	return pool, nil
+}
@@ -2,7 +2,11 @@ package api
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"strings"
+	"time"
 
 	"github.com/google/go-github/v58/github"
 	"github.com/kunlu/git-keys/internal/logger"
@@ -16,18 +20,30 @@ type GitHubClient struct {
 
 // NewGitHubClient creates a new GitHub API client
 func NewGitHubClient(token string) *GitHubClient {
-	client := github.NewClient(nil).WithAuthToken(token)
+	client := github.NewClient(newHTTPClient(nil)).WithAuthToken(token)
 	return &GitHubClient{
 		client: client,
 		token:  token,
 	}
 }
 
+// cacheID identifies the account's listing in the key cache
+func (c *GitHubClient) cacheID() string {
+	return keyCacheID("https://github.com", c.token)
+}
+
 // ListKeys lists all SSH keys for the authenticated user
 func (c *GitHubClient) ListKeys(ctx context.Context) ([]SSHKey, error) {
 	logger.Debug("Listing GitHub SSH keys")
 
-	keys, _, err := c.client.Users.ListKeys(ctx, "", nil)
+	keys, err := paginate(ctx, func(ctx context.Context, page int) ([]*github.Key, int, error) {
+		opts := &github.ListOptions{Page: page, PerPage: pageSize}
+		keys, resp, err := c.client.Users.ListKeys(ctx, "", opts)
+		if err != nil {
+			return nil, 0, err
+		}
+		return keys, resp.NextPage, nil
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to list GitHub keys: %w", err)
 	}
@@ -46,9 +62,11 @@ func (c *GitHubClient) ListKeys(ctx context.Context) ([]SSHKey, error) {
 	return result, nil
 }
 
-// AddKey adds a new SSH key to GitHub
-func (c *GitHubClient) AddKey(ctx context.Context, title, publicKey string) (string, error) {
+// AddKey adds a new SSH key to GitHub. GitHub keys do not expire, so
+// expiresAt is ignored.
+func (c *GitHubClient) AddKey(ctx context.Context, title, publicKey string, expiresAt time.Time) (string, error) {
 	logger.Debug("Adding SSH key to GitHub: %s", title)
+	defer invalidateKeyCache(c)
 
 	key := &github.Key{
 		Title: github.String(title),
@@ -68,6 +86,7 @@ func (c *GitHubClient) AddKey(ctx context.Context, title, publicKey string) (str
 // DeleteKey removes an SSH key from GitHub
 func (c *GitHubClient) DeleteKey(ctx context.Context, keyID string) error {
 	logger.Debug("Deleting GitHub SSH key: %s", keyID)
+	defer invalidateKeyCache(c)
 
 	var id int64
 	fmt.Sscanf(keyID, "%d", &id)
@@ -81,6 +100,154 @@ func (c *GitHubClient) DeleteKey(ctx context.Context, keyID string) error {
 	return nil
 }
 
+// ListSigningKeys lists the SSH signing keys of the authenticated user
+func (c *GitHubClient) ListSigningKeys(ctx context.Context) ([]SSHKey, error) {
+	logger.Debug("Listing GitHub SSH signing keys")
+
+	keys, err := paginate(ctx, func(ctx context.Context, page int) ([]*github.SSHSigningKey, int, error) {
+		opts := &github.ListOptions{Page: page, PerPage: pageSize}
+		keys, resp, err := c.client.Users.ListSSHSigningKeys(ctx, "", opts)
+		if err != nil {
+			return nil, 0, err
+		}
+		return keys, resp.NextPage, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list GitHub signing keys: %w", err)
+	}
+
+	result := make([]SSHKey, len(keys))
+	for i, key := range keys {
+		result[i] = SSHKey{
+			ID:        fmt.Sprintf("%d", key.GetID()),
+			Title:     key.GetTitle(),
+			Key:       key.GetKey(),
+			CreatedAt: key.GetCreatedAt().String(),
+		}
+	}
+
+	logger.Info("Found %d SSH signing keys on GitHub", len(result))
+	return result, nil
+}
+
+// AddSigningKey adds an SSH signing key to GitHub. expiresAt is ignored.
+func (c *GitHubClient) AddSigningKey(ctx context.Context, title, publicKey string, expiresAt time.Time) (string, error) {
+	logger.Debug("Adding SSH signing key to GitHub: %s", title)
+
+	key := &github.Key{
+		Title: github.String(title),
+		Key:   github.String(publicKey),
+	}
+
+	created, _, err := c.client.Users.CreateSSHSigningKey(ctx, key)
+	if err != nil {
+		return "", fmt.Errorf("failed to add GitHub signing key: %w", err)
+	}
+
+	keyID := fmt.Sprintf("%d", created.GetID())
+	logger.Info("Added SSH signing key to GitHub: %s (ID: %s)", title, keyID)
+	return keyID, nil
+}
+
+// DeleteSigningKey removes an SSH signing key from GitHub
+func (c *GitHubClient) DeleteSigningKey(ctx context.Context, keyID string) error {
+	logger.Debug("Deleting GitHub SSH signing key: %s", keyID)
+
+	var id int64
+	fmt.Sscanf(keyID, "%d", &id)
+
+	if _, err := c.client.Users.DeleteSSHSigningKey(ctx, id); err != nil {
+		return fmt.Errorf("failed to delete GitHub signing key: %w", err)
+	}
+
+	logger.Info("Deleted SSH signing key from GitHub: %s", keyID)
+	return nil
+}
+
+// AddGPGKey adds a GPG public key to GitHub
+func (c *GitHubClient) AddGPGKey(ctx context.Context, armoredPublicKey string) (string, error) {
+	logger.Debug("Adding GPG key to GitHub")
+
+	created, _, err := c.client.Users.CreateGPGKey(ctx, armoredPublicKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to add GitHub GPG key: %w", err)
+	}
+
+	keyID := fmt.Sprintf("%d", created.GetID())
+	logger.Info("Added GPG key to GitHub: %s (ID: %s)", created.GetKeyID(), keyID)
+	return keyID, nil
+}
+
+// DeleteGPGKey removes a GPG key from GitHub
+func (c *GitHubClient) DeleteGPGKey(ctx context.Context, keyID string) error {
+	logger.Debug("Deleting GitHub GPG key: %s", keyID)
+
+	var id int64
+	fmt.Sscanf(keyID, "%d", &id)
+
+	if _, err := c.client.Users.DeleteGPGKey(ctx, id); err != nil {
+		return fmt.Errorf("failed to delete GitHub GPG key: %w", err)
+	}
+
+	logger.Info("Deleted GPG key from GitHub: %s", keyID)
+	return nil
+}
+
+// splitRepo splits owner/name
+func splitRepo(repo string) (string, string, error) {
+	owner, name, ok := strings.Cut(repo, "/")
+	if !ok || owner == "" || name == "" || strings.Contains(name, "/") {
+		return "", "", fmt.Errorf("invalid GitHub repository %q: use owner/name", repo)
+	}
+	return owner, name, nil
+}
+
+// AddDeployKey adds a deploy key to a GitHub repository. Deploy keys do not
+// expire on GitHub, so expiresAt is ignored.
+func (c *GitHubClient) AddDeployKey(ctx context.Context, repo, title, publicKey string, readWrite bool, expiresAt time.Time) (string, error) {
+	logger.Debug("Adding deploy key to GitHub repository %s: %s", repo, title)
+
+	owner, name, err := splitRepo(repo)
+	if err != nil {
+		return "", err
+	}
+
+	key := &github.Key{
+		Title:    github.String(title),
+		Key:      github.String(publicKey),
+		ReadOnly: github.Bool(!readWrite),
+	}
+
+	created, _, err := c.client.Repositories.CreateKey(ctx, owner, name, key)
+	if err != nil {
+		return "", fmt.Errorf("failed to add GitHub deploy key: %w", err)
+	}
+
+	keyID := fmt.Sprintf("%d", created.GetID())
+	logger.Info("Added deploy key to %s: %s (ID: %s)", repo, title, keyID)
+	return keyID, nil
+}
+
+// DeleteDeployKey removes a deploy key from a GitHub repository
+func (c *GitHubClient) DeleteDeployKey(ctx context.Context, repo, keyID string) error {
+	logger.Debug("Deleting deploy key %s from GitHub repository %s", keyID, repo)
+
+	owner, name, err := splitRepo(repo)
+	if err != nil {
+		return err
+	}
+
+	var id int64
+	fmt.Sscanf(keyID, "%d", &id)
+
+	if _, err := c.client.Repositories.DeleteKey(ctx, owner, name, id); err != nil {
+		return fmt.Errorf("failed to delete GitHub deploy key: %w", err)
+	}
+
+	logger.Info("Deleted deploy key from %s: %s", repo, keyID)
+	return nil
+}
+
 // GetKey retrieves a specific SSH key from GitHub
 func (c *GitHubClient) GetKey(ctx context.Context, keyID string) (*SSHKey, error) {
 	logger.Debug("Getting GitHub SSH key: %s", keyID)
@@ -102,3 +269,173 @@ func (c *GitHubClient) GetKey(ctx context.Context, keyID string) (*SSHKey, error
 
 	return result, nil
 }
+
+// CheckToken verifies the token against /user and reads its scopes and
+// expiry from the X-OAuth-Scopes and GitHub-Authentication-Token-Expiration
+// headers. Fine-grained tokens do not report scopes, so Scopes is nil for them.
+func (c *GitHubClient) CheckToken(ctx context.Context) (*TokenInfo, error) {
+	logger.Debug("Checking GitHub token")
+
+	user, resp, err := c.client.Users.Get(ctx, "")
+	if err != nil {
+		var errResp *github.ErrorResponse
+		if errors.As(err, &errResp) && errResp.Response.StatusCode == http.StatusUnauthorized {
+			return nil, fmt.Errorf("GitHub rejected the token (invalid, expired or revoked)")
+		}
+		return nil, fmt.Errorf("failed to check GitHub token: %w", err)
+	}
+
+	info := &TokenInfo{User: user.GetLogin()}
+
+	if _, ok := resp.Header["X-Oauth-Scopes"]; ok {
+		info.Scopes = parseScopes(resp.Header.Get("X-OAuth-Scopes"))
+	}
+
+	if expiry := resp.Header.Get("GitHub-Authentication-Token-Expiration"); expiry != "" {
+		for _, layout := range []string{"2006-01-02 15:04:05 MST", "2006-01-02 15:04:05 -0700"} {
+			if t, err := time.Parse(layout, expiry); err == nil {
+				info.ExpiresAt = &t
+				break
+			}
+		}
+	}
+
+	return info, nil
+}
+
+// ListMemberKeys lists the public SSH keys of every member of a GitHub
+// organization. GitHub does not report when another user's key was added;
+// for organizations with SAML SSO the date the key was authorized for the
+// organization is filled in instead.
+func (c *GitHubClient) ListMemberKeys(ctx context.Context, org string) ([]MemberKey, error) {
+	logger.Debug("Listing members of GitHub organization %s", org)
+
+	members, err := paginate(ctx, func(ctx context.Context, page int) ([]*github.User, int, error) {
+		opts := &github.ListMembersOptions{ListOptions: github.ListOptions{Page: page, PerPage: pageSize}}
+		members, resp, err := c.client.Organizations.ListMembers(ctx, org, opts)
+		if err != nil {
+			return nil, 0, err
+		}
+		return members, resp.NextPage, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list members of %s: %w", org, err)
+	}
+
+	authorized := c.sshKeyAuthorizations(ctx, org)
+
+	var result []MemberKey
+	for _, member := range members {
+		login := member.GetLogin()
+		keys, err := paginate(ctx, func(ctx context.Context, page int) ([]*github.Key, int, error) {
+			opts := &github.ListOptions{Page: page, PerPage: pageSize}
+			keys, resp, err := c.client.Users.ListKeys(ctx, login, opts)
+			if err != nil {
+				return nil, 0, err
+			}
+			return keys, resp.NextPage, nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list keys of %s: %w", login, err)
+		}
+
+		for _, key := range keys {
+			result = append(result, MemberKey{
+				Member: login,
+				SSHKey: SSHKey{
+					ID:  fmt.Sprintf("%d", key.GetID()),
+					Key: key.GetKey(),
+				},
+				Authorized: authorized[key.GetID()],
+			})
+		}
+	}
+
+	logger.Info("Found %d SSH keys of %d members in %s", len(result), len(members), org)
+	return result, nil
+}
+
+// sshKeyAuthorizations returns when each SSH key was authorized for an
+// organization with SAML SSO, by key ID. Other organizations return nil.
+func (c *GitHubClient) sshKeyAuthorizations(ctx context.Context, org string) map[int64]string {
+	auths, err := paginate(ctx, func(ctx context.Context, page int) ([]*github.CredentialAuthorization, int, error) {
+		opts := &github.ListOptions{Page: page, PerPage: pageSize}
+		auths, resp, err := c.client.Organizations.ListCredentialAuthorizations(ctx, org, opts)
+		if err != nil {
+			return nil, 0, err
+		}
+		return auths, resp.NextPage, nil
+	})
+	if err != nil {
+		logger.Debug("No credential authorizations for %s: %v", org, err)
+		return nil
+	}
+
+	authorized := make(map[int64]string)
+	for _, auth := range auths {
+		if strings.EqualFold(auth.GetCredentialType(), "SSH key") && auth.AuthorizedCredentialID != nil {
+			authorized[auth.GetAuthorizedCredentialID()] = auth.GetCredentialAuthorizedAt().Format(time.RFC3339)
+		}
+	}
+	return authorized
+}
+
+// ListVerifiedDomains lists the verified domains of a GitHub organization.
+// They are only available through the GraphQL API.
+func (c *GitHubClient) ListVerifiedDomains(ctx context.Context, org string) ([]string, error) {
+	logger.Debug("Listing verified domains of GitHub organization %s", org)
+
+	query := map[string]any{
+		"query":     `query($login: String!) { organization(login: $login) { domains(first: 100, isVerified: true) { nodes { domain } } } }`,
+		"variables": map[string]string{"login": org},
+	}
+	req, err := c.client.NewRequest("POST", "graphql", query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	var resp struct {
+		Data struct {
+			Organization *struct {
+				Domains struct {
+					Nodes []struct {
+						Domain string `json:"domain"`
+					} `json:"nodes"`
+				} `json:"domains"`
+			} `json:"organization"`
+		} `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if _, err := c.client.Do(ctx, req, &resp); err != nil {
+		return nil, fmt.Errorf("failed to list verified domains of %s: %w", org, err)
+	}
+	if len(resp.Errors) > 0 {
+		return nil, fmt.Errorf("failed to list verified domains of %s: %s", org, resp.Errors[0].Message)
+	}
+	if resp.Data.Organization == nil {
+		return nil, fmt.Errorf("organization %s not found", org)
+	}
+
+	var domains []string
+	for _, node := range resp.Data.Organization.Domains.Nodes {
+		domains = append(domains, node.Domain)
+	}
+	return domains, nil
+}
+
+// GitHubHostKeys returns the SSH host keys github.com publishes in its /meta
+// API, as "type base64" lines. The endpoint needs no token.
+func GitHubHostKeys(ctx context.Context) ([]string, error) {
+	logger.Debug("Fetching GitHub SSH host keys")
+
+	meta, _, err := github.NewClient(newHTTPClient(nil)).Meta.Get(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch GitHub host keys: %w", err)
+	}
+	if len(meta.SSHKeys) == 0 {
+		return nil, fmt.Errorf("GitHub's /meta API lists no SSH host keys")
+	}
+	return meta.SSHKeys, nil
+}
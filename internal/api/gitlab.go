@@ -7,7 +7,9 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"strings"
+	"time"
 
 	"github.com/kunlu/git-keys/internal/logger"
 )
@@ -29,66 +31,159 @@ func NewGitLabClient(baseURL, token string) *GitLabClient {
 	return &GitLabClient{
 		baseURL: baseURL,
 		token:   token,
-		client:  &http.Client{},
+		client:  newHTTPClient(nil),
 	}
 }
 
+// NewGitLabClientWithOptions creates a GitLab API client that connects
+// through a proxy or trusts a custom CA, e.g. for a self-hosted instance
+func NewGitLabClientWithOptions(baseURL, token string, opts ConnectionOptions) (*GitLabClient, error) {
+	transport, err := newBaseTransport(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	client := NewGitLabClient(baseURL, token)
+	client.client = newHTTPClient(transport)
+	return client, nil
+}
+
+// authorize adds the token to a request. GitLab accepts both personal access
+// tokens and OAuth access tokens as bearer tokens.
+func (c *GitLabClient) authorize(req *http.Request) {
+	req.Header.Set("Authorization", "Bearer "+c.token)
+}
+
+// cacheID identifies the account's listing in the key cache
+func (c *GitLabClient) cacheID() string {
+	return keyCacheID(c.baseURL, c.token)
+}
+
 type gitlabKey struct {
-	ID        int    `json:"id"`
-	Title     string `json:"title"`
-	Key       string `json:"key"`
-	CreatedAt string `json:"created_at"`
+	ID         int    `json:"id"`
+	Title      string `json:"title"`
+	Key        string `json:"key"`
+	CreatedAt  string `json:"created_at"`
+	LastUsedAt string `json:"last_used_at"` // null until the key authenticates
+	UsageType  string `json:"usage_type"`   // auth, signing or auth_and_signing (GitLab 15.7+)
+}
+
+// GitLab key usage types
+const (
+	gitlabUsageAuth    = "auth"
+	gitlabUsageSigning = "signing"
+)
+
+// sshKey converts a GitLab key to an SSHKey
+func (k gitlabKey) sshKey() SSHKey {
+	return SSHKey{
+		ID:         fmt.Sprintf("%d", k.ID),
+		Title:      k.Title,
+		Key:        k.Key,
+		CreatedAt:  k.CreatedAt,
+		LastUsedAt: k.LastUsedAt,
+	}
 }
 
-// ListKeys lists all SSH keys for the authenticated user
+// ListKeys lists the SSH keys of the authenticated user that can
+// authenticate; signing-only keys are left to ListSigningKeys
 func (c *GitLabClient) ListKeys(ctx context.Context) ([]SSHKey, error) {
 	logger.Debug("Listing GitLab SSH keys")
 
-	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/api/v4/user/keys", nil)
+	keys, err := paginate(ctx, c.listKeysPage)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, err
+	}
+
+	var result []SSHKey
+	for _, key := range keys {
+		if key.UsageType != gitlabUsageSigning {
+			result = append(result, key.sshKey())
+		}
+	}
+
+	logger.Info("Found %d SSH keys on GitLab", len(result))
+	return result, nil
+}
+
+// ListSigningKeys lists the SSH keys of the authenticated user that can sign
+// commits
+func (c *GitLabClient) ListSigningKeys(ctx context.Context) ([]SSHKey, error) {
+	logger.Debug("Listing GitLab SSH signing keys")
+
+	keys, err := paginate(ctx, c.listKeysPage)
+	if err != nil {
+		return nil, err
 	}
 
-	req.Header.Set("PRIVATE-TOKEN", c.token)
+	var result []SSHKey
+	for _, key := range keys {
+		if key.UsageType != gitlabUsageAuth {
+			result = append(result, key.sshKey())
+		}
+	}
+
+	logger.Info("Found %d SSH signing keys on GitLab", len(result))
+	return result, nil
+}
+
+// listKeysPage fetches one page of the authenticated user's SSH keys
+func (c *GitLabClient) listKeysPage(ctx context.Context, page int) ([]gitlabKey, int, error) {
+	url := fmt.Sprintf("%s/api/v4/user/keys?page=%d&per_page=%d", c.baseURL, page, pageSize)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	c.authorize(req)
 
 	resp, err := c.client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list GitLab keys: %w", err)
+		return nil, 0, fmt.Errorf("failed to list GitLab keys: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("GitLab API error (status %d): %s", resp.StatusCode, string(body))
+		return nil, 0, fmt.Errorf("GitLab API error (status %d): %s", resp.StatusCode, string(body))
 	}
 
 	var keys []gitlabKey
 	if err := json.NewDecoder(resp.Body).Decode(&keys); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+		return nil, 0, fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	result := make([]SSHKey, len(keys))
-	for i, key := range keys {
-		result[i] = SSHKey{
-			ID:        fmt.Sprintf("%d", key.ID),
-			Title:     key.Title,
-			Key:       key.Key,
-			CreatedAt: key.CreatedAt,
-		}
-	}
-
-	logger.Info("Found %d SSH keys on GitLab", len(result))
-	return result, nil
+	return keys, gitlabNextPage(resp.Header), nil
 }
 
-// AddKey adds a new SSH key to GitLab
-func (c *GitLabClient) AddKey(ctx context.Context, title, publicKey string) (string, error) {
+// AddKey adds a new SSH key to GitLab. A future expiresAt is sent as
+// expires_at, so GitLab disables the key then even if it is never rotated.
+func (c *GitLabClient) AddKey(ctx context.Context, title, publicKey string, expiresAt time.Time) (string, error) {
 	logger.Debug("Adding SSH key to GitLab: %s", title)
+	return c.addKey(ctx, title, publicKey, expiresAt, "")
+}
+
+// AddSigningKey adds an SSH key to GitLab that can only sign commits
+func (c *GitLabClient) AddSigningKey(ctx context.Context, title, publicKey string, expiresAt time.Time) (string, error) {
+	logger.Debug("Adding SSH signing key to GitLab: %s", title)
+	return c.addKey(ctx, title, publicKey, expiresAt, gitlabUsageSigning)
+}
+
+// addKey posts a key with the given usage type ("" for GitLab's default,
+// which allows authentication and signing)
+func (c *GitLabClient) addKey(ctx context.Context, title, publicKey string, expiresAt time.Time, usageType string) (string, error) {
+	defer invalidateKeyCache(c)
 
 	payload := map[string]string{
 		"title": title,
 		"key":   publicKey,
 	}
+	if usageType != "" {
+		payload["usage_type"] = usageType
+	}
+	if expiresAt.After(time.Now()) {
+		payload["expires_at"] = expiresAt.UTC().Format(time.RFC3339)
+	}
 
 	jsonData, err := json.Marshal(payload)
 	if err != nil {
@@ -100,7 +195,7 @@ func (c *GitLabClient) AddKey(ctx context.Context, title, publicKey string) (str
 		return "", fmt.Errorf("failed to create request: %w", err)
 	}
 
-	req.Header.Set("PRIVATE-TOKEN", c.token)
+	c.authorize(req)
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := c.client.Do(req)
@@ -127,13 +222,14 @@ func (c *GitLabClient) AddKey(ctx context.Context, title, publicKey string) (str
 // DeleteKey removes an SSH key from GitLab
 func (c *GitLabClient) DeleteKey(ctx context.Context, keyID string) error {
 	logger.Debug("Deleting GitLab SSH key: %s", keyID)
+	defer invalidateKeyCache(c)
 
 	req, err := http.NewRequestWithContext(ctx, "DELETE", c.baseURL+"/api/v4/user/keys/"+keyID, nil)
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
 
-	req.Header.Set("PRIVATE-TOKEN", c.token)
+	c.authorize(req)
 
 	resp, err := c.client.Do(req)
 	if err != nil {
@@ -150,6 +246,154 @@ func (c *GitLabClient) DeleteKey(ctx context.Context, keyID string) error {
 	return nil
 }
 
+// DeleteSigningKey removes an SSH signing key from GitLab, which keeps
+// signing keys with the authentication keys
+func (c *GitLabClient) DeleteSigningKey(ctx context.Context, keyID string) error {
+	return c.DeleteKey(ctx, keyID)
+}
+
+// AddGPGKey adds a GPG public key to GitLab
+func (c *GitLabClient) AddGPGKey(ctx context.Context, armoredPublicKey string) (string, error) {
+	logger.Debug("Adding GPG key to GitLab")
+
+	jsonData, err := json.Marshal(map[string]string{"key": armoredPublicKey})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/api/v4/user/gpg_keys", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+
+	c.authorize(req)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to add GitLab GPG key: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		body, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("GitLab API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var key struct {
+		ID int `json:"id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&key); err != nil {
+		return "", fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	keyID := fmt.Sprintf("%d", key.ID)
+	logger.Info("Added GPG key to GitLab (ID: %s)", keyID)
+	return keyID, nil
+}
+
+// DeleteGPGKey removes a GPG key from GitLab
+func (c *GitLabClient) DeleteGPGKey(ctx context.Context, keyID string) error {
+	logger.Debug("Deleting GitLab GPG key: %s", keyID)
+
+	req, err := http.NewRequestWithContext(ctx, "DELETE", c.baseURL+"/api/v4/user/gpg_keys/"+keyID, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	c.authorize(req)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to delete GitLab GPG key: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("GitLab API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	logger.Info("Deleted GPG key from GitLab: %s", keyID)
+	return nil
+}
+
+// AddDeployKey adds a deploy key to a GitLab project. A future expiresAt is
+// sent as expires_at.
+func (c *GitLabClient) AddDeployKey(ctx context.Context, repo, title, publicKey string, readWrite bool, expiresAt time.Time) (string, error) {
+	logger.Debug("Adding deploy key to GitLab project %s: %s", repo, title)
+
+	payload := map[string]any{
+		"title":    title,
+		"key":      publicKey,
+		"can_push": readWrite,
+	}
+	if expiresAt.After(time.Now()) {
+		payload["expires_at"] = expiresAt.UTC().Format(time.RFC3339)
+	}
+
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	endpoint := fmt.Sprintf("%s/api/v4/projects/%s/deploy_keys", c.baseURL, url.PathEscape(repo))
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+
+	c.authorize(req)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to add GitLab deploy key: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		body, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("GitLab API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var key gitlabKey
+	if err := json.NewDecoder(resp.Body).Decode(&key); err != nil {
+		return "", fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	keyID := fmt.Sprintf("%d", key.ID)
+	logger.Info("Added deploy key to %s: %s (ID: %s)", repo, title, keyID)
+	return keyID, nil
+}
+
+// DeleteDeployKey removes a deploy key from a GitLab project
+func (c *GitLabClient) DeleteDeployKey(ctx context.Context, repo, keyID string) error {
+	logger.Debug("Deleting deploy key %s from GitLab project %s", keyID, repo)
+
+	endpoint := fmt.Sprintf("%s/api/v4/projects/%s/deploy_keys/%s", c.baseURL, url.PathEscape(repo), keyID)
+	req, err := http.NewRequestWithContext(ctx, "DELETE", endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	c.authorize(req)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to delete GitLab deploy key: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("GitLab API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	logger.Info("Deleted deploy key from %s: %s", repo, keyID)
+	return nil
+}
+
 // GetKey retrieves a specific SSH key from GitLab
 func (c *GitLabClient) GetKey(ctx context.Context, keyID string) (*SSHKey, error) {
 	logger.Debug("Getting GitLab SSH key: %s", keyID)
@@ -159,7 +403,7 @@ func (c *GitLabClient) GetKey(ctx context.Context, keyID string) (*SSHKey, error
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	req.Header.Set("PRIVATE-TOKEN", c.token)
+	c.authorize(req)
 
 	resp, err := c.client.Do(req)
 	if err != nil {
@@ -177,12 +421,166 @@ func (c *GitLabClient) GetKey(ctx context.Context, keyID string) (*SSHKey, error
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	result := &SSHKey{
-		ID:        fmt.Sprintf("%d", key.ID),
-		Title:     key.Title,
-		Key:       key.Key,
-		CreatedAt: key.CreatedAt,
+	result := key.sshKey()
+	return &result, nil
+}
+
+type gitlabTokenSelf struct {
+	Name      string   `json:"name"`
+	Scopes    []string `json:"scopes"`
+	ExpiresAt string   `json:"expires_at"`
+	Active    bool     `json:"active"`
+	Revoked   bool     `json:"revoked"`
+}
+
+// CheckToken verifies the token against /user and reads its name, scopes and
+// expiry from /personal_access_tokens/self. Tokens that are not personal
+// access tokens (e.g. OAuth) are reported without scopes.
+func (c *GitLabClient) CheckToken(ctx context.Context) (*TokenInfo, error) {
+	logger.Debug("Checking GitLab token")
+
+	var user struct {
+		Username string `json:"username"`
 	}
+	status, err := c.getJSON(ctx, "/api/v4/user", &user)
+	if err != nil {
+		if status == http.StatusUnauthorized {
+			return nil, fmt.Errorf("GitLab rejected the token (invalid, expired or revoked)")
+		}
+		return nil, fmt.Errorf("failed to check GitLab token: %w", err)
+	}
+
+	info := &TokenInfo{User: user.Username}
 
+	var self gitlabTokenSelf
+	if _, err := c.getJSON(ctx, "/api/v4/personal_access_tokens/self", &self); err != nil {
+		logger.Debug("Token details unavailable: %v", err)
+		return info, nil
+	}
+
+	if self.Revoked || !self.Active {
+		return nil, fmt.Errorf("GitLab token %q is revoked or inactive", self.Name)
+	}
+
+	info.Name = self.Name
+	info.Scopes = self.Scopes
+	if self.ExpiresAt != "" {
+		if t, err := time.Parse("2006-01-02", self.ExpiresAt); err == nil {
+			info.ExpiresAt = &t
+		}
+	}
+
+	return info, nil
+}
+
+// getJSON performs an authenticated GET and decodes the response into v.
+// The status code is returned for callers that distinguish auth failures.
+func (c *GitLabClient) getJSON(ctx context.Context, path string, v any) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+path, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	c.authorize(req)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, fmt.Errorf("GitLab API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return resp.StatusCode, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// gitlabMemberKey is a key from /users/:id/keys
+type gitlabMemberKey struct {
+	gitlabKey
+	ExpiresAt string `json:"expires_at"`
+}
+
+// ListMemberKeys lists the SSH keys of every member of a GitLab group,
+// including members inherited from parent groups. GitLab reports when each
+// key was added and when it expires.
+func (c *GitLabClient) ListMemberKeys(ctx context.Context, group string) ([]MemberKey, error) {
+	logger.Debug("Listing members of GitLab group %s", group)
+
+	type member struct {
+		ID       int    `json:"id"`
+		Username string `json:"username"`
+	}
+	members, err := paginate(ctx, func(ctx context.Context, page int) ([]member, int, error) {
+		var members []member
+		next, err := c.getJSONPage(ctx, fmt.Sprintf("/api/v4/groups/%s/members/all", url.PathEscape(group)), page, &members)
+		return members, next, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list members of %s: %w", group, err)
+	}
+
+	var result []MemberKey
+	for _, m := range members {
+		keys, err := paginate(ctx, func(ctx context.Context, page int) ([]gitlabMemberKey, int, error) {
+			var keys []gitlabMemberKey
+			next, err := c.getJSONPage(ctx, fmt.Sprintf("/api/v4/users/%d/keys", m.ID), page, &keys)
+			return keys, next, err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list keys of %s: %w", m.Username, err)
+		}
+
+		for _, key := range keys {
+			result = append(result, MemberKey{
+				Member:    m.Username,
+				SSHKey:    key.sshKey(),
+				ExpiresAt: key.ExpiresAt,
+			})
+		}
+	}
+
+	logger.Info("Found %d SSH keys of %d members in %s", len(result), len(members), group)
 	return result, nil
 }
+
+// ListVerifiedDomains returns nil: GitLab has no API for a group's verified
+// domains
+func (c *GitLabClient) ListVerifiedDomains(ctx context.Context, group string) ([]string, error) {
+	return nil, nil
+}
+
+// getJSONPage fetches one page of a GitLab list endpoint into v and returns
+// the next page number, or 0 on the last page
+func (c *GitLabClient) getJSONPage(ctx context.Context, path string, page int, v any) (int, error) {
+	endpoint := fmt.Sprintf("%s%s?page=%d&per_page=%d", c.baseURL, path, page, pageSize)
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	c.authorize(req)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return 0, fmt.Errorf("GitLab API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return 0, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return gitlabNextPage(resp.Header), nil
+}
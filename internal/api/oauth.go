@@ -0,0 +1,168 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/kunlu/git-keys/internal/logger"
+)
+
+// GitLabOAuthScope is the scope git-keys requests; managing SSH keys needs api
+const GitLabOAuthScope = "api"
+
+// tokenExpiryMargin refreshes access tokens slightly before they expire
+const tokenExpiryMargin = time.Minute
+
+// OAuthToken is an OAuth access token with what is needed to refresh it
+type OAuthToken struct {
+	AccessToken  string            `json:"access_token"`
+	RefreshToken string            `json:"refresh_token"`
+	ExpiresAt    time.Time         `json:"expires_at"`
+	BaseURL      string            `json:"base_url"`
+	ClientID     string            `json:"client_id"`
+	Connection   ConnectionOptions `json:"connection,omitempty"`
+}
+
+// Expired reports whether the access token has expired or is about to
+func (t *OAuthToken) Expired() bool {
+	return !t.ExpiresAt.IsZero() && time.Now().Add(tokenExpiryMargin).After(t.ExpiresAt)
+}
+
+// GitLabOAuth runs the OAuth authorization code flow with PKCE against a
+// GitLab instance, for an application registered without a client secret
+type GitLabOAuth struct {
+	BaseURL     string
+	ClientID    string
+	RedirectURL string
+	Connection  ConnectionOptions
+}
+
+// NewPKCE returns a random code verifier and its S256 code challenge
+func NewPKCE() (string, string, error) {
+	verifier, err := randomURLString(32)
+	if err != nil {
+		return "", "", err
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	return verifier, base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// NewOAuthState returns a random state value to protect the redirect
+func NewOAuthState() (string, error) {
+	return randomURLString(16)
+}
+
+func randomURLString(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate random value: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// AuthorizeURL returns the URL to open in the browser
+func (o *GitLabOAuth) AuthorizeURL(state, challenge string) string {
+	params := url.Values{
+		"client_id":             {o.ClientID},
+		"redirect_uri":          {o.RedirectURL},
+		"response_type":         {"code"},
+		"scope":                 {GitLabOAuthScope},
+		"state":                 {state},
+		"code_challenge":        {challenge},
+		"code_challenge_method": {"S256"},
+	}
+	return strings.TrimSuffix(o.BaseURL, "/") + "/oauth/authorize?" + params.Encode()
+}
+
+// Exchange trades an authorization code for tokens
+func (o *GitLabOAuth) Exchange(ctx context.Context, code, verifier string) (*OAuthToken, error) {
+	return o.requestToken(ctx, url.Values{
+		"grant_type":    {"authorization_code"},
+		"client_id":     {o.ClientID},
+		"code":          {code},
+		"redirect_uri":  {o.RedirectURL},
+		"code_verifier": {verifier},
+	})
+}
+
+// RefreshGitLabToken uses a token's refresh token to obtain a new access token
+func RefreshGitLabToken(ctx context.Context, token *OAuthToken) (*OAuthToken, error) {
+	if token.RefreshToken == "" {
+		return nil, fmt.Errorf("token has no refresh token; run 'git-keys login gitlab' again")
+	}
+
+	o := &GitLabOAuth{BaseURL: token.BaseURL, ClientID: token.ClientID, Connection: token.Connection}
+	return o.requestToken(ctx, url.Values{
+		"grant_type":    {"refresh_token"},
+		"client_id":     {token.ClientID},
+		"refresh_token": {token.RefreshToken},
+	})
+}
+
+// requestToken posts to the token endpoint and returns the issued token
+func (o *GitLabOAuth) requestToken(ctx context.Context, form url.Values) (*OAuthToken, error) {
+	transport, err := newBaseTransport(o.Connection)
+	if err != nil {
+		return nil, err
+	}
+	client := newHTTPClient(transport)
+
+	endpoint := strings.TrimSuffix(o.BaseURL, "/") + "/oauth/token"
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("GitLab token endpoint error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var payload struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int64  `json:"expires_in"`
+		CreatedAt    int64  `json:"created_at"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return nil, fmt.Errorf("failed to decode token response: %w", err)
+	}
+	if payload.AccessToken == "" {
+		return nil, fmt.Errorf("GitLab returned no access token")
+	}
+
+	token := &OAuthToken{
+		AccessToken:  payload.AccessToken,
+		RefreshToken: payload.RefreshToken,
+		BaseURL:      o.BaseURL,
+		ClientID:     o.ClientID,
+		Connection:   o.Connection,
+	}
+	if payload.ExpiresIn > 0 {
+		issued := time.Now()
+		if payload.CreatedAt > 0 {
+			issued = time.Unix(payload.CreatedAt, 0)
+		}
+		token.ExpiresAt = issued.Add(time.Duration(payload.ExpiresIn) * time.Second)
+	}
+
+	logger.Debug("Obtained GitLab OAuth token (expires %s)", token.ExpiresAt.Format(time.RFC3339))
+	return token, nil
+}
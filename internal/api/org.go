@@ -0,0 +1,20 @@
+package api
+
+import "context"
+
+// MemberKey is an SSH key of an organization (GitHub) or group (GitLab) member
+type MemberKey struct {
+	Member     string // Login of the member
+	SSHKey            // CreatedAt may be "" when the platform does not report it
+	ExpiresAt  string // GitLab only; "" when the key does not expire
+	Authorized string // GitHub SAML SSO only: when the key was authorized for the org
+}
+
+// OrgAuditor is implemented by clients that can list the SSH keys of an
+// organization's members. It needs an owner or admin token.
+type OrgAuditor interface {
+	ListMemberKeys(ctx context.Context, org string) ([]MemberKey, error)
+	// ListVerifiedDomains returns the organization's verified email domains,
+	// or nil when the platform does not report them
+	ListVerifiedDomains(ctx context.Context, org string) ([]string, error)
+}
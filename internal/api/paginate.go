@@ -0,0 +1,50 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+const (
+	// pageSize is requested from list endpoints (the maximum on GitHub and GitLab)
+	pageSize = 100
+
+	// maxPages guards against a server that never reports the last page
+	maxPages = 1000
+)
+
+// pageFunc fetches one page of results and returns the next page number,
+// or 0 when there are no more pages
+type pageFunc[T any] func(ctx context.Context, page int) (items []T, nextPage int, err error)
+
+// paginate collects all items by calling fetch until it reports no next page
+func paginate[T any](ctx context.Context, fetch pageFunc[T]) ([]T, error) {
+	var all []T
+	page := 1
+
+	for i := 0; i < maxPages; i++ {
+		items, next, err := fetch(ctx, page)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, items...)
+
+		if next == 0 || next <= page {
+			return all, nil
+		}
+		page = next
+	}
+
+	return nil, fmt.Errorf("pagination did not finish after %d pages", maxPages)
+}
+
+// gitlabNextPage reads GitLab's X-Next-Page header (empty on the last page)
+func gitlabNextPage(header http.Header) int {
+	next, err := strconv.Atoi(header.Get("X-Next-Page"))
+	if err != nil {
+		return 0
+	}
+	return next
+}
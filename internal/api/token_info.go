@@ -0,0 +1,41 @@
+package api
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// TokenInfo describes an API token as reported by the platform
+type TokenInfo struct {
+	User      string     // Account the token authenticates as
+	Name      string     // Token name, when the platform reports it
+	Scopes    []string   // Granted scopes; nil when the platform does not report them
+	ExpiresAt *time.Time // Expiry date; nil when the token does not expire or it is unknown
+}
+
+// HasScope reports whether the token was granted scope
+func (i *TokenInfo) HasScope(scope string) bool {
+	for _, s := range i.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// TokenChecker is implemented by clients that can describe their own token
+type TokenChecker interface {
+	CheckToken(ctx context.Context) (*TokenInfo, error)
+}
+
+// parseScopes splits a comma-separated scope header such as X-OAuth-Scopes
+func parseScopes(header string) []string {
+	scopes := []string{}
+	for _, s := range strings.Split(header, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			scopes = append(scopes, s)
+		}
+	}
+	return scopes
+}
@@ -0,0 +1,228 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/kunlu/git-keys/internal/logger"
+)
+
+const (
+	// DefaultRetries is how often a failed request is retried
+	DefaultRetries = 3
+
+	// DefaultTimeout bounds a single request attempt
+	DefaultTimeout = 30 * time.Second
+
+	baseBackoff = 500 * time.Millisecond
+	maxBackoff  = 30 * time.Second
+
+	// maxRateLimitWait is the longest we wait for a rate limit to reset
+	// before giving up and returning the error to the caller
+	maxRateLimitWait = 2 * time.Minute
+)
+
+// TransportOptions configures retries and timeouts for platform API clients
+type TransportOptions struct {
+	Retries int           // Retries after the first attempt; negative disables retries
+	Timeout time.Duration // Timeout per attempt; zero uses DefaultTimeout
+}
+
+var (
+	defaultOptionsMu sync.RWMutex
+	defaultOptions   = TransportOptions{Retries: DefaultRetries, Timeout: DefaultTimeout}
+)
+
+// SetDefaultTransportOptions sets the options used by clients created afterwards.
+// Zero values keep the built-in defaults.
+func SetDefaultTransportOptions(opts TransportOptions) {
+	if opts.Retries == 0 {
+		opts.Retries = DefaultRetries
+	}
+	if opts.Retries < 0 {
+		opts.Retries = 0
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+
+	defaultOptionsMu.Lock()
+	defaultOptions = opts
+	defaultOptionsMu.Unlock()
+}
+
+func getDefaultTransportOptions() TransportOptions {
+	defaultOptionsMu.RLock()
+	defer defaultOptionsMu.RUnlock()
+	return defaultOptions
+}
+
+// newHTTPClient returns an HTTP client that retries transient failures
+func newHTTPClient(base http.RoundTripper) *http.Client {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	opts := getDefaultTransportOptions()
+	return &http.Client{
+		Transport: &retryTransport{base: base, retries: opts.Retries, timeout: opts.Timeout},
+	}
+}
+
+var errNoRewind = errors.New("cannot retry request: body is not rewindable")
+
+// retryTransport retries requests on 5xx, 429 and rate-limit responses with
+// exponential backoff, honoring Retry-After and GitHub's X-RateLimit-Reset.
+// Non-idempotent requests are only retried when the server did not process
+// them (429 or rate limited).
+type retryTransport struct {
+	base    http.RoundTripper
+	retries int
+	timeout time.Duration
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		attemptReq := req
+		if attempt > 0 && req.Body != nil {
+			if req.GetBody == nil {
+				return nil, errNoRewind
+			}
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			attemptReq = req.Clone(req.Context())
+			attemptReq.Body = body
+		}
+
+		resp, err := t.roundTripOnce(attemptReq)
+
+		retry, wait := t.shouldRetry(req, resp, err, attempt)
+		if !retry || attempt >= t.retries {
+			return resp, err
+		}
+
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			logger.Debug("%s %s returned %d, retrying in %s (attempt %d/%d)",
+				req.Method, req.URL.Path, resp.StatusCode, wait, attempt+1, t.retries)
+		} else {
+			logger.Debug("%s %s failed: %v, retrying in %s (attempt %d/%d)",
+				req.Method, req.URL.Path, err, wait, attempt+1, t.retries)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		}
+	}
+}
+
+// roundTripOnce performs one attempt bounded by the per-attempt timeout. The
+// timeout stays active until the response body is closed.
+func (t *retryTransport) roundTripOnce(req *http.Request) (*http.Response, error) {
+	if t.timeout <= 0 {
+		return t.base.RoundTrip(req)
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), t.timeout)
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// shouldRetry decides whether to retry and how long to wait first
+func (t *retryTransport) shouldRetry(req *http.Request, resp *http.Response, err error, attempt int) (bool, time.Duration) {
+	if err != nil {
+		// Connection-level failures; give up if the caller canceled
+		if req.Context().Err() != nil {
+			return false, 0
+		}
+		return isIdempotent(req.Method), backoff(attempt)
+	}
+
+	if wait, limited := rateLimitWait(resp); limited {
+		return wait <= maxRateLimitWait, wait
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return true, backoff(attempt)
+	case resp.StatusCode >= 500 && isIdempotent(req.Method):
+		return true, backoff(attempt)
+	}
+
+	return false, 0
+}
+
+// rateLimitWait reports whether resp is a rate-limit response and how long
+// to wait, based on Retry-After or GitHub's X-RateLimit-* headers
+func rateLimitWait(resp *http.Response) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusForbidden {
+		return 0, false
+	}
+
+	if retryAfter := resp.Header.Get("Retry-After"); retryAfter != "" {
+		if seconds, err := strconv.Atoi(retryAfter); err == nil {
+			return time.Duration(seconds) * time.Second, true
+		}
+		if at, err := http.ParseTime(retryAfter); err == nil {
+			return time.Until(at), true
+		}
+	}
+
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			wait := time.Until(time.Unix(reset, 0)) + time.Second
+			if wait < 0 {
+				wait = 0
+			}
+			return wait, true
+		}
+	}
+
+	return 0, false
+}
+
+// backoff returns an exponential delay with jitter for the given attempt
+func backoff(attempt int) time.Duration {
+	delay := baseBackoff << attempt
+	if delay > maxBackoff || delay <= 0 {
+		delay = maxBackoff
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodDelete, http.MethodPut:
+		return true
+	}
+	return false
+}
+
+// cancelOnClose releases a per-attempt context once the body is consumed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
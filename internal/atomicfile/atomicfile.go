@@ -0,0 +1,55 @@
+// Package atomicfile writes configuration files other programs read, such
+// as ~/.ssh/config and ~/.gitconfig. A write goes to a temporary file that
+// replaces the original in one rename, so a crash never leaves a truncated
+// file behind, and Lock keeps concurrent runs from interleaving their
+// read-modify-write cycles.
+package atomicfile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// WriteFile replaces the content of path with data. An existing file keeps
+// its permissions; a new one gets perm. A symlink, e.g. from a dotfiles
+// repository, is followed so the file it points to is replaced rather than
+// the link.
+func WriteFile(path string, data []byte, perm os.FileMode) error {
+	if target, err := filepath.EvalSymlinks(path); err == nil {
+		path = target
+	}
+	if info, err := os.Stat(path); err == nil {
+		perm = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), perm)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// LockTimeout is how long Lock waits for another holder of the lock
+var LockTimeout = 30 * time.Second
+
+// lockRetryInterval is how often Lock tries again while waiting
+const lockRetryInterval = 50 * time.Millisecond
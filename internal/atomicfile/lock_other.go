@@ -0,0 +1,8 @@
+//go:build !unix
+
+package atomicfile
+
+// Lock does nothing where flock is not available; writes are still atomic
+func Lock(path string) (unlock func(), err error) {
+	return func() {}, nil
+}
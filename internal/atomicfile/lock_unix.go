@@ -0,0 +1,49 @@
+//go:build unix
+
+package atomicfile
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// Lock takes an advisory lock for writing path and returns the function that
+// releases it. It waits up to LockTimeout for another holder, in this or
+// another process, to finish.
+//
+// The lock is held on the directory of path rather than the file, as
+// WriteFile replaces the file and a lock on the old one would not exclude
+// a writer that opened the new one. Locks are not reentrant: release one
+// before locking the same directory again.
+func Lock(path string) (unlock func(), err error) {
+	dir := filepath.Dir(path)
+	f, err := os.Open(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+	}
+
+	deadline := time.Now().Add(LockTimeout)
+	for {
+		err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, syscall.EWOULDBLOCK) || time.Now().After(deadline) {
+			f.Close()
+			if errors.Is(err, syscall.EWOULDBLOCK) {
+				return nil, fmt.Errorf("timed out waiting for another git-keys run to finish writing %s", path)
+			}
+			return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+		}
+		time.Sleep(lockRetryInterval)
+	}
+
+	return func() {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
@@ -0,0 +1,271 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kunlu/git-keys/internal/api"
+	"github.com/kunlu/git-keys/internal/config"
+	"github.com/kunlu/git-keys/internal/history"
+	"github.com/kunlu/git-keys/internal/journal"
+	"github.com/kunlu/git-keys/internal/logger"
+	"github.com/kunlu/git-keys/internal/sshkey"
+	"github.com/spf13/cobra"
+)
+
+var adoptNoUpload bool
+
+var adoptCmd = &cobra.Command{
+	Use:   "adopt <keyfile> <persona>/<platform>[@account]",
+	Short: "Bring one existing key under management",
+	Long: `Register a single existing key pair with a persona's platform, without the
+import wizard.
+
+The key stays where it is. adopt:
+  1. checks that the private and public key files belong together
+  2. records the key with its fingerprint, creation date (from the key
+     comment, or the file date) and expiry
+  3. looks the key up on the platform and uploads it if it is not registered
+  4. writes the platform's SSH config block
+
+The platform must not have an active key yet; replace one with
+'git-keys rotate' instead. Every change can be taken back with 'git-keys undo'.
+
+Examples:
+  git-keys adopt ~/.ssh/id_ed25519_work work/github
+  git-keys adopt ~/.ssh/id_rsa_gitlab work/gitlab@work-user
+  git-keys adopt ~/.ssh/id_ed25519 personal/github --no-upload
+`,
+	Args:              cobra.ExactArgs(2),
+	SilenceUsage:      true,
+	ValidArgsFunction: completeAdoptArgs,
+	RunE:              runAdopt,
+}
+
+func init() {
+	adoptCmd.Flags().BoolVar(&adoptNoUpload, "no-upload", false, "Record the key without checking or uploading it on the platform")
+	rootCmd.AddCommand(adoptCmd)
+}
+
+func runAdopt(cmd *cobra.Command, args []string) (err error) {
+	ctx := context.Background()
+
+	// Load configuration
+	configPath := cfgFile
+	if configPath == "" {
+		configPath = config.GetDefaultConfigPath()
+	}
+
+	mgr := config.NewManager(configPath)
+	if !mgr.Exists() {
+		return fmt.Errorf("configuration file not found at %s\nRun 'git-keys init' first", configPath)
+	}
+
+	cfg, err := mgr.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	persona, plat, err := resolvePlatformTarget(cfg, args[1])
+	if err != nil {
+		return err
+	}
+	if active := plat.GetActiveKey(); active != nil {
+		return fmt.Errorf("%s/%s@%s already has key %s; use 'git-keys rotate' to replace it",
+			persona.Name, plat.Type, plat.Account, active.LocalPath)
+	}
+
+	keysDir := cfg.Defaults.GetKeysDir()
+	keyMgr := sshkey.NewManager(keysDir)
+	key, err := readAdoptedKey(cfg, keyMgr, persona, plat, args[0])
+	if err != nil {
+		return err
+	}
+
+	label := fmt.Sprintf("%s/%s@%s", persona.Name, plat.Type, plat.Account)
+	printHeader(fmt.Sprintf("\n📥 Adopt %s", filepath.Base(args[0])))
+	fmt.Printf("  Target: %s\n", label)
+	fmt.Printf("  Key: %s (%s)\n", keyMgr.IdentityFilePath(key.LocalPath), key.Type)
+	fmt.Printf("  Fingerprint: %s\n", key.Fingerprint)
+	fmt.Printf("  Created: %s\n", key.CreatedAt.Format("2006-01-02"))
+	fmt.Printf("  Expires: %s\n", key.ExpiresAt.Format("2006-01-02"))
+	fmt.Println()
+
+	j, err := journal.Begin("", "adopt")
+	if err != nil {
+		return fmt.Errorf("failed to start journal: %w", err)
+	}
+	defer func() {
+		finishJournal(j, err)
+	}()
+
+	if adoptNoUpload {
+		fmt.Println("⊘ Not checked on the platform (--no-upload); 'git-keys apply' uploads it")
+	} else if err := registerAdoptedKey(ctx, cfg, keyMgr, persona, plat, key, j); err != nil {
+		logger.Warn("Could not register key on %s: %v", label, err)
+		fmt.Printf("⚠️  Not registered on the platform: %v\n", err)
+		fmt.Println("   'git-keys apply' uploads it once a token is available")
+	}
+
+	plat.Keys = append(plat.Keys, *key)
+
+	sshMgr := sshConfigManager(cfg)
+	journalSSHConfig(j, sshMgr)
+	if err := updateSSHConfig(cfg, sshMgr, keyMgr, persona, plat, key); err != nil {
+		return err
+	}
+	alias, _ := sshHostAlias(persona, plat)
+	fmt.Printf("✓ Updated SSH config: Host %s\n", alias)
+
+	journalWarn(j.BackupFile(config.StoragePath(configPath)))
+	if err := mgr.Save(cfg); err != nil {
+		return fmt.Errorf("failed to save configuration: %w", err)
+	}
+	fmt.Println("✓ Recorded key in configuration")
+
+	histMgr := history.NewManager("")
+	if err := histMgr.Record(history.Entry{
+		Action:  "adopt",
+		Summary: fmt.Sprintf("Adopted %s for %s", filepath.Base(args[0]), label),
+		Details: map[string]string{
+			"key":         keyMgr.IdentityFilePath(key.LocalPath),
+			"fingerprint": key.Fingerprint,
+			"remote_id":   key.RemoteID,
+		},
+	}); err != nil {
+		logger.Warn("Failed to record history: %v", err)
+	}
+
+	if time.Now().After(key.ExpiresAt) {
+		fmt.Printf("\n⚠️  The key is past its expiry; replace it with 'git-keys rotate %s/%s'.\n", persona.Name, plat.Type)
+	}
+	fmt.Printf("\n✅ Adopted %s for %s.\n", filepath.Base(args[0]), label)
+	return nil
+}
+
+// resolvePlatformTarget resolves persona/platform[@account] to a single platform
+func resolvePlatformTarget(cfg *config.Config, target string) (*config.Persona, *config.Platform, error) {
+	if !strings.Contains(target, "/") {
+		return nil, nil, fmt.Errorf("invalid target %q: use <persona>/<platform>[@account]", target)
+	}
+	t, err := parsePlatformTarget(cfg, target)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	persona := cfg.FindPersona(t.Persona)
+	var found *config.Platform
+	for i := range persona.Platforms {
+		plat := &persona.Platforms[i]
+		if !t.includes(persona, plat) {
+			continue
+		}
+		if found != nil {
+			return nil, nil, fmt.Errorf("persona '%s' has several %s accounts; use %s@<account>", persona.Name, plat.Type, target)
+		}
+		found = plat
+	}
+	return persona, found, nil
+}
+
+// readAdoptedKey checks an existing key pair and returns its config entry,
+// with local_path relative to the keys directory
+func readAdoptedKey(cfg *config.Config, keyMgr *sshkey.Manager, persona *config.Persona, plat *config.Platform, keyFile string) (*config.KeyConfig, error) {
+	path, err := filepath.Abs(sshkey.ExpandHome(strings.TrimSuffix(keyFile, ".pub")))
+	if err != nil {
+		return nil, err
+	}
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("private key not found: %w", err)
+	}
+	if !fileExists(path + ".pub") {
+		return nil, fmt.Errorf("public key %s.pub not found; recreate it with 'ssh-keygen -y -f %s > %s.pub'", path, path, path)
+	}
+
+	localPath, err := filepath.Rel(keyMgr.KeysDir(), path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot reference %s from %s: %w", path, keyMgr.KeysDir(), err)
+	}
+
+	if err := keyMgr.VerifyKeyPair(localPath); err != nil {
+		return nil, err
+	}
+
+	publicKey, err := keyMgr.GetPublicKey(localPath)
+	if err != nil {
+		return nil, err
+	}
+	info, err := sshkey.ParsePublicKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// A key can only be managed once
+	for _, p := range cfg.Personas {
+		for _, other := range p.Platforms {
+			for _, k := range other.Keys {
+				if k.Fingerprint == info.Fingerprint && k.Status != config.KeyStatusRevoked {
+					return nil, fmt.Errorf("key %s is already managed by %s/%s@%s", info.Fingerprint, p.Name, other.Type, other.Account)
+				}
+			}
+		}
+	}
+
+	createdAt := stat.ModTime()
+	if created, ok := sshkey.ParseCreationHint(info.Comment); ok {
+		createdAt = created
+	}
+	settings := cfg.ResolveKeySettings(persona, plat)
+
+	return &config.KeyConfig{
+		Type:        sshkey.KeyTypeFromPublicKey(info.Type),
+		CreatedAt:   createdAt,
+		ExpiresAt:   settings.ExpiresAt(createdAt),
+		Fingerprint: info.Fingerprint,
+		LocalPath:   localPath,
+		Status:      config.KeyStatusActive,
+	}, nil
+}
+
+// registerAdoptedKey records the remote ID of an adopted key that is already
+// registered on its platform, or uploads it
+func registerAdoptedKey(ctx context.Context, cfg *config.Config, keyMgr *sshkey.Manager, persona *config.Persona, plat *config.Platform, key *config.KeyConfig, j *journal.Journal) error {
+	token, _, err := lookupToken(plat, loadTokensFromEnv())
+	if err != nil {
+		return err
+	}
+	client, err := newPlatformClientWithToken(plat, token)
+	if err != nil {
+		return err
+	}
+
+	remoteKeys, _, err := api.ListKeysCached(ctx, client, true)
+	if err != nil {
+		return fmt.Errorf("failed to list remote keys: %w", err)
+	}
+	for _, remote := range remoteKeys {
+		if remoteFingerprint(remote) == strings.TrimPrefix(key.Fingerprint, "SHA256:") {
+			key.RemoteID = remote.ID
+			fmt.Printf("✓ Already registered on %s as %q (ID %s)\n", plat.Type, remote.Title, remote.ID)
+			return nil
+		}
+	}
+
+	settings := cfg.ResolveKeySettings(persona, plat)
+	title, err := sshkey.RenderTitle(settings, sshkey.DefaultTitleTemplate,
+		sshkey.NewNameData(persona, plat, key.Type, cfg.Machine.Name, time.Now()), key.ExpiresAt)
+	if err != nil {
+		return err
+	}
+	if err := uploadKeyWithToken(ctx, keyMgr, plat, key, title, token); err != nil {
+		return err
+	}
+	journalWarn(j.KeyUploaded(persona.Name, string(plat.Type), plat.Account, key.RemoteID, key.Fingerprint))
+	fmt.Printf("✓ Uploaded key to %s (ID %s)\n", plat.Type, key.RemoteID)
+	return nil
+}
@@ -1,18 +1,22 @@
 package commands
 
 import (
-	"bufio"
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/kunlu/git-keys/internal/api"
+	"github.com/kunlu/git-keys/internal/atomicfile"
 	"github.com/kunlu/git-keys/internal/config"
+	"github.com/kunlu/git-keys/internal/history"
+	"github.com/kunlu/git-keys/internal/journal"
 	"github.com/kunlu/git-keys/internal/logger"
 	"github.com/kunlu/git-keys/internal/platform"
+	"github.com/kunlu/git-keys/internal/prompt"
 	"github.com/kunlu/git-keys/internal/sshconfig"
 	"github.com/kunlu/git-keys/internal/sshkey"
 	"github.com/spf13/cobra"
@@ -32,20 +36,59 @@ func sanitizeHostname(name string) string {
 var applyCmd = &cobra.Command{
 	Use:   "apply",
 	Short: "Apply the configuration changes",
-	Long:  `Generate SSH keys, upload to platforms, update SSH config, and configure git identity switching.`,
-	RunE:  runApply,
+	Long: `Generate SSH keys, upload to platforms, update SSH config, and configure git identity switching.
+
+With --safe (or defaults.safe_apply), apply first checks the SSH config,
+~/.gitconfig and per-platform git config files, and refuses to run if it would
+change anything outside the regions git-keys manages, such as an existing
+hand-written Host entry for a git-keys alias or a managed block whose end
+marker was deleted. The conflicting lines are printed.
+
+With --target, apply only works on one persona or one of its platforms, e.g.
+when onboarding a new account. The target is persona, persona/platform or
+persona/platform@account (for a persona with several accounts on a platform):
+
+  git-keys apply --target work
+  git-keys apply --target work/github
+  git-keys apply --target work/github@acme-bot
+
+With --strict (or defaults.strict), apply refuses to run while there are
+warnings: personas without an email, platforms without a gitdir, insecure
+permissions or weak keys. With --target, only the targeted platforms are
+checked; permissions are always checked.
+
+With --verify (or defaults.verify_apply), apply finishes by testing each SSH
+host alias with 'ssh -T' and checking that each gitdir's includeIf applies the
+persona's email. The apply is recorded as verified in history only when every
+check passes; otherwise apply fails, keeping its changes, and prints the
+command that fixes each problem.`,
+	RunE: runApply,
 }
 
 var (
-	applyYes bool
+	applyYes      bool
+	applySafe     bool
+	applyParallel int
+	applyTarget   string
+	applyStrict   bool
+	applyVerify   bool
+	applyGitDirs  []string
 )
 
 func init() {
 	applyCmd.Flags().BoolVarP(&applyYes, "yes", "y", false, "skip confirmation prompts")
+	applyCmd.Flags().BoolVar(&applySafe, "safe", false, "refuse to modify anything outside git-keys managed regions")
+	applyCmd.Flags().IntVar(&applyParallel, "parallel", defaultParallel, "number of platform accounts to upload keys to at once")
+	applyCmd.Flags().StringVar(&applyTarget, "target", "", "only apply persona[/platform[@account]]")
+	applyCmd.RegisterFlagCompletionFunc("target", completeTargetFlag)
+	applyCmd.Flags().BoolVar(&applyStrict, "strict", false, "refuse to apply when there are warnings")
+	applyCmd.Flags().BoolVar(&applyVerify, "verify", false, "test SSH connections and git identities after applying")
+	applyCmd.Flags().StringArrayVar(&applyGitDirs, "gitdir", nil, "directory of new platforms without one, as persona[/platform[@account]]=dir (repeatable)")
+	applyCmd.RegisterFlagCompletionFunc("gitdir", completeTargetAnswer)
 	rootCmd.AddCommand(applyCmd)
 }
 
-func runApply(cmd *cobra.Command, args []string) error {
+func runApply(cmd *cobra.Command, args []string) (err error) {
 	logger.Info("Applying configuration...")
 
 	// Load config
@@ -64,6 +107,27 @@ func runApply(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
+	// Apply on top of an interrupted operation would make undoing it harder
+	if last, err := journal.Latest(""); err != nil {
+		logger.Warn("Failed to read operation history: %v", err)
+	} else if last != nil && (last.Status == journal.StatusRunning || last.Status == journal.StatusFailed) {
+		return fmt.Errorf("the %s started %s was not completed or rolled back; run 'git-keys undo' to undo it, or 'git-keys undo --keep' to keep its changes",
+			last.Operation, last.StartedAt.Local().Format("2006-01-02 15:04"))
+	}
+
+	reconcileExpiry(mgr, cfg)
+
+	var target *platformTarget
+	if applyTarget != "" {
+		if target, err = parsePlatformTarget(cfg, applyTarget); err != nil {
+			return err
+		}
+	}
+	gitDirs, err := parseTargetAnswers(cfg, "--gitdir", applyGitDirs)
+	if err != nil {
+		return err
+	}
+
 	// Get platform info
 	plat, err := platform.NewPlatform()
 	if err != nil {
@@ -75,22 +139,59 @@ func runApply(cmd *cobra.Command, args []string) error {
 		machineName = "unknown"
 	}
 
+	if strictMode(cmd, cfg) {
+		if err := checkStrict(cfg, sshkey.NewManager(cfg.Defaults.GetKeysDir()), target); err != nil {
+			return err
+		}
+		logger.Info("Strict mode: no warnings")
+	}
+
+	if err := enforcePolicy(cfg); err != nil {
+		return err
+	}
+
+	if applySafe || cfg.Defaults.SafeApply {
+		conflicts, err := findApplyConflicts(cfg, sshConfigManager(cfg))
+		if err != nil {
+			return fmt.Errorf("safe mode check failed: %w", err)
+		}
+		if len(conflicts) > 0 {
+			fmt.Println("\n❌ Safe mode: apply would modify content outside git-keys managed regions:")
+			for _, c := range conflicts {
+				printWrapped("   • ", c.String())
+			}
+			fmt.Println("\nResolve these lines, or run without --safe, and try again.")
+			return fmt.Errorf("safe mode: %d conflict(s) with unmanaged configuration", len(conflicts))
+		}
+		logger.Info("Safe mode: no conflicts with unmanaged configuration")
+	}
+
 	// Confirm unless -y flag
 	if !applyYes {
-		fmt.Print("\nThis will generate SSH keys and modify your SSH config. Continue? (y/n): ")
-		reader := bufio.NewReader(os.Stdin)
-		response, _ := reader.ReadString('\n')
-		response = strings.TrimSpace(strings.ToLower(response))
-
-		if response != "y" && response != "yes" {
+		if target != nil {
+			fmt.Printf("\nOnly %s will be applied.", applyTarget)
+		}
+		if !prompt.Confirm("\nThis will generate SSH keys and modify your SSH config. Continue?") {
 			fmt.Println("Cancelled.")
 			return nil
 		}
 	}
 
 	// Initialize managers
-	keyMgr := sshkey.NewManager("")
-	sshMgr := sshconfig.NewManager(cfg.Defaults.SSHConfigPath)
+	keyMgr := sshkey.NewManager(cfg.Defaults.GetKeysDir())
+	sshMgr := sshConfigManager(cfg)
+
+	// Every change is journaled so a failure can roll back the whole apply
+	j, err := journal.Begin("", "apply")
+	if err != nil {
+		return fmt.Errorf("failed to start journal: %w", err)
+	}
+	defer func() {
+		finishApplyJournal(j, configPath, err)
+	}()
+	if err := journalApplyFiles(j, cfg, configPath, sshMgr, target); err != nil {
+		return err
+	}
 
 	// Backup SSH config
 	if _, err := sshMgr.BackupConfig(); err != nil {
@@ -102,51 +203,82 @@ func runApply(cmd *cobra.Command, args []string) error {
 	// Process each persona and platform
 	for personaIdx := range cfg.Personas {
 		persona := &cfg.Personas[personaIdx]
+		if persona.Archived {
+			continue
+		}
 
 		for platformIdx := range persona.Platforms {
 			platform := &persona.Platforms[platformIdx]
+			if !target.includes(persona, platform) {
+				continue
+			}
 
 			logger.Info("Processing %s/%s for persona %s", platform.Type, platform.Account, persona.Name)
 
+			signingChanged, err := ensureSigningKey(cfg, keyMgr, persona, platform, machineName, j)
+			if err != nil {
+				return err
+			}
+			gpgChanged, err := ensureGPGKey(persona, j)
+			if err != nil {
+				return err
+			}
+			if signingChanged || gpgChanged {
+				configChanged = true
+			}
+
 			// Check if active key exists
 			activeKey := platform.GetActiveKey()
 
-			if activeKey == nil {
-				// Generate new key
-				keyFileName := sshkey.BuildKeyFileName(platform.Type, platform.Account, cfg.Defaults.KeyType)
-				keyComment := sshkey.BuildKeyComment(platform.Type, platform.Account, machineName)
+			if activeKey == nil && platform.UsesExternalAgent() {
+				// Key lives in an external agent (e.g., Secure Enclave); only record its public key
+				agentKey, err := adoptAgentKey(keyMgr, cfg, persona, platform, machineName)
+				if err != nil {
+					return fmt.Errorf("failed to read key from agent %s: %w", platform.IdentityAgent, err)
+				}
+				if err := j.KeyCreated(filepath.Join(keyMgr.KeysDir(), agentKey.LocalPath)); err != nil {
+					return err
+				}
 
-				logger.Info("Generating new %s key: %s", cfg.Defaults.KeyType, keyFileName)
+				platform.Keys = append(platform.Keys, *agentKey)
+				activeKey = &platform.Keys[len(platform.Keys)-1]
+				configChanged = true
 
-				if err := keyMgr.GenerateKey(cfg.Defaults.KeyType, keyComment, keyFileName); err != nil {
-					return fmt.Errorf("failed to generate key: %w", err)
+				fmt.Printf("✓ Using agent key: %s (%s)\n", activeKey.Fingerprint, platform.IdentityAgent)
+			}
+
+			if activeKey != nil && activeKey.PublicOnly {
+				// Private key lives elsewhere; only track and register the public key
+				if activeKey.Fingerprint == "" {
+					if err := fillPublicOnlyKey(keyMgr, activeKey); err != nil {
+						return fmt.Errorf("failed to read public-only key %s: %w", activeKey.LocalPath, err)
+					}
+					configChanged = true
 				}
 
-				// Get fingerprint
-				fingerprint, err := keyMgr.GetFingerprint(keyFileName)
+				fmt.Printf("✓ Tracking public-only key for %s@%s: %s (no SSH config entry)\n",
+					platform.Account, platform.Type, activeKey.LocalPath)
+				continue
+			}
+
+			if activeKey == nil {
+				newKey, err := generatePlatformKey(cfg, keyMgr, persona, platform, machineName)
 				if err != nil {
-					return fmt.Errorf("failed to get fingerprint: %w", err)
+					return err
 				}
-
-				// Create key config
-				newKey := config.KeyConfig{
-					Type:        cfg.Defaults.KeyType,
-					CreatedAt:   time.Now(),
-					ExpiresAt:   time.Now().AddDate(0, 6, 0), // 6 months default
-					Fingerprint: fingerprint,
-					LocalPath:   keyFileName,
-					Status:      config.KeyStatusActive,
+				if err := j.KeyCreated(filepath.Join(keyMgr.KeysDir(), newKey.LocalPath)); err != nil {
+					return err
 				}
 
-				platform.Keys = append(platform.Keys, newKey)
+				platform.Keys = append(platform.Keys, *newKey)
 				activeKey = &platform.Keys[len(platform.Keys)-1]
 				configChanged = true
 
-				fmt.Printf("✓ Generated key: %s\n", keyFileName)
+				fmt.Printf("✓ Generated key: %s\n", activeKey.LocalPath)
 			}
 
 			// Update SSH config
-			if err := updateSSHConfig(sshMgr, persona, platform, activeKey); err != nil {
+			if err := updateSSHConfig(cfg, sshMgr, keyMgr, persona, platform, activeKey); err != nil {
 				return fmt.Errorf("failed to update SSH config: %w", err)
 			}
 
@@ -154,6 +286,21 @@ func runApply(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	// A Host * or Host github.* section read before the managed blocks can
+	// still decide which key ssh offers, and a Host github.com of its own
+	// decides it for git commands that do not use an alias
+	if err := resolveSSHHostConflicts(cfg, sshMgr, keyMgr); err != nil {
+		logger.Warn("Failed to resolve SSH config conflicts: %v", err)
+	}
+
+	// Pin the host keys so the first connection is not trust on first use
+	if changed, err := updateKnownHosts(context.Background(), cfg); err != nil {
+		logger.Warn("Failed to update known_hosts: %v", err)
+		fmt.Println("\n⚠️  Could not update host keys in known_hosts; ssh will ask to trust new hosts")
+	} else if changed {
+		fmt.Printf("✓ Updated host keys in %s\n", cfg.Defaults.GetKnownHostsFile())
+	}
+
 	// Save updated config if changed
 	if configChanged {
 		if err := mgr.Save(cfg); err != nil {
@@ -167,28 +314,134 @@ func runApply(cmd *cobra.Command, args []string) error {
 	ctx := context.Background()
 	envTokens := loadTokensFromEnv()
 
+	// Tokens are collected first since a missing one is prompted for; the
+	// uploads then run concurrently per platform account
+	var uploads []platformTask
+
 	for personaIdx := range cfg.Personas {
 		persona := &cfg.Personas[personaIdx]
+		if persona.Archived {
+			continue
+		}
 		for platformIdx := range persona.Platforms {
 			platform := &persona.Platforms[platformIdx]
-			activeKey := platform.GetActiveKey()
+			if !target.includes(persona, platform) {
+				continue
+			}
 
-			if activeKey == nil || activeKey.RemoteID != "" {
-				continue // Skip if no key or already uploaded
+			activeKey := platform.GetActiveKey()
+			if activeKey != nil && activeKey.RemoteID != "" {
+				activeKey = nil // Already uploaded
+			}
+			signingKey := platform.GetSigningKey()
+			if signingKey != nil && signingKey.RemoteID != "" {
+				signingKey = nil
+			}
+			gpgKey := persona.GPGKey
+			if !persona.SignsWithGPG() || platform.GPGKeyID != "" {
+				gpgKey = nil
+			}
+			if activeKey == nil && signingKey == nil && gpgKey == nil {
+				continue
 			}
 
-			// Try to upload key
-			if err := uploadKeyToPlatform(ctx, persona, platform, activeKey, machineName, envTokens); err != nil {
+			label := fmt.Sprintf("%s@%s", platform.Account, platform.Type)
+			lane := platformLane(platform.Type, platform.BaseURL, platform.Account)
+
+			token, err := getTokenForPlatform(platform, envTokens)
+			if err != nil {
 				logger.Warn("Failed to upload key for %s/%s: %v", persona.Name, platform.Type, err)
-				fmt.Printf("⚠️  Could not auto-upload key for %s@%s: %v\n", platform.Account, platform.Type, err)
-				fmt.Printf("   Please upload manually: cat ~/.ssh/%s.pub\n", activeKey.LocalPath)
-			} else {
-				configChanged = true
-				fmt.Printf("✓ Uploaded key to %s@%s\n", platform.Account, platform.Type)
+				fmt.Printf("⚠️  Could not auto-upload key for %s: %v\n", label, err)
+				for _, key := range []*config.KeyConfig{activeKey, signingKey} {
+					if key != nil {
+						fmt.Printf("   Please upload manually: cat %s.pub\n", keyMgr.IdentityFilePath(strings.TrimSuffix(key.LocalPath, ".pub")))
+					}
+				}
+				if gpgKey != nil {
+					fmt.Printf("   Please upload manually: gpg --armor --export %s\n", gpgKey.Fingerprint)
+				}
+				continue
+			}
+
+			if activeKey != nil {
+				settings := cfg.ResolveKeySettings(persona, platform)
+				title, err := sshkey.RenderTitle(settings, sshkey.DefaultTitleTemplate,
+					sshkey.NewNameData(persona, platform, activeKey.Type, machineName, time.Now()), activeKey.ExpiresAt)
+				if err != nil {
+					return err
+				}
+
+				uploads = append(uploads, platformTask{
+					Label: label,
+					Lane:  lane,
+					Run: func(ctx context.Context, out io.Writer) error {
+						if err := uploadKeyWithToken(ctx, keyMgr, platform, activeKey, title, token); err != nil {
+							return err
+						}
+						return j.KeyUploaded(persona.Name, string(platform.Type), platform.Account, activeKey.RemoteID, activeKey.Fingerprint)
+					},
+				})
+			}
+
+			if signingKey != nil {
+				title := signingKeyTitle(platform, signingKey, machineName)
+				uploads = append(uploads, platformTask{
+					Label: label + " (signing key)",
+					Lane:  lane,
+					Run: func(ctx context.Context, out io.Writer) error {
+						if err := uploadSigningKeyWithToken(ctx, keyMgr, platform, signingKey, title, token); err != nil {
+							return err
+						}
+						return j.SigningKeyUploaded(persona.Name, string(platform.Type), platform.Account, signingKey.RemoteID, signingKey.Fingerprint)
+					},
+				})
+			}
+
+			if gpgKey != nil {
+				uploads = append(uploads, platformTask{
+					Label: label + " (GPG key)",
+					Lane:  lane,
+					Run: func(ctx context.Context, out io.Writer) error {
+						if err := uploadGPGKeyWithToken(ctx, platform, gpgKey, token); err != nil {
+							return err
+						}
+						return j.GPGKeyUploaded(persona.Name, string(platform.Type), platform.Account, platform.GPGKeyID, gpgKey.Fingerprint)
+					},
+				})
 			}
 		}
 	}
 
+	failedUploads := 0
+	uploads, progress := startProgress("Uploading keys", uploads)
+	runPlatformTasks(ctx, uploads, applyParallel, func(result platformTaskResult) {
+		if progress != nil {
+			progress.finish(result)
+		}
+		if result.Err != nil {
+			logger.WithFields(logger.Fields{"target": result.Label, "error": result.Err}).Warn("Failed to upload key")
+			failedUploads++
+			if progress == nil {
+				fmt.Printf("❌ Could not upload key to %s: %v\n", result.Label, result.Err)
+			}
+			return
+		}
+		configChanged = true
+		if progress == nil {
+			fmt.Printf("✓ Uploaded key to %s\n", result.Label)
+		}
+	})
+	if progress != nil {
+		progress.stop()
+		progress.printSummary()
+	}
+
+	// A key that is generated but not registered would leave the platform
+	// unusable, so a failed upload fails the whole apply
+	if failedUploads > 0 {
+		return fmt.Errorf("failed to upload %d key(s)", failedUploads)
+	}
+
 	// Save config again if keys were uploaded
 	if configChanged {
 		if err := mgr.Save(cfg); err != nil {
@@ -198,7 +451,7 @@ func runApply(cmd *cobra.Command, args []string) error {
 
 	// Setup git configuration for personas
 	fmt.Println("\n⚙️  Setting up git configuration...")
-	if err := setupGitConfigForPersonas(cfg, &configChanged); err != nil {
+	if err := setupGitConfigForPersonas(cfg, &configChanged, target, gitDirs); err != nil {
 		logger.Warn("Failed to setup git config: %v", err)
 		fmt.Printf("⚠️  Git config setup had issues. You can run 'git-keys setup-git' manually.\n")
 	}
@@ -210,13 +463,89 @@ func runApply(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	fmt.Println("\n✅ Successfully applied configuration!")
+	verify := applyVerify || cfg.Defaults.VerifyApply
+	failures := 0
+	if verify {
+		failures = verifyApply(cfg, target)
+	}
+
+	details := map[string]string{}
+	if applyTarget != "" {
+		details["target"] = applyTarget
+	}
+	if verify {
+		details["verified"] = fmt.Sprintf("%t", failures == 0)
+	}
+	histMgr := history.NewManager("")
+	if err := histMgr.Record(history.Entry{
+		Action:  "apply",
+		Summary: "Applied configuration",
+		Details: details,
+	}); err != nil {
+		logger.Warn("Failed to record history: %v", err)
+	}
+
+	if failures > 0 {
+		fmt.Printf("\n⚠️  Applied configuration, but %d verification check(s) failed.\n", failures)
+		fmt.Println("   The changes are kept; fix the problems above and run 'git-keys doctor'.")
+		return fmt.Errorf("%w: %d check(s) failed", errApplyUnverified, failures)
+	}
+
+	if verify {
+		fmt.Println("\n✅ Successfully applied and verified configuration!")
+	} else {
+		fmt.Println("\n✅ Successfully applied configuration!")
+	}
 	fmt.Println("\nYour SSH keys are ready.")
-	fmt.Printf("\nSSH config: %s\n", cfg.Defaults.SSHConfigPath)
+	fmt.Printf("\nSSH config: %s\n", sshConfigManager(cfg).BlocksPath())
 
 	return nil
 }
 
+// platformTarget selects a persona, or one of its platforms, by
+// persona[/platform[@account]]
+type platformTarget struct {
+	Persona  string
+	Platform config.PlatformType // "" for all of the persona's platforms
+	Account  string              // "" for any account
+}
+
+// parsePlatformTarget parses a target and checks that it selects at least one
+// platform of a non-archived persona
+func parsePlatformTarget(cfg *config.Config, target string) (*platformTarget, error) {
+	personaName, platformPart, _ := strings.Cut(target, "/")
+	platformType, account, _ := strings.Cut(platformPart, "@")
+	t := &platformTarget{Persona: personaName, Platform: config.PlatformType(platformType), Account: account}
+
+	persona := cfg.FindPersona(personaName)
+	if persona == nil {
+		return nil, fmt.Errorf("persona '%s' not found", personaName)
+	}
+	if persona.Archived {
+		return nil, fmt.Errorf("persona '%s' is archived", personaName)
+	}
+	for platformIdx := range persona.Platforms {
+		if t.includes(persona, &persona.Platforms[platformIdx]) {
+			return t, nil
+		}
+	}
+	if platformPart == "" {
+		return nil, fmt.Errorf("persona '%s' has no platforms", personaName)
+	}
+	return nil, fmt.Errorf("persona '%s' has no platform matching '%s'", personaName, platformPart)
+}
+
+// includes reports whether the target selects a persona's platform. A nil
+// target selects every platform.
+func (t *platformTarget) includes(persona *config.Persona, plat *config.Platform) bool {
+	if t == nil {
+		return true
+	}
+	return persona.Name == t.Persona &&
+		(t.Platform == "" || plat.Type == t.Platform) &&
+		(t.Account == "" || strings.EqualFold(plat.Account, t.Account))
+}
+
 // loadTokensFromEnv reads API tokens from .env file in current directory
 func loadTokensFromEnv() map[string]string {
 	tokens := make(map[string]string)
@@ -254,31 +583,44 @@ func loadTokensFromEnv() map[string]string {
 	return tokens
 }
 
-// getTokenForPlatform retrieves token from env map or prompts user
-func getTokenForPlatform(platformType config.PlatformType, account string, envTokens map[string]string) (string, error) {
-	var tokenKey string
-
-	if platformType == config.PlatformGitHub {
-		tokenKey = fmt.Sprintf("GITHUB_API_TOKEN_%s", account)
-	} else if platformType == config.PlatformGitLab {
-		tokenKey = fmt.Sprintf("GITLAB_TOKEN_%s", account)
-	} else {
+// envTokenKey returns the .env variable holding the token for an account
+func envTokenKey(platformType config.PlatformType, account string) (string, error) {
+	switch platformType {
+	case config.PlatformGitHub:
+		return fmt.Sprintf("GITHUB_API_TOKEN_%s", account), nil
+	case config.PlatformGitLab:
+		return fmt.Sprintf("GITLAB_TOKEN_%s", account), nil
+	default:
 		return "", fmt.Errorf("unsupported platform: %s", platformType)
 	}
+}
+
+// getTokenForPlatform retrieves token from env map or keychain, or prompts user
+func getTokenForPlatform(platform *config.Platform, envTokens map[string]string) (string, error) {
+	platformType, account := platform.Type, platform.Account
+	tokenKey, err := envTokenKey(platformType, account)
+	if err != nil {
+		return "", err
+	}
 
 	// Check if token exists in env
 	if token, ok := envTokens[tokenKey]; ok && token != "" {
 		return token, nil
 	}
 
-	// Prompt user for token
-	fmt.Printf("\n🔑 API token for %s@%s not found in .env\n", account, platformType)
-	fmt.Printf("   Expected: %s=<token>\n", tokenKey)
-	fmt.Print("   Enter token now (or press Enter to skip): ")
+	// Fall back to tokens stored with 'git-keys token set'
+	if token, err := getKeychainToken(platform); err == nil && token != "" {
+		return token, nil
+	}
+
+	if nonInteractive {
+		return "", fmt.Errorf("no token for %s@%s (set %s in .env or run 'git-keys token set %s %s')", account, platformType, tokenKey, platformType, account)
+	}
 
-	reader := bufio.NewReader(os.Stdin)
-	token, _ := reader.ReadString('\n')
-	token = strings.TrimSpace(token)
+	// Prompt user for token
+	fmt.Printf("\n🔑 API token for %s@%s not found in .env or keychain\n", account, platformType)
+	fmt.Printf("   Expected: %s=<token> (or run 'git-keys token set %s %s')\n", tokenKey, platformType, account)
+	token := prompt.Secret("   Enter token now (or press Enter to skip)")
 
 	if token == "" {
 		return "", fmt.Errorf("no token provided")
@@ -287,39 +629,29 @@ func getTokenForPlatform(platformType config.PlatformType, account string, envTo
 	return token, nil
 }
 
-// uploadKeyToPlatform uploads SSH key to GitHub/GitLab
-func uploadKeyToPlatform(ctx context.Context, persona *config.Persona, platform *config.Platform, key *config.KeyConfig, machineName string, envTokens map[string]string) error {
-	// Get API token
-	token, err := getTokenForPlatform(platform.Type, platform.Account, envTokens)
-	if err != nil {
-		return err
-	}
-
+// uploadKeyWithToken uploads SSH key to GitHub/GitLab
+func uploadKeyWithToken(ctx context.Context, keyMgr *sshkey.Manager, platform *config.Platform, key *config.KeyConfig, title, token string) error {
 	// Read public key
-	pubKeyPath := filepath.Join(os.Getenv("HOME"), ".ssh", key.LocalPath+".pub")
-	pubKeyData, err := os.ReadFile(pubKeyPath)
+	publicKey, err := keyMgr.GetPublicKey(key.LocalPath)
 	if err != nil {
-		return fmt.Errorf("failed to read public key: %w", err)
+		return err
 	}
-	publicKey := strings.TrimSpace(string(pubKeyData))
 
 	// Create API client
 	var client api.PlatformClient
 	if platform.Type == config.PlatformGitHub {
 		client = api.NewGitHubClient(token)
 	} else if platform.Type == config.PlatformGitLab {
-		baseURL := platform.BaseURL
-		if baseURL == "" {
-			baseURL = "https://gitlab.com"
+		client, err = newGitLabClient(platform, token)
+		if err != nil {
+			return err
 		}
-		client = api.NewGitLabClient(baseURL, token)
 	} else {
 		return fmt.Errorf("unsupported platform: %s", platform.Type)
 	}
 
 	// Upload key
-	title := fmt.Sprintf("%s@%s (git-keys %s)", platform.Account, machineName, time.Now().Format("2006-01-02"))
-	remoteID, err := client.AddKey(ctx, title, publicKey)
+	remoteID, err := client.AddKey(ctx, title, publicKey, key.ExpiresAt)
 	if err != nil {
 		return fmt.Errorf("API error: %w", err)
 	}
@@ -330,9 +662,130 @@ func uploadKeyToPlatform(ctx context.Context, persona *config.Persona, platform
 	return nil
 }
 
-// setupGitConfigForPersonas creates git config files and includeIf entries
-func setupGitConfigForPersonas(cfg *config.Config, configChanged *bool) error {
-	reader := bufio.NewReader(os.Stdin)
+// generatePlatformKey generates a key for a platform using the most specific
+// key settings and returns its config entry. The caller adds it to the platform.
+func generatePlatformKey(cfg *config.Config, keyMgr *sshkey.Manager, persona *config.Persona, platform *config.Platform, machineName string) (*config.KeyConfig, error) {
+	settings := cfg.ResolveKeySettings(persona, platform)
+	nameData := sshkey.NewNameData(persona, platform, settings.Type, machineName, time.Now())
+
+	keyFileName, err := sshkey.RenderFileName(settings.FileNameTemplate, nameData)
+	if err != nil {
+		return nil, err
+	}
+	keyComment, err := sshkey.RenderName(settings.CommentTemplate, sshkey.DefaultCommentTemplate, nameData)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Generating new %s key: %s", settings.Type, keyFileName)
+
+	if err := keyMgr.GenerateKey(settings.Type, keyComment, keyFileName); err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+
+	fingerprint, err := keyMgr.GetFingerprint(keyFileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fingerprint: %w", err)
+	}
+
+	createdAt := time.Now()
+	return &config.KeyConfig{
+		Type:        settings.Type,
+		CreatedAt:   createdAt,
+		ExpiresAt:   settings.ExpiresAt(createdAt),
+		Fingerprint: fingerprint,
+		LocalPath:   keyFileName,
+		Status:      config.KeyStatusActive,
+	}, nil
+}
+
+// adoptAgentKey records the public key of a key held by an external agent.
+// The public key is saved in the keys directory so IdentityFile can select it in the agent.
+func adoptAgentKey(keyMgr *sshkey.Manager, cfg *config.Config, persona *config.Persona, platform *config.Platform, machineName string) (*config.KeyConfig, error) {
+	var publicKey string
+
+	if agentKeyPath := sshkey.ExpandHome(platform.AgentKey); platform.AgentKey != "" && fileExists(agentKeyPath) {
+		data, err := os.ReadFile(agentKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", platform.AgentKey, err)
+		}
+		publicKey = strings.TrimSpace(string(data))
+	} else {
+		exported, err := sshkey.ExportAgentPublicKey(platform.IdentityAgent, platform.AgentKey)
+		if err != nil {
+			return nil, err
+		}
+		publicKey = exported
+	}
+
+	info, err := sshkey.ParsePublicKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	keyType := sshkey.KeyTypeFromPublicKey(info.Type)
+	settings := cfg.ResolveKeySettings(persona, platform)
+	baseName, err := sshkey.RenderFileName(settings.FileNameTemplate,
+		sshkey.NewNameData(persona, platform, keyType, machineName, time.Now()))
+	if err != nil {
+		return nil, err
+	}
+
+	fileName := baseName + ".pub"
+	if err := keyMgr.SavePublicKey(fileName, publicKey); err != nil {
+		return nil, err
+	}
+
+	return &config.KeyConfig{
+		Type:        keyType,
+		CreatedAt:   time.Now(),
+		ExpiresAt:   settings.ExpiresAt(time.Now()),
+		Fingerprint: info.Fingerprint,
+		LocalPath:   fileName,
+		Status:      config.KeyStatusActive,
+		Agent:       true,
+	}, nil
+}
+
+// fillPublicOnlyKey completes a hand-written public-only key entry from its
+// public key file. Only local_path and public_only need to be set in config.
+func fillPublicOnlyKey(keyMgr *sshkey.Manager, key *config.KeyConfig) error {
+	publicKey, err := keyMgr.GetPublicKey(key.LocalPath)
+	if err != nil {
+		return err
+	}
+
+	info, err := sshkey.ParsePublicKey(publicKey)
+	if err != nil {
+		return err
+	}
+
+	key.Type = sshkey.KeyTypeFromPublicKey(info.Type)
+	key.Fingerprint = info.Fingerprint
+	if key.CreatedAt.IsZero() {
+		key.CreatedAt = time.Now()
+		if created, ok := sshkey.ParseCreationHint(info.Comment); ok {
+			key.CreatedAt = created
+		}
+	}
+	if key.ExpiresAt.IsZero() {
+		key.ExpiresAt = key.CreatedAt.AddDate(0, 6, 0) // 6 months default
+	}
+
+	return nil
+}
+
+// fileExists reports whether a regular file exists at path
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+// setupGitConfigForPersonas creates git config files and includeIf entries.
+// Platforms outside target keep their includeIf entry but are not prompted
+// for or rewritten. Platforms without a gitdir take it from gitDirs, and are
+// skipped when there is none in non-interactive mode.
+func setupGitConfigForPersonas(cfg *config.Config, configChanged *bool, target *platformTarget, gitDirs []targetAnswer) error {
 	home, err := os.UserHomeDir()
 	if err != nil {
 		return fmt.Errorf("failed to get home directory: %w", err)
@@ -345,6 +798,9 @@ func setupGitConfigForPersonas(cfg *config.Config, configChanged *bool) error {
 	// Iterate through personas and their platforms
 	for personaIdx := range cfg.Personas {
 		persona := &cfg.Personas[personaIdx]
+		if persona.Archived {
+			continue
+		}
 
 		for platformIdx := range persona.Platforms {
 			platform := &persona.Platforms[platformIdx]
@@ -352,31 +808,40 @@ func setupGitConfigForPersonas(cfg *config.Config, configChanged *bool) error {
 			// Create a unique identifier for this platform
 			platformID := fmt.Sprintf("%s-%s", string(platform.Type), platform.Account)
 
-			// Check if gitdir already configured for this platform
-			if platform.GitDir != "" {
+			// Platforms outside the target keep their entry in the managed
+			// section, which is rewritten as a whole
+			if !target.includes(persona, platform) {
+				configPath := filepath.Join(home, platformGitConfigName(persona.Name, platformID))
+				includeEntries = append(includeEntries, platformIncludeEntries(platform, configPath)...)
+				continue
+			}
+
+			// Check if gitdir or remote_urls are already configured for this platform
+			if platform.HasIncludeConditions() {
 				// Create git config file for this persona-platform combo
-				configName := fmt.Sprintf(".gitconfig-%s-%s", persona.Name, platformID)
+				configName := platformGitConfigName(persona.Name, platformID)
 				configPath := filepath.Join(home, configName)
 
-				if err := createPlatformGitConfigFile(persona, platform, configPath); err != nil {
+				if err := createPlatformGitConfigFile(cfg, persona, platform, configPath); err != nil {
 					logger.Warn("Failed to create git config for %s/%s: %v", persona.Name, platformID, err)
 					continue
 				}
 
-				includeEntry := fmt.Sprintf("[includeIf \"gitdir:%s\"]\n\tpath = %s\n", platform.GitDir, configPath)
-				includeEntries = append(includeEntries, includeEntry)
+				includeEntries = append(includeEntries, platformIncludeEntries(platform, configPath)...)
 				continue
 			}
 
-			// Prompt for gitdir
-			fmt.Printf("\n📁 Directory pattern for %s <%s> - %s/%s\n",
-				persona.Name, persona.Email, platform.Type, platform.Account)
-			fmt.Printf("   This sets where git will use this identity and SSH key\n")
-			fmt.Printf("   Example: ~/Projects/%s/\n", platform.Account)
-			fmt.Print("   Enter directory pattern (or press Enter to skip): ")
+			// Prompt for gitdir unless --gitdir answered it
+			pattern := targetAnswerFor(gitDirs, persona, platform)
+			if pattern == "" && !nonInteractive {
+				fmt.Printf("\n📁 Directory pattern for %s <%s> - %s/%s\n",
+					persona.Name, persona.Email, platform.Type, platform.Account)
+				fmt.Printf("   This sets where git will use this identity and SSH key\n")
+				fmt.Printf("   Example: ~/Projects/%s/\n", platform.Account)
+				fmt.Print("   Enter directory pattern (or press Enter to skip): ")
 
-			pattern, _ := reader.ReadString('\n')
-			pattern = strings.TrimSpace(pattern)
+				pattern, _ = prompt.Line()
+			}
 
 			if pattern == "" {
 				fmt.Printf("   ⚠️  Skipped git config for %s/%s\n", persona.Name, platformID)
@@ -398,18 +863,17 @@ func setupGitConfigForPersonas(cfg *config.Config, configChanged *bool) error {
 			needsGitConfigUpdate = true
 
 			// Create git config file
-			configName := fmt.Sprintf(".gitconfig-%s-%s", persona.Name, platformID)
+			configName := platformGitConfigName(persona.Name, platformID)
 			configPath := filepath.Join(home, configName)
 
-			if err := createPlatformGitConfigFile(persona, platform, configPath); err != nil {
+			if err := createPlatformGitConfigFile(cfg, persona, platform, configPath); err != nil {
 				logger.Warn("Failed to create git config for %s/%s: %v", persona.Name, platformID, err)
 				continue
 			}
 
 			fmt.Printf("   ✓ Created: %s\n", configPath)
 
-			includeEntry := fmt.Sprintf("[includeIf \"gitdir:%s\"]\n\tpath = %s\n", platform.GitDir, configPath)
-			includeEntries = append(includeEntries, includeEntry)
+			includeEntries = append(includeEntries, platformIncludeEntries(platform, configPath)...)
 		}
 	}
 
@@ -437,8 +901,39 @@ func setupGitConfigForPersonas(cfg *config.Config, configChanged *bool) error {
 	return nil
 }
 
+// platformIncludeConditions returns the includeIf conditions that apply a
+// platform's git config file: one per gitdir pattern, a
+// hasconfig:remote.*.url condition for each remote_urls pattern, which
+// applies wherever the repository is cloned, and the include_if conditions
+func platformIncludeConditions(platform *config.Platform) []string {
+	var conditions []string
+	for _, dir := range platform.AllGitDirs() {
+		conditions = append(conditions, "gitdir:"+dir)
+	}
+	for _, pattern := range platform.RemoteURLs {
+		conditions = append(conditions, "hasconfig:remote.*.url:"+pattern)
+	}
+	return append(conditions, platform.IncludeIf...)
+}
+
+// platformIncludeEntries returns the includeIf entries of ~/.gitconfig that
+// include a platform's git config file at configPath
+func platformIncludeEntries(platform *config.Platform, configPath string) []string {
+	var entries []string
+	for _, condition := range platformIncludeConditions(platform) {
+		entries = append(entries, fmt.Sprintf("[includeIf \"%s\"]\n\tpath = %s\n", condition, configPath))
+	}
+	return entries
+}
+
 // createPlatformGitConfigFile creates a git config file for a persona-platform combination
-func createPlatformGitConfigFile(persona *config.Persona, platform *config.Platform, configPath string) error {
+func createPlatformGitConfigFile(cfg *config.Config, persona *config.Persona, platform *config.Platform, configPath string) error {
+	return atomicfile.WriteFile(configPath, []byte(platformGitConfigContent(cfg, persona, platform)), 0644)
+}
+
+// platformGitConfigContent returns the git config file apply writes for a
+// persona-platform combination
+func platformGitConfigContent(cfg *config.Config, persona *config.Persona, platform *config.Platform) string {
 	var content strings.Builder
 
 	content.WriteString(fmt.Sprintf("# Git configuration for %s <%s>\n", persona.Name, persona.Email))
@@ -450,6 +945,8 @@ func createPlatformGitConfigFile(persona *config.Persona, platform *config.Platf
 	content.WriteString(fmt.Sprintf("\tname = %s\n", persona.Name))
 	content.WriteString(fmt.Sprintf("\temail = %s\n\n", persona.Email))
 
+	content.WriteString(signingGitConfigContent(cfg, persona))
+
 	// URL rewrites for this specific platform's SSH host
 	var baseHost string
 
@@ -465,30 +962,82 @@ func createPlatformGitConfigFile(persona *config.Persona, platform *config.Platf
 		}
 	}
 
-	if baseHost != "" {
-		// Use platform-specific SSH host (e.g., github.com.personal)
-		// Sanitize persona name to ensure valid hostname (no spaces)
-		sanitizedPersona := sanitizeHostname(persona.Name)
-		personaHost := fmt.Sprintf("%s.%s", baseHost, sanitizedPersona)
+	if baseHost == "" {
+		return content.String()
+	}
+
+	// Use platform-specific SSH host (e.g., github.com.personal)
+	// Sanitize persona name to ensure valid hostname (no spaces)
+	sanitizedPersona := sanitizeHostname(persona.Name)
+	personaHost := fmt.Sprintf("%s.%s", baseHost, sanitizedPersona)
+
+	switch cfg.GetSwitchStrategy(persona) {
+	case config.SwitchSSHCommand:
+		// Remote URLs stay as they are; ssh gets the key on its command line
+		key := platform.GetActiveKey()
+		if key == nil {
+			content.WriteString("# No active key yet; apply sets core.sshCommand once there is one\n")
+			break
+		}
+		keyMgr := sshkey.NewManager(cfg.Defaults.GetKeysDir())
+		content.WriteString("# SSH command offering only the platform-specific key\n")
+		content.WriteString("[core]\n")
+		content.WriteString(fmt.Sprintf("\tsshCommand = %s\n\n", gitConfigValue(personaSSHCommand(platform, keyMgr.IdentityFilePath(key.LocalPath)))))
+	case config.SwitchHostAlias:
+		content.WriteString(fmt.Sprintf("# Remotes select the key with the SSH host alias: git@%s:<path>\n", personaHost))
+	default:
 		content.WriteString("# SSH host rewrite for platform-specific key\n")
 		content.WriteString(fmt.Sprintf("[url \"git@%s:\"]\n", personaHost))
 		content.WriteString(fmt.Sprintf("\tinsteadOf = git@%s:\n", baseHost))
-		content.WriteString(fmt.Sprintf("\tinsteadOf = https://%s/\n\n", baseHost))
+		content.WriteString(fmt.Sprintf("\tinsteadOf = https://%s/\n", baseHost))
+		content.WriteString(previousAliasRewrites(persona, baseHost))
+		content.WriteString("\n")
 	}
 
-	return os.WriteFile(configPath, []byte(content.String()), 0644)
+	return content.String()
+}
+
+// gitConfigValue quotes a value for a git config file when git would
+// otherwise read it differently: with quotes, backslashes, comment
+// characters or surrounding spaces
+func gitConfigValue(value string) string {
+	if !strings.ContainsAny(value, "\"\\#;") && strings.TrimSpace(value) == value {
+		return value
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
+
+// previousAliasRewrites returns insteadOf lines that send remotes still using
+// the SSH host alias of a persona's previous name to its current alias
+func previousAliasRewrites(persona *config.Persona, baseHost string) string {
+	var content strings.Builder
+	current := sanitizeHostname(persona.Name)
+	for _, name := range persona.PreviousNames {
+		if previous := sanitizeHostname(name); previous != current {
+			content.WriteString(fmt.Sprintf("\tinsteadOf = git@%s.%s:\n", baseHost, previous))
+		}
+	}
+	return content.String()
 }
 
 // addGitConfigIncludes adds or updates includeIf entries in ~/.gitconfig
 func addGitConfigIncludes(gitConfigPath string, entries []string) error {
+	// Hold the lock from the read to the write so a concurrent run's change
+	// is not lost
+	unlock, err := atomicfile.Lock(gitConfigPath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	// Read existing gitconfig
 	var existingContent string
 	if data, err := os.ReadFile(gitConfigPath); err == nil {
 		existingContent = string(data)
 	}
 
-	managedMarker := "# BEGIN git-keys managed conditional includes"
-	endMarker := "# END git-keys managed conditional includes"
+	managedMarker := gitConfigManagedStart()
+	endMarker := gitConfigManagedEnd()
 
 	var newContent string
 
@@ -515,15 +1064,12 @@ func addGitConfigIncludes(gitConfigPath string, entries []string) error {
 			endMarker + "\n"
 	}
 
-	return os.WriteFile(gitConfigPath, []byte(newContent), 0644)
+	return atomicfile.WriteFile(gitConfigPath, []byte(newContent), 0644)
 }
 
-func updateSSHConfig(sshMgr *sshconfig.Manager, persona *config.Persona, platform *config.Platform, key *config.KeyConfig) error {
-	logger.Info("Updating SSH config for %s/%s", platform.Type, platform.Account)
-
-	blockID := sshconfig.GetManagedBlockID(persona.Name, platform.Type, platform.Account)
-
-	// Determine hostname based on platform
+// sshHostAlias returns the SSH Host alias apply writes for a persona's
+// platform (e.g. github.com.personal) and the real hostname behind it
+func sshHostAlias(persona *config.Persona, platform *config.Platform) (string, string) {
 	hostname := "github.com"
 	if platform.Type == config.PlatformGitLab {
 		if platform.BaseURL != "" && platform.BaseURL != "https://gitlab.com" {
@@ -534,24 +1080,148 @@ func updateSSHConfig(sshMgr *sshconfig.Manager, persona *config.Persona, platfor
 		}
 	}
 
-	// Create SSH config entry
 	// Sanitize persona name to ensure valid hostname (no spaces)
-	sanitizedPersona := sanitizeHostname(persona.Name)
+	return fmt.Sprintf("%s.%s", hostname, sanitizeHostname(persona.Name)), hostname
+}
+
+func updateSSHConfig(cfg *config.Config, sshMgr *sshconfig.Manager, keyMgr *sshkey.Manager, persona *config.Persona, platform *config.Platform, key *config.KeyConfig) error {
+	logger.Info("Updating SSH config for %s/%s", platform.Type, platform.Account)
+
+	blockID := sshconfig.GetManagedBlockID(persona.Name, platform.Type, platform.Account)
+	entries := platformSSHEntries(cfg, keyMgr, persona, platform, key)
+
+	// A persona or account rename changes the block ID; move the old block
+	// instead of leaving it behind next to a new one
+	oldID, err := findRenamedBlock(cfg, sshMgr, blockID, entries[0])
+	if err != nil {
+		logger.Warn("Failed to check SSH config for renamed blocks: %v", err)
+	}
+	if oldID != "" {
+		if err := sshMgr.RenameEntry(oldID, blockID, entries); err != nil {
+			return fmt.Errorf("failed to rename SSH config block %s: %w", oldID, err)
+		}
+		fmt.Printf("✓ Renamed SSH config block %s → %s\n", oldID, blockID)
+		return nil
+	}
+
+	if err := sshMgr.AddOrUpdateEntry(blockID, entries); err != nil {
+		return fmt.Errorf("failed to update SSH config: %w", err)
+	}
+
+	return nil
+}
+
+// sshConfigManager returns the manager of the SSH config, writing the
+// managed blocks to the ssh_include file when one is set
+func sshConfigManager(cfg *config.Config) *sshconfig.Manager {
+	mgr := sshconfig.NewManager(cfg.Defaults.SSHConfigPath)
+	mgr.UseIncludeFile(cfg.Defaults.SSHInclude)
+	return mgr
+}
+
+// platformSSHEntries returns the entries of a platform's managed SSH config
+// block: the host alias with any ssh_host_patterns, a Match entry for each
+// ssh_match, and with ssh_over_https the port 443 fallback
+func platformSSHEntries(cfg *config.Config, keyMgr *sshkey.Manager, persona *config.Persona, platform *config.Platform, key *config.KeyConfig) []sshconfig.Entry {
+	alias, hostname := sshHostAlias(persona, platform)
+
+	// Create SSH config entry
 	entries := []sshconfig.Entry{
 		{
-			Host:         fmt.Sprintf("%s.%s", hostname, sanitizedPersona),
+			Host:         alias,
+			Patterns:     platform.SSHHostPatterns,
 			HostName:     hostname,
 			User:         "git",
-			IdentityFile: fmt.Sprintf("~/.ssh/%s", key.LocalPath),
-			Extra: map[string]string{
-				"IdentitiesOnly": "yes",
-			},
+			IdentityFile: keyMgr.IdentityFilePath(key.LocalPath),
+			Extra:        platformSSHOptions(cfg, platform),
 		},
 	}
+	entries[0].Extra["IdentitiesOnly"] = "yes"
 
-	if err := sshMgr.AddOrUpdateEntry(blockID, entries); err != nil {
-		return fmt.Errorf("failed to update SSH config: %w", err)
+	// Keys held by an external agent are offered through its socket
+	if platform.UsesExternalAgent() {
+		agent := platform.IdentityAgent
+		if strings.Contains(agent, " ") {
+			agent = fmt.Sprintf("%q", agent)
+		}
+		entries[0].Extra["IdentityAgent"] = agent
 	}
 
-	return nil
+	// For networks blocking port 22: the same host key, reached on port 443
+	// of another host, so known_hosts needs no new entry
+	if httpsHost := config.SSHOverHTTPSHost(hostname); httpsHost != "" && cfg.UsesSSHOverHTTPS(platform) {
+		fallback := entries[0]
+		fallback.Host = sshOverHTTPSAlias(alias)
+		fallback.Patterns = nil
+		fallback.HostName = httpsHost
+		fallback.Extra = make(map[string]string, len(entries[0].Extra)+2)
+		for option, value := range entries[0].Extra {
+			fallback.Extra[option] = value
+		}
+		fallback.Extra["Port"] = "443"
+		fallback.Extra["HostKeyAlias"] = hostname
+		entries = append(entries, fallback)
+	}
+
+	// Match blocks offer the same key to connections the criteria select,
+	// e.g. plain github.com URLs under a work directory
+	for _, criteria := range platform.SSHMatch {
+		match := sshconfig.Entry{
+			Match:        criteria,
+			IdentityFile: entries[0].IdentityFile,
+			Extra:        make(map[string]string, len(entries[0].Extra)),
+		}
+		for option, value := range entries[0].Extra {
+			match.Extra[option] = value
+		}
+		entries = append(entries, match)
+	}
+	return entries
+}
+
+// sshOverHTTPSAlias returns the host alias of the port 443 fallback of
+// alias, e.g. github.com.work-443
+func sshOverHTTPSAlias(alias string) string {
+	return alias + "-443"
+}
+
+// findRenamedBlock returns the ID of an orphaned managed block that belongs
+// to the platform under an old name: its block ID matches no configured
+// persona/platform, and it uses the platform's key or host alias. Returns ""
+// when blockID already exists or nothing matches.
+func findRenamedBlock(cfg *config.Config, sshMgr *sshconfig.Manager, blockID string, entry sshconfig.Entry) (string, error) {
+	blocks, err := sshMgr.ManagedBlocks()
+	if err != nil {
+		return "", err
+	}
+
+	known := make(map[string]bool)
+	for _, persona := range cfg.Personas {
+		for _, plat := range persona.Platforms {
+			known[sshconfig.GetManagedBlockID(persona.Name, plat.Type, plat.Account)] = true
+		}
+	}
+
+	var candidates []string
+	for _, block := range blocks {
+		if block.ID == blockID {
+			return "", nil
+		}
+		// Blocks written by import and rotate are not per-persona blocks
+		if known[block.ID] || strings.HasPrefix(block.ID, "git-keys-") {
+			continue
+		}
+		if contains(block.IdentityFiles, entry.IdentityFile) || contains(block.Hosts, entry.Host) {
+			candidates = append(candidates, block.ID)
+		}
+	}
+
+	if len(candidates) > 1 {
+		logger.Warn("Several old SSH config blocks match %s (%s); leaving them in place", blockID, strings.Join(candidates, ", "))
+		return "", nil
+	}
+	if len(candidates) == 1 {
+		return candidates[0], nil
+	}
+	return "", nil
 }
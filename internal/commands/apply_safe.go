@@ -0,0 +1,236 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kunlu/git-keys/internal/config"
+	"github.com/kunlu/git-keys/internal/profile"
+	"github.com/kunlu/git-keys/internal/prompt"
+	"github.com/kunlu/git-keys/internal/sshconfig"
+	"github.com/kunlu/git-keys/internal/sshkey"
+)
+
+// gitConfigManagedFileMarker identifies per-platform git config files written by git-keys
+const gitConfigManagedFileMarker = "# Managed by git-keys"
+
+// gitConfigManagedStart returns the line that starts the managed section of
+// ~/.gitconfig. Each profile manages a section of its own.
+func gitConfigManagedStart() string {
+	return "# BEGIN git-keys " + profile.Tag() + "managed conditional includes"
+}
+
+// gitConfigManagedEnd returns the line that ends the managed section of
+// ~/.gitconfig
+func gitConfigManagedEnd() string {
+	return "# END git-keys " + profile.Tag() + "managed conditional includes"
+}
+
+// platformGitConfigName returns the name of a platform's git config file in
+// the home directory, e.g. .gitconfig-work-github-alice. Files of a profile
+// other than the default start with the profile: .gitconfig-acme.work-github-alice.
+func platformGitConfigName(personaName, platformID string) string {
+	if profile.IsDefault() {
+		return fmt.Sprintf(".gitconfig-%s-%s", personaName, platformID)
+	}
+	return fmt.Sprintf(".gitconfig-%s.%s-%s", profile.Name(), personaName, platformID)
+}
+
+// findApplyConflicts checks everything apply writes (SSH config, ~/.gitconfig
+// and per-platform git config files) for content outside the regions git-keys
+// manages that the write would change
+func findApplyConflicts(cfg *config.Config, sshMgr *sshconfig.Manager) ([]sshconfig.Conflict, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get home directory: %w", err)
+	}
+
+	var hosts []string
+	gitDirs := make(map[string]bool)
+	var platformConfigs []string
+
+	for personaIdx := range cfg.Personas {
+		persona := &cfg.Personas[personaIdx]
+		if persona.Archived {
+			continue
+		}
+		for platformIdx := range persona.Platforms {
+			platform := &persona.Platforms[platformIdx]
+
+			// Public-only keys get no SSH config entry
+			if key := platform.GetActiveKey(); key == nil || !key.PublicOnly {
+				alias, _ := sshHostAlias(persona, platform)
+				hosts = append(hosts, alias)
+			}
+
+			for _, gitdir := range platform.AllGitDirs() {
+				gitDirs[gitdir] = true
+			}
+			if platform.HasIncludeConditions() {
+				platformID := fmt.Sprintf("%s-%s", string(platform.Type), platform.Account)
+				platformConfigs = append(platformConfigs, filepath.Join(home, platformGitConfigName(persona.Name, platformID)))
+			}
+		}
+	}
+
+	conflicts, err := sshMgr.FindConflicts(hosts)
+	if err != nil {
+		return nil, err
+	}
+
+	gitConflicts, err := findGitConfigConflicts(filepath.Join(home, ".gitconfig"), gitDirs)
+	if err != nil {
+		return nil, err
+	}
+	conflicts = append(conflicts, gitConflicts...)
+
+	for _, path := range platformConfigs {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue // Missing files are created
+		}
+		if !strings.Contains(string(data), gitConfigManagedFileMarker) {
+			conflicts = append(conflicts, sshconfig.Conflict{
+				Path:   path,
+				Line:   1,
+				Text:   firstLineOf(string(data)),
+				Reason: "file exists but was not written by git-keys; apply would overwrite it",
+			})
+		}
+	}
+
+	return conflicts, nil
+}
+
+// findSSHHostConflicts reports Host sections outside the managed blocks that
+// overlap the hosts apply writes for each persona platform: wildcards
+// overriding the IdentityFile or IdentitiesOnly it sets, entries declaring
+// an alias again, and entries for the real host offering another key
+func findSSHHostConflicts(cfg *config.Config, sshMgr *sshconfig.Manager, keyMgr *sshkey.Manager) ([]sshconfig.Conflict, error) {
+	var expected []sshconfig.ExpectedHost
+	for personaIdx := range cfg.Personas {
+		persona := &cfg.Personas[personaIdx]
+		if persona.Archived {
+			continue
+		}
+		for platformIdx := range persona.Platforms {
+			platform := &persona.Platforms[platformIdx]
+			key := platform.GetActiveKey()
+			if key == nil || key.PublicOnly {
+				continue
+			}
+			alias, hostname := sshHostAlias(persona, platform)
+			expected = append(expected, sshconfig.ExpectedHost{
+				Alias:        alias,
+				HostName:     hostname,
+				IdentityFile: keyMgr.IdentityFilePath(key.LocalPath),
+			})
+		}
+	}
+
+	return sshMgr.FindHostConflicts(expected)
+}
+
+// findGitConfigConflicts reports unbalanced managed markers in ~/.gitconfig and
+// unmanaged includeIf sections for gitdirs that apply would also configure
+func findGitConfigConflicts(path string, gitDirs map[string]bool) ([]sshconfig.Conflict, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var conflicts []sshconfig.Conflict
+	add := func(line int, text, reason string) {
+		conflicts = append(conflicts, sshconfig.Conflict{Path: path, Line: line, Text: text, Reason: reason})
+	}
+
+	startLine := 0
+	for i, line := range strings.Split(string(data), "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == gitConfigManagedStart():
+			if startLine > 0 {
+				add(i+1, trimmed, "managed section starts twice")
+			}
+			startLine = i + 1
+			continue
+		case trimmed == gitConfigManagedEnd():
+			if startLine == 0 {
+				add(i+1, trimmed, "end marker before the begin marker; apply would replace the whole file")
+			}
+			startLine = 0
+			continue
+		}
+
+		if startLine > 0 || !strings.HasPrefix(trimmed, `[includeIf "gitdir:`) {
+			continue
+		}
+
+		gitDir := strings.TrimSuffix(strings.TrimPrefix(trimmed, `[includeIf "gitdir:`), `"]`)
+		if gitDirs[gitDir] {
+			add(i+1, trimmed, "unmanaged include for a gitdir git-keys configures")
+		}
+	}
+
+	if startLine > 0 {
+		add(startLine, gitConfigManagedStart(), "managed section has no end marker; apply would replace the whole file")
+	}
+
+	return conflicts, nil
+}
+
+func firstLineOf(s string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	return line
+}
+
+// resolveSSHHostConflicts lists the Host sections that conflict with the
+// managed hosts and offers to comment them out or move them below the
+// managed blocks. Non-interactive runs only list them.
+func resolveSSHHostConflicts(cfg *config.Config, sshMgr *sshconfig.Manager, keyMgr *sshkey.Manager) error {
+	conflicts, err := findSSHHostConflicts(cfg, sshMgr, keyMgr)
+	if err != nil || len(conflicts) == 0 {
+		return err
+	}
+
+	fmt.Println("\n⚠️  Host sections outside git-keys conflict with managed hosts:")
+	var lines []int
+	seen := make(map[int]bool)
+	for _, c := range conflicts {
+		printWrapped("   • ", c.String())
+		if c.Line > 0 && c.Path == sshMgr.ConfigPath() && !seen[c.Line] {
+			seen[c.Line] = true
+			lines = append(lines, c.Line)
+		}
+	}
+
+	choice := -1
+	if len(lines) > 0 && !applyYes && !nonInteractive {
+		choice = prompt.Select("How should git-keys resolve them?", []string{
+			"Comment them out",
+			"Move them below the managed blocks (fixes overrides, not entries for the same host)",
+			"Leave them",
+		}, 2)
+	}
+	switch choice {
+	case 0:
+		if err := sshMgr.CommentOutSections(lines); err != nil {
+			return err
+		}
+		fmt.Printf("   ✓ Commented out %d Host section(s) in %s\n", len(lines), sshMgr.ConfigPath())
+	case 1:
+		if err := sshMgr.MoveSectionsToEnd(lines); err != nil {
+			return err
+		}
+		fmt.Printf("   ✓ Moved %d Host section(s) to the end of %s\n", len(lines), sshMgr.ConfigPath())
+	default:
+		fmt.Println("   Comment them out, move them below the managed blocks, or narrow their Host patterns.")
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/kunlu/git-keys/internal/config"
+)
+
+// errApplyUnverified is returned when apply made every change but the setup
+// does not pass verification. The changes are kept, not rolled back.
+var errApplyUnverified = errors.New("apply verification failed")
+
+// verifyApply runs the connection tests ('ssh -T' on each SSH host alias) and
+// the git identity checks (includeIf applies the persona's email) on the
+// platforms apply worked on, and returns the number of failures
+func verifyApply(cfg *config.Config, target *platformTarget) int {
+	fmt.Println("\n🧪 Verifying setup...")
+
+	includes, _ := exec.Command("git", "config", "--global", "--get-regexp", `^includeif\.`).Output()
+	tested := make(map[string]bool)
+	failures := 0
+
+	for personaIdx := range cfg.Personas {
+		persona := &cfg.Personas[personaIdx]
+		if persona.Archived {
+			continue
+		}
+		for platformIdx := range persona.Platforms {
+			plat := &persona.Platforms[platformIdx]
+			key := plat.GetActiveKey()
+			if key == nil || !target.includes(persona, plat) {
+				continue
+			}
+			subject := fmt.Sprintf("%s/%s@%s", persona.Name, plat.Type, plat.Account)
+
+			// Public-only keys have no SSH config entry to connect with
+			alias, _ := sshHostAlias(persona, plat)
+			if !key.PublicOnly && !tested[alias] {
+				tested[alias] = true
+				user, output := sshAuthenticatedUser(alias)
+				switch {
+				case user == "":
+					printWrapped("❌ ", fmt.Sprintf("%s: ssh -T git@%s fails: %s (fix: git-keys fix %s)", subject, alias, firstLineOf(output), alias))
+					failures++
+				case !strings.EqualFold(user, plat.Account):
+					printWrapped("❌ ", fmt.Sprintf("%s: git@%s authenticates as %s, not %s (fix: git-keys fix %s)", subject, alias, user, plat.Account, alias))
+					failures++
+				default:
+					fmt.Printf("✓ %s: git@%s authenticates as %s\n", subject, alias, user)
+				}
+			}
+
+			for _, condition := range platformIncludeConditions(plat) {
+				if problem := includeIfProblem(string(includes), persona, condition); problem != "" {
+					printWrapped("❌ ", fmt.Sprintf("%s: %s (fix: git-keys setup-git)", subject, problem))
+					failures++
+				} else {
+					fmt.Printf("✓ %s: %s uses %s\n", subject, condition, persona.Email)
+				}
+			}
+		}
+	}
+
+	return failures
+}
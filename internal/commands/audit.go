@@ -0,0 +1,478 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kunlu/git-keys/internal/api"
+	"github.com/kunlu/git-keys/internal/config"
+	"github.com/kunlu/git-keys/internal/history"
+	"github.com/kunlu/git-keys/internal/logger"
+	"github.com/kunlu/git-keys/internal/prompt"
+	"github.com/kunlu/git-keys/internal/sshkey"
+	"github.com/spf13/cobra"
+)
+
+var (
+	auditPruneRemote bool
+	auditUnusedDays  int
+	auditOrg         string
+	auditOrgPlatform string
+	auditOrgAccount  string
+	auditOrgMaxAge   string
+)
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Find unknown, stale and missing keys on the platforms",
+	Long: `Compare the keys registered on each GitHub/GitLab account with the
+configuration and the key files on this machine.
+
+Flags:
+  - unknown remote keys: registered on an account but not in the
+    configuration (a stale laptop, a key added by hand, or a compromise)
+  - stale remote keys: still registered although the configuration has them
+    revoked or expired, or their persona is archived
+  - missing remote keys: active keys in the configuration that are not
+    registered on their account
+  - untracked local keys: key pairs in ~/.ssh or the keys directory that no
+    persona uses
+  - unused keys: configured GitLab keys that have not authenticated for
+    --unused-days (defaults.unused_key_days, 90 by default), judged by the
+    last_used_at GitLab reports; candidates for 'git-keys revoke'
+
+With --prune-remote, each unknown or stale remote key is shown and deleted
+after you confirm it. A key is only deleted if the platform still holds the
+same public key when it is removed.
+
+With --org, the SSH keys of every member of a GitHub organization (or GitLab
+group, with --platform gitlab) are audited instead, using the token of a
+configured account that owns or administers it. Keys older than --max-age
+(the default key lifetime when not given), weak keys (DSA, or RSA shorter
+than 3072 bits) and GitLab keys without an expiry are reported, along with
+the organization's verified domains.
+
+Examples:
+  git-keys audit
+  git-keys audit --prune-remote
+  git-keys audit --org acme
+  git-keys audit --org platform/infra --platform gitlab --max-age 365d
+`,
+	Annotations: map[string]string{structuredOutputAnnotation: structuredProgress},
+	RunE:        runAudit,
+}
+
+func init() {
+	auditCmd.Flags().BoolVar(&auditPruneRemote, "prune-remote", false, "Offer to delete unknown and stale remote keys")
+	auditCmd.Flags().IntVar(&auditUnusedDays, "unused-days", 0, "Flag GitLab keys unused for this many days (default defaults.unused_key_days, or 90)")
+	auditCmd.Flags().StringVar(&auditOrg, "org", "", "Audit the members' keys of a GitHub organization or GitLab group")
+	auditCmd.Flags().StringVar(&auditOrgPlatform, "platform", "github", "Platform of --org: github or gitlab")
+	auditCmd.RegisterFlagCompletionFunc("platform", completePlatformTypes)
+	auditCmd.Flags().StringVar(&auditOrgAccount, "account", "", "Configured account whose token audits --org, when there are several")
+	auditCmd.Flags().StringVar(&auditOrgMaxAge, "max-age", "", "With --org, report keys older than this, e.g. 365d (default the key lifetime)")
+	rootCmd.AddCommand(auditCmd)
+}
+
+// auditKnownKey is a configured key and the persona that holds it
+type auditKnownKey struct {
+	Persona *config.Persona
+	Key     *config.KeyConfig
+}
+
+// auditAccount is a platform account and the configured keys it should hold.
+// Accounts shared by several personas are audited once.
+type auditAccount struct {
+	Label      string // type@account
+	Platform   *config.Platform
+	Expiration time.Duration            // Key lifetime, to estimate expiry from old titles
+	Known      map[string]auditKnownKey // By fingerprint without the SHA256: prefix
+}
+
+// auditRemoteKey is a remote key that the configuration does not account for
+type auditRemoteKey struct {
+	Account     *auditAccount
+	Client      api.PlatformClient
+	Remote      api.SSHKey
+	Fingerprint string
+	Reason      string
+}
+
+func runAudit(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	if auditPruneRemote && nonInteractive {
+		return errNonInteractive("--prune-remote", "audit without it and delete stale keys on the platform")
+	}
+
+	// Load configuration
+	configPath := cfgFile
+	if configPath == "" {
+		configPath = config.GetDefaultConfigPath()
+	}
+
+	mgr := config.NewManager(configPath)
+	if !mgr.Exists() {
+		return fmt.Errorf("configuration file not found at %s\nRun 'git-keys init' first", configPath)
+	}
+
+	cfg, err := mgr.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	if auditOrg != "" {
+		if structuredOutput() {
+			return fmt.Errorf("--output %s is not supported with --org", outputFormat)
+		}
+		return runOrgAudit(ctx, cfg)
+	}
+
+	printHeader("\n🕵️  Key Audit")
+	fmt.Println()
+
+	accounts, known := auditAccounts(cfg)
+
+	var flagged []auditRemoteKey
+	missing := 0
+	var unused []string
+	var failures []string
+
+	unusedDays := auditUnusedDays
+	if unusedDays <= 0 {
+		unusedDays = unusedKeyDays(cfg)
+	}
+	report := newAuditReport(unusedDays)
+
+	for _, account := range accounts {
+		fmt.Println(account.Label)
+
+		client, err := newPlatformClient(account.Platform)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", account.Label, err))
+			fmt.Printf("  ⚠️  Not checked: %v\n\n", err)
+			continue
+		}
+
+		// An audit is judged against a fresh listing, never the cache
+		remoteKeys, _, err := api.ListKeysCached(ctx, client, true)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: failed to list remote keys: %v", account.Label, err))
+			fmt.Printf("  ⚠️  Not checked: %v\n\n", err)
+			continue
+		}
+
+		registered := make(map[string]bool)
+		problems := 0
+		for _, remote := range remoteKeys {
+			fingerprint := ""
+			if info, err := sshkey.ParsePublicKey(remote.Key); err == nil {
+				fingerprint = info.Fingerprint
+			}
+			registered[strings.TrimPrefix(fingerprint, "SHA256:")] = true
+
+			// Keys this configuration does not know about may still carry
+			// the machine and expiry in their title
+			meta, hasMeta := sshkey.ParseTitleMeta(remote.Title)
+			expires, estimated := meta.Expires, false
+			if expires.IsZero() && !meta.Created.IsZero() {
+				expires, estimated = meta.Created.Add(account.Expiration), true
+			}
+
+			// Configured keys that sit unused are prune candidates
+			if k, ok := account.Known[strings.TrimPrefix(fingerprint, "SHA256:")]; ok && k.Key.InUse() && !k.Persona.Archived &&
+				account.Platform.Type == config.PlatformGitLab {
+				if usage := remoteKeyUsage(remote); usage.Unused(unusedDays) {
+					problems++
+					unused = append(unused, k.Key.Fingerprint)
+					report.Unused = append(report.Unused, auditReportKey{Account: account.Label, Persona: k.Persona.Name, Fingerprint: k.Key.Fingerprint, Reason: usage.String()})
+					fmt.Printf("  💤 Unused key: %s (persona '%s', %s)\n", k.Key.Fingerprint, k.Persona.Name, usage)
+				}
+			}
+
+			reason, stale := "", true
+			if k, ok := account.Known[strings.TrimPrefix(fingerprint, "SHA256:")]; !ok {
+				reason, stale = "not in the configuration", false
+				switch {
+				case hasMeta && meta.Machine == cfg.Machine.Name:
+					reason += "; uploaded from this machine by an older setup"
+				case hasMeta && meta.Machine != "":
+					reason += fmt.Sprintf("; from machine '%s'", meta.Machine)
+				}
+				if !expires.IsZero() && time.Now().After(expires) {
+					reason += "; expired " + expires.Format("2006-01-02")
+					if estimated {
+						reason += " (estimated from the upload date)"
+					}
+					stale = true
+				}
+			} else if k.Persona.Archived {
+				reason = fmt.Sprintf("persona '%s' is archived", k.Persona.Name)
+			} else if k.Key.Status == config.KeyStatusRevoked || k.Key.Status == config.KeyStatusExpired {
+				reason = fmt.Sprintf("%s in the configuration (persona '%s')", k.Key.Status, k.Persona.Name)
+			}
+			if reason == "" {
+				continue
+			}
+
+			problems++
+			flagged = append(flagged, auditRemoteKey{Account: account, Client: client, Remote: remote, Fingerprint: fingerprint, Reason: reason})
+			label := "Unknown"
+			if stale {
+				label = "Stale"
+			}
+			report.Remote = append(report.Remote, auditReportKey{
+				Account: account.Label, Fingerprint: fingerprint, Title: remote.Title, RemoteID: remote.ID,
+				Stale: stale, Reason: reason,
+			})
+			fmt.Printf("  ⚠️  %s remote key: %q (ID %s)\n", label, remote.Title, remote.ID)
+			fmt.Printf("      %s", fingerprint)
+			if remote.CreatedAt != "" {
+				fmt.Printf(", created %s", remote.CreatedAt)
+			}
+			if !expires.IsZero() && !stale {
+				fmt.Printf(", expires %s", expires.Format("2006-01-02"))
+				if estimated {
+					fmt.Print(" (estimated)")
+				}
+			}
+			fmt.Println()
+			printWrapped("      ", reason)
+		}
+
+		fingerprints := make([]string, 0, len(account.Known))
+		for fingerprint := range account.Known {
+			fingerprints = append(fingerprints, fingerprint)
+		}
+		sort.Strings(fingerprints)
+		for _, fingerprint := range fingerprints {
+			k := account.Known[fingerprint]
+			if k.Persona.Archived || !k.Key.InUse() || registered[fingerprint] {
+				continue
+			}
+			problems++
+			missing++
+			report.Missing = append(report.Missing, auditReportKey{Account: account.Label, Persona: k.Persona.Name, Fingerprint: k.Key.Fingerprint, LocalPath: k.Key.LocalPath})
+			fmt.Printf("  ❌ Missing remote key: %s (persona '%s', %s)\n", k.Key.Fingerprint, k.Persona.Name, k.Key.LocalPath)
+		}
+
+		if problems == 0 {
+			fmt.Printf("  ✓ %d remote key(s), all accounted for\n", len(remoteKeys))
+		}
+		fmt.Println()
+	}
+
+	untracked := auditLocalKeys(cfg, known)
+	for _, key := range untracked {
+		report.Untracked = append(report.Untracked, auditReportKey{Fingerprint: key.Fingerprint, LocalPath: key.Path, Type: key.Type})
+	}
+	report.Failures = append(report.Failures, failures...)
+	if structuredOutput() {
+		if err := printStructured("AuditReport", report); err != nil {
+			return err
+		}
+	}
+	if len(untracked) > 0 {
+		fmt.Println("Local key files not used by any persona")
+		for _, key := range untracked {
+			fmt.Printf("  • %s (%s, %s)\n", key.Path, key.Type, key.Fingerprint)
+		}
+		fmt.Println()
+	}
+
+	// Summary
+	printHeader("📋 Audit Summary")
+	fmt.Printf("  Unknown or stale remote keys: %d\n", len(flagged))
+	fmt.Printf("  Missing remote keys:          %d\n", missing)
+	fmt.Printf("  Untracked local keys:         %d\n", len(untracked))
+	fmt.Printf("  Unused keys:                  %d (not used for %d days)\n", len(unused), unusedDays)
+	if len(failures) > 0 {
+		fmt.Printf("\n⚠️  %d account(s) could not be checked:\n", len(failures))
+		for _, f := range failures {
+			printWrapped("   • ", f)
+		}
+	}
+	fmt.Println()
+
+	if missing > 0 {
+		fmt.Println("💡 Run 'git-keys apply' to upload missing keys.")
+	}
+	if len(untracked) > 0 {
+		fmt.Println("💡 Run 'git-keys import' to manage untracked keys, or remove them if unused.")
+	}
+	for _, fingerprint := range unused {
+		fmt.Printf("💡 Unused for %d days; if no longer needed: git-keys revoke --fingerprint %s\n", unusedDays, fingerprint)
+	}
+
+	if len(flagged) == 0 {
+		return nil
+	}
+	if !auditPruneRemote {
+		fmt.Println("💡 Run 'git-keys audit --prune-remote' to review and delete unknown and stale remote keys.")
+		return nil
+	}
+
+	return pruneRemoteKeys(ctx, flagged)
+}
+
+// auditAccounts groups the configured keys by platform account and returns
+// the fingerprints of every configured key
+func auditAccounts(cfg *config.Config) ([]*auditAccount, map[string]bool) {
+	var accounts []*auditAccount
+	byLane := make(map[string]*auditAccount)
+	known := make(map[string]bool)
+
+	for personaIdx := range cfg.Personas {
+		persona := &cfg.Personas[personaIdx]
+		for platformIdx := range persona.Platforms {
+			plat := &persona.Platforms[platformIdx]
+
+			lane := platformLane(plat.Type, plat.BaseURL, plat.Account)
+			account, ok := byLane[lane]
+			if !ok {
+				account = &auditAccount{
+					Label:      fmt.Sprintf("%s@%s", plat.Account, plat.Type),
+					Platform:   plat,
+					Expiration: cfg.ResolveKeySettings(persona, plat).Expiration,
+					Known:      make(map[string]auditKnownKey),
+				}
+				byLane[lane] = account
+				accounts = append(accounts, account)
+			}
+
+			// Keys of other machines sharing the configuration are known
+			// remotely, but their key pairs are not on this machine
+			for i, keys := range [][]config.KeyConfig{plat.Keys, plat.OtherKeys} {
+				for keyIdx := range keys {
+					key := &keys[keyIdx]
+					if key.Fingerprint == "" {
+						continue
+					}
+					fingerprint := strings.TrimPrefix(key.Fingerprint, "SHA256:")
+					if i == 0 {
+						known[fingerprint] = true
+					}
+					// A key in use wins over an old entry with the same fingerprint
+					if existing, ok := account.Known[fingerprint]; ok && existing.Key.InUse() && !existing.Persona.Archived {
+						continue
+					}
+					account.Known[fingerprint] = auditKnownKey{Persona: persona, Key: key}
+				}
+			}
+		}
+	}
+
+	return accounts, known
+}
+
+// auditLocalKeys returns the key pairs in ~/.ssh and the keys directory that
+// no configured key uses
+func auditLocalKeys(cfg *config.Config, known map[string]bool) []DiscoveredKey {
+	dirs := []string{filepath.Join(os.Getenv("HOME"), ".ssh")}
+	if keysDir := cfg.Defaults.GetKeysDir(); keysDir != dirs[0] {
+		dirs = append(dirs, keysDir)
+	}
+
+	var untracked []DiscoveredKey
+	for _, dir := range dirs {
+		keys, err := scanSSHKeys(dir)
+		if err != nil {
+			logger.Debug("Skipping %s: %v", dir, err)
+			continue
+		}
+		for _, key := range keys {
+			if !known[strings.TrimPrefix(key.Fingerprint, "SHA256:")] {
+				untracked = append(untracked, key)
+			}
+		}
+	}
+	return untracked
+}
+
+// pruneRemoteKeys asks about each flagged remote key and deletes the confirmed
+// ones after checking the platform still holds the same public key
+func pruneRemoteKeys(ctx context.Context, flagged []auditRemoteKey) error {
+	printHeader("\n🧹 Prune Remote Keys")
+	fmt.Println("Unknown keys may belong to another of your machines; delete only keys you recognize as stale.")
+	fmt.Println()
+
+	details := make(map[string]string)
+	for _, k := range flagged {
+		fmt.Printf("%s: %q (%s)\n", k.Account.Label, k.Remote.Title, k.Reason)
+		check := checkRemoteKey(ctx, k.Client, k.Remote.ID, k.Fingerprint)
+		check.Print(os.Stdout, "  ")
+		if check.Problem() != "" {
+			fmt.Println()
+			continue
+		}
+
+		if !prompt.Confirm("  Delete this key?") {
+			fmt.Println("  Kept")
+			fmt.Println()
+			continue
+		}
+
+		if err := deleteCheckedKey(ctx, k.Client, check); err != nil {
+			logger.Warn("Failed to delete remote key %s: %v", k.Remote.ID, err)
+			fmt.Printf("  ❌ %v\n\n", err)
+			continue
+		}
+		fmt.Println("  ✓ Deleted")
+		fmt.Println()
+		details[fmt.Sprintf("%s %s", k.Account.Label, k.Remote.ID)] = fmt.Sprintf("%s (%s)", k.Remote.Title, k.Fingerprint)
+	}
+
+	if len(details) == 0 {
+		fmt.Println("No remote keys deleted.")
+		return nil
+	}
+
+	histMgr := history.NewManager("")
+	if err := histMgr.Record(history.Entry{
+		Action:  "audit-prune",
+		Summary: fmt.Sprintf("Deleted %d unknown or stale remote key(s)", len(details)),
+		Details: details,
+	}); err != nil {
+		logger.Warn("Failed to record history: %v", err)
+	}
+
+	fmt.Printf("✅ Deleted %d remote key(s).\n", len(details))
+	return nil
+}
+
+// auditReport is the AuditReport document of 'audit --output json|yaml'
+type auditReport struct {
+	UnusedDays int              `json:"unused_days"`
+	Remote     []auditReportKey `json:"remote"`    // Unknown or stale remote keys
+	Missing    []auditReportKey `json:"missing"`   // Configured keys not on the platform
+	Untracked  []auditReportKey `json:"untracked"` // Local key files no persona uses
+	Unused     []auditReportKey `json:"unused"`    // GitLab keys unused for unused_days
+	Failures   []string         `json:"failures"`  // Accounts that could not be checked
+}
+
+// auditReportKey is a key an audit reports
+type auditReportKey struct {
+	Account     string `json:"account,omitempty"` // type@account
+	Persona     string `json:"persona,omitempty"`
+	Fingerprint string `json:"fingerprint"`
+	Type        string `json:"type,omitempty"`
+	Title       string `json:"title,omitempty"`
+	RemoteID    string `json:"remote_id,omitempty"`
+	LocalPath   string `json:"local_path,omitempty"`
+	Stale       bool   `json:"stale,omitempty"`
+	Reason      string `json:"reason,omitempty"`
+}
+
+func newAuditReport(unusedDays int) auditReport {
+	return auditReport{
+		UnusedDays: unusedDays,
+		Remote:     []auditReportKey{},
+		Missing:    []auditReportKey{},
+		Untracked:  []auditReportKey{},
+		Unused:     []auditReportKey{},
+		Failures:   []string{},
+	}
+}
@@ -0,0 +1,211 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kunlu/git-keys/internal/api"
+	"github.com/kunlu/git-keys/internal/config"
+	"github.com/kunlu/git-keys/internal/sshkey"
+)
+
+// orgKeyFinding is a member key flagged by an organization audit
+type orgKeyFinding struct {
+	Key      api.MemberKey
+	Info     *sshkey.PublicKeyInfo
+	Weak     bool
+	Old      bool
+	NoExpiry bool
+	Problems []string
+}
+
+// runOrgAudit reports old, weak and unexpiring SSH keys of the members of
+// the organization or group given with --org
+func runOrgAudit(ctx context.Context, cfg *config.Config) error {
+	plat, err := orgAuditPlatform(cfg, config.PlatformType(auditOrgPlatform), auditOrgAccount)
+	if err != nil {
+		return err
+	}
+
+	maxAge := cfg.ResolveKeySettings(nil, nil).Expiration
+	if auditOrgMaxAge != "" {
+		if maxAge, err = parseDueWindow(auditOrgMaxAge); err != nil {
+			return fmt.Errorf("invalid --max-age: %w", err)
+		}
+	}
+
+	token, source, err := lookupToken(plat, loadTokensFromEnv())
+	if err != nil {
+		return fmt.Errorf("no token for %s@%s: %w", plat.Account, plat.Type, err)
+	}
+	client, err := newPlatformClientWithToken(plat, token)
+	if err != nil {
+		return err
+	}
+	auditor, ok := client.(api.OrgAuditor)
+	if !ok {
+		return fmt.Errorf("%s does not support organization audits", plat.Type)
+	}
+
+	printHeader(fmt.Sprintf("\n🏢 Organization Audit: %s (%s)", auditOrg, plat.Type))
+	fmt.Printf("Using the token of %s@%s (%s)\n\n", plat.Account, plat.Type, source)
+
+	domains, err := auditor.ListVerifiedDomains(ctx, auditOrg)
+	switch {
+	case err != nil:
+		fmt.Printf("⚠️  Verified domains not checked: %v\n", err)
+	case len(domains) > 0:
+		fmt.Printf("Verified domains: %s\n", strings.Join(domains, ", "))
+	case plat.Type == config.PlatformGitHub:
+		fmt.Println("Verified domains: none")
+	}
+
+	keys, err := auditor.ListMemberKeys(ctx, auditOrg)
+	if err != nil {
+		return err
+	}
+
+	findings, members := gradeMemberKeys(keys, maxAge, time.Now())
+	fmt.Printf("Members with SSH keys: %d, keys: %d\n\n", members, len(keys))
+
+	weak, old, unexpiring := 0, 0, 0
+	current := ""
+	for _, f := range findings {
+		if f.Key.Member != current {
+			current = f.Key.Member
+			fmt.Println(current)
+		}
+		fmt.Printf("  ⚠️  %s", orgKeyLabel(f))
+		if f.Key.Title != "" {
+			fmt.Printf(" %q", f.Key.Title)
+		}
+		fmt.Println()
+		for _, problem := range f.Problems {
+			printWrapped("      ", problem)
+		}
+		if f.Weak {
+			weak++
+		}
+		if f.Old {
+			old++
+		}
+		if f.NoExpiry {
+			unexpiring++
+		}
+	}
+	if len(findings) == 0 {
+		fmt.Println("✓ No old, weak or unexpiring keys found")
+	}
+	fmt.Println()
+
+	printHeader("📋 Organization Audit Summary")
+	fmt.Printf("  Weak keys:              %d\n", weak)
+	fmt.Printf("  Old keys:               %d (older than %s)\n", old, formatDueWindow(maxAge))
+	if plat.Type == config.PlatformGitLab {
+		fmt.Printf("  Keys without an expiry: %d\n", unexpiring)
+	}
+	fmt.Println()
+
+	if plat.Type == config.PlatformGitHub {
+		printWrapped("💡 ", "GitHub SSH keys cannot expire, and GitHub only reports when a key was added for organizations with SAML SSO; keys of other organizations are graded on strength alone.")
+	}
+	if len(findings) > 0 {
+		fmt.Println("💡 Ask members to replace flagged keys with ed25519 keys, e.g. with 'git-keys rotate'.")
+	}
+	return nil
+}
+
+// gradeMemberKeys returns the member keys that are weak, older than maxAge
+// or (on GitLab) never expire, sorted by member, and the number of members
+// with keys
+func gradeMemberKeys(keys []api.MemberKey, maxAge time.Duration, now time.Time) ([]orgKeyFinding, int) {
+	var findings []orgKeyFinding
+	members := make(map[string]bool)
+
+	for _, key := range keys {
+		members[key.Member] = true
+		f := orgKeyFinding{Key: key}
+
+		if info, err := sshkey.ParsePublicKey(key.Key); err == nil {
+			f.Info = info
+			switch {
+			case info.Type == "ssh-dss":
+				f.Weak = true
+				f.Problems = append(f.Problems, "weak: DSA")
+			case info.Type == "ssh-rsa" && info.Bits < recommendedRSABits:
+				f.Weak = true
+				f.Problems = append(f.Problems, fmt.Sprintf("weak: RSA %d bits (minimum %d)", info.Bits, recommendedRSABits))
+			}
+		}
+
+		if t, err := time.Parse(time.RFC3339, key.CreatedAt); err == nil && now.Sub(t) > maxAge {
+			f.Old = true
+			f.Problems = append(f.Problems, "old: added "+t.Local().Format("2006-01-02"))
+		} else if t, err := time.Parse(time.RFC3339, key.Authorized); err == nil && now.Sub(t) > maxAge {
+			f.Old = true
+			f.Problems = append(f.Problems, "old: authorized for the organization "+t.Local().Format("2006-01-02"))
+		}
+
+		// GitHub keys never expire, so only GitLab keys are judged by it
+		if key.CreatedAt != "" && key.ExpiresAt == "" {
+			f.NoExpiry = true
+			f.Problems = append(f.Problems, "no expiry")
+		}
+
+		if len(f.Problems) > 0 {
+			findings = append(findings, f)
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].Key.Member < findings[j].Key.Member
+	})
+	return findings, len(members)
+}
+
+// orgKeyLabel describes a member key by type, size and fingerprint
+func orgKeyLabel(f orgKeyFinding) string {
+	if f.Info == nil {
+		return fmt.Sprintf("key %s (unparseable)", f.Key.ID)
+	}
+	if f.Info.Type == "ssh-rsa" {
+		return fmt.Sprintf("%s %d %s", f.Info.Type, f.Info.Bits, f.Info.Fingerprint)
+	}
+	return fmt.Sprintf("%s %s", f.Info.Type, f.Info.Fingerprint)
+}
+
+// orgAuditPlatform returns the configured platform whose token audits an
+// organization: the one account of platformType, or the one named account
+func orgAuditPlatform(cfg *config.Config, platformType config.PlatformType, account string) (*config.Platform, error) {
+	if platformType != config.PlatformGitHub && platformType != config.PlatformGitLab {
+		return nil, fmt.Errorf("invalid --platform %q: use github or gitlab", platformType)
+	}
+
+	var found *config.Platform
+	for personaIdx := range cfg.Personas {
+		persona := &cfg.Personas[personaIdx]
+		for platformIdx := range persona.Platforms {
+			plat := &persona.Platforms[platformIdx]
+			if plat.Type != platformType || (account != "" && !strings.EqualFold(plat.Account, account)) {
+				continue
+			}
+			if found != nil && !strings.EqualFold(found.Account, plat.Account) {
+				return nil, fmt.Errorf("several %s accounts are configured; choose the one that administers %s with --account", platformType, auditOrg)
+			}
+			if found == nil {
+				found = plat
+			}
+		}
+	}
+
+	if found == nil {
+		if account != "" {
+			return nil, fmt.Errorf("no %s account '%s' in the configuration", platformType, account)
+		}
+		return nil, fmt.Errorf("no %s account in the configuration", platformType)
+	}
+	return found, nil
+}
@@ -0,0 +1,376 @@
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kunlu/git-keys/internal/config"
+	"github.com/kunlu/git-keys/internal/history"
+	"github.com/kunlu/git-keys/internal/sshkey"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// bugreportHistoryEntries is how many of the latest history entries are included
+	bugreportHistoryEntries = 20
+
+	// bugreportLogLines is how many lines of the end of --log are included
+	bugreportLogLines = 200
+)
+
+var (
+	bugreportOutput string
+	bugreportLog    string
+)
+
+var bugreportCmd = &cobra.Command{
+	Use:   "bugreport",
+	Short: "Collect redacted diagnostics into a file to attach to an issue",
+	Long: `Gather the diagnostics needed to reproduce a problem into a single text
+file you can attach to an issue. Nothing is sent anywhere.
+
+The report contains:
+  - git-keys, Go, git and OpenSSH versions, OS and architecture
+  - the configuration, redacted
+  - the offline 'git-keys doctor' findings
+  - the last history entries
+  - the end of a log file given with --log
+
+Redaction replaces account names, emails, self-hosted platform hosts and the
+machine name with placeholders (the same placeholder for the same value
+throughout the report), your home directory with ~, and anything that looks
+like an API token. Persona names, key types, dates and fingerprints are kept.
+Review the file before attaching it.
+
+To include a log, rerun the failing command with debug logging first:
+  git-keys apply --log-level debug 2> git-keys.log
+  git-keys bugreport --log git-keys.log
+
+Examples:
+  git-keys bugreport
+  git-keys bugreport --output report.txt
+  git-keys bugreport --output -          # write to stdout
+`,
+	Args: cobra.NoArgs,
+	RunE: runBugreport,
+}
+
+func init() {
+	bugreportCmd.Flags().StringVarP(&bugreportOutput, "output", "o", "", "Report file (default: git-keys-bugreport-<time>.txt; - for stdout)")
+	bugreportCmd.Flags().StringVar(&bugreportLog, "log", "", "Log file to include the end of")
+	rootCmd.AddCommand(bugreportCmd)
+}
+
+func runBugreport(cmd *cobra.Command, args []string) error {
+	configPath := cfgFile
+	if configPath == "" {
+		configPath = config.GetDefaultConfigPath()
+	}
+
+	// The config is read directly, so a report can be made when it does not load
+	var cfg *config.Config
+	var configErr error
+	raw, err := config.ReadFile(configPath)
+	if err != nil {
+		configErr = err
+	} else {
+		cfg, configErr = config.NewManager(configPath).Load()
+	}
+
+	// Placeholders come from whatever parses, even if the config is invalid
+	var parsed config.Config
+	_ = yaml.Unmarshal(raw, &parsed)
+	// Files from before version 1.2 have a single machine
+	var legacy struct {
+		Machine config.Machine `yaml:"machine"`
+	}
+	if yaml.Unmarshal(raw, &legacy) == nil && legacy.Machine.ID != "" {
+		parsed.Machines = append(parsed.Machines, legacy.Machine)
+	}
+	if cfg != nil && parsed.FindMachine(cfg.Machine.ID) == nil {
+		parsed.Machines = append(parsed.Machines, cfg.Machine)
+	}
+	r := newRedactor(&parsed)
+	var report strings.Builder
+	section := func(title string) {
+		fmt.Fprintf(&report, "\n## %s\n\n", title)
+	}
+
+	fmt.Fprintf(&report, "# git-keys bug report\n\nGenerated: %s\n", time.Now().UTC().Format(time.RFC3339))
+
+	section("Versions")
+	fmt.Fprintf(&report, "git-keys: %s\n", gitKeysVersion())
+	fmt.Fprintf(&report, "go:       %s\n", runtime.Version())
+	fmt.Fprintf(&report, "git:      %s\n", commandVersion("git", "--version"))
+	fmt.Fprintf(&report, "ssh:      %s\n", commandVersion("ssh", "-V"))
+
+	section("System")
+	fmt.Fprintf(&report, "os/arch:       %s/%s\n", runtime.GOOS, runtime.GOARCH)
+	fmt.Fprintf(&report, "kernel:        %s\n", commandVersion("uname", "-sr"))
+	fmt.Fprintf(&report, "shell:         %s\n", valueOr(os.Getenv("SHELL"), "(not set)"))
+	fmt.Fprintf(&report, "SSH_AUTH_SOCK: %s\n", setOrNot(os.Getenv("SSH_AUTH_SOCK")))
+
+	section("Configuration")
+	fmt.Fprintf(&report, "path: %s\n", configPath)
+	switch {
+	case raw == nil:
+		fmt.Fprintf(&report, "error: %v\n", configErr)
+	case configErr != nil:
+		// Show what failed to parse; redaction still applies to the raw text
+		fmt.Fprintf(&report, "error: %v\n\n```yaml\n%s\n```\n", configErr, strings.TrimRight(string(raw), "\n"))
+	default:
+		data, err := yaml.Marshal(cfg)
+		if err != nil {
+			return fmt.Errorf("failed to encode configuration: %w", err)
+		}
+		fmt.Fprintf(&report, "\n```yaml\n%s```\n", data)
+	}
+
+	section("Diagnostics (git-keys doctor --offline)")
+	if cfg == nil {
+		report.WriteString("skipped: the configuration does not load\n")
+	} else {
+		d := &doctor{}
+		keyMgr := sshkey.NewManager(cfg.Defaults.GetKeysDir())
+		d.checkConfig(cfg, sshConfigManager(cfg), keyMgr)
+		d.checkKeys(cfg, keyMgr)
+		d.checkAgent(cfg, keyMgr)
+		d.checkGitConfig(cfg)
+		findings := prioritizeFindings(d.findings)
+		if len(findings) == 0 {
+			report.WriteString("no problems found\n")
+		}
+		for _, f := range findings {
+			subject := ""
+			if f.Subject != "" {
+				subject = f.Subject + ": "
+			}
+			fmt.Fprintf(&report, "- %s [%s] %s%s (fix: %s)\n", f.Severity, f.Check, subject, f.Problem, f.Fix)
+		}
+	}
+
+	section(fmt.Sprintf("History (last %d entries)", bugreportHistoryEntries))
+	entries, err := history.NewManager("").Entries()
+	switch {
+	case err != nil:
+		fmt.Fprintf(&report, "error: %v\n", err)
+	case len(entries) == 0:
+		report.WriteString("no entries\n")
+	}
+	if len(entries) > bugreportHistoryEntries {
+		entries = entries[len(entries)-bugreportHistoryEntries:]
+	}
+	for _, entry := range entries {
+		fmt.Fprintf(&report, "- %s %s: %s\n", entry.Timestamp.UTC().Format(time.RFC3339), entry.Action, entry.Summary)
+		keys := make([]string, 0, len(entry.Details))
+		for key := range entry.Details {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Fprintf(&report, "    %s: %s\n", key, entry.Details[key])
+		}
+	}
+
+	section("Log")
+	if bugreportLog == "" {
+		report.WriteString("none given; rerun the failing command with --log-level debug 2> git-keys.log and pass --log git-keys.log\n")
+	} else if lines, err := tailFile(bugreportLog, bugreportLogLines); err != nil {
+		fmt.Fprintf(&report, "error: %v\n", err)
+	} else {
+		fmt.Fprintf(&report, "last %d line(s) of %s:\n\n```\n%s\n```\n", len(lines), bugreportLog, strings.Join(lines, "\n"))
+	}
+
+	content := r.redact(report.String())
+
+	if bugreportOutput == "-" {
+		fmt.Print(content)
+		return nil
+	}
+
+	output := bugreportOutput
+	if output == "" {
+		output = fmt.Sprintf("git-keys-bugreport-%s.txt", time.Now().Format("20060102-150405"))
+	}
+	if err := os.WriteFile(output, []byte(content), 0600); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+
+	fmt.Printf("✓ Wrote bug report to %s\n", output)
+	fmt.Println("  Review it before attaching it to an issue; redaction is best effort.")
+	return nil
+}
+
+// gitKeysVersion returns the module version and VCS revision the binary was built from
+func gitKeysVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+
+	version := info.Main.Version
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			version += " " + setting.Value
+		case "vcs.modified":
+			if setting.Value == "true" {
+				version += " (modified)"
+			}
+		}
+	}
+	return version
+}
+
+// commandVersion runs a version command and returns the first line of its
+// output (ssh -V prints to stderr)
+func commandVersion(name string, args ...string) string {
+	output, err := exec.Command(name, args...).CombinedOutput()
+	if err != nil && len(output) == 0 {
+		return fmt.Sprintf("not available (%v)", err)
+	}
+	return firstLineOf(string(output))
+}
+
+func valueOr(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+func setOrNot(value string) string {
+	if value == "" {
+		return "not set"
+	}
+	return "set"
+}
+
+// tailFile returns the last n lines of a file
+func tailFile(path string, n int) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+		if len(lines) > n {
+			lines = lines[1:]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}`)
+	tokenPattern = regexp.MustCompile(`\b(gh[pousr]_[A-Za-z0-9]{20,}|github_pat_[A-Za-z0-9_]{20,}|glpat-[A-Za-z0-9_-]{20,})`)
+)
+
+// redactor replaces identifying values in a report with placeholders
+type redactor struct {
+	home         string
+	replacements []redaction
+}
+
+type redaction struct {
+	pattern     *regexp.Regexp
+	placeholder string
+}
+
+// newRedactor builds the placeholders for the accounts, emails, hosts and
+// machines of cfg
+func newRedactor(cfg *config.Config) *redactor {
+	r := &redactor{}
+	if home, err := os.UserHomeDir(); err == nil && home != "/" {
+		r.home = home
+	}
+
+	seen := make(map[string]bool)
+	add := func(value, placeholder string) {
+		// Very short values would also replace unrelated words
+		if len(value) < 3 || seen[value] {
+			return
+		}
+		seen[value] = true
+		r.replacements = append(r.replacements, redaction{
+			pattern:     regexp.MustCompile(`(^|[^A-Za-z0-9_.])` + regexp.QuoteMeta(value) + `($|[^A-Za-z0-9_])`),
+			placeholder: "${1}" + placeholder + "${2}",
+		})
+	}
+
+	accounts, emails, hosts := 0, 0, 0
+	for _, persona := range cfg.Personas {
+		if !seen[persona.Email] && len(persona.Email) >= 3 {
+			emails++
+		}
+		add(persona.Email, fmt.Sprintf("email-%d@example.com", emails))
+		for _, plat := range persona.Platforms {
+			if !seen[plat.Account] && len(plat.Account) >= 3 {
+				accounts++
+			}
+			add(plat.Account, fmt.Sprintf("account-%d", accounts))
+
+			if plat.BaseURL != "" {
+				host := strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(plat.BaseURL, "https://"), "http://"), "/")
+				if host != "gitlab.com" && host != "github.com" {
+					if !seen[host] && len(host) >= 3 {
+						hosts++
+					}
+					add(host, fmt.Sprintf("git-%d.example.com", hosts))
+				}
+			}
+		}
+	}
+	for i, machine := range cfg.Machines {
+		suffix := ""
+		if i > 0 {
+			suffix = fmt.Sprintf("-%d", i+1)
+		}
+		add(machine.Name, "machine"+suffix)
+		add(machine.ID, "machine-id"+suffix)
+	}
+
+	// Longer values first, so an account that is part of an email or host
+	// does not break up the longer value's placeholder
+	sort.SliceStable(r.replacements, func(i, j int) bool {
+		return len(r.replacements[i].pattern.String()) > len(r.replacements[j].pattern.String())
+	})
+	return r
+}
+
+// redact returns text with the home directory, tokens, known values and
+// other email addresses replaced
+func (r *redactor) redact(text string) string {
+	if r.home != "" {
+		text = strings.ReplaceAll(text, r.home, "~")
+	}
+	text = tokenPattern.ReplaceAllString(text, "<token>")
+	for _, repl := range r.replacements {
+		// Twice, since a match consumes the separator an adjacent match needs
+		text = repl.pattern.ReplaceAllString(text, repl.placeholder)
+		text = repl.pattern.ReplaceAllString(text, repl.placeholder)
+	}
+	return emailPattern.ReplaceAllStringFunc(text, func(email string) string {
+		if strings.HasSuffix(email, "@example.com") {
+			return email
+		}
+		return "<email>"
+	})
+}
@@ -0,0 +1,108 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kunlu/git-keys/internal/api"
+	"github.com/kunlu/git-keys/internal/config"
+)
+
+// configureAPIClients applies defaults.api_retries, defaults.api_timeout and
+// defaults.remote_cache_ttl to API clients
+func configureAPIClients(cfg *config.Config) {
+	api.SetDefaultTransportOptions(api.TransportOptions{
+		Retries: cfg.Defaults.APIRetries,
+		Timeout: cfg.Defaults.APITimeout,
+	})
+	api.SetDefaultKeyCache(api.NewKeyCache("", cfg.Defaults.RemoteCacheTTL))
+}
+
+// tokenServiceName returns the keychain service that stores a platform's tokens
+func tokenServiceName(platformType config.PlatformType) (string, error) {
+	switch platformType {
+	case config.PlatformGitHub:
+		return "git-keys-github", nil
+	case config.PlatformGitLab:
+		return "git-keys-gitlab", nil
+	default:
+		return "", fmt.Errorf("unsupported platform: %s", platformType)
+	}
+}
+
+// getKeychainToken retrieves the API token for a platform's account from the
+// keychain, falling back to the "default" account
+func getKeychainToken(platform *config.Platform) (string, error) {
+	tokenService, err := tokenServiceName(platform.Type)
+	if err != nil {
+		return "", err
+	}
+
+	// Prefer a token from 'git-keys login gitlab' for the same instance
+	if platform.Type == config.PlatformGitLab {
+		if token, err := loadGitLabOAuthToken(gitlabBaseURL(platform), platform.Account); err == nil {
+			return token, nil
+		}
+	}
+
+	tokenMgr := api.NewTokenManager(tokenService)
+	token, err := tokenMgr.GetToken(platform.Account)
+	if err != nil {
+		token, err = tokenMgr.GetToken("default")
+		if err != nil {
+			return "", fmt.Errorf("no API token found (service: %s): %w", tokenService, err)
+		}
+	}
+
+	return token, nil
+}
+
+// newPlatformClient creates an API client for a configured platform using keychain tokens
+func newPlatformClient(platform *config.Platform) (api.PlatformClient, error) {
+	token, err := getKeychainToken(platform)
+	if err != nil {
+		return nil, err
+	}
+
+	return newPlatformClientWithToken(platform, token)
+}
+
+// newPlatformClientWithToken creates an API client for a configured platform
+func newPlatformClientWithToken(platform *config.Platform, token string) (api.PlatformClient, error) {
+	switch platform.Type {
+	case config.PlatformGitHub:
+		return api.NewGitHubClient(token), nil
+	case config.PlatformGitLab:
+		return newGitLabClient(platform, token)
+	default:
+		return nil, fmt.Errorf("unsupported platform: %s", platform.Type)
+	}
+}
+
+// gitlabConnectionOptions returns the proxy and TLS settings of a platform
+func gitlabConnectionOptions(platform *config.Platform) api.ConnectionOptions {
+	return api.ConnectionOptions{
+		CACertPath:         platform.GetCACertPath(),
+		InsecureSkipVerify: platform.InsecureSkipVerify,
+		Proxy:              platform.Proxy,
+	}
+}
+
+// gitlabBaseURL returns the base URL of a GitLab platform, without a
+// trailing slash; gitlab.com when none is configured
+func gitlabBaseURL(platform *config.Platform) string {
+	if platform.BaseURL == "" {
+		return "https://gitlab.com"
+	}
+	return strings.TrimSuffix(platform.BaseURL, "/")
+}
+
+// newGitLabClient creates a GitLab client for a platform's base URL and
+// connection settings
+func newGitLabClient(platform *config.Platform, token string) (*api.GitLabClient, error) {
+	client, err := api.NewGitLabClientWithOptions(gitlabBaseURL(platform), token, gitlabConnectionOptions(platform))
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure GitLab client: %w", err)
+	}
+	return client, nil
+}
@@ -0,0 +1,186 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/kunlu/git-keys/internal/config"
+	"github.com/kunlu/git-keys/internal/sshkey"
+	"github.com/spf13/cobra"
+)
+
+var clonePersona string
+
+var cloneCmd = &cobra.Command{
+	Use:   "clone <url> [directory]",
+	Short: "Clone a repository with the right persona",
+	Long: `Clone a repository as the persona it belongs to.
+
+The platform is detected from the URL and the persona is picked the way
+'git-keys persona suggest' ranks them (prompting when several fit), or
+given with --persona. Then:
+  - the URL is rewritten to the persona's SSH host alias, so the clone and
+    later pushes use the persona's key
+  - the repository is cloned into the persona's gitdir, so the includeIf
+    applies the persona's identity
+  - the identity is verified afterwards
+
+When the destination is outside the persona's gitdir (or the platform has
+none), the repository is bound to the persona as 'git-keys use' does.
+
+Examples:
+  git-keys clone https://github.com/acme/api.git
+  git-keys clone git@gitlab.com:me/dotfiles.git --persona personal
+  git-keys clone git@github.com:acme/api.git ~/src/api
+`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: runClone,
+}
+
+func init() {
+	cloneCmd.Flags().StringVar(&clonePersona, "persona", "", "Persona to clone as")
+	cloneCmd.RegisterFlagCompletionFunc("persona", completePersonaFlag)
+	rootCmd.AddCommand(cloneCmd)
+}
+
+func runClone(cmd *cobra.Command, args []string) error {
+	url := args[0]
+
+	// Load configuration
+	configPath := cfgFile
+	if configPath == "" {
+		configPath = config.GetDefaultConfigPath()
+	}
+
+	mgr := config.NewManager(configPath)
+	if !mgr.Exists() {
+		return fmt.Errorf("configuration file not found at %s\nRun 'git-keys init' first", configPath)
+	}
+
+	cfg, err := mgr.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	suggestions, err := suggestPersonas(cfg, url)
+	if err != nil {
+		return err
+	}
+	if clonePersona != "" {
+		if cfg.FindPersona(clonePersona) == nil {
+			return fmt.Errorf("persona not found: %s", clonePersona)
+		}
+		var filtered []personaSuggestion
+		for _, s := range suggestions {
+			if s.Persona.Name == clonePersona {
+				filtered = append(filtered, s)
+			}
+		}
+		if len(filtered) == 0 {
+			return fmt.Errorf("persona '%s' has no platform for %s", clonePersona, remoteHost(url))
+		}
+		suggestions = filtered
+	}
+	if len(suggestions) == 0 {
+		return fmt.Errorf("no configured persona uses the host of %s", url)
+	}
+
+	repoPath := remotePath(url)
+	if repoPath == "" {
+		return fmt.Errorf("cannot find the repository path in %s", url)
+	}
+
+	printHeader("\n📥 Clone Repository")
+	fmt.Printf("\n  URL: %s\n\n", url)
+
+	chosen := &suggestions[0]
+	if len(suggestions) > 1 {
+		if chosen = choosePersona(suggestions); chosen == nil {
+			fmt.Println("Clone cancelled.")
+			return nil
+		}
+		fmt.Println()
+	}
+	persona, plat := chosen.Persona, chosen.Platform
+
+	if plat.GetActiveKey() == nil {
+		return fmt.Errorf("%s@%s has no active key; run 'git-keys apply' first", plat.Account, plat.Type)
+	}
+
+	alias, _ := sshHostAlias(persona, plat)
+	cloneURL := fmt.Sprintf("git@%s:%s", alias, repoPath)
+
+	dest, err := cloneDestination(plat, repoPath, args)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(dest); err == nil {
+		return fmt.Errorf("destination already exists: %s", dest)
+	}
+
+	fmt.Printf("  Persona:     %s <%s>\n", persona.Name, persona.Email)
+	fmt.Printf("  Platform:    %s@%s\n", plat.Account, plat.Type)
+	fmt.Printf("  Clone URL:   %s\n", cloneURL)
+	fmt.Printf("  Destination: %s\n\n", dest)
+
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(dest), err)
+	}
+
+	clone := exec.Command("git", "clone", cloneURL, dest)
+	clone.Stdout = os.Stdout
+	clone.Stderr = os.Stderr
+	clone.Stdin = os.Stdin
+	if err := clone.Run(); err != nil {
+		return fmt.Errorf("git clone failed: %w\nRun 'git-keys fix %s' to diagnose SSH authentication", err, alias)
+	}
+	fmt.Println()
+
+	// Outside the gitdir no includeIf applies, so bind the repository
+	if platformGitDirFor(plat, dest) == "" {
+		if err := bindRepository(mgr, cfg, dest, persona, plat); err != nil {
+			return err
+		}
+	}
+
+	// Verify the identity git resolves in the new clone
+	var warnings []string
+	email, emailOrigin := gitConfigWithOrigin(dest, "user.email")
+	if !strings.EqualFold(email, persona.Email) {
+		warnings = append(warnings, fmt.Sprintf("commits will use %s, not %s; run 'git-keys setup-git' or 'git-keys use %s'",
+			whoamiValue(email, emailOrigin), persona.Email, persona.Name))
+	}
+	if remote, _ := gitOutput(dest, "ls-remote", "--get-url", "origin"); !strings.EqualFold(remoteHost(remote), alias) {
+		warnings = append(warnings, fmt.Sprintf("origin resolves to %s, which bypasses %s (check url.insteadOf rules)", remote, alias))
+	}
+
+	if len(warnings) > 0 {
+		fmt.Printf("⚠️  Cloned, but the identity does not match:\n")
+		for _, w := range warnings {
+			printWrapped("   • ", w)
+		}
+		return nil
+	}
+
+	fmt.Printf("✅ Cloned %s as %s <%s>.\n", dest, persona.Name, persona.Email)
+	return nil
+}
+
+// cloneDestination returns the directory to clone into: the given one, or
+// the repository name under the platform's gitdir (or the current directory)
+func cloneDestination(plat *config.Platform, repoPath string, args []string) (string, error) {
+	if len(args) > 1 {
+		return filepath.Abs(sshkey.ExpandHome(args[1]))
+	}
+
+	name := strings.TrimSuffix(path.Base(repoPath), ".git")
+	if dirs := plat.AllGitDirs(); len(dirs) > 0 {
+		gitdir := strings.TrimSuffix(sshkey.ExpandHome(strings.TrimPrefix(dirs[0], "gitdir:")), "/")
+		return filepath.Join(gitdir, name), nil
+	}
+	return filepath.Abs(name)
+}
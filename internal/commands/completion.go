@@ -0,0 +1,275 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/kunlu/git-keys/internal/config"
+	"github.com/kunlu/git-keys/internal/logger"
+	"github.com/kunlu/git-keys/internal/profile"
+	"github.com/spf13/cobra"
+)
+
+var completionCmd = &cobra.Command{
+	Use:   "completion <bash|zsh|fish>",
+	Short: "Generate the shell completion script",
+	Long: `Print a completion script for bash, zsh or fish.
+
+Besides commands and flags, the script completes values from your
+configuration: 'git-keys rotate <TAB>' offers your personas and their
+platforms (personal, personal/github, work/gitlab@jdoe), and flags such as
+--persona, --target, --fingerprint and --profile offer the configured values.
+
+Bash (needs the bash-completion package):
+  git-keys completion bash > $(brew --prefix)/etc/bash_completion.d/git-keys
+
+Zsh:
+  git-keys completion zsh > "${fpath[1]}/_git-keys"
+  # Start a new shell; run 'autoload -U compinit; compinit' first if
+  # completion is not enabled yet
+
+Fish:
+  git-keys completion fish > ~/.config/fish/completions/git-keys.fish`,
+	ValidArgs:             []string{"bash", "zsh", "fish"},
+	Args:                  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	DisableFlagsInUseLine: true,
+	RunE:                  runCompletion,
+}
+
+func init() {
+	// Replaces cobra's default command, which also offers powershell
+	rootCmd.CompletionOptions.DisableDefaultCmd = true
+	rootCmd.AddCommand(completionCmd)
+}
+
+func runCompletion(cmd *cobra.Command, args []string) error {
+	switch args[0] {
+	case "bash":
+		return rootCmd.GenBashCompletionV2(os.Stdout, true)
+	case "zsh":
+		return rootCmd.GenZshCompletion(os.Stdout)
+	case "fish":
+		return rootCmd.GenFishCompletion(os.Stdout, true)
+	}
+	return fmt.Errorf("unsupported shell %q (use bash, zsh or fish)", args[0])
+}
+
+// isCompletionRequest reports whether cmd is cobra's hidden command that
+// answers the completion scripts
+func isCompletionRequest(cmd *cobra.Command) bool {
+	return cmd.Name() == cobra.ShellCompRequestCmd || cmd.Name() == cobra.ShellCompNoDescRequestCmd
+}
+
+// completionConfig loads the configuration of the command line being
+// completed, without logging; nil when there is none. The --config and
+// --profile flags of that line are parsed by the time completions run.
+func completionConfig() *config.Config {
+	logger.SetLevel(logger.ERROR)
+	if err := profile.Set(profileName); err != nil {
+		return nil
+	}
+	return loadGlobalDefaults()
+}
+
+// completeMatching keeps the candidates that start with toComplete. A tab
+// and description may follow each value.
+func completeMatching(candidates []string, toComplete string) []string {
+	var matches []string
+	for _, c := range candidates {
+		if strings.HasPrefix(c, toComplete) {
+			matches = append(matches, c)
+		}
+	}
+	return matches
+}
+
+// completePersonaNames offers the personas that are, or are not, archived
+func completePersonaNames(archived bool, toComplete string) ([]string, cobra.ShellCompDirective) {
+	cfg := completionConfig()
+	if cfg == nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var names []string
+	for _, persona := range cfg.Personas {
+		if persona.Archived == archived {
+			names = append(names, persona.Name+"\t"+persona.Email)
+		}
+	}
+	return completeMatching(names, toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completePersonas completes the first argument with an active persona
+func completePersonas(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return completePersonaNames(false, toComplete)
+}
+
+// completeArchivedPersonas completes the first argument with an archived
+// persona
+func completeArchivedPersonas(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return completePersonaNames(true, toComplete)
+}
+
+// completePersonaFlag completes a --persona flag
+func completePersonaFlag(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return completePersonaNames(false, toComplete)
+}
+
+// personaPlatformTargets lists the targets of the active personas in the
+// form parsePlatformTarget reads: persona, persona/platform, and
+// persona/platform@account where a persona has several accounts on a platform
+func personaPlatformTargets(cfg *config.Config) []string {
+	var targets []string
+	for i := range cfg.Personas {
+		persona := &cfg.Personas[i]
+		if persona.Archived {
+			continue
+		}
+		targets = append(targets, persona.Name+"\t"+persona.Email)
+
+		accounts := make(map[config.PlatformType]int)
+		for _, plat := range persona.Platforms {
+			accounts[plat.Type]++
+		}
+		seen := make(map[config.PlatformType]bool)
+		for _, plat := range persona.Platforms {
+			if !seen[plat.Type] {
+				seen[plat.Type] = true
+				targets = append(targets, fmt.Sprintf("%s/%s\t%s", persona.Name, plat.Type, plat.Account))
+			}
+			if accounts[plat.Type] > 1 {
+				targets = append(targets, fmt.Sprintf("%s/%s@%s", persona.Name, plat.Type, plat.Account))
+			}
+		}
+	}
+	return targets
+}
+
+// completePersonaPlatforms completes the first argument with a persona or
+// one of its platforms
+func completePersonaPlatforms(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return completeTargetFlag(cmd, args, toComplete)
+}
+
+// completeTargetFlag completes a persona[/platform[@account]] flag
+func completeTargetFlag(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	cfg := completionConfig()
+	if cfg == nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return completeMatching(personaPlatformTargets(cfg), toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeTargetAnswer completes an answer flag such as --gitdir up to the
+// '=' that starts the value
+func completeTargetAnswer(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if strings.Contains(toComplete, "=") {
+		return nil, cobra.ShellCompDirectiveDefault
+	}
+	cfg := completionConfig()
+	if cfg == nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var targets []string
+	for _, target := range personaPlatformTargets(cfg) {
+		value, _, _ := strings.Cut(target, "\t")
+		targets = append(targets, value+"=")
+	}
+	return completeMatching(targets, toComplete), cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace
+}
+
+// completeFingerprints completes a --fingerprint flag with the keys in use
+func completeFingerprints(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	cfg := completionConfig()
+	if cfg == nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var fingerprints []string
+	for _, persona := range cfg.Personas {
+		for _, plat := range persona.Platforms {
+			for _, key := range plat.Keys {
+				if key.Fingerprint != "" && key.Status != config.KeyStatusRevoked {
+					fingerprints = append(fingerprints, fmt.Sprintf("%s\t%s/%s@%s", key.Fingerprint, persona.Name, plat.Account, plat.Type))
+				}
+			}
+		}
+	}
+	return completeMatching(fingerprints, toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completePlatformTypes completes a github|gitlab argument or flag
+func completePlatformTypes(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return completeMatching([]string{string(config.PlatformGitHub), string(config.PlatformGitLab)}, toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeTokenArgs completes <github|gitlab> <account> with the accounts
+// configured on that platform
+func completeTokenArgs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	switch len(args) {
+	case 0:
+		return completePlatformTypes(cmd, args, toComplete)
+	case 1:
+		cfg := completionConfig()
+		if cfg == nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		seen := make(map[string]bool)
+		var accounts []string
+		for _, persona := range cfg.Personas {
+			for _, plat := range persona.Platforms {
+				if string(plat.Type) == args[0] && !seen[plat.Account] {
+					seen[plat.Account] = true
+					accounts = append(accounts, plat.Account+"\t"+persona.Name)
+				}
+			}
+		}
+		return completeMatching(accounts, toComplete), cobra.ShellCompDirectiveNoFileComp
+	}
+	return nil, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeAdoptArgs completes <keyfile> with files, then the
+// persona/platform to adopt it for
+func completeAdoptArgs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) == 0 {
+		return nil, cobra.ShellCompDirectiveDefault
+	}
+	return completePersonaPlatforms(cmd, args[1:], toComplete)
+}
+
+// completeDeployKeyRepos completes a repository with a managed deploy key
+func completeDeployKeyRepos(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	cfg := completionConfig()
+	if cfg == nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var repos []string
+	for _, dk := range cfg.DeployKeys {
+		repos = append(repos, fmt.Sprintf("%s\t%s", dk.Repo, dk.Platform))
+	}
+	return completeMatching(repos, toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeProfiles completes --profile with the profiles that exist
+func completeProfiles(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return completeMatching(append([]string{profile.Default}, profile.List()...), toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeValues returns a completion function offering fixed values
+func completeValues(values ...string) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return completeMatching(values, toComplete), cobra.ShellCompDirectiveNoFileComp
+	}
+}
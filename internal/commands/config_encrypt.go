@@ -0,0 +1,155 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/kunlu/git-keys/internal/config"
+	"github.com/kunlu/git-keys/internal/history"
+	"github.com/kunlu/git-keys/internal/logger"
+	"github.com/spf13/cobra"
+)
+
+var (
+	configEncryptRecipients []string
+	configEncryptIdentities []string
+)
+
+var configEncryptCmd = &cobra.Command{
+	Use:   "encrypt",
+	Short: "Encrypt the configuration file with age",
+	Long: `Store the configuration encrypted with age (https://age-encryption.org),
+for dotfiles synced to cloud storage or a public repository. Machine IDs,
+emails and key fingerprints are then not readable from the synced file.
+
+The configuration moves to <config>.age and the plaintext file is removed
+once the encrypted copy has been decrypted and checked. Every command
+decrypts it when it loads the configuration and encrypts it again when it
+saves it.
+
+The configuration is encrypted to the age or SSH public keys (or files of
+them) given with --recipient, kept in <config>.age.recipients. --identity
+names the private key files that decrypt it; they are remembered in the
+recipients file, and $GIT_KEYS_AGE_IDENTITY adds one more. Passphrases are not
+supported: age would ask for a new one on every save, and scheduled rotations
+could not save at all. A configuration encrypted with a passphrase by an
+earlier version is still read; decrypt it and encrypt it to a key to make
+changes.
+
+Requires the age command.
+
+Examples:
+  git-keys config encrypt --recipient ~/.ssh/id_ed25519.pub --identity ~/.ssh/id_ed25519
+  git-keys config encrypt --recipient age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p --identity ~/.config/age/key.txt
+`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE:         runConfigEncrypt,
+}
+
+var configDecryptCmd = &cobra.Command{
+	Use:   "decrypt",
+	Short: "Store the configuration file in plaintext again",
+	Long: `Decrypt a configuration encrypted with 'git-keys config encrypt' and store it
+in plaintext again. The encrypted file and its recipients file are removed.
+`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE:         runConfigDecrypt,
+}
+
+func init() {
+	configEncryptCmd.Flags().StringArrayVarP(&configEncryptRecipients, "recipient", "r", nil, "age or SSH public key, or a file of them, to encrypt to (repeatable)")
+	configEncryptCmd.Flags().StringArrayVarP(&configEncryptIdentities, "identity", "i", nil, "Private key file that decrypts the configuration (repeatable)")
+
+	configCmd.AddCommand(configEncryptCmd)
+	configCmd.AddCommand(configDecryptCmd)
+}
+
+func runConfigEncrypt(cmd *cobra.Command, args []string) error {
+	configPath := cfgFile
+	if configPath == "" {
+		configPath = config.GetDefaultConfigPath()
+	}
+
+	if config.IsEncrypted(configPath) {
+		fmt.Printf("✓ %s is already encrypted\n", config.EncryptedPath(configPath))
+		return nil
+	}
+	if _, err := os.Stat(configPath); err != nil {
+		return fmt.Errorf("configuration file not found at %s\nRun 'git-keys init' first", configPath)
+	}
+	if len(configEncryptRecipients) == 0 {
+		return fmt.Errorf("--recipient is required (passphrases are not supported, since every save would ask for a new one)")
+	}
+	if !config.AgeAvailable() {
+		return fmt.Errorf("age is not installed\nInstall it from https://age-encryption.org (e.g. 'brew install age')")
+	}
+
+	// Make sure the plaintext being encrypted is a configuration that loads
+	if _, err := config.NewManager(configPath).Load(); err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	if err := config.Encrypt(configPath, configEncryptRecipients, configEncryptIdentities); err != nil {
+		return err
+	}
+	fmt.Printf("✓ Encrypted configuration to %s\n", config.EncryptedPath(configPath))
+	fmt.Printf("✓ Recipients in %s\n", config.RecipientsPath(configPath))
+
+	// The editor summary lists emails and aliases in plaintext; it is not
+	// written for encrypted configurations
+	if summaryPath := getSummaryPath(); summaryPath != "" {
+		if err := os.Remove(summaryPath); err == nil {
+			fmt.Printf("✓ Removed editor summary %s\n", summaryPath)
+		} else if !os.IsNotExist(err) {
+			logger.Warn("Failed to remove editor summary: %v", err)
+		}
+	}
+
+	recordConfigHistory("config-encrypt", "Encrypted configuration", configPath)
+
+	fmt.Println("\n✅ Configuration encrypted.")
+	if len(configEncryptIdentities) == 0 {
+		fmt.Printf("\n💡 Set $%s to the private key that decrypts it.\n", config.IdentityEnv)
+	}
+	return nil
+}
+
+func runConfigDecrypt(cmd *cobra.Command, args []string) error {
+	configPath := cfgFile
+	if configPath == "" {
+		configPath = config.GetDefaultConfigPath()
+	}
+
+	if !config.IsEncrypted(configPath) {
+		fmt.Printf("✓ %s is not encrypted\n", configPath)
+		return nil
+	}
+	if len(configEncryptRecipients) == 0 {
+		return fmt.Errorf("--recipient is required (passphrases are not supported, since every save would ask for a new one)")
+	}
+	if !config.AgeAvailable() {
+		return fmt.Errorf("age is not installed\nInstall it from https://age-encryption.org (e.g. 'brew install age')")
+	}
+
+	if err := config.Decrypt(configPath); err != nil {
+		return err
+	}
+	recordConfigHistory("config-decrypt", "Decrypted configuration", configPath)
+
+	fmt.Printf("✅ Configuration stored in plaintext at %s\n", configPath)
+	return nil
+}
+
+// recordConfigHistory records a change to how the configuration is stored
+func recordConfigHistory(action, summary, configPath string) {
+	histMgr := history.NewManager("")
+	if err := histMgr.Record(history.Entry{
+		Action:  action,
+		Summary: summary,
+		Details: map[string]string{"config": configPath},
+	}); err != nil {
+		logger.Warn("Failed to record history: %v", err)
+	}
+}
@@ -0,0 +1,69 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/kunlu/git-keys/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var configSchemaOutput string
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Work with the configuration file",
+	Long: `Commands about the git-keys configuration file and how it is stored.
+
+Subcommands:
+  schema   - Print the JSON Schema of .git-keys.yaml
+  encrypt  - Encrypt the configuration file with age
+  decrypt  - Store the configuration file in plaintext again
+  sync     - Sync the configuration through a private git repository
+`,
+}
+
+var configSchemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print the JSON Schema of the configuration file",
+	Long: `Print a JSON Schema for .git-keys.yaml.
+
+Editors with YAML language support validate and complete the configuration
+with it, and flag misspelled keys as you type. For the YAML language server,
+add this first line to the configuration:
+
+  # yaml-language-server: $schema=/path/to/git-keys.schema.json
+
+Examples:
+  git-keys config schema > ~/.config/git-keys.schema.json
+  git-keys config schema --output git-keys.schema.json
+`,
+	Args: cobra.NoArgs,
+	RunE: runConfigSchema,
+}
+
+func init() {
+	configSchemaCmd.Flags().StringVarP(&configSchemaOutput, "output", "o", "", "Write the schema to a file instead of stdout")
+
+	configCmd.AddCommand(configSchemaCmd)
+	rootCmd.AddCommand(configCmd)
+}
+
+func runConfigSchema(cmd *cobra.Command, args []string) error {
+	data, err := json.MarshalIndent(config.Schema(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode schema: %w", err)
+	}
+	data = append(data, '\n')
+
+	if configSchemaOutput == "" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(configSchemaOutput, data, 0644); err != nil {
+		return fmt.Errorf("failed to write schema: %w", err)
+	}
+	fmt.Printf("✓ Wrote %s\n", configSchemaOutput)
+	return nil
+}
@@ -0,0 +1,351 @@
+package commands
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/kunlu/git-keys/internal/config"
+	"github.com/kunlu/git-keys/internal/logger"
+	"github.com/kunlu/git-keys/internal/profile"
+	"github.com/spf13/cobra"
+)
+
+var (
+	configSyncRepo    string
+	configSyncPrefer  string
+	configSyncDisable bool
+)
+
+// configSyncBefore is a digest of the stored config before a command runs,
+// so the config is only pushed after a command that changed it
+var (
+	configSyncBefore string
+	configSyncSkip   bool
+)
+
+var configSyncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Sync the configuration through a private git repository",
+	Long: `Keep the configuration in a private git repository shared by your machines.
+
+'git-keys config sync --repo <url>' clones the repository to
+~/.git-keys/config-repo. If the repository has a configuration and this
+machine has none, it is copied into place; if only this machine has one, it
+is committed and pushed.
+
+From then on, plan and apply pull the repository first, and every command
+that changes the configuration commits and pushes it. Run 'git-keys config
+sync' to sync by hand. An encrypted configuration ('git-keys config encrypt')
+is synced encrypted, with its recipients file.
+
+When the configuration changed both here and in the repository, nothing is
+overwritten: choose which one wins with --prefer local or --prefer remote.
+
+Use a host alias to push with a persona's key, e.g.
+git@github.com.personal:me/dotkeys.git.
+
+Examples:
+  git-keys config sync --repo git@github.com:me/dotkeys.git
+  git-keys config sync
+  git-keys config sync --prefer remote
+  git-keys config sync --disable
+`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE:         runConfigSync,
+}
+
+func init() {
+	configSyncCmd.Flags().StringVar(&configSyncRepo, "repo", "", "Git repository to sync the configuration through")
+	configSyncCmd.Flags().StringVar(&configSyncPrefer, "prefer", "", "Resolve a conflict with the local or remote configuration (local, remote)")
+	configSyncCmd.Flags().BoolVar(&configSyncDisable, "disable", false, "Stop syncing and remove the local clone of the repository")
+
+	configCmd.AddCommand(configSyncCmd)
+}
+
+func runConfigSync(cmd *cobra.Command, args []string) error {
+	configSyncSkip = true
+
+	configPath := cfgFile
+	if configPath == "" {
+		configPath = config.GetDefaultConfigPath()
+	}
+	if configSyncPrefer != "" && configSyncPrefer != "local" && configSyncPrefer != "remote" {
+		return fmt.Errorf("--prefer must be local or remote")
+	}
+
+	dir := configSyncDir()
+	if dir == "" {
+		return fmt.Errorf("failed to get home directory")
+	}
+
+	if configSyncDisable {
+		if !configSyncEnabled() {
+			fmt.Println("✓ Config sync is not set up")
+			return nil
+		}
+		if err := os.RemoveAll(dir); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", dir, err)
+		}
+		recordConfigHistory("config-sync-disable", "Stopped syncing configuration", configPath)
+		fmt.Println("✅ Config sync disabled. The repository itself is unchanged.")
+		return nil
+	}
+
+	var cloned bool
+	if configSyncRepo != "" {
+		var err error
+		if cloned, err = setupConfigSync(dir, configSyncRepo); err != nil {
+			return err
+		}
+	} else if !configSyncEnabled() {
+		return fmt.Errorf("config sync is not set up\nRun 'git-keys config sync --repo <url>' first")
+	}
+
+	result, err := syncConfig(configPath, configSyncPrefer, cloned)
+	if err != nil {
+		return err
+	}
+	if configSyncRepo != "" {
+		recordConfigHistory("config-sync", "Syncing configuration through "+configSyncRepo, configPath)
+	}
+
+	switch result {
+	case "pushed":
+		fmt.Println("✅ Configuration pushed.")
+	case "pulled":
+		fmt.Println("✅ Configuration pulled.")
+		fmt.Println("\nRun 'git-keys plan' to see what the change will do.")
+	default:
+		fmt.Println("✅ Configuration is up to date.")
+	}
+	return nil
+}
+
+// setupConfigSync clones the sync repository, or checks that the existing
+// clone is of the same repository. Reports whether it cloned.
+func setupConfigSync(dir, repo string) (bool, error) {
+	if configSyncEnabled() {
+		origin, _ := gitOutput(dir, "remote", "get-url", "origin")
+		if origin == repo {
+			return false, nil
+		}
+		return false, fmt.Errorf("already syncing with %s\nRun 'git-keys config sync --disable' first", origin)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dir), 0700); err != nil {
+		return false, fmt.Errorf("failed to create directory: %w", err)
+	}
+	fmt.Printf("Cloning %s...\n", repo)
+	if _, err := syncGit("", "clone", "--quiet", repo, dir); err != nil {
+		return false, fmt.Errorf("failed to clone %s: %w", repo, err)
+	}
+
+	// Commits need an identity, even where git has no global one
+	if email, _ := gitOutput(dir, "config", "user.email"); email == "" {
+		host, _ := os.Hostname()
+		gitOutput(dir, "config", "user.name", "git-keys")
+		gitOutput(dir, "config", "user.email", "git-keys@"+host)
+	}
+	fmt.Printf("✓ Cloned to %s\n", dir)
+	return true, nil
+}
+
+// syncConfig pulls the sync repository and reconciles it with the local
+// configuration: local changes are committed and pushed, remote changes are
+// copied into place. Changes on both sides are a conflict unless prefer
+// names the side that wins; right after cloning, a configuration in the
+// repository counts as a remote change. Returns "pushed", "pulled" or "".
+func syncConfig(configPath, prefer string, cloned bool) (string, error) {
+	dir := configSyncDir()
+	base := filepath.Base(configPath)
+
+	// The clone holds the configuration as it was last synced
+	local := configSyncDigest(filepath.Dir(configPath), base)
+	localChanged := local != "" && local != configSyncDigest(dir, base)
+
+	before, _ := gitOutput(dir, "rev-parse", "HEAD")
+	if heads, err := syncGit(dir, "ls-remote", "--heads", "origin"); err != nil {
+		return "", fmt.Errorf("failed to reach the sync repository: %w", err)
+	} else if heads != "" {
+		if _, err := syncGit(dir, "pull", "--quiet", "--ff-only"); err != nil {
+			return "", fmt.Errorf("failed to pull the sync repository: %w", err)
+		}
+	}
+	after, _ := gitOutput(dir, "rev-parse", "HEAD")
+	remoteChanged := before != after || (cloned && configSyncDigest(dir, base) != "")
+
+	if localChanged && remoteChanged && prefer == "" {
+		return "", fmt.Errorf("the configuration changed both here and in the sync repository\n" +
+			"Run 'git-keys config sync --prefer local' to push this one, or --prefer remote to take the repository's")
+	}
+
+	switch {
+	case localChanged && prefer != "remote":
+		if err := copyConfigFiles(filepath.Dir(configPath), dir, base); err != nil {
+			return "", err
+		}
+		host, _ := os.Hostname()
+		if _, err := syncGit(dir, "add", "--all"); err != nil {
+			return "", fmt.Errorf("failed to stage configuration: %w", err)
+		}
+		if _, err := syncGit(dir, "commit", "--quiet", "-m", fmt.Sprintf("Update %s from %s", base, host)); err != nil {
+			return "", fmt.Errorf("failed to commit configuration: %w", err)
+		}
+		if _, err := syncGit(dir, "push", "--quiet", "origin", "HEAD"); err != nil {
+			return "", fmt.Errorf("failed to push configuration: %w", err)
+		}
+		fmt.Printf("✓ Pushed %s\n", base)
+		return "pushed", nil
+
+	case remoteChanged || localChanged:
+		if configSyncDigest(dir, base) == "" {
+			return "", nil // Nothing to pull yet
+		}
+		if err := copyConfigFiles(dir, filepath.Dir(configPath), base); err != nil {
+			return "", err
+		}
+		fmt.Printf("✓ Pulled %s\n", base)
+		return "pulled", nil
+	}
+	return "", nil
+}
+
+// pullConfigBeforeCommand syncs the configuration before a command that acts
+// on it, so it works on the latest one. Failures only warn.
+func pullConfigBeforeCommand(configPath string) {
+	if _, err := syncConfig(configPath, "", false); err != nil {
+		logger.Warn("Config sync failed: %v", err)
+	}
+}
+
+// pushConfigAfterCommand syncs the configuration after a command that changed
+// it. Failures only warn; 'git-keys config sync' retries.
+func pushConfigAfterCommand(configPath string) {
+	if configSyncSkip || !configSyncEnabled() {
+		return
+	}
+	if configSyncDigest(filepath.Dir(configPath), filepath.Base(configPath)) == configSyncBefore {
+		return
+	}
+	if _, err := syncConfig(configPath, "", false); err != nil {
+		logger.Warn("Config sync failed: %v", err)
+		fmt.Fprintln(os.Stderr, "Run 'git-keys config sync' to retry.")
+	}
+}
+
+// configSyncDir returns the local clone of the sync repository
+func configSyncDir() string {
+	return filepath.Join(profile.Dir(), "config-repo")
+}
+
+// configSyncEnabled reports whether the configuration is synced
+func configSyncEnabled() bool {
+	dir := configSyncDir()
+	if dir == "" {
+		return false
+	}
+	_, err := os.Stat(filepath.Join(dir, ".git"))
+	return err == nil
+}
+
+// configSyncNames returns the files a configuration named base is stored
+// in: the plaintext file, or the encrypted file and its recipients
+func configSyncNames(base string) []string {
+	return []string{base, config.EncryptedPath(base), config.RecipientsPath(base)}
+}
+
+// configSyncDigest returns a digest of the configuration files named base in
+// dir, or "" when there are none
+func configSyncDigest(dir, base string) string {
+	h := sha256.New()
+	found := false
+	for _, name := range configSyncNames(base) {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		found = true
+		fmt.Fprintf(h, "%s %d\n", name, len(data))
+		h.Write(data)
+	}
+	if !found {
+		return ""
+	}
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+// copyConfigFiles makes the configuration files named base in to the same as
+// in from
+func copyConfigFiles(from, to, base string) error {
+	for _, name := range configSyncNames(base) {
+		src, dst := filepath.Join(from, name), filepath.Join(to, name)
+		data, err := os.ReadFile(src)
+		if os.IsNotExist(err) {
+			if err := os.Remove(dst); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to remove %s: %w", dst, err)
+			}
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", src, err)
+		}
+		if err := writeFileAtomic(dst, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeFileAtomic replaces path with data, readable by the owner only
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".git-keys-sync-*")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0600)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// syncGit runs git in dir and returns its output; errors include what git
+// printed
+func syncGit(dir string, args ...string) (string, error) {
+	if dir != "" {
+		args = append([]string{"-C", dir}, args...)
+	}
+	output, err := exec.Command("git", args...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("%w\nOutput: %s", err, strings.TrimSpace(string(output)))
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// recordConfigSyncState remembers the stored configuration before a command
+// runs
+func recordConfigSyncState(cmd *cobra.Command, configPath string) {
+	if !configSyncEnabled() {
+		return
+	}
+	// Plan and apply act on the latest configuration
+	if cmd == planCmd || cmd == applyCmd {
+		pullConfigBeforeCommand(configPath)
+	}
+	configSyncBefore = configSyncDigest(filepath.Dir(configPath), filepath.Base(configPath))
+}
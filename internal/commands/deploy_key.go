@@ -0,0 +1,741 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kunlu/git-keys/internal/api"
+	"github.com/kunlu/git-keys/internal/config"
+	"github.com/kunlu/git-keys/internal/history"
+	"github.com/kunlu/git-keys/internal/journal"
+	"github.com/kunlu/git-keys/internal/logger"
+	"github.com/kunlu/git-keys/internal/prompt"
+	"github.com/kunlu/git-keys/internal/sshconfig"
+	"github.com/kunlu/git-keys/internal/sshkey"
+	"github.com/spf13/cobra"
+)
+
+var (
+	deployKeyPersona   string
+	deployKeyPlatform  string
+	deployKeyAccount   string
+	deployKeyReadWrite bool
+	deployKeyDue       bool
+	deployKeyWithin    string
+	deployKeyYes       bool
+)
+
+var deployKeyCmd = &cobra.Command{
+	Use:   "deploy-key",
+	Short: "Manage repository deploy keys",
+	Long: `Manage SSH deploy keys, which give this machine access to a single
+repository, e.g. a build server that only needs to clone one project.
+
+Each deploy key is listed under deploy_keys in the config with the repository,
+the persona whose API token manages it, and whether it may push. git-keys
+generates a key per repository, adds it to the repository (read-only unless
+--read-write), and writes an SSH host alias for it:
+
+  git clone git@github.com.deploy-acme-api:acme/api.git
+
+Deploy keys expire like user keys (key_expiration of the persona) and are
+rotated by 'git-keys rotate --due' on the same schedule.
+
+Subcommands:
+  add     - Add a deploy key to a repository
+  list    - Show configured deploy keys
+  rotate  - Replace deploy keys with new ones
+  remove  - Delete a deploy key from its repository
+
+Examples:
+  git-keys deploy-key add acme/api --persona work --platform github
+  git-keys deploy-key add infra/charts --persona work --platform gitlab --read-write
+  git-keys deploy-key rotate --due
+  git-keys deploy-key remove acme/api
+`,
+}
+
+var deployKeyAddCmd = &cobra.Command{
+	Use:          "add <repo>",
+	Short:        "Generate a deploy key and add it to a repository",
+	Args:         cobra.ExactArgs(1),
+	RunE:         runDeployKeyAdd,
+	SilenceUsage: true,
+}
+
+var deployKeyListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List configured deploy keys",
+	RunE:  runDeployKeyList,
+}
+
+var deployKeyRotateCmd = &cobra.Command{
+	Use:               "rotate [repo]",
+	Short:             "Replace deploy keys with new ones",
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeDeployKeyRepos,
+	RunE:              runDeployKeyRotate,
+	SilenceUsage:      true,
+}
+
+var deployKeyRemoveCmd = &cobra.Command{
+	Use:               "remove <repo>",
+	Short:             "Delete a deploy key from its repository and the config",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeDeployKeyRepos,
+	RunE:              runDeployKeyRemove,
+	SilenceUsage:      true,
+}
+
+func init() {
+	deployKeyAddCmd.Flags().StringVar(&deployKeyPersona, "persona", "", "Persona whose API token manages the key (required for new entries)")
+	deployKeyAddCmd.Flags().StringVar(&deployKeyPlatform, "platform", "", "github or gitlab (required for new entries)")
+	deployKeyAddCmd.RegisterFlagCompletionFunc("persona", completePersonaFlag)
+	deployKeyAddCmd.RegisterFlagCompletionFunc("platform", completePlatformTypes)
+	deployKeyAddCmd.Flags().StringVar(&deployKeyAccount, "account", "", "Platform account, when the persona has several")
+	deployKeyAddCmd.Flags().BoolVar(&deployKeyReadWrite, "read-write", false, "Allow pushes with the key")
+
+	deployKeyRotateCmd.Flags().BoolVar(&deployKeyDue, "due", false, "Only rotate deploy keys that expire within the due window")
+	deployKeyRotateCmd.Flags().StringVar(&deployKeyWithin, "within", "", "Due window for --due, e.g. 14d or 72h (default defaults.rotate_within, or 14d)")
+	deployKeyRotateCmd.Flags().BoolVarP(&deployKeyYes, "yes", "y", false, "Skip confirmation prompt")
+	deployKeyRemoveCmd.Flags().BoolVarP(&deployKeyYes, "yes", "y", false, "Skip confirmation prompt")
+
+	deployKeyCmd.AddCommand(deployKeyAddCmd)
+	deployKeyCmd.AddCommand(deployKeyListCmd)
+	deployKeyCmd.AddCommand(deployKeyRotateCmd)
+	deployKeyCmd.AddCommand(deployKeyRemoveCmd)
+	rootCmd.AddCommand(deployKeyCmd)
+}
+
+// loadDeployKeyConfig loads the config and its path
+func loadDeployKeyConfig() (*config.Manager, *config.Config, string, error) {
+	configPath := cfgFile
+	if configPath == "" {
+		configPath = config.GetDefaultConfigPath()
+	}
+
+	mgr := config.NewManager(configPath)
+	if !mgr.Exists() {
+		return nil, nil, "", fmt.Errorf("configuration file not found at %s\nRun 'git-keys init' first", configPath)
+	}
+	cfg, err := mgr.Load()
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("failed to load config: %w", err)
+	}
+	return mgr, cfg, configPath, nil
+}
+
+func runDeployKeyAdd(cmd *cobra.Command, args []string) (err error) {
+	mgr, cfg, configPath, err := loadDeployKeyConfig()
+	if err != nil {
+		return err
+	}
+	repo := strings.Trim(args[0], "/")
+
+	dk := cfg.FindDeployKey(repo, config.PlatformType(deployKeyPlatform))
+	if dk == nil {
+		if deployKeyPersona == "" || deployKeyPlatform == "" {
+			return fmt.Errorf("deploy key %s is not configured; give --persona and --platform", repo)
+		}
+		cfg.DeployKeys = append(cfg.DeployKeys, config.DeployKey{
+			Repo:      repo,
+			Persona:   deployKeyPersona,
+			Platform:  config.PlatformType(deployKeyPlatform),
+			Account:   deployKeyAccount,
+			ReadWrite: deployKeyReadWrite,
+		})
+		dk = &cfg.DeployKeys[len(cfg.DeployKeys)-1]
+		if err := cfg.Validate(); err != nil {
+			return err
+		}
+	} else if key := dk.GetActiveKey(); key != nil {
+		return fmt.Errorf("%s already has deploy key %s; use 'git-keys deploy-key rotate %s' to replace it", repo, key.Fingerprint, repo)
+	}
+
+	client, err := deployKeyClient(cfg, dk)
+	if err != nil {
+		return err
+	}
+
+	j, err := journal.Begin("", "deploy-key")
+	if err != nil {
+		return fmt.Errorf("failed to start journal: %w", err)
+	}
+	defer func() {
+		finishJournal(j, err)
+	}()
+	if err := j.BackupFile(config.StoragePath(configPath)); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	if err := provisionDeployKey(ctx, cfg, dk, client, j); err != nil {
+		return err
+	}
+
+	if err := mgr.Save(cfg); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+
+	histMgr := history.NewManager("")
+	if err := histMgr.Record(history.Entry{
+		Action:  "deploy-key",
+		Summary: fmt.Sprintf("Added deploy key to %s", dk.Repo),
+		Details: map[string]string{"repo": dk.Repo, "platform": string(dk.Platform), "read_write": fmt.Sprintf("%t", dk.ReadWrite)},
+	}); err != nil {
+		logger.Warn("Failed to record history: %v", err)
+	}
+
+	alias, _ := deployKeyAlias(cfg, dk)
+	fmt.Printf("\n✅ Deploy key added to %s (%s)\n", dk.Repo, deployKeyAccess(dk))
+	fmt.Printf("   git clone git@%s:%s.git\n", alias, dk.Repo)
+	return nil
+}
+
+func runDeployKeyList(cmd *cobra.Command, args []string) error {
+	_, cfg, _, err := loadDeployKeyConfig()
+	if err != nil {
+		return err
+	}
+
+	if len(cfg.DeployKeys) == 0 {
+		fmt.Println("No deploy keys configured. Add one with 'git-keys deploy-key add <repo>'.")
+		return nil
+	}
+
+	printHeader("\n🔑 Deploy Keys")
+	for i := range cfg.DeployKeys {
+		dk := &cfg.DeployKeys[i]
+		fmt.Printf("\n  %s (%s, %s, persona %s)\n", dk.Repo, dk.Platform, deployKeyAccess(dk), dk.Persona)
+
+		key := dk.GetActiveKey()
+		if key == nil {
+			fmt.Printf("    ⊘ No key yet; run 'git-keys deploy-key add %s'\n", dk.Repo)
+			continue
+		}
+		alias, _ := deployKeyAlias(cfg, dk)
+		fmt.Printf("    Host: %s\n", alias)
+		fmt.Printf("    Key: %s (%s)\n", key.Fingerprint, key.LocalPath)
+		if expiresAt, known := deployKeyExpiry(cfg, dk, key); known {
+			icon := "✓"
+			if expiresAt.Before(time.Now()) {
+				icon = "⚠️ "
+			}
+			fmt.Printf("    %s Expires: %s\n", icon, expiresAt.Format("2006-01-02"))
+		}
+	}
+	fmt.Println()
+	return nil
+}
+
+func runDeployKeyRotate(cmd *cobra.Command, args []string) (err error) {
+	mgr, cfg, configPath, err := loadDeployKeyConfig()
+	if err != nil {
+		return err
+	}
+
+	if deployKeyWithin != "" && !deployKeyDue {
+		return fmt.Errorf("--within requires --due")
+	}
+	window, err := rotateDueWindow(cfg, deployKeyWithin)
+	if err != nil {
+		return err
+	}
+
+	repo := ""
+	if len(args) > 0 {
+		repo = strings.Trim(args[0], "/")
+		if cfg.FindDeployKey(repo, "") == nil {
+			return fmt.Errorf("deploy key %s not found", repo)
+		}
+	}
+
+	var due []*config.DeployKey
+	for i := range cfg.DeployKeys {
+		dk := &cfg.DeployKeys[i]
+		if repo != "" && !strings.EqualFold(dk.Repo, repo) {
+			continue
+		}
+		if dk.GetActiveKey() == nil || (deployKeyDue && !deployKeyIsDue(cfg, dk, window)) {
+			continue
+		}
+		due = append(due, dk)
+	}
+
+	if len(due) == 0 {
+		if deployKeyDue {
+			fmt.Printf("No deploy keys expire within %s.\n", formatDueWindow(window))
+		} else {
+			fmt.Println("No deploy keys to rotate.")
+		}
+		return nil
+	}
+
+	printHeader("\n🔄 Deploy Keys to Rotate:")
+	for _, dk := range due {
+		fmt.Printf("  • %s (%s): %s\n", dk.Repo, dk.Platform, dk.GetActiveKey().Fingerprint)
+	}
+	fmt.Println()
+
+	if !deployKeyYes {
+		if !prompt.Confirm("Rotate these deploy keys?") {
+			fmt.Println("Rotation cancelled.")
+			return nil
+		}
+	}
+
+	j, err := journal.Begin("", "rotate")
+	if err != nil {
+		return fmt.Errorf("failed to start journal: %w", err)
+	}
+	defer func() {
+		finishJournal(j, err)
+	}()
+
+	ctx := context.Background()
+	failed := 0
+	for _, dk := range due {
+		fmt.Printf("\n  Processing %s...\n", dk.Repo)
+		if err := rotateDeployKey(ctx, cfg, dk, j, os.Stdout); err != nil {
+			fmt.Printf("    ❌ Failed: %v\n", err)
+			failed++
+			continue
+		}
+		fmt.Println("    ✓ Rotation complete")
+	}
+
+	if failed < len(due) {
+		journalWarn(j.BackupFile(config.StoragePath(configPath)))
+		if err := mgr.Save(cfg); err != nil {
+			return fmt.Errorf("failed to save configuration: %w", err)
+		}
+	}
+
+	fmt.Printf("\n✅ Deploy key rotation: %d succeeded, %d failed\n", len(due)-failed, failed)
+	if failed > 0 {
+		return fmt.Errorf("%d deploy key rotation(s) failed", failed)
+	}
+	return nil
+}
+
+func runDeployKeyRemove(cmd *cobra.Command, args []string) (err error) {
+	mgr, cfg, configPath, err := loadDeployKeyConfig()
+	if err != nil {
+		return err
+	}
+	repo := strings.Trim(args[0], "/")
+
+	idx := -1
+	for i := range cfg.DeployKeys {
+		if strings.EqualFold(cfg.DeployKeys[i].Repo, repo) {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		return fmt.Errorf("deploy key %s not found", repo)
+	}
+	dk := &cfg.DeployKeys[idx]
+
+	if !deployKeyYes {
+		if !prompt.Confirm(fmt.Sprintf("Delete the deploy key of %s from %s and this machine?", dk.Repo, dk.Platform)) {
+			fmt.Println("Cancelled.")
+			return nil
+		}
+	}
+
+	j, err := journal.Begin("", "deploy-key")
+	if err != nil {
+		return fmt.Errorf("failed to start journal: %w", err)
+	}
+	defer func() {
+		finishJournal(j, err)
+	}()
+
+	ctx := context.Background()
+	keyMgr := sshkey.NewManager(cfg.Defaults.GetKeysDir())
+	if key := dk.GetActiveKey(); key != nil {
+		if key.RemoteID != "" {
+			if err := deleteDeployKey(ctx, cfg, dk, key, j); err != nil {
+				return fmt.Errorf("failed to delete deploy key from %s: %w", dk.Repo, err)
+			}
+			fmt.Printf("✓ Deleted deploy key from %s\n", dk.Repo)
+		}
+
+		item, err := keyMgr.TrashKey(key.LocalPath)
+		if err != nil {
+			logger.Warn("Failed to move %s to the trash: %v", key.LocalPath, err)
+		} else if item != nil {
+			journalWarn(j.KeyTrashed(filepath.Join(keyMgr.KeysDir(), key.LocalPath), item.ID))
+			fmt.Printf("✓ Moved %s to the trash\n", key.LocalPath)
+		}
+	}
+
+	sshMgr := sshConfigManager(cfg)
+	journalSSHConfig(j, sshMgr)
+	if err := sshMgr.RemoveEntry(deployKeyBlockID(dk)); err != nil {
+		logger.Warn("Failed to remove SSH config entry: %v", err)
+	}
+
+	journalWarn(j.BackupFile(config.StoragePath(configPath)))
+	cfg.DeployKeys = append(cfg.DeployKeys[:idx], cfg.DeployKeys[idx+1:]...)
+	if err := mgr.Save(cfg); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+
+	histMgr := history.NewManager("")
+	if err := histMgr.Record(history.Entry{
+		Action:  "deploy-key",
+		Summary: fmt.Sprintf("Removed deploy key of %s", repo),
+		Details: map[string]string{"repo": repo},
+	}); err != nil {
+		logger.Warn("Failed to record history: %v", err)
+	}
+
+	fmt.Printf("\n✅ Removed deploy key of %s\n", repo)
+	return nil
+}
+
+// deployKeyAccess describes what a deploy key may do
+func deployKeyAccess(dk *config.DeployKey) string {
+	if dk.ReadWrite {
+		return "read-write"
+	}
+	return "read-only"
+}
+
+// deployKeySlug turns owner/name into owner-name for host aliases and file names
+func deployKeySlug(repo string) string {
+	return sanitizeHostname(strings.ReplaceAll(strings.ToLower(repo), "/", "-"))
+}
+
+// deployKeyBlockID returns the ID of a deploy key's managed SSH config block.
+// The git-keys- prefix keeps apply and sync from treating it as a stale
+// persona block.
+func deployKeyBlockID(dk *config.DeployKey) string {
+	return fmt.Sprintf("git-keys-deploy-%s-%s", dk.Platform, deployKeySlug(dk.Repo))
+}
+
+// deployKeyAlias returns the SSH host alias of a deploy key (e.g.
+// github.com.deploy-acme-api) and the real hostname behind it
+func deployKeyAlias(cfg *config.Config, dk *config.DeployKey) (string, string) {
+	hostname := "github.com"
+	if persona, plat, err := cfg.DeployKeyPlatform(dk); err == nil {
+		_, hostname = sshHostAlias(persona, plat)
+	} else if dk.Platform == config.PlatformGitLab {
+		hostname = "gitlab.com"
+	}
+	return fmt.Sprintf("%s.deploy-%s", hostname, deployKeySlug(dk.Repo)), hostname
+}
+
+// deployKeyExpiry returns when a deploy key expires, using the key settings
+// of the platform that manages it
+func deployKeyExpiry(cfg *config.Config, dk *config.DeployKey, key *config.KeyConfig) (time.Time, bool) {
+	persona, plat, err := cfg.DeployKeyPlatform(dk)
+	if err != nil {
+		return key.ExpiresAt, !key.ExpiresAt.IsZero()
+	}
+	return cfg.KeyExpiry(persona, plat, key)
+}
+
+// deployKeyIsDue reports whether a deploy key expires within window
+func deployKeyIsDue(cfg *config.Config, dk *config.DeployKey, window time.Duration) bool {
+	key := dk.GetActiveKey()
+	if key == nil {
+		return false
+	}
+	expiresAt, known := deployKeyExpiry(cfg, dk, key)
+	return known && !expiresAt.After(time.Now().Add(window))
+}
+
+// deployKeyClient creates an API client with the token of the platform that
+// manages a deploy key
+func deployKeyClient(cfg *config.Config, dk *config.DeployKey) (api.PlatformClient, error) {
+	_, plat, err := cfg.DeployKeyPlatform(dk)
+	if err != nil {
+		return nil, err
+	}
+	token, _, err := lookupToken(plat, loadTokensFromEnv())
+	if err != nil {
+		return nil, err
+	}
+	return newPlatformClientWithToken(plat, token)
+}
+
+// generateDeployKey generates a key pair for a deploy key under fileName
+func generateDeployKey(cfg *config.Config, keyMgr *sshkey.Manager, dk *config.DeployKey, fileName string) (*config.KeyConfig, error) {
+	persona, plat, err := cfg.DeployKeyPlatform(dk)
+	if err != nil {
+		return nil, err
+	}
+	settings := cfg.ResolveKeySettings(persona, plat)
+	createdAt := time.Now()
+
+	comment := fmt.Sprintf("git-keys:deploy:%s:%s:%s:%s", dk.Platform, dk.Repo, cfg.Machine.Name, createdAt.Format("2006-01-02"))
+	if err := keyMgr.GenerateKey(settings.Type, comment, fileName); err != nil {
+		return nil, fmt.Errorf("failed to generate deploy key: %w", err)
+	}
+
+	fingerprint, err := keyMgr.GetFingerprint(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fingerprint: %w", err)
+	}
+
+	return &config.KeyConfig{
+		Type:        settings.Type,
+		CreatedAt:   createdAt,
+		ExpiresAt:   settings.ExpiresAt(createdAt),
+		Fingerprint: fingerprint,
+		LocalPath:   fileName,
+		Status:      config.KeyStatusActive,
+	}, nil
+}
+
+// deployKeyFileName returns the key file name of a deploy key
+func deployKeyFileName(cfg *config.Config, dk *config.DeployKey) string {
+	keyType := cfg.Defaults.KeyType
+	if persona, plat, err := cfg.DeployKeyPlatform(dk); err == nil {
+		keyType = cfg.ResolveKeySettings(persona, plat).Type
+	}
+	return fmt.Sprintf("git-keys-deploy-%s-%s-%s", dk.Platform, deployKeySlug(dk.Repo), keyType)
+}
+
+// deployKeyTitle returns the title a deploy key is added under
+func deployKeyTitle(cfg *config.Config, key *config.KeyConfig) string {
+	title := fmt.Sprintf("%s (git-keys deploy %s)", cfg.Machine.Name, key.CreatedAt.Format("2006-01-02"))
+	if cfg.Defaults.DisableTitleMetadata {
+		return title
+	}
+	return sshkey.AppendTitleMeta(title, sshkey.TitleMeta{Machine: cfg.Machine.Name, Expires: key.ExpiresAt})
+}
+
+// provisionDeployKey generates a deploy key, adds it to its repository and
+// writes its SSH host alias
+func provisionDeployKey(ctx context.Context, cfg *config.Config, dk *config.DeployKey, client api.PlatformClient, j *journal.Journal) error {
+	keyMgr := sshkey.NewManager(cfg.Defaults.GetKeysDir())
+
+	key, err := generateDeployKey(cfg, keyMgr, dk, deployKeyFileName(cfg, dk))
+	if err != nil {
+		return err
+	}
+	if err := j.KeyCreated(filepath.Join(keyMgr.KeysDir(), key.LocalPath)); err != nil {
+		return err
+	}
+	fmt.Printf("✓ Generated deploy key: %s\n", key.LocalPath)
+
+	publicKey, err := keyMgr.GetPublicKey(key.LocalPath)
+	if err != nil {
+		return err
+	}
+	remoteID, err := client.AddDeployKey(ctx, dk.Repo, deployKeyTitle(cfg, key), publicKey, dk.ReadWrite, key.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to add deploy key to %s: %w", dk.Repo, err)
+	}
+	key.RemoteID = remoteID
+	journalWarn(j.DeployKeyUploaded(dk.Persona, string(dk.Platform), deployKeyOwnerAccount(cfg, dk), dk.Repo, remoteID, key.Fingerprint))
+	fmt.Printf("✓ Added deploy key to %s\n", dk.Repo)
+
+	if err := writeDeployKeySSHConfig(cfg, dk, keyMgr, key, j); err != nil {
+		return err
+	}
+
+	dk.Keys = append(dk.Keys, *key)
+	return nil
+}
+
+// deployKeyOwnerAccount returns the account of the platform that manages a
+// deploy key, for journal steps
+func deployKeyOwnerAccount(cfg *config.Config, dk *config.DeployKey) string {
+	if _, plat, err := cfg.DeployKeyPlatform(dk); err == nil {
+		return plat.Account
+	}
+	return dk.Account
+}
+
+// writeDeployKeySSHConfig writes the managed SSH config block of a deploy key
+func writeDeployKeySSHConfig(cfg *config.Config, dk *config.DeployKey, keyMgr *sshkey.Manager, key *config.KeyConfig, j *journal.Journal) error {
+	sshMgr := sshConfigManager(cfg)
+	alias, hostname := deployKeyAlias(cfg, dk)
+
+	sshConfigMu.Lock()
+	defer sshConfigMu.Unlock()
+	journalSSHConfig(j, sshMgr)
+
+	entry := sshconfig.Entry{
+		Host:         alias,
+		HostName:     hostname,
+		User:         "git",
+		IdentityFile: keyMgr.IdentityFilePath(key.LocalPath),
+		Extra: map[string]string{
+			"IdentitiesOnly": "yes",
+		},
+	}
+	// Deploy keys reach the host the way the platform does
+	if _, plat, err := cfg.DeployKeyPlatform(dk); err == nil {
+		for option, value := range platformSSHOptions(cfg, plat) {
+			entry.Extra[option] = value
+		}
+	}
+	if err := sshMgr.AddOrUpdateEntry(deployKeyBlockID(dk), []sshconfig.Entry{entry}); err != nil {
+		return fmt.Errorf("failed to update SSH config: %w", err)
+	}
+	fmt.Printf("✓ Updated SSH config: Host %s\n", alias)
+	return nil
+}
+
+// deleteDeployKey deletes a deploy key from its repository, journaling it
+// so undo can add it again
+func deleteDeployKey(ctx context.Context, cfg *config.Config, dk *config.DeployKey, key *config.KeyConfig, j *journal.Journal) error {
+	client, err := deployKeyClient(cfg, dk)
+	if err != nil {
+		return err
+	}
+	if err := client.DeleteDeployKey(ctx, dk.Repo, key.RemoteID); err != nil {
+		return err
+	}
+
+	publicKey, _ := sshkey.NewManager(cfg.Defaults.GetKeysDir()).GetPublicKey(key.LocalPath)
+	journalWarn(j.DeployKeyDeleted(dk.Persona, string(dk.Platform), deployKeyOwnerAccount(cfg, dk), dk.Repo, key.RemoteID,
+		key.Fingerprint, publicKey, deployKeyTitle(cfg, key)))
+	return nil
+}
+
+// rotateDeployKey replaces a deploy key: the new key is added to the
+// repository and the SSH alias before the old key is deleted and archived
+func rotateDeployKey(ctx context.Context, cfg *config.Config, dk *config.DeployKey, j *journal.Journal, out io.Writer) error {
+	keysDir := cfg.Defaults.GetKeysDir()
+	keyMgr := sshkey.NewManager(keysDir)
+	oldKey := *dk.GetActiveKey()
+
+	client, err := deployKeyClient(cfg, dk)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(out, "    → Generating new deploy key...")
+	fileName := deployKeyFileName(cfg, dk)
+	newKey, err := generateDeployKey(cfg, keyMgr, dk, fileName+"-new")
+	if err != nil {
+		return err
+	}
+	publicKey, err := keyMgr.GetPublicKey(newKey.LocalPath)
+	if err != nil {
+		keyMgr.DeleteKey(newKey.LocalPath)
+		return err
+	}
+
+	fmt.Fprintln(out, "    → Adding new key to repository...")
+	remoteID, err := client.AddDeployKey(ctx, dk.Repo, deployKeyTitle(cfg, newKey), publicKey, dk.ReadWrite, newKey.ExpiresAt)
+	if err != nil {
+		keyMgr.DeleteKey(newKey.LocalPath)
+		return fmt.Errorf("failed to add deploy key: %w", err)
+	}
+	newKey.RemoteID = remoteID
+	journalWarn(j.DeployKeyUploaded(dk.Persona, string(dk.Platform), deployKeyOwnerAccount(cfg, dk), dk.Repo, remoteID, newKey.Fingerprint))
+
+	if oldKey.RemoteID != "" {
+		fmt.Fprintln(out, "    → Removing old key from repository...")
+		if err := deleteDeployKey(ctx, cfg, dk, &oldKey, j); err != nil {
+			logger.Warn("Failed to delete old deploy key: %v", err)
+			fmt.Fprintf(out, "    ⚠️  Warning: Old key %s not removed from %s; remove it manually\n", oldKey.RemoteID, dk.Repo)
+		}
+	}
+
+	fmt.Fprintln(out, "    → Archiving old key...")
+	if archived, err := archiveOldKey(oldKey.LocalPath, keysDir); err != nil {
+		logger.Warn("Failed to archive old deploy key: %v", err)
+		fmt.Fprintln(out, "    ⚠️  Warning: Could not archive old key")
+	} else {
+		journalWarn(j.KeyMoved(filepath.Join(keysDir, oldKey.LocalPath), archived))
+	}
+
+	// The new key takes the old key's file name
+	if err := os.Rename(filepath.Join(keysDir, newKey.LocalPath), filepath.Join(keysDir, fileName)); err != nil {
+		logger.Warn("Failed to rename new deploy key: %v", err)
+	} else {
+		os.Rename(filepath.Join(keysDir, newKey.LocalPath+".pub"), filepath.Join(keysDir, fileName+".pub"))
+		newKey.LocalPath = fileName
+	}
+	journalWarn(j.KeyCreated(filepath.Join(keysDir, newKey.LocalPath)))
+
+	if err := writeDeployKeySSHConfig(cfg, dk, keyMgr, newKey, j); err != nil {
+		return err
+	}
+
+	for i := range dk.Keys {
+		if dk.Keys[i].Fingerprint == oldKey.Fingerprint {
+			dk.Keys[i] = *newKey
+		}
+	}
+	return nil
+}
+
+// rollbackDeployKey deletes a deploy key an operation added
+func rollbackDeployKey(ctx context.Context, configPath string, step journal.Step, envTokens map[string]string) error {
+	_, plat, err := journalStepPlatform(configPath, step)
+	if err != nil {
+		return err
+	}
+
+	token, _, err := lookupToken(plat, envTokens)
+	if err != nil {
+		return err
+	}
+	client, err := newPlatformClientWithToken(plat, token)
+	if err != nil {
+		return err
+	}
+	return client.DeleteDeployKey(ctx, step.Repo, step.RemoteID)
+}
+
+// readdDeletedDeployKey adds a deploy key an operation deleted back to its
+// repository, and records its new remote ID on the deploy key
+func readdDeletedDeployKey(ctx context.Context, configPath string, step journal.Step, envTokens map[string]string) error {
+	cfg, plat, err := journalStepPlatform(configPath, step)
+	if err != nil {
+		return err
+	}
+
+	token, _, err := lookupToken(plat, envTokens)
+	if err != nil {
+		return err
+	}
+	client, err := newPlatformClientWithToken(plat, token)
+	if err != nil {
+		return err
+	}
+
+	dk := cfg.FindDeployKey(step.Repo, config.PlatformType(step.Platform))
+	var key *config.KeyConfig
+	readWrite := false
+	if dk != nil {
+		readWrite = dk.ReadWrite
+		for i := range dk.Keys {
+			if dk.Keys[i].Fingerprint == step.Fingerprint {
+				key = &dk.Keys[i]
+			}
+		}
+	}
+
+	var expiresAt time.Time
+	if key != nil {
+		expiresAt = key.ExpiresAt
+	}
+	remoteID, err := client.AddDeployKey(ctx, step.Repo, step.Title, step.PublicKey, readWrite, expiresAt)
+	if err != nil {
+		return fmt.Errorf("API error: %w", err)
+	}
+	if key == nil {
+		return nil
+	}
+	key.RemoteID = remoteID
+	if err := config.NewManager(configPath).Save(cfg); err != nil {
+		return fmt.Errorf("added as %s, but failed to save config: %w", remoteID, err)
+	}
+	return nil
+}
@@ -0,0 +1,534 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kunlu/git-keys/internal/api"
+	"github.com/kunlu/git-keys/internal/config"
+	"github.com/kunlu/git-keys/internal/policy"
+	"github.com/kunlu/git-keys/internal/sshconfig"
+	"github.com/kunlu/git-keys/internal/sshkey"
+	"github.com/spf13/cobra"
+)
+
+var (
+	doctorJSON    bool
+	doctorOffline bool
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the whole setup and list what to fix",
+	Long: `Run every health check git-keys knows and print a prioritized fix list.
+
+Checks, in order:
+  - config:    the configuration loads, conflicting SSH Host entries, repository bindings,
+               the machine name
+  - keys:      active key files exist, match their fingerprints and private keys,
+               have safe permissions
+  - remote:    active keys are registered on their platform under the recorded ID
+  - agent:     an SSH agent runs and holds the keys (or the identity agent socket exists)
+  - ssh:       'ssh -T' authenticates each SSH host alias as the configured account
+  - gitconfig: each gitdir has an includeIf applying the persona's email
+  - tokens:    API tokens are valid, unexpired and can manage SSH keys
+
+Errors come before warnings in the fix list, and each entry names the command
+that fixes it. Use --offline to skip the checks that contact the platforms
+(remote, ssh, tokens).
+
+Examples:
+  git-keys doctor
+  git-keys doctor --offline
+  git-keys doctor --json | jq '.findings[] | select(.severity == "error")'
+`,
+	Annotations:  map[string]string{structuredOutputAnnotation: structuredQuiet},
+	RunE:         runDoctor,
+	SilenceUsage: true,
+}
+
+func init() {
+	doctorCmd.Flags().BoolVar(&doctorJSON, "json", false, "Output as JSON")
+	doctorCmd.Flags().BoolVar(&doctorOffline, "offline", false, "Skip checks that contact the platforms")
+	rootCmd.AddCommand(doctorCmd)
+}
+
+const (
+	doctorError   = "error"
+	doctorWarning = "warning"
+)
+
+// doctorChecks are the checks in the order they run and are prioritized
+var doctorChecks = []struct {
+	Name  string
+	Title string
+}{
+	{"config", "Configuration"},
+	{"keys", "Key files"},
+	{"remote", "Remote keys"},
+	{"agent", "SSH agent"},
+	{"ssh", "SSH connectivity"},
+	{"gitconfig", "Git identity"},
+	{"tokens", "API tokens"},
+}
+
+// doctorFinding is a problem found by a check and the command that fixes it
+type doctorFinding struct {
+	Check    string `json:"check"`
+	Severity string `json:"severity"`
+	Subject  string `json:"subject,omitempty"` // persona/type@account, when specific to one
+	Problem  string `json:"problem"`
+	Fix      string `json:"fix"`
+}
+
+// doctorCheckResult is the outcome of one check
+type doctorCheckResult struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"` // ok, warning, error or skipped
+	Errors   int    `json:"errors"`
+	Warnings int    `json:"warnings"`
+}
+
+// doctorReport is the output of 'git-keys doctor --json'
+type doctorReport struct {
+	Config   string              `json:"config"`
+	Checks   []doctorCheckResult `json:"checks"`
+	Findings []doctorFinding     `json:"findings"` // Prioritized
+}
+
+// doctor collects findings while the checks run
+type doctor struct {
+	findings []doctorFinding
+}
+
+func (d *doctor) add(check, severity, subject, problem, fix string) {
+	d.findings = append(d.findings, doctorFinding{Check: check, Severity: severity, Subject: subject, Problem: problem, Fix: fix})
+}
+
+func runDoctor(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	configPath := cfgFile
+	if configPath == "" {
+		configPath = config.GetDefaultConfigPath()
+	}
+
+	d := &doctor{}
+	skipped := make(map[string]bool)
+
+	mgr := config.NewManager(configPath)
+	var cfg *config.Config
+	if !mgr.Exists() {
+		d.add("config", doctorError, "", "configuration file not found at "+configPath, "git-keys init")
+	} else if loaded, err := mgr.Load(); err != nil {
+		d.add("config", doctorError, "", fmt.Sprintf("configuration does not load: %v", err), "git-keys validate")
+	} else {
+		cfg = loaded
+	}
+
+	if cfg == nil {
+		// Nothing else can be checked without a configuration
+		for _, check := range doctorChecks[1:] {
+			skipped[check.Name] = true
+		}
+	} else {
+		keyMgr := sshkey.NewManager(cfg.Defaults.GetKeysDir())
+		sshMgr := sshConfigManager(cfg)
+
+		d.checkConfig(cfg, sshMgr, keyMgr)
+		d.checkKeys(cfg, keyMgr)
+		d.checkAgent(cfg, keyMgr)
+		d.checkGitConfig(cfg)
+		if doctorOffline {
+			skipped["remote"], skipped["ssh"], skipped["tokens"] = true, true, true
+		} else {
+			d.checkRemote(ctx, cfg)
+			d.checkSSH(cfg)
+			d.checkTokens(ctx, cfg)
+		}
+	}
+
+	report := doctorReport{Config: configPath, Findings: prioritizeFindings(d.findings)}
+	errorCount := 0
+	for _, check := range doctorChecks {
+		result := doctorCheckResult{Name: check.Name, Status: "ok"}
+		for _, f := range report.Findings {
+			if f.Check != check.Name {
+				continue
+			}
+			if f.Severity == doctorError {
+				result.Errors++
+			} else {
+				result.Warnings++
+			}
+		}
+		switch {
+		case skipped[check.Name]:
+			result.Status = "skipped"
+		case result.Errors > 0:
+			result.Status = doctorError
+		case result.Warnings > 0:
+			result.Status = doctorWarning
+		}
+		errorCount += result.Errors
+		report.Checks = append(report.Checks, result)
+	}
+	if report.Findings == nil {
+		report.Findings = []doctorFinding{}
+	}
+
+	if structuredOutput() {
+		if err := printStructured("DoctorReport", report); err != nil {
+			return err
+		}
+	} else if doctorJSON {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode JSON: %w", err)
+		}
+		fmt.Println(string(data))
+	} else {
+		printDoctorReport(report)
+	}
+
+	if errorCount > 0 {
+		return fmt.Errorf("doctor found %d error(s)", errorCount)
+	}
+	return nil
+}
+
+// prioritizeFindings orders findings errors first, then by check order
+func prioritizeFindings(findings []doctorFinding) []doctorFinding {
+	order := make(map[string]int)
+	for i, check := range doctorChecks {
+		order[check.Name] = i
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Severity != findings[j].Severity {
+			return findings[i].Severity == doctorError
+		}
+		return order[findings[i].Check] < order[findings[j].Check]
+	})
+	return findings
+}
+
+func printDoctorReport(report doctorReport) {
+	printHeader("\n🩺 git-keys Doctor")
+	fmt.Printf("\n  Config: %s\n\n", report.Config)
+
+	for i, check := range doctorChecks {
+		result := report.Checks[i]
+		switch result.Status {
+		case "ok":
+			fmt.Printf("  ✓ %s\n", check.Title)
+		case "skipped":
+			fmt.Printf("  - %s (skipped)\n", check.Title)
+		case doctorError:
+			fmt.Printf("  ❌ %s: %d error(s), %d warning(s)\n", check.Title, result.Errors, result.Warnings)
+		default:
+			fmt.Printf("  ⚠️  %s: %d warning(s)\n", check.Title, result.Warnings)
+		}
+	}
+	fmt.Println()
+
+	if len(report.Findings) == 0 {
+		fmt.Println("✅ Everything looks healthy.")
+		return
+	}
+
+	printHeader("📋 Fix List")
+	fmt.Println()
+	for i, f := range report.Findings {
+		icon := "⚠️ "
+		if f.Severity == doctorError {
+			icon = "❌"
+		}
+		problem := fmt.Sprintf("[%s] %s", f.Check, f.Problem)
+		if f.Subject != "" {
+			problem = fmt.Sprintf("[%s] %s: %s", f.Check, f.Subject, f.Problem)
+		}
+		printWrapped(fmt.Sprintf("%2d. %s ", i+1, icon), problem)
+		printWrapped("       → ", f.Fix)
+	}
+	fmt.Println()
+}
+
+// doctorPlatforms calls fn for each platform of the non-archived personas
+func doctorPlatforms(cfg *config.Config, fn func(persona *config.Persona, plat *config.Platform, subject string)) {
+	for personaIdx := range cfg.Personas {
+		persona := &cfg.Personas[personaIdx]
+		if persona.Archived {
+			continue
+		}
+		for platformIdx := range persona.Platforms {
+			plat := &persona.Platforms[platformIdx]
+			fn(persona, plat, fmt.Sprintf("%s/%s@%s", persona.Name, plat.Type, plat.Account))
+		}
+	}
+}
+
+func (d *doctor) checkConfig(cfg *config.Config, sshMgr *sshconfig.Manager, keyMgr *sshkey.Manager) {
+	conflicts, err := findSSHHostConflicts(cfg, sshMgr, keyMgr)
+	if err != nil {
+		d.add("config", doctorWarning, "", fmt.Sprintf("SSH config conflict check incomplete: %v", err), "git-keys validate")
+	}
+	for _, c := range conflicts {
+		d.add("config", doctorWarning, "", "SSH config conflict: "+c.String(), "git-keys apply, which offers to comment out or move the Host section")
+	}
+
+	if current, err := detectMachineName(); err == nil && current != "" && current != cfg.Machine.Name {
+		d.add("config", doctorWarning, "", fmt.Sprintf("machine name changed: config has '%s', system reports '%s'", cfg.Machine.Name, current),
+			"git-keys machine rename")
+	}
+
+	for _, violation := range policyViolations(cfg) {
+		d.add("config", doctorError, "", violation, "change the configuration to follow the policy in "+policy.Path(cfg))
+	}
+
+	for _, persona := range cfg.Personas {
+		for _, plat := range persona.Platforms {
+			for _, repo := range plat.Repos {
+				if problem := repoBindingProblem(repo, persona.Email); problem != "" {
+					d.add("config", doctorWarning, persona.Name, fmt.Sprintf("binding of %s: %s", repo, problem),
+						fmt.Sprintf("cd %s && git-keys use %s (or git-keys use --clear)", repo, persona.Name))
+				}
+			}
+		}
+	}
+}
+
+func (d *doctor) checkKeys(cfg *config.Config, keyMgr *sshkey.Manager) {
+	doctorPlatforms(cfg, func(persona *config.Persona, plat *config.Platform, subject string) {
+		key := plat.GetActiveKey()
+		if key == nil {
+			d.add("keys", doctorError, subject, "no active key", "git-keys apply")
+			return
+		}
+		if !keyMgr.KeyExists(key.LocalPath) {
+			d.add("keys", doctorError, subject, "key file not found: "+key.LocalPath, "git-keys trash restore, or git-keys rotate")
+			return
+		}
+		pubKeyPath := strings.TrimSuffix(key.LocalPath, ".pub") + ".pub"
+		if actual, err := keyMgr.GetFingerprint(pubKeyPath); err == nil && key.Fingerprint != "" && actual != key.Fingerprint {
+			d.add("keys", doctorError, subject, fmt.Sprintf("fingerprint mismatch for %s (config: %s, actual: %s)", key.LocalPath, key.Fingerprint, actual),
+				"git-keys validate")
+		}
+		if key.HasPrivateKey() {
+			if err := keyMgr.VerifyKeyPair(key.LocalPath); err != nil {
+				if sshkey.IsKeyPairMismatch(err) {
+					d.add("keys", doctorError, subject, fmt.Sprintf("key pair mismatch for %s: %v", key.LocalPath, err),
+						"git-keys rotate --persona "+persona.Name)
+				} else {
+					d.add("keys", doctorWarning, subject, fmt.Sprintf("cannot verify key pair %s: %v", key.LocalPath, err), "git-keys validate")
+				}
+			}
+		}
+	})
+
+	sshDir := filepath.Join(os.Getenv("HOME"), ".ssh")
+	issues, err := sshkey.CheckPermissions(sshDir, cfg.Defaults.GetKeysDir())
+	if err != nil {
+		d.add("keys", doctorWarning, "", fmt.Sprintf("permission check incomplete: %v", err), "git-keys validate")
+	}
+	for _, issue := range issues {
+		d.add("keys", doctorWarning, "", fmt.Sprintf("unexpected permissions on %s %s: %o (expected: %o)", issue.Kind, issue.Path, issue.Mode, issue.Expected),
+			"git-keys validate --fix")
+	}
+}
+
+func (d *doctor) checkRemote(ctx context.Context, cfg *config.Config) {
+	links, failures := findRemoteDrift(ctx, cfg)
+	for _, link := range links {
+		if !link.Key.InUse() {
+			continue
+		}
+		if link.NewID == "" {
+			d.add("remote", doctorError, link.label(), fmt.Sprintf("active key is no longer registered (remote ID %s)", link.OldID), "git-keys relink && git-keys apply")
+		} else {
+			d.add("remote", doctorWarning, link.label(), fmt.Sprintf("remote ID %s points at the wrong entry (registered as %s)", relinkID(link.OldID), link.NewID), "git-keys relink")
+		}
+	}
+	for _, failure := range failures {
+		d.add("remote", doctorWarning, "", "could not list remote keys: "+failure, "git-keys token check")
+	}
+
+	doctorPlatforms(cfg, func(persona *config.Persona, plat *config.Platform, subject string) {
+		if key := plat.GetActiveKey(); key != nil && key.RemoteID == "" {
+			for _, link := range links {
+				if link.Key == key {
+					return // Registered by hand; reported above
+				}
+			}
+			d.add("remote", doctorError, subject, "active key was never uploaded", "git-keys apply")
+		}
+	})
+}
+
+func (d *doctor) checkAgent(cfg *config.Config, keyMgr *sshkey.Manager) {
+	agentRunning := runtime.GOOS == "darwin" || os.Getenv("SSH_AUTH_SOCK") != ""
+	if !agentRunning {
+		d.add("agent", doctorWarning, "", "no SSH agent is running; passphrases are asked on every connection", `eval "$(ssh-agent -s)"`)
+	}
+
+	doctorPlatforms(cfg, func(persona *config.Persona, plat *config.Platform, subject string) {
+		key := plat.GetActiveKey()
+		if key == nil {
+			return
+		}
+		if plat.UsesExternalAgent() {
+			if _, err := os.Stat(sshkey.ExpandHome(plat.IdentityAgent)); err != nil {
+				d.add("agent", doctorError, subject, "identity agent socket not found: "+plat.IdentityAgent, "start the agent that provides "+plat.IdentityAgent)
+			}
+			return
+		}
+		if !agentRunning || !key.HasPrivateKey() || !keyMgr.KeyExists(key.LocalPath) {
+			return
+		}
+		privatePath := sshkey.ExpandHome(keyMgr.IdentityFilePath(key.LocalPath))
+		if !isKeyInAgent(privatePath) {
+			fix := "ssh-add " + privatePath
+			if runtime.GOOS == "darwin" {
+				fix = "git-keys keychain add --all"
+			}
+			d.add("agent", doctorWarning, subject, "key is not loaded in the SSH agent", fix)
+		}
+	})
+}
+
+func (d *doctor) checkSSH(cfg *config.Config) {
+	tested := make(map[string]bool)
+	doctorPlatforms(cfg, func(persona *config.Persona, plat *config.Platform, subject string) {
+		if plat.GetActiveKey() == nil {
+			return
+		}
+		alias, _ := sshHostAlias(persona, plat)
+		if tested[alias] {
+			return
+		}
+		tested[alias] = true
+
+		user, output := sshAuthenticatedUser(alias)
+		switch {
+		case user == "":
+			d.add("ssh", doctorError, subject, fmt.Sprintf("ssh -T git@%s fails: %s", alias, firstLineOf(output)), "git-keys fix "+alias)
+		case !strings.EqualFold(user, plat.Account):
+			d.add("ssh", doctorError, subject, fmt.Sprintf("git@%s authenticates as %s, not %s", alias, user, plat.Account), "git-keys fix "+alias)
+		}
+	})
+}
+
+func (d *doctor) checkGitConfig(cfg *config.Config) {
+	output, _ := exec.Command("git", "config", "--global", "--get-regexp", `^includeif\.`).Output()
+
+	doctorPlatforms(cfg, func(persona *config.Persona, plat *config.Platform, subject string) {
+		if !plat.HasIncludeConditions() {
+			if len(plat.Repos) == 0 {
+				d.add("gitconfig", doctorWarning, subject, "no gitdir or remote_urls, so the persona's identity is never applied automatically", "git-keys setup-git")
+			}
+			return
+		}
+		for _, condition := range platformIncludeConditions(plat) {
+			if problem := includeIfProblem(string(output), persona, condition); problem != "" {
+				d.add("gitconfig", doctorError, subject, problem, "git-keys setup-git")
+			}
+		}
+	})
+}
+
+// includeIfProblem checks the includeIf in ~/.gitconfig that applies the
+// persona's identity under condition (gitdir:<pattern> or
+// hasconfig:remote.*.url:<pattern>), given the output of 'git config
+// --global --get-regexp ^includeif\.'. Returns "" when it is correct.
+func includeIfProblem(includes string, persona *config.Persona, condition string) string {
+	want := "includeif." + condition + ".path"
+
+	path := ""
+	for _, line := range strings.Split(includes, "\n") {
+		if key, value, ok := strings.Cut(line, " "); ok && key == want {
+			path = value
+		}
+	}
+	if path == "" {
+		return fmt.Sprintf("~/.gitconfig has no includeIf for %s", condition)
+	}
+	if pattern, isGitDir := strings.CutPrefix(condition, "gitdir:"); isGitDir && !strings.HasSuffix(pattern, "/") {
+		return fmt.Sprintf("gitdir:%s has no trailing slash, so it only matches a repository at exactly that path", pattern)
+	}
+
+	expanded := sshkey.ExpandHome(path)
+	if _, err := os.Stat(expanded); err != nil {
+		return fmt.Sprintf("includeIf for %s points at missing file %s", condition, path)
+	}
+	email, _ := exec.Command("git", "config", "--file", expanded, "--get", "user.email").Output()
+	if got := strings.TrimSpace(string(email)); !strings.EqualFold(got, persona.Email) {
+		if got == "" {
+			got = "no email"
+		}
+		return fmt.Sprintf("%s sets %s, not %s", path, got, persona.Email)
+	}
+	return ""
+}
+
+func (d *doctor) checkTokens(ctx context.Context, cfg *config.Config) {
+	envTokens := loadTokensFromEnv()
+	checked := make(map[string]bool)
+
+	doctorPlatforms(cfg, func(persona *config.Persona, plat *config.Platform, subject string) {
+		// Accounts shared by several personas use the same token
+		id := platformLane(plat.Type, plat.BaseURL, plat.Account)
+		if checked[id] {
+			return
+		}
+		checked[id] = true
+
+		setFix := fmt.Sprintf("git-keys token set %s %s", plat.Type, plat.Account)
+		token, source, err := lookupToken(plat, envTokens)
+		if err != nil {
+			d.add("tokens", doctorError, subject, err.Error(), setFix)
+			return
+		}
+		client, err := newPlatformClientWithToken(plat, token)
+		if err != nil {
+			d.add("tokens", doctorError, subject, err.Error(), setFix)
+			return
+		}
+		checker, ok := client.(api.TokenChecker)
+		if !ok {
+			return
+		}
+		info, err := checker.CheckToken(ctx)
+		if err != nil {
+			d.add("tokens", doctorError, subject, fmt.Sprintf("token from %s is rejected: %v", source, err), setFix)
+			return
+		}
+
+		if info.User != "" && !strings.EqualFold(info.User, plat.Account) {
+			d.add("tokens", doctorWarning, subject, fmt.Sprintf("token belongs to '%s', not '%s'", info.User, plat.Account), setFix)
+		}
+		if info.ExpiresAt != nil {
+			until := time.Until(*info.ExpiresAt)
+			switch {
+			case until <= 0:
+				d.add("tokens", doctorError, subject, "token expired on "+info.ExpiresAt.Format("2006-01-02"), setFix)
+			case until < tokenExpiryWarning:
+				d.add("tokens", doctorWarning, subject, fmt.Sprintf("token expires in %d day(s)", int(until.Hours()/24)), setFix)
+			}
+		}
+		if info.Scopes != nil {
+			switch {
+			case plat.Type == config.PlatformGitHub && !info.HasScope("admin:public_key"):
+				d.add("tokens", doctorWarning, subject, "token lacks admin:public_key, needed to delete keys", setFix)
+			case plat.Type == config.PlatformGitLab && !info.HasScope("api"):
+				d.add("tokens", doctorError, subject, "token lacks the api scope, needed to manage SSH keys", setFix)
+			}
+		}
+	})
+}
@@ -0,0 +1,184 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/kunlu/git-keys/internal/config"
+	"github.com/kunlu/git-keys/internal/history"
+	"github.com/kunlu/git-keys/internal/journal"
+	"github.com/kunlu/git-keys/internal/logger"
+	"github.com/kunlu/git-keys/internal/prompt"
+	"github.com/spf13/cobra"
+)
+
+var editCmd = &cobra.Command{
+	Use:   "edit",
+	Short: "Edit the configuration file in $EDITOR",
+	Long: `Open the git-keys configuration in your editor ($VISUAL, $EDITOR, or vi).
+
+You edit a copy of the configuration. When the editor exits, the copy is
+checked with the same checks as 'git-keys validate', and unknown (misspelled)
+keys count as errors; the live configuration is only replaced when there are
+no errors. On errors, edit the copy again or abort. An aborted copy is kept,
+so no hand edits are lost.
+
+The previous configuration can be restored with 'git-keys undo'.
+`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE:         runEdit,
+}
+
+func init() {
+	rootCmd.AddCommand(editCmd)
+}
+
+func runEdit(cmd *cobra.Command, args []string) (err error) {
+	configPath := cfgFile
+	if configPath == "" {
+		configPath = config.GetDefaultConfigPath()
+	}
+
+	original, err := config.ReadFile(configPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("configuration file not found at %s\nRun 'git-keys init' first", configPath)
+		}
+		return fmt.Errorf("failed to read configuration: %w", err)
+	}
+
+	// The copy lives next to the config so it can replace it with a rename
+	tmp, err := os.CreateTemp(filepath.Dir(configPath), ".git-keys-edit-*.yaml")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary copy: %w", err)
+	}
+	tmpPath := tmp.Name()
+	_, err = tmp.Write(original)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write temporary copy: %w", err)
+	}
+
+	for {
+		if err := runEditor(tmpPath); err != nil {
+			fmt.Printf("Your changes are kept in %s\n", tmpPath)
+			return err
+		}
+
+		edited, err := os.ReadFile(tmpPath)
+		if err != nil {
+			return fmt.Errorf("failed to read edited copy: %w", err)
+		}
+		if bytes.Equal(edited, original) {
+			os.Remove(tmpPath)
+			fmt.Println("No changes.")
+			return nil
+		}
+
+		problems, warnings := checkEditedConfig(tmpPath)
+		if len(problems) == 0 {
+			for _, warn := range warnings {
+				printWrapped("⚠️  ", warn)
+			}
+			break
+		}
+
+		fmt.Printf("\n❌ The edited configuration has %d error(s):\n", len(problems))
+		for _, problem := range problems {
+			printWrapped("   • ", problem)
+		}
+		if nonInteractive || prompt.Choice("\nEdit again or abort?", []string{"edit", "abort"}, "abort") != "edit" {
+			fmt.Printf("Aborted; the live configuration is unchanged. Your edits are kept in %s\n", tmpPath)
+			return nil
+		}
+	}
+
+	j, err := journal.Begin("", "edit")
+	if err != nil {
+		return fmt.Errorf("failed to start journal: %w", err)
+	}
+	defer func() {
+		finishJournal(j, err)
+	}()
+	journalWarn(j.BackupFile(config.StoragePath(configPath)))
+
+	if config.IsEncrypted(configPath) {
+		// Encrypt the edits like the rest of the configuration
+		edited, err := os.ReadFile(tmpPath)
+		if err != nil {
+			return fmt.Errorf("failed to read edited copy: %w", err)
+		}
+		if err := config.WriteEncrypted(configPath, edited); err != nil {
+			return fmt.Errorf("failed to save configuration (your edits are kept in %s): %w", tmpPath, err)
+		}
+		os.Remove(tmpPath)
+	} else {
+		if err := os.Chmod(tmpPath, 0600); err != nil {
+			return fmt.Errorf("failed to set permissions: %w", err)
+		}
+		if err := os.Rename(tmpPath, configPath); err != nil {
+			return fmt.Errorf("failed to replace configuration (your edits are kept in %s): %w", tmpPath, err)
+		}
+	}
+
+	histMgr := history.NewManager("")
+	if err := histMgr.Record(history.Entry{
+		Action:  "edit",
+		Summary: "Edited configuration",
+		Details: map[string]string{"config": configPath},
+	}); err != nil {
+		logger.Warn("Failed to record history: %v", err)
+	}
+
+	fmt.Printf("✅ Saved %s\n", configPath)
+	fmt.Println("\nRun 'git-keys plan' to see what the change will do.")
+	return nil
+}
+
+// runEditor opens path in the user's editor and waits for it to exit
+func runEditor(path string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	// The editor may come with arguments, e.g. "code --wait"
+	fields := strings.Fields(editor)
+	c := exec.Command(fields[0], append(fields[1:], path)...)
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	if err := c.Run(); err != nil {
+		return fmt.Errorf("editor %s failed: %w", fields[0], err)
+	}
+	return nil
+}
+
+// checkEditedConfig parses and validates an edited configuration. It returns
+// the errors that block saving it and the warnings.
+func checkEditedConfig(path string) (problems, warnings []string) {
+	cfg, err := config.NewManager(path).Load()
+	if err != nil {
+		return []string{err.Error()}, nil
+	}
+	problems, warnings, _ = checkConfig(cfg, false)
+
+	// A misspelled key would be dropped silently; catch it while editing
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return []string{err.Error()}, warnings
+	}
+	unknown, _ := config.UnknownFields(data)
+	return append(unknown, problems...), warnings
+}
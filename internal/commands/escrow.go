@@ -0,0 +1,259 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/kunlu/git-keys/internal/config"
+	"github.com/kunlu/git-keys/internal/escrow"
+	"github.com/kunlu/git-keys/internal/history"
+	"github.com/kunlu/git-keys/internal/logger"
+	"github.com/kunlu/git-keys/internal/sshkey"
+	"github.com/spf13/cobra"
+)
+
+var (
+	escrowOutput         string
+	escrowSigningKey     string
+	escrowIncludeRevoked bool
+
+	escrowSignature      string
+	escrowAllowedSigners string
+	escrowSignerKey      string
+	escrowPrincipal      string
+)
+
+var escrowCmd = &cobra.Command{
+	Use:   "escrow",
+	Short: "Export signed public key manifests for team admins",
+	Long: `Export and verify signed manifests of public keys for organizations that
+require key escrow.
+
+Manifests contain public keys and metadata only, never private keys. They are
+signed with an SSH key (ssh-keygen -Y sign) so admins can check where a
+manifest came from before ingesting it.
+
+Subcommands:
+  export  - Write a signed manifest of this machine's public keys
+  verify  - Check a manifest's signature and contents
+`,
+}
+
+var escrowExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Write a signed manifest of public keys",
+	Long: `Write a signed manifest of the public keys managed on this machine.
+
+Escrow is opt-in: set escrow_enabled: true in the defaults section of the
+configuration first. The manifest is signed with --sign-key or
+escrow_signing_key; the signature is written next to it with a .sig suffix.
+
+Send both files, plus the signing key's public key, to your admin.
+
+Examples:
+  git-keys escrow export --sign-key ~/.ssh/id_ed25519_escrow
+  git-keys escrow export --output ~/escrow/laptop.json
+`,
+	Args: cobra.NoArgs,
+	RunE: runEscrowExport,
+}
+
+var escrowVerifyCmd = &cobra.Command{
+	Use:   "verify <manifest>",
+	Short: "Verify a signed manifest",
+	Long: `Verify an escrow manifest before ingesting it.
+
+The signature (default <manifest>.sig) must be valid for the git-keys-escrow
+namespace and made by a trusted key: either --signer-key (the expected public
+key) or an entry for --principal in an ssh-keygen allowed_signers file. Each
+entry's public key must also match its recorded fingerprint.
+
+This does not need a git-keys configuration, so admins can run it anywhere.
+
+Examples:
+  git-keys escrow verify laptop.json --signer-key alice_escrow.pub
+  git-keys escrow verify laptop.json --allowed-signers ~/escrow/allowed_signers --principal alice@company.com
+`,
+	Args: cobra.ExactArgs(1),
+	RunE: runEscrowVerify,
+}
+
+func init() {
+	escrowExportCmd.Flags().StringVarP(&escrowOutput, "output", "o", "", "Manifest path (default git-keys-escrow-<machine>-<date>.json)")
+	escrowExportCmd.Flags().StringVar(&escrowSigningKey, "sign-key", "", "SSH private key to sign with (default escrow_signing_key)")
+	escrowExportCmd.Flags().BoolVar(&escrowIncludeRevoked, "include-revoked", false, "Include revoked keys")
+
+	escrowVerifyCmd.Flags().StringVar(&escrowSignature, "signature", "", "Signature file (default <manifest>.sig)")
+	escrowVerifyCmd.Flags().StringVar(&escrowAllowedSigners, "allowed-signers", "", "ssh-keygen allowed_signers file")
+	escrowVerifyCmd.Flags().StringVar(&escrowSignerKey, "signer-key", "", "Public key the manifest must be signed with")
+	escrowVerifyCmd.Flags().StringVar(&escrowPrincipal, "principal", "", "Signer identity in the allowed_signers file")
+
+	escrowCmd.AddCommand(escrowExportCmd)
+	escrowCmd.AddCommand(escrowVerifyCmd)
+	rootCmd.AddCommand(escrowCmd)
+}
+
+func runEscrowExport(cmd *cobra.Command, args []string) error {
+	// Load configuration
+	configPath := cfgFile
+	if configPath == "" {
+		configPath = config.GetDefaultConfigPath()
+	}
+
+	mgr := config.NewManager(configPath)
+	if !mgr.Exists() {
+		return fmt.Errorf("configuration file not found at %s\nRun 'git-keys init' first", configPath)
+	}
+
+	cfg, err := mgr.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	if !cfg.Defaults.EscrowEnabled {
+		return fmt.Errorf("escrow export is not enabled\nSet 'escrow_enabled: true' under defaults in %s to opt in", configPath)
+	}
+
+	signingKey := escrowSigningKey
+	if signingKey == "" {
+		signingKey = cfg.Defaults.EscrowSigningKey
+	}
+	if signingKey == "" {
+		return fmt.Errorf("no signing key; pass --sign-key or set escrow_signing_key")
+	}
+	signingKey = sshkey.ExpandHome(signingKey)
+
+	signer, err := escrow.SignatureFingerprint(signingKey)
+	if err != nil {
+		return err
+	}
+
+	printHeader("\n🔏 Escrow Export")
+	fmt.Println()
+
+	keyMgr := sshkey.NewManager(cfg.Defaults.GetKeysDir())
+	manifest, warnings := escrow.BuildManifest(cfg, keyMgr, escrowIncludeRevoked)
+	manifest.Signer = signer
+
+	if len(manifest.Keys) == 0 {
+		return fmt.Errorf("no public keys to export")
+	}
+
+	output := escrowOutput
+	if output == "" {
+		output = fmt.Sprintf("git-keys-escrow-%s-%s.json", cfg.Machine.Name, time.Now().Format("2006-01-02"))
+	}
+	output = sshkey.ExpandHome(output)
+
+	data, err := manifest.Marshal()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(output), 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	if err := os.WriteFile(output, data, 0644); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	sigPath, err := escrow.Sign(output, signingKey)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range manifest.Keys {
+		fmt.Printf("  %s/%s@%s  %s\n", entry.Persona, entry.Platform, entry.Account, entry.Fingerprint)
+	}
+
+	if len(warnings) > 0 {
+		fmt.Printf("\n⚠️  %d key(s) skipped:\n", len(warnings))
+		for _, w := range warnings {
+			printWrapped("   • ", w)
+		}
+	}
+
+	histMgr := history.NewManager("")
+	if err := histMgr.Record(history.Entry{
+		Action:  "escrow-export",
+		Summary: fmt.Sprintf("Exported %d public key(s) for escrow", len(manifest.Keys)),
+		Details: map[string]string{
+			"manifest": output,
+			"signer":   signer,
+		},
+	}); err != nil {
+		logger.Warn("Failed to record history: %v", err)
+	}
+
+	fmt.Printf("\n✓ Manifest:  %s\n", output)
+	fmt.Printf("✓ Signature: %s\n", sigPath)
+	fmt.Printf("  Signed by %s\n", signer)
+	fmt.Printf("\nSend both files and %s.pub to your admin.\n", signingKey)
+	return nil
+}
+
+func runEscrowVerify(cmd *cobra.Command, args []string) error {
+	manifestPath := args[0]
+
+	sigPath := escrowSignature
+	if sigPath == "" {
+		sigPath = manifestPath + ".sig"
+	}
+
+	allowedSigners := escrowAllowedSigners
+	principal := escrowPrincipal
+	switch {
+	case escrowSignerKey != "" && allowedSigners != "":
+		return fmt.Errorf("use either --signer-key or --allowed-signers, not both")
+	case escrowSignerKey != "":
+		if principal == "" {
+			principal = "escrow"
+		}
+		tmp, err := escrow.WriteAllowedSigners(escrowSignerKey, principal)
+		if err != nil {
+			return err
+		}
+		defer os.Remove(tmp)
+		allowedSigners = tmp
+	case allowedSigners != "":
+		if principal == "" {
+			return fmt.Errorf("--principal is required with --allowed-signers")
+		}
+	default:
+		return fmt.Errorf("specify the trusted signer with --signer-key or --allowed-signers")
+	}
+
+	printHeader("\n🔏 Verify Escrow Manifest")
+	fmt.Println()
+
+	if err := escrow.Verify(manifestPath, sigPath, allowedSigners, principal); err != nil {
+		fmt.Println("❌ Signature is not valid")
+		return err
+	}
+	fmt.Println("✓ Signature is valid")
+
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return fmt.Errorf("failed to read manifest: %w", err)
+	}
+	manifest, err := escrow.ParseManifest(data)
+	if err != nil {
+		fmt.Println("❌ Manifest contents are not valid")
+		return err
+	}
+	fmt.Printf("✓ %d public key(s) match their fingerprints\n", len(manifest.Keys))
+
+	fmt.Printf("\n  Machine:   %s (%s)\n", manifest.Machine.Name, manifest.Machine.ID)
+	fmt.Printf("  Generated: %s\n", manifest.GeneratedAt.Local().Format("2006-01-02 15:04"))
+	if manifest.Signer != "" {
+		fmt.Printf("  Signer:    %s\n", manifest.Signer)
+	}
+	fmt.Println()
+	for _, entry := range manifest.Keys {
+		fmt.Printf("  %s <%s>  %s@%s  %s  %s\n", entry.Persona, entry.Email, entry.Account, entry.Platform, entry.Status, entry.Fingerprint)
+	}
+
+	fmt.Println("\n✅ Manifest verified.")
+	return nil
+}
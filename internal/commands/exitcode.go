@@ -0,0 +1,95 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/kunlu/git-keys/internal/config"
+	"github.com/kunlu/git-keys/internal/logger"
+	"github.com/spf13/cobra"
+)
+
+// ExitStatus is returned by commands that report their result through the
+// exit status alone, such as 'plan --detailed-exitcode'. No error is printed.
+type ExitStatus int
+
+func (s ExitStatus) Error() string {
+	return fmt.Sprintf("exit status %d", int(s))
+}
+
+// Exit statuses of status, plan and validate with --detailed-exitcode or
+// --quiet. Errors take precedence over expiring keys, and expiring keys over
+// warnings.
+const (
+	ExitOK       ExitStatus = 0 // Healthy and nothing to change
+	ExitError    ExitStatus = 1 // The command failed, or found errors
+	ExitWarnings ExitStatus = 2 // Warnings, or drift from the configuration
+	ExitExpiring ExitStatus = 3 // Keys expired or expire within the due window
+)
+
+// quiet is the value of --quiet: the command prints nothing but errors and
+// reports its result through the exit status
+var quiet bool
+
+// setupQuiet applies --quiet to cmd, which must support --detailed-exitcode.
+// The human-readable output is dropped; a --output document is still printed.
+func setupQuiet(cmd *cobra.Command) error {
+	if !quiet {
+		return nil
+	}
+	if cmd.Flags().Lookup("detailed-exitcode") == nil {
+		return fmt.Errorf("'%s' does not support --quiet", cmd.CommandPath())
+	}
+	logger.SetLevel(logger.ERROR)
+
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", os.DevNull, err)
+	}
+	os.Stdout = devNull
+	return nil
+}
+
+// detailedExitCode reports whether cmd reports its result through the exit
+// status: with its --detailed-exitcode flag, or with --quiet
+func detailedExitCode(cmd *cobra.Command) bool {
+	if quiet {
+		return true
+	}
+	flag := cmd.Flags().Lookup("detailed-exitcode")
+	return flag != nil && flag.Value.String() == "true"
+}
+
+// exitWithStatus ends a health check with status when cmd reports through
+// the exit status, and with success otherwise
+func exitWithStatus(cmd *cobra.Command, status ExitStatus) error {
+	if status == ExitOK || !detailedExitCode(cmd) {
+		return nil
+	}
+	cmd.SilenceErrors = true
+	cmd.SilenceUsage = true
+	return status
+}
+
+// expiringKeys counts the active keys of active personas that have expired
+// or expire within window
+func expiringKeys(cfg *config.Config, window time.Duration) int {
+	count := 0
+	for i := range cfg.Personas {
+		persona := &cfg.Personas[i]
+		if persona.Archived {
+			continue
+		}
+		for j := range persona.Platforms {
+			plat := &persona.Platforms[j]
+			for k := range plat.Keys {
+				key := &plat.Keys[k]
+				if key.Status == config.KeyStatusExpired || (key.Status == config.KeyStatusActive && keyDue(cfg, persona, plat, key, window)) {
+					count++
+				}
+			}
+		}
+	}
+	return count
+}
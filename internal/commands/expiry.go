@@ -0,0 +1,43 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kunlu/git-keys/internal/config"
+	"github.com/kunlu/git-keys/internal/history"
+	"github.com/kunlu/git-keys/internal/logger"
+)
+
+// reconcileExpiry marks active keys past their expiry as expired, saves the
+// config when any changed and prints a warning for each. Expired keys stay in
+// use until they are rotated. Run by status, plan and apply.
+func reconcileExpiry(mgr *config.Manager, cfg *config.Config) []config.ExpiredKey {
+	expired := cfg.MarkExpiredKeys(time.Now())
+	if len(expired) == 0 {
+		return nil
+	}
+
+	if err := mgr.Save(cfg); err != nil {
+		logger.Warn("Failed to save expired key status: %v", err)
+	}
+
+	var labels []string
+	for _, e := range expired {
+		label := fmt.Sprintf("%s/%s@%s", e.Persona.Name, e.Platform.Type, e.Platform.Account)
+		labels = append(labels, label)
+		printWrapped("⚠️  ", fmt.Sprintf("Key %s of %s expired %s; rotate it with 'git-keys rotate %s/%s'",
+			e.Key.Fingerprint, label, e.ExpiresAt.Local().Format("2006-01-02"), e.Persona.Name, e.Platform.Type))
+	}
+
+	histMgr := history.NewManager("")
+	if err := histMgr.Record(history.Entry{
+		Action:  "expire",
+		Summary: fmt.Sprintf("Marked %d key(s) expired", len(expired)),
+		Details: map[string]string{"keys": strings.Join(labels, ", ")},
+	}); err != nil {
+		logger.Warn("Failed to record history: %v", err)
+	}
+	return expired
+}
@@ -0,0 +1,445 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/kunlu/git-keys/internal/api"
+	"github.com/kunlu/git-keys/internal/config"
+	"github.com/kunlu/git-keys/internal/history"
+	"github.com/kunlu/git-keys/internal/logger"
+	"github.com/kunlu/git-keys/internal/prompt"
+	"github.com/kunlu/git-keys/internal/sshconfig"
+	"github.com/kunlu/git-keys/internal/sshkey"
+	"github.com/spf13/cobra"
+)
+
+var (
+	fixYes    bool
+	fixRemote string
+)
+
+var fixCmd = &cobra.Command{
+	Use:   "fix [repo-path|host]",
+	Short: "Diagnose and repair \"Permission denied (publickey)\"",
+	Long: `Walk through the usual causes of "Permission denied (publickey)" for a
+repository (default: the current directory) or an SSH host, and offer to fix
+each problem found:
+
+  1. Alias      - the remote uses the persona's SSH alias (fix: rewrite the remote)
+  2. Key        - the persona's key exists on disk
+  3. SSH config - ssh picks the persona's key for the alias (fix: rewrite the block)
+  4. Agent      - the key is loaded in the SSH agent (fix: ssh-add)
+  5. Remote     - the key is registered on the platform (fix: upload it)
+  6. Account    - the platform authenticates the key as the persona's account
+
+Examples:
+  git-keys fix
+  git-keys fix ~/src/api
+  git-keys fix github.com.work
+`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runFix,
+}
+
+func init() {
+	fixCmd.Flags().BoolVarP(&fixYes, "yes", "y", false, "Apply fixes without prompting")
+	fixCmd.Flags().StringVar(&fixRemote, "remote", "origin", "Remote to check in repository mode")
+	rootCmd.AddCommand(fixCmd)
+}
+
+// fixStep is a link in the diagnostic chain. Fix is nil when the problem
+// needs manual action, described by Hint.
+type fixStep struct {
+	Name   string
+	OK     bool
+	Detail string
+	Hint   string
+	Fix    func() error
+	// FixText describes what Fix does
+	FixText string
+}
+
+// fixTarget is what the diagnostic chain runs against
+type fixTarget struct {
+	Repo      string // Repository root; "" in host mode
+	RemoteURL string // Remote URL after insteadOf rewrites
+	Host      string // SSH host the remote or argument uses
+	Persona   *config.Persona
+	Platform  *config.Platform
+	Alias     string
+}
+
+func runFix(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	// Load configuration
+	configPath := cfgFile
+	if configPath == "" {
+		configPath = config.GetDefaultConfigPath()
+	}
+
+	mgr := config.NewManager(configPath)
+	if !mgr.Exists() {
+		return fmt.Errorf("configuration file not found at %s\nRun 'git-keys init' first", configPath)
+	}
+
+	cfg, err := mgr.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	target, err := resolveFixTarget(cfg, args)
+	if err != nil {
+		return err
+	}
+
+	keyMgr := sshkey.NewManager(cfg.Defaults.GetKeysDir())
+	sshMgr := sshConfigManager(cfg)
+
+	printHeader("\n🩺 Fix SSH Authentication")
+	if target.Repo != "" {
+		fmt.Printf("\n  Repository: %s\n", target.Repo)
+		fmt.Printf("  Remote:     %s\n", target.RemoteURL)
+	} else {
+		fmt.Printf("\n  Host:       %s\n", target.Host)
+	}
+	fmt.Printf("  Persona:    %s (%s@%s)\n\n", target.Persona.Name, target.Platform.Account, target.Platform.Type)
+
+	configChanged := false
+	steps := diagnoseFixTarget(ctx, cfg, keyMgr, sshMgr, target, &configChanged)
+
+	var failed []*fixStep
+	for i := range steps {
+		step := &steps[i]
+		if step.OK {
+			fmt.Printf("  ✓ %-11s %s\n", step.Name, step.Detail)
+			continue
+		}
+		fmt.Printf("  ❌ %-10s %s\n", step.Name, step.Detail)
+		failed = append(failed, step)
+	}
+	fmt.Println()
+
+	if len(failed) == 0 {
+		fmt.Println("✅ No problems found. If pushes still fail, check that the account can access the repository.")
+		return nil
+	}
+
+	fixed := 0
+	for _, step := range failed {
+		if step.Fix == nil {
+			printWrapped(fmt.Sprintf("  %s: ", step.Name), step.Hint)
+			continue
+		}
+
+		if !fixYes && !prompt.Confirm(fmt.Sprintf("  Fix %s: %s?", step.Name, step.FixText)) {
+			continue
+		}
+
+		if err := step.Fix(); err != nil {
+			logger.Warn("Fix for %s failed: %v", step.Name, err)
+			fmt.Printf("    ❌ %v\n", err)
+			continue
+		}
+		fmt.Printf("    ✓ %s\n", step.FixText)
+		fixed++
+	}
+
+	if configChanged {
+		if err := mgr.Save(cfg); err != nil {
+			return fmt.Errorf("failed to save configuration: %w", err)
+		}
+	}
+
+	if fixed == 0 {
+		return nil
+	}
+
+	histMgr := history.NewManager("")
+	if err := histMgr.Record(history.Entry{
+		Action:  "fix",
+		Summary: fmt.Sprintf("Applied %d fix(es) for %s", fixed, target.Alias),
+		Details: map[string]string{
+			"persona":  target.Persona.Name,
+			"platform": fmt.Sprintf("%s@%s", target.Platform.Account, target.Platform.Type),
+			"host":     target.Host,
+		},
+	}); err != nil {
+		logger.Warn("Failed to record history: %v", err)
+	}
+
+	// Check the connection again now that fixes are in place
+	fmt.Println()
+	if user, output := sshAuthenticatedUser(target.Alias); user != "" {
+		fmt.Printf("✅ %s authenticates as %s\n", target.Alias, user)
+	} else {
+		fmt.Printf("⚠️  %s still fails: %s\n", target.Alias, firstLineOf(output))
+	}
+	return nil
+}
+
+// resolveFixTarget finds the persona platform for a repository or host
+func resolveFixTarget(cfg *config.Config, args []string) (*fixTarget, error) {
+	target := &fixTarget{}
+
+	arg := "."
+	if len(args) > 0 {
+		arg = args[0]
+	}
+
+	if info, err := os.Stat(sshkey.ExpandHome(arg)); err == nil && info.IsDir() {
+		dir, _ := filepath.Abs(sshkey.ExpandHome(arg))
+		repo, err := gitOutput(dir, "rev-parse", "--show-toplevel")
+		if err != nil {
+			return nil, fmt.Errorf("%s is not inside a git repository", dir)
+		}
+		remoteURL, err := gitOutput(repo, "ls-remote", "--get-url", fixRemote)
+		if err != nil || remoteURL == fixRemote {
+			return nil, fmt.Errorf("repository %s has no remote '%s'", repo, fixRemote)
+		}
+		target.Repo = repo
+		target.RemoteURL = remoteURL
+		target.Host = remoteHost(remoteURL)
+	} else {
+		target.Host = remoteHost(arg)
+	}
+
+	// The host is a persona's alias
+	for personaIdx := range cfg.Personas {
+		persona := &cfg.Personas[personaIdx]
+		for platformIdx := range persona.Platforms {
+			plat := &persona.Platforms[platformIdx]
+			if alias, _ := sshHostAlias(persona, plat); strings.EqualFold(alias, target.Host) {
+				target.Persona, target.Platform, target.Alias = persona, plat, alias
+				return target, nil
+			}
+		}
+	}
+
+	// The host is a platform hostname; pick the persona the repository
+	// belongs to by binding, then gitdir, then by the remote itself
+	if target.Repo != "" {
+		if persona, plat, _ := cfg.FindRepoBinding(target.Repo); persona != nil {
+			alias, hostname := sshHostAlias(persona, plat)
+			if strings.EqualFold(hostname, target.Host) {
+				target.Persona, target.Platform, target.Alias = persona, plat, alias
+				return target, nil
+			}
+		}
+		for personaIdx := range cfg.Personas {
+			persona := &cfg.Personas[personaIdx]
+			if persona.Archived {
+				continue
+			}
+			for platformIdx := range persona.Platforms {
+				plat := &persona.Platforms[platformIdx]
+				alias, hostname := sshHostAlias(persona, plat)
+				if !strings.EqualFold(hostname, target.Host) {
+					continue
+				}
+				if platformGitDirFor(plat, target.Repo) != "" {
+					target.Persona, target.Platform, target.Alias = persona, plat, alias
+					return target, nil
+				}
+			}
+		}
+
+		suggestions, err := suggestPersonas(cfg, target.RemoteURL)
+		if err == nil && len(suggestions) > 0 {
+			best := suggestions[0]
+			alias, _ := sshHostAlias(best.Persona, best.Platform)
+			target.Persona, target.Platform, target.Alias = best.Persona, best.Platform, alias
+			return target, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no persona uses the SSH host %s", target.Host)
+}
+
+// diagnoseFixTarget runs the diagnostic chain. Later steps are skipped when
+// an earlier one leaves nothing to check.
+func diagnoseFixTarget(ctx context.Context, cfg *config.Config, keyMgr *sshkey.Manager, sshMgr *sshconfig.Manager, target *fixTarget, configChanged *bool) []fixStep {
+	var steps []fixStep
+	persona, plat := target.Persona, target.Platform
+
+	// 1. Alias resolution
+	sshCommand := ""
+	if target.Repo != "" {
+		sshCommand, _ = gitOutput(target.Repo, "config", "core.sshCommand")
+	}
+	if strings.EqualFold(target.Host, target.Alias) {
+		steps = append(steps, fixStep{Name: "Alias", OK: true, Detail: "remote uses " + target.Alias})
+	} else if cfg.GetSwitchStrategy(persona) == config.SwitchSSHCommand && strings.Contains(sshCommand, " -i ") {
+		// The ssh-command strategy keeps the platform's hostname in remotes
+		steps = append(steps, fixStep{Name: "Alias", OK: true, Detail: "core.sshCommand selects the key: " + sshCommand})
+	} else {
+		step := fixStep{
+			Name:   "Alias",
+			Detail: fmt.Sprintf("remote uses %s, so ssh offers the default key instead of %s's", target.Host, persona.Name),
+			Hint:   fmt.Sprintf("use git@%s:<path> as the remote URL", target.Alias),
+		}
+		if target.Repo != "" {
+			newURL := fmt.Sprintf("git@%s:%s", target.Alias, remotePath(target.RemoteURL))
+			step.FixText = "set the remote URL to " + newURL
+			step.Fix = func() error {
+				return exec.Command("git", "-C", target.Repo, "remote", "set-url", fixRemote, newURL).Run()
+			}
+		}
+		steps = append(steps, step)
+	}
+
+	// 2. Key presence
+	key := plat.GetActiveKey()
+	if key == nil {
+		return append(steps, fixStep{Name: "Key", Detail: "no active key", Hint: "run 'git-keys apply' to generate one"})
+	}
+	identityFile := keyMgr.IdentityFilePath(key.LocalPath)
+	if !keyMgr.KeyExists(key.LocalPath) {
+		return append(steps, fixStep{
+			Name:   "Key",
+			Detail: identityFile + " is missing",
+			Hint:   "restore it with 'git-keys trash restore', or rotate to a new key with 'git-keys rotate'",
+		})
+	}
+	steps = append(steps, fixStep{Name: "Key", OK: true, Detail: identityFile})
+
+	// 3. SSH config
+	effective, err := sshMgr.EffectiveSettings(target.Alias)
+	switch {
+	case err != nil:
+		steps = append(steps, fixStep{Name: "SSH config", Detail: err.Error(), Hint: "check the SSH config with 'git-keys validate'"})
+	case filepath.Clean(sshkey.ExpandHome(effective.IdentityFile)) != filepath.Clean(sshkey.ExpandHome(identityFile)):
+		steps = append(steps, fixStep{
+			Name:    "SSH config",
+			Detail:  fmt.Sprintf("%s uses %s", target.Alias, effective.IdentityFile),
+			FixText: "rewrite the managed block for " + target.Alias,
+			Fix: func() error {
+				return updateSSHConfig(cfg, sshMgr, keyMgr, persona, plat, key)
+			},
+		})
+	default:
+		steps = append(steps, fixStep{Name: "SSH config", OK: true, Detail: fmt.Sprintf("%s → %s", target.Alias, effective.HostName)})
+	}
+
+	// 4. Agent state; agent-held keys are offered by their own agent
+	if key.HasPrivateKey() && !plat.UsesExternalAgent() {
+		privatePath := sshkey.ExpandHome(identityFile)
+		switch {
+		case isKeyInAgent(privatePath):
+			steps = append(steps, fixStep{Name: "Agent", OK: true, Detail: "key is loaded"})
+		case os.Getenv("SSH_AUTH_SOCK") == "":
+			steps = append(steps, fixStep{
+				Name:   "Agent",
+				Detail: "no SSH agent is running",
+				Hint:   "start one with 'eval \"$(ssh-agent -s)\"'; ssh still reads the key file, but asks for its passphrase every time",
+			})
+		default:
+			steps = append(steps, fixStep{
+				Name:    "Agent",
+				Detail:  "key is not loaded in the SSH agent",
+				FixText: "add the key to the agent",
+				Fix: func() error {
+					return addKeyToKeychain(cfg.Defaults, privatePath)
+				},
+			})
+		}
+	}
+
+	// 5. Remote registration
+	steps = append(steps, remoteRegistrationStep(ctx, cfg, keyMgr, persona, plat, key, configChanged))
+
+	// 6. Account match
+	user, output := sshAuthenticatedUser(target.Alias)
+	switch {
+	case user == "":
+		steps = append(steps, fixStep{Name: "Account", Detail: "not authenticated: " + firstLineOf(output), Hint: "fix the problems above first"})
+	case !strings.EqualFold(user, plat.Account):
+		steps = append(steps, fixStep{
+			Name:   "Account",
+			Detail: fmt.Sprintf("key authenticates as %s, not %s", user, plat.Account),
+			Hint:   fmt.Sprintf("remove the key from %s's account, then run 'git-keys fix' again to upload it to %s", user, plat.Account),
+		})
+	default:
+		steps = append(steps, fixStep{Name: "Account", OK: true, Detail: "authenticated as " + user})
+	}
+
+	return steps
+}
+
+// remoteRegistrationStep checks that the key is registered on the platform
+func remoteRegistrationStep(ctx context.Context, cfg *config.Config, keyMgr *sshkey.Manager, persona *config.Persona, plat *config.Platform, key *config.KeyConfig, configChanged *bool) fixStep {
+	client, err := newPlatformClient(plat)
+	if err != nil {
+		return fixStep{Name: "Remote", Detail: err.Error(), Hint: "store an API token with 'git-keys token set', or upload the public key by hand"}
+	}
+
+	remoteKeys, _, err := api.ListKeysCached(ctx, client, true)
+	if err != nil {
+		return fixStep{Name: "Remote", Detail: fmt.Sprintf("failed to list keys: %v", err), Hint: "check the API token with 'git-keys token check'"}
+	}
+
+	for _, remote := range remoteKeys {
+		info, err := sshkey.ParsePublicKey(remote.Key)
+		if err != nil || strings.TrimPrefix(info.Fingerprint, "SHA256:") != strings.TrimPrefix(key.Fingerprint, "SHA256:") {
+			continue
+		}
+		if key.RemoteID != remote.ID {
+			// Registered by hand or re-uploaded; record the current entry
+			key.RemoteID = remote.ID
+			*configChanged = true
+		}
+		return fixStep{Name: "Remote", OK: true, Detail: fmt.Sprintf("registered as %q", remote.Title)}
+	}
+
+	return fixStep{
+		Name:    "Remote",
+		Detail:  fmt.Sprintf("key is not registered on %s@%s", plat.Account, plat.Type),
+		FixText: "upload the key",
+		Fix: func() error {
+			publicKey, err := keyMgr.GetPublicKey(key.LocalPath)
+			if err != nil {
+				return err
+			}
+			settings := cfg.ResolveKeySettings(persona, plat)
+			title, err := sshkey.RenderTitle(settings, sshkey.DefaultTitleTemplate,
+				sshkey.NewNameData(persona, plat, key.Type, cfg.Machine.Name, time.Now()), key.ExpiresAt)
+			if err != nil {
+				return err
+			}
+			remoteID, err := client.AddKey(ctx, title, publicKey, key.ExpiresAt)
+			if err != nil {
+				return err
+			}
+			key.RemoteID = remoteID
+			*configChanged = true
+			return nil
+		},
+	}
+}
+
+var (
+	githubGreeting = regexp.MustCompile(`Hi ([^!/]+)[!/]`)
+	gitlabGreeting = regexp.MustCompile(`Welcome to GitLab, @([^!]+)!`)
+)
+
+// sshAuthenticatedUser connects to host with 'ssh -T' and returns the account
+// the platform greets, or "" and the output when authentication fails
+func sshAuthenticatedUser(host string) (string, string) {
+	cmd := exec.Command("ssh", "-T", "-o", "BatchMode=yes", "-o", "ConnectTimeout=10", "git@"+host)
+	output, _ := cmd.CombinedOutput()
+	text := strings.TrimSpace(string(output))
+
+	for _, greeting := range []*regexp.Regexp{githubGreeting, gitlabGreeting} {
+		if m := greeting.FindStringSubmatch(text); m != nil {
+			return m[1], text
+		}
+	}
+	return "", text
+}
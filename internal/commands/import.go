@@ -1,7 +1,6 @@
 package commands
 
 import (
-	"bufio"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -11,6 +10,7 @@ import (
 	"github.com/kunlu/git-keys/internal/config"
 	"github.com/kunlu/git-keys/internal/logger"
 	"github.com/kunlu/git-keys/internal/platform"
+	"github.com/kunlu/git-keys/internal/prompt"
 	"github.com/kunlu/git-keys/internal/sshconfig"
 	"github.com/spf13/cobra"
 )
@@ -18,6 +18,7 @@ import (
 var (
 	importInteractive bool
 	importDryRun      bool
+	importAuto        bool
 )
 
 // KeyImport represents a key to be imported
@@ -27,6 +28,8 @@ type KeyImport struct {
 	PersonaName string
 	Email       string
 	BaseURL     string
+	Account     string // Platform account; the persona name when empty
+	Host        string // SSH host alias the key is used with; the platform host when empty
 	Action      string // "move", "copy", or "reference"
 	TargetPath  string
 }
@@ -45,6 +48,12 @@ The wizard will:
   4. Update SSH config with managed blocks
   5. Create or update git-keys configuration
 
+With --auto, keys are mapped without prompting: SSH config IdentityFile
+entries and key comments name the platform, git conditional includes name the
+persona and email, and keys registered on accounts with a token (.env or
+keychain) name the account. The proposed configuration changes are shown as a
+diff and applied after one confirmation; keys stay where they are.
+
 All changes are backed up and reversible.`,
 	RunE: runImport,
 }
@@ -52,10 +61,21 @@ All changes are backed up and reversible.`,
 func init() {
 	importCmd.Flags().BoolVar(&importInteractive, "interactive", true, "Interactive wizard mode")
 	importCmd.Flags().BoolVar(&importDryRun, "dry-run", false, "Show what would be imported without making changes")
+	importCmd.Flags().BoolVar(&importAuto, "auto", false, "Map keys to personas and platforms without prompting")
 	rootCmd.AddCommand(importCmd)
 }
 
 func runImport(cmd *cobra.Command, args []string) error {
+	if importAuto {
+		logger.Info("Starting automatic import...")
+		fmt.Println()
+		return runAutoImport()
+	}
+
+	if nonInteractive {
+		return errNonInteractive("The import wizard", "use 'git-keys import --auto' to map keys without prompting")
+	}
+
 	logger.Info("Starting import wizard...")
 	fmt.Println()
 
@@ -91,8 +111,6 @@ func runImport(cmd *cobra.Command, args []string) error {
 	fmt.Println()
 
 	var imports []KeyImport
-	reader := bufio.NewReader(os.Stdin)
-
 	for _, key := range keys {
 		fmt.Printf("Key: %s\n", filepath.Base(key.Path))
 		if len(key.UsedBy) > 0 {
@@ -101,7 +119,7 @@ func runImport(cmd *cobra.Command, args []string) error {
 		fmt.Println()
 
 		// Ask if user wants to import this key
-		importKey := promptYesNo(reader, "  Import this key?")
+		importKey := prompt.Confirm("  Import this key?")
 		if !importKey {
 			fmt.Println("  ⊘ Skipping")
 			fmt.Println()
@@ -109,7 +127,7 @@ func runImport(cmd *cobra.Command, args []string) error {
 		}
 
 		// Determine platform
-		platform := promptPlatform(reader, key)
+		platform := promptPlatform(key)
 		if platform == "skip" {
 			fmt.Println("  ⊘ Skipping")
 			fmt.Println()
@@ -117,20 +135,20 @@ func runImport(cmd *cobra.Command, args []string) error {
 		}
 
 		// Determine persona
-		persona := promptString(reader, "  Persona name (e.g., personal, work)")
+		persona := prompt.Input("  Persona name (e.g., personal, work)", "")
 		if persona == "" {
 			persona = "default"
 		}
 
 		// Get email
-		email := promptString(reader, "  Email for commits")
+		email := prompt.Input("  Email for commits", "")
 
 		// Get base URL for GitLab
 		baseURL := ""
 		if platform == "gitlab" {
-			selfHosted := promptYesNo(reader, "  Is this self-hosted GitLab?")
+			selfHosted := prompt.Confirm("  Is this self-hosted GitLab?")
 			if selfHosted {
-				baseURL = promptString(reader, "  GitLab URL (e.g., https://gitlab.company.com)")
+				baseURL = prompt.Input("  GitLab URL (e.g., https://gitlab.company.com)", "")
 			}
 		}
 
@@ -178,7 +196,7 @@ func runImport(cmd *cobra.Command, args []string) error {
 	fmt.Println("     - git-keys manages copies")
 	fmt.Println()
 
-	choice := promptChoice(reader, "  Choice", []string{"1", "2", "3"}, "1")
+	choice := prompt.Choice("  Choice", []string{"1", "2", "3"}, "1")
 
 	action := "move"
 	switch choice {
@@ -242,7 +260,7 @@ func runImport(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
-	proceed := promptYesNo(reader, "Proceed with import?")
+	proceed := prompt.Confirm("Proceed with import?")
 	if !proceed {
 		fmt.Println()
 		fmt.Println("Import cancelled.")
@@ -301,7 +319,10 @@ func executeImport(imports []KeyImport, sshDir, gitKeysDir string) error {
 	osVersion, _ := plat.GetOSVersion()
 
 	// Load or create config
-	configPath := config.GetDefaultConfigPath()
+	configPath := cfgFile
+	if configPath == "" {
+		configPath = config.GetDefaultConfigPath()
+	}
 	mgr := config.NewManager(configPath)
 
 	var cfg *config.Config
@@ -322,6 +343,11 @@ func executeImport(imports []KeyImport, sshDir, gitKeysDir string) error {
 
 	fmt.Println("  ✓ Created machine profile")
 
+	// Relocated keys live in the git-keys directory; manage new keys there too
+	if len(imports) > 0 && imports[0].Action != "reference" && cfg.Defaults.KeysDir == "" {
+		cfg.Defaults.KeysDir = gitKeysDir
+	}
+
 	// Process each import
 
 	for _, imp := range imports {
@@ -338,10 +364,14 @@ func executeImport(imports []KeyImport, sshDir, gitKeysDir string) error {
 			platformType = config.PlatformGitLab
 		}
 
-		// Create platform config
+		// Create platform config, using the persona name as account if unknown
+		account := imp.Account
+		if account == "" {
+			account = imp.PersonaName
+		}
 		platformCfg := config.Platform{
 			Type:    platformType,
-			Account: imp.PersonaName, // Using persona name as account
+			Account: account,
 			BaseURL: imp.BaseURL,
 			Keys:    []config.KeyConfig{},
 		}
@@ -372,7 +402,7 @@ func executeImport(imports []KeyImport, sshDir, gitKeysDir string) error {
 	fmt.Println("  ✓ Updated configuration")
 
 	// Update SSH config
-	if err := updateSSHConfigForImport(imports, sshDir); err != nil {
+	if err := updateSSHConfigForImport(imports, sshDir, cfg.Defaults.SSHInclude); err != nil {
 		logger.Warn("Failed to update SSH config: %v", err)
 		fmt.Println("  ⚠ Could not update SSH config automatically")
 		fmt.Println("    You may need to update it manually")
@@ -434,9 +464,10 @@ func copyOrMoveKey(src, dst string, move bool) error {
 	return nil
 }
 
-func updateSSHConfigForImport(imports []KeyImport, sshDir string) error {
+func updateSSHConfigForImport(imports []KeyImport, sshDir, sshInclude string) error {
 	configPath := filepath.Join(sshDir, "config")
 	mgr := sshconfig.NewManager(configPath)
+	mgr.UseIncludeFile(sshInclude)
 
 	// Build SSH config entries for all imports
 	var entries []sshconfig.Entry
@@ -457,8 +488,13 @@ func updateSSHConfigForImport(imports []KeyImport, sshDir string) error {
 			}
 		}
 
+		alias := host
+		if imp.Host != "" {
+			alias = imp.Host
+		}
+
 		entry := sshconfig.Entry{
-			Host:         host,
+			Host:         alias,
 			HostName:     host,
 			IdentityFile: imp.TargetPath,
 			User:         "git",
@@ -475,53 +511,9 @@ func updateSSHConfigForImport(imports []KeyImport, sshDir string) error {
 	return nil
 }
 
-// Helper functions for prompts
-
-func promptYesNo(reader *bufio.Reader, prompt string) bool {
-	for {
-		fmt.Printf("%s (y/n): ", prompt)
-		response, _ := reader.ReadString('\n')
-		response = strings.ToLower(strings.TrimSpace(response))
-
-		if response == "y" || response == "yes" {
-			return true
-		} else if response == "n" || response == "no" {
-			return false
-		}
-
-		fmt.Println("Please enter 'y' or 'n'")
-	}
-}
-
-func promptString(reader *bufio.Reader, prompt string) string {
-	fmt.Printf("%s: ", prompt)
-	response, _ := reader.ReadString('\n')
-	return strings.TrimSpace(response)
-}
-
-func promptChoice(reader *bufio.Reader, prompt string, choices []string, defaultChoice string) string {
-	for {
-		choiceStr := strings.Join(choices, "/")
-		fmt.Printf("%s [%s]: ", prompt, choiceStr)
-		response, _ := reader.ReadString('\n')
-		response = strings.TrimSpace(response)
-
-		if response == "" {
-			return defaultChoice
-		}
-
-		for _, choice := range choices {
-			if response == choice {
-				return response
-			}
-		}
-
-		fmt.Printf("Please enter one of: %s\n", choiceStr)
-	}
-}
-
-func promptPlatform(reader *bufio.Reader, key DiscoveredKey) string {
-	// Try to guess from key usage
+// promptPlatform asks for the platform of key, defaulting to GitLab when
+// the key is used for a GitLab host
+func promptPlatform(key DiscoveredKey) string {
 	defaultPlatform := "github"
 	for _, host := range key.UsedBy {
 		if strings.Contains(host, "gitlab") {
@@ -530,24 +522,5 @@ func promptPlatform(reader *bufio.Reader, key DiscoveredKey) string {
 		}
 	}
 
-	fmt.Printf("  Platform [github/gitlab/other/skip] (default: %s): ", defaultPlatform)
-	response, _ := reader.ReadString('\n')
-	response = strings.ToLower(strings.TrimSpace(response))
-
-	if response == "" {
-		return defaultPlatform
-	}
-
-	validPlatforms := map[string]bool{
-		"github": true,
-		"gitlab": true,
-		"other":  true,
-		"skip":   true,
-	}
-
-	if validPlatforms[response] {
-		return response
-	}
-
-	return defaultPlatform
+	return prompt.Choice("  Platform", []string{"github", "gitlab", "other", "skip"}, defaultPlatform)
 }
@@ -0,0 +1,419 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/kunlu/git-keys/internal/api"
+	"github.com/kunlu/git-keys/internal/config"
+	"github.com/kunlu/git-keys/internal/logger"
+	"github.com/kunlu/git-keys/internal/prompt"
+)
+
+// remoteKeyOwner is the account a key is registered on
+type remoteKeyOwner struct {
+	Platform config.PlatformType
+	BaseURL  string
+	Account  string
+}
+
+// autoImport is a key mapped to a persona and platform without prompting,
+// with the evidence the mapping is based on
+type autoImport struct {
+	KeyImport
+	Evidence []string
+	Warnings []string
+}
+
+// runAutoImport maps existing keys to personas and platforms from the SSH
+// config, git conditional includes, key comments and the keys registered on
+// the platforms, then asks once to apply the proposed changes
+func runAutoImport() error {
+	fmt.Println("🔍 Discovering existing SSH setup...")
+	fmt.Println()
+
+	sshDir := filepath.Join(os.Getenv("HOME"), ".ssh")
+	keys, err := scanSSHKeys(sshDir)
+	if err != nil {
+		return fmt.Errorf("failed to scan SSH keys: %w", err)
+	}
+	if len(keys) == 0 {
+		fmt.Println("No SSH keys found. Nothing to import.")
+		fmt.Println()
+		fmt.Println("To create a new setup, run: git-keys init")
+		return nil
+	}
+
+	result := &ScanResult{Keys: keys}
+	if hosts, err := scanSSHConfig(sshDir); err != nil {
+		logger.Warn("Failed to parse SSH config: %v", err)
+	} else {
+		result.SSHConfigHosts = hosts
+	}
+	matchKeysToHosts(result)
+	if gitConf, err := scanGitConfig(); err != nil {
+		logger.Warn("Failed to parse Git config: %v", err)
+	} else {
+		result.GitConfig = gitConf
+	}
+
+	cfg := loadImportConfig()
+	owners := remoteKeyOwners(context.Background(), cfg)
+	managed := managedKeyCreationTimes()
+
+	var imports []autoImport
+	for _, key := range result.Keys {
+		name := filepath.Base(key.Path)
+		if _, ok := managed[key.Fingerprint]; ok {
+			fmt.Printf("  ⊘ %s: already managed by git-keys\n", name)
+			continue
+		}
+		imp, reason := inferKeyImport(key, result, owners)
+		if imp == nil {
+			fmt.Printf("  ⊘ %s: %s\n", name, reason)
+			continue
+		}
+		if other := duplicateImport(cfg, imports, imp); other != "" {
+			fmt.Printf("  ⊘ %s: %s/%s@%s is already %s\n", name, imp.PersonaName, imp.Platform, imp.Account, other)
+			continue
+		}
+		imports = append(imports, *imp)
+	}
+
+	if len(imports) == 0 {
+		fmt.Println()
+		fmt.Println("No keys could be mapped automatically. Run 'git-keys import' to map them by hand.")
+		return nil
+	}
+
+	fmt.Println()
+	printHeader("📝 Proposed configuration changes")
+	printAutoImportDiff(cfg, imports)
+
+	fmt.Println("  Keys stay where they are; git-keys references them in place.")
+	fmt.Println()
+
+	if importDryRun {
+		fmt.Println("  [DRY RUN - no changes made]")
+		return nil
+	}
+
+	if !nonInteractive && !prompt.Confirm("Apply these changes?") {
+		fmt.Println()
+		fmt.Println("Import cancelled.")
+		return nil
+	}
+
+	fmt.Println()
+	fmt.Println("⚙️  Executing import...")
+	fmt.Println()
+
+	keyImports := make([]KeyImport, len(imports))
+	for i, imp := range imports {
+		keyImports[i] = imp.KeyImport
+	}
+	if err := executeImport(keyImports, sshDir, filepath.Join(sshDir, "git-keys")); err != nil {
+		return fmt.Errorf("import failed: %w", err)
+	}
+
+	fmt.Println()
+	fmt.Println("✅ Import complete! Review it with 'git-keys plan', then run 'git-keys apply'.")
+	return nil
+}
+
+// inferKeyImport maps a key to a persona and platform. It returns nil and
+// the reason when the key's platform cannot be told.
+func inferKeyImport(key DiscoveredKey, result *ScanResult, owners map[string]remoteKeyOwner) (*autoImport, string) {
+	imp := &autoImport{KeyImport: KeyImport{SourcePath: key.Path, TargetPath: key.Path, Action: "reference"}}
+	var personaHint string
+
+	// Keys generated by git-keys name their platform and account
+	if parts := strings.Split(key.Comment, ":"); len(parts) >= 3 && parts[0] == "git-keys" &&
+		(parts[1] == string(config.PlatformGitHub) || parts[1] == string(config.PlatformGitLab)) {
+		imp.Platform, imp.Account = parts[1], parts[2]
+		imp.Evidence = append(imp.Evidence, "git-keys key comment")
+	} else if email := commentEmail(key.Comment); email != "" {
+		imp.Email = email
+		imp.Evidence = append(imp.Evidence, "email in key comment")
+	}
+
+	// SSH config hosts using the key name the platform host
+	for _, host := range result.SSHConfigHosts {
+		if host.IdentityFile != key.Path {
+			continue
+		}
+		hostname := host.HostName
+		if hostname == "" {
+			hostname = host.Host
+		}
+		platformType, baseURL, _ := parseGitRemoteURL("git@" + hostname + ":")
+		if platformType == "" {
+			continue
+		}
+		if imp.Platform == "" {
+			imp.Platform, imp.BaseURL = platformType, baseURL
+		}
+		if imp.Host == "" {
+			imp.Host = host.Host
+			personaHint = hostAliasSuffix(host.Host, hostname)
+			evidence := "SSH config Host " + host.Host
+			if host.Source != "" && host.Source != "config" {
+				evidence += " in " + host.Source
+			}
+			imp.Evidence = append(imp.Evidence, evidence)
+		}
+	}
+
+	// The account a key is registered on is the strongest evidence
+	if owner, ok := owners[strings.TrimPrefix(key.Fingerprint, "SHA256:")]; ok {
+		imp.Platform, imp.BaseURL, imp.Account = string(owner.Platform), owner.BaseURL, owner.Account
+		imp.Evidence = append(imp.Evidence, fmt.Sprintf("registered on %s as %s", owner.Platform, owner.Account))
+	}
+
+	if imp.Platform == "" {
+		return nil, "no SSH config host, key comment or registered key names its platform"
+	}
+
+	// A conditional include supplies the persona, its email and often the account
+	if inc := matchGitInclude(imp, personaHint, result.GitConfig.Includes); inc != nil {
+		imp.PersonaName = includePersonaName(inc)
+		if imp.Email == "" {
+			imp.Email = inc.Email
+		}
+		imp.Evidence = append(imp.Evidence, "includeIf gitdir:"+inc.Condition)
+		if imp.Account == "" {
+			for _, p := range inc.DiscoveredPlatforms {
+				if p.Type == imp.Platform && p.BaseURL == imp.BaseURL && len(p.Groups) == 1 {
+					imp.Account = p.Groups[0]
+					imp.Evidence = append(imp.Evidence, "repositories under the gitdir")
+					imp.Warnings = append(imp.Warnings, "account taken from the repositories' namespace, which may be an organization; check it")
+				}
+			}
+		}
+	}
+
+	if imp.PersonaName == "" {
+		imp.PersonaName = personaHint
+	}
+	if imp.PersonaName == "" {
+		imp.PersonaName = "default"
+	}
+	if imp.Email == "" {
+		imp.Email = result.GitConfig.GlobalEmail
+	}
+	if imp.Account == "" {
+		imp.Account = imp.PersonaName
+		imp.Warnings = append(imp.Warnings, "account unknown; set it before 'git-keys apply'")
+	}
+	return imp, ""
+}
+
+// duplicateImport describes where the persona platform of imp is already
+// taken: configured, or mapped to another key. Returns "" if it is not.
+func duplicateImport(cfg *config.Config, imports []autoImport, imp *autoImport) string {
+	for _, other := range imports {
+		if other.PersonaName == imp.PersonaName && other.Platform == imp.Platform &&
+			other.BaseURL == imp.BaseURL && strings.EqualFold(other.Account, imp.Account) {
+			return "mapped to " + filepath.Base(other.SourcePath)
+		}
+	}
+	if cfg == nil {
+		return ""
+	}
+	if persona := cfg.FindPersona(imp.PersonaName); persona != nil {
+		for _, plat := range persona.Platforms {
+			if string(plat.Type) == imp.Platform && strings.EqualFold(plat.Account, imp.Account) {
+				return "configured"
+			}
+		}
+	}
+	return ""
+}
+
+// matchGitInclude picks the conditional include of a key: the one with the
+// key's email, the one named like its host alias, or the only include with
+// repositories on its platform
+func matchGitInclude(imp *autoImport, personaHint string, includes []GitInclude) *GitInclude {
+	for i := range includes {
+		if imp.Email != "" && strings.EqualFold(includes[i].Email, imp.Email) {
+			return &includes[i]
+		}
+	}
+	for i := range includes {
+		if personaHint != "" && includePersonaName(&includes[i]) == personaHint {
+			return &includes[i]
+		}
+	}
+
+	var found *GitInclude
+	for i := range includes {
+		for _, p := range includes[i].DiscoveredPlatforms {
+			if p.Type == imp.Platform && p.BaseURL == imp.BaseURL {
+				if found != nil {
+					return nil
+				}
+				found = &includes[i]
+				break
+			}
+		}
+	}
+	return found
+}
+
+// includePersonaName names a persona after the directory of an include's
+// gitdir, e.g. "work" for ~/Projects/work/
+func includePersonaName(inc *GitInclude) string {
+	dir := strings.TrimSuffix(strings.TrimSuffix(inc.Condition, "**"), "/")
+	return strings.ToLower(filepath.Base(dir))
+}
+
+// hostAliasSuffix returns what an SSH host alias adds to the platform host,
+// e.g. "work" for github.com-work or gitlab-work
+func hostAliasSuffix(alias, hostname string) string {
+	if strings.EqualFold(alias, hostname) {
+		return ""
+	}
+	suffix := strings.ToLower(alias)
+	for _, prefix := range []string{strings.ToLower(hostname), strings.SplitN(strings.ToLower(hostname), ".", 2)[0]} {
+		if rest, ok := strings.CutPrefix(suffix, prefix); ok {
+			suffix = rest
+			break
+		}
+	}
+	return strings.Trim(suffix, "-._")
+}
+
+// commentEmail returns the email address in a key comment, if any
+func commentEmail(comment string) string {
+	for _, field := range strings.Fields(comment) {
+		if at := strings.Index(field, "@"); at > 0 && strings.Contains(field[at:], ".") {
+			return field
+		}
+	}
+	return ""
+}
+
+// loadImportConfig loads the existing configuration, or returns nil
+func loadImportConfig() *config.Config {
+	configPath := cfgFile
+	if configPath == "" {
+		configPath = config.GetDefaultConfigPath()
+	}
+	mgr := config.NewManager(configPath)
+	if !mgr.Exists() {
+		return nil
+	}
+	cfg, err := mgr.Load()
+	if err != nil {
+		logger.Debug("Ignoring config for import: %v", err)
+		return nil
+	}
+	return cfg
+}
+
+// remoteKeyOwners lists the keys registered on every account with a token in
+// .env or, for configured accounts, the keychain, by fingerprint without the
+// SHA256: prefix. Accounts without a token are skipped.
+func remoteKeyOwners(ctx context.Context, cfg *config.Config) map[string]remoteKeyOwner {
+	envTokens := loadTokensFromEnv()
+
+	var platforms []*config.Platform
+	seen := make(map[string]bool)
+	addPlatform := func(plat *config.Platform) {
+		lane := platformLane(plat.Type, plat.BaseURL, plat.Account)
+		if !seen[lane] {
+			seen[lane] = true
+			platforms = append(platforms, plat)
+		}
+	}
+	if cfg != nil {
+		for personaIdx := range cfg.Personas {
+			for platformIdx := range cfg.Personas[personaIdx].Platforms {
+				addPlatform(&cfg.Personas[personaIdx].Platforms[platformIdx])
+			}
+		}
+	}
+	var envKeys []string
+	for key := range envTokens {
+		envKeys = append(envKeys, key)
+	}
+	sort.Strings(envKeys)
+	for _, key := range envKeys {
+		if account, ok := strings.CutPrefix(key, "GITHUB_API_TOKEN_"); ok && account != "" {
+			addPlatform(&config.Platform{Type: config.PlatformGitHub, Account: account})
+		} else if account, ok := strings.CutPrefix(key, "GITLAB_TOKEN_"); ok && account != "" {
+			addPlatform(&config.Platform{Type: config.PlatformGitLab, Account: account})
+		}
+	}
+
+	owners := make(map[string]remoteKeyOwner)
+	for _, plat := range platforms {
+		token, _, err := lookupToken(plat, envTokens)
+		if err != nil {
+			logger.Debug("Not checking %s@%s: %v", plat.Account, plat.Type, err)
+			continue
+		}
+		client, err := newPlatformClientWithToken(plat, token)
+		if err != nil {
+			logger.Debug("Not checking %s@%s: %v", plat.Account, plat.Type, err)
+			continue
+		}
+
+		logger.Info("Checking %s@%s for registered keys...", plat.Account, plat.Type)
+		remoteKeys, _, err := api.ListKeysCached(ctx, client, refreshRemote)
+		if err != nil {
+			logger.Warn("Failed to list keys of %s@%s: %v", plat.Account, plat.Type, err)
+			continue
+		}
+		for _, remote := range remoteKeys {
+			owners[remoteFingerprint(remote)] = remoteKeyOwner{Platform: plat.Type, BaseURL: plat.BaseURL, Account: plat.Account}
+		}
+	}
+	return owners
+}
+
+// printAutoImportDiff shows the personas and platforms an automatic import
+// adds to the configuration as a diff of its personas section
+func printAutoImportDiff(cfg *config.Config, imports []autoImport) {
+	var order []string
+	byPersona := make(map[string][]autoImport)
+	for _, imp := range imports {
+		if _, ok := byPersona[imp.PersonaName]; !ok {
+			order = append(order, imp.PersonaName)
+		}
+		byPersona[imp.PersonaName] = append(byPersona[imp.PersonaName], imp)
+	}
+
+	added := func(line string) {
+		fmt.Println(colorize(ansiGreen, "  + "+line))
+	}
+
+	fmt.Println("  personas:")
+	for _, name := range order {
+		group := byPersona[name]
+		if cfg != nil && cfg.FindPersona(name) != nil {
+			fmt.Printf("      - name: %s\n", name)
+			fmt.Println("        platforms:")
+		} else {
+			added(fmt.Sprintf("  - name: %s", name))
+			added(fmt.Sprintf("    email: %s", group[0].Email))
+			added("    platforms:")
+		}
+		for _, imp := range group {
+			added(fmt.Sprintf("      - type: %s", imp.Platform))
+			added(fmt.Sprintf("        account: %s", imp.Account))
+			if imp.BaseURL != "" {
+				added(fmt.Sprintf("        base_url: %s", imp.BaseURL))
+			}
+			fmt.Printf("          # %s, from %s\n", filepath.Base(imp.SourcePath), strings.Join(imp.Evidence, "; "))
+			for _, warning := range imp.Warnings {
+				fmt.Printf("          # ⚠ %s\n", warning)
+			}
+		}
+	}
+	fmt.Println()
+}
@@ -1,14 +1,13 @@
 package commands
 
 import (
-	"bufio"
 	"fmt"
-	"os"
 	"strings"
 
 	"github.com/kunlu/git-keys/internal/config"
 	"github.com/kunlu/git-keys/internal/logger"
 	"github.com/kunlu/git-keys/internal/platform"
+	"github.com/kunlu/git-keys/internal/prompt"
 	"github.com/spf13/cobra"
 )
 
@@ -22,16 +21,35 @@ This command will:
   2. Create a new .git-keys.yaml configuration file
   3. Guide you through setting up your first persona
   
-If a configuration file already exists, this command will fail unless --force is used.`,
+With --from-url, the configuration starts from a template instead, such as
+one a team hands to new hires: a .git-keys.yaml file served over HTTPS, or
+kept in a git repository (name the file after '#'). Machine-specific fields
+are filled in, and only the accounts and emails the template leaves empty
+are asked for, unless --email and --account give them.
+
+If a configuration file already exists, this command will fail unless --force is used.
+
+Examples:
+  git-keys init
+  git-keys init --from-url https://intranet.example.com/git-keys.yaml
+  git-keys init --from-url git@github.com:acme/onboarding.git#git-keys/engineering.yaml
+  git-keys init --yes --from-url https://intranet.example.com/git-keys.yaml \
+    --email work=jdoe@acme.com --account work/github=jdoe-acme`,
 	RunE: runInit,
 }
 
 var (
-	forceInit bool
+	forceInit    bool
+	initFromURL  string
+	initEmails   []string
+	initAccounts []string
 )
 
 func init() {
 	initCmd.Flags().BoolVarP(&forceInit, "force", "f", false, "overwrite existing configuration")
+	initCmd.Flags().StringVar(&initFromURL, "from-url", "", "start from a configuration template at an HTTPS URL or in a git repository")
+	initCmd.Flags().StringArrayVar(&initEmails, "email", nil, "email a template leaves empty, as persona=email (repeatable)")
+	initCmd.Flags().StringArrayVar(&initAccounts, "account", nil, "account a template leaves empty, as persona/platform=account (repeatable)")
 	rootCmd.AddCommand(initCmd)
 }
 
@@ -87,18 +105,18 @@ func runInit(cmd *cobra.Command, args []string) error {
 		OSVersion: osVersion,
 	})
 
+	if initFromURL != "" {
+		return initFromTemplate(mgr, cfg)
+	}
+
 	// Interactive setup
 	fmt.Println("\n=== Git-Keys Setup ===")
 	fmt.Println()
 
-	// Ask if user wants to add a persona now
-	reader := bufio.NewReader(os.Stdin)
-	fmt.Print("Would you like to add a persona now? (y/n): ")
-	response, _ := reader.ReadString('\n')
-	response = strings.TrimSpace(strings.ToLower(response))
-
-	if response == "y" || response == "yes" {
-		persona, err := promptForPersona(reader)
+	// Ask if user wants to add a persona now; non-interactive mode leaves
+	// that to 'git-keys persona add'
+	if !nonInteractive && prompt.Confirm("Would you like to add a persona now?") {
+		persona, err := promptForPersona()
 		if err != nil {
 			return fmt.Errorf("failed to create persona: %w", err)
 		}
@@ -119,39 +137,68 @@ func runInit(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-func promptForPersona(reader *bufio.Reader) (*config.Persona, error) {
-	persona := &config.Persona{}
+// initFromTemplate creates the configuration from the template at
+// initFromURL, with the machine and defaults of base
+func initFromTemplate(mgr *config.Manager, base *config.Config) error {
+	data, err := fetchTemplate(initFromURL)
+	if err != nil {
+		return err
+	}
+	cfg, err := config.FromTemplate(data)
+	if err != nil {
+		return err
+	}
 
-	fmt.Print("\nPersona name (e.g., personal, work): ")
-	name, _ := reader.ReadString('\n')
-	persona.Name = strings.TrimSpace(name)
+	cfg.Machine = base.Machine
+	cfg.Machines = base.Machines
+	if cfg.Defaults.KeyType == "" {
+		cfg.Defaults.KeyType = base.Defaults.KeyType
+	}
+	if cfg.Defaults.SSHConfigPath == "" {
+		cfg.Defaults.SSHConfigPath = base.Defaults.SSHConfigPath
+	}
 
-	fmt.Print("Email (for git commits): ")
-	email, _ := reader.ReadString('\n')
-	persona.Email = strings.TrimSpace(email)
+	fmt.Printf("\n=== Git-Keys Setup from %s ===\n\n", initFromURL)
+	for _, persona := range cfg.Personas {
+		var platforms []string
+		for _, plat := range persona.Platforms {
+			platforms = append(platforms, string(plat.Type))
+		}
+		fmt.Printf("  Persona %s: %s\n", persona.Name, strings.Join(platforms, ", "))
+	}
+	fmt.Println()
 
-	// Ask for platform
-	fmt.Print("\nAdd a platform? (y/n): ")
-	response, _ := reader.ReadString('\n')
-	response = strings.TrimSpace(strings.ToLower(response))
+	if err := fillTemplate(cfg); err != nil {
+		return err
+	}
 
-	if response == "y" || response == "yes" {
-		platform, err := promptForPlatform(reader)
-		if err != nil {
-			return nil, err
-		}
-		persona.Platforms = append(persona.Platforms, *platform)
+	if err := mgr.Save(cfg); err != nil {
+		return fmt.Errorf("failed to save configuration: %w", err)
 	}
 
+	fmt.Printf("\n✅ Configuration saved to: %s\n", mgr.GetPath())
+	fmt.Println("\nNext steps:")
+	fmt.Println("  1. Run 'git-keys plan' to see what changes will be made")
+	fmt.Println("  2. Run 'git-keys apply' to generate keys and update SSH config")
+	return nil
+}
+
+func promptForPersona() (*config.Persona, error) {
+	persona := &config.Persona{}
+
+	persona.Name = prompt.Input("\nPersona name (e.g., personal, work)", "")
+	persona.Email = prompt.Input("Email (for git commits)", "")
+
+	// Pick the persona's platforms in one pass
+	persona.Platforms = append(persona.Platforms, promptForPlatforms(platformChoices(nil))...)
+
 	return persona, nil
 }
 
-func promptForPlatform(reader *bufio.Reader) (*config.Platform, error) {
+func promptForPlatform() (*config.Platform, error) {
 	platform := &config.Platform{}
 
-	fmt.Print("Platform type (github/gitlab): ")
-	platformType, _ := reader.ReadString('\n')
-	platformType = strings.TrimSpace(strings.ToLower(platformType))
+	platformType := strings.ToLower(prompt.Input("Platform type (github/gitlab)", ""))
 
 	switch platformType {
 	case "github":
@@ -162,19 +209,140 @@ func promptForPlatform(reader *bufio.Reader) (*config.Platform, error) {
 		return nil, fmt.Errorf("invalid platform type: %s", platformType)
 	}
 
-	fmt.Print("Account/username: ")
-	account, _ := reader.ReadString('\n')
-	platform.Account = strings.TrimSpace(account)
+	platform.Account = prompt.Input("Account/username", "")
 
 	if platform.Type == config.PlatformGitLab {
-		fmt.Print("GitLab base URL (press Enter for gitlab.com): ")
-		baseURL, _ := reader.ReadString('\n')
-		baseURL = strings.TrimSpace(baseURL)
-		if baseURL == "" {
-			baseURL = "https://gitlab.com"
-		}
-		platform.BaseURL = baseURL
+		platform.BaseURL = prompt.Input("GitLab base URL", "https://gitlab.com")
 	}
 
 	return platform, nil
 }
+
+// platformChoice is a platform offered when setting up a persona
+type platformChoice struct {
+	Label      string
+	Type       config.PlatformType
+	BaseURL    string // "" for github.com and gitlab.com
+	Discovered bool   // Seen in the remotes of local repositories
+	Other      bool   // Self-hosted GitLab whose URL is asked for
+}
+
+// platformChoices returns the platforms to offer: GitHub.com, GitLab.com,
+// any self-hosted GitLab instances seen in repositories, and another
+// self-hosted GitLab
+func platformChoices(discovered []RecommendedPlatform) []platformChoice {
+	choices := []platformChoice{
+		{Label: "GitHub.com", Type: config.PlatformGitHub},
+		{Label: "GitLab.com", Type: config.PlatformGitLab},
+	}
+
+	for _, p := range discovered {
+		switch {
+		case p.Type == "github":
+			choices[0].Discovered = true
+		case p.Type == "gitlab" && p.BaseURL == "":
+			choices[1].Discovered = true
+		case p.Type == "gitlab":
+			seen := false
+			for _, c := range choices {
+				seen = seen || c.BaseURL == p.BaseURL
+			}
+			if !seen {
+				choices = append(choices, platformChoice{
+					Label:      fmt.Sprintf("GitLab (%s)", p.BaseURL),
+					Type:       config.PlatformGitLab,
+					BaseURL:    p.BaseURL,
+					Discovered: true,
+				})
+			}
+		}
+	}
+
+	return append(choices, platformChoice{Label: "Other self-hosted GitLab", Type: config.PlatformGitLab, Other: true})
+}
+
+// promptForPlatforms lets the user pick several platforms at once and enter
+// one username for all of them, with per-platform overrides for accounts
+// that differ
+func promptForPlatforms(choices []platformChoice) []config.Platform {
+	var defaults []string
+	fmt.Println("\n  Platforms:")
+	for i, c := range choices {
+		marker := ""
+		if c.Discovered {
+			marker = "  (found in your repositories)"
+			defaults = append(defaults, fmt.Sprintf("%d", i+1))
+		}
+		fmt.Printf("    [%d] %s%s\n", i+1, c.Label, marker)
+	}
+
+	question := "  Select platforms (e.g. 1,2; Enter for none): "
+	if len(defaults) > 0 {
+		question = fmt.Sprintf("  Select platforms (e.g. 1,2; Enter for %s): ", strings.Join(defaults, ","))
+	}
+	fmt.Print(question)
+	selection, _ := prompt.Line()
+	if selection == "" {
+		selection = strings.Join(defaults, ",")
+	}
+
+	var selected []platformChoice
+	for _, field := range strings.FieldsFunc(selection, func(r rune) bool { return r == ',' || r == ' ' }) {
+		var n int
+		if _, err := fmt.Sscanf(field, "%d", &n); err != nil || n < 1 || n > len(choices) {
+			fmt.Printf("    Ignoring invalid choice: %s\n", field)
+			continue
+		}
+		selected = append(selected, choices[n-1])
+	}
+	if len(selected) == 0 {
+		return nil
+	}
+
+	// Resolve the URL of other self-hosted instances first
+	for i := range selected {
+		if !selected[i].Other {
+			continue
+		}
+		baseURL := strings.TrimSuffix(prompt.Input("  GitLab base URL (e.g. https://gitlab.company.com)", ""), "/")
+		if baseURL != "" && !strings.Contains(baseURL, "://") {
+			baseURL = "https://" + baseURL
+		}
+		selected[i].BaseURL = baseURL
+		selected[i].Label = fmt.Sprintf("GitLab (%s)", baseURL)
+	}
+
+	username := prompt.Input("  Username (used for all selected platforms)", "")
+
+	perPlatform := username == ""
+	if !perPlatform && len(selected) > 1 {
+		perPlatform = prompt.Confirm("  Use a different username on some platforms?")
+	}
+
+	var platforms []config.Platform
+	for _, c := range selected {
+		if c.Other && c.BaseURL == "" {
+			fmt.Println("    Skipping self-hosted GitLab without a base URL")
+			continue
+		}
+
+		account := username
+		if perPlatform {
+			account = prompt.Input(fmt.Sprintf("    %s username", c.Label), username)
+		}
+		if account == "" {
+			fmt.Printf("    Skipping %s without a username\n", c.Label)
+			continue
+		}
+
+		platforms = append(platforms, config.Platform{
+			Type:    c.Type,
+			Account: account,
+			BaseURL: c.BaseURL,
+			Keys:    []config.KeyConfig{},
+		})
+		fmt.Printf("    ✓ Added %s account: %s\n", c.Label, account)
+	}
+
+	return platforms
+}
@@ -0,0 +1,153 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kunlu/git-keys/internal/api"
+	"github.com/kunlu/git-keys/internal/config"
+	"github.com/kunlu/git-keys/internal/prompt"
+)
+
+// templateFileNames are looked up in a git repository given without a file
+var templateFileNames = []string{".git-keys.yaml", "git-keys.yaml", "git-keys.template.yaml"}
+
+// maxTemplateSize bounds a downloaded template
+const maxTemplateSize = 1 << 20
+
+// fetchTemplate returns a configuration template from an HTTPS URL or a git
+// repository. A file in a repository is named after '#', e.g.
+// git@github.com:acme/onboarding.git#git-keys/engineering.yaml.
+func fetchTemplate(source string) ([]byte, error) {
+	if isGitTemplateSource(source) {
+		return fetchGitTemplate(source)
+	}
+	if strings.HasPrefix(source, "http://") {
+		return nil, fmt.Errorf("templates must be fetched over HTTPS: %s", source)
+	}
+	if !strings.HasPrefix(source, "https://") {
+		return nil, fmt.Errorf("unsupported template URL %s (use https:// or a git repository)", source)
+	}
+
+	client := &http.Client{Timeout: api.DefaultTimeout}
+	resp, err := client.Get(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download template: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download template: %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxTemplateSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download template: %w", err)
+	}
+	if len(data) > maxTemplateSize {
+		return nil, fmt.Errorf("template is larger than %d bytes", maxTemplateSize)
+	}
+	return data, nil
+}
+
+// isGitTemplateSource reports whether source names a git repository rather
+// than a file served over HTTPS
+func isGitTemplateSource(source string) bool {
+	repo, _, _ := strings.Cut(source, "#")
+	return strings.HasPrefix(repo, "git@") || strings.HasPrefix(repo, "ssh://") ||
+		strings.HasPrefix(repo, "git://") || strings.HasSuffix(repo, ".git")
+}
+
+// fetchGitTemplate clones a repository shallowly and reads the template from
+// it
+func fetchGitTemplate(source string) ([]byte, error) {
+	repo, file, _ := strings.Cut(source, "#")
+
+	dir, err := os.MkdirTemp("", "git-keys-template-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	fmt.Printf("Cloning %s...\n", repo)
+	if _, err := syncGit("", "clone", "--quiet", "--depth", "1", repo, dir); err != nil {
+		return nil, fmt.Errorf("failed to clone %s: %w", repo, err)
+	}
+
+	names := templateFileNames
+	if file != "" {
+		names = []string{filepath.FromSlash(file)}
+	}
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		if rel, err := filepath.Rel(dir, path); err != nil || strings.HasPrefix(rel, "..") {
+			return nil, fmt.Errorf("template path %s is outside the repository", name)
+		}
+		data, err := os.ReadFile(path)
+		if err == nil {
+			return data, nil
+		}
+		if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read template: %w", err)
+		}
+	}
+	return nil, fmt.Errorf("no template found in %s (looked for %s)", repo, strings.Join(names, ", "))
+}
+
+// fillTemplate fills in the emails and accounts a template leaves empty from
+// --email and --account, and prompts for the rest
+func fillTemplate(cfg *config.Config) error {
+	emails, err := parseTargetAnswers(cfg, "--email", initEmails)
+	if err != nil {
+		return err
+	}
+	accounts, err := parseTargetAnswers(cfg, "--account", initAccounts)
+	if err != nil {
+		return err
+	}
+
+	for i := range cfg.Personas {
+		persona := &cfg.Personas[i]
+		if persona.Name == "" {
+			return fmt.Errorf("template persona %d has no name", i+1)
+		}
+
+		for _, answer := range emails {
+			if persona.Email == "" && answer.target.Persona == persona.Name {
+				persona.Email = answer.value
+			}
+		}
+		if persona.Email == "" {
+			if nonInteractive {
+				return fmt.Errorf("persona '%s' needs an email; pass --email %s=<email>", persona.Name, persona.Name)
+			}
+			if persona.Email = prompt.Input(fmt.Sprintf("  Email for persona '%s'", persona.Name), ""); persona.Email == "" {
+				return fmt.Errorf("persona '%s' needs an email", persona.Name)
+			}
+		}
+
+		for j := range persona.Platforms {
+			plat := &persona.Platforms[j]
+			if plat.Account == "" {
+				plat.Account = targetAnswerFor(accounts, persona, plat)
+			}
+			if plat.Account != "" {
+				continue
+			}
+			label := string(plat.Type)
+			if plat.BaseURL != "" {
+				label = fmt.Sprintf("%s (%s)", plat.Type, plat.BaseURL)
+			}
+			if nonInteractive {
+				return fmt.Errorf("%s platform of persona '%s' needs an account; pass --account %s/%s=<account>", label, persona.Name, persona.Name, plat.Type)
+			}
+			if plat.Account = prompt.Input(fmt.Sprintf("  %s account for persona '%s'", label, persona.Name), ""); plat.Account == "" {
+				return fmt.Errorf("%s platform of persona '%s' needs an account", label, persona.Name)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kunlu/git-keys/internal/config"
+	"github.com/kunlu/git-keys/internal/prompt"
+	"github.com/spf13/cobra"
+)
+
+// nonInteractive is set by --yes or --non-interactive: confirmations are
+// accepted without asking, and questions that need an answer take it from
+// flags or fail, so no command waits on stdin
+var nonInteractive bool
+
+// setupNonInteractive ties the global flags to the --yes flags of single
+// commands: a command's own --yes turns on non-interactive mode, and the
+// global flags answer the command's confirmation. Confirmations asked
+// through the prompt package are then accepted as well.
+func setupNonInteractive(cmd *cobra.Command) {
+	if yes := cmd.Flags().Lookup("yes"); yes != nil {
+		if yes.Changed && yes.Value.String() == "true" {
+			nonInteractive = true
+		} else if nonInteractive {
+			yes.Value.Set("true")
+		}
+	}
+	prompt.SetAssumeYes(nonInteractive)
+}
+
+// errNonInteractive is returned in non-interactive mode by a command that
+// would prompt without a flag-based answer; hint says what to run instead
+func errNonInteractive(what, hint string) error {
+	return fmt.Errorf("%s prompts, which non-interactive mode does not allow; %s", what, hint)
+}
+
+// targetAnswer answers a question about the platforms a target selects, given
+// as a flag value of the form persona[/platform[@account]]=value
+type targetAnswer struct {
+	target *platformTarget
+	value  string
+}
+
+// parseTargetAnswers parses the values of a repeatable answer flag
+func parseTargetAnswers(cfg *config.Config, flag string, values []string) ([]targetAnswer, error) {
+	var answers []targetAnswer
+	for _, v := range values {
+		targetPart, value, ok := strings.Cut(v, "=")
+		value = strings.TrimSpace(value)
+		if !ok || value == "" {
+			return nil, fmt.Errorf("invalid %s %q (use persona[/platform[@account]]=value)", flag, v)
+		}
+		target, err := parsePlatformTarget(cfg, strings.TrimSpace(targetPart))
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q: %w", flag, v, err)
+		}
+		answers = append(answers, targetAnswer{target: target, value: value})
+	}
+	return answers, nil
+}
+
+// targetAnswerFor returns the first answer whose target selects a persona's
+// platform, or ""
+func targetAnswerFor(answers []targetAnswer, persona *config.Persona, plat *config.Platform) string {
+	for _, answer := range answers {
+		if answer.target.includes(persona, plat) {
+			return answer.value
+		}
+	}
+	return ""
+}
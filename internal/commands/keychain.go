@@ -1,15 +1,19 @@
 package commands
 
 import (
-	"bufio"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/kunlu/git-keys/internal/config"
 	"github.com/kunlu/git-keys/internal/logger"
+	"github.com/kunlu/git-keys/internal/prompt"
+	"github.com/kunlu/git-keys/internal/sshkey"
 	"github.com/spf13/cobra"
 )
 
@@ -120,10 +124,14 @@ func runKeychainAdd(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
-	fmt.Printf("\n🔑 Adding SSH Keys to Keychain\n")
-	fmt.Printf("==============================\n\n")
+	printHeader("\n🔑 Adding SSH Keys to Keychain")
+	fmt.Println()
+
+	// Non-interactive mode adds every key, as --all does
+	if nonInteractive {
+		keychainAll = true
+	}
 
-	reader := bufio.NewReader(os.Stdin)
 	addedCount := 0
 	skippedCount := 0
 
@@ -146,11 +154,7 @@ func runKeychainAdd(cmd *cobra.Command, args []string) error {
 
 		if !keychainAll {
 			// Interactive mode - prompt for confirmation
-			fmt.Printf("Add %s to Keychain?%s [Y/n]: ", keyName, status)
-			response, _ := reader.ReadString('\n')
-			response = strings.ToLower(strings.TrimSpace(response))
-
-			if response == "n" || response == "no" {
+			if !prompt.ConfirmDefault(fmt.Sprintf("Add %s to Keychain?%s", keyName, status), true) {
 				fmt.Printf("  ⊘ Skipped\n\n")
 				skippedCount++
 				continue
@@ -158,7 +162,7 @@ func runKeychainAdd(cmd *cobra.Command, args []string) error {
 		}
 
 		// Add key to Keychain
-		if err := addKeyToKeychain(keyPath); err != nil {
+		if err := addKeyToKeychain(cfg.Defaults, keyPath); err != nil {
 			logger.Warn("Failed to add %s: %v", keyName, err)
 			skippedCount++
 			continue
@@ -180,14 +184,11 @@ func runKeychainAdd(cmd *cobra.Command, args []string) error {
 		fmt.Println("\nVerify with: ssh-add -l")
 
 		// Prompt to test SSH connections
-		fmt.Print("\nTest SSH connections to verify setup? [Y/n]: ")
-		reader := bufio.NewReader(os.Stdin)
-		response, _ := reader.ReadString('\n')
-		response = strings.TrimSpace(strings.ToLower(response))
-
-		if response == "" || response == "y" || response == "yes" {
-			fmt.Println()
-			testSSHConnections(cfg)
+		if !nonInteractive {
+			if prompt.ConfirmDefault("\nTest SSH connections to verify setup?", true) {
+				fmt.Println()
+				testSSHConnections(cfg)
+			}
 		}
 	}
 
@@ -219,10 +220,14 @@ func runKeychainRemove(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
-	fmt.Printf("\n🔑 Removing SSH Keys from Agent\n")
-	fmt.Printf("================================\n\n")
+	printHeader("\n🔑 Removing SSH Keys from Agent")
+	fmt.Println()
+
+	// Non-interactive mode removes every key, as --all does
+	if nonInteractive {
+		keychainAll = true
+	}
 
-	reader := bufio.NewReader(os.Stdin)
 	removedCount := 0
 	skippedCount := 0
 
@@ -242,11 +247,7 @@ func runKeychainRemove(cmd *cobra.Command, args []string) error {
 
 		if !keychainAll {
 			// Interactive mode - prompt for confirmation
-			fmt.Printf("Remove %s from agent? [Y/n]: ", keyName)
-			response, _ := reader.ReadString('\n')
-			response = strings.ToLower(strings.TrimSpace(response))
-
-			if response == "n" || response == "no" {
+			if !prompt.ConfirmDefault(fmt.Sprintf("Remove %s from agent?", keyName), true) {
 				fmt.Printf("  ⊘ Skipped\n\n")
 				skippedCount++
 				continue
@@ -282,20 +283,20 @@ func runKeychainRemove(cmd *cobra.Command, args []string) error {
 // collectKeyPaths gathers all SSH key paths from the configuration
 func collectKeyPaths(cfg *config.Config) []string {
 	var keyPaths []string
-	homeDir, _ := os.UserHomeDir()
+	keysDir := cfg.Defaults.GetKeysDir()
 
 	for _, persona := range cfg.Personas {
+		if persona.Archived {
+			continue
+		}
 		for _, platform := range persona.Platforms {
 			for _, key := range platform.Keys {
-				// Expand path
-				keyPath := key.LocalPath
-				if strings.HasPrefix(keyPath, "~/") {
-					keyPath = filepath.Join(homeDir, keyPath[2:])
-				} else if !filepath.IsAbs(keyPath) {
-					keyPath = filepath.Join(homeDir, ".ssh", keyPath)
+				// Agent-held and public-only keys have no private key file to load
+				if !key.HasPrivateKey() {
+					continue
 				}
 
-				keyPaths = append(keyPaths, keyPath)
+				keyPaths = append(keyPaths, expandKeyPath(keysDir, key.LocalPath))
 			}
 		}
 	}
@@ -303,50 +304,90 @@ func collectKeyPaths(cfg *config.Config) []string {
 	return keyPaths
 }
 
-// addKeyToKeychain adds an SSH key to the macOS Keychain and SSH agent
-func addKeyToKeychain(keyPath string) error {
-	cmd := exec.Command("ssh-add", "--apple-use-keychain", keyPath)
-	output, err := cmd.CombinedOutput()
+// expandKeyPath returns the full path of a key's local path, which is
+// relative to the keys directory unless it is absolute or starts with ~/
+func expandKeyPath(keysDir, keyPath string) string {
+	if strings.HasPrefix(keyPath, "~/") {
+		homeDir, _ := os.UserHomeDir()
+		return filepath.Join(homeDir, keyPath[2:])
+	}
+	if !filepath.IsAbs(keyPath) {
+		return filepath.Join(keysDir, keyPath)
+	}
+	return keyPath
+}
+
+// addKeyToKeychain adds an SSH key to the SSH agent with the lifetime and
+// confirmation of the defaults. On macOS the key's passphrase is also stored
+// in the Keychain, which only Apple's ssh-add can do; elsewhere the key is
+// added over the agent protocol.
+func addKeyToKeychain(defaults config.Defaults, keyPath string) error {
+	if runtime.GOOS == "darwin" {
+		args := []string{"--apple-use-keychain"}
+		if defaults.AgentLifetime > 0 {
+			args = append(args, "-t", strconv.Itoa(int(defaults.AgentLifetime/time.Second)))
+		}
+		if defaults.AgentConfirm {
+			args = append(args, "-c")
+		}
+		cmd := exec.Command("ssh-add", append(args, keyPath)...)
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("%s: %w", strings.TrimSpace(string(output)), err)
+		}
+		return nil
+	}
+	return addKeyToAgent(defaults, keyPath)
+}
+
+// addKeyToAgent loads a private key into the SSH agent, asking for its
+// passphrase when it has one
+func addKeyToAgent(defaults config.Defaults, keyPath string) error {
+	agent, err := sshkey.ConnectAgent("")
 	if err != nil {
-		return fmt.Errorf("%s: %w", strings.TrimSpace(string(output)), err)
+		return err
+	}
+	defer agent.Close()
+
+	return agent.AddKey(keyPath, sshkey.AgentKeyOptions{
+		Lifetime:   defaults.AgentLifetime,
+		Confirm:    defaults.AgentConfirm,
+		Passphrase: agentPassphrase(keyPath),
+	})
+}
+
+// agentPassphrase asks for the passphrase of an encrypted key, unless
+// prompting is not allowed
+func agentPassphrase(keyPath string) func() ([]byte, error) {
+	if nonInteractive {
+		return nil
+	}
+	return func() ([]byte, error) {
+		return []byte(prompt.Secret(fmt.Sprintf("Enter passphrase for %s", keyPath))), nil
 	}
-	return nil
 }
 
 // removeKeyFromAgent removes an SSH key from the SSH agent
 func removeKeyFromAgent(keyPath string) error {
-	cmd := exec.Command("ssh-add", "-d", keyPath)
-	output, err := cmd.CombinedOutput()
+	agent, err := sshkey.ConnectAgent("")
 	if err != nil {
-		return fmt.Errorf("%s: %w", strings.TrimSpace(string(output)), err)
+		return err
 	}
-	return nil
+	defer agent.Close()
+
+	return agent.RemoveKey(keyPath)
 }
 
 // isKeyInAgent checks if a key is currently loaded in the SSH agent
 func isKeyInAgent(keyPath string) bool {
-	// Get list of loaded keys
-	cmd := exec.Command("ssh-add", "-l")
-	output, err := cmd.Output()
+	agent, err := sshkey.ConnectAgent("")
 	if err != nil {
 		return false
 	}
+	defer agent.Close()
 
-	// Get the public key fingerprint
-	pubKeyPath := keyPath + ".pub"
-	fingerprintCmd := exec.Command("ssh-keygen", "-lf", pubKeyPath)
-	fingerprintOutput, err := fingerprintCmd.Output()
-	if err != nil {
-		return false
-	}
-
-	fingerprint := strings.Fields(string(fingerprintOutput))
-	if len(fingerprint) < 2 {
-		return false
-	}
-
-	// Check if fingerprint exists in agent list
-	return strings.Contains(string(output), fingerprint[1])
+	loaded, err := agent.HasKey(keyPath)
+	return err == nil && loaded
 }
 
 // testSSHConnections tests SSH connections to all configured platforms
@@ -358,6 +399,9 @@ func testSSHConnections(cfg *config.Config) {
 	failureCount := 0
 
 	for _, persona := range cfg.Personas {
+		if persona.Archived {
+			continue
+		}
 		for _, platform := range persona.Platforms {
 			// Build SSH host based on platform
 			var hostname string
@@ -388,7 +432,7 @@ func testSSHConnections(cfg *config.Config) {
 			// Check for successful authentication
 			// GitHub: "Hi {username}! You've successfully authenticated"
 			// GitLab: "Welcome to GitLab, @{username}!"
-			if strings.Contains(outputStr, "successfully authenticated") || strings.Contains(outputStr, "Welcome to GitLab") {
+			if sshAuthenticated(outputStr) {
 				fmt.Printf("  ✓ %s (%s): %s\n", platform.Account, platform.Type, extractAuthMessage(outputStr))
 				successCount++
 			} else {
@@ -409,6 +453,12 @@ func testSSHConnections(cfg *config.Config) {
 	}
 }
 
+// sshAuthenticated reports whether the output of 'ssh -T' shows a successful
+// authentication
+func sshAuthenticated(output string) bool {
+	return strings.Contains(output, "successfully authenticated") || strings.Contains(output, "Welcome to GitLab")
+}
+
 // extractAuthMessage extracts the relevant authentication message from SSH output
 func extractAuthMessage(output string) string {
 	lines := strings.Split(output, "\n")
@@ -0,0 +1,123 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/kunlu/git-keys/internal/api"
+	"github.com/kunlu/git-keys/internal/config"
+	"github.com/kunlu/git-keys/internal/logger"
+	"github.com/kunlu/git-keys/internal/sshconfig"
+)
+
+// platformSSHOptions returns the extra options of a platform's managed SSH
+// config blocks: its own, and UserKnownHostsFile when host keys go to a file
+// other than ~/.ssh/known_hosts
+func platformSSHOptions(cfg *config.Config, platform *config.Platform) map[string]string {
+	options := platform.ExtraSSHOptions()
+	if file := knownHostsOption(cfg); file != "" {
+		options["UserKnownHostsFile"] = file
+	}
+	return options
+}
+
+// knownHostsOption returns the UserKnownHostsFile value that makes ssh read
+// the managed known_hosts file before ~/.ssh/known_hosts, or "" when host
+// keys are written to ~/.ssh/known_hosts itself
+func knownHostsOption(cfg *config.Config) string {
+	defaults := &cfg.Defaults
+	if defaults.DisableKnownHosts || defaults.KnownHostsFile == "" {
+		return ""
+	}
+	if filepath.Clean(defaults.GetKnownHostsFile()) == filepath.Clean((&config.Defaults{}).GetKnownHostsFile()) {
+		return ""
+	}
+	file := defaults.KnownHostsFile
+	if strings.ContainsAny(file, " \t") {
+		file = fmt.Sprintf("%q", file)
+	}
+	return file + " " + config.DefaultKnownHostsFile
+}
+
+// collectKnownHosts returns the host keys of the hosts the platforms connect
+// to: the platform's pinned host_keys, or for github.com the keys GitHub
+// publishes. Hosts without either, such as gitlab.com without host_keys, are
+// left to ssh.
+func collectKnownHosts(ctx context.Context, cfg *config.Config) ([]sshconfig.KnownHost, error) {
+	keys := make(map[string][]string)
+	var order []string
+	add := func(host string, hostKeys []string) {
+		if _, seen := keys[host]; !seen {
+			order = append(order, host)
+		}
+		for _, key := range hostKeys {
+			if !contains(keys[host], key) {
+				keys[host] = append(keys[host], key)
+			}
+		}
+	}
+
+	var githubKeys []string
+	for personaIdx := range cfg.Personas {
+		persona := &cfg.Personas[personaIdx]
+		if persona.Archived {
+			continue
+		}
+		for platformIdx := range persona.Platforms {
+			platform := &persona.Platforms[platformIdx]
+			_, hostname := sshHostAlias(persona, platform)
+			host := sshconfig.KnownHostPattern(hostname, sshOption(platform.ExtraSSHOptions(), "Port"))
+
+			switch {
+			case len(platform.HostKeys) > 0:
+				add(host, platform.HostKeys)
+			case hostname == "github.com":
+				if githubKeys == nil {
+					fetched, err := api.GitHubHostKeys(ctx)
+					if err != nil {
+						return nil, err
+					}
+					githubKeys = fetched
+				}
+				add(host, githubKeys)
+			default:
+				logger.Debug("No published host keys for %s; set host_keys to pin them", hostname)
+			}
+		}
+	}
+
+	hosts := make([]sshconfig.KnownHost, 0, len(order))
+	for _, host := range order {
+		hosts = append(hosts, sshconfig.KnownHost{Host: host, Keys: keys[host]})
+	}
+	return hosts, nil
+}
+
+// updateKnownHosts writes the host keys of the platforms to the managed
+// known_hosts file. Reports whether it changed.
+func updateKnownHosts(ctx context.Context, cfg *config.Config) (bool, error) {
+	if cfg.Defaults.DisableKnownHosts {
+		return false, nil
+	}
+	hosts, err := collectKnownHosts(ctx, cfg)
+	if err != nil {
+		return false, err
+	}
+	if len(hosts) == 0 {
+		return false, nil
+	}
+	return sshconfig.WriteKnownHosts(cfg.Defaults.GetKnownHostsFile(), hosts)
+}
+
+// sshOption returns an option of a Host block, whose names are not case
+// sensitive
+func sshOption(options map[string]string, name string) string {
+	for key, value := range options {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,268 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/kunlu/git-keys/internal/config"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	listJSON    bool
+	listYAML    bool
+	listPersona string
+)
+
+var listCmd = &cobra.Command{
+	Use:   "list <personas|platforms|keys>",
+	Short: "List personas, platforms or keys",
+	Long: `List the personas, platforms or keys in the configuration.
+
+This is read-only: nothing is fetched from the platforms and nothing is
+changed. Output is a table by default; use --json or --yaml for scripts, or
+--output json|yaml for a versioned document.
+
+Examples:
+  git-keys list personas
+  git-keys list platforms --persona work
+  git-keys list keys --json | jq '.[] | select(.status == "active")'
+`,
+	Args:        cobra.ExactArgs(1),
+	ValidArgs:   []string{"personas", "platforms", "keys"},
+	Annotations: map[string]string{structuredOutputAnnotation: structuredQuiet},
+	RunE:        runList,
+}
+
+func init() {
+	listCmd.Flags().BoolVar(&listJSON, "json", false, "Output as JSON")
+	listCmd.Flags().BoolVar(&listYAML, "yaml", false, "Output as YAML")
+	listCmd.Flags().StringVar(&listPersona, "persona", "", "Only list this persona")
+	listCmd.RegisterFlagCompletionFunc("persona", completePersonaFlag)
+	rootCmd.AddCommand(listCmd)
+}
+
+// personaListing is a row of 'list personas'
+type personaListing struct {
+	Name      string `json:"name" yaml:"name"`
+	Email     string `json:"email" yaml:"email"`
+	Platforms int    `json:"platforms" yaml:"platforms"`
+	Keys      int    `json:"keys" yaml:"keys"`
+	Archived  bool   `json:"archived" yaml:"archived"`
+}
+
+// platformListing is a row of 'list platforms'
+type platformListing struct {
+	Persona   string `json:"persona" yaml:"persona"`
+	Type      string `json:"type" yaml:"type"`
+	Account   string `json:"account" yaml:"account"`
+	BaseURL   string `json:"base_url,omitempty" yaml:"base_url,omitempty"`
+	Host      string `json:"host" yaml:"host"` // SSH host alias
+	GitDir    string `json:"gitdir,omitempty" yaml:"gitdir,omitempty"`
+	ActiveKey string `json:"active_key,omitempty" yaml:"active_key,omitempty"` // Fingerprint
+}
+
+// keyListing is a row of 'list keys'
+type keyListing struct {
+	Persona     string     `json:"persona" yaml:"persona"`
+	Platform    string     `json:"platform" yaml:"platform"`
+	Account     string     `json:"account" yaml:"account"`
+	Type        string     `json:"type" yaml:"type"`
+	Purpose     string     `json:"purpose" yaml:"purpose"` // auth or signing
+	Status      string     `json:"status" yaml:"status"`
+	Fingerprint string     `json:"fingerprint" yaml:"fingerprint"`
+	LocalPath   string     `json:"local_path" yaml:"local_path"`
+	RemoteID    string     `json:"remote_id,omitempty" yaml:"remote_id,omitempty"`
+	CreatedAt   *time.Time `json:"created_at,omitempty" yaml:"created_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty" yaml:"expires_at,omitempty"`
+}
+
+func runList(cmd *cobra.Command, args []string) error {
+	if listJSON && listYAML {
+		return fmt.Errorf("use either --json or --yaml, not both")
+	}
+
+	// Load configuration
+	configPath := cfgFile
+	if configPath == "" {
+		configPath = config.GetDefaultConfigPath()
+	}
+
+	mgr := config.NewManager(configPath)
+	if !mgr.Exists() {
+		return fmt.Errorf("configuration file not found at %s\nRun 'git-keys init' first", configPath)
+	}
+
+	cfg, err := mgr.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	if listPersona != "" && cfg.FindPersona(listPersona) == nil {
+		return fmt.Errorf("persona not found: %s", listPersona)
+	}
+
+	switch args[0] {
+	case "personas", "persona":
+		rows := listPersonas(cfg)
+		return writeListing("PersonaList", rows, []string{"NAME", "EMAIL", "PLATFORMS", "KEYS", "ARCHIVED"}, func(i int) []string {
+			r := rows[i]
+			archived := ""
+			if r.Archived {
+				archived = "yes"
+			}
+			return []string{r.Name, r.Email, fmt.Sprint(r.Platforms), fmt.Sprint(r.Keys), archived}
+		}, len(rows))
+	case "platforms", "platform":
+		rows := listPlatforms(cfg)
+		return writeListing("PlatformList", rows, []string{"PERSONA", "TYPE", "ACCOUNT", "HOST", "GITDIR", "ACTIVE KEY"}, func(i int) []string {
+			r := rows[i]
+			return []string{r.Persona, r.Type, r.Account, r.Host, r.GitDir, r.ActiveKey}
+		}, len(rows))
+	case "keys", "key":
+		rows := listKeys(cfg)
+		return writeListing("KeyList", rows, []string{"PERSONA", "PLATFORM", "ACCOUNT", "TYPE", "PURPOSE", "STATUS", "CREATED", "EXPIRES", "FINGERPRINT"}, func(i int) []string {
+			r := rows[i]
+			created, expires := "", ""
+			if r.CreatedAt != nil {
+				created = r.CreatedAt.Format("2006-01-02")
+			}
+			if r.ExpiresAt != nil {
+				expires = r.ExpiresAt.Format("2006-01-02")
+			}
+			return []string{r.Persona, r.Platform, r.Account, r.Type, r.Purpose, r.Status, created, expires, r.Fingerprint}
+		}, len(rows))
+	default:
+		return fmt.Errorf("unknown subject %q; use personas, platforms or keys", args[0])
+	}
+}
+
+func listPersonas(cfg *config.Config) []personaListing {
+	rows := []personaListing{}
+	for _, persona := range cfg.Personas {
+		if listPersona != "" && persona.Name != listPersona {
+			continue
+		}
+		keys := 0
+		for _, plat := range persona.Platforms {
+			keys += len(plat.Keys)
+		}
+		rows = append(rows, personaListing{
+			Name:      persona.Name,
+			Email:     persona.Email,
+			Platforms: len(persona.Platforms),
+			Keys:      keys,
+			Archived:  persona.Archived,
+		})
+	}
+	return rows
+}
+
+func listPlatforms(cfg *config.Config) []platformListing {
+	rows := []platformListing{}
+	for personaIdx := range cfg.Personas {
+		persona := &cfg.Personas[personaIdx]
+		if listPersona != "" && persona.Name != listPersona {
+			continue
+		}
+		for platformIdx := range persona.Platforms {
+			plat := &persona.Platforms[platformIdx]
+			alias, _ := sshHostAlias(persona, plat)
+			row := platformListing{
+				Persona: persona.Name,
+				Type:    string(plat.Type),
+				Account: plat.Account,
+				BaseURL: plat.BaseURL,
+				Host:    alias,
+				GitDir:  strings.Join(plat.AllGitDirs(), ", "),
+			}
+			if key := plat.GetActiveKey(); key != nil {
+				row.ActiveKey = key.Fingerprint
+			}
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+func listKeys(cfg *config.Config) []keyListing {
+	rows := []keyListing{}
+	for _, persona := range cfg.Personas {
+		if listPersona != "" && persona.Name != listPersona {
+			continue
+		}
+		for _, plat := range persona.Platforms {
+			// Signing keys are listed after the platform's authentication keys
+			for _, key := range append(append([]config.KeyConfig{}, plat.Keys...), plat.SigningKeys...) {
+				purpose := key.Purpose
+				if purpose == "" {
+					purpose = config.KeyPurposeAuth
+				}
+				row := keyListing{
+					Persona:     persona.Name,
+					Platform:    string(plat.Type),
+					Account:     plat.Account,
+					Type:        string(key.Type),
+					Purpose:     string(purpose),
+					Status:      string(key.Status),
+					Fingerprint: key.Fingerprint,
+					LocalPath:   key.LocalPath,
+					RemoteID:    key.RemoteID,
+				}
+				if !key.CreatedAt.IsZero() {
+					created := key.CreatedAt
+					row.CreatedAt = &created
+				}
+				if !key.ExpiresAt.IsZero() {
+					expires := key.ExpiresAt
+					row.ExpiresAt = &expires
+				}
+				rows = append(rows, row)
+			}
+		}
+	}
+	return rows
+}
+
+// writeListing prints rows as JSON, YAML or a table with the given headers.
+// --output json|yaml prints them as a document of the given kind.
+func writeListing(kind string, rows interface{}, headers []string, cells func(int) []string, count int) error {
+	switch {
+	case structuredOutput():
+		if count == 0 {
+			rows = []struct{}{}
+		}
+		return printStructured(kind, rows)
+	case listJSON:
+		data, err := json.MarshalIndent(rows, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode JSON: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	case listYAML:
+		data, err := yaml.Marshal(rows)
+		if err != nil {
+			return fmt.Errorf("failed to encode YAML: %w", err)
+		}
+		fmt.Print(string(data))
+		return nil
+	}
+
+	if count == 0 {
+		fmt.Println("Nothing to list.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(headers, "\t"))
+	for i := 0; i < count; i++ {
+		fmt.Fprintln(w, strings.Join(cells(i), "\t"))
+	}
+	return w.Flush()
+}
@@ -0,0 +1,78 @@
+package commands
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/kunlu/git-keys/internal/config"
+	"github.com/kunlu/git-keys/internal/logger"
+	"github.com/kunlu/git-keys/internal/profile"
+	"github.com/kunlu/git-keys/internal/sshkey"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// defaultLogFileName is the log file in ~/.git-keys/logs used by
+	// --log-file without a path
+	defaultLogFileName = "git-keys.log"
+
+	// logFileDefault is the value of --log-file given without a path
+	logFileDefault = "default"
+)
+
+var (
+	// logFile is the value of --log-file
+	logFile string
+
+	// openLogFile is the log file of this run, closed when it ends
+	openLogFile *logger.RotatingFile
+)
+
+// configureLogFile tees the log to --log-file, or else defaults.log_file,
+// rotating it by defaults.log_max_size and log_max_files. cfg may be nil.
+func configureLogFile(cmd *cobra.Command, cfg *config.Config) {
+	path := logFile
+	var maxSize, maxFiles int
+	if cfg != nil {
+		if path == "" {
+			path = cfg.Defaults.LogFile
+		}
+		maxSize, maxFiles = cfg.Defaults.LogMaxSize, cfg.Defaults.LogMaxFiles
+	}
+	switch path {
+	case "":
+		return
+	case logFileDefault:
+		path = filepath.Join(profile.Dir(), "logs", defaultLogFileName)
+	}
+	path = sshkey.ExpandHome(path)
+
+	file, err := logger.OpenRotatingFile(path, int64(maxSize)<<20, maxFiles)
+	if err != nil {
+		logger.Warn("Not logging to %s: %v", path, err)
+		return
+	}
+	openLogFile = file
+	logger.SetFile(file)
+	logger.Record(logger.INFO, logger.Fields{"command": cmd.CommandPath(), "pid": os.Getpid()}, "Running")
+}
+
+// closeLogFile records how the run ended and stops logging to its log file
+func closeLogFile(err error) {
+	if openLogFile == nil {
+		return
+	}
+	var status ExitStatus
+	switch {
+	case errors.As(err, &status):
+		logger.Record(logger.INFO, logger.Fields{"exit_status": int(status)}, "Finished")
+	case err != nil:
+		logger.Record(logger.ERROR, logger.Fields{"error": err, "exit_status": 1}, "Failed")
+	default:
+		logger.Record(logger.INFO, logger.Fields{"exit_status": 0}, "Finished")
+	}
+	logger.SetFile(nil)
+	openLogFile.Close()
+	openLogFile = nil
+}
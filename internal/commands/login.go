@@ -0,0 +1,309 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/kunlu/git-keys/internal/api"
+	"github.com/kunlu/git-keys/internal/config"
+	"github.com/kunlu/git-keys/internal/logger"
+	"github.com/kunlu/git-keys/internal/platform"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// gitlabOAuthService is the keychain service for GitLab OAuth tokens
+	gitlabOAuthService = "git-keys-gitlab-oauth"
+
+	// defaultOAuthPort is where the redirect listener waits for the browser
+	defaultOAuthPort = 7171
+
+	// oauthLoginTimeout bounds how long we wait for the browser login
+	oauthLoginTimeout = 5 * time.Minute
+)
+
+var (
+	loginBaseURL  string
+	loginClientID string
+	loginAccount  string
+	loginPort     int
+)
+
+var loginCmd = &cobra.Command{
+	Use:   "login",
+	Short: "Log in to a platform in the browser",
+	Long: `Log in to a platform in the browser instead of creating an API token by hand.
+
+Subcommands:
+  gitlab  - OAuth login for gitlab.com or a self-hosted GitLab
+`,
+}
+
+var loginGitLabCmd = &cobra.Command{
+	Use:   "gitlab",
+	Short: "Log in to GitLab with OAuth",
+	Long: `Log in to GitLab in the browser using the OAuth authorization code flow
+with PKCE.
+
+This needs an OAuth application on the GitLab instance (User Settings →
+Applications, or Admin → Applications):
+  - Redirect URI: http://127.0.0.1:7171/callback (match --port)
+  - Confidential: unchecked
+  - Scopes: api
+
+Pass its Application ID with --client-id, GIT_KEYS_GITLAB_CLIENT_ID, or
+oauth_client_id on the GitLab platform in the config.
+
+The access and refresh tokens are stored in the keychain and used in place of
+a personal access token. Access tokens are refreshed automatically when they
+expire.
+
+Examples:
+  git-keys login gitlab --client-id <application-id>
+  git-keys login gitlab --base-url https://gitlab.company.com
+`,
+	Args: cobra.NoArgs,
+	RunE: runLoginGitLab,
+}
+
+func init() {
+	loginGitLabCmd.Flags().StringVar(&loginBaseURL, "base-url", "", "GitLab instance URL (default https://gitlab.com)")
+	loginGitLabCmd.Flags().StringVar(&loginClientID, "client-id", "", "OAuth application ID")
+	loginGitLabCmd.Flags().StringVar(&loginAccount, "account", "", "Store the token for this account (default: the logged-in user)")
+	loginGitLabCmd.Flags().IntVar(&loginPort, "port", defaultOAuthPort, "Local port for the OAuth redirect")
+
+	loginCmd.AddCommand(loginGitLabCmd)
+	rootCmd.AddCommand(loginCmd)
+}
+
+func runLoginGitLab(cmd *cobra.Command, args []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), oauthLoginTimeout)
+	defer cancel()
+
+	// Settings from a matching configured platform, if any
+	plat := findGitLabPlatform(loginBaseURL)
+
+	baseURL := loginBaseURL
+	if baseURL == "" && plat.BaseURL != "" {
+		baseURL = plat.BaseURL
+	}
+	if baseURL == "" {
+		baseURL = "https://gitlab.com"
+	}
+	baseURL = strings.TrimSuffix(baseURL, "/")
+
+	clientID := loginClientID
+	if clientID == "" {
+		clientID = os.Getenv("GIT_KEYS_GITLAB_CLIENT_ID")
+	}
+	if clientID == "" {
+		clientID = plat.OAuthClientID
+	}
+	if clientID == "" {
+		return fmt.Errorf("no OAuth application ID; pass --client-id (see 'git-keys login gitlab --help')")
+	}
+
+	oauth := &api.GitLabOAuth{
+		BaseURL:     baseURL,
+		ClientID:    clientID,
+		RedirectURL: fmt.Sprintf("http://127.0.0.1:%d/callback", loginPort),
+		Connection:  gitlabConnectionOptions(plat),
+	}
+
+	verifier, challenge, err := api.NewPKCE()
+	if err != nil {
+		return err
+	}
+	state, err := api.NewOAuthState()
+	if err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", loginPort))
+	if err != nil {
+		return fmt.Errorf("failed to listen for the OAuth redirect on port %d: %w", loginPort, err)
+	}
+
+	codes := make(chan oauthCallback, 1)
+	server := &http.Server{Handler: oauthCallbackHandler(state, codes)}
+	go server.Serve(listener)
+	defer server.Close()
+
+	authURL := oauth.AuthorizeURL(state, challenge)
+	printHeader("\n🔐 GitLab Login")
+	fmt.Printf("\nOpening %s in your browser...\n", baseURL)
+	fmt.Printf("If it does not open, visit:\n  %s\n\n", authURL)
+
+	if plat, err := platform.NewPlatform(); err == nil {
+		if err := plat.OpenURL(authURL); err != nil {
+			logger.Debug("Could not open browser: %v", err)
+		}
+	}
+
+	fmt.Println("Waiting for authorization...")
+
+	var result oauthCallback
+	select {
+	case result = <-codes:
+	case <-ctx.Done():
+		return fmt.Errorf("timed out waiting for the browser login")
+	}
+	if result.err != nil {
+		return result.err
+	}
+
+	token, err := oauth.Exchange(ctx, result.code, verifier)
+	if err != nil {
+		return err
+	}
+
+	// Identify the account the token belongs to
+	client, err := api.NewGitLabClientWithOptions(baseURL, token.AccessToken, oauth.Connection)
+	if err != nil {
+		return err
+	}
+	info, err := client.CheckToken(ctx)
+	if err != nil {
+		return fmt.Errorf("login succeeded but the token does not work: %w", err)
+	}
+
+	account := loginAccount
+	if account == "" {
+		account = info.User
+	}
+
+	if err := saveGitLabOAuthToken(baseURL, account, token); err != nil {
+		return err
+	}
+
+	fmt.Printf("\n✓ Logged in to %s as %s\n", baseURL, info.User)
+	fmt.Printf("  Token stored in the keychain for account '%s' on %s\n", account, baseURL)
+	if account != info.User {
+		fmt.Printf("  ⚠️  The logged-in user is '%s', not '%s'\n", info.User, account)
+	}
+	return nil
+}
+
+// oauthCallback carries the result of the browser redirect
+type oauthCallback struct {
+	code string
+	err  error
+}
+
+// oauthCallbackHandler receives the authorization code on /callback
+func oauthCallbackHandler(state string, results chan<- oauthCallback) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/callback", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+
+		var result oauthCallback
+		switch {
+		case query.Get("error") != "":
+			result.err = fmt.Errorf("authorization denied: %s %s", query.Get("error"), query.Get("error_description"))
+		case query.Get("state") != state:
+			result.err = errors.New("authorization response has an unexpected state; try again")
+		case query.Get("code") == "":
+			result.err = errors.New("authorization response has no code")
+		default:
+			result.code = query.Get("code")
+		}
+
+		if result.err != nil {
+			http.Error(w, "git-keys login failed: "+result.err.Error(), http.StatusBadRequest)
+		} else {
+			fmt.Fprintln(w, "git-keys login complete. You can close this window.")
+		}
+
+		select {
+		case results <- result:
+		default:
+		}
+	})
+	return mux
+}
+
+// findGitLabPlatform returns the first configured GitLab platform matching
+// baseURL (any GitLab platform when baseURL is empty), or an empty platform
+func findGitLabPlatform(baseURL string) *config.Platform {
+	cfg := loadGlobalDefaults()
+	if cfg == nil {
+		return &config.Platform{Type: config.PlatformGitLab}
+	}
+
+	want := strings.TrimSuffix(baseURL, "/")
+	for personaIdx := range cfg.Personas {
+		for platformIdx := range cfg.Personas[personaIdx].Platforms {
+			plat := &cfg.Personas[personaIdx].Platforms[platformIdx]
+			if plat.Type != config.PlatformGitLab {
+				continue
+			}
+			if want == "" || strings.TrimSuffix(plat.BaseURL, "/") == want {
+				return plat
+			}
+		}
+	}
+
+	return &config.Platform{Type: config.PlatformGitLab}
+}
+
+// gitlabOAuthKey returns the keychain account of the OAuth token for
+// account on the GitLab instance at baseURL, so a login to one instance is
+// never sent to another with the same account name
+func gitlabOAuthKey(baseURL, account string) string {
+	return account + "@" + strings.TrimSuffix(baseURL, "/")
+}
+
+// saveGitLabOAuthToken stores an OAuth token for account on the GitLab
+// instance at baseURL in the keychain
+func saveGitLabOAuthToken(baseURL, account string, token *api.OAuthToken) error {
+	data, err := json.Marshal(token)
+	if err != nil {
+		return fmt.Errorf("failed to encode token: %w", err)
+	}
+	return api.NewTokenManager(gitlabOAuthService).SetToken(gitlabOAuthKey(baseURL, account), string(data))
+}
+
+// loadGitLabOAuthToken returns the OAuth access token stored for account on
+// the GitLab instance at baseURL, refreshing it first if it has expired.
+// Tokens stored under the account alone by earlier versions are used only
+// when they were issued by the same instance.
+func loadGitLabOAuthToken(baseURL, account string) (string, error) {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	tokenMgr := api.NewTokenManager(gitlabOAuthService)
+	data, err := tokenMgr.GetToken(gitlabOAuthKey(baseURL, account))
+	if err != nil {
+		if data, err = tokenMgr.GetToken(account); err != nil {
+			return "", err
+		}
+	}
+
+	var token api.OAuthToken
+	if err := json.Unmarshal([]byte(data), &token); err != nil {
+		return "", fmt.Errorf("stored OAuth token is invalid: %w", err)
+	}
+	if strings.TrimSuffix(token.BaseURL, "/") != baseURL {
+		return "", fmt.Errorf("stored OAuth token for %s was issued by %s, not %s", account, token.BaseURL, baseURL)
+	}
+
+	if token.Expired() {
+		logger.Debug("Refreshing GitLab OAuth token for %s", account)
+		refreshed, err := api.RefreshGitLabToken(context.Background(), &token)
+		if err != nil {
+			logger.Warn("Failed to refresh GitLab login for %s: %v", account, err)
+			return "", err
+		}
+		if err := saveGitLabOAuthToken(baseURL, account, refreshed); err != nil {
+			logger.Warn("Failed to store refreshed GitLab token: %v", err)
+		}
+		return refreshed.AccessToken, nil
+	}
+
+	return token.AccessToken, nil
+}
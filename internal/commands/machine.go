@@ -0,0 +1,259 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kunlu/git-keys/internal/config"
+	"github.com/kunlu/git-keys/internal/history"
+	"github.com/kunlu/git-keys/internal/logger"
+	"github.com/kunlu/git-keys/internal/platform"
+	"github.com/kunlu/git-keys/internal/prompt"
+	"github.com/kunlu/git-keys/internal/sshkey"
+	"github.com/spf13/cobra"
+)
+
+var (
+	machineRenameRemote bool
+	machineRenameYes    bool
+)
+
+var machineCmd = &cobra.Command{
+	Use:   "machine",
+	Short: "Manage the local machine identity",
+	Long: `Manage the machine identity recorded in the git-keys configuration.
+
+A configuration can be shared by several machines; each key belongs to the
+machine with its private key, and commands only work on the keys of the
+machine they run on. 'git-keys status' shows which machines have which keys.
+
+The machine name is embedded in key comments and remote key titles. If the
+computer is renamed (e.g., in System Settings), these go stale until you run
+'git-keys machine rename'.
+
+Subcommands:
+  rename  - Update the machine name in config, key comments, and remote titles
+`,
+}
+
+var machineRenameCmd = &cobra.Command{
+	Use:   "rename [new-name]",
+	Short: "Update the recorded machine name",
+	Long: `Update the machine name used by git-keys.
+
+If no name is given, the current ComputerName is detected and used.
+
+This command will:
+  1. Update machine.name in the configuration
+  2. Rewrite the comment of each active local key
+  3. Re-register active keys on platforms with an updated title (--update-remote)
+  4. Record the change in ~/.git-keys/history.jsonl
+
+GitHub and GitLab do not allow editing a key's title, so --update-remote
+deletes each key and registers the same public key again. The key material
+does not change.
+
+Examples:
+  # Use the detected ComputerName
+  git-keys machine rename
+
+  # Use an explicit name and refresh remote key titles
+  git-keys machine rename work-laptop --update-remote
+`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runMachineRename,
+}
+
+func init() {
+	machineRenameCmd.Flags().BoolVar(&machineRenameRemote, "update-remote", false, "Re-register remote keys with titles using the new name")
+	machineRenameCmd.Flags().BoolVarP(&machineRenameYes, "yes", "y", false, "Skip confirmation prompt")
+
+	machineCmd.AddCommand(machineRenameCmd)
+	rootCmd.AddCommand(machineCmd)
+}
+
+func runMachineRename(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	// Load configuration
+	configPath := cfgFile
+	if configPath == "" {
+		configPath = config.GetDefaultConfigPath()
+	}
+
+	mgr := config.NewManager(configPath)
+	if !mgr.Exists() {
+		return fmt.Errorf("configuration file not found at %s\nRun 'git-keys init' first", configPath)
+	}
+
+	cfg, err := mgr.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	// Determine new name
+	var newName string
+	if len(args) > 0 {
+		newName = strings.TrimSpace(args[0])
+	} else {
+		newName, err = detectMachineName()
+		if err != nil {
+			return fmt.Errorf("failed to detect machine name (pass it explicitly): %w", err)
+		}
+	}
+
+	if newName == "" {
+		return fmt.Errorf("machine name cannot be empty")
+	}
+
+	oldName := cfg.Machine.Name
+	if newName == oldName {
+		fmt.Printf("Machine name is already '%s'. Nothing to do.\n", oldName)
+		return nil
+	}
+
+	printHeader("\n💻 Machine Rename")
+	fmt.Printf("\n  Current: %s\n", oldName)
+	fmt.Printf("  New:     %s\n\n", newName)
+
+	if machineRenameRemote {
+		fmt.Println("  Remote keys will be deleted and re-registered with a new title.")
+		fmt.Println()
+	}
+
+	if !machineRenameYes {
+		if !prompt.Confirm("Continue?") {
+			fmt.Println("Rename cancelled.")
+			return nil
+		}
+	}
+
+	keyMgr := sshkey.NewManager(cfg.Defaults.GetKeysDir())
+
+	commentsUpdated := 0
+	titlesUpdated := 0
+	var failures []string
+
+	for personaIdx := range cfg.Personas {
+		persona := &cfg.Personas[personaIdx]
+		if persona.Archived {
+			continue
+		}
+		for platformIdx := range persona.Platforms {
+			plat := &persona.Platforms[platformIdx]
+			key := plat.GetActiveKey()
+			if key == nil {
+				continue
+			}
+
+			settings := cfg.ResolveKeySettings(persona, plat)
+			// Keep the original creation date in the comment
+			created := key.CreatedAt
+			if created.IsZero() {
+				created = time.Now()
+			}
+			nameData := sshkey.NewNameData(persona, plat, key.Type, newName, created)
+
+			// Update local key comment
+			if key.HasPrivateKey() && key.LocalPath != "" && keyMgr.KeyExists(key.LocalPath) {
+				comment, err := sshkey.RenderName(settings.CommentTemplate, sshkey.DefaultCommentTemplate, nameData)
+				if err == nil {
+					err = keyMgr.SetComment(key.LocalPath, comment)
+				}
+				if err != nil {
+					logger.Warn("Failed to update comment for %s: %v", key.LocalPath, err)
+					failures = append(failures, fmt.Sprintf("%s: comment not updated", key.LocalPath))
+				} else {
+					fmt.Printf("✓ Updated comment: %s\n", key.LocalPath)
+					commentsUpdated++
+				}
+			}
+
+			// Re-register remote key with new title
+			if machineRenameRemote && key.RemoteID != "" {
+				title, err := sshkey.RenderTitle(settings, sshkey.DefaultTitleTemplate, nameData, key.ExpiresAt)
+				if err == nil {
+					err = retitleRemoteKey(ctx, keyMgr, plat, key, title)
+				}
+				if err != nil {
+					logger.Warn("Failed to update remote title for %s/%s: %v", persona.Name, plat.Type, err)
+					failures = append(failures, fmt.Sprintf("%s/%s@%s: %v", persona.Name, plat.Type, plat.Account, err))
+				} else {
+					fmt.Printf("✓ Re-registered %s@%s with new title\n", plat.Account, plat.Type)
+					titlesUpdated++
+				}
+			}
+		}
+	}
+
+	cfg.Machine.Name = newName
+	if err := mgr.Save(cfg); err != nil {
+		return fmt.Errorf("failed to save configuration: %w", err)
+	}
+	fmt.Printf("✓ Updated configuration: machine.name = %s\n", newName)
+
+	histMgr := history.NewManager("")
+	if err := histMgr.Record(history.Entry{
+		Action:  "machine-rename",
+		Summary: fmt.Sprintf("Renamed machine from '%s' to '%s'", oldName, newName),
+		Details: map[string]string{
+			"old_name":         oldName,
+			"new_name":         newName,
+			"comments_updated": fmt.Sprintf("%d", commentsUpdated),
+			"titles_updated":   fmt.Sprintf("%d", titlesUpdated),
+		},
+	}); err != nil {
+		logger.Warn("Failed to record history: %v", err)
+	}
+
+	if len(failures) > 0 {
+		fmt.Printf("\n⚠️  %d item(s) could not be updated:\n", len(failures))
+		for _, f := range failures {
+			fmt.Printf("   • %s\n", f)
+		}
+		fmt.Println("\nRun 'git-keys apply' to upload any keys that are no longer registered.")
+	}
+
+	fmt.Println("\n✅ Machine renamed.")
+	return nil
+}
+
+// retitleRemoteKey deletes and re-adds a key so the platform shows the new machine name.
+// If re-adding fails, the remote ID is cleared so 'git-keys apply' uploads it again.
+func retitleRemoteKey(ctx context.Context, keyMgr *sshkey.Manager, plat *config.Platform, key *config.KeyConfig, title string) error {
+	publicKey, err := keyMgr.GetPublicKey(key.LocalPath)
+	if err != nil {
+		return err
+	}
+
+	client, err := newPlatformClient(plat)
+	if err != nil {
+		return err
+	}
+
+	// Only delete the registration if it is still this key
+	check := checkRemoteKey(ctx, client, key.RemoteID, key.Fingerprint)
+	if err := deleteCheckedKey(ctx, client, check); err != nil {
+		return fmt.Errorf("failed to remove old registration: %w", err)
+	}
+
+	remoteID, err := client.AddKey(ctx, title, publicKey, key.ExpiresAt)
+	if err != nil {
+		key.RemoteID = ""
+		return fmt.Errorf("key was removed but could not be re-added: %w", err)
+	}
+
+	key.RemoteID = remoteID
+	return nil
+}
+
+// detectMachineName returns the current machine name reported by the OS
+func detectMachineName() (string, error) {
+	plat, err := platform.NewPlatform()
+	if err != nil {
+		return "", err
+	}
+	return plat.GetMachineName()
+}
@@ -0,0 +1,219 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kunlu/git-keys/internal/config"
+	"github.com/kunlu/git-keys/internal/history"
+	"github.com/kunlu/git-keys/internal/logger"
+	"github.com/kunlu/git-keys/internal/prompt"
+	"github.com/kunlu/git-keys/internal/sshkey"
+	"github.com/spf13/cobra"
+)
+
+var (
+	normalizeTitlesDryRun bool
+	normalizeTitlesYes    bool
+)
+
+var normalizeTitlesCmd = &cobra.Command{
+	Use:   "normalize-titles",
+	Short: "Rename remote keys to the current title template",
+	Long: `Rename remote SSH keys so their titles follow the current title template.
+
+Keys uploaded by older versions of git-keys, or before key_title_template was
+changed, can have inconsistent titles on GitHub and GitLab. This command
+renders the expected title for each active key, compares it with the title
+registered on the platform, and fixes the ones that differ.
+
+Neither GitHub nor GitLab allows editing a key's title, so each key is deleted
+and the same public key is registered again. The key material does not change.
+If re-registering fails, the key is marked for upload by 'git-keys apply'.
+
+Examples:
+  # Show which titles would change
+  git-keys normalize-titles --dry-run
+
+  # Rename without prompting
+  git-keys normalize-titles --yes
+`,
+	RunE: runNormalizeTitles,
+}
+
+func init() {
+	normalizeTitlesCmd.Flags().BoolVar(&normalizeTitlesDryRun, "dry-run", false, "Show title changes without making them")
+	normalizeTitlesCmd.Flags().BoolVarP(&normalizeTitlesYes, "yes", "y", false, "Skip confirmation prompt")
+
+	rootCmd.AddCommand(normalizeTitlesCmd)
+}
+
+// titleChange is a remote key whose title differs from the template
+type titleChange struct {
+	Persona  *config.Persona
+	Platform *config.Platform
+	Key      *config.KeyConfig
+	OldTitle string
+	NewTitle string
+}
+
+func runNormalizeTitles(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	// Load configuration
+	configPath := cfgFile
+	if configPath == "" {
+		configPath = config.GetDefaultConfigPath()
+	}
+
+	mgr := config.NewManager(configPath)
+	if !mgr.Exists() {
+		return fmt.Errorf("configuration file not found at %s\nRun 'git-keys init' first", configPath)
+	}
+
+	cfg, err := mgr.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	printHeader("\n🏷️  Normalize Remote Key Titles")
+	fmt.Println()
+
+	changes, failures := findTitleChanges(ctx, cfg)
+
+	if len(changes) == 0 {
+		fmt.Println("✓ All remote key titles match the title template.")
+		printTitleFailures(failures)
+		return nil
+	}
+
+	for _, change := range changes {
+		fmt.Printf("  %s/%s@%s\n", change.Persona.Name, change.Platform.Type, change.Platform.Account)
+		fmt.Printf("    - %s\n", change.OldTitle)
+		fmt.Printf("    + %s\n", change.NewTitle)
+	}
+	fmt.Println()
+
+	if normalizeTitlesDryRun {
+		fmt.Printf("🔍 DRY RUN: %d title(s) would be changed\n", len(changes))
+		printTitleFailures(failures)
+		return nil
+	}
+
+	if !normalizeTitlesYes {
+		fmt.Println("  Each key will be deleted and re-registered with the new title.")
+		if !prompt.Confirm("\nContinue?") {
+			fmt.Println("Normalization cancelled.")
+			return nil
+		}
+	}
+
+	keyMgr := sshkey.NewManager(cfg.Defaults.GetKeysDir())
+	renamed := 0
+
+	for _, change := range changes {
+		label := fmt.Sprintf("%s/%s@%s", change.Persona.Name, change.Platform.Type, change.Platform.Account)
+		if err := retitleRemoteKey(ctx, keyMgr, change.Platform, change.Key, change.NewTitle); err != nil {
+			logger.Warn("Failed to rename remote key for %s: %v", label, err)
+			failures = append(failures, fmt.Sprintf("%s: %v", label, err))
+			continue
+		}
+		fmt.Printf("✓ Renamed %s\n", label)
+		renamed++
+	}
+
+	// Remote IDs change when keys are re-registered
+	if err := mgr.Save(cfg); err != nil {
+		return fmt.Errorf("failed to save configuration: %w", err)
+	}
+
+	histMgr := history.NewManager("")
+	if err := histMgr.Record(history.Entry{
+		Action:  "normalize-titles",
+		Summary: fmt.Sprintf("Renamed %d remote key(s) to the title template", renamed),
+		Details: map[string]string{
+			"renamed": fmt.Sprintf("%d", renamed),
+			"failed":  fmt.Sprintf("%d", len(failures)),
+		},
+	}); err != nil {
+		logger.Warn("Failed to record history: %v", err)
+	}
+
+	printTitleFailures(failures)
+	if renamed > 0 {
+		fmt.Printf("\n✅ Renamed %d remote key(s).\n", renamed)
+	}
+	return nil
+}
+
+// findTitleChanges looks up the registered title of each active remote key
+// and returns those that differ from the rendered title template
+func findTitleChanges(ctx context.Context, cfg *config.Config) ([]titleChange, []string) {
+	var changes []titleChange
+	var failures []string
+
+	for personaIdx := range cfg.Personas {
+		persona := &cfg.Personas[personaIdx]
+		if persona.Archived {
+			continue
+		}
+		for platformIdx := range persona.Platforms {
+			plat := &persona.Platforms[platformIdx]
+			key := plat.GetActiveKey()
+			if key == nil || key.RemoteID == "" {
+				continue
+			}
+			label := fmt.Sprintf("%s/%s@%s", persona.Name, plat.Type, plat.Account)
+
+			created := key.CreatedAt
+			if created.IsZero() {
+				created = time.Now()
+			}
+			settings := cfg.ResolveKeySettings(persona, plat)
+			nameData := sshkey.NewNameData(persona, plat, key.Type, cfg.Machine.Name, created)
+			title, err := sshkey.RenderTitle(settings, sshkey.DefaultTitleTemplate, nameData, key.ExpiresAt)
+			if err != nil {
+				failures = append(failures, fmt.Sprintf("%s: %v", label, err))
+				continue
+			}
+
+			client, err := newPlatformClient(plat)
+			if err != nil {
+				failures = append(failures, fmt.Sprintf("%s: %v", label, err))
+				continue
+			}
+
+			remote, err := client.GetKey(ctx, key.RemoteID)
+			if err != nil {
+				failures = append(failures, fmt.Sprintf("%s: failed to fetch remote key: %v", label, err))
+				continue
+			}
+
+			if remote.Title == title {
+				logger.Debug("Title already normalized for %s", label)
+				continue
+			}
+
+			changes = append(changes, titleChange{
+				Persona:  persona,
+				Platform: plat,
+				Key:      key,
+				OldTitle: remote.Title,
+				NewTitle: title,
+			})
+		}
+	}
+
+	return changes, failures
+}
+
+func printTitleFailures(failures []string) {
+	if len(failures) == 0 {
+		return
+	}
+	fmt.Printf("\n⚠️  %d key(s) could not be checked or renamed:\n", len(failures))
+	for _, f := range failures {
+		printWrapped("   • ", f)
+	}
+}
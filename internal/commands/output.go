@@ -0,0 +1,160 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/kunlu/git-keys/internal/config"
+)
+
+const (
+	// defaultOutputWidth is used when the terminal width cannot be determined
+	defaultOutputWidth = 80
+
+	// minOutputWidth keeps wrapping sensible in very narrow panes
+	minOutputWidth = 20
+)
+
+var (
+	// plainOutput drops emoji and decorative rules from headers
+	plainOutput bool
+
+	// outputWidth is the configured width; 0 detects the terminal width
+	outputWidth int
+)
+
+// configureOutput applies defaults.plain_output and defaults.output_width
+func configureOutput(cfg *config.Config) {
+	plainOutput = cfg.Defaults.PlainOutput
+	outputWidth = cfg.Defaults.OutputWidth
+}
+
+// terminalWidth returns the width to format output for: the configured
+// width, then $COLUMNS, then the width of the terminal on stdout
+func terminalWidth() int {
+	width := outputWidth
+	if width <= 0 {
+		width, _ = strconv.Atoi(os.Getenv("COLUMNS"))
+	}
+	if width <= 0 {
+		width = sttyWidth()
+	}
+	if width <= 0 {
+		width = defaultOutputWidth
+	}
+	if width < minOutputWidth {
+		width = minOutputWidth
+	}
+	return width
+}
+
+// sttyWidth asks stty for the terminal width. Returns 0 when stdout is not a terminal.
+func sttyWidth() int {
+	if info, err := os.Stdout.Stat(); err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return 0
+	}
+
+	cmd := exec.Command("stty", "size")
+	cmd.Stdin = os.Stdin
+	output, err := cmd.Output()
+	if err != nil {
+		return 0
+	}
+
+	fields := strings.Fields(string(output))
+	if len(fields) != 2 {
+		return 0
+	}
+	width, _ := strconv.Atoi(fields[1])
+	return width
+}
+
+// printHeader prints a section title underlined to its width (capped at the
+// terminal width). Leading newlines in title are kept. In plain mode the
+// emoji and underline are dropped.
+func printHeader(title string) {
+	text := strings.TrimLeft(title, "\n")
+	fmt.Print(title[:len(title)-len(text)])
+
+	if plainOutput {
+		fmt.Println(stripDecoration(text))
+		return
+	}
+
+	fmt.Println(text)
+	fmt.Println(strings.Repeat("=", min(displayWidth(text), terminalWidth())))
+}
+
+// printRule prints a horizontal separator spanning up to width columns
+func printRule(width int) {
+	char := "━"
+	if plainOutput {
+		char = "-"
+	}
+	fmt.Println(strings.Repeat(char, min(width, terminalWidth())))
+}
+
+// printWrapped prints text after prefix, wrapping at the terminal width and
+// indenting continuation lines to align with the text
+func printWrapped(prefix, text string) {
+	indent := strings.Repeat(" ", displayWidth(prefix))
+	for i, line := range wrapText(text, terminalWidth()-displayWidth(prefix)) {
+		if i == 0 {
+			fmt.Println(prefix + line)
+		} else {
+			fmt.Println(indent + line)
+		}
+	}
+}
+
+// wrapText splits text into lines of at most width columns, breaking at
+// spaces. Words longer than width (paths, URLs) are kept on their own line.
+func wrapText(text string, width int) []string {
+	if width < minOutputWidth/2 {
+		width = minOutputWidth / 2
+	}
+
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return []string{""}
+	}
+
+	var lines []string
+	line := words[0]
+	for _, word := range words[1:] {
+		if displayWidth(line)+1+displayWidth(word) > width {
+			lines = append(lines, line)
+			line = word
+			continue
+		}
+		line += " " + word
+	}
+	return append(lines, line)
+}
+
+// stripDecoration removes leading emoji and symbols from a header
+func stripDecoration(s string) string {
+	return strings.TrimLeftFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// displayWidth approximates the number of terminal columns s occupies.
+// Emoji take two columns; variation selectors and combining marks take none.
+func displayWidth(s string) int {
+	width := 0
+	for _, r := range s {
+		switch {
+		case r == '\uFE0F' || unicode.Is(unicode.Mn, r):
+		case r >= 0x1F000:
+			width += 2
+		default:
+			width++
+		}
+	}
+	return width
+}
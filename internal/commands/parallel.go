@@ -0,0 +1,120 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/kunlu/git-keys/internal/config"
+)
+
+// defaultParallel is the default number of platforms processed at once
+const defaultParallel = 4
+
+// sshConfigMu serializes SSH config writes from concurrent platform tasks
+var sshConfigMu sync.Mutex
+
+// platformTask is the API work for one persona/platform. Tasks in the same
+// lane (the same account on the same host) run one after another; different
+// lanes run concurrently, so a slow self-hosted GitLab does not hold up
+// GitHub.
+type platformTask struct {
+	Label string
+	Lane  string
+	Run   func(ctx context.Context, out io.Writer) error
+}
+
+// platformTaskResult is the outcome of a platform task. Output holds what
+// the task printed when it ran concurrently.
+type platformTaskResult struct {
+	Index   int // Position of the task in the input
+	Label   string
+	Err     error
+	Elapsed time.Duration
+	Output  string
+}
+
+// platformLane returns the lane of an account on a platform
+func platformLane(platformType config.PlatformType, baseURL, account string) string {
+	return fmt.Sprintf("%s|%s|%s", platformType, baseURL, account)
+}
+
+// runPlatformTasks runs tasks on up to parallel workers. onDone is called on
+// the calling goroutine as each task finishes. With parallel <= 1, tasks run
+// in order and print directly to stdout.
+func runPlatformTasks(ctx context.Context, tasks []platformTask, parallel int, onDone func(platformTaskResult)) []platformTaskResult {
+	results := make([]platformTaskResult, len(tasks))
+
+	if parallel <= 1 || len(tasks) <= 1 {
+		for i, task := range tasks {
+			start := time.Now()
+			err := task.Run(ctx, os.Stdout)
+			results[i] = platformTaskResult{Index: i, Label: task.Label, Err: err, Elapsed: time.Since(start)}
+			onDone(results[i])
+		}
+		return results
+	}
+
+	// Group tasks into lanes, keeping the task order within each lane
+	var lanes [][]int
+	laneIndex := make(map[string]int)
+	for i, task := range tasks {
+		idx, ok := laneIndex[task.Lane]
+		if !ok {
+			idx = len(lanes)
+			laneIndex[task.Lane] = idx
+			lanes = append(lanes, nil)
+		}
+		lanes[idx] = append(lanes[idx], i)
+	}
+
+	laneCh := make(chan []int)
+	doneCh := make(chan platformTaskResult)
+
+	workers := parallel
+	if workers > len(lanes) {
+		workers = len(lanes)
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for lane := range laneCh {
+				for _, i := range lane {
+					var out bytes.Buffer
+					start := time.Now()
+					err := tasks[i].Run(ctx, &out)
+					doneCh <- platformTaskResult{
+						Index:   i,
+						Label:   tasks[i].Label,
+						Err:     err,
+						Elapsed: time.Since(start),
+						Output:  out.String(),
+					}
+				}
+			}
+		}()
+	}
+
+	go func() {
+		for _, lane := range lanes {
+			laneCh <- lane
+		}
+		close(laneCh)
+		wg.Wait()
+		close(doneCh)
+	}()
+
+	for result := range doneCh {
+		results[result.Index] = result
+		onDone(result)
+	}
+
+	return results
+}
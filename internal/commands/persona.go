@@ -0,0 +1,339 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/kunlu/git-keys/internal/api"
+	"github.com/kunlu/git-keys/internal/config"
+	"github.com/kunlu/git-keys/internal/history"
+	"github.com/kunlu/git-keys/internal/logger"
+	"github.com/kunlu/git-keys/internal/prompt"
+	"github.com/kunlu/git-keys/internal/sshconfig"
+	"github.com/kunlu/git-keys/internal/sshkey"
+	"github.com/spf13/cobra"
+)
+
+var personaArchiveYes bool
+
+var personaCmd = &cobra.Command{
+	Use:   "persona",
+	Short: "Manage personas",
+	Long: `Manage the personas defined in the git-keys configuration.
+
+Subcommands:
+  add        - Add a persona with its first platform
+  remove     - Remove a persona and delete its keys
+  rename     - Rename a persona
+  archive    - Pause a persona: revoke its keys and remove its SSH and git config
+  unarchive  - Restore an archived persona's keys
+  suggest    - Rank personas for a git remote
+`,
+}
+
+var personaArchiveCmd = &cobra.Command{
+	Use:   "archive <persona>",
+	Short: "Pause a persona without deleting it",
+	Long: `Archive a persona, e.g. when a contract pauses.
+
+This command will:
+  1. Remove the persona's keys from GitHub/GitLab
+  2. Move its key files to <keys_dir>/archive/personas/<persona>/
+  3. Remove its SSH config blocks and git identity configuration
+  4. Keep the full persona definition in the config, marked archived
+
+Archived personas are skipped by apply, rotate, status and the other commands
+until 'git-keys persona unarchive' restores them.
+`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completePersonas,
+	RunE:              runPersonaArchive,
+}
+
+var personaUnarchiveCmd = &cobra.Command{
+	Use:   "unarchive <persona>",
+	Short: "Restore an archived persona",
+	Long: `Restore an archived persona.
+
+Key files are moved back from <keys_dir>/archive/personas/<persona>/ and the
+persona is marked active again. Run 'git-keys apply' afterwards to recreate
+its SSH config blocks and git identity and to register its keys again.
+`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeArchivedPersonas,
+	RunE:              runPersonaUnarchive,
+}
+
+func init() {
+	personaArchiveCmd.Flags().BoolVarP(&personaArchiveYes, "yes", "y", false, "Skip confirmation prompt")
+
+	personaCmd.AddCommand(personaArchiveCmd)
+	personaCmd.AddCommand(personaUnarchiveCmd)
+	rootCmd.AddCommand(personaCmd)
+}
+
+func runPersonaArchive(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	// Load configuration
+	configPath := cfgFile
+	if configPath == "" {
+		configPath = config.GetDefaultConfigPath()
+	}
+
+	mgr := config.NewManager(configPath)
+	if !mgr.Exists() {
+		return fmt.Errorf("configuration file not found at %s\nRun 'git-keys init' first", configPath)
+	}
+
+	cfg, err := mgr.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	persona := cfg.FindPersona(args[0])
+	if persona == nil {
+		return fmt.Errorf("persona not found: %s", args[0])
+	}
+	if persona.Archived {
+		fmt.Printf("Persona '%s' is already archived.\n", persona.Name)
+		return nil
+	}
+
+	keyMgr := sshkey.NewManager(cfg.Defaults.GetKeysDir())
+	archiveDir := keyMgr.PersonaArchiveDir(persona.Name)
+
+	printHeader("\n📦 Archive Persona")
+	fmt.Printf("\n  Persona: %s <%s>\n", persona.Name, persona.Email)
+	for _, plat := range persona.Platforms {
+		fmt.Printf("  Platform: %s@%s\n", plat.Account, plat.Type)
+	}
+	fmt.Printf("  Key archive: %s\n\n", archiveDir)
+
+	// Look up the remote keys that will be deleted
+	checks := make(map[*config.KeyConfig]*remoteKeyCheck)
+	clients := make(map[*config.Platform]api.PlatformClient)
+	for platformIdx := range persona.Platforms {
+		plat := &persona.Platforms[platformIdx]
+		for keyIdx := range plat.Keys {
+			key := &plat.Keys[keyIdx]
+			if key.RemoteID == "" {
+				continue
+			}
+
+			client, err := newPlatformClient(plat)
+			if err != nil {
+				checks[key] = &remoteKeyCheck{RemoteID: key.RemoteID, Fingerprint: key.Fingerprint, Err: err}
+			} else {
+				clients[plat] = client
+				checks[key] = checkRemoteKey(ctx, client, key.RemoteID, key.Fingerprint)
+			}
+
+			fmt.Printf("  %s@%s key %s\n", plat.Account, plat.Type, key.Fingerprint)
+			checks[key].Print(os.Stdout, "    ")
+		}
+	}
+	if len(checks) > 0 {
+		fmt.Println()
+	}
+
+	if !personaArchiveYes {
+		if !prompt.Confirm("Revoke this persona's remote keys and archive it?") {
+			fmt.Println("Archive cancelled.")
+			return nil
+		}
+	}
+
+	// Revoke remote keys first; nothing local changes unless all succeed
+	var failures []string
+	for platformIdx := range persona.Platforms {
+		plat := &persona.Platforms[platformIdx]
+		for keyIdx := range plat.Keys {
+			key := &plat.Keys[keyIdx]
+			if key.RemoteID == "" {
+				continue
+			}
+
+			if err := deleteCheckedKey(ctx, clients[plat], checks[key]); err != nil {
+				logger.Warn("Failed to revoke key %s: %v", key.Fingerprint, err)
+				failures = append(failures, fmt.Sprintf("%s@%s: %v", plat.Account, plat.Type, err))
+				continue
+			}
+
+			fmt.Printf("✓ Removed key from %s@%s\n", plat.Account, plat.Type)
+			key.RemoteID = ""
+		}
+	}
+
+	if len(failures) > 0 {
+		// Keep the remote IDs cleared so far; rerunning picks up where this left off
+		if err := mgr.Save(cfg); err != nil {
+			return fmt.Errorf("failed to save configuration: %w", err)
+		}
+		fmt.Printf("\n❌ %d key(s) could not be removed from remote platforms:\n", len(failures))
+		for _, f := range failures {
+			printWrapped("   • ", f)
+		}
+		return fmt.Errorf("persona not archived; fix the errors above and run the command again")
+	}
+
+	// Move key files out of the keys directory
+	for _, plat := range persona.Platforms {
+		for _, key := range plat.Keys {
+			if key.LocalPath == "" {
+				continue
+			}
+			moved, err := keyMgr.ArchiveKey(key.LocalPath, archiveDir)
+			if err != nil {
+				return fmt.Errorf("failed to archive key %s: %w", key.LocalPath, err)
+			}
+			if moved {
+				fmt.Printf("✓ Archived key: %s\n", key.LocalPath)
+			}
+		}
+	}
+
+	// Remove SSH config blocks
+	sshMgr := sshConfigManager(cfg)
+	for _, plat := range persona.Platforms {
+		blockID := sshconfig.GetManagedBlockID(persona.Name, plat.Type, plat.Account)
+		if err := sshMgr.RemoveEntry(blockID); err != nil {
+			return fmt.Errorf("failed to remove SSH config block %s: %w", blockID, err)
+		}
+	}
+	fmt.Println("✓ Removed SSH config entries")
+
+	persona.Archived = true
+	persona.ArchivedAt = time.Now()
+
+	// Remove git identity switching for the persona
+	if err := removePersonaGitConfig(cfg, persona); err != nil {
+		logger.Warn("Failed to update git config: %v", err)
+		fmt.Println("⚠️  Could not update git config. Run 'git-keys setup-git' to fix it.")
+	} else {
+		fmt.Println("✓ Removed git identity configuration")
+	}
+
+	if err := mgr.Save(cfg); err != nil {
+		return fmt.Errorf("failed to save configuration: %w", err)
+	}
+
+	recordPersonaHistory("persona-archive", fmt.Sprintf("Archived persona '%s'", persona.Name), persona.Name)
+
+	fmt.Printf("\n✅ Persona '%s' archived. Restore it with 'git-keys persona unarchive %s'.\n", persona.Name, persona.Name)
+	return nil
+}
+
+func runPersonaUnarchive(cmd *cobra.Command, args []string) error {
+	// Load configuration
+	configPath := cfgFile
+	if configPath == "" {
+		configPath = config.GetDefaultConfigPath()
+	}
+
+	mgr := config.NewManager(configPath)
+	if !mgr.Exists() {
+		return fmt.Errorf("configuration file not found at %s\nRun 'git-keys init' first", configPath)
+	}
+
+	cfg, err := mgr.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	persona := cfg.FindPersona(args[0])
+	if persona == nil {
+		return fmt.Errorf("persona not found: %s", args[0])
+	}
+	if !persona.Archived {
+		fmt.Printf("Persona '%s' is not archived.\n", persona.Name)
+		return nil
+	}
+
+	keyMgr := sshkey.NewManager(cfg.Defaults.GetKeysDir())
+	archiveDir := keyMgr.PersonaArchiveDir(persona.Name)
+
+	for _, plat := range persona.Platforms {
+		for _, key := range plat.Keys {
+			if key.LocalPath == "" {
+				continue
+			}
+			restored, err := keyMgr.RestoreKey(key.LocalPath, archiveDir)
+			if err != nil {
+				return fmt.Errorf("failed to restore key %s: %w", key.LocalPath, err)
+			}
+			if restored {
+				fmt.Printf("✓ Restored key: %s\n", key.LocalPath)
+			}
+		}
+	}
+
+	// Drop the archive directory if everything was restored
+	os.Remove(archiveDir)
+
+	persona.Archived = false
+	persona.ArchivedAt = time.Time{}
+
+	if err := mgr.Save(cfg); err != nil {
+		return fmt.Errorf("failed to save configuration: %w", err)
+	}
+
+	recordPersonaHistory("persona-unarchive", fmt.Sprintf("Unarchived persona '%s'", persona.Name), persona.Name)
+
+	fmt.Printf("\n✅ Persona '%s' restored.\n", persona.Name)
+	fmt.Println("\nRun 'git-keys apply' to recreate its SSH config and git identity and to")
+	fmt.Println("register its keys again.")
+	return nil
+}
+
+// removePersonaGitConfig deletes the persona's per-platform git config files
+// and rewrites the managed includes in ~/.gitconfig without them
+func removePersonaGitConfig(cfg *config.Config, persona *config.Persona) error {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return fmt.Errorf("failed to get home directory: %w", err)
+	}
+
+	for _, plat := range persona.Platforms {
+		platformID := fmt.Sprintf("%s-%s", string(plat.Type), plat.Account)
+		configPath := filepath.Join(home, platformGitConfigName(persona.Name, platformID))
+		if err := os.Remove(configPath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %w", configPath, err)
+		}
+	}
+
+	var includeEntries []string
+	for _, p := range cfg.Personas {
+		if p.Archived {
+			continue
+		}
+		for _, plat := range p.Platforms {
+			platformID := fmt.Sprintf("%s-%s", string(plat.Type), plat.Account)
+			configPath := filepath.Join(home, platformGitConfigName(p.Name, platformID))
+			includeEntries = append(includeEntries, platformIncludeEntries(&plat, configPath)...)
+		}
+	}
+
+	if len(includeEntries) == 0 {
+		if err := removeGitKeysConfig(); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	return addGitConfigIncludes(filepath.Join(home, ".gitconfig"), includeEntries)
+}
+
+func recordPersonaHistory(action, summary, persona string) {
+	histMgr := history.NewManager("")
+	if err := histMgr.Record(history.Entry{
+		Action:  action,
+		Summary: summary,
+		Details: map[string]string{"persona": persona},
+	}); err != nil {
+		logger.Warn("Failed to record history: %v", err)
+	}
+}
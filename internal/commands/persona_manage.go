@@ -0,0 +1,375 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kunlu/git-keys/internal/config"
+	"github.com/kunlu/git-keys/internal/journal"
+	"github.com/kunlu/git-keys/internal/logger"
+	"github.com/kunlu/git-keys/internal/prompt"
+	"github.com/kunlu/git-keys/internal/sshconfig"
+	"github.com/kunlu/git-keys/internal/sshkey"
+	"github.com/spf13/cobra"
+)
+
+var (
+	personaAddName    string
+	personaAddEmail   string
+	personaAddType    string
+	personaAddAccount string
+	personaAddBaseURL string
+	personaAddGitDir  string
+	personaRemoveYes  bool
+)
+
+var personaAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "Add a persona without the init wizard",
+	Long: `Add a persona with its first platform.
+
+Every value comes from flags, so setups can be scripted or provisioned by a
+dotfiles manager. Add more platforms with 'git-keys platform add', then run
+'git-keys apply' to generate and register the keys.
+
+Examples:
+  git-keys persona add --name work --email me@acme.com --type github --account acme-me --gitdir ~/work/
+  git-keys persona add --name oss --email me@example.org --type gitlab --account me --base-url https://gitlab.example.org
+`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE:         runPersonaAdd,
+}
+
+var personaRemoveCmd = &cobra.Command{
+	Use:   "remove <persona>",
+	Short: "Remove a persona and its keys",
+	Long: `Remove a persona from the configuration.
+
+Its keys are deleted from GitHub/GitLab, its key files are moved to the trash,
+and its SSH config blocks and git identity configuration are removed. Use
+'git-keys persona archive' instead to pause a persona and keep its definition.
+'git-keys undo' takes the removal back.
+`,
+	Args:              cobra.ExactArgs(1),
+	SilenceUsage:      true,
+	ValidArgsFunction: completePersonas,
+	RunE:              runPersonaRemove,
+}
+
+func init() {
+	personaAddCmd.Flags().StringVar(&personaAddName, "name", "", "Persona name (e.g. personal, work)")
+	personaAddCmd.Flags().StringVar(&personaAddEmail, "email", "", "Email for git commits")
+	personaAddCmd.Flags().StringVar(&personaAddType, "type", "", "Platform type (github/gitlab)")
+	personaAddCmd.RegisterFlagCompletionFunc("type", completePlatformTypes)
+	personaAddCmd.Flags().StringVar(&personaAddAccount, "account", "", "Account/username on the platform")
+	personaAddCmd.Flags().StringVar(&personaAddBaseURL, "base-url", "", "Base URL of a self-hosted GitLab")
+	personaAddCmd.Flags().StringVar(&personaAddGitDir, "gitdir", "", "Directory whose repositories use this persona (e.g. ~/work/)")
+	for _, name := range []string{"name", "email", "type", "account"} {
+		personaAddCmd.MarkFlagRequired(name)
+	}
+	personaRemoveCmd.Flags().BoolVarP(&personaRemoveYes, "yes", "y", false, "Skip confirmation prompt")
+
+	personaCmd.AddCommand(personaAddCmd)
+	personaCmd.AddCommand(personaRemoveCmd)
+}
+
+// loadPersonaConfig loads the configuration for the persona and platform commands
+func loadPersonaConfig() (*config.Manager, *config.Config, string, error) {
+	configPath := cfgFile
+	if configPath == "" {
+		configPath = config.GetDefaultConfigPath()
+	}
+
+	mgr := config.NewManager(configPath)
+	if !mgr.Exists() {
+		return nil, nil, "", fmt.Errorf("configuration file not found at %s\nRun 'git-keys init' first", configPath)
+	}
+
+	cfg, err := mgr.Load()
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("failed to load configuration: %w", err)
+	}
+	return mgr, cfg, configPath, nil
+}
+
+func runPersonaAdd(cmd *cobra.Command, args []string) error {
+	mgr, cfg, _, err := loadPersonaConfig()
+	if err != nil {
+		return err
+	}
+
+	name := strings.TrimSpace(personaAddName)
+	if err := checkPersonaName(name); err != nil {
+		return err
+	}
+	if cfg.FindPersona(name) != nil {
+		return fmt.Errorf("persona '%s' already exists; add platforms with 'git-keys platform add %s'", name, name)
+	}
+	email := strings.TrimSpace(personaAddEmail)
+	if !strings.Contains(email, "@") {
+		return fmt.Errorf("invalid email: %q", personaAddEmail)
+	}
+
+	plat, err := newPlatformFromFlags(personaAddType, personaAddAccount, personaAddBaseURL, personaAddGitDir)
+	if err != nil {
+		return err
+	}
+
+	cfg.Personas = append(cfg.Personas, config.Persona{
+		Name:      name,
+		Email:     email,
+		Platforms: []config.Platform{*plat},
+	})
+	if err := mgr.Save(cfg); err != nil {
+		return fmt.Errorf("failed to save configuration: %w", err)
+	}
+
+	recordPersonaHistory("persona-add", fmt.Sprintf("Added persona '%s'", name), name)
+
+	fmt.Printf("✓ Added persona '%s' <%s> with %s@%s\n", name, email, plat.Account, plat.Type)
+	fmt.Println("\nRun 'git-keys apply' to generate its keys and configure SSH and git.")
+	return nil
+}
+
+func runPersonaRemove(cmd *cobra.Command, args []string) (err error) {
+	mgr, cfg, configPath, err := loadPersonaConfig()
+	if err != nil {
+		return err
+	}
+
+	persona := cfg.FindPersona(args[0])
+	if persona == nil {
+		return fmt.Errorf("persona not found: %s", args[0])
+	}
+	if len(cfg.Personas) == 1 {
+		return fmt.Errorf("cannot remove the only persona")
+	}
+	for _, dk := range cfg.DeployKeys {
+		if dk.Persona == persona.Name {
+			return fmt.Errorf("deploy key of %s uses persona '%s'; remove it first with 'git-keys deploy-key remove %s'", dk.Repo, persona.Name, dk.Repo)
+		}
+	}
+	if persona.Archived {
+		fmt.Printf("Persona '%s' is archived; its archived key files are left in place.\n", persona.Name)
+	}
+
+	printHeader("\n🗑️  Remove Persona")
+	fmt.Printf("\n  Persona: %s <%s>\n", persona.Name, persona.Email)
+	for _, plat := range persona.Platforms {
+		fmt.Printf("  Platform: %s@%s\n", plat.Account, plat.Type)
+	}
+	fmt.Println()
+
+	revocations := platformRevocations(persona, &platformTarget{Persona: persona.Name})
+	if !confirmPlatformRemoval(revocations, personaRemoveYes, fmt.Sprintf("Remove persona '%s'", persona.Name)) {
+		fmt.Println("Removal cancelled.")
+		return nil
+	}
+
+	j, err := journal.Begin("", "persona-remove")
+	if err != nil {
+		return fmt.Errorf("failed to start journal: %w", err)
+	}
+	defer func() {
+		finishJournal(j, err)
+	}()
+
+	sshMgr := sshConfigManager(cfg)
+	if err := journalApplyFiles(j, cfg, configPath, sshMgr, &platformTarget{Persona: persona.Name}); err != nil {
+		return fmt.Errorf("failed to back up files: %w", err)
+	}
+
+	removed := *persona
+	if err := removePlatformState(context.Background(), cfg, &removed, removed.Platforms, revocations, j); err != nil {
+		return err
+	}
+
+	for i := range cfg.Personas {
+		if cfg.Personas[i].Name == removed.Name {
+			cfg.Personas = append(cfg.Personas[:i], cfg.Personas[i+1:]...)
+			break
+		}
+	}
+	if err := removePersonaGitConfig(cfg, &removed); err != nil {
+		logger.Warn("Failed to update git config: %v", err)
+		fmt.Println("⚠️  Could not update git config. Run 'git-keys setup-git' to fix it.")
+	} else if !removed.Archived {
+		fmt.Println("✓ Removed git identity configuration")
+	}
+
+	if err := mgr.Save(cfg); err != nil {
+		return fmt.Errorf("failed to save configuration: %w", err)
+	}
+
+	recordPersonaHistory("persona-remove", fmt.Sprintf("Removed persona '%s'", removed.Name), removed.Name)
+
+	fmt.Printf("\n✅ Persona '%s' removed.\n", removed.Name)
+	return nil
+}
+
+// checkPersonaName rejects names that cannot be used in <persona>/<platform>
+// targets
+func checkPersonaName(name string) error {
+	if name == "" {
+		return fmt.Errorf("persona name is required")
+	}
+	if strings.ContainsAny(name, "/@") {
+		return fmt.Errorf("invalid persona name %q: '/' and '@' are not allowed", name)
+	}
+	return nil
+}
+
+// newPlatformFromFlags builds a platform from the values of --type, --account,
+// --base-url and --gitdir
+func newPlatformFromFlags(platformType, account, baseURL, gitDir string) (*config.Platform, error) {
+	plat := &config.Platform{
+		Type:    config.PlatformType(strings.ToLower(strings.TrimSpace(platformType))),
+		Account: strings.TrimSpace(account),
+	}
+	if plat.Type != config.PlatformGitHub && plat.Type != config.PlatformGitLab {
+		return nil, fmt.Errorf("invalid platform type: %s (use github or gitlab)", platformType)
+	}
+	if plat.Account == "" {
+		return nil, fmt.Errorf("account is required")
+	}
+
+	baseURL = strings.TrimSuffix(strings.TrimSpace(baseURL), "/")
+	if baseURL != "" {
+		if plat.Type != config.PlatformGitLab {
+			return nil, fmt.Errorf("--base-url is only supported for gitlab")
+		}
+		if !strings.HasPrefix(baseURL, "https://") && !strings.HasPrefix(baseURL, "http://") {
+			baseURL = "https://" + baseURL
+		}
+		plat.BaseURL = baseURL
+	} else if plat.Type == config.PlatformGitLab {
+		plat.BaseURL = "https://gitlab.com"
+	}
+
+	// A trailing slash makes includeIf match everything below the directory
+	if gitDir = strings.TrimSpace(gitDir); gitDir != "" && !strings.HasSuffix(gitDir, "/") {
+		gitDir += "/"
+	}
+	plat.GitDir = gitDir
+	return plat, nil
+}
+
+// platformRevocations lists the keys of the targeted platforms that are
+// still registered or present
+func platformRevocations(persona *config.Persona, target *platformTarget) []keyRevocation {
+	var revocations []keyRevocation
+	for platformIdx := range persona.Platforms {
+		plat := &persona.Platforms[platformIdx]
+		if !target.includes(persona, plat) {
+			continue
+		}
+		// Keys of other machines go too, as the platform leaves the shared
+		// configuration; their key files are not on this machine
+		keys := append([]config.KeyConfig(nil), plat.Keys...)
+		for _, key := range plat.OtherKeys {
+			key.LocalPath = ""
+			keys = append(keys, key)
+		}
+		for _, key := range keys {
+			if key.Status == config.KeyStatusRevoked && key.LocalPath == "" {
+				continue
+			}
+			revocations = append(revocations, keyRevocation{
+				Persona:     persona.Name,
+				Platform:    plat.Type,
+				Account:     plat.Account,
+				BaseURL:     plat.BaseURL,
+				Key:         key,
+				PersonaRef:  persona,
+				PlatformRef: plat,
+			})
+		}
+	}
+	return revocations
+}
+
+// confirmPlatformRemoval shows the keys a removal deletes and asks before
+// going ahead
+func confirmPlatformRemoval(revocations []keyRevocation, yes bool, question string) bool {
+	ctx := context.Background()
+	for i := range revocations {
+		kr := &revocations[i]
+		fmt.Printf("  %s@%s key %s\n", kr.Account, kr.Platform, kr.Key.Fingerprint)
+		if kr.Key.RemoteID != "" && kr.Key.Status != config.KeyStatusRevoked {
+			kr.checkRemote(ctx)
+			kr.RemoteCheck.Print(os.Stdout, "    ")
+		}
+	}
+	if len(revocations) > 0 {
+		fmt.Println()
+	}
+
+	if yes {
+		return true
+	}
+	return prompt.Confirm(question + " and delete its keys?")
+}
+
+// removePlatformState deletes the keys of the given platforms from their
+// platforms, moves the key files to the trash and removes the SSH config
+// blocks. Nothing local changes unless every remote key was deleted.
+func removePlatformState(ctx context.Context, cfg *config.Config, persona *config.Persona, platforms []config.Platform, revocations []keyRevocation, j *journal.Journal) error {
+	var failures []string
+	for i := range revocations {
+		kr := &revocations[i]
+		if kr.Key.Status == config.KeyStatusRevoked {
+			continue
+		}
+		if err := revokeKey(ctx, kr, j); err != nil {
+			logger.Warn("Failed to revoke key %s: %v", kr.Key.Fingerprint, err)
+			failures = append(failures, fmt.Sprintf("%s@%s: %v", kr.Account, kr.Platform, err))
+			continue
+		}
+		if kr.Key.RemoteID != "" {
+			fmt.Printf("✓ Removed key from %s@%s\n", kr.Account, kr.Platform)
+		}
+	}
+	if len(failures) > 0 {
+		fmt.Printf("\n❌ %d key(s) could not be removed from remote platforms:\n", len(failures))
+		for _, f := range failures {
+			printWrapped("   • ", f)
+		}
+		return fmt.Errorf("nothing was removed; fix the errors above and run the command again")
+	}
+
+	// Archived personas keep their key files in the archive directory
+	if !persona.Archived {
+		keyMgr := sshkey.NewManager(cfg.Defaults.GetKeysDir())
+		for _, kr := range revocations {
+			if kr.Key.LocalPath == "" {
+				continue
+			}
+			item, err := keyMgr.TrashKey(kr.Key.LocalPath)
+			if err != nil {
+				logger.Warn("Failed to move %s to the trash: %v", kr.Key.LocalPath, err)
+				fmt.Printf("⚠️  %s: %v\n", kr.Key.LocalPath, err)
+				continue
+			}
+			if item != nil {
+				journalWarn(j.KeyTrashed(filepath.Join(keyMgr.KeysDir(), kr.Key.LocalPath), item.ID))
+				fmt.Printf("✓ Moved %s to the trash\n", kr.Key.LocalPath)
+			}
+		}
+	}
+
+	sshMgr := sshConfigManager(cfg)
+	for _, plat := range platforms {
+		blockID := sshconfig.GetManagedBlockID(persona.Name, plat.Type, plat.Account)
+		if err := sshMgr.RemoveEntry(blockID); err != nil {
+			return fmt.Errorf("failed to remove SSH config block %s: %w", blockID, err)
+		}
+	}
+	if !persona.Archived {
+		fmt.Println("✓ Removed SSH config entries")
+	}
+	return nil
+}
@@ -0,0 +1,259 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kunlu/git-keys/internal/config"
+	"github.com/kunlu/git-keys/internal/journal"
+	"github.com/kunlu/git-keys/internal/logger"
+	"github.com/kunlu/git-keys/internal/sshconfig"
+	"github.com/kunlu/git-keys/internal/sshkey"
+	"github.com/spf13/cobra"
+)
+
+var personaRenameCmd = &cobra.Command{
+	Use:   "rename <old> <new>",
+	Short: "Rename a persona",
+	Long: `Rename a persona and everything named after it.
+
+The persona name is part of its SSH host aliases (github.com.<persona>), SSH
+config block IDs and git config file names. rename updates them together:
+  1. the persona in the configuration, and the deploy keys that use it
+  2. the managed SSH config blocks, moved in place to the new block IDs
+  3. the git config files (~/.gitconfig-<persona>-...) and ~/.gitconfig includes
+  4. user.name and remote URLs of repositories bound with 'git-keys use'
+
+Repositories that still use the old host alias in their remote keep working:
+the git config files rewrite it to the new alias. If any step fails, every
+change is rolled back.
+
+Example:
+  git-keys persona rename work acme
+`,
+	Args:              cobra.ExactArgs(2),
+	SilenceUsage:      true,
+	ValidArgsFunction: completePersonas,
+	RunE:              runPersonaRename,
+}
+
+func init() {
+	personaCmd.AddCommand(personaRenameCmd)
+}
+
+func runPersonaRename(cmd *cobra.Command, args []string) (err error) {
+	mgr, cfg, configPath, err := loadPersonaConfig()
+	if err != nil {
+		return err
+	}
+
+	oldName, newName := args[0], strings.TrimSpace(args[1])
+	persona := cfg.FindPersona(oldName)
+	if persona == nil {
+		return fmt.Errorf("persona not found: %s", oldName)
+	}
+	if err := checkPersonaName(newName); err != nil {
+		return err
+	}
+	if cfg.FindPersona(newName) != nil {
+		return fmt.Errorf("persona '%s' already exists", newName)
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return fmt.Errorf("failed to get home directory: %w", err)
+	}
+
+	printHeader(fmt.Sprintf("\n✏️  Rename Persona %s → %s", oldName, newName))
+	fmt.Println()
+
+	// Every change is recorded so a failure part of the way is rolled back
+	j, err := journal.Begin("", "persona-rename")
+	if err != nil {
+		return fmt.Errorf("failed to start journal: %w", err)
+	}
+	defer func() {
+		finishAtomicJournal(j, configPath, "Rename", err)
+	}()
+
+	sshMgr := sshConfigManager(cfg)
+	if err := journalApplyFiles(j, cfg, configPath, sshMgr, &platformTarget{Persona: oldName}); err != nil {
+		return fmt.Errorf("failed to back up files: %w", err)
+	}
+	for _, plat := range persona.Platforms {
+		if err := j.BackupFile(platformGitConfigPath(home, newName, &plat)); err != nil {
+			return fmt.Errorf("failed to back up files: %w", err)
+		}
+	}
+
+	old := *persona
+	old.Platforms = append([]config.Platform(nil), persona.Platforms...)
+
+	persona.Name = newName
+	persona.PreviousNames = renamedPreviousNames(persona.PreviousNames, oldName, newName)
+	for i := range cfg.DeployKeys {
+		if cfg.DeployKeys[i].Persona == oldName {
+			cfg.DeployKeys[i].Persona = newName
+		}
+	}
+
+	keyMgr := sshkey.NewManager(cfg.Defaults.GetKeysDir())
+	if persona.Archived {
+		// Archived key files live in a directory named after the persona
+		oldDir, newDir := keyMgr.PersonaArchiveDir(oldName), keyMgr.PersonaArchiveDir(newName)
+		if _, err := os.Stat(oldDir); err == nil {
+			if err := os.Rename(oldDir, newDir); err != nil {
+				return fmt.Errorf("failed to move key archive: %w", err)
+			}
+			journalWarn(j.KeyMoved(oldDir, newDir))
+			fmt.Printf("✓ Moved key archive to %s\n", newDir)
+		}
+	} else {
+		if err := renamePersonaSSHBlocks(cfg, sshMgr, keyMgr, &old, persona); err != nil {
+			return err
+		}
+		if err := renamePersonaGitConfig(cfg, home, &old, persona); err != nil {
+			return err
+		}
+		renameBoundRepos(j, &old, persona)
+	}
+
+	if err := mgr.Save(cfg); err != nil {
+		return fmt.Errorf("failed to save configuration: %w", err)
+	}
+	fmt.Println("✓ Updated configuration")
+
+	recordPersonaHistory("persona-rename", fmt.Sprintf("Renamed persona '%s' to '%s'", oldName, newName), newName)
+
+	fmt.Printf("\n✅ Persona '%s' renamed to '%s'.\n", oldName, newName)
+	return nil
+}
+
+// renamedPreviousNames records oldName as a previous name of a persona now
+// called newName
+func renamedPreviousNames(previous []string, oldName, newName string) []string {
+	var names []string
+	for _, name := range previous {
+		if name != newName && name != oldName {
+			names = append(names, name)
+		}
+	}
+	return append(names, oldName)
+}
+
+// renamePersonaSSHBlocks moves the managed SSH config blocks of a renamed
+// persona to their new IDs and host aliases
+func renamePersonaSSHBlocks(cfg *config.Config, sshMgr *sshconfig.Manager, keyMgr *sshkey.Manager, old, persona *config.Persona) error {
+	blocks, err := sshMgr.ManagedBlocks()
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]bool)
+	for _, block := range blocks {
+		existing[block.ID] = true
+	}
+
+	for i := range persona.Platforms {
+		plat := &persona.Platforms[i]
+		oldID := sshconfig.GetManagedBlockID(old.Name, plat.Type, plat.Account)
+		newID := sshconfig.GetManagedBlockID(persona.Name, plat.Type, plat.Account)
+		if !existing[oldID] {
+			continue
+		}
+
+		key := plat.GetActiveKey()
+		if key == nil || key.PublicOnly {
+			// Nothing to point the new block at; drop the stale one
+			if err := sshMgr.RemoveEntry(oldID); err != nil {
+				return fmt.Errorf("failed to remove SSH config block %s: %w", oldID, err)
+			}
+			continue
+		}
+
+		if err := sshMgr.RenameEntry(oldID, newID, platformSSHEntries(cfg, keyMgr, persona, plat, key)); err != nil {
+			return fmt.Errorf("failed to rename SSH config block %s: %w", oldID, err)
+		}
+		alias, _ := sshHostAlias(persona, plat)
+		fmt.Printf("✓ Renamed SSH config block %s → %s (Host %s)\n", oldID, newID, alias)
+	}
+	return nil
+}
+
+// renamePersonaGitConfig writes the git config files of a renamed persona
+// under their new names, removes the old files and rewrites the includes in
+// ~/.gitconfig
+func renamePersonaGitConfig(cfg *config.Config, home string, old, persona *config.Persona) error {
+	var written bool
+	for i := range persona.Platforms {
+		plat := &persona.Platforms[i]
+		if _, err := os.Stat(platformGitConfigPath(home, old.Name, plat)); err != nil {
+			continue // Not set up yet; apply writes it under the new name
+		}
+
+		newPath := platformGitConfigPath(home, persona.Name, plat)
+		if err := createPlatformGitConfigFile(cfg, persona, plat, newPath); err != nil {
+			return fmt.Errorf("failed to write %s: %w", newPath, err)
+		}
+		fmt.Printf("✓ Wrote %s\n", newPath)
+		written = true
+	}
+	if !written {
+		return nil
+	}
+
+	// Removes the old files and rebuilds the includes from the renamed config
+	if err := removePersonaGitConfig(cfg, old); err != nil {
+		return fmt.Errorf("failed to update git config: %w", err)
+	}
+	fmt.Println("✓ Updated ~/.gitconfig includes")
+	return nil
+}
+
+// renameBoundRepos updates the local identity and the remote URLs that use
+// the old host alias in repositories bound to a renamed persona. Failures
+// only warn; the git config files rewrite old aliases anyway.
+func renameBoundRepos(j *journal.Journal, old, persona *config.Persona) {
+	for i := range persona.Platforms {
+		plat := &persona.Platforms[i]
+		oldAlias, _ := sshHostAlias(old, plat)
+		newAlias, _ := sshHostAlias(persona, plat)
+
+		for _, repo := range plat.Repos {
+			gitPath, err := gitOutput(repo, "rev-parse", "--git-path", "config")
+			if err != nil {
+				logger.Warn("Failed to update bound repository %s: %v", repo, err)
+				continue
+			}
+			if !filepath.IsAbs(gitPath) {
+				gitPath = filepath.Join(repo, gitPath)
+			}
+			journalWarn(j.BackupFile(gitPath))
+
+			if name, _ := gitOutput(repo, "config", "--local", "--get", "user.name"); name == old.Name {
+				if _, err := gitOutput(repo, "config", "--local", "user.name", persona.Name); err != nil {
+					logger.Warn("Failed to set user.name in %s: %v", repo, err)
+				}
+			}
+
+			remotes, _ := gitOutput(repo, "config", "--local", "--get-regexp", `^remote\..*\.url$`)
+			for _, line := range strings.Split(remotes, "\n") {
+				key, url, ok := strings.Cut(line, " ")
+				if !ok || remoteHost(url) != oldAlias {
+					continue
+				}
+				if _, err := gitOutput(repo, "config", "--local", key, strings.Replace(url, oldAlias, newAlias, 1)); err != nil {
+					logger.Warn("Failed to update %s in %s: %v", key, repo, err)
+				}
+			}
+			fmt.Printf("✓ Updated bound repository %s\n", repo)
+		}
+	}
+}
+
+// platformGitConfigPath returns the git config file apply writes for a
+// persona's platform
+func platformGitConfigPath(home, personaName string, plat *config.Platform) string {
+	return filepath.Join(home, platformGitConfigName(personaName, fmt.Sprintf("%s-%s", plat.Type, plat.Account)))
+}
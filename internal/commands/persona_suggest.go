@@ -0,0 +1,319 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/kunlu/git-keys/internal/config"
+	"github.com/kunlu/git-keys/internal/prompt"
+	"github.com/kunlu/git-keys/internal/sshkey"
+	"github.com/spf13/cobra"
+)
+
+var personaSuggestCmd = &cobra.Command{
+	Use:   "suggest <remote-url>",
+	Short: "Suggest which persona a remote belongs to",
+	Long: `Rank the configured personas for a git remote.
+
+The remote's host and namespace (the user or group in the URL) are compared
+with each persona's platforms:
+  - an SSH alias written by git-keys (github.com.work) names the persona
+  - a namespace equal to the platform account
+  - a namespace seen in repositories under the platform's gitdir
+  - a namespace that matches the persona's email domain
+  - the current directory being inside the platform's gitdir
+
+In a terminal, pick a persona with one keystroke to get the remote URL
+rewritten to that persona's SSH alias.
+
+Examples:
+  git-keys persona suggest git@github.com:acme/api.git
+  git-keys persona suggest https://gitlab.company.com/platform/infra.git
+`,
+	Args: cobra.ExactArgs(1),
+	RunE: runPersonaSuggest,
+}
+
+func init() {
+	personaCmd.AddCommand(personaSuggestCmd)
+}
+
+// personaSuggestion is a persona/platform ranked for a remote
+type personaSuggestion struct {
+	Persona  *config.Persona
+	Platform *config.Platform
+	Score    int
+	Reasons  []string
+}
+
+func runPersonaSuggest(cmd *cobra.Command, args []string) error {
+	// Load configuration
+	configPath := cfgFile
+	if configPath == "" {
+		configPath = config.GetDefaultConfigPath()
+	}
+
+	mgr := config.NewManager(configPath)
+	if !mgr.Exists() {
+		return fmt.Errorf("configuration file not found at %s\nRun 'git-keys init' first", configPath)
+	}
+
+	cfg, err := mgr.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	remote := args[0]
+	suggestions, err := suggestPersonas(cfg, remote)
+	if err != nil {
+		return err
+	}
+	if len(suggestions) == 0 {
+		return fmt.Errorf("no configured persona uses the host of %s", remote)
+	}
+
+	printHeader("\n🧭 Persona Suggestions")
+	fmt.Printf("\n  Remote: %s\n\n", remote)
+
+	chosen := choosePersona(suggestions)
+	if chosen == nil {
+		fmt.Println("No persona chosen.")
+		return nil
+	}
+
+	alias, _ := sshHostAlias(chosen.Persona, chosen.Platform)
+	fmt.Printf("\n✓ %s (%s@%s)\n", chosen.Persona.Name, chosen.Platform.Account, chosen.Platform.Type)
+	if path := remotePath(remote); path != "" {
+		fmt.Printf("  Remote URL for this persona: git@%s:%s\n", alias, path)
+	}
+	return nil
+}
+
+// suggestPersonas ranks the non-archived persona platforms on the remote's
+// host, best match first. Platforms on other hosts are not returned.
+func suggestPersonas(cfg *config.Config, remote string) ([]personaSuggestion, error) {
+	platformType, _, namespace := parseGitRemoteURL(remote)
+	if platformType == "" {
+		return nil, fmt.Errorf("not a GitHub or GitLab remote: %s", remote)
+	}
+	host := remoteHost(remote)
+	namespace = strings.ToLower(namespace)
+
+	cwd, _ := os.Getwd()
+	var boundPlatform *config.Platform
+	if cwd != "" {
+		_, boundPlatform, _ = cfg.FindRepoBinding(cwd)
+	}
+
+	var suggestions []personaSuggestion
+	for personaIdx := range cfg.Personas {
+		persona := &cfg.Personas[personaIdx]
+		if persona.Archived {
+			continue
+		}
+		for platformIdx := range persona.Platforms {
+			plat := &persona.Platforms[platformIdx]
+			if string(plat.Type) != platformType {
+				continue
+			}
+
+			alias, hostname := sshHostAlias(persona, plat)
+			if strings.EqualFold(host, alias) {
+				// The remote already uses this persona's alias
+				suggestions = append(suggestions, personaSuggestion{
+					Persona: persona, Platform: plat, Score: 1000,
+					Reasons: []string{"remote uses the persona's SSH alias " + alias},
+				})
+				continue
+			}
+			if !strings.EqualFold(hostname, host) {
+				continue
+			}
+
+			s := personaSuggestion{Persona: persona, Platform: plat, Score: 1}
+			if namespace != "" {
+				if strings.EqualFold(plat.Account, namespace) {
+					s.Score += 100
+					s.Reasons = append(s.Reasons, "namespace is the account "+plat.Account)
+				}
+				if gitdir := gitDirWithNamespace(plat, namespace); gitdir != "" {
+					s.Score += 50
+					s.Reasons = append(s.Reasons, "namespace used by repos in "+gitdir)
+				}
+				if emailDomainMatches(persona.Email, namespace) {
+					s.Score += 30
+					s.Reasons = append(s.Reasons, "namespace matches "+persona.Email)
+				}
+			}
+			if plat == boundPlatform {
+				s.Score += 200
+				s.Reasons = append(s.Reasons, "current repository is bound to the persona")
+			}
+			if gitdir := platformGitDirFor(plat, cwd); cwd != "" && gitdir != "" {
+				s.Score += 20
+				s.Reasons = append(s.Reasons, "current directory is in "+gitdir)
+			}
+			suggestions = append(suggestions, s)
+		}
+	}
+
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		return suggestions[i].Score > suggestions[j].Score
+	})
+	return suggestions, nil
+}
+
+// gitDirWithNamespace returns the first of the platform's gitdir patterns
+// holding repositories with remotes in namespace, or "" when none does
+func gitDirWithNamespace(plat *config.Platform, namespace string) string {
+	for _, gitdir := range plat.AllGitDirs() {
+		for _, discovered := range discoverPlatformsInDirectory(gitdir) {
+			if discovered.Type != string(plat.Type) {
+				continue
+			}
+			for _, group := range discovered.Groups {
+				if strings.EqualFold(group, namespace) {
+					return gitdir
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// emailDomainMatches reports whether namespace is a label of the email's
+// domain, e.g. "acme" for bob@acme.com or bob@eng.acme.io
+func emailDomainMatches(email, namespace string) bool {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	for _, label := range strings.Split(strings.ToLower(email[at+1:]), ".") {
+		if label == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+// platformGitDirFor returns the platform's gitdir pattern that contains
+// dir, or "" when none does
+func platformGitDirFor(plat *config.Platform, dir string) string {
+	for _, gitdir := range plat.AllGitDirs() {
+		if insideGitDir(dir, gitdir) {
+			return gitdir
+		}
+	}
+	return ""
+}
+
+// insideGitDir reports whether dir is inside a gitdir pattern
+func insideGitDir(dir, gitdir string) bool {
+	pattern := strings.TrimSuffix(sshkey.ExpandHome(strings.TrimPrefix(gitdir, "gitdir:")), "/")
+	if pattern == "" {
+		return false
+	}
+	rel, err := filepath.Rel(pattern, dir)
+	return err == nil && !strings.HasPrefix(rel, "..")
+}
+
+// remoteHost returns the host of an SSH or HTTPS remote URL
+func remoteHost(remote string) string {
+	rest := remote
+	for _, prefix := range []string{"ssh://", "https://", "http://"} {
+		rest = strings.TrimPrefix(rest, prefix)
+	}
+	if at := strings.Index(rest, "@"); at >= 0 && at < strings.IndexAny(rest+":", ":/") {
+		rest = rest[at+1:]
+	}
+	if end := strings.IndexAny(rest, ":/"); end >= 0 {
+		rest = rest[:end]
+	}
+	return rest
+}
+
+// remotePath returns the repository path of a remote URL, e.g. acme/api.git
+func remotePath(remote string) string {
+	separator := ":" // scp-like git@host:path
+	rest := remote
+	for _, prefix := range []string{"ssh://", "https://", "http://"} {
+		if strings.HasPrefix(rest, prefix) {
+			rest = strings.TrimPrefix(rest, prefix)
+			separator = "/"
+		}
+	}
+	if idx := strings.Index(rest, separator); idx >= 0 {
+		return strings.TrimPrefix(rest[idx+1:], "/")
+	}
+	return ""
+}
+
+// choosePersona shows ranked suggestions and lets the user pick one with a
+// single keystroke: a number, Enter for the first, or q to cancel. Without
+// a terminal, or in non-interactive mode, the best suggestion is returned.
+func choosePersona(suggestions []personaSuggestion) *personaSuggestion {
+	if len(suggestions) > 9 {
+		suggestions = suggestions[:9]
+	}
+
+	for i, s := range suggestions {
+		fmt.Printf("  [%d] %s <%s>  %s@%s\n", i+1, s.Persona.Name, s.Persona.Email, s.Platform.Account, s.Platform.Type)
+		for _, reason := range s.Reasons {
+			printWrapped("        • ", reason)
+		}
+	}
+
+	info, err := os.Stdin.Stat()
+	if nonInteractive || err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return &suggestions[0]
+	}
+
+	fmt.Printf("\nChoose persona [1-%d, Enter = 1, q = cancel]: ", len(suggestions))
+	for {
+		key := readKey()
+		switch {
+		case key == '\r' || key == '\n':
+			fmt.Println("1")
+			return &suggestions[0]
+		case key == 'q' || key == 'Q' || key == 0x1b || key == 0:
+			fmt.Println()
+			return nil
+		case key >= '1' && int(key-'0') <= len(suggestions):
+			fmt.Println(string(key))
+			return &suggestions[key-'1']
+		}
+	}
+}
+
+// readKey reads a single keystroke from the terminal without waiting for
+// Enter. It falls back to reading a line when the terminal mode cannot be
+// changed. Returns 0 on EOF.
+func readKey() byte {
+	raw := exec.Command("stty", "-icanon", "-echo", "min", "1")
+	raw.Stdin = os.Stdin
+	if err := raw.Run(); err != nil {
+		line, ok := prompt.Line()
+		if line == "" {
+			if !ok {
+				return 0
+			}
+			return '\n'
+		}
+		return line[0]
+	}
+	defer func() {
+		restore := exec.Command("stty", "icanon", "echo")
+		restore.Stdin = os.Stdin
+		restore.Run()
+	}()
+
+	buf := make([]byte, 1)
+	if n, _ := os.Stdin.Read(buf); n == 0 {
+		return 0
+	}
+	return buf[0]
+}
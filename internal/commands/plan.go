@@ -1,21 +1,62 @@
 package commands
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/kunlu/git-keys/internal/config"
 	"github.com/kunlu/git-keys/internal/logger"
+	"github.com/kunlu/git-keys/internal/sshkey"
 	"github.com/spf13/cobra"
 )
 
+var (
+	planDetailedExitCode bool
+	planOffline          bool
+	planStrict           bool
+)
+
 var planCmd = &cobra.Command{
 	Use:   "plan",
 	Short: "Show what changes git-keys will make",
-	Long:  `Show a detailed plan of changes without applying them.`,
-	RunE:  runPlan,
+	Long: `Compare the configuration with the actual state and show the changes
+'git-keys sync' would make, without making them:
+  - keys to generate, and keys to upload to a platform
+  - SSH config blocks to add, modify or remove
+  - per-platform git config files and includeIf entries to write
+  - remote keys that would be deleted (revoked keys, and keys of archived
+    personas, that are still registered)
+  - differences that need a manual fix, with the command that fixes them
+
+Changes are marked + (create), ~ (update), - (delete) and ! (manual).
+Use --offline to skip the remote keys on the platforms.
+
+With --strict (or strict: true under defaults), plan fails when there are
+warnings: personas without an email, platforms without a gitdir, insecure
+permissions or weak keys.
+
+With --detailed-exitcode, or --quiet, the exit status tells CI whether
+anything drifted:
+  0  the actual state matches the configuration
+  1  plan failed
+  2  there are changes
+  3  keys expired or expire within the due window (defaults.rotate_within)
+
+Examples:
+  git-keys plan
+  git-keys plan --offline --detailed-exitcode
+  git-keys plan --strict
+  git-keys plan --offline --quiet || echo "drift: $?"
+`,
+	Annotations:  map[string]string{structuredOutputAnnotation: structuredQuiet},
+	RunE:         runPlan,
+	SilenceUsage: true,
 }
 
 func init() {
+	planCmd.Flags().BoolVar(&planDetailedExitCode, "detailed-exitcode", false, "Exit with status 2 when there are changes, and 3 when keys expire soon")
+	planCmd.Flags().BoolVar(&planOffline, "offline", false, "Skip the remote keys on the platforms")
+	planCmd.Flags().BoolVar(&planStrict, "strict", false, "Treat warnings as errors")
 	rootCmd.AddCommand(planCmd)
 }
 
@@ -38,26 +79,121 @@ func runPlan(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
-	// Display summary
-	fmt.Printf("\n📋 Configuration Summary:\n\n")
-	fmt.Printf("Machine: %s (%s)\n", cfg.Machine.Name, cfg.Machine.ID)
-	fmt.Printf("Personas: %d\n", len(cfg.Personas))
-
-	for _, persona := range cfg.Personas {
-		fmt.Printf("\n  • %s (%s)\n", persona.Name, persona.Email)
-		for _, platform := range persona.Platforms {
-			fmt.Printf("    - %s/%s\n", platform.Type, platform.Account)
-			activeKey := platform.GetActiveKey()
-			if activeKey != nil {
-				fmt.Printf("      Key: %s (expires: %s)\n",
-					activeKey.Fingerprint,
-					activeKey.ExpiresAt.Format("2006-01-02"))
-			} else {
-				fmt.Printf("      ⚠️  No active key - run 'git-keys apply' to create\n")
-			}
+	keyMgr := sshkey.NewManager(cfg.Defaults.GetKeysDir())
+	sshMgr := sshConfigManager(cfg)
+
+	printHeader("\n📋 Execution Plan")
+	fmt.Printf("\n  Config:  %s\n", configPath)
+	fmt.Printf("  Machine: %s (%s)\n\n", cfg.Machine.Name, cfg.Machine.ID)
+
+	if expired := reconcileExpiry(mgr, cfg); len(expired) > 0 {
+		fmt.Println()
+	}
+
+	plan, failures, err := buildSyncPlan(context.Background(), cfg, keyMgr, sshMgr, planOffline)
+	if err != nil {
+		return err
+	}
+
+	if len(plan.changes) == 0 {
+		printCheck(checkOK, "No changes. The actual state matches the configuration.")
+		printRelinkFailures(failures)
+	} else {
+		counts := plan.print()
+		printRelinkFailures(failures)
+
+		if counts[syncManual] < len(plan.changes) {
+			fmt.Println("\nRun 'git-keys sync' to make these changes.")
+		}
+	}
+
+	// Plans are checked in CI; a policy violation fails them
+	violations := policyViolations(cfg)
+	if structuredOutput() {
+		if err := printStructured("Plan", newPlanReport(configPath, cfg, plan, failures, violations)); err != nil {
+			return err
+		}
+	}
+	if len(violations) > 0 {
+		fmt.Println()
+		if err := enforcePolicy(cfg); err != nil {
+			return err
 		}
 	}
 
-	fmt.Println("\nRun 'git-keys apply' to apply configuration.")
+	if strictMode(cmd, cfg) {
+		if err := checkStrict(cfg, keyMgr, nil); err != nil {
+			return err
+		}
+	}
+
+	dueWindow, err := rotateDueWindow(cfg, "")
+	if err != nil {
+		return err
+	}
+	if expiringKeys(cfg, dueWindow) > 0 {
+		return exitWithStatus(cmd, ExitExpiring)
+	}
+	if len(plan.changes) > 0 {
+		return exitWithStatus(cmd, ExitWarnings)
+	}
 	return nil
 }
+
+// planReport is the Plan document of 'plan --output json|yaml'
+type planReport struct {
+	Config           string             `json:"config"`
+	Machine          string             `json:"machine"`
+	Changes          []planReportChange `json:"changes"`
+	Create           int                `json:"create"`
+	Update           int                `json:"update"`
+	Delete           int                `json:"delete"`
+	Manual           int                `json:"manual"`
+	Failures         []string           `json:"failures"` // Platforms that could not be checked
+	PolicyViolations []string           `json:"policy_violations"`
+}
+
+// planReportChange is a change of the plan
+type planReportChange struct {
+	Action        string `json:"action"` // create, update, delete or manual
+	Resource      string `json:"resource"`
+	Detail        string `json:"detail"`
+	UpdatesConfig bool   `json:"updates_config"`
+}
+
+// planActions names the change operations in documents
+var planActions = map[string]string{
+	syncCreate: "create",
+	syncUpdate: "update",
+	syncDelete: "delete",
+	syncManual: "manual",
+}
+
+func newPlanReport(configPath string, cfg *config.Config, plan *syncPlan, failures, violations []string) planReport {
+	report := planReport{
+		Config:           configPath,
+		Machine:          cfg.Machine.ID,
+		Changes:          []planReportChange{},
+		Failures:         append([]string{}, failures...),
+		PolicyViolations: append([]string{}, violations...),
+	}
+	for _, c := range plan.changes {
+		report.Changes = append(report.Changes, planReportChange{
+			Action:        planActions[c.Op],
+			Resource:      c.Resource,
+			Detail:        c.Detail,
+			UpdatesConfig: c.UpdatesConfig,
+		})
+		switch c.Op {
+		case syncCreate:
+			report.Create++
+		case syncUpdate:
+			report.Update++
+		case syncDelete:
+			report.Delete++
+		case syncManual:
+			report.Manual++
+		}
+	}
+	return report
+}
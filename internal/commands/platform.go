@@ -0,0 +1,206 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kunlu/git-keys/internal/config"
+	"github.com/kunlu/git-keys/internal/history"
+	"github.com/kunlu/git-keys/internal/journal"
+	"github.com/kunlu/git-keys/internal/logger"
+	"github.com/spf13/cobra"
+)
+
+var (
+	platformAddType    string
+	platformAddAccount string
+	platformAddBaseURL string
+	platformAddGitDir  string
+	platformRemoveYes  bool
+)
+
+var platformCmd = &cobra.Command{
+	Use:   "platform",
+	Short: "Manage a persona's platforms",
+	Long: `Add or remove the platform accounts of a persona without the init wizard.
+
+Subcommands:
+  add     - Add a platform account to a persona
+  remove  - Remove a platform account and its keys
+`,
+}
+
+var platformAddCmd = &cobra.Command{
+	Use:   "add <persona>",
+	Short: "Add a platform account to a persona",
+	Long: `Add a platform account to an existing persona.
+
+Run 'git-keys apply' afterwards to generate and register its key.
+
+Examples:
+  git-keys platform add work --type gitlab --account acme-me --base-url gitlab.acme.com
+  git-keys platform add personal --type github --account me --gitdir ~/src/
+`,
+	Args:              cobra.ExactArgs(1),
+	SilenceUsage:      true,
+	ValidArgsFunction: completePersonas,
+	RunE:              runPlatformAdd,
+}
+
+var platformRemoveCmd = &cobra.Command{
+	Use:   "remove <persona>/<platform>[@account]",
+	Short: "Remove a platform account and its keys",
+	Long: `Remove a platform account from a persona.
+
+Its keys are deleted from the platform, its key files are moved to the trash,
+and its SSH config block and git config file are removed. The last platform of
+a persona cannot be removed; use 'git-keys persona remove' instead.
+'git-keys undo' takes the removal back.
+
+Examples:
+  git-keys platform remove work/gitlab
+  git-keys platform remove personal/github@old-account -y
+`,
+	Args:              cobra.ExactArgs(1),
+	SilenceUsage:      true,
+	ValidArgsFunction: completePersonaPlatforms,
+	RunE:              runPlatformRemove,
+}
+
+func init() {
+	platformAddCmd.Flags().StringVar(&platformAddType, "type", "", "Platform type (github/gitlab)")
+	platformAddCmd.RegisterFlagCompletionFunc("type", completePlatformTypes)
+	platformAddCmd.Flags().StringVar(&platformAddAccount, "account", "", "Account/username on the platform")
+	platformAddCmd.Flags().StringVar(&platformAddBaseURL, "base-url", "", "Base URL of a self-hosted GitLab")
+	platformAddCmd.Flags().StringVar(&platformAddGitDir, "gitdir", "", "Directory whose repositories use this account (e.g. ~/work/)")
+	platformAddCmd.MarkFlagRequired("type")
+	platformAddCmd.MarkFlagRequired("account")
+	platformRemoveCmd.Flags().BoolVarP(&platformRemoveYes, "yes", "y", false, "Skip confirmation prompt")
+
+	platformCmd.AddCommand(platformAddCmd)
+	platformCmd.AddCommand(platformRemoveCmd)
+	rootCmd.AddCommand(platformCmd)
+}
+
+func runPlatformAdd(cmd *cobra.Command, args []string) error {
+	mgr, cfg, _, err := loadPersonaConfig()
+	if err != nil {
+		return err
+	}
+
+	persona := cfg.FindPersona(args[0])
+	if persona == nil {
+		return fmt.Errorf("persona not found: %s", args[0])
+	}
+
+	plat, err := newPlatformFromFlags(platformAddType, platformAddAccount, platformAddBaseURL, platformAddGitDir)
+	if err != nil {
+		return err
+	}
+	for _, existing := range persona.Platforms {
+		_, existingHost := sshHostAlias(persona, &existing)
+		_, host := sshHostAlias(persona, plat)
+		if existingHost == host && existing.Account == plat.Account {
+			return fmt.Errorf("persona '%s' already has %s@%s", persona.Name, plat.Account, plat.Type)
+		}
+	}
+
+	persona.Platforms = append(persona.Platforms, *plat)
+	if err := mgr.Save(cfg); err != nil {
+		return fmt.Errorf("failed to save configuration: %w", err)
+	}
+
+	recordPlatformHistory("platform-add", fmt.Sprintf("Added %s@%s to persona '%s'", plat.Account, plat.Type, persona.Name), persona, plat)
+
+	fmt.Printf("✓ Added %s@%s to persona '%s'\n", plat.Account, plat.Type, persona.Name)
+	if !persona.Archived {
+		fmt.Printf("\nRun 'git-keys apply %s/%s' to generate its key and configure SSH and git.\n", persona.Name, plat.Type)
+	}
+	return nil
+}
+
+func runPlatformRemove(cmd *cobra.Command, args []string) (err error) {
+	mgr, cfg, configPath, err := loadPersonaConfig()
+	if err != nil {
+		return err
+	}
+
+	persona, plat, err := resolvePlatformTarget(cfg, args[0])
+	if err != nil {
+		return err
+	}
+	if len(persona.Platforms) == 1 {
+		return fmt.Errorf("%s@%s is the only platform of persona '%s'; use 'git-keys persona remove %s'", plat.Account, plat.Type, persona.Name, persona.Name)
+	}
+	for _, dk := range cfg.DeployKeys {
+		if dkPersona, dkPlat, err := cfg.DeployKeyPlatform(&dk); err == nil && dkPersona == persona && dkPlat == plat {
+			return fmt.Errorf("deploy key of %s uses %s@%s; remove it first with 'git-keys deploy-key remove %s'", dk.Repo, plat.Account, plat.Type, dk.Repo)
+		}
+	}
+
+	label := fmt.Sprintf("%s/%s@%s", persona.Name, plat.Type, plat.Account)
+	printHeader("\n🗑️  Remove Platform")
+	fmt.Printf("\n  Platform: %s\n\n", label)
+
+	target := &platformTarget{Persona: persona.Name, Platform: plat.Type, Account: plat.Account}
+	revocations := platformRevocations(persona, target)
+	if !confirmPlatformRemoval(revocations, platformRemoveYes, fmt.Sprintf("Remove %s", label)) {
+		fmt.Println("Removal cancelled.")
+		return nil
+	}
+
+	j, err := journal.Begin("", "platform-remove")
+	if err != nil {
+		return fmt.Errorf("failed to start journal: %w", err)
+	}
+	defer func() {
+		finishJournal(j, err)
+	}()
+
+	sshMgr := sshConfigManager(cfg)
+	if err := journalApplyFiles(j, cfg, configPath, sshMgr, target); err != nil {
+		return fmt.Errorf("failed to back up files: %w", err)
+	}
+
+	removed := *plat
+	if err := removePlatformState(context.Background(), cfg, persona, []config.Platform{removed}, revocations, j); err != nil {
+		return err
+	}
+
+	for i := range persona.Platforms {
+		if &persona.Platforms[i] == plat {
+			persona.Platforms = append(persona.Platforms[:i], persona.Platforms[i+1:]...)
+			break
+		}
+	}
+	if err := removePersonaGitConfig(cfg, &config.Persona{Name: persona.Name, Platforms: []config.Platform{removed}}); err != nil {
+		logger.Warn("Failed to update git config: %v", err)
+		fmt.Println("⚠️  Could not update git config. Run 'git-keys setup-git' to fix it.")
+	} else {
+		fmt.Println("✓ Removed git identity configuration")
+	}
+
+	if err := mgr.Save(cfg); err != nil {
+		return fmt.Errorf("failed to save configuration: %w", err)
+	}
+
+	recordPlatformHistory("platform-remove", fmt.Sprintf("Removed %s", label), persona, &removed)
+
+	fmt.Printf("\n✅ Removed %s.\n", label)
+	return nil
+}
+
+func recordPlatformHistory(action, summary string, persona *config.Persona, plat *config.Platform) {
+	histMgr := history.NewManager("")
+	if err := histMgr.Record(history.Entry{
+		Action:  action,
+		Summary: summary,
+		Details: map[string]string{
+			"persona":  persona.Name,
+			"platform": string(plat.Type),
+			"account":  plat.Account,
+		},
+	}); err != nil {
+		logger.Warn("Failed to record history: %v", err)
+	}
+}
@@ -0,0 +1,44 @@
+package commands
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/kunlu/git-keys/internal/config"
+	"github.com/kunlu/git-keys/internal/policy"
+)
+
+// policyViolations returns the violations of the organization policy of cfg,
+// if there is one. A policy that does not load is reported as a violation,
+// so it cannot be bypassed by breaking the file.
+func policyViolations(cfg *config.Config) []string {
+	p, err := policy.Load(cfg)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	if p == nil {
+		return nil
+	}
+
+	var violations []string
+	for _, v := range p.Check(cfg, time.Now()) {
+		violations = append(violations, "Policy: "+v.String())
+	}
+	return violations
+}
+
+// enforcePolicy prints the violations of the organization policy and returns
+// an error when there are any
+func enforcePolicy(cfg *config.Config) error {
+	violations := policyViolations(cfg)
+	if len(violations) == 0 {
+		return nil
+	}
+
+	fmt.Printf("❌ The configuration violates the policy in %s:\n", policy.Path(cfg))
+	for _, v := range violations {
+		printWrapped("   • ", v)
+	}
+	fmt.Println()
+	return fmt.Errorf("policy violated: %d problem(s)", len(violations))
+}
@@ -0,0 +1,245 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/kunlu/git-keys/internal/logger"
+)
+
+const (
+	// progressMinTasks is the number of platform tasks from which rotate and
+	// apply show live progress instead of the steps of each task
+	progressMinTasks = 2
+
+	// progressInterval is how often the spinners and timers are redrawn
+	progressInterval = 100 * time.Millisecond
+
+	// progressBarWidth is the number of cells in the progress bar
+	progressBarWidth = 20
+)
+
+var progressSpinner = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// taskProgress shows platform tasks on a terminal: a progress bar, and a
+// spinner with the current step and elapsed time for each running task.
+// Finished tasks are printed above it, and a summary table follows at the
+// end. The steps the tasks print ("→ Uploading...") become the step shown;
+// warnings become notes in the summary.
+type taskProgress struct {
+	mu      sync.Mutex
+	title   string
+	ops     []*progressOp
+	start   time.Time
+	done    int
+	failed  int
+	frame   int
+	width   int          // Terminal width, to keep each line on one row
+	drawn   int          // Lines of the live area on screen
+	logs    bytes.Buffer // Log lines held back until the display ends
+	stopCh  chan struct{}
+	stopped chan struct{}
+}
+
+// progressOp is one task on the display
+type progressOp struct {
+	label   string
+	step    string
+	start   time.Time
+	elapsed time.Duration
+	running bool
+	err     error
+	notes   []string
+	pending string // Output after the last newline
+}
+
+// startProgress wraps tasks so they report to a live progress display, and
+// starts it. Returns tasks unchanged and a nil display when stdout is not a
+// terminal, for structured or quiet output, or with too few tasks.
+func startProgress(title string, tasks []platformTask) ([]platformTask, *taskProgress) {
+	if len(tasks) < progressMinTasks || structuredOutput() || quiet || !stdoutIsTerminal() || os.Getenv("TERM") == "dumb" {
+		return tasks, nil
+	}
+
+	p := &taskProgress{
+		title:   title,
+		start:   time.Now(),
+		width:   terminalWidth(),
+		stopCh:  make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	wrapped := make([]platformTask, len(tasks))
+	for i, task := range tasks {
+		op := &progressOp{label: task.Label}
+		p.ops = append(p.ops, op)
+		run := task.Run
+		wrapped[i] = platformTask{
+			Label: task.Label,
+			Lane:  task.Lane,
+			Run: func(ctx context.Context, _ io.Writer) error {
+				p.mu.Lock()
+				op.running = true
+				op.start = time.Now()
+				p.mu.Unlock()
+				return run(ctx, &progressWriter{p: p, op: op})
+			},
+		}
+	}
+
+	// Log lines would tear the live area apart
+	logger.SetOutput(&p.logs)
+
+	go func() {
+		defer close(p.stopped)
+		ticker := time.NewTicker(progressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-p.stopCh:
+				return
+			case <-ticker.C:
+				p.mu.Lock()
+				p.frame++
+				p.redraw()
+				p.mu.Unlock()
+			}
+		}
+	}()
+
+	return wrapped, p
+}
+
+// finish records the result of a task and prints it above the live area
+func (p *taskProgress) finish(result platformTaskResult) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	op := p.ops[result.Index]
+	op.running = false
+	op.elapsed = result.Elapsed
+	op.err = result.Err
+	p.done++
+
+	p.clear()
+	elapsed := result.Elapsed.Round(100 * time.Millisecond)
+	if result.Err != nil {
+		p.failed++
+		fmt.Printf("  %s (%s): %v\n", checkText(checkFail, op.label), elapsed, result.Err)
+	} else if len(op.notes) > 0 {
+		fmt.Printf("  %s (%s)\n", checkText(checkWarn, op.label), elapsed)
+	} else {
+		fmt.Printf("  %s (%s)\n", checkText(checkOK, op.label), elapsed)
+	}
+	p.redraw()
+}
+
+// stop removes the live area and prints the log lines held back meanwhile
+func (p *taskProgress) stop() {
+	close(p.stopCh)
+	<-p.stopped
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.clear()
+	logger.SetOutput(os.Stderr)
+	os.Stderr.Write(p.logs.Bytes())
+}
+
+// printSummary prints a table of the tasks with their time, result and the
+// warnings they printed
+func (p *taskProgress) printSummary() {
+	fmt.Printf("\n%s: %d done, %d failed in %s\n\n", p.title, p.done-p.failed, p.failed, formatElapsed(time.Since(p.start)))
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  OPERATION\tTIME\tRESULT")
+	for _, op := range p.ops {
+		result := colorize(ansiGreen, "ok")
+		switch {
+		case op.err != nil:
+			result = colorize(ansiRed, "failed: "+op.err.Error())
+		case len(op.notes) > 0:
+			result = colorize(ansiYellow, "ok, "+strings.Join(op.notes, "; "))
+		}
+		fmt.Fprintf(w, "  %s\t%s\t%s\n", op.label, op.elapsed.Round(100*time.Millisecond), result)
+	}
+	w.Flush()
+}
+
+// redraw replaces the live area: the progress bar, then one line for each
+// running task. Called with mu held.
+func (p *taskProgress) redraw() {
+	p.clear()
+
+	filled := progressBarWidth * p.done / len(p.ops)
+	bar := strings.Repeat("█", filled) + strings.Repeat("░", progressBarWidth-filled)
+	status := fmt.Sprintf("%d/%d", p.done, len(p.ops))
+	if p.failed > 0 {
+		status += ", " + colorize(ansiRed, fmt.Sprintf("%d failed", p.failed))
+	}
+	lines := []string{fmt.Sprintf("%s %s [%s] %s  %s", progressSpinner[p.frame%len(progressSpinner)], p.title, bar, status, formatElapsed(time.Since(p.start)))}
+
+	for _, op := range p.ops {
+		if !op.running {
+			continue
+		}
+		step := op.step
+		if step == "" {
+			step = "Working"
+		}
+		line := fmt.Sprintf("    %s %s: %s… %s", progressSpinner[p.frame%len(progressSpinner)], op.label, step, time.Since(op.start).Round(100*time.Millisecond))
+		lines = append(lines, uiTruncate(line, p.width-1))
+	}
+
+	for _, line := range lines {
+		fmt.Println(line)
+	}
+	p.drawn = len(lines)
+}
+
+// clear erases the live area. Called with mu held.
+func (p *taskProgress) clear() {
+	if p.drawn > 0 {
+		fmt.Printf("\033[%dF\033[J", p.drawn)
+		p.drawn = 0
+	}
+}
+
+// progressWriter takes the output of a task: "→ Step..." lines set the
+// step on the display, and warnings are kept as notes
+type progressWriter struct {
+	p  *taskProgress
+	op *progressOp
+}
+
+func (w *progressWriter) Write(b []byte) (int, error) {
+	w.p.mu.Lock()
+	defer w.p.mu.Unlock()
+
+	text := w.op.pending + string(b)
+	lines := strings.Split(text, "\n")
+	w.op.pending = lines[len(lines)-1]
+	for _, line := range lines[:len(lines)-1] {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "→ "):
+			w.op.step = strings.TrimSuffix(strings.TrimPrefix(line, "→ "), "...")
+		case strings.HasPrefix(line, "⚠️"):
+			note := strings.TrimSpace(strings.TrimPrefix(line, "⚠️"))
+			w.op.notes = append(w.op.notes, strings.TrimPrefix(note, "Warning: "))
+		}
+	}
+	return len(b), nil
+}
+
+// formatElapsed prints a duration as m:ss
+func formatElapsed(d time.Duration) string {
+	d = d.Round(time.Second)
+	return fmt.Sprintf("%d:%02d", int(d.Minutes()), int(d.Seconds())%60)
+}
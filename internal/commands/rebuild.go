@@ -1,7 +1,6 @@
 package commands
 
 import (
-	"bufio"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -12,8 +11,11 @@ import (
 
 	"github.com/kunlu/git-keys/internal/api"
 	"github.com/kunlu/git-keys/internal/config"
+	"github.com/kunlu/git-keys/internal/journal"
 	"github.com/kunlu/git-keys/internal/logger"
 	"github.com/kunlu/git-keys/internal/platform"
+	"github.com/kunlu/git-keys/internal/profile"
+	"github.com/kunlu/git-keys/internal/prompt"
 	"github.com/kunlu/git-keys/internal/sshconfig"
 	"github.com/kunlu/git-keys/internal/sshkey"
 	"github.com/spf13/cobra"
@@ -103,9 +105,11 @@ func init() {
 
 func runRebuild(cmd *cobra.Command, args []string) error {
 	ctx := context.Background()
+	if rebuildInteractive && nonInteractive {
+		return errNonInteractive("'rebuild --interactive'", "rebuild without it, then add personas with 'git-keys persona add'")
+	}
 
-	fmt.Println("\n🔄 Git-Keys Rebuild")
-	fmt.Println("==================")
+	printHeader("\n🔄 Git-Keys Rebuild")
 	fmt.Println()
 
 	// Step 1: Scan current setup
@@ -148,8 +152,7 @@ func runRebuild(cmd *cobra.Command, args []string) error {
 	}
 
 	// Step 4: Show summary
-	fmt.Println("📋 Step 3: Summary of Current Setup")
-	fmt.Println("===================================")
+	printHeader("📋 Step 3: Summary of Current Setup")
 	if existingConfig != nil {
 		fmt.Printf("\nCurrent Config:\n")
 		fmt.Printf("  • Personas: %d\n", len(existingConfig.Personas))
@@ -178,8 +181,7 @@ func runRebuild(cmd *cobra.Command, args []string) error {
 	}
 
 	// Step 5: Confirm cleanup
-	fmt.Println("\n⚠️  Step 4: Confirm Cleanup")
-	fmt.Println("=========================")
+	printHeader("\n⚠️  Step 4: Confirm Cleanup")
 	if rebuildDryRun {
 		fmt.Println("\n🔍 DRY RUN MODE - No changes will be made")
 	}
@@ -196,6 +198,21 @@ func runRebuild(cmd *cobra.Command, args []string) error {
 	fmt.Println("  ✗ Delete non-git-keys SSH keys")
 	fmt.Println("  ✗ Delete entire SSH config (only managed blocks)")
 
+	// Show the remote keys that would be deleted, as the platforms report them
+	var revocations []keyRevocation
+	if !rebuildKeepRemote && existingConfig != nil {
+		revocations = collectRebuildRevocations(ctx, existingConfig)
+		if len(revocations) > 0 {
+			fmt.Println("\nRemote keys to delete:")
+			for i := range revocations {
+				kr := &revocations[i]
+				fmt.Printf("\n  %s/%s (%s)\n", kr.Persona, kr.Platform, kr.Account)
+				fmt.Printf("  Local Fingerprint: %s\n", kr.Key.Fingerprint)
+				kr.RemoteCheck.Print(os.Stdout, "  ")
+			}
+		}
+	}
+
 	if !rebuildSkipBackup {
 		fmt.Printf("\n💾 Your backup is safe at:\n   %s\n", backupPath)
 	}
@@ -205,17 +222,22 @@ func runRebuild(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
-	fmt.Print("\nType 'yes' to continue: ")
-	var response string
-	fmt.Scanln(&response)
-	if strings.ToLower(response) != "yes" {
-		fmt.Println("\n❌ Rebuild cancelled. No changes made.")
-		return nil
+	if !nonInteractive {
+		if !prompt.ConfirmWord("\nContinue?", "yes") {
+			fmt.Println("\n❌ Rebuild cancelled. No changes made.")
+			return nil
+		}
 	}
 
 	// Step 6: Clean everything
 	fmt.Println("\n🧹 Step 5: Cleaning up...")
-	if err := performCleanup(ctx, existingConfig, !rebuildKeepRemote); err != nil {
+	j, err := journal.Begin("", "rebuild")
+	if err != nil {
+		return fmt.Errorf("failed to start journal: %w", err)
+	}
+	err = performCleanup(ctx, existingConfig, revocations, j)
+	finishJournal(j, err)
+	if err != nil {
 		return fmt.Errorf("cleanup failed: %w", err)
 	}
 	fmt.Println("✓ Cleanup complete")
@@ -223,8 +245,7 @@ func runRebuild(cmd *cobra.Command, args []string) error {
 
 	// Step 7: Interactive re-setup
 	if rebuildInteractive {
-		fmt.Println("🎯 Step 6: Interactive Re-setup")
-		fmt.Println("===============================")
+		printHeader("🎯 Step 6: Interactive Re-setup")
 		fmt.Println()
 
 		recommended := analyzeAndRecommend(scanResult, existingConfig)
@@ -290,8 +311,7 @@ func createBackup(scanResult *ScanResult, existingConfig *config.Config) (string
 	}
 
 	// Create backup directory
-	homeDir, _ := os.UserHomeDir()
-	backupDir := filepath.Join(homeDir, ".git-keys", "backups")
+	backupDir := filepath.Join(profile.Dir(), "backups")
 	if err := os.MkdirAll(backupDir, 0755); err != nil {
 		return "", fmt.Errorf("failed to create backup directory: %w", err)
 	}
@@ -321,7 +341,8 @@ func createBackup(scanResult *ScanResult, existingConfig *config.Config) (string
 	}
 
 	// Backup current config file if exists
-	configPath := config.GetDefaultConfigPath()
+	// An encrypted config is backed up as it is stored
+	configPath := config.StoragePath(config.GetDefaultConfigPath())
 	if _, err := os.Stat(configPath); err == nil {
 		backupConfigPath := configPath + fmt.Sprintf(".pre-rebuild-%s", timestamp.Format(backupTimestampFormat))
 		content, err := os.ReadFile(configPath)
@@ -473,31 +494,50 @@ func analyzeAndRecommend(scanResult *ScanResult, existingConfig *config.Config)
 	return recommended
 }
 
-func performCleanup(ctx context.Context, existingConfig *config.Config, revokeRemote bool) error {
-	// 1. Revoke remote keys if requested
-	if revokeRemote && existingConfig != nil {
-		fmt.Println("  → Revoking keys from remote platforms...")
-		for _, persona := range existingConfig.Personas {
-			for _, platform := range persona.Platforms {
-				for _, key := range platform.Keys {
-					if key.Status != config.KeyStatusActive || key.RemoteID == "" {
-						continue
-					}
-
-					kr := &keyRevocation{
-						Persona:  persona.Name,
-						Platform: platform.Type,
-						Account:  platform.Account,
-						BaseURL:  platform.BaseURL,
-						Key:      key,
-					}
+// collectRebuildRevocations returns the active keys with a remote ID, each
+// with the remote key it would delete
+func collectRebuildRevocations(ctx context.Context, existingConfig *config.Config) []keyRevocation {
+	var revocations []keyRevocation
+	for personaIdx := range existingConfig.Personas {
+		persona := &existingConfig.Personas[personaIdx]
+		for platformIdx := range persona.Platforms {
+			platform := &persona.Platforms[platformIdx]
+			for _, key := range platform.Keys {
+				if !key.InUse() || key.RemoteID == "" {
+					continue
+				}
 
-					if err := revokeKey(ctx, kr); err != nil {
-						logger.Warn("Failed to revoke key %s: %v", key.Fingerprint, err)
-					} else {
-						fmt.Printf("    ✓ Revoked %s/%s\n", persona.Name, platform.Type)
-					}
+				kr := keyRevocation{
+					Persona:     persona.Name,
+					Platform:    platform.Type,
+					Account:     platform.Account,
+					BaseURL:     platform.BaseURL,
+					Key:         key,
+					PersonaRef:  persona,
+					PlatformRef: platform,
 				}
+				kr.checkRemote(ctx)
+				revocations = append(revocations, kr)
+			}
+		}
+	}
+	return revocations
+}
+
+// performCleanup revokes, removes and trashes what git-keys set up, recording
+// each change in j so 'git-keys undo' can restore it. Cleared API tokens are
+// not recorded.
+func performCleanup(ctx context.Context, existingConfig *config.Config, revocations []keyRevocation, j *journal.Journal) error {
+	// 1. Revoke remote keys that were confirmed
+	if len(revocations) > 0 {
+		fmt.Println("  → Revoking keys from remote platforms...")
+		for i := range revocations {
+			kr := &revocations[i]
+			if err := revokeKey(ctx, kr, j); err != nil {
+				logger.Warn("Failed to revoke key %s: %v", kr.Key.Fingerprint, err)
+				fmt.Printf("    ⚠️  %s/%s: %v\n", kr.Persona, kr.Platform, err)
+			} else {
+				fmt.Printf("    ✓ Revoked %s/%s\n", kr.Persona, kr.Platform)
 			}
 		}
 	}
@@ -506,6 +546,10 @@ func performCleanup(ctx context.Context, existingConfig *config.Config, revokeRe
 	fmt.Println("  → Removing managed SSH config blocks...")
 	sshConfigPath := filepath.Join(os.Getenv("HOME"), ".ssh", "config")
 	sshMgr := sshconfig.NewManager(sshConfigPath)
+	if existingConfig != nil {
+		sshMgr.UseIncludeFile(existingConfig.Defaults.SSHInclude)
+	}
+	journalSSHConfig(j, sshMgr)
 	if err := sshMgr.RemoveAllManagedBlocks(); err != nil {
 		logger.Warn("Failed to clean SSH config: %v", err)
 	} else {
@@ -515,8 +559,7 @@ func performCleanup(ctx context.Context, existingConfig *config.Config, revokeRe
 	// 3. Delete git-keys managed key files (if tracked in config)
 	if existingConfig != nil {
 		fmt.Println("  → Deleting git-keys managed key files...")
-		sshDir := filepath.Join(os.Getenv("HOME"), ".ssh")
-		keyMgr := sshkey.NewManager(sshDir)
+		keyMgr := sshkey.NewManager(existingConfig.Defaults.GetKeysDir())
 
 		deletedCount := 0
 		for _, persona := range existingConfig.Personas {
@@ -526,20 +569,25 @@ func performCleanup(ctx context.Context, existingConfig *config.Config, revokeRe
 						continue
 					}
 
-					if err := keyMgr.DeleteKey(key.LocalPath); err != nil {
+					item, err := keyMgr.TrashKey(key.LocalPath)
+					if err != nil {
 						logger.Warn("Failed to delete key %s: %v", key.LocalPath, err)
-					} else {
-						deletedCount++
+						continue
+					}
+					if item != nil {
+						journalWarn(j.KeyTrashed(filepath.Join(keyMgr.KeysDir(), key.LocalPath), item.ID))
 					}
+					deletedCount++
 				}
 			}
 		}
-		fmt.Printf("    ✓ Deleted %d key files\n", deletedCount)
+		fmt.Printf("    ✓ Deleted %d key files (moved to trash)\n", deletedCount)
 	}
 
 	// 4. Delete config file
 	fmt.Println("  → Removing configuration file...")
 	configPath := config.GetDefaultConfigPath()
+	journalWarn(j.BackupFile(config.StoragePath(configPath)))
 	if err := os.Remove(configPath); err != nil && !os.IsNotExist(err) {
 		logger.Warn("Failed to delete config file: %v", err)
 	} else {
@@ -596,12 +644,11 @@ func interactiveRebuild(recommended RecommendedMap, scanResult *ScanResult) erro
 	fmt.Println("Based on your backup, I found these identities:")
 	fmt.Println()
 
-	reader := bufio.NewReader(os.Stdin)
-
 	for i, recPersona := range recommended.Personas {
-		fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+		printRule(44)
 		fmt.Printf("Identity %d: %s <%s>\n", i+1, recPersona.Name, recPersona.Email)
-		fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n\n")
+		printRule(44)
+		fmt.Println()
 
 		// Show discovered platforms from git repos
 		if len(recPersona.Platforms) > 0 {
@@ -621,22 +668,13 @@ func interactiveRebuild(recommended RecommendedMap, scanResult *ScanResult) erro
 		}
 
 		// Ask if user wants to create a persona for this identity
-		fmt.Print("Create a persona for this identity? (y/n): ")
-		response, _ := reader.ReadString('\n')
-		response = strings.TrimSpace(strings.ToLower(response))
-
-		if response != "y" && response != "yes" {
+		if !prompt.Confirm("Create a persona for this identity?") {
 			fmt.Println()
 			continue
 		}
 
 		// Allow customizing persona name
-		fmt.Printf("Persona name [%s]: ", recPersona.Name)
-		personaName, _ := reader.ReadString('\n')
-		personaName = strings.TrimSpace(personaName)
-		if personaName == "" {
-			personaName = recPersona.Name
-		}
+		personaName := prompt.Input("Persona name", recPersona.Name)
 
 		persona := config.Persona{
 			Name:      personaName,
@@ -644,115 +682,17 @@ func interactiveRebuild(recommended RecommendedMap, scanResult *ScanResult) erro
 			Platforms: []config.Platform{},
 		}
 
-		// Group discovered platforms by type for prompting
-		hasGitHub := false
-		hasGitLabPublic := false
-		gitlabPrivateBaseURLs := []string{}
-
-		for _, p := range recPersona.Platforms {
-			if p.Type == "github" && !hasGitHub {
-				hasGitHub = true
-			} else if p.Type == "gitlab" {
-				if p.BaseURL != "" {
-					// Check if we already have this baseURL
-					found := false
-					for _, url := range gitlabPrivateBaseURLs {
-						if url == p.BaseURL {
-							found = true
-							break
-						}
-					}
-					if !found {
-						gitlabPrivateBaseURLs = append(gitlabPrivateBaseURLs, p.BaseURL)
-					}
-				} else if !hasGitLabPublic {
-					hasGitLabPublic = true
-				}
-			}
-		}
-
-		// Prompt for GitHub account (if discovered from repos)
-		if hasGitHub {
-			fmt.Print("\n  Add GitHub account for this persona? (y/n): ")
-			response, _ := reader.ReadString('\n')
-			response = strings.TrimSpace(strings.ToLower(response))
-
-			if response == "y" || response == "yes" {
-				fmt.Print("  Enter your GitHub username: ")
-				account, _ := reader.ReadString('\n')
-				account = strings.TrimSpace(account)
-
-				if account != "" {
-					platform := config.Platform{
-						Type:    config.PlatformGitHub,
-						Account: account,
-						Keys:    []config.KeyConfig{},
-					}
-					persona.Platforms = append(persona.Platforms, platform)
-					fmt.Printf("    ✓ Added GitHub account: %s\n", account)
-				}
-			}
-		}
-
-		// Prompt for GitLab.com account (if discovered from repos)
-		if hasGitLabPublic {
-			fmt.Print("\n  Add GitLab.com account for this persona? (y/n): ")
-			response, _ := reader.ReadString('\n')
-			response = strings.TrimSpace(strings.ToLower(response))
-
-			if response == "y" || response == "yes" {
-				fmt.Print("  Enter your GitLab.com username: ")
-				account, _ := reader.ReadString('\n')
-				account = strings.TrimSpace(account)
-
-				if account != "" {
-					platform := config.Platform{
-						Type:    config.PlatformGitLab,
-						Account: account,
-						Keys:    []config.KeyConfig{},
-					}
-					persona.Platforms = append(persona.Platforms, platform)
-					fmt.Printf("    ✓ Added GitLab.com account: %s\n", account)
-				}
-			}
-		}
-
-		// Prompt for self-hosted GitLab accounts (if any discovered from repos)
-		for _, baseURL := range gitlabPrivateBaseURLs {
-			fmt.Printf("\n  Add GitLab account for %s? (y/n): ", baseURL)
-			response, _ := reader.ReadString('\n')
-			response = strings.TrimSpace(strings.ToLower(response))
-
-			if response == "y" || response == "yes" {
-				fmt.Printf("  Enter your username for %s: ", baseURL)
-				account, _ := reader.ReadString('\n')
-				account = strings.TrimSpace(account)
-
-				if account != "" {
-					platform := config.Platform{
-						Type:    config.PlatformGitLab,
-						Account: account,
-						BaseURL: baseURL,
-						Keys:    []config.KeyConfig{},
-					}
-					persona.Platforms = append(persona.Platforms, platform)
-					fmt.Printf("    ✓ Added GitLab account: %s (%s)\n", account, baseURL)
-				}
-			}
-		}
+		// Pick platforms and enter the username once for all of them
+		persona.Platforms = append(persona.Platforms, promptForPlatforms(platformChoices(recPersona.Platforms))...)
 
 		// Option to manually add platform if none discovered or user wants to add more
 		for {
-			fmt.Print("\nAdd another platform manually? (y/n): ")
-			response, _ = reader.ReadString('\n')
-			response = strings.TrimSpace(strings.ToLower(response))
-
-			if response != "y" && response != "yes" {
+			if !prompt.Confirm("\nAdd another platform manually?") {
 				break
 			}
 
 			// Manual platform addition
-			manualPlatform, err := promptForPlatform(reader)
+			manualPlatform, err := promptForPlatform()
 			if err != nil {
 				fmt.Printf("  Error: %v\n", err)
 				continue
@@ -761,6 +701,11 @@ func interactiveRebuild(recommended RecommendedMap, scanResult *ScanResult) erro
 			fmt.Printf("    ✓ Added %s/%s\n", manualPlatform.Type, manualPlatform.Account)
 		}
 
+		// Offer to reuse keys found during the scan instead of generating new ones
+		for idx := range persona.Platforms {
+			offerKeyReuse(cfg, &persona.Platforms[idx], recPersona.Platforms)
+		}
+
 		if len(persona.Platforms) > 0 {
 			cfg.Personas = append(cfg.Personas, persona)
 			fmt.Printf("\n  ✅ Created persona '%s' with %d platform(s)\n\n", persona.Name, len(persona.Platforms))
@@ -787,3 +732,102 @@ func interactiveRebuild(recommended RecommendedMap, scanResult *ScanResult) erro
 
 	return nil
 }
+
+// offerKeyReuse lets the user adopt a key discovered for a platform (from the
+// scanned SSH config or the previous configuration) so apply does not
+// generate a new one. Keys deleted during cleanup are not offered.
+func offerKeyReuse(cfg *config.Config, plat *config.Platform, recommended []RecommendedPlatform) {
+	keysDir := cfg.Defaults.GetKeysDir()
+
+	for _, rec := range recommended {
+		if rec.KeyPath == "" || rec.Type != plat.Type || rec.BaseURL != plat.BaseURL {
+			continue
+		}
+
+		keyPath := sshkey.ExpandHome(rec.KeyPath)
+		if !filepath.IsAbs(keyPath) {
+			keyPath = filepath.Join(keysDir, keyPath)
+		}
+		if !fileExists(keyPath) || !fileExists(keyPath+".pub") {
+			continue
+		}
+
+		if !prompt.Confirm(fmt.Sprintf("\n  Reuse existing key %s for %s/%s?", keyPath, plat.Type, plat.Account)) {
+			continue
+		}
+
+		key, err := adoptExistingKey(keysDir, keyPath, cfg.ResolveKeySettings(nil, plat))
+		if err != nil {
+			fmt.Printf("    ⚠️  Cannot reuse %s: %v\n", keyPath, err)
+			continue
+		}
+
+		if remoteID, err := findRemoteKeyID(plat, key.Fingerprint); err != nil {
+			logger.Debug("Could not check remote registration for %s: %v", keyPath, err)
+			fmt.Println("    ○ Remote registration not checked; 'git-keys apply' will upload it if needed")
+		} else if remoteID != "" {
+			key.RemoteID = remoteID
+			fmt.Printf("    ✓ Already registered on %s (ID: %s)\n", plat.Type, remoteID)
+		}
+
+		plat.Keys = append(plat.Keys, *key)
+		fmt.Printf("    ✓ Reusing %s\n", keyPath)
+		return
+	}
+}
+
+// adoptExistingKey builds an active key entry for an existing key pair.
+// LocalPath is stored relative to the keys directory.
+func adoptExistingKey(keysDir, keyPath string, settings config.KeySettings) (*config.KeyConfig, error) {
+	localPath, err := filepath.Rel(keysDir, keyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	keyMgr := sshkey.NewManager(keysDir)
+	publicKey, err := keyMgr.GetPublicKey(localPath)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := sshkey.ParsePublicKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	createdAt, ok := sshkey.ParseCreationHint(info.Comment)
+	if !ok {
+		createdAt = fileModTime(keyPath)
+	}
+
+	return &config.KeyConfig{
+		Type:        sshkey.KeyTypeFromPublicKey(info.Type),
+		CreatedAt:   createdAt,
+		ExpiresAt:   settings.ExpiresAt(createdAt),
+		Fingerprint: info.Fingerprint,
+		LocalPath:   localPath,
+		Status:      config.KeyStatusActive,
+	}, nil
+}
+
+// findRemoteKeyID returns the ID of the platform key with the given
+// fingerprint, or "" if it is not registered
+func findRemoteKeyID(plat *config.Platform, fingerprint string) (string, error) {
+	client, err := newPlatformClient(plat)
+	if err != nil {
+		return "", err
+	}
+
+	remoteKeys, err := client.ListKeys(context.Background())
+	if err != nil {
+		return "", err
+	}
+
+	for _, remote := range remoteKeys {
+		if info, err := sshkey.ParsePublicKey(remote.Key); err == nil && info.Fingerprint == fingerprint {
+			return remote.ID, nil
+		}
+	}
+
+	return "", nil
+}
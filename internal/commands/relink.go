@@ -0,0 +1,240 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/kunlu/git-keys/internal/api"
+	"github.com/kunlu/git-keys/internal/config"
+	"github.com/kunlu/git-keys/internal/history"
+	"github.com/kunlu/git-keys/internal/logger"
+	"github.com/kunlu/git-keys/internal/prompt"
+	"github.com/kunlu/git-keys/internal/sshkey"
+	"github.com/spf13/cobra"
+)
+
+var (
+	relinkDryRun bool
+	relinkYes    bool
+)
+
+var relinkCmd = &cobra.Command{
+	Use:   "relink",
+	Short: "Re-link keys to their remote entries after remote IDs drift",
+	Long: `Repair the remote IDs recorded for keys in the configuration.
+
+A key that was deleted and uploaded again by hand, or restored from an old
+backup, has a remote_id that points at an entry that no longer exists. This
+command lists the keys registered on each platform, matches them to the
+configured keys by public key fingerprint, and:
+  - updates remote IDs that point at the wrong entry
+  - records the remote ID of keys that were registered by hand
+  - clears remote IDs whose key is no longer registered, so 'git-keys apply'
+    uploads it again
+
+Revoked keys are left alone. Every re-link is recorded in history.
+
+Examples:
+  # Show what would be re-linked
+  git-keys relink --dry-run
+
+  # Re-link without prompting
+  git-keys relink --yes
+`,
+	RunE: runRelink,
+}
+
+func init() {
+	relinkCmd.Flags().BoolVar(&relinkDryRun, "dry-run", false, "Show re-links without saving them")
+	relinkCmd.Flags().BoolVarP(&relinkYes, "yes", "y", false, "Skip confirmation prompt")
+
+	rootCmd.AddCommand(relinkCmd)
+}
+
+// remoteLink is a key whose recorded remote ID differs from the entry that
+// holds its public key on the platform
+type remoteLink struct {
+	Persona  *config.Persona
+	Platform *config.Platform
+	Key      *config.KeyConfig
+	OldID    string // Recorded remote ID; "" if none was recorded
+	NewID    string // ID of the matching remote entry; "" if not registered
+	Title    string // Title of the matching remote entry
+}
+
+func (l remoteLink) label() string {
+	return fmt.Sprintf("%s/%s@%s", l.Persona.Name, l.Platform.Type, l.Platform.Account)
+}
+
+func runRelink(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	// Load configuration
+	configPath := cfgFile
+	if configPath == "" {
+		configPath = config.GetDefaultConfigPath()
+	}
+
+	mgr := config.NewManager(configPath)
+	if !mgr.Exists() {
+		return fmt.Errorf("configuration file not found at %s\nRun 'git-keys init' first", configPath)
+	}
+
+	cfg, err := mgr.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	printHeader("\n🔗 Re-link Remote Keys")
+	fmt.Println()
+
+	links, failures := findRemoteDrift(ctx, cfg)
+
+	if len(links) == 0 {
+		fmt.Println("✓ All remote IDs match the keys registered on the platforms.")
+		printRelinkFailures(failures)
+		return nil
+	}
+
+	for _, link := range links {
+		fmt.Printf("  %s  %s\n", link.label(), link.Key.Fingerprint)
+		switch {
+		case link.NewID == "":
+			fmt.Printf("    remote ID %s: key is no longer registered; apply will upload it again\n", link.OldID)
+		case link.OldID == "":
+			fmt.Printf("    registered as %s (%s)\n", link.NewID, link.Title)
+		default:
+			fmt.Printf("    remote ID %s → %s (%s)\n", link.OldID, link.NewID, link.Title)
+		}
+	}
+	fmt.Println()
+
+	if relinkDryRun {
+		fmt.Printf("🔍 DRY RUN: %d key(s) would be re-linked\n", len(links))
+		printRelinkFailures(failures)
+		return nil
+	}
+
+	if !relinkYes {
+		if !prompt.Confirm("Update these remote IDs?") {
+			fmt.Println("Re-link cancelled.")
+			return nil
+		}
+	}
+
+	details := make(map[string]string)
+	for _, link := range links {
+		link.Key.RemoteID = link.NewID
+		details[link.label()+" "+link.Key.Fingerprint] = fmt.Sprintf("%s -> %s", relinkID(link.OldID), relinkID(link.NewID))
+	}
+
+	if err := mgr.Save(cfg); err != nil {
+		return fmt.Errorf("failed to save configuration: %w", err)
+	}
+
+	histMgr := history.NewManager("")
+	if err := histMgr.Record(history.Entry{
+		Action:  "relink",
+		Summary: fmt.Sprintf("Re-linked %d key(s) to their remote entries", len(links)),
+		Details: details,
+	}); err != nil {
+		logger.Warn("Failed to record history: %v", err)
+	}
+
+	printRelinkFailures(failures)
+	fmt.Printf("\n✅ Re-linked %d key(s).\n", len(links))
+	return nil
+}
+
+// findRemoteDrift lists the keys on each platform and returns the configured
+// keys whose remote ID does not point at the entry with their public key
+func findRemoteDrift(ctx context.Context, cfg *config.Config) ([]remoteLink, []string) {
+	var links []remoteLink
+	var failures []string
+
+	for personaIdx := range cfg.Personas {
+		persona := &cfg.Personas[personaIdx]
+		if persona.Archived {
+			continue
+		}
+		for platformIdx := range persona.Platforms {
+			plat := &persona.Platforms[platformIdx]
+			label := fmt.Sprintf("%s/%s@%s", persona.Name, plat.Type, plat.Account)
+
+			hasKeys := false
+			for _, key := range plat.Keys {
+				if key.Status != config.KeyStatusRevoked && key.Fingerprint != "" {
+					hasKeys = true
+				}
+			}
+			if !hasKeys {
+				continue
+			}
+
+			client, err := newPlatformClient(plat)
+			if err != nil {
+				failures = append(failures, fmt.Sprintf("%s: %v", label, err))
+				continue
+			}
+
+			// Drift is judged against a fresh listing, never the cache
+			remoteKeys, _, err := api.ListKeysCached(ctx, client, true)
+			if err != nil {
+				failures = append(failures, fmt.Sprintf("%s: failed to list remote keys: %v", label, err))
+				continue
+			}
+
+			byFingerprint := make(map[string]api.SSHKey)
+			for _, remote := range remoteKeys {
+				if info, err := sshkey.ParsePublicKey(remote.Key); err == nil {
+					byFingerprint[strings.TrimPrefix(info.Fingerprint, "SHA256:")] = remote
+				}
+			}
+
+			for keyIdx := range plat.Keys {
+				key := &plat.Keys[keyIdx]
+				if key.Status == config.KeyStatusRevoked || key.Fingerprint == "" {
+					continue
+				}
+
+				remote, registered := byFingerprint[strings.TrimPrefix(key.Fingerprint, "SHA256:")]
+				switch {
+				case registered && remote.ID == key.RemoteID:
+					continue
+				case !registered && key.RemoteID == "":
+					continue // Not uploaded yet; apply handles it
+				}
+
+				links = append(links, remoteLink{
+					Persona:  persona,
+					Platform: plat,
+					Key:      key,
+					OldID:    key.RemoteID,
+					NewID:    remote.ID,
+					Title:    remote.Title,
+				})
+			}
+		}
+	}
+
+	return links, failures
+}
+
+// relinkID shows an empty remote ID in history details
+func relinkID(id string) string {
+	if id == "" {
+		return "none"
+	}
+	return id
+}
+
+func printRelinkFailures(failures []string) {
+	if len(failures) == 0 {
+		return
+	}
+	fmt.Printf("\n⚠️  %d platform(s) could not be checked:\n", len(failures))
+	for _, f := range failures {
+		printWrapped("   • ", f)
+	}
+}
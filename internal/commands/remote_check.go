@@ -0,0 +1,94 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/kunlu/git-keys/internal/api"
+	"github.com/kunlu/git-keys/internal/sshkey"
+)
+
+// remoteKeyCheck is the remote key a delete would remove, fetched with GetKey
+// before anything is deleted. Remote IDs in the config can drift (a key
+// removed and re-added in the web UI, a restored backup), so the remote key
+// is shown to the user and must match the local fingerprint to be deleted.
+type remoteKeyCheck struct {
+	RemoteID          string
+	Fingerprint       string // Fingerprint recorded in the config
+	Remote            *api.SSHKey
+	RemoteFingerprint string
+	Err               error
+}
+
+// checkRemoteKey fetches the remote key with the given ID
+func checkRemoteKey(ctx context.Context, client api.PlatformClient, remoteID, fingerprint string) *remoteKeyCheck {
+	check := &remoteKeyCheck{RemoteID: remoteID, Fingerprint: fingerprint}
+
+	remote, err := client.GetKey(ctx, remoteID)
+	if err != nil {
+		check.Err = err
+		return check
+	}
+	check.Remote = remote
+
+	if info, err := sshkey.ParsePublicKey(remote.Key); err == nil {
+		check.RemoteFingerprint = info.Fingerprint
+	}
+	return check
+}
+
+// remoteFingerprint returns the fingerprint of a remote key without the
+// SHA256: prefix, computed from its public key when the platform does not
+// report one
+func remoteFingerprint(remote api.SSHKey) string {
+	if remote.Fingerprint == "" {
+		if info, err := sshkey.ParsePublicKey(remote.Key); err == nil {
+			return strings.TrimPrefix(info.Fingerprint, "SHA256:")
+		}
+	}
+	return strings.TrimPrefix(remote.Fingerprint, "SHA256:")
+}
+
+// Problem explains why the remote key must not be deleted, or returns ""
+// when it is the key recorded locally
+func (c *remoteKeyCheck) Problem() string {
+	switch {
+	case c == nil:
+		return "remote key was not checked"
+	case c.Err != nil:
+		return fmt.Sprintf("could not fetch remote key %s: %v", c.RemoteID, c.Err)
+	case c.RemoteFingerprint == "":
+		return fmt.Sprintf("remote key %s has no readable public key", c.RemoteID)
+	case c.Fingerprint == "":
+		return "no local fingerprint to compare the remote key with"
+	case strings.TrimPrefix(c.RemoteFingerprint, "SHA256:") != strings.TrimPrefix(c.Fingerprint, "SHA256:"):
+		return fmt.Sprintf("remote key %s is %s, not the local key %s; the remote ID has drifted",
+			c.RemoteID, c.RemoteFingerprint, c.Fingerprint)
+	}
+	return ""
+}
+
+// Print writes the remote key's title, fingerprint and creation date, and
+// why it will not be deleted if it does not match
+func (c *remoteKeyCheck) Print(out io.Writer, indent string) {
+	if c.Remote != nil {
+		fmt.Fprintf(out, "%sRemote Key: %s (ID %s)\n", indent, c.Remote.Title, c.RemoteID)
+		fmt.Fprintf(out, "%sRemote Fingerprint: %s\n", indent, c.RemoteFingerprint)
+		if c.Remote.CreatedAt != "" {
+			fmt.Fprintf(out, "%sRemote Created: %s\n", indent, c.Remote.CreatedAt)
+		}
+	}
+	if problem := c.Problem(); problem != "" {
+		fmt.Fprintf(out, "%s⚠️  Will not be deleted: %s\n", indent, problem)
+	}
+}
+
+// deleteCheckedKey deletes a remote key only if its check matched
+func deleteCheckedKey(ctx context.Context, client api.PlatformClient, check *remoteKeyCheck) error {
+	if problem := check.Problem(); problem != "" {
+		return fmt.Errorf("refusing to delete remote key: %s", problem)
+	}
+	return client.DeleteKey(ctx, check.RemoteID)
+}
@@ -0,0 +1,82 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/kunlu/git-keys/internal/config"
+)
+
+// ANSI color codes for colorize
+const (
+	ansiRed     = "31"
+	ansiGreen   = "32"
+	ansiYellow  = "33"
+	ansiMagenta = "35"
+)
+
+// noColor is the value of --no-color
+var noColor bool
+
+// colorEnabled reports whether output to stdout is colored: it must be a
+// terminal, and --no-color, plain output, NO_COLOR (https://no-color.org) and
+// TERM=dumb turn colors off
+func colorEnabled() bool {
+	if noColor || plainOutput || os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	return stdoutIsTerminal()
+}
+
+// stdoutIsTerminal reports whether stdout is a terminal
+func stdoutIsTerminal() bool {
+	info, err := os.Stdout.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// colorize wraps text in an ANSI color when colors are enabled
+func colorize(color, text string) string {
+	if color == "" || !colorEnabled() {
+		return text
+	}
+	return "\033[" + color + "m" + text + "\033[0m"
+}
+
+// checkLevel is the outcome of a health check in status, plan, validate and
+// scan, shown with an icon and a color
+type checkLevel int
+
+const (
+	checkOK   checkLevel = iota // Green ✓
+	checkWarn                   // Yellow ⚠️
+	checkFail                   // Red ❌
+)
+
+var (
+	checkIcons  = map[checkLevel]string{checkOK: "✓ ", checkWarn: "⚠️  ", checkFail: "❌ "}
+	checkColors = map[checkLevel]string{checkOK: ansiGreen, checkWarn: ansiYellow, checkFail: ansiRed}
+)
+
+// checkText prefixes text with the icon of level and colors it
+func checkText(level checkLevel, text string) string {
+	return checkIcons[level] + colorize(checkColors[level], text)
+}
+
+// printCheck prints a check result on a line of its own
+func printCheck(level checkLevel, format string, a ...interface{}) {
+	fmt.Println(checkText(level, fmt.Sprintf(format, a...)))
+}
+
+// keyColor is the color a key is listed in: red when its file is missing,
+// yellow when it has expired or expires soon, green when it is active
+func keyColor(status config.KeyStatus, missing, expiring bool) string {
+	switch {
+	case missing:
+		return ansiRed
+	case status == config.KeyStatusExpired || expiring:
+		return ansiYellow
+	case status == config.KeyStatusActive:
+		return ansiGreen
+	}
+	return ""
+}
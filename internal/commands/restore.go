@@ -10,6 +10,8 @@ import (
 
 	"github.com/kunlu/git-keys/internal/config"
 	"github.com/kunlu/git-keys/internal/logger"
+	"github.com/kunlu/git-keys/internal/profile"
+	"github.com/kunlu/git-keys/internal/prompt"
 	"github.com/spf13/cobra"
 )
 
@@ -55,7 +57,7 @@ func init() {
 }
 
 func runRestore(cmd *cobra.Command, args []string) error {
-	backupDir := filepath.Join(os.Getenv("HOME"), ".git-keys", "backups")
+	backupDir := filepath.Join(profile.Dir(), "backups")
 
 	// If no backup file specified, list available backups
 	if len(args) == 0 {
@@ -76,8 +78,7 @@ func runRestore(cmd *cobra.Command, args []string) error {
 	}
 
 	// Show backup summary
-	fmt.Println("\n📦 Backup Information")
-	fmt.Println("====================")
+	printHeader("\n📦 Backup Information")
 	fmt.Printf("Created: %s\n", backupData.Timestamp.Format("2006-01-02 15:04:05"))
 	fmt.Printf("File: %s\n\n", backupPath)
 
@@ -103,17 +104,11 @@ func runRestore(cmd *cobra.Command, args []string) error {
 
 	// Check if config already exists
 	configPath := config.GetDefaultConfigPath()
-	configExists := false
-	if _, err := os.Stat(configPath); err == nil {
-		configExists = true
-	}
+	configExists := config.NewManager(configPath).Exists()
 
-	if configExists && !restoreForce {
+	if configExists && !restoreForce && !nonInteractive {
 		fmt.Printf("\n⚠️  Warning: Configuration file already exists at:\n   %s\n\n", configPath)
-		fmt.Print("Overwrite existing configuration? (yes/no): ")
-		var response string
-		fmt.Scanln(&response)
-		if strings.ToLower(response) != "yes" {
+		if !prompt.ConfirmWord("Overwrite existing configuration?", "yes") {
 			fmt.Println("\n❌ Restore cancelled.")
 			return nil
 		}
@@ -134,8 +129,7 @@ func runRestore(cmd *cobra.Command, args []string) error {
 	}
 
 	// Show next steps
-	fmt.Println("\n✅ Restore Complete")
-	fmt.Println("===================")
+	printHeader("\n✅ Restore Complete")
 	fmt.Println("\nNext steps:")
 	fmt.Println("  1. Review the restored configuration:")
 	fmt.Printf("     cat %s\n", configPath)
@@ -149,8 +143,7 @@ func runRestore(cmd *cobra.Command, args []string) error {
 }
 
 func listBackups(backupDir string) error {
-	fmt.Println("\n📦 Available Backups")
-	fmt.Println("===================")
+	printHeader("\n📦 Available Backups")
 	fmt.Println()
 
 	// Check if backup directory exists
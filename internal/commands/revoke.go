@@ -9,7 +9,9 @@ import (
 
 	"github.com/kunlu/git-keys/internal/api"
 	"github.com/kunlu/git-keys/internal/config"
+	"github.com/kunlu/git-keys/internal/journal"
 	"github.com/kunlu/git-keys/internal/logger"
+	"github.com/kunlu/git-keys/internal/prompt"
 	"github.com/kunlu/git-keys/internal/sshkey"
 	"github.com/spf13/cobra"
 )
@@ -28,7 +30,11 @@ var revokeCmd = &cobra.Command{
 	Long: `Remove SSH keys from remote platforms (GitHub/GitLab).
 
 By default, keys are only removed from remote platforms. Use --local to also
-delete the local key files.
+delete the local key files. Deleted files are moved to ~/.git-keys/trash and
+can be recovered with 'git-keys trash restore'.
+
+A mistaken revocation can be undone with 'git-keys undo', which uploads the
+deleted public keys again and restores deleted key files.
 
 Examples:
   # Revoke all keys for a specific persona
@@ -43,7 +49,8 @@ Examples:
   # Revoke and delete local files
   git-keys revoke personal --local
 `,
-	RunE: runRevoke,
+	ValidArgsFunction: completePersonaPlatforms,
+	RunE:              runRevoke,
 }
 
 func init() {
@@ -52,10 +59,13 @@ func init() {
 	revokeCmd.Flags().StringVar(&revokeFingerprint, "fingerprint", "", "Revoke specific key by fingerprint")
 	revokeCmd.Flags().StringVar(&revokePersona, "persona", "", "Revoke keys for specific persona")
 	revokeCmd.Flags().StringVar(&revokePlatform, "platform", "", "Revoke keys for specific platform (github/gitlab)")
+	revokeCmd.RegisterFlagCompletionFunc("fingerprint", completeFingerprints)
+	revokeCmd.RegisterFlagCompletionFunc("persona", completePersonaFlag)
+	revokeCmd.RegisterFlagCompletionFunc("platform", completePlatformTypes)
 	rootCmd.AddCommand(revokeCmd)
 }
 
-func runRevoke(cmd *cobra.Command, args []string) error {
+func runRevoke(cmd *cobra.Command, args []string) (err error) {
 	ctx := context.Background()
 
 	// Load configuration
@@ -98,6 +108,9 @@ func runRevoke(cmd *cobra.Command, args []string) error {
 	var keysToRevoke []keyRevocation
 
 	for _, persona := range cfg.Personas {
+		if persona.Archived {
+			continue
+		}
 		if targetPersona != "" && persona.Name != targetPersona {
 			continue
 		}
@@ -131,34 +144,43 @@ func runRevoke(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
-	// Show what will be revoked
-	fmt.Println("\n🔑 Keys to Revoke:")
-	fmt.Println("==================")
-	for _, kr := range keysToRevoke {
+	// Show what will be revoked, including the remote key each delete removes
+	printHeader("\n🔑 Keys to Revoke:")
+	for i := range keysToRevoke {
+		kr := &keysToRevoke[i]
 		fmt.Printf("\n  Persona: %s\n", kr.Persona)
 		fmt.Printf("  Platform: %s (%s)\n", kr.Platform, kr.Account)
 		fmt.Printf("  Fingerprint: %s\n", kr.Key.Fingerprint)
 		fmt.Printf("  Local Path: %s\n", kr.Key.LocalPath)
 		if kr.Key.RemoteID != "" {
-			fmt.Printf("  Remote ID: %s\n", kr.Key.RemoteID)
+			kr.checkRemote(ctx)
+			kr.RemoteCheck.Print(os.Stdout, "  ")
 		}
 	}
 	fmt.Println()
 
-	// Confirm
-	fmt.Print("Revoke these keys from remote platforms? (y/n): ")
-	var response string
-	fmt.Scanln(&response)
-	if strings.ToLower(response) != "y" {
-		fmt.Println("Revocation cancelled.")
-		return nil
+	// Confirm unless non-interactive
+	if !nonInteractive {
+		if !prompt.Confirm("Revoke these keys from remote platforms?") {
+			fmt.Println("Revocation cancelled.")
+			return nil
+		}
+	}
+
+	// Record each change so 'git-keys undo' can take the revocation back
+	j, err := journal.Begin("", "revoke")
+	if err != nil {
+		return fmt.Errorf("failed to start journal: %w", err)
 	}
+	defer func() {
+		finishJournal(j, err)
+	}()
 
 	// Revoke keys
 	fmt.Println("\n⚙️  Revoking keys...")
 	for i := range keysToRevoke {
 		kr := &keysToRevoke[i]
-		if err := revokeKey(ctx, kr); err != nil {
+		if err := revokeKey(ctx, kr, j); err != nil {
 			logger.Error("Failed to revoke %s/%s: %v", kr.Persona, kr.Platform, err)
 			fmt.Printf("  ❌ %s/%s: %v\n", kr.Persona, kr.Platform, err)
 			continue
@@ -172,24 +194,29 @@ func runRevoke(cmd *cobra.Command, args []string) error {
 	// Delete local files if requested
 	if revokeLocal {
 		fmt.Println("\n🗑️  Deleting local key files...")
-		sshDir := filepath.Join(os.Getenv("HOME"), ".ssh")
-		keyMgr := sshkey.NewManager(sshDir)
+		keyMgr := sshkey.NewManager(cfg.Defaults.GetKeysDir())
 
 		for _, kr := range keysToRevoke {
 			if kr.Key.LocalPath == "" {
 				continue
 			}
 
-			if err := keyMgr.DeleteKey(kr.Key.LocalPath); err != nil {
+			item, err := keyMgr.TrashKey(kr.Key.LocalPath)
+			if err != nil {
 				logger.Warn("Failed to delete local key %s: %v", kr.Key.LocalPath, err)
 				fmt.Printf("  ⚠️  %s: %v\n", kr.Key.LocalPath, err)
-			} else {
-				fmt.Printf("  ✓ Deleted %s\n", kr.Key.LocalPath)
+				continue
+			}
+			if item != nil {
+				journalWarn(j.KeyTrashed(filepath.Join(keyMgr.KeysDir(), kr.Key.LocalPath), item.ID))
 			}
+			fmt.Printf("  ✓ Deleted %s\n", kr.Key.LocalPath)
 		}
+		fmt.Println("\n  Deleted keys were moved to the trash. Recover with 'git-keys trash restore'.")
 	}
 
 	// Save updated configuration
+	journalWarn(j.BackupFile(config.StoragePath(configPath)))
 	if err := mgr.Save(cfg); err != nil {
 		return fmt.Errorf("failed to save configuration: %w", err)
 	}
@@ -210,14 +237,48 @@ type keyRevocation struct {
 	Key         config.KeyConfig
 	PersonaRef  *config.Persona
 	PlatformRef *config.Platform
+	RemoteCheck *remoteKeyCheck // Remote key fetched before confirmation
 }
 
-func revokeKey(ctx context.Context, kr *keyRevocation) error {
+// checkRemote fetches the remote key the revocation would delete
+func (kr *keyRevocation) checkRemote(ctx context.Context) {
+	client, err := revocationClient(kr)
+	if err != nil {
+		kr.RemoteCheck = &remoteKeyCheck{RemoteID: kr.Key.RemoteID, Fingerprint: kr.Key.Fingerprint, Err: err}
+		return
+	}
+	kr.RemoteCheck = checkRemoteKey(ctx, client, kr.Key.RemoteID, kr.Key.Fingerprint)
+}
+
+// revokeKey deletes the key from its platform, recording the public key in j
+// so it can be uploaded again
+func revokeKey(ctx context.Context, kr *keyRevocation, j *journal.Journal) error {
 	if kr.Key.RemoteID == "" {
 		logger.Debug("No remote ID for key, skipping remote revocation")
 		return nil
 	}
 
+	client, err := revocationClient(kr)
+	if err != nil {
+		return err
+	}
+
+	if kr.RemoteCheck == nil {
+		kr.RemoteCheck = checkRemoteKey(ctx, client, kr.Key.RemoteID, kr.Key.Fingerprint)
+	}
+
+	// Delete key from platform
+	if err := deleteCheckedKey(ctx, client, kr.RemoteCheck); err != nil {
+		return fmt.Errorf("failed to delete key from platform: %w", err)
+	}
+	journalWarn(j.RemoteKeyDeleted(kr.Persona, string(kr.Platform), kr.Account, kr.Key.RemoteID,
+		kr.Key.Fingerprint, kr.RemoteCheck.Remote.Key, kr.RemoteCheck.Remote.Title))
+
+	return nil
+}
+
+// revocationClient creates an API client for the platform of a revocation
+func revocationClient(kr *keyRevocation) (api.PlatformClient, error) {
 	// Get API token
 	var tokenService string
 	if kr.Platform == config.PlatformGitHub {
@@ -225,7 +286,7 @@ func revokeKey(ctx context.Context, kr *keyRevocation) error {
 	} else if kr.Platform == config.PlatformGitLab {
 		tokenService = "git-keys-gitlab"
 	} else {
-		return fmt.Errorf("unsupported platform: %s", kr.Platform)
+		return nil, fmt.Errorf("unsupported platform: %s", kr.Platform)
 	}
 
 	tokenMgr := api.NewTokenManager(tokenService)
@@ -234,37 +295,27 @@ func revokeKey(ctx context.Context, kr *keyRevocation) error {
 		// Try default account
 		token, err = tokenMgr.GetToken("default")
 		if err != nil {
-			return fmt.Errorf("no API token found (service: %s): %w", tokenService, err)
+			return nil, fmt.Errorf("no API token found (service: %s): %w", tokenService, err)
 		}
 	}
 
 	// Create API client
-	var client api.PlatformClient
-	if kr.Platform == config.PlatformGitHub {
-		client = api.NewGitHubClient(token)
-	} else if kr.Platform == config.PlatformGitLab {
-		baseURL := kr.BaseURL
-		if baseURL == "" {
-			baseURL = "https://gitlab.com"
-		}
-		client = api.NewGitLabClient(baseURL, token)
-	}
-
-	// Delete key from platform
-	if err := client.DeleteKey(ctx, kr.Key.RemoteID); err != nil {
-		return fmt.Errorf("failed to delete key from platform: %w", err)
+	if kr.Platform == config.PlatformGitLab {
+		return newGitLabClient(kr.PlatformRef, token)
 	}
-
-	return nil
+	return api.NewGitHubClient(token), nil
 }
 
-func revokeByFingerprint(ctx context.Context, cfg *config.Config, fingerprint string) error {
+func revokeByFingerprint(ctx context.Context, cfg *config.Config, fingerprint string) (err error) {
 	// Normalize fingerprint (strip SHA256: prefix if present)
 	fingerprint = strings.TrimPrefix(fingerprint, "SHA256:")
 
 	var found *keyRevocation
 
 	for _, persona := range cfg.Personas {
+		if persona.Archived {
+			continue
+		}
 		for _, platform := range persona.Platforms {
 			for _, key := range platform.Keys {
 				keyFP := strings.TrimPrefix(key.Fingerprint, "SHA256:")
@@ -298,18 +349,29 @@ func revokeByFingerprint(ctx context.Context, cfg *config.Config, fingerprint st
 	fmt.Printf("  Persona: %s\n", found.Persona)
 	fmt.Printf("  Platform: %s\n", found.Platform)
 	fmt.Printf("  Fingerprint: %s\n", found.Key.Fingerprint)
+	if found.Key.RemoteID != "" {
+		found.checkRemote(ctx)
+		found.RemoteCheck.Print(os.Stdout, "  ")
+	}
 	fmt.Println()
 
-	fmt.Print("Revoke this key? (y/n): ")
-	var response string
-	fmt.Scanln(&response)
-	if strings.ToLower(response) != "y" {
-		fmt.Println("Revocation cancelled.")
-		return nil
+	if !nonInteractive {
+		if !prompt.Confirm("Revoke this key?") {
+			fmt.Println("Revocation cancelled.")
+			return nil
+		}
+	}
+
+	j, err := journal.Begin("", "revoke")
+	if err != nil {
+		return fmt.Errorf("failed to start journal: %w", err)
 	}
+	defer func() {
+		finishJournal(j, err)
+	}()
 
 	// Revoke from remote platform
-	if err := revokeKey(ctx, found); err != nil {
+	if err := revokeKey(ctx, found, j); err != nil {
 		return fmt.Errorf("failed to revoke key: %w", err)
 	}
 
@@ -324,6 +386,7 @@ func revokeByFingerprint(ctx context.Context, cfg *config.Config, fingerprint st
 
 	// Save configuration
 	mgr := config.NewManager(config.GetDefaultConfigPath())
+	journalWarn(j.BackupFile(config.GetDefaultConfigPath()))
 	if err := mgr.Save(cfg); err != nil {
 		return fmt.Errorf("failed to save configuration: %w", err)
 	}
@@ -4,20 +4,31 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/kunlu/git-keys/internal/config"
 	"github.com/kunlu/git-keys/internal/logger"
+	"github.com/kunlu/git-keys/internal/profile"
 	"github.com/spf13/cobra"
 )
 
 var (
-	cfgFile  string
-	logLevel string
-	rootCmd  = &cobra.Command{
+	cfgFile       string
+	profileName   string
+	logLevel      string
+	logFormat     string
+	refreshRemote bool
+	rootCmd       = &cobra.Command{
 		Use:   "git-keys",
 		Short: "Automated SSH key management for Git platforms",
 		Long: `git-keys is a tool for managing SSH keys across GitHub and GitLab.
 It automatically generates, rotates, and manages SSH keys with per-persona
 configuration, ensuring secure and organized access to your repositories.`,
 		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			// Completions load what they need themselves and must not
+			// print, pull or push anything
+			if isCompletionRequest(cmd) {
+				return
+			}
+
 			// Set up logging
 			if logLevel != "" {
 				if err := logger.SetLevelFromString(logLevel); err != nil {
@@ -25,18 +36,106 @@ configuration, ensuring secure and organized access to your repositories.`,
 					os.Exit(1)
 				}
 			}
+			if logFormat != "" {
+				if err := logger.SetFormatFromString(logFormat); err != nil {
+					fmt.Fprintf(os.Stderr, "Invalid log format: %s (use text or json)\n", logFormat)
+					os.Exit(1)
+				}
+			}
+
+			// Select the profile before any path is resolved
+			if err := profile.Set(profileName); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			if err := setupStructuredOutput(cmd); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			if err := setupQuiet(cmd); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			setupNonInteractive(cmd)
+
+			// Remember the config file so the editor summary can be refreshed
+			// if the command changes it
+			summaryConfigPath = cfgFile
+			if summaryConfigPath == "" {
+				summaryConfigPath = config.GetDefaultConfigPath()
+			}
+			summaryConfigBefore, summaryConfigExisted = statConfig(summaryConfigPath)
+
+			// Pull the synced config before plan and apply, and remember it
+			// so a change is pushed afterwards
+			recordConfigSyncState(cmd, summaryConfigPath)
+
+			cfg := loadGlobalDefaults()
+			if cfg != nil {
+				configureAPIClients(cfg)
+				configureOutput(cfg)
+				if logFormat == "" && cfg.Defaults.LogFormat != "" {
+					if err := logger.SetFormatFromString(cfg.Defaults.LogFormat); err != nil {
+						logger.Warn("Ignoring defaults.log_format: %v", err)
+					}
+				}
+			}
+			configureLogFile(cmd, cfg)
 		},
 	}
 )
 
 func init() {
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.git-keys.yaml)")
+	rootCmd.PersistentFlags().StringVar(&profileName, "profile", "", "Profile with its own config, history, backups and tokens (default $GIT_KEYS_PROFILE)")
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", formatTable, "Output format of status, plan, list, validate, rotate, audit, doctor, scan and sshconfig lint: table, json or yaml")
+	rootCmd.PersistentFlags().BoolVar(&noColor, "no-color", false, "Disable colored output (also with NO_COLOR set, or when stdout is not a terminal)")
+	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "Print only errors and report the result of status, plan and validate through the exit status")
+	rootCmd.PersistentFlags().BoolVarP(&nonInteractive, "yes", "y", false, "Accept confirmations and never prompt; questions without a flag-based answer fail")
+	rootCmd.PersistentFlags().BoolVar(&nonInteractive, "non-interactive", false, "Same as --yes")
 	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "info", "log level (error, warn, info, debug, trace)")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", "", "Log line format: text (default) or json, one object per line for log aggregators")
+	rootCmd.PersistentFlags().StringVar(&logFile, "log-file", "", "Also log to this file, rotated by size and day (default ~/.git-keys/logs/git-keys.log without a path)")
+	rootCmd.PersistentFlags().Lookup("log-file").NoOptDefVal = logFileDefault
+	rootCmd.RegisterFlagCompletionFunc("profile", completeProfiles)
+	rootCmd.RegisterFlagCompletionFunc("output", completeValues(formatTable, formatJSON, formatYAML))
+	rootCmd.RegisterFlagCompletionFunc("log-level", completeValues("error", "warn", "info", "debug", "trace"))
+	rootCmd.RegisterFlagCompletionFunc("log-format", completeValues(string(logger.FormatText), string(logger.FormatJSON)))
+	rootCmd.PersistentFlags().BoolVar(&refreshRemote, "refresh", false, "Re-fetch remote key listings instead of using the cache")
+}
+
+// loadGlobalDefaults loads the config for settings that apply to every
+// command. Returns nil when the config is missing or invalid, in which case
+// the built-in defaults stay in effect.
+func loadGlobalDefaults() *config.Config {
+	configPath := cfgFile
+	if configPath == "" {
+		configPath = config.GetDefaultConfigPath()
+	}
+
+	mgr := config.NewManager(configPath)
+	if !mgr.Exists() {
+		return nil
+	}
+
+	cfg, err := mgr.Load()
+	if err != nil {
+		return nil
+	}
+	return cfg
 }
 
 // Execute runs the root command
 func Execute() error {
-	return rootCmd.Execute()
+	err := rootCmd.Execute()
+	if summaryConfigPath != "" {
+		// Also after a failed command, which may have saved the config first
+		refreshEditorSummary(summaryConfigPath, summaryConfigBefore, summaryConfigExisted)
+		pushConfigAfterCommand(summaryConfigPath)
+	}
+	closeLogFile(err)
+	return err
 }
 
 // GetConfigFile returns the config file path
@@ -3,27 +3,39 @@ package commands
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/kunlu/git-keys/internal/api"
 	"github.com/kunlu/git-keys/internal/config"
+	"github.com/kunlu/git-keys/internal/journal"
 	"github.com/kunlu/git-keys/internal/logger"
-	"github.com/kunlu/git-keys/internal/platform"
+	"github.com/kunlu/git-keys/internal/prompt"
 	"github.com/kunlu/git-keys/internal/sshconfig"
 	"github.com/kunlu/git-keys/internal/sshkey"
 	"github.com/spf13/cobra"
 )
 
 var (
-	rotateAll     bool
-	rotatePersona string
-	rotateDryRun  bool
+	rotateAll      bool
+	rotatePersona  string
+	rotateDryRun   bool
+	rotateParallel int
+	rotateDue      bool
+	rotateWithin   string
+	rotateYes      bool
+	rotateSchedule bool // Run by the job of 'git-keys schedule install'
 )
 
+// defaultDueWindow is how long before its expiry a key is due for rotation
+// when neither --within nor defaults.rotate_within is set
+const defaultDueWindow = 14 * 24 * time.Hour
+
 var rotateCmd = &cobra.Command{
 	Use:   "rotate [persona/platform]",
 	Short: "Rotate SSH keys for personas/platforms",
@@ -38,7 +50,7 @@ The rotation process:
   6. Archive old key locally (renamed with .old-YYYY-MM-DD suffix)
 
 This is an atomic operation per persona/platform. If any step fails, changes
-are rolled back.
+are rolled back. A completed rotation can be undone with 'git-keys undo'.
 
 Examples:
   # Rotate keys for a specific persona
@@ -49,18 +61,37 @@ Examples:
 
   # Dry run to see what would be rotated
   git-keys rotate --all --dry-run
+
+  # Rotate only keys expiring within 14 days (defaults.rotate_within),
+  # without prompting; this is what 'git-keys schedule install' runs
+  git-keys rotate --due --yes
+
+  # Rotate keys expiring within 30 days
+  git-keys rotate --due --within 30d
+
+Deploy keys (see 'git-keys deploy-key') managed by the selected personas are
+rotated along with their keys.
 `,
-	RunE: runRotate,
+	Annotations:       map[string]string{structuredOutputAnnotation: structuredProgress},
+	ValidArgsFunction: completePersonaPlatforms,
+	RunE:              runRotate,
 }
 
 func init() {
 	rotateCmd.Flags().BoolVar(&rotateAll, "all", false, "Rotate all keys")
 	rotateCmd.Flags().StringVar(&rotatePersona, "persona", "", "Rotate keys for specific persona")
 	rotateCmd.Flags().BoolVar(&rotateDryRun, "dry-run", false, "Show what would be rotated without making changes")
+	rotateCmd.Flags().IntVar(&rotateParallel, "parallel", defaultParallel, "Number of platform accounts to rotate at once")
+	rotateCmd.Flags().BoolVar(&rotateDue, "due", false, "Only rotate keys that expire within the due window (all personas unless one is given)")
+	rotateCmd.Flags().StringVar(&rotateWithin, "within", "", "Due window for --due, e.g. 14d or 72h (default defaults.rotate_within, or 14d)")
+	rotateCmd.Flags().BoolVarP(&rotateYes, "yes", "y", false, "Skip confirmation prompt")
+	rotateCmd.Flags().BoolVar(&rotateSchedule, "scheduled", false, "Run as the scheduled job: do nothing unless defaults.auto_rotate is true")
+	rotateCmd.Flags().MarkHidden("scheduled")
+	rotateCmd.RegisterFlagCompletionFunc("persona", completePersonaFlag)
 	rootCmd.AddCommand(rotateCmd)
 }
 
-func runRotate(cmd *cobra.Command, args []string) error {
+func runRotate(cmd *cobra.Command, args []string) (err error) {
 	ctx := context.Background()
 
 	// Load configuration
@@ -79,14 +110,19 @@ func runRotate(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to load configuration: %w", err)
 	}
 
-	// Get platform info for key comments
-	plat, err := platform.NewPlatform()
-	if err != nil {
-		return fmt.Errorf("failed to get platform info: %w", err)
+	// auto_rotate may have been turned off since the job was installed,
+	// here or in a config pulled from elsewhere
+	if rotateSchedule && !cfg.Defaults.AutoRotate {
+		return fmt.Errorf("automatic rotation is disabled; not rotating (set 'auto_rotate: true' under defaults in %s, or run 'git-keys schedule uninstall')", configPath)
 	}
 
-	machineName, err := plat.GetMachineName()
-	if err != nil {
+	// Machine name for key comments; where it cannot be detected (scheduled
+	// runs on Linux), the name recorded in the config
+	machineName, err := detectMachineName()
+	if err != nil || machineName == "" {
+		machineName = cfg.Machine.Name
+	}
+	if machineName == "" {
 		machineName = "unknown"
 	}
 
@@ -102,14 +138,25 @@ func runRotate(cmd *cobra.Command, args []string) error {
 		}
 	} else if rotatePersona != "" {
 		targetPersona = rotatePersona
-	} else if !rotateAll {
-		return fmt.Errorf("specify a persona or use --all")
+	} else if !rotateAll && !rotateDue {
+		return fmt.Errorf("specify a persona, or use --all or --due")
+	}
+
+	if rotateWithin != "" && !rotateDue {
+		return fmt.Errorf("--within requires --due")
+	}
+	dueWindow, err := rotateDueWindow(cfg, rotateWithin)
+	if err != nil {
+		return err
 	}
 
 	// Collect keys to rotate
 	var rotations []keyRotation
 
 	for personaIdx, persona := range cfg.Personas {
+		if persona.Archived {
+			continue
+		}
 		if targetPersona != "" && persona.Name != targetPersona {
 			continue
 		}
@@ -120,86 +167,269 @@ func runRotate(cmd *cobra.Command, args []string) error {
 			}
 
 			for keyIdx, key := range platform.Keys {
-				if key.Status != config.KeyStatusActive {
-					logger.Debug("Skipping non-active key: %s", key.Fingerprint)
+				if !key.InUse() {
+					logger.Debug("Skipping key not in use: %s", key.Fingerprint)
+					continue
+				}
+
+				if key.Agent {
+					fmt.Printf("⊘ Skipping %s/%s: key is held by %s; rotate it in the agent app\n",
+						persona.Name, platform.Type, platform.IdentityAgent)
+					continue
+				}
+
+				if key.PublicOnly {
+					fmt.Printf("⊘ Skipping %s/%s: public-only key; rotate it where the private key lives\n",
+						persona.Name, platform.Type)
+					continue
+				}
+
+				if rotateDue && !keyDue(cfg, &persona, &platform, &key, dueWindow) {
+					logger.Debug("Key not due for rotation: %s", key.Fingerprint)
 					continue
 				}
 
-				rotations = append(rotations, keyRotation{
-					PersonaName:  persona.Name,
-					PersonaIdx:   personaIdx,
-					PlatformType: platform.Type,
-					PlatformIdx:  platformIdx,
-					KeyIdx:       keyIdx,
-					Account:      platform.Account,
-					BaseURL:      platform.BaseURL,
-					OldKey:       key,
-					MachineName:  machineName,
-				})
+				rotations = append(rotations, newKeyRotation(cfg, personaIdx, platformIdx, keyIdx, machineName))
 			}
 		}
 	}
 
-	if len(rotations) == 0 {
-		fmt.Println("No keys to rotate.")
+	// Deploy keys managed by the selected personas follow the same schedule
+	var deployRotations []*config.DeployKey
+	for i := range cfg.DeployKeys {
+		dk := &cfg.DeployKeys[i]
+		if dk.GetActiveKey() == nil {
+			continue
+		}
+		if (targetPersona != "" && dk.Persona != targetPersona) || (targetPlatform != "" && string(dk.Platform) != targetPlatform) {
+			continue
+		}
+		if rotateDue && !deployKeyIsDue(cfg, dk, dueWindow) {
+			continue
+		}
+		deployRotations = append(deployRotations, dk)
+	}
+
+	if len(rotations) == 0 && len(deployRotations) == 0 {
+		if structuredOutput() {
+			if err := printStructured("RotationResult", newRotationReport(cfg, nil, nil, nil, "")); err != nil {
+				return err
+			}
+		}
+		if rotateDue {
+			fmt.Printf("No keys expire within %s.\n", formatDueWindow(dueWindow))
+		} else {
+			fmt.Println("No keys to rotate.")
+		}
 		return nil
 	}
 
-	// Show what will be rotated
-	fmt.Println("\n🔄 Keys to Rotate:")
-	fmt.Println("==================")
-	for _, rot := range rotations {
+	printRotations(ctx, cfg, rotations, deployRotations)
+
+	if rotateDryRun {
+		fmt.Println("[DRY RUN - no changes made]")
+		if structuredOutput() {
+			return printStructured("RotationResult", newRotationReport(cfg, rotations, deployRotations, nil, rotationPlanned))
+		}
+		return nil
+	}
+
+	// Confirm unless -y flag
+	if !rotateYes {
+		if !prompt.Confirm("Rotate these keys?") {
+			fmt.Println("Rotation cancelled.")
+			if structuredOutput() {
+				return printStructured("RotationResult", newRotationReport(cfg, rotations, deployRotations, nil, rotationCancelled))
+			}
+			return nil
+		}
+	}
+
+	// Deploy keys are rotated in place; their old fingerprints tell whether
+	// they were
+	deployBefore := make(map[*config.DeployKey]string)
+	for _, dk := range deployRotations {
+		deployBefore[dk] = dk.GetActiveKey().Fingerprint
+	}
+
+	err = executeRotations(ctx, cfg, mgr, configPath, rotations, deployRotations, rotateParallel)
+	if structuredOutput() {
+		if printErr := printStructured("RotationResult", newRotationReport(cfg, rotations, deployRotations, deployBefore, "")); printErr != nil && err == nil {
+			err = printErr
+		}
+	}
+	return err
+}
+
+// newKeyRotation prepares the rotation of a configured key
+func newKeyRotation(cfg *config.Config, personaIdx, platformIdx, keyIdx int, machineName string) keyRotation {
+	persona := &cfg.Personas[personaIdx]
+	platform := &persona.Platforms[platformIdx]
+	return keyRotation{
+		PersonaName:  persona.Name,
+		PersonaIdx:   personaIdx,
+		PlatformType: platform.Type,
+		PlatformIdx:  platformIdx,
+		KeyIdx:       keyIdx,
+		Account:      platform.Account,
+		BaseURL:      platform.BaseURL,
+		Connection:   gitlabConnectionOptions(platform),
+		SSHOptions:   platformSSHOptions(cfg, platform),
+		OldKey:       platform.Keys[keyIdx],
+		MachineName:  machineName,
+	}
+}
+
+// printRotations shows what will be rotated and checks the old remote keys
+// that will be deleted
+func printRotations(ctx context.Context, cfg *config.Config, rotations []keyRotation, deployRotations []*config.DeployKey) {
+	printHeader("\n🔄 Keys to Rotate:")
+	for i := range rotations {
+		rot := &rotations[i]
 		fmt.Printf("\n  Persona: %s\n", rot.PersonaName)
 		fmt.Printf("  Platform: %s (%s)\n", rot.PlatformType, rot.Account)
 		fmt.Printf("  Current Key: %s\n", rot.OldKey.LocalPath)
 		fmt.Printf("  Fingerprint: %s\n", rot.OldKey.Fingerprint)
+		if rot.KeyType != "" {
+			fmt.Printf("  New Key Type: %s\n", rot.KeyType)
+		}
 		if !rot.OldKey.ExpiresAt.IsZero() {
 			fmt.Printf("  Expires: %s\n", rot.OldKey.ExpiresAt.Format("2006-01-02"))
 		}
+
+		// The old remote key is deleted in step 5; show which one it is
+		if rot.OldKey.RemoteID != "" {
+			client, err := rotationClient(rot)
+			if err != nil {
+				rot.RemoteCheck = &remoteKeyCheck{RemoteID: rot.OldKey.RemoteID, Fingerprint: rot.OldKey.Fingerprint, Err: err}
+			} else {
+				rot.RemoteCheck = checkRemoteKey(ctx, client, rot.OldKey.RemoteID, rot.OldKey.Fingerprint)
+			}
+			rot.RemoteCheck.Print(os.Stdout, "  ")
+		}
+	}
+	for _, dk := range deployRotations {
+		key := dk.GetActiveKey()
+		fmt.Printf("\n  Deploy key: %s (%s)\n", dk.Repo, dk.Platform)
+		fmt.Printf("  Current Key: %s\n", key.LocalPath)
+		fmt.Printf("  Fingerprint: %s\n", key.Fingerprint)
+		if expiresAt, known := deployKeyExpiry(cfg, dk, key); known {
+			fmt.Printf("  Expires: %s\n", expiresAt.Format("2006-01-02"))
+		}
 	}
 	fmt.Println()
+}
 
-	if rotateDryRun {
-		fmt.Println("[DRY RUN - no changes made]")
-		return nil
+// executeRotations rotates keys and deploy keys, parallel platform accounts
+// at a time, and saves the configuration
+func executeRotations(ctx context.Context, cfg *config.Config, mgr *config.Manager, configPath string, rotations []keyRotation, deployRotations []*config.DeployKey, parallel int) (err error) {
+	// Record each change so 'git-keys undo' can take the rotation back
+	j, err := journal.Begin("", "rotate")
+	if err != nil {
+		return fmt.Errorf("failed to start journal: %w", err)
 	}
+	defer func() {
+		finishJournal(j, err)
+	}()
 
-	// Confirm
-	fmt.Print("Rotate these keys? (y/n): ")
-	var response string
-	fmt.Scanln(&response)
-	if strings.ToLower(response) != "y" {
-		fmt.Println("Rotation cancelled.")
-		return nil
+	// The new keys are validated with ssh, which must know the hosts
+	if !cfg.Defaults.DisableKnownHosts {
+		journalWarn(j.BackupFile(cfg.Defaults.GetKnownHostsFile()))
+		if _, err := updateKnownHosts(ctx, cfg); err != nil {
+			logger.Warn("Failed to update known_hosts: %v", err)
+		}
 	}
 
-	// Rotate keys
+	// Rotate keys; independent platform accounts are processed concurrently
 	fmt.Println("\n⚙️  Rotating keys...")
 	var successful int
 	var failed int
 
+	tasks := make([]platformTask, len(rotations), len(rotations)+len(deployRotations))
 	for i := range rotations {
 		rot := &rotations[i]
-		fmt.Printf("\n  Processing %s/%s...\n", rot.PersonaName, rot.PlatformType)
+		tasks[i] = platformTask{
+			Label: fmt.Sprintf("%s/%s", rot.PersonaName, rot.PlatformType),
+			Lane:  platformLane(rot.PlatformType, rot.BaseURL, rot.Account),
+			Run: func(ctx context.Context, out io.Writer) error {
+				if parallel <= 1 {
+					fmt.Fprintf(out, "\n  Processing %s/%s...\n", rot.PersonaName, rot.PlatformType)
+				}
+				rot.Err = rotateKey(ctx, cfg, rot, j, out)
+				return rot.Err
+			},
+		}
+	}
+	for _, dk := range deployRotations {
+		_, plat, err := cfg.DeployKeyPlatform(dk)
+		if err != nil {
+			return err
+		}
+		tasks = append(tasks, platformTask{
+			Label: fmt.Sprintf("deploy key %s", dk.Repo),
+			Lane:  platformLane(plat.Type, plat.BaseURL, plat.Account),
+			Run: func(ctx context.Context, out io.Writer) error {
+				if parallel <= 1 {
+					fmt.Fprintf(out, "\n  Processing deploy key %s...\n", dk.Repo)
+				}
+				return rotateDeployKey(ctx, cfg, dk, j, out)
+			},
+		})
+	}
+
+	// Many rotations show live progress and a summary table instead of
+	// their steps
+	tasks, progress := startProgress("Rotating keys", tasks)
+	runPlatformTasks(ctx, tasks, parallel, func(result platformTaskResult) {
+		if result.Err != nil {
+			logger.WithFields(logger.Fields{"target": result.Label, "error": result.Err}).Error("Failed to rotate")
+		}
+		if progress != nil {
+			progress.finish(result)
+			if result.Err != nil {
+				failed++
+			} else {
+				successful++
+			}
+			return
+		}
+
+		if result.Output != "" {
+			// Concurrent rotations print their steps as one block when done
+			fmt.Printf("\n  Processing %s... (%s)\n", result.Label, result.Elapsed.Round(time.Millisecond))
+			fmt.Print(result.Output)
+		}
 
-		if err := rotateKey(ctx, cfg, rot); err != nil {
-			logger.Error("Failed to rotate %s/%s: %v", rot.PersonaName, rot.PlatformType, err)
-			fmt.Printf("    ❌ Failed: %v\n", err)
+		if result.Err != nil {
+			fmt.Printf("    ❌ Failed: %v\n", result.Err)
 			failed++
-			continue
+			return
 		}
 
 		fmt.Printf("    ✓ Rotation complete\n")
 		successful++
+	})
+	if progress != nil {
+		progress.stop()
+		progress.printSummary()
 	}
 
 	// Save updated configuration
 	if successful > 0 {
+		journalWarn(j.BackupFile(config.StoragePath(configPath)))
 		if err := mgr.Save(cfg); err != nil {
 			return fmt.Errorf("failed to save configuration: %w", err)
 		}
 	}
 
+	// The key file name can change with a rotation (its type is part of
+	// it), so point the SSH commands naming the old file at the new one
+	for i := range rotations {
+		if rot := &rotations[i]; rot.Err == nil && rot.NewKey != nil {
+			updateRotatedSSHCommands(cfg, rot, j)
+		}
+	}
+
 	fmt.Println("\n" + strings.Repeat("=", 40))
 	fmt.Printf("✅ Rotation Summary: %d succeeded, %d failed\n", successful, failed)
 
@@ -218,32 +448,44 @@ type keyRotation struct {
 	KeyIdx       int
 	Account      string
 	BaseURL      string
+	Connection   api.ConnectionOptions
+	SSHOptions   map[string]string // Extra options of the platform's SSH config block
 	OldKey       config.KeyConfig
+	KeyType      config.KeyType // Overrides the configured key type, e.g. to replace weak keys
 	NewKey       *config.KeyConfig
 	MachineName  string
+	RemoteCheck  *remoteKeyCheck // Old remote key, fetched before confirmation
+	Err          error           // Why the rotation failed
 }
 
-func rotateKey(ctx context.Context, cfg *config.Config, rot *keyRotation) error {
-	sshDir := filepath.Join(os.Getenv("HOME"), ".ssh")
-	keyMgr := sshkey.NewManager(sshDir)
+func rotateKey(ctx context.Context, cfg *config.Config, rot *keyRotation, j *journal.Journal, out io.Writer) error {
+	keysDir := cfg.Defaults.GetKeysDir()
+	keyMgr := sshkey.NewManager(keysDir)
 
-	// Get default key type from config
-	keyType := cfg.Defaults.KeyType
-	if keyType == "" {
-		keyType = config.KeyTypeED25519
+	// Resolve key type, expiry and file name (platform > persona > defaults)
+	persona := &cfg.Personas[rot.PersonaIdx]
+	settings := cfg.ResolveKeySettings(persona, &persona.Platforms[rot.PlatformIdx])
+	keyType := settings.Type
+	if rot.KeyType != "" {
+		keyType = rot.KeyType
 	}
-
-	// Calculate expiry (6 months default)
-	expiryMonths := 6
-	if cfg.Defaults.KeyExpiration > 0 {
-		expiryMonths = int(cfg.Defaults.KeyExpiration.Hours() / 24 / 30)
-	}
-	expiresAt := time.Now().AddDate(0, expiryMonths, 0)
+	expiresAt := settings.ExpiresAt(time.Now())
+	nameData := sshkey.NewNameData(persona, &persona.Platforms[rot.PlatformIdx], keyType, rot.MachineName, time.Now())
 
 	// Step 1: Generate new key pair
-	fmt.Println("    → Generating new key pair...")
-	keyFileName := sshkey.BuildKeyFileName(rot.PlatformType, rot.Account, keyType)
-	keyComment := sshkey.BuildKeyComment(rot.PlatformType, rot.Account, rot.MachineName)
+	fmt.Fprintln(out, "    → Generating new key pair...")
+	keyFileName, err := sshkey.RenderFileName(settings.FileNameTemplate, nameData)
+	if err != nil {
+		return err
+	}
+	keyComment, err := sshkey.RenderName(settings.CommentTemplate, sshkey.DefaultCommentTemplate, nameData)
+	if err != nil {
+		return err
+	}
+	title, err := sshkey.RenderTitle(settings, rotatedTitleTemplate, nameData, expiresAt)
+	if err != nil {
+		return err
+	}
 
 	// Add timestamp to avoid collision with existing key
 	newKeyPath := keyFileName + "-new"
@@ -271,11 +513,12 @@ func rotateKey(ctx context.Context, cfg *config.Config, rot *keyRotation) error
 	}
 
 	// Step 2: Upload new key to remote platform
-	fmt.Println("    → Uploading new key to platform...")
-	remoteID, err := uploadKey(ctx, rot, publicKey)
+	fmt.Fprintln(out, "    → Uploading new key to platform...")
+	remoteID, err := uploadKey(ctx, rot, title, publicKey, expiresAt)
 	if err != nil {
 		return fmt.Errorf("failed to upload new key: %w", err)
 	}
+	journalWarn(j.KeyUploaded(rot.PersonaName, string(rot.PlatformType), rot.Account, remoteID, fingerprint))
 
 	// Create new key config
 	rot.NewKey = &config.KeyConfig{
@@ -289,51 +532,63 @@ func rotateKey(ctx context.Context, cfg *config.Config, rot *keyRotation) error
 	}
 
 	// Step 3: Update SSH config
-	fmt.Println("    → Updating SSH config...")
-	if err := updateSSHConfigForRotation(rot, sshDir); err != nil {
+	fmt.Fprintln(out, "    → Updating SSH config...")
+	sshConfigMu.Lock()
+	sshMgr := sshConfigManager(cfg)
+	journalSSHConfig(j, sshMgr)
+	err = updateSSHConfigForRotation(rot, sshMgr, keyMgr)
+	sshConfigMu.Unlock()
+	if err != nil {
 		// Try to clean up remote key
 		deleteKey(ctx, rot, remoteID)
 		return fmt.Errorf("failed to update SSH config: %w", err)
 	}
 
 	// Step 4: Validate new key works
-	fmt.Println("    → Validating new key...")
+	fmt.Fprintln(out, "    → Validating new key...")
 	if err := validateSSHKey(rot); err != nil {
 		logger.Warn("Key validation failed: %v", err)
-		fmt.Println("    ⚠️  Warning: Could not validate new key (connection test failed)")
-		fmt.Println("    The key has been uploaded and SSH config updated.")
+		fmt.Fprintln(out, "    ⚠️  Warning: Could not validate new key (connection test failed)")
+		fmt.Fprintln(out, "    The key has been uploaded and SSH config updated.")
 		// Continue anyway - validation failures are often due to network/firewall
 	} else {
-		fmt.Println("    ✓ New key validated")
+		fmt.Fprintln(out, "    ✓ New key validated")
 	}
 
 	// Step 5: Remove old key from remote platform
 	if rot.OldKey.RemoteID != "" {
-		fmt.Println("    → Removing old key from platform...")
-		if err := deleteKey(ctx, rot, rot.OldKey.RemoteID); err != nil {
+		fmt.Fprintln(out, "    → Removing old key from platform...")
+		if problem := rot.RemoteCheck.Problem(); problem != "" {
+			logger.Warn("Not deleting old key from platform: %s", problem)
+			fmt.Fprintf(out, "    ⚠️  Warning: Old key not removed: %s\n", problem)
+			fmt.Fprintln(out, "    Check the platform's key list and remove it manually")
+		} else if err := deleteKey(ctx, rot, rot.OldKey.RemoteID); err != nil {
 			logger.Warn("Failed to delete old key from platform: %v", err)
-			fmt.Println("    ⚠️  Warning: Could not remove old key from platform")
-			fmt.Println("    You may need to manually remove it")
+			fmt.Fprintln(out, "    ⚠️  Warning: Could not remove old key from platform")
+			fmt.Fprintln(out, "    You may need to manually remove it")
 		} else {
-			fmt.Println("    ✓ Old key removed from platform")
+			journalWarn(j.RemoteKeyDeleted(rot.PersonaName, string(rot.PlatformType), rot.Account, rot.OldKey.RemoteID,
+				rot.OldKey.Fingerprint, rot.RemoteCheck.Remote.Key, rot.RemoteCheck.Remote.Title))
+			fmt.Fprintln(out, "    ✓ Old key removed from platform")
 		}
 	}
 
 	// Step 6: Archive old key locally
 	if rot.OldKey.LocalPath != "" {
-		fmt.Println("    → Archiving old key...")
-		if err := archiveOldKey(rot.OldKey.LocalPath, sshDir); err != nil {
+		fmt.Fprintln(out, "    → Archiving old key...")
+		if archived, err := archiveOldKey(rot.OldKey.LocalPath, keysDir); err != nil {
 			logger.Warn("Failed to archive old key: %v", err)
-			fmt.Println("    ⚠️  Warning: Could not archive old key")
+			fmt.Fprintln(out, "    ⚠️  Warning: Could not archive old key")
 		} else {
-			fmt.Println("    ✓ Old key archived")
+			journalWarn(j.KeyMoved(filepath.Join(keysDir, rot.OldKey.LocalPath), archived))
+			fmt.Fprintln(out, "    ✓ Old key archived")
 		}
 	}
 
 	// Step 7: Rename new key to final name (remove -new suffix)
 	finalKeyPath := keyFileName
-	oldFullPath := filepath.Join(sshDir, newKeyPath)
-	newFullPath := filepath.Join(sshDir, finalKeyPath)
+	oldFullPath := filepath.Join(keysDir, newKeyPath)
+	newFullPath := filepath.Join(keysDir, finalKeyPath)
 
 	if err := os.Rename(oldFullPath, newFullPath); err != nil {
 		logger.Warn("Failed to rename new private key: %v", err)
@@ -341,6 +596,7 @@ func rotateKey(ctx context.Context, cfg *config.Config, rot *keyRotation) error
 		os.Rename(oldFullPath+".pub", newFullPath+".pub")
 		rot.NewKey.LocalPath = finalKeyPath
 	}
+	journalWarn(j.KeyCreated(filepath.Join(keysDir, rot.NewKey.LocalPath)))
 
 	// Update config with new key
 	cfg.Personas[rot.PersonaIdx].Platforms[rot.PlatformIdx].Keys[rot.KeyIdx] = *rot.NewKey
@@ -348,7 +604,106 @@ func rotateKey(ctx context.Context, cfg *config.Config, rot *keyRotation) error
 	return nil
 }
 
-func uploadKey(ctx context.Context, rot *keyRotation, publicKey string) (string, error) {
+// updateRotatedSSHCommands rewrites the core.sshCommand settings that name
+// a rotated platform's key file: the platform's git config file with the
+// ssh-command switch strategy, and the repositories bound with 'git-keys
+// use'. Failures only warn; 'git-keys apply' and 'git-keys use' write them
+// again.
+func updateRotatedSSHCommands(cfg *config.Config, rot *keyRotation, j *journal.Journal) {
+	persona := &cfg.Personas[rot.PersonaIdx]
+	plat := &persona.Platforms[rot.PlatformIdx]
+
+	if cfg.GetSwitchStrategy(persona) == config.SwitchSSHCommand {
+		if home, err := os.UserHomeDir(); err == nil {
+			path := platformGitConfigPath(home, persona.Name, plat)
+			if _, err := os.Stat(path); err == nil {
+				journalWarn(j.BackupFile(path))
+				if err := createPlatformGitConfigFile(cfg, persona, plat, path); err != nil {
+					logger.Warn("Failed to update %s: %v", path, err)
+				} else {
+					fmt.Printf("✓ Updated core.sshCommand in %s\n", path)
+				}
+			}
+		}
+	}
+
+	key := plat.GetActiveKey()
+	if key == nil || len(plat.Repos) == 0 {
+		return
+	}
+	keyMgr := sshkey.NewManager(cfg.Defaults.GetKeysDir())
+	sshCommand := personaSSHCommand(plat, keyMgr.IdentityFilePath(key.LocalPath))
+	for _, repo := range plat.Repos {
+		if current, err := gitOutput(repo, "config", "--local", "--get", "core.sshCommand"); err != nil || current == sshCommand {
+			continue // Not bound here any more, or already up to date
+		}
+		gitPath, err := gitOutput(repo, "rev-parse", "--git-path", "config")
+		if err != nil {
+			logger.Warn("Failed to update bound repository %s: %v", repo, err)
+			continue
+		}
+		if !filepath.IsAbs(gitPath) {
+			gitPath = filepath.Join(repo, gitPath)
+		}
+		journalWarn(j.BackupFile(gitPath))
+		if _, err := gitOutput(repo, "config", "--local", "core.sshCommand", sshCommand); err != nil {
+			logger.Warn("Failed to set core.sshCommand in %s: %v", repo, err)
+			continue
+		}
+		fmt.Printf("✓ Updated core.sshCommand in bound repository %s\n", repo)
+	}
+}
+
+// parseDueWindow parses a due window: a Go duration such as "72h", or a
+// number of days such as "14d"
+func parseDueWindow(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid due window %q: use days (14d) or a duration (72h)", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid due window %q: use days (14d) or a duration (72h)", s)
+	}
+	return d, nil
+}
+
+// rotateDueWindow returns the window of 'rotate --due': within when given,
+// otherwise defaults.rotate_within, otherwise defaultDueWindow
+func rotateDueWindow(cfg *config.Config, within string) (time.Duration, error) {
+	if within != "" {
+		return parseDueWindow(within)
+	}
+	if cfg.Defaults.RotateWithin > 0 {
+		return cfg.Defaults.RotateWithin, nil
+	}
+	return defaultDueWindow, nil
+}
+
+// formatDueWindow prints whole days as "14 days" and anything else as a duration
+func formatDueWindow(window time.Duration) string {
+	day := 24 * time.Hour
+	if window >= day && window%day == 0 {
+		return fmt.Sprintf("%d days", window/day)
+	}
+	return window.String()
+}
+
+// keyDue reports whether a key expires within window from now. Keys without
+// an expiry or creation date are never due.
+func keyDue(cfg *config.Config, persona *config.Persona, plat *config.Platform, key *config.KeyConfig, window time.Duration) bool {
+	expiresAt, known := cfg.KeyExpiry(persona, plat, key)
+	return known && !expiresAt.After(time.Now().Add(window))
+}
+
+// rotatedTitleTemplate is the remote key title used by rotate when no
+// defaults.key_title_template is configured
+const rotatedTitleTemplate = "{{.Account}}@{{.Machine}} (rotated {{.Date}})"
+
+func uploadKey(ctx context.Context, rot *keyRotation, title string, publicKey string, expiresAt time.Time) (string, error) {
 	// Get API token
 	var tokenService string
 	if rot.PlatformType == config.PlatformGitHub {
@@ -377,12 +732,14 @@ func uploadKey(ctx context.Context, rot *keyRotation, publicKey string) (string,
 		if baseURL == "" {
 			baseURL = "https://gitlab.com"
 		}
-		client = api.NewGitLabClient(baseURL, token)
+		client, err = api.NewGitLabClientWithOptions(baseURL, token, rot.Connection)
+		if err != nil {
+			return "", err
+		}
 	}
 
 	// Upload key
-	title := fmt.Sprintf("%s@%s (rotated %s)", rot.Account, rot.MachineName, time.Now().Format("2006-01-02"))
-	remoteID, err := client.AddKey(ctx, title, publicKey)
+	remoteID, err := client.AddKey(ctx, title, publicKey, expiresAt)
 	if err != nil {
 		return "", fmt.Errorf("failed to upload key: %w", err)
 	}
@@ -391,6 +748,16 @@ func uploadKey(ctx context.Context, rot *keyRotation, publicKey string) (string,
 }
 
 func deleteKey(ctx context.Context, rot *keyRotation, keyID string) error {
+	client, err := rotationClient(rot)
+	if err != nil {
+		return err
+	}
+
+	return client.DeleteKey(ctx, keyID)
+}
+
+// rotationClient creates an API client for the platform of a rotation
+func rotationClient(rot *keyRotation) (api.PlatformClient, error) {
 	// Get API token
 	var tokenService string
 	if rot.PlatformType == config.PlatformGitHub {
@@ -404,28 +771,22 @@ func deleteKey(ctx context.Context, rot *keyRotation, keyID string) error {
 	if err != nil {
 		token, err = tokenMgr.GetToken("default")
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
 
 	// Create client
-	var client api.PlatformClient
 	if rot.PlatformType == config.PlatformGitHub {
-		client = api.NewGitHubClient(token)
-	} else {
-		baseURL := rot.BaseURL
-		if baseURL == "" {
-			baseURL = "https://gitlab.com"
-		}
-		client = api.NewGitLabClient(baseURL, token)
+		return api.NewGitHubClient(token), nil
 	}
-
-	return client.DeleteKey(ctx, keyID)
+	baseURL := rot.BaseURL
+	if baseURL == "" {
+		baseURL = "https://gitlab.com"
+	}
+	return api.NewGitLabClientWithOptions(baseURL, token, rot.Connection)
 }
 
-func updateSSHConfigForRotation(rot *keyRotation, sshDir string) error {
-	configPath := filepath.Join(sshDir, "config")
-	mgr := sshconfig.NewManager(configPath)
+func updateSSHConfigForRotation(rot *keyRotation, mgr *sshconfig.Manager, keyMgr *sshkey.Manager) error {
 
 	// Determine host
 	var host string
@@ -444,8 +805,9 @@ func updateSSHConfigForRotation(rot *keyRotation, sshDir string) error {
 	entry := sshconfig.Entry{
 		Host:         host,
 		HostName:     host,
-		IdentityFile: rot.NewKey.LocalPath,
+		IdentityFile: keyMgr.IdentityFilePath(rot.NewKey.LocalPath),
 		User:         "git",
+		Extra:        rot.SSHOptions,
 	}
 
 	blockID := fmt.Sprintf("git-keys-%s-%s", rot.PersonaName, rot.PlatformType)
@@ -469,7 +831,9 @@ func validateSSHKey(rot *keyRotation) error {
 	}
 
 	// Test SSH connection (should fail with "successfully authenticated" message)
-	cmd := exec.Command("ssh", "-T", "-o", "StrictHostKeyChecking=no", "-o", "ConnectTimeout=10", sshHost)
+	// BatchMode fails instead of asking to trust a host whose key is not in
+	// known_hosts
+	cmd := exec.Command("ssh", "-T", "-o", "BatchMode=yes", "-o", "ConnectTimeout=10", sshHost)
 	output, err := cmd.CombinedOutput()
 
 	outputStr := string(output)
@@ -489,22 +853,24 @@ func validateSSHKey(rot *keyRotation) error {
 	return nil
 }
 
-func archiveOldKey(keyPath string, sshDir string) error {
+// archiveOldKey moves a key pair into the archive directory and returns the
+// new path of the private key
+func archiveOldKey(keyPath string, keysDir string) (string, error) {
 	timestamp := time.Now().Format("2006-01-02")
-	archiveDir := filepath.Join(sshDir, "archive")
+	archiveDir := filepath.Join(keysDir, "archive")
 
 	// Create archive directory if needed
 	if err := os.MkdirAll(archiveDir, 0700); err != nil {
-		return fmt.Errorf("failed to create archive directory: %w", err)
+		return "", fmt.Errorf("failed to create archive directory: %w", err)
 	}
 
 	// Move private key
-	oldPrivate := filepath.Join(sshDir, keyPath)
+	oldPrivate := filepath.Join(keysDir, keyPath)
 	newPrivate := filepath.Join(archiveDir, filepath.Base(keyPath)+".old-"+timestamp)
 
 	if err := os.Rename(oldPrivate, newPrivate); err != nil {
 		if !os.IsNotExist(err) {
-			return fmt.Errorf("failed to archive private key: %w", err)
+			return "", fmt.Errorf("failed to archive private key: %w", err)
 		}
 	}
 
@@ -518,5 +884,101 @@ func archiveOldKey(keyPath string, sshDir string) error {
 		}
 	}
 
-	return nil
+	return newPrivate, nil
+}
+
+// Statuses of keys in a RotationResult document
+const (
+	rotationPlanned   = "planned"
+	rotationCancelled = "cancelled"
+	rotationRotated   = "rotated"
+	rotationFailed    = "failed"
+)
+
+// rotationReport is the RotationResult document of
+// 'rotate --output json|yaml'
+type rotationReport struct {
+	DryRun    bool                `json:"dry_run"`
+	Keys      []rotationReportKey `json:"keys"`
+	Succeeded int                 `json:"succeeded"`
+	Failed    int                 `json:"failed"`
+}
+
+// rotationReportKey is a key or deploy key selected for rotation
+type rotationReportKey struct {
+	Persona        string    `json:"persona,omitempty"`
+	DeployKey      string    `json:"deploy_key,omitempty"` // Repository of a deploy key
+	Platform       string    `json:"platform"`
+	Account        string    `json:"account,omitempty"`
+	Status         string    `json:"status"` // planned, cancelled, rotated or failed
+	OldFingerprint string    `json:"old_fingerprint"`
+	NewFingerprint string    `json:"new_fingerprint,omitempty"`
+	ExpiresAt      time.Time `json:"expires_at,omitzero"` // Of the new key
+	Error          string    `json:"error,omitempty"`
+}
+
+// newRotationReport describes the rotations. status is the status of every
+// key when none were executed; otherwise deployBefore holds the deploy keys'
+// fingerprints before the rotation.
+func newRotationReport(cfg *config.Config, rotations []keyRotation, deployRotations []*config.DeployKey, deployBefore map[*config.DeployKey]string, status string) rotationReport {
+	report := rotationReport{DryRun: rotateDryRun, Keys: []rotationReportKey{}}
+
+	for i := range rotations {
+		rot := &rotations[i]
+		key := rotationReportKey{
+			Persona:        rot.PersonaName,
+			Platform:       string(rot.PlatformType),
+			Account:        rot.Account,
+			Status:         status,
+			OldFingerprint: rot.OldKey.Fingerprint,
+		}
+		if status == "" {
+			key.Status = rotationRotated
+			if rot.Err != nil || rot.NewKey == nil {
+				key.Status = rotationFailed
+			}
+		}
+		switch key.Status {
+		case rotationRotated:
+			key.NewFingerprint = rot.NewKey.Fingerprint
+			key.ExpiresAt = rot.NewKey.ExpiresAt
+			report.Succeeded++
+		case rotationFailed:
+			if rot.Err != nil {
+				key.Error = rot.Err.Error()
+			}
+			report.Failed++
+		}
+		report.Keys = append(report.Keys, key)
+	}
+
+	for _, dk := range deployRotations {
+		active := dk.GetActiveKey()
+		key := rotationReportKey{
+			DeployKey: dk.Repo,
+			Platform:  string(dk.Platform),
+			Account:   dk.Account,
+			Status:    status,
+		}
+		if active != nil {
+			key.OldFingerprint = active.Fingerprint
+		}
+		if status == "" {
+			key.OldFingerprint = deployBefore[dk]
+			key.Status = rotationFailed
+			if active != nil && active.Fingerprint != key.OldFingerprint {
+				key.Status = rotationRotated
+				key.NewFingerprint = active.Fingerprint
+				key.ExpiresAt = active.ExpiresAt
+			}
+		}
+		switch key.Status {
+		case rotationRotated:
+			report.Succeeded++
+		case rotationFailed:
+			report.Failed++
+		}
+		report.Keys = append(report.Keys, key)
+	}
+	return report
 }
@@ -2,6 +2,7 @@ package commands
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
@@ -23,40 +24,55 @@ var (
 	scanPath        string
 	scanCheckRemote bool
 	scanJSON        bool
+	scanFromArchive string
+	scanMigrateWeak bool
+	scanYes         bool
 )
 
 // DiscoveredKey represents a found SSH key
 type DiscoveredKey struct {
-	Path        string
-	Type        string
-	Bits        int
-	Fingerprint string
-	Comment     string
-	Created     time.Time
-	UsedBy      []string // SSH config hosts using this key
-	InAgent     bool
-	OnGitHub    bool
-	OnGitLab    bool
-}
-
-// ScanResult holds all discovered information
+	Path        string    `json:"path"`
+	Type        string    `json:"type"`
+	Bits        int       `json:"bits,omitempty"`
+	Fingerprint string    `json:"fingerprint"`
+	Comment     string    `json:"comment,omitempty"`
+	Created     time.Time `json:"created,omitzero"`
+	CreatedFrom string    `json:"created_from,omitempty"` // "config", "comment", or "mtime" (approximate)
+	UsedBy      []string  `json:"used_by"`                // SSH config hosts using this key
+	InAgent     bool      `json:"in_agent"`
+	OnGitHub    bool      `json:"on_github"`
+	OnGitLab    bool      `json:"on_gitlab"`
+
+	// Usage GitLab reports for the key, with --check-remote
+	GitLabLastUsed time.Time `json:"gitlab_last_used,omitzero"`
+	GitLabUnused   bool      `json:"gitlab_unused,omitempty"` // Not used for defaults.unused_key_days
+
+	// Strength grading (see gradeKey)
+	Grade        string   `json:"grade,omitempty"`    // strong, fair or weak
+	Findings     []string `json:"findings,omitempty"` // Why the key is not graded strong
+	NoPassphrase bool     `json:"no_passphrase,omitempty"`
+}
+
+// ScanResult holds all discovered information. It is also the output of
+// 'scan --json'.
 type ScanResult struct {
-	Keys           []DiscoveredKey
-	SSHConfigHosts []SSHConfigHost
-	GitConfig      GitConfig
+	Keys           []DiscoveredKey `json:"keys"`
+	SSHConfigHosts []SSHConfigHost `json:"ssh_config_hosts"`
+	GitConfig      GitConfig       `json:"git_config"`
 }
 
 type SSHConfigHost struct {
-	Host         string
-	HostName     string
-	IdentityFile string
-	User         string
+	Host         string `json:"host"`
+	HostName     string `json:"hostname,omitempty"`
+	IdentityFile string `json:"identity_file,omitempty"`
+	User         string `json:"user,omitempty"`
+	Source       string `json:"source,omitempty"` // File declaring the host: "config", an included file relative to the SSH directory, or an absolute path
 }
 
 type GitConfig struct {
-	GlobalName  string
-	GlobalEmail string
-	Includes    []GitInclude
+	GlobalName  string       `json:"global_name,omitempty"`
+	GlobalEmail string       `json:"global_email,omitempty"`
+	Includes    []GitInclude `json:"includes"`
 }
 
 type GitInclude struct {
@@ -86,18 +102,50 @@ Scans:
   - Active keys in SSH agent (if running)
   - Remote platform keys (if --check-remote and credentials available)
 
-This helps you understand your current setup before migration.`,
-	RunE: runScan,
+This helps you understand your current setup before migration.
+
+Each key is graded by strength: weak (DSA, or RSA shorter than 3072 bits),
+fair (no passphrase or no comment) or strong. With --migrate-weak, weak keys
+that git-keys manages are rotated to ed25519 replacements in one go; weak
+keys it does not manage yet are listed for 'git-keys import'.
+
+With --from-archive, an exported bundle (tar/zip of ~/.ssh and ~/.gitconfig)
+is analyzed offline instead of the local machine. Nothing in the bundle is
+executed and the local SSH agent is not queried, so helpdesk staff can
+diagnose a colleague's setup from a sanitized snapshot.
+
+Examples:
+  # Scan this machine
+  git-keys scan
+
+  # Analyze a colleague's exported setup
+  git-keys scan --from-archive ~/Downloads/ssh-bundle.tar.gz
+
+  # Replace weak managed keys with ed25519 keys
+  git-keys scan --migrate-weak`,
+	Annotations: map[string]string{structuredOutputAnnotation: structuredQuiet},
+	RunE:        runScan,
 }
 
 func init() {
 	scanCmd.Flags().StringVar(&scanPath, "path", filepath.Join(os.Getenv("HOME"), ".ssh"), "SSH directory to scan")
 	scanCmd.Flags().BoolVar(&scanCheckRemote, "check-remote", false, "Query GitHub/GitLab for registered keys (requires tokens)")
 	scanCmd.Flags().BoolVar(&scanJSON, "json", false, "Output as JSON")
+	scanCmd.Flags().StringVar(&scanFromArchive, "from-archive", "", "Analyze an exported tar/zip bundle of ~/.ssh and gitconfig offline")
+	scanCmd.Flags().BoolVar(&scanMigrateWeak, "migrate-weak", false, "Rotate weak managed keys to ed25519 replacements")
+	scanCmd.Flags().BoolVarP(&scanYes, "yes", "y", false, "Skip confirmation prompt for --migrate-weak")
 	rootCmd.AddCommand(scanCmd)
 }
 
 func runScan(cmd *cobra.Command, args []string) error {
+	if scanFromArchive != "" {
+		return runArchiveScan(scanFromArchive)
+	}
+
+	if scanMigrateWeak && (scanJSON || structuredOutput()) {
+		return fmt.Errorf("--migrate-weak cannot be used with --json or --output")
+	}
+
 	logger.Info("Scanning SSH configuration...")
 
 	result := &ScanResult{}
@@ -108,6 +156,7 @@ func runScan(cmd *cobra.Command, args []string) error {
 		logger.Warn("Failed to scan SSH keys: %v", err)
 	} else {
 		result.Keys = keys
+		gradeKeys(result.Keys)
 	}
 
 	// Parse SSH config
@@ -140,15 +189,52 @@ func runScan(cmd *cobra.Command, args []string) error {
 	}
 
 	// Output results
-	if scanJSON {
+	if scanJSON || structuredOutput() {
 		return outputJSON(result)
 	}
 
+	if err := outputHuman(result); err != nil {
+		return err
+	}
+	if scanMigrateWeak {
+		return migrateWeakKeys(result)
+	}
+	return nil
+}
+
+// runArchiveScan analyzes an exported bundle without touching the local setup
+func runArchiveScan(archivePath string) error {
+	if scanCheckRemote {
+		return fmt.Errorf("--check-remote cannot be used with --from-archive")
+	}
+	if scanMigrateWeak {
+		return fmt.Errorf("--migrate-weak cannot be used with --from-archive")
+	}
+
+	logger.Info("Analyzing archive %s (offline)...", archivePath)
+
+	result, sshDir, err := scanArchive(archivePath)
+	if err != nil {
+		return err
+	}
+
+	// Point messages at the bundle instead of the local ~/.ssh
+	scanPath = fmt.Sprintf("%s:%s", filepath.Base(archivePath), sshDir)
+
+	if scanJSON || structuredOutput() {
+		return outputJSON(result)
+	}
+
+	fmt.Println()
+	fmt.Printf("📦 Offline analysis of %s\n", filepath.Base(archivePath))
+	fmt.Println("   Agent status and remote registration are not available for archives.")
+
 	return outputHuman(result)
 }
 
 func scanSSHKeys(sshDir string) ([]DiscoveredKey, error) {
 	var keys []DiscoveredKey
+	managed := managedKeyCreationTimes()
 
 	entries, err := os.ReadDir(sshDir)
 	if err != nil {
@@ -215,20 +301,19 @@ func scanSSHKeys(sshDir string) ([]DiscoveredKey, error) {
 		// Determine bit size
 		bits := getKeyBits(keyType, keyPath)
 
-		// Get file modification time as proxy for creation time
-		info, _ := os.Stat(keyPath)
-		created := info.ModTime()
-
 		key := DiscoveredKey{
 			Path:        keyPath,
 			Type:        keyType,
 			Bits:        bits,
 			Fingerprint: fingerprint,
 			Comment:     comment,
-			Created:     created,
 			UsedBy:      []string{},
 		}
 
+		// File modification time is only a fallback for the creation time
+		info, _ := os.Stat(keyPath)
+		resolveCreation(&key, managed, info.ModTime())
+
 		keys = append(keys, key)
 	}
 
@@ -240,6 +325,76 @@ func scanSSHKeys(sshDir string) ([]DiscoveredKey, error) {
 	return keys, nil
 }
 
+// Sources of a discovered key's creation time
+const (
+	createdFromConfig  = "config"
+	createdFromComment = "comment"
+	createdFromMtime   = "mtime"
+)
+
+// resolveCreation sets the key's creation time from the most reliable source:
+// the git-keys config for managed keys, then a date in the key comment, and
+// finally the file mtime, which dotfile syncs and restores can reset.
+func resolveCreation(key *DiscoveredKey, managed map[string]time.Time, mtime time.Time) {
+	if created, ok := managed[key.Fingerprint]; ok {
+		key.Created = created
+		key.CreatedFrom = createdFromConfig
+		return
+	}
+
+	if created, ok := sshkey.ParseCreationHint(key.Comment); ok {
+		key.Created = created
+		key.CreatedFrom = createdFromComment
+		return
+	}
+
+	key.Created = mtime
+	key.CreatedFrom = createdFromMtime
+}
+
+// managedKeyCreationTimes maps fingerprints of keys tracked in the config to
+// their recorded creation time. Returns an empty map if there is no config.
+func managedKeyCreationTimes() map[string]time.Time {
+	times := make(map[string]time.Time)
+
+	configPath := cfgFile
+	if configPath == "" {
+		configPath = config.GetDefaultConfigPath()
+	}
+
+	mgr := config.NewManager(configPath)
+	if !mgr.Exists() {
+		return times
+	}
+
+	cfg, err := mgr.Load()
+	if err != nil {
+		logger.Debug("Ignoring config for creation times: %v", err)
+		return times
+	}
+
+	for _, persona := range cfg.Personas {
+		for _, platform := range persona.Platforms {
+			for _, key := range platform.Keys {
+				if key.Fingerprint != "" && !key.CreatedAt.IsZero() {
+					times[key.Fingerprint] = key.CreatedAt
+				}
+			}
+		}
+	}
+
+	return times
+}
+
+// formatCreated renders a creation date, marking mtime-based dates as approximate
+func formatCreated(key DiscoveredKey) string {
+	date := key.Created.Format("2006-01-02")
+	if key.CreatedFrom == createdFromMtime {
+		return date + " (approximate, from file mtime)"
+	}
+	return date
+}
+
 func getKeyBits(keyType, keyPath string) int {
 	// For ed25519, it's always 256 bits
 	if strings.Contains(keyType, "ed25519") {
@@ -264,24 +419,57 @@ func getKeyBits(keyType, keyPath string) int {
 	return 0
 }
 
+// sshConfigMaxIncludeDepth is how deep scan follows nested Include
+// directives, as ssh does
+const sshConfigMaxIncludeDepth = 16
+
+// scanSSHConfig lists the hosts with an IdentityFile in the SSH config of
+// sshDir and, recursively, in the files it includes
 func scanSSHConfig(sshDir string) ([]SSHConfigHost, error) {
-	configPath := filepath.Join(sshDir, "config")
+	return scanSSHConfigIn(sshDir, "")
+}
 
-	file, err := os.Open(configPath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return []SSHConfigHost{}, nil
-		}
+// scanSSHConfigIn is scanSSHConfig for an sshDir inside root, the directory
+// an archive was extracted to: Include paths are looked up inside root and
+// no file outside it is read. An empty root scans this machine.
+func scanSSHConfigIn(sshDir, root string) ([]SSHConfigHost, error) {
+	hosts := []SSHConfigHost{}
+	visited := make(map[string]bool)
+	if err := scanSSHConfigFile(sshDir, root, filepath.Join(sshDir, "config"), 0, visited, &hosts); err != nil {
 		return nil, err
 	}
-	defer file.Close()
+	return hosts, nil
+}
 
-	cfg, err := ssh_config.Decode(file)
+// scanSSHConfigFile adds the hosts of one SSH config file, then of the files
+// it includes, in the order ssh reads them
+func scanSSHConfigFile(sshDir, root, path string, depth int, visited map[string]bool, hosts *[]SSHConfigHost) error {
+	if visited[path] {
+		return nil
+	}
+	visited[path] = true
+
+	data, err := os.ReadFile(path)
 	if err != nil {
-		return nil, fmt.Errorf("parsing SSH config: %w", err)
+		if os.IsNotExist(err) && depth == 0 {
+			return nil
+		}
+		if depth > 0 {
+			// An unreadable included file does not hide the rest of the config
+			logger.Warn("Could not read included SSH config %s: %v", path, err)
+			return nil
+		}
+		return err
 	}
 
-	var hosts []SSHConfigHost
+	// The parser would resolve Include directives against $HOME rather than
+	// sshDir and hide the hosts of included files, so follow them here
+	content, includes := extractSSHIncludes(string(data))
+
+	cfg, err := ssh_config.Decode(strings.NewReader(content))
+	if err != nil {
+		return fmt.Errorf("parsing SSH config %s: %w", path, err)
+	}
 
 	for _, host := range cfg.Hosts {
 		// Skip wildcard and managed blocks
@@ -298,7 +486,8 @@ func scanSSHConfig(sshDir string) ([]SSHConfigHost, error) {
 		// (This is a simple check - a more robust version would parse comments)
 
 		hostEntry := SSHConfigHost{
-			Host: pattern,
+			Host:   pattern,
+			Source: sshConfigSource(sshDir, root, path),
 		}
 
 		// Extract HostName
@@ -308,8 +497,9 @@ func scanSSHConfig(sshDir string) ([]SSHConfigHost, error) {
 
 		// Extract IdentityFile
 		if identityFile, err := cfg.Get(pattern, "IdentityFile"); err == nil {
-			// Expand ~ to home directory
-			if strings.HasPrefix(identityFile, "~") {
+			// Expand ~ to home directory; in an archive it is the
+			// colleague's home, which is not on this machine
+			if strings.HasPrefix(identityFile, "~") && root == "" {
 				identityFile = strings.Replace(identityFile, "~", os.Getenv("HOME"), 1)
 			}
 			hostEntry.IdentityFile = identityFile
@@ -321,11 +511,101 @@ func scanSSHConfig(sshDir string) ([]SSHConfigHost, error) {
 		}
 
 		if hostEntry.IdentityFile != "" {
-			hosts = append(hosts, hostEntry)
+			*hosts = append(*hosts, hostEntry)
 		}
 	}
 
-	return hosts, nil
+	if len(includes) > 0 && depth >= sshConfigMaxIncludeDepth {
+		logger.Warn("SSH config includes nest deeper than %d levels; not following those in %s", sshConfigMaxIncludeDepth, path)
+		return nil
+	}
+	for _, pattern := range includes {
+		resolved, ok := resolveSSHInclude(sshDir, root, pattern)
+		if !ok {
+			logger.Warn("Include %q in %s points outside the archive; skipped", pattern, sshConfigSource(sshDir, root, path))
+			continue
+		}
+		matches, err := filepath.Glob(resolved)
+		if err != nil {
+			logger.Warn("Invalid Include pattern %q in %s: %v", pattern, path, err)
+			continue
+		}
+		if len(matches) == 0 && root != "" && (strings.HasPrefix(pattern, "~") || filepath.IsAbs(pattern)) {
+			logger.Warn("Include %q in %s is not in the archive; skipped", pattern, sshConfigSource(sshDir, root, path))
+		}
+		// ssh reads the files of a pattern in lexical order, which Glob keeps
+		for _, match := range matches {
+			if err := scanSSHConfigFile(sshDir, root, match, depth+1, visited, hosts); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// extractSSHIncludes returns the SSH config content with its Include lines
+// commented out, and the file patterns those lines named
+func extractSSHIncludes(content string) (string, []string) {
+	lines := strings.Split(content, "\n")
+	var includes []string
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		idx := strings.IndexAny(trimmed, " \t=")
+		if idx < 0 || !strings.EqualFold(trimmed[:idx], "include") {
+			continue
+		}
+		value := strings.TrimLeft(trimmed[idx:], " \t=")
+		for _, pattern := range strings.Fields(value) {
+			includes = append(includes, strings.Trim(pattern, `"`))
+		}
+		lines[i] = "# " + line
+	}
+	return strings.Join(lines, "\n"), includes
+}
+
+// sshConfigSource names an SSH config file relative to sshDir when it is
+// inside it, so results read the same for an extracted archive; other files
+// of an archive are named relative to its root
+func sshConfigSource(sshDir, root, path string) string {
+	if rel, err := filepath.Rel(sshDir, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	if root != "" {
+		if rel, err := filepath.Rel(root, path); err == nil && !strings.HasPrefix(rel, "..") {
+			return rel
+		}
+	}
+	return path
+}
+
+// resolveSSHInclude returns the path of an Include pattern: ~ is the home
+// directory, and relative paths are taken from sshDir, as ssh takes them
+// from ~/.ssh. Inside an extracted archive (root not empty), ~ is the
+// directory holding sshDir and absolute paths are taken from root; ok is
+// false when the pattern leads outside root.
+func resolveSSHInclude(sshDir, root, pattern string) (resolved string, ok bool) {
+	home := os.Getenv("HOME")
+	if root != "" {
+		home = root
+		if filepath.Base(sshDir) == ".ssh" {
+			home = filepath.Dir(sshDir)
+		}
+	}
+
+	switch {
+	case strings.HasPrefix(pattern, "~/"):
+		resolved = filepath.Join(home, pattern[2:])
+	case filepath.IsAbs(pattern):
+		resolved = filepath.Join(root, pattern)
+	default:
+		resolved = filepath.Join(sshDir, pattern)
+	}
+	if root == "" {
+		return resolved, true
+	}
+	rel, err := filepath.Rel(root, resolved)
+	return resolved, err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
 }
 
 func matchKeysToHosts(result *ScanResult) {
@@ -341,26 +621,17 @@ func matchKeysToHosts(result *ScanResult) {
 }
 
 func checkSSHAgent(result *ScanResult) {
-	// Run ssh-add -l to list keys in agent
-	cmd := exec.Command("ssh-add", "-l")
-	output, err := cmd.Output()
+	agent, err := sshkey.ConnectAgent("")
 	if err != nil {
-		// Agent not running or no keys
+		// Agent not running
 		return
 	}
+	defer agent.Close()
 
-	lines := strings.Split(string(output), "\n")
-	agentFingerprints := make(map[string]bool)
-
-	for _, line := range lines {
-		if line == "" {
-			continue
-		}
-		// Format: "2048 SHA256:... comment (RSA)"
-		fields := strings.Fields(line)
-		if len(fields) >= 2 {
-			agentFingerprints[fields[1]] = true
-		}
+	agentFingerprints, err := agent.Fingerprints()
+	if err != nil {
+		logger.Debug("Could not list keys in the SSH agent: %v", err)
+		return
 	}
 
 	// Match agent fingerprints to discovered keys
@@ -614,7 +885,7 @@ func checkRemotePlatforms(result *ScanResult) error {
 	if err == nil && githubToken != "" {
 		logger.Info("Checking GitHub for registered keys...")
 		client := api.NewGitHubClient(githubToken)
-		remoteKeys, err := client.ListKeys(ctx)
+		remoteKeys, _, err := api.ListKeysCached(ctx, client, refreshRemote)
 		if err != nil {
 			logger.Warn("Failed to list GitHub keys: %v", err)
 		} else {
@@ -631,13 +902,15 @@ func checkRemotePlatforms(result *ScanResult) error {
 		// Try to load config to get GitLab base URL
 		configPath := config.GetDefaultConfigPath()
 		mgr := config.NewManager(configPath)
-		baseURL := "https://gitlab.com"
+		gitlabPlatform := &config.Platform{Type: config.PlatformGitLab}
+		unusedDays := defaultUnusedKeyDays
 		if mgr.Exists() {
 			if cfg, err := mgr.Load(); err == nil {
+				unusedDays = unusedKeyDays(cfg)
 				for _, persona := range cfg.Personas {
 					for _, platform := range persona.Platforms {
 						if platform.Type == config.PlatformGitLab && platform.BaseURL != "" {
-							baseURL = platform.BaseURL
+							gitlabPlatform = &platform
 							break
 						}
 					}
@@ -645,12 +918,16 @@ func checkRemotePlatforms(result *ScanResult) error {
 			}
 		}
 
-		client := api.NewGitLabClient(baseURL, gitlabToken)
-		remoteKeys, err := client.ListKeys(ctx)
+		var remoteKeys []api.SSHKey
+		client, err := newGitLabClient(gitlabPlatform, gitlabToken)
+		if err == nil {
+			remoteKeys, _, err = api.ListKeysCached(ctx, client, refreshRemote)
+		}
 		if err != nil {
 			logger.Warn("Failed to list GitLab keys: %v", err)
 		} else {
 			matchRemoteKeys(result, remoteKeys, "GitLab")
+			matchRemoteUsage(result, remoteKeys, unusedDays)
 		}
 	}
 
@@ -663,9 +940,8 @@ func matchRemoteKeys(result *ScanResult, remoteKeys []api.SSHKey, platform strin
 		for _, remote := range remoteKeys {
 			// Compare fingerprints (strip "SHA256:" prefix if present)
 			localFP := strings.TrimPrefix(key.Fingerprint, "SHA256:")
-			remoteFP := strings.TrimPrefix(remote.Fingerprint, "SHA256:")
 
-			if localFP == remoteFP {
+			if localFP == remoteFingerprint(remote) {
 				if platform == "GitHub" {
 					key.OnGitHub = true
 				} else if platform == "GitLab" {
@@ -677,10 +953,24 @@ func matchRemoteKeys(result *ScanResult, remoteKeys []api.SSHKey, platform strin
 	}
 }
 
+// matchRemoteUsage records the usage GitLab reports for each scanned key
+func matchRemoteUsage(result *ScanResult, remoteKeys []api.SSHKey, unusedDays int) {
+	for i := range result.Keys {
+		key := &result.Keys[i]
+		for _, remote := range remoteKeys {
+			if strings.TrimPrefix(key.Fingerprint, "SHA256:") == remoteFingerprint(remote) {
+				usage := remoteKeyUsage(remote)
+				key.GitLabLastUsed = usage.LastUsed
+				key.GitLabUnused = usage.Unused(unusedDays)
+				break
+			}
+		}
+	}
+}
+
 func outputHuman(result *ScanResult) error {
 	fmt.Println()
-	fmt.Println("🔍 SSH Configuration Scan Results")
-	fmt.Println("==================================")
+	printHeader("🔍 SSH Configuration Scan Results")
 	fmt.Println()
 
 	// SSH Keys
@@ -690,17 +980,23 @@ func outputHuman(result *ScanResult) error {
 		fmt.Printf("Found %d SSH key(s):\n\n", len(result.Keys))
 
 		for _, key := range result.Keys {
-			status := "✓"
+			level := checkOK
 			if len(key.UsedBy) == 0 && !key.InAgent {
-				status = "⚠"
+				level = checkWarn
 			}
 
-			fmt.Printf("  %s %s (%s, %d bits)\n", status, filepath.Base(key.Path), key.Type, key.Bits)
+			fmt.Printf("  %s (%s, %d bits)\n", checkText(level, filepath.Base(key.Path)), key.Type, key.Bits)
 			fmt.Printf("    Fingerprint: %s\n", key.Fingerprint)
 			if key.Comment != "" {
 				fmt.Printf("    Comment: %s\n", key.Comment)
 			}
-			fmt.Printf("    Created: %s\n", key.Created.Format("2006-01-02"))
+			fmt.Printf("    Created: %s\n", formatCreated(key))
+			if key.Grade != "" {
+				fmt.Printf("    Strength: %s\n", key.Grade)
+				for _, finding := range key.Findings {
+					fmt.Printf("    %s\n", checkText(checkWarn, finding))
+				}
+			}
 
 			if len(key.UsedBy) > 0 {
 				fmt.Printf("    Used by: %s\n", strings.Join(key.UsedBy, ", "))
@@ -723,10 +1019,17 @@ func outputHuman(result *ScanResult) error {
 				} else {
 					fmt.Println("    Remote: Not found on any platform")
 				}
+				if key.OnGitLab {
+					usage := keyUsage{LastUsed: key.GitLabLastUsed}
+					fmt.Printf("    GitLab: %s\n", usage)
+					if key.GitLabUnused {
+						fmt.Println("    " + checkText(checkWarn, "Not used on GitLab recently; prune candidate"))
+					}
+				}
 			}
 
 			if len(key.UsedBy) == 0 && !key.InAgent {
-				fmt.Println("    ⚠ Not referenced in SSH config or agent")
+				fmt.Println("    " + checkText(checkWarn, "Not referenced in SSH config or agent"))
 				fmt.Println("    Recommendation: Archive or delete")
 			}
 
@@ -740,7 +1043,11 @@ func outputHuman(result *ScanResult) error {
 		fmt.Println()
 
 		for _, host := range result.SSHConfigHosts {
-			fmt.Printf("  Host %s\n", host.Host)
+			fmt.Printf("  Host %s", host.Host)
+			if host.Source != "" && host.Source != "config" {
+				fmt.Printf("  (from %s)", host.Source)
+			}
+			fmt.Println()
 			if host.HostName != "" && host.HostName != host.Host {
 				fmt.Printf("    HostName %s\n", host.HostName)
 			}
@@ -772,14 +1079,46 @@ func outputHuman(result *ScanResult) error {
 	fmt.Println("Recommendation:")
 	fmt.Println("  Run: git-keys import --interactive")
 	fmt.Println("  This will help you adopt existing keys into git-keys management.")
+	for _, key := range result.Keys {
+		if key.Grade == keyGradeWeak {
+			fmt.Println("  Replace weak keys with ed25519 keys: git-keys scan --migrate-weak")
+			break
+		}
+	}
 	fmt.Println()
 
 	return nil
 }
 
+// outputJSON prints the scan result as JSON, or as a ScanResult document
+// with --output. Empty lists are printed as [] rather than null, so
+// consumers can rely on the shape.
 func outputJSON(result *ScanResult) error {
-	// For now, just print a simple message
-	// In a full implementation, this would marshal result to JSON
-	fmt.Println("JSON output not yet implemented")
+	out := *result
+	if out.Keys == nil {
+		out.Keys = []DiscoveredKey{}
+	}
+	out.Keys = append([]DiscoveredKey(nil), out.Keys...)
+	for i := range out.Keys {
+		if out.Keys[i].UsedBy == nil {
+			out.Keys[i].UsedBy = []string{}
+		}
+	}
+	if out.SSHConfigHosts == nil {
+		out.SSHConfigHosts = []SSHConfigHost{}
+	}
+	if out.GitConfig.Includes == nil {
+		out.GitConfig.Includes = []GitInclude{}
+	}
+
+	if structuredOutput() {
+		return printStructured("ScanResult", out)
+	}
+
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode scan result: %w", err)
+	}
+	fmt.Println(string(data))
 	return nil
 }
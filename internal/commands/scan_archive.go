@@ -0,0 +1,352 @@
+package commands
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kunlu/git-keys/internal/logger"
+	"github.com/kunlu/git-keys/internal/sshkey"
+)
+
+// maxArchiveFileSize caps individual files extracted from a support bundle
+const maxArchiveFileSize = 10 << 20
+
+// scanArchive analyzes an exported ~/.ssh + gitconfig bundle offline.
+// Nothing inside the bundle is executed and the live agent is never queried.
+func scanArchive(archivePath string) (*ScanResult, string, error) {
+	tmpDir, err := os.MkdirTemp("", "git-keys-scan-")
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	if err := extractArchive(archivePath, tmpDir); err != nil {
+		return nil, "", fmt.Errorf("failed to extract archive: %w", err)
+	}
+
+	sshDir := findArchivedSSHDir(tmpDir)
+	logger.Debug("Using %s as SSH directory from archive", sshDir)
+
+	result := &ScanResult{}
+
+	keys, err := scanArchivedSSHKeys(sshDir)
+	if err != nil {
+		logger.Warn("Failed to scan SSH keys in archive: %v", err)
+	} else {
+		result.Keys = keys
+		gradeKeys(result.Keys)
+	}
+
+	// Includes of ~ or absolute paths must not read the files of this machine
+	hosts, err := scanSSHConfigIn(sshDir, tmpDir)
+	if err != nil {
+		logger.Warn("Failed to parse SSH config in archive: %v", err)
+	} else {
+		result.SSHConfigHosts = hosts
+	}
+
+	// IdentityFile entries refer to the colleague's home, so match by file name
+	for i := range result.Keys {
+		key := &result.Keys[i]
+		for _, host := range result.SSHConfigHosts {
+			if filepath.Base(host.IdentityFile) == filepath.Base(key.Path) {
+				key.UsedBy = append(key.UsedBy, host.Host)
+			}
+		}
+	}
+
+	if gitConfigPath := findArchivedFile(tmpDir, ".gitconfig", "gitconfig"); gitConfigPath != "" {
+		result.GitConfig = scanArchivedGitConfig(gitConfigPath, tmpDir)
+	}
+
+	// Report paths relative to the bundle rather than the temp directory
+	for i := range result.Keys {
+		if rel, err := filepath.Rel(tmpDir, result.Keys[i].Path); err == nil {
+			result.Keys[i].Path = rel
+		}
+	}
+
+	rel, _ := filepath.Rel(tmpDir, sshDir)
+	return result, rel, nil
+}
+
+// extractArchive unpacks a .tar, .tar.gz/.tgz, or .zip file into destDir
+func extractArchive(archivePath, destDir string) error {
+	lower := strings.ToLower(archivePath)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		return extractZip(archivePath, destDir)
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		f, err := os.Open(archivePath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return fmt.Errorf("reading gzip: %w", err)
+		}
+		defer gz.Close()
+		return extractTar(gz, destDir)
+	case strings.HasSuffix(lower, ".tar"):
+		f, err := os.Open(archivePath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return extractTar(f, destDir)
+	default:
+		return fmt.Errorf("unsupported archive format: %s (expected .tar, .tar.gz, .tgz or .zip)", filepath.Base(archivePath))
+	}
+}
+
+func extractTar(r io.Reader, destDir string) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("reading tar: %w", err)
+		}
+
+		// Only regular files are extracted; symlinks and devices are ignored
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		if err := writeArchiveEntry(destDir, hdr.Name, tr, hdr.Size, hdr.ModTime); err != nil {
+			return err
+		}
+	}
+}
+
+func extractZip(archivePath, destDir string) error {
+	zr, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return fmt.Errorf("reading zip: %w", err)
+	}
+	defer zr.Close()
+
+	for _, f := range zr.File {
+		if !f.Mode().IsRegular() {
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", f.Name, err)
+		}
+		err = writeArchiveEntry(destDir, f.Name, rc, int64(f.UncompressedSize64), f.Modified)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeArchiveEntry writes a single archive member, rejecting paths that escape destDir
+func writeArchiveEntry(destDir, name string, r io.Reader, size int64, modTime time.Time) error {
+	if size > maxArchiveFileSize {
+		logger.Debug("Skipping oversized archive entry: %s", name)
+		return nil
+	}
+
+	target := filepath.Join(destDir, filepath.Clean("/"+name))
+	if !strings.HasPrefix(target, filepath.Clean(destDir)+string(os.PathSeparator)) {
+		return fmt.Errorf("invalid path in archive: %s", name)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), 0700); err != nil {
+		return fmt.Errorf("creating directory for %s: %w", name, err)
+	}
+
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("creating %s: %w", name, err)
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, io.LimitReader(r, maxArchiveFileSize)); err != nil {
+		return fmt.Errorf("extracting %s: %w", name, err)
+	}
+
+	// Preserve the original timestamp so key ages stay meaningful
+	if !modTime.IsZero() {
+		os.Chtimes(target, modTime, modTime)
+	}
+	return nil
+}
+
+// findArchivedSSHDir locates the .ssh directory inside an extracted bundle,
+// falling back to the bundle root
+func findArchivedSSHDir(root string) string {
+	found := ""
+	filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || found != "" {
+			return nil
+		}
+		if info.IsDir() && (info.Name() == ".ssh" || info.Name() == "ssh") {
+			found = path
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if found == "" {
+		return root
+	}
+	return found
+}
+
+// findArchivedFile returns the first file in the bundle matching one of the names
+func findArchivedFile(root string, names ...string) string {
+	var matches []string
+	filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return nil
+		}
+		for _, name := range names {
+			if info.Name() == name {
+				matches = append(matches, path)
+			}
+		}
+		return nil
+	})
+	if len(matches) == 0 {
+		return ""
+	}
+	// Prefer the shallowest match
+	sort.Slice(matches, func(i, j int) bool {
+		return strings.Count(matches[i], string(os.PathSeparator)) < strings.Count(matches[j], string(os.PathSeparator))
+	})
+	return matches[0]
+}
+
+// scanArchivedSSHKeys discovers key pairs by parsing public keys in-process
+func scanArchivedSSHKeys(sshDir string) ([]DiscoveredKey, error) {
+	var keys []DiscoveredKey
+
+	entries, err := os.ReadDir(sshDir)
+	if err != nil {
+		return nil, fmt.Errorf("reading SSH directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".pub") {
+			continue
+		}
+
+		pubPath := filepath.Join(sshDir, entry.Name())
+		data, err := os.ReadFile(pubPath)
+		if err != nil {
+			continue
+		}
+
+		info, err := sshkey.ParsePublicKey(string(data))
+		if err != nil {
+			logger.Debug("Skipping %s: %v", entry.Name(), err)
+			continue
+		}
+
+		keyPath := strings.TrimSuffix(pubPath, ".pub")
+		mtime := fileModTime(pubPath)
+		if st, err := os.Stat(keyPath); err == nil {
+			mtime = st.ModTime()
+		}
+
+		key := DiscoveredKey{
+			Path:        keyPath,
+			Type:        info.Type,
+			Bits:        info.Bits,
+			Fingerprint: info.Fingerprint,
+			Comment:     info.Comment,
+			UsedBy:      []string{},
+		}
+		resolveCreation(&key, nil, mtime)
+
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].Created.After(keys[j].Created)
+	})
+
+	return keys, nil
+}
+
+// scanArchivedGitConfig reads identity and includeIf entries from a bundled gitconfig
+func scanArchivedGitConfig(gitConfigPath, root string) GitConfig {
+	gitConf := GitConfig{}
+
+	data, err := os.ReadFile(gitConfigPath)
+	if err != nil {
+		return gitConf
+	}
+
+	gitConf.GlobalName, gitConf.GlobalEmail = parseGitUserSection(string(data))
+
+	includePattern := regexp.MustCompile(`\[includeIf "gitdir:([^"]+)"\]\s+path\s*=\s*(.+)`)
+	for _, match := range includePattern.FindAllStringSubmatch(string(data), -1) {
+		inc := GitInclude{
+			Condition: strings.TrimSpace(match[1]),
+			Path:      strings.TrimSpace(match[2]),
+		}
+
+		// Included paths point at the colleague's home; look them up by name
+		if included := findArchivedFile(root, filepath.Base(inc.Path)); included != "" {
+			if includeData, err := os.ReadFile(included); err == nil {
+				inc.Name, inc.Email = parseGitUserSection(string(includeData))
+			}
+		}
+
+		gitConf.Includes = append(gitConf.Includes, inc)
+	}
+
+	return gitConf
+}
+
+// parseGitUserSection extracts user.name and user.email from gitconfig content
+func parseGitUserSection(content string) (string, string) {
+	var name, email string
+	inUser := false
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "[") {
+			inUser = strings.EqualFold(trimmed, "[user]")
+			continue
+		}
+		if !inUser {
+			continue
+		}
+		parts := strings.SplitN(trimmed, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		switch strings.ToLower(strings.TrimSpace(parts[0])) {
+		case "name":
+			name = strings.TrimSpace(parts[1])
+		case "email":
+			email = strings.TrimSpace(parts[1])
+		}
+	}
+	return name, email
+}
+
+// fileModTime returns the modification time of a file, or the zero time
+func fileModTime(path string) time.Time {
+	if st, err := os.Stat(path); err == nil {
+		return st.ModTime()
+	}
+	return time.Time{}
+}
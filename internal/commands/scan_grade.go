@@ -0,0 +1,158 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/kunlu/git-keys/internal/config"
+	"github.com/kunlu/git-keys/internal/logger"
+	"github.com/kunlu/git-keys/internal/platform"
+	"github.com/kunlu/git-keys/internal/prompt"
+	"github.com/kunlu/git-keys/internal/sshkey"
+)
+
+// Strength grades of discovered keys
+const (
+	keyGradeStrong = "strong"
+	keyGradeFair   = "fair" // Sound algorithm, but no passphrase or comment
+	keyGradeWeak   = "weak" // DSA, or RSA shorter than recommendedRSABits
+)
+
+// gradeKeys grades the strength of each discovered key
+func gradeKeys(keys []DiscoveredKey) {
+	for i := range keys {
+		gradeKey(&keys[i])
+	}
+}
+
+// gradeKey grades a key by its algorithm and size, whether its private key
+// has a passphrase and whether it has a comment, and records why
+func gradeKey(key *DiscoveredKey) {
+	key.Grade = keyGradeStrong
+	key.Findings = nil
+
+	switch {
+	case key.Type == "ssh-dss":
+		key.Grade = keyGradeWeak
+		key.Findings = append(key.Findings, "DSA keys are disabled by default since OpenSSH 7.0")
+	case key.Type == "ssh-rsa" && key.Bits > 0 && key.Bits < recommendedRSABits:
+		key.Grade = keyGradeWeak
+		key.Findings = append(key.Findings, fmt.Sprintf("RSA %d bits (minimum %d)", key.Bits, recommendedRSABits))
+	}
+
+	if encrypted, err := sshkey.PrivateKeyEncrypted(key.Path); err != nil {
+		logger.Debug("Cannot tell whether %s has a passphrase: %v", key.Path, err)
+	} else if !encrypted {
+		key.NoPassphrase = true
+		key.Findings = append(key.Findings, "No passphrase; protected by file permissions only")
+	}
+
+	if key.Comment == "" {
+		key.Findings = append(key.Findings, "No comment; hard to tell where the key is used once uploaded")
+	}
+
+	if key.Grade == keyGradeStrong && len(key.Findings) > 0 {
+		key.Grade = keyGradeFair
+	}
+}
+
+// migrateWeakKeys rotates the weak keys found by scan that git-keys manages,
+// replacing them with ed25519 keys
+func migrateWeakKeys(result *ScanResult) error {
+	var weak []DiscoveredKey
+	for _, key := range result.Keys {
+		if key.Grade == keyGradeWeak {
+			weak = append(weak, key)
+		}
+	}
+	if len(weak) == 0 {
+		fmt.Println("No weak keys to migrate.")
+		return nil
+	}
+
+	configPath := cfgFile
+	if configPath == "" {
+		configPath = config.GetDefaultConfigPath()
+	}
+
+	mgr := config.NewManager(configPath)
+	if !mgr.Exists() {
+		fmt.Printf("%d weak key(s) found, but git-keys manages no keys yet.\n", len(weak))
+		fmt.Println("💡 Adopt them with 'git-keys adopt' or 'git-keys import', then run 'git-keys scan --migrate-weak' again.")
+		return nil
+	}
+
+	cfg, err := mgr.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	printHeader("\n🔐 Migrate Weak Keys")
+	var rotations []keyRotation
+	for _, key := range weak {
+		matched := false
+		for personaIdx := range cfg.Personas {
+			persona := &cfg.Personas[personaIdx]
+			if persona.Archived {
+				continue
+			}
+			for platformIdx := range persona.Platforms {
+				p := &persona.Platforms[platformIdx]
+				for keyIdx := range p.Keys {
+					k := &p.Keys[keyIdx]
+					if !k.InUse() || k.Fingerprint != key.Fingerprint || !k.HasPrivateKey() {
+						continue
+					}
+					matched = true
+					rot := newKeyRotation(cfg, personaIdx, platformIdx, keyIdx, "")
+					rot.KeyType = config.KeyTypeED25519
+					rotations = append(rotations, rot)
+				}
+			}
+		}
+		if !matched {
+			fmt.Printf("⊘ %s: not managed by git-keys; adopt it with 'git-keys adopt' to migrate it\n", filepath.Base(key.Path))
+		}
+	}
+
+	if len(rotations) == 0 {
+		fmt.Println("\nNo managed keys to migrate.")
+		return nil
+	}
+
+	plat, err := platform.NewPlatform()
+	if err != nil {
+		return fmt.Errorf("failed to get platform info: %w", err)
+	}
+	machineName, err := plat.GetMachineName()
+	if err != nil {
+		machineName = "unknown"
+	}
+	for i := range rotations {
+		rotations[i].MachineName = machineName
+	}
+
+	printRotations(context.Background(), cfg, rotations, nil)
+
+	if !scanYes {
+		if !prompt.Confirm("Replace these keys with ed25519 keys?") {
+			fmt.Println("Migration cancelled.")
+			return nil
+		}
+	}
+
+	if err := executeRotations(context.Background(), cfg, mgr, configPath, rotations, nil, defaultParallel); err != nil {
+		return err
+	}
+
+	// Later rotations follow the configured key type again
+	for _, rot := range rotations {
+		persona := &cfg.Personas[rot.PersonaIdx]
+		if settings := cfg.ResolveKeySettings(persona, &persona.Platforms[rot.PlatformIdx]); settings.Type != config.KeyTypeED25519 {
+			fmt.Printf("💡 %s/%s is configured for %s keys; set key_type: ed25519 so later rotations keep ed25519.\n",
+				rot.PersonaName, rot.PlatformType, settings.Type)
+		}
+	}
+	return nil
+}
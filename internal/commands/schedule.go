@@ -0,0 +1,288 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/kunlu/git-keys/internal/config"
+	"github.com/kunlu/git-keys/internal/history"
+	"github.com/kunlu/git-keys/internal/logger"
+	"github.com/kunlu/git-keys/internal/profile"
+	"github.com/kunlu/git-keys/internal/schedule"
+	"github.com/spf13/cobra"
+)
+
+var (
+	scheduleEvery time.Duration
+	scheduleLog   string
+)
+
+var scheduleCmd = &cobra.Command{
+	Use:   "schedule",
+	Short: "Rotate keys automatically on a schedule",
+	Long: `Run 'git-keys rotate --due --yes' periodically, so keys are rotated before
+they expire without anyone having to remember.
+
+On macOS this installs a LaunchAgent (~/Library/LaunchAgents), on Linux a
+systemd user timer (~/.config/systemd/user). Each run rotates only the keys
+that expire within defaults.rotate_within (14 days by default); a key's expiry
+is its expires_at, or its creation date plus key_expiration. Output of every run is appended to a log file
+(~/.git-keys/logs/rotate.log by default).
+
+Scheduling requires defaults.auto_rotate: true in the config; each run checks
+it again and rotates nothing once it is false. API tokens must
+be available without a prompt (keychain or .env), since nobody is there to
+answer one.
+
+Subcommands:
+  install    - Install or update the scheduled job
+  uninstall  - Remove the scheduled job
+  status     - Show whether the job is installed and which keys are due
+
+Examples:
+  git-keys schedule install
+  git-keys schedule install --every 12h
+  git-keys schedule status
+`,
+}
+
+var scheduleInstallCmd = &cobra.Command{
+	Use:          "install",
+	Short:        "Install or update the scheduled rotation job",
+	RunE:         runScheduleInstall,
+	SilenceUsage: true,
+}
+
+var scheduleUninstallCmd = &cobra.Command{
+	Use:          "uninstall",
+	Short:        "Remove the scheduled rotation job",
+	RunE:         runScheduleUninstall,
+	SilenceUsage: true,
+}
+
+var scheduleStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the scheduled rotation job and the keys due",
+	RunE:  runScheduleStatus,
+}
+
+func init() {
+	scheduleInstallCmd.Flags().DurationVar(&scheduleEvery, "every", 24*time.Hour, "Time between runs (at most the due window, defaults.rotate_within)")
+	scheduleInstallCmd.Flags().StringVar(&scheduleLog, "log", "", "Log file of scheduled runs (default ~/.git-keys/logs/rotate.log)")
+
+	scheduleCmd.AddCommand(scheduleInstallCmd)
+	scheduleCmd.AddCommand(scheduleUninstallCmd)
+	scheduleCmd.AddCommand(scheduleStatusCmd)
+	rootCmd.AddCommand(scheduleCmd)
+}
+
+// loadScheduleConfig loads the config and its absolute path, which the
+// scheduled job passes with --config
+func loadScheduleConfig() (*config.Config, string, error) {
+	configPath := cfgFile
+	if configPath == "" {
+		configPath = config.GetDefaultConfigPath()
+	}
+	configPath, err := filepath.Abs(configPath)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to resolve config path: %w", err)
+	}
+
+	mgr := config.NewManager(configPath)
+	if !mgr.Exists() {
+		return nil, "", fmt.Errorf("configuration file not found at %s\nRun 'git-keys init' first", configPath)
+	}
+	cfg, err := mgr.Load()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to load config: %w", err)
+	}
+	return cfg, configPath, nil
+}
+
+func runScheduleInstall(cmd *cobra.Command, args []string) error {
+	scheduler, err := schedule.Scheduler()
+	if err != nil {
+		return err
+	}
+
+	cfg, configPath, err := loadScheduleConfig()
+	if err != nil {
+		return err
+	}
+	if !cfg.Defaults.AutoRotate {
+		return fmt.Errorf("automatic rotation is disabled; set 'auto_rotate: true' under defaults in %s first", configPath)
+	}
+
+	// A key must not be able to expire between two runs
+	window, err := rotateDueWindow(cfg, "")
+	if err != nil {
+		return err
+	}
+	if scheduleEvery < time.Hour || scheduleEvery > window {
+		return fmt.Errorf("--every must be between 1h and the due window (%s), got %s", formatDueWindow(window), scheduleEvery)
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate git-keys executable: %w", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(executable); err == nil {
+		executable = resolved
+	}
+
+	logPath := scheduleLog
+	if logPath == "" {
+		logPath = schedule.GetDefaultLogPath()
+	}
+	if logPath, err = filepath.Abs(logPath); err != nil {
+		return fmt.Errorf("failed to resolve log path: %w", err)
+	}
+
+	command := []string{executable, "--config", configPath}
+	if !profile.IsDefault() {
+		command = append(command, "--profile", profile.Name())
+	}
+	job := &schedule.Job{
+		Command:  append(command, "rotate", "--due", "--yes", "--scheduled"),
+		Interval: scheduleEvery,
+		LogPath:  logPath,
+	}
+	if err := schedule.Install(job); err != nil {
+		return fmt.Errorf("failed to install %s job: %w", scheduler, err)
+	}
+
+	histMgr := history.NewManager("")
+	if err := histMgr.Record(history.Entry{
+		Action:  "schedule",
+		Summary: fmt.Sprintf("Installed %s job rotating due keys every %s", scheduler, scheduleEvery),
+		Details: map[string]string{"every": scheduleEvery.String(), "log": logPath},
+	}); err != nil {
+		logger.Warn("Failed to record history: %v", err)
+	}
+
+	files, _ := schedule.Files()
+	fmt.Printf("✓ Installed %s job: 'git-keys rotate --due --yes' every %s\n", scheduler, scheduleEvery)
+	for _, path := range files {
+		fmt.Printf("  %s\n", path)
+	}
+	fmt.Printf("  Log: %s\n", logPath)
+	return nil
+}
+
+func runScheduleUninstall(cmd *cobra.Command, args []string) error {
+	scheduler, err := schedule.Scheduler()
+	if err != nil {
+		return err
+	}
+
+	installed, err := schedule.Installed()
+	if err != nil {
+		return err
+	}
+	if !installed {
+		fmt.Println("No scheduled rotation job is installed.")
+		return nil
+	}
+
+	if err := schedule.Uninstall(); err != nil {
+		return fmt.Errorf("failed to remove %s job: %w", scheduler, err)
+	}
+
+	histMgr := history.NewManager("")
+	if err := histMgr.Record(history.Entry{
+		Action:  "schedule",
+		Summary: fmt.Sprintf("Removed %s job", scheduler),
+	}); err != nil {
+		logger.Warn("Failed to record history: %v", err)
+	}
+
+	fmt.Printf("✓ Removed %s job\n", scheduler)
+	return nil
+}
+
+func runScheduleStatus(cmd *cobra.Command, args []string) error {
+	scheduler, err := schedule.Scheduler()
+	if err != nil {
+		return err
+	}
+
+	cfg, _, err := loadScheduleConfig()
+	if err != nil {
+		return err
+	}
+
+	printHeader("\n⏰ Scheduled Rotation")
+	fmt.Println()
+
+	installed, err := schedule.Installed()
+	if err != nil {
+		return err
+	}
+	if installed {
+		fmt.Printf("✓ %s job installed\n", scheduler)
+		files, _ := schedule.Files()
+		for _, path := range files {
+			fmt.Printf("  %s\n", path)
+		}
+	} else {
+		fmt.Printf("⊘ No %s job installed (run 'git-keys schedule install')\n", scheduler)
+	}
+
+	if info, err := os.Stat(schedule.GetDefaultLogPath()); err == nil {
+		fmt.Printf("  Log: %s (last written %s)\n", schedule.GetDefaultLogPath(), info.ModTime().Format("2006-01-02 15:04"))
+	}
+
+	if !cfg.Defaults.AutoRotate {
+		if installed {
+			fmt.Println("⚠️  defaults.auto_rotate is off, but the job is still installed; run 'git-keys schedule uninstall'")
+		} else {
+			fmt.Println("  defaults.auto_rotate is off")
+		}
+	}
+
+	window, err := rotateDueWindow(cfg, "")
+	if err != nil {
+		return err
+	}
+
+	// Keys the next run would rotate, soonest first
+	type dueKey struct {
+		label     string
+		expiresAt time.Time
+	}
+	var due []dueKey
+	for personaIdx := range cfg.Personas {
+		persona := &cfg.Personas[personaIdx]
+		if persona.Archived {
+			continue
+		}
+		for platformIdx := range persona.Platforms {
+			plat := &persona.Platforms[platformIdx]
+			for keyIdx := range plat.Keys {
+				key := &plat.Keys[keyIdx]
+				if !key.InUse() || key.Agent || key.PublicOnly {
+					continue
+				}
+				if keyDue(cfg, persona, plat, key, window) {
+					expiresAt, _ := cfg.KeyExpiry(persona, plat, key)
+					due = append(due, dueKey{fmt.Sprintf("%s/%s@%s", persona.Name, plat.Type, plat.Account), expiresAt})
+				}
+			}
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].expiresAt.Before(due[j].expiresAt) })
+
+	fmt.Println()
+	if len(due) == 0 {
+		fmt.Printf("No keys expire within %s.\n", formatDueWindow(window))
+		return nil
+	}
+	fmt.Printf("Keys expiring within %s (%d):\n", formatDueWindow(window), len(due))
+	for _, d := range due {
+		fmt.Printf("  • %s expires %s\n", d.label, d.expiresAt.Format("2006-01-02"))
+	}
+	return nil
+}
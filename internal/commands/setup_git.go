@@ -1,20 +1,22 @@
 package commands
 
 import (
-	"bufio"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
 
+	"github.com/kunlu/git-keys/internal/atomicfile"
 	"github.com/kunlu/git-keys/internal/config"
 	"github.com/kunlu/git-keys/internal/logger"
+	"github.com/kunlu/git-keys/internal/prompt"
 	"github.com/spf13/cobra"
 )
 
 var (
-	setupGitDryRun bool
+	setupGitDryRun  bool
+	setupGitGitDirs []string
 )
 
 var setupGitCmd = &cobra.Command{
@@ -36,7 +38,9 @@ This command will:
 After running this, your git commits will automatically use the correct identity
 and SSH key based on which directory you're working in.
 
-You'll be prompted to specify a directory pattern for each persona.
+You'll be prompted to specify a directory pattern for each persona, unless
+--gitdir gives it. In non-interactive mode (--yes), platforms without a
+--gitdir keep their current pattern or are skipped.
 
 Examples:
   # Reconfigure git setup for all personas
@@ -44,12 +48,17 @@ Examples:
 
   # Preview what would be created
   git-keys setup-git --dry-run
+
+  # Answer without prompting, e.g. in a provisioning script
+  git-keys setup-git --yes --gitdir work/github=~/work/ --gitdir personal=~/src/
 `,
 	RunE: runSetupGit,
 }
 
 func init() {
 	setupGitCmd.Flags().BoolVar(&setupGitDryRun, "dry-run", false, "Show what would be created without making changes")
+	setupGitCmd.Flags().StringArrayVar(&setupGitGitDirs, "gitdir", nil, "Directory pattern of persona[/platform[@account]], as target=dir (repeatable)")
+	setupGitCmd.RegisterFlagCompletionFunc("gitdir", completeTargetAnswer)
 	rootCmd.AddCommand(setupGitCmd)
 }
 
@@ -74,8 +83,12 @@ func runSetupGit(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("no personas configured. Run 'git-keys init' first")
 	}
 
-	fmt.Println("\n⚙️  Git Configuration Setup")
-	fmt.Println("=========================")
+	gitDirs, err := parseTargetAnswers(cfg, "--gitdir", setupGitGitDirs)
+	if err != nil {
+		return err
+	}
+
+	printHeader("\n⚙️  Git Configuration Setup")
 	fmt.Println()
 
 	if setupGitDryRun {
@@ -92,16 +105,18 @@ func runSetupGit(cmd *cobra.Command, args []string) error {
 	}
 
 	var platforms []platformEntry
-	reader := bufio.NewReader(os.Stdin)
 	configChanged := false
 
 	// Build list of all platforms across all personas
 	for personaIdx := range cfg.Personas {
 		persona := &cfg.Personas[personaIdx]
+		if persona.Archived {
+			continue
+		}
 		for platformIdx := range persona.Platforms {
 			platform := &persona.Platforms[platformIdx]
 			platformID := fmt.Sprintf("%s-%s", string(platform.Type), platform.Account)
-			configName := fmt.Sprintf(".gitconfig-%s-%s", persona.Name, platformID)
+			configName := platformGitConfigName(persona.Name, platformID)
 
 			platforms = append(platforms, platformEntry{
 				personaIdx:  personaIdx,
@@ -130,20 +145,30 @@ func runSetupGit(cmd *cobra.Command, args []string) error {
 		if existingPattern != "" {
 			fmt.Printf("   Current pattern: %s\n", existingPattern)
 		}
-		fmt.Printf("   Enter directory pattern (e.g., ~/Projects/%s/", platform.Account)
-		if existingPattern != "" {
-			fmt.Print(", or press Enter to keep current): ")
-		} else {
-			fmt.Print("): ")
-		}
 
-		pattern, _ := reader.ReadString('\n')
-		pattern = strings.TrimSpace(pattern)
+		pattern := targetAnswerFor(gitDirs, persona, platform)
+		if pattern == "" && !nonInteractive {
+			fmt.Printf("   Enter directory pattern (e.g., ~/Projects/%s/", platform.Account)
+			if existingPattern != "" {
+				fmt.Print(", or press Enter to keep current): ")
+			} else {
+				fmt.Print("): ")
+			}
+
+			pattern, _ = prompt.Line()
+		}
 
 		// Use existing if no new input
 		if pattern == "" {
 			if existingPattern != "" {
 				pattern = existingPattern
+			} else if platform.HasIncludeConditions() {
+				// gitdirs, remote_urls and include_if apply the identity
+				// without a gitdir
+				key := fmt.Sprintf("%s-%s-%s", persona.Name, platform.Type, platform.Account)
+				personaDirs[key] = ""
+				fmt.Printf("   ✓ Will configure for: %s\n\n", strings.Join(platformIncludeConditions(platform), ", "))
+				continue
 			} else {
 				fmt.Printf("   ⚠️  Skipping (no pattern provided)\n\n")
 				continue
@@ -207,8 +232,7 @@ func runSetupGit(cmd *cobra.Command, args []string) error {
 		platform := entry.platform
 		key := fmt.Sprintf("%s-%s-%s", persona.Name, platform.Type, platform.Account)
 
-		dirPattern, ok := personaDirs[key]
-		if !ok {
+		if _, ok := personaDirs[key]; !ok {
 			continue
 		}
 
@@ -218,16 +242,15 @@ func runSetupGit(cmd *cobra.Command, args []string) error {
 		if setupGitDryRun {
 			fmt.Printf("Would create: %s\n", configPath)
 		} else {
-			if err := createPlatformGitConfig(persona, platform, configPath); err != nil {
+			if err := createPlatformGitConfig(cfg, persona, platform, configPath); err != nil {
 				logger.Warn("Failed to create config for %s/%s-%s: %v", persona.Name, platform.Type, platform.Account, err)
 				continue
 			}
 			fmt.Printf("✓ Created: %s\n", configPath)
 		}
 
-		// Create includeIf entry
-		includeEntry := fmt.Sprintf("[includeIf \"gitdir:%s\"]\n\tpath = %s\n", dirPattern, configPath)
-		includeEntries = append(includeEntries, includeEntry)
+		// Create includeIf entries for the gitdir and remote_urls
+		includeEntries = append(includeEntries, platformIncludeEntries(platform, configPath)...)
 	}
 
 	// Update global gitconfig with includeIf entries
@@ -264,6 +287,9 @@ func runSetupGit(cmd *cobra.Command, args []string) error {
 		fmt.Println("and SSH key based on your working directory.")
 		fmt.Println("\nTest it:")
 		for name, dir := range personaDirs {
+			if dir == "" {
+				continue // Applied by remote_urls in any directory
+			}
 			fmt.Printf("  cd %s\n", dir)
 			fmt.Printf("  git config user.email  # Should show persona '%s'\n", name)
 			fmt.Println()
@@ -273,46 +299,19 @@ func runSetupGit(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-func createPlatformGitConfig(persona *config.Persona, platform *config.Platform, configPath string) error {
-	var content strings.Builder
-
-	// User identity
-	content.WriteString(fmt.Sprintf("# Git configuration for %s <%s>\n", persona.Name, persona.Email))
-	content.WriteString(fmt.Sprintf("# Platform: %s/%s\n", platform.Type, platform.Account))
-	content.WriteString(fmt.Sprintf("# Managed by git-keys\n\n"))
-	content.WriteString("[user]\n")
-	content.WriteString(fmt.Sprintf("\tname = %s\n", persona.Name))
-	content.WriteString(fmt.Sprintf("\temail = %s\n\n", persona.Email))
-
-	// URL rewrites for SSH hosts (platform-specific)
-	var baseHost string
-
-	if platform.Type == config.PlatformGitHub {
-		baseHost = "github.com"
-	} else if platform.Type == config.PlatformGitLab {
-		if platform.BaseURL != "" && platform.BaseURL != "https://gitlab.com" {
-			baseHost = strings.TrimPrefix(platform.BaseURL, "https://")
-			baseHost = strings.TrimPrefix(baseHost, "http://")
-			baseHost = strings.TrimSuffix(baseHost, "/")
-		} else {
-			baseHost = "gitlab.com"
-		}
-	}
-
-	if baseHost != "" {
-		// Sanitize persona name to ensure valid hostname (no spaces)
-		sanitizedPersona := sanitizeHostname(persona.Name)
-		personaHost := fmt.Sprintf("%s.%s", baseHost, sanitizedPersona)
-		content.WriteString("# SSH host rewrite for platform-specific key\n")
-		content.WriteString(fmt.Sprintf("[url \"git@%s:\"]\n", personaHost))
-		content.WriteString(fmt.Sprintf("\tinsteadOf = git@%s:\n", baseHost))
-		content.WriteString(fmt.Sprintf("\tinsteadOf = https://%s/\n\n", baseHost))
-	}
-
-	return os.WriteFile(configPath, []byte(content.String()), 0644)
+func createPlatformGitConfig(cfg *config.Config, persona *config.Persona, platform *config.Platform, configPath string) error {
+	return atomicfile.WriteFile(configPath, []byte(platformGitConfigContent(cfg, persona, platform)), 0644)
 }
 
 func addIncludeIfEntries(gitConfigPath string, entries []string) error {
+	// Hold the lock from the read to the write so a concurrent run's change
+	// is not lost
+	unlock, err := atomicfile.Lock(gitConfigPath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	// Read existing gitconfig
 	var existingContent string
 	if data, err := os.ReadFile(gitConfigPath); err == nil {
@@ -320,8 +319,8 @@ func addIncludeIfEntries(gitConfigPath string, entries []string) error {
 	}
 
 	// Check if git-keys managed section already exists
-	managedMarker := "# BEGIN git-keys managed conditional includes"
-	endMarker := "# END git-keys managed conditional includes"
+	managedMarker := gitConfigManagedStart()
+	endMarker := gitConfigManagedEnd()
 
 	var newContent string
 
@@ -349,7 +348,7 @@ func addIncludeIfEntries(gitConfigPath string, entries []string) error {
 			endMarker + "\n"
 	}
 
-	return os.WriteFile(gitConfigPath, []byte(newContent), 0644)
+	return atomicfile.WriteFile(gitConfigPath, []byte(newContent), 0644)
 }
 
 func removeGitKeysConfig() error {
@@ -361,6 +360,12 @@ func removeGitKeysConfig() error {
 
 	globalGitConfig := filepath.Join(home, ".gitconfig")
 
+	unlock, err := atomicfile.Lock(globalGitConfig)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	// Read existing gitconfig
 	data, err := os.ReadFile(globalGitConfig)
 	if err != nil {
@@ -368,8 +373,8 @@ func removeGitKeysConfig() error {
 	}
 
 	content := string(data)
-	managedMarker := "# BEGIN git-keys managed conditional includes"
-	endMarker := "# END git-keys managed conditional includes"
+	managedMarker := gitConfigManagedStart()
+	endMarker := gitConfigManagedEnd()
 
 	if !strings.Contains(content, managedMarker) {
 		return nil // Nothing to remove
@@ -385,7 +390,7 @@ func removeGitKeysConfig() error {
 
 		newContent := strings.TrimRight(before, "\n") + "\n" + strings.TrimLeft(after, "\n")
 
-		return os.WriteFile(globalGitConfig, []byte(newContent), 0644)
+		return atomicfile.WriteFile(globalGitConfig, []byte(newContent), 0644)
 	}
 
 	return nil
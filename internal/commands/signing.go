@@ -0,0 +1,193 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kunlu/git-keys/internal/config"
+	"github.com/kunlu/git-keys/internal/gpgkey"
+	"github.com/kunlu/git-keys/internal/journal"
+	"github.com/kunlu/git-keys/internal/logger"
+	"github.com/kunlu/git-keys/internal/sshkey"
+)
+
+// ensureSigningKey records the signing key of a persona that signs commits
+// on one of its platforms. The key file is generated for the first platform
+// and shared by the others. Reports whether the platform changed.
+func ensureSigningKey(cfg *config.Config, keyMgr *sshkey.Manager, persona *config.Persona, platform *config.Platform, machineName string, j *journal.Journal) (bool, error) {
+	if !persona.SignsWithSSH() || platform.GetSigningKey() != nil {
+		return false, nil
+	}
+
+	if shared := persona.GetSigningKey(); shared != nil {
+		key := *shared
+		key.RemoteID = ""
+		platform.SigningKeys = append(platform.SigningKeys, key)
+		return true, nil
+	}
+
+	key, err := generateSigningKey(cfg, keyMgr, persona, machineName)
+	if err != nil {
+		return false, err
+	}
+	if err := j.KeyCreated(filepath.Join(keyMgr.KeysDir(), key.LocalPath)); err != nil {
+		return false, err
+	}
+
+	platform.SigningKeys = append(platform.SigningKeys, *key)
+	fmt.Printf("✓ Generated signing key for %s: %s\n", persona.Name, key.LocalPath)
+	return true, nil
+}
+
+// generateSigningKey generates a persona's commit signing key. Signing keys
+// have no expiry: platforms stop verifying commits signed by a key once it is
+// removed, so it is kept registered for as long as those commits matter.
+func generateSigningKey(cfg *config.Config, keyMgr *sshkey.Manager, persona *config.Persona, machineName string) (*config.KeyConfig, error) {
+	settings := cfg.ResolveKeySettings(persona, nil)
+	createdAt := time.Now()
+
+	fileName := fmt.Sprintf("git-keys-signing-%s-%s", sanitizeHostname(persona.Name), settings.Type)
+	comment := fmt.Sprintf("git-keys:signing:%s:%s:%s", persona.Name, machineName, createdAt.Format("2006-01-02"))
+
+	logger.Info("Generating new %s signing key: %s", settings.Type, fileName)
+
+	if err := keyMgr.GenerateKey(settings.Type, comment, fileName); err != nil {
+		return nil, fmt.Errorf("failed to generate signing key: %w", err)
+	}
+
+	fingerprint, err := keyMgr.GetFingerprint(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fingerprint: %w", err)
+	}
+
+	return &config.KeyConfig{
+		Type:        settings.Type,
+		CreatedAt:   createdAt,
+		Fingerprint: fingerprint,
+		LocalPath:   fileName,
+		Status:      config.KeyStatusActive,
+		Purpose:     config.KeyPurposeSigning,
+	}, nil
+}
+
+// signingKeyTitle returns the title a signing key is registered under
+func signingKeyTitle(platform *config.Platform, key *config.KeyConfig, machineName string) string {
+	return fmt.Sprintf("%s@%s signing (git-keys %s)", platform.Account, machineName, key.CreatedAt.Format("2006-01-02"))
+}
+
+// uploadSigningKeyWithToken registers a signing key on GitHub/GitLab
+func uploadSigningKeyWithToken(ctx context.Context, keyMgr *sshkey.Manager, platform *config.Platform, key *config.KeyConfig, title, token string) error {
+	publicKey, err := keyMgr.GetPublicKey(key.LocalPath)
+	if err != nil {
+		return err
+	}
+
+	client, err := newPlatformClientWithToken(platform, token)
+	if err != nil {
+		return err
+	}
+
+	remoteID, err := client.AddSigningKey(ctx, title, publicKey, key.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("API error: %w", err)
+	}
+
+	key.RemoteID = remoteID
+	return nil
+}
+
+// ensureGPGKey makes sure a persona that signs with GPG has its secret key
+// in the keyring: the existing key named by gpg_key.fingerprint, or a key
+// generated for the persona's name and email. Reports whether the persona
+// changed.
+func ensureGPGKey(persona *config.Persona, j *journal.Journal) (bool, error) {
+	if !persona.SignsWithGPG() || (persona.GPGKey != nil && !persona.GPGKey.CreatedAt.IsZero()) {
+		return false, nil
+	}
+	if !gpgkey.Available() {
+		return false, fmt.Errorf("persona '%s' signs commits with GPG, but gpg is not installed", persona.Name)
+	}
+
+	if persona.GPGKey != nil {
+		// A hand-written fingerprint adopts a key already in the keyring
+		key, err := gpgkey.Find(persona.GPGKey.Fingerprint)
+		if err != nil {
+			return false, err
+		}
+		if key == nil {
+			return false, fmt.Errorf("GPG key %s of persona '%s' has no secret key in the keyring; import it with 'gpg --import' first",
+				persona.GPGKey.Fingerprint, persona.Name)
+		}
+		persona.GPGKey.Fingerprint = key.Fingerprint
+		persona.GPGKey.CreatedAt = key.CreatedAt
+		fmt.Printf("✓ Using GPG key for %s: %s (%s)\n", persona.Name, key.Fingerprint, key.UserID)
+		return true, nil
+	}
+
+	key, err := gpgkey.Generate(persona.Name, persona.Email)
+	if err != nil {
+		return false, err
+	}
+	if err := j.GPGKeyCreated(key.Fingerprint); err != nil {
+		return false, err
+	}
+
+	persona.GPGKey = &config.GPGKey{Fingerprint: key.Fingerprint, CreatedAt: key.CreatedAt, Generated: true}
+	fmt.Printf("✓ Generated GPG key for %s: %s\n", persona.Name, key.Fingerprint)
+	return true, nil
+}
+
+// uploadGPGKeyWithToken registers a persona's GPG key on GitHub/GitLab
+func uploadGPGKeyWithToken(ctx context.Context, platform *config.Platform, key *config.GPGKey, token string) error {
+	publicKey, err := gpgkey.ExportPublicKey(key.Fingerprint)
+	if err != nil {
+		return err
+	}
+
+	client, err := newPlatformClientWithToken(platform, token)
+	if err != nil {
+		return err
+	}
+
+	remoteID, err := client.AddGPGKey(ctx, publicKey)
+	if err != nil {
+		return fmt.Errorf("API error: %w", err)
+	}
+
+	platform.GPGKeyID = remoteID
+	return nil
+}
+
+// signingGitConfigContent returns the git config that makes git sign a
+// persona's commits with its signing key, or "" when it does not sign
+func signingGitConfigContent(cfg *config.Config, persona *config.Persona) string {
+	var format, signingKey string
+	switch {
+	case persona.SignsWithSSH():
+		key := persona.GetSigningKey()
+		if key == nil {
+			return ""
+		}
+		format, signingKey = "ssh", filepath.Join(cfg.Defaults.GetKeysDir(), key.LocalPath)
+	case persona.SignsWithGPG():
+		if persona.GPGKey == nil {
+			return ""
+		}
+		format, signingKey = "openpgp", persona.GPGKey.Fingerprint
+	default:
+		return ""
+	}
+
+	var content strings.Builder
+	content.WriteString("# Commit signing\n")
+	content.WriteString("[gpg]\n")
+	content.WriteString(fmt.Sprintf("\tformat = %s\n", format))
+	content.WriteString("[user]\n")
+	content.WriteString(fmt.Sprintf("\tsigningkey = %s\n", signingKey))
+	content.WriteString("[commit]\n")
+	content.WriteString("\tgpgsign = true\n\n")
+	return content.String()
+}
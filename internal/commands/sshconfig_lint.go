@@ -0,0 +1,149 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/kunlu/git-keys/internal/config"
+	"github.com/kunlu/git-keys/internal/sshconfig"
+	"github.com/spf13/cobra"
+)
+
+var sshconfigCmd = &cobra.Command{
+	Use:   "sshconfig",
+	Short: "Work with the SSH config",
+	Long: `Commands about the SSH config git-keys writes its Host blocks to.
+
+Subcommands:
+  lint   - Check the SSH config and the files it includes for problems
+`,
+}
+
+var sshconfigLintCmd = &cobra.Command{
+	Use:   "lint",
+	Short: "Check the SSH config for problems",
+	Long: `Check the SSH config, and every file it includes, for problems that make
+ssh ignore settings or fail to authenticate:
+
+  - ssh rejects the config ('ssh -G')
+  - an IdentityFile does not exist, or is a private key others can read
+  - a Host section sets options an earlier section matching the same hosts
+    already sets; ssh takes the first value, so the later one never applies
+  - a managed block appears twice, or has no end marker
+  - a managed block belongs to no configured persona, e.g. after the persona
+    was removed ('git-keys sync' removes it)
+
+Without a configuration file, ~/.ssh/config is checked and dangling blocks
+are not reported. Exits with an error when errors are found.
+
+Examples:
+  git-keys sshconfig lint
+  git-keys sshconfig lint -o json
+`,
+	Args:         cobra.NoArgs,
+	Annotations:  map[string]string{structuredOutputAnnotation: structuredQuiet},
+	RunE:         runSSHConfigLint,
+	SilenceUsage: true,
+}
+
+func init() {
+	sshconfigCmd.AddCommand(sshconfigLintCmd)
+	rootCmd.AddCommand(sshconfigCmd)
+}
+
+// sshConfigLintReport is the data of the SSHConfigLint document of
+// 'git-keys sshconfig lint --output json|yaml'
+type sshConfigLintReport struct {
+	SSHConfig     string                `json:"ssh_config"`
+	PersonasKnown bool                  `json:"personas_known"`
+	Errors        int                   `json:"errors"`
+	Warnings      int                   `json:"warnings"`
+	Issues        []sshconfig.LintIssue `json:"issues"`
+}
+
+func runSSHConfigLint(cmd *cobra.Command, args []string) error {
+	configPath := cfgFile
+	if configPath == "" {
+		configPath = config.GetDefaultConfigPath()
+	}
+
+	sshMgr := sshconfig.NewManager("")
+	var known func(string) bool
+	if mgr := config.NewManager(configPath); mgr.Exists() {
+		cfg, err := mgr.Load()
+		if err != nil {
+			return fmt.Errorf("failed to load configuration: %w", err)
+		}
+		sshMgr = sshConfigManager(cfg)
+		known = knownBlockIDs(cfg)
+	}
+
+	issues, err := sshMgr.Lint(known)
+	if err != nil {
+		return err
+	}
+	if issues == nil {
+		issues = []sshconfig.LintIssue{}
+	}
+
+	errorCount := 0
+	for _, issue := range issues {
+		if issue.Severity == sshconfig.LintError {
+			errorCount++
+		}
+	}
+
+	if structuredOutput() {
+		report := sshConfigLintReport{
+			SSHConfig:     sshMgr.ConfigPath(),
+			PersonasKnown: known != nil,
+			Errors:        errorCount,
+			Warnings:      len(issues) - errorCount,
+			Issues:        issues,
+		}
+		if err := printStructured("SSHConfigLint", report); err != nil {
+			return err
+		}
+	}
+
+	printHeader("\n🔎 SSH Config Lint")
+	fmt.Printf("\n  SSH config: %s\n", sshMgr.ConfigPath())
+	if known == nil {
+		fmt.Println("  No git-keys configuration; dangling managed blocks are not checked")
+	}
+	fmt.Println()
+
+	if len(issues) == 0 {
+		fmt.Println("✅ No problems found.")
+		return nil
+	}
+	for _, issue := range issues {
+		level := checkWarn
+		if issue.Severity == sshconfig.LintError {
+			level = checkFail
+		}
+		printWrapped("  "+checkIcons[level], issue.String())
+	}
+	fmt.Println()
+
+	if errorCount > 0 {
+		return fmt.Errorf("lint found %d error(s)", errorCount)
+	}
+	return nil
+}
+
+// knownBlockIDs returns whether a managed block ID belongs to the
+// configuration: a platform of a persona (archived ones included), a key
+// rotation, a deploy key, or the block of imported keys
+func knownBlockIDs(cfg *config.Config) func(string) bool {
+	ids := map[string]bool{"git-keys-imported": true}
+	for _, persona := range cfg.Personas {
+		for _, plat := range persona.Platforms {
+			ids[sshconfig.GetManagedBlockID(persona.Name, plat.Type, plat.Account)] = true
+			ids[fmt.Sprintf("git-keys-%s-%s", persona.Name, plat.Type)] = true
+		}
+	}
+	for i := range cfg.DeployKeys {
+		ids[deployKeyBlockID(&cfg.DeployKeys[i])] = true
+	}
+	return func(id string) bool { return ids[id] }
+}
@@ -1,18 +1,22 @@
 package commands
 
 import (
+	"context"
 	"fmt"
 	"os"
-	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/kunlu/git-keys/internal/config"
+	"github.com/kunlu/git-keys/internal/logger"
+	"github.com/kunlu/git-keys/internal/profile"
 	"github.com/kunlu/git-keys/internal/sshkey"
 	"github.com/spf13/cobra"
 )
 
 var (
-	statusVerbose bool
+	statusVerbose          bool
+	statusDetailedExitCode bool
 )
 
 var statusCmd = &cobra.Command{
@@ -31,24 +35,35 @@ This command shows:
 Use this to quickly verify your git-keys setup is healthy and identify
 keys that may need rotation or attention.
 
+With --detailed-exitcode, or --quiet, the exit status tells a CI job or a
+shell prompt whether the keys are healthy:
+  0  all checks passed
+  1  status failed, or key files are missing
+  2  warnings: keys needing rotation, unused keys, stale bindings
+  3  keys expired or expire within the due window (defaults.rotate_within)
+
 Examples:
   # Show overview status
   git-keys status
 
   # Show detailed status
   git-keys status --verbose
+
+  # Check key health without output
+  git-keys status --quiet || echo "keys need attention: $?"
 `,
-	RunE: runStatus,
+	Annotations: map[string]string{structuredOutputAnnotation: structuredQuiet},
+	RunE:        runStatus,
 }
 
 func init() {
 	statusCmd.Flags().BoolVarP(&statusVerbose, "verbose", "v", false, "Show detailed status information")
+	statusCmd.Flags().BoolVar(&statusDetailedExitCode, "detailed-exitcode", false, "Exit with status 1 on errors, 2 on warnings and 3 when keys expire soon")
 	rootCmd.AddCommand(statusCmd)
 }
 
 func runStatus(cmd *cobra.Command, args []string) error {
-	fmt.Println("\n📊 Git-Keys Status")
-	fmt.Println("==================")
+	printHeader("\n📊 Git-Keys Status")
 	fmt.Println()
 
 	// Check configuration file
@@ -58,16 +73,30 @@ func runStatus(cmd *cobra.Command, args []string) error {
 	cfg, err := configMgr.Load()
 	if err != nil {
 		if os.IsNotExist(err) {
-			fmt.Println("❌ Configuration Status: Not initialized")
+			if structuredOutput() {
+				if err := printStructured("Status", statusReport{Config: configPath, Profile: profile.Name()}); err != nil {
+					return err
+				}
+			}
+			printCheck(checkFail, "Configuration Status: Not initialized")
 			fmt.Printf("   Config file not found: %s\n\n", configPath)
 			fmt.Println("Run 'git-keys init' to get started")
-			return nil
+			return exitWithStatus(cmd, ExitError)
 		}
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
-	fmt.Println("✓ Configuration Status: OK")
-	fmt.Printf("  Config file: %s\n\n", configPath)
+	printCheck(checkOK, "Configuration Status: OK")
+	if !profile.IsDefault() {
+		fmt.Printf("  Profile: %s (%s)\n", profile.Name(), profile.Dir())
+	}
+	fmt.Printf("  Config file: %s\n", configPath)
+	printEnvOverrides(cfg)
+	fmt.Println()
+
+	if expired := reconcileExpiry(configMgr, cfg); len(expired) > 0 {
+		fmt.Println()
+	}
 
 	// Overview
 	totalPersonas := len(cfg.Personas)
@@ -76,8 +105,13 @@ func runStatus(cmd *cobra.Command, args []string) error {
 	activeKeys := 0
 	revokedKeys := 0
 	expiredKeys := 0
+	archivedPersonas := 0
 
 	for _, persona := range cfg.Personas {
+		if persona.Archived {
+			archivedPersonas++
+			continue
+		}
 		totalPlatforms += len(persona.Platforms)
 		for _, platform := range persona.Platforms {
 			totalKeys += len(platform.Keys)
@@ -94,9 +128,12 @@ func runStatus(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	fmt.Println("📈 Overview")
-	fmt.Println("===========")
-	fmt.Printf("Personas: %d\n", totalPersonas)
+	printHeader("📈 Overview")
+	if archivedPersonas > 0 {
+		fmt.Printf("Personas: %d (%d archived)\n", totalPersonas, archivedPersonas)
+	} else {
+		fmt.Printf("Personas: %d\n", totalPersonas)
+	}
 	fmt.Printf("Platforms: %d\n", totalPlatforms)
 	fmt.Printf("Total Keys: %d\n", totalKeys)
 	fmt.Printf("  Active: %d\n", activeKeys)
@@ -108,22 +145,68 @@ func runStatus(cmd *cobra.Command, args []string) error {
 	}
 	fmt.Println()
 
+	if len(cfg.Machines) > 1 || cfg.FindMachine(cfg.Machine.ID) == nil {
+		printMachineKeys(cfg)
+	}
+
 	// Health checks
-	fmt.Println("🏥 Health Checks")
-	fmt.Println("================")
+	printHeader("🏥 Health Checks")
 
 	warnings := []string{}
 	errors := []string{}
 
-	sshDir := filepath.Join(os.Getenv("HOME"), ".ssh")
-	keyMgr := sshkey.NewManager(sshDir)
+	keyMgr := sshkey.NewManager(cfg.Defaults.GetKeysDir())
 
 	keysNeedingRotation := 0
+	keysExpiringSoon := 0
+	unusedKeys := 0
 	missingKeyFiles := 0
+	staleBindings := 0
+
+	dueWindow, err := rotateDueWindow(cfg, "")
+	if err != nil {
+		return err
+	}
+
+	// GitLab reports when each key last authenticated; by platform lane
+	usageByLane := make(map[string]map[string]keyUsage)
+	unusedDays := unusedKeyDays(cfg)
 
 	for _, persona := range cfg.Personas {
-		for _, platform := range persona.Platforms {
+		if persona.Archived {
+			continue
+		}
+		for platformIdx, platform := range persona.Platforms {
+			// Repositories bound with 'git-keys use' must still exist and
+			// use the persona's email
+			for _, repo := range platform.Repos {
+				if problem := repoBindingProblem(repo, persona.Email); problem != "" {
+					staleBindings++
+					if statusVerbose {
+						warnings = append(warnings, fmt.Sprintf("Repository binding %s (%s): %s", repo, persona.Name, problem))
+					}
+				}
+			}
+
+			lane := platformLane(platform.Type, platform.BaseURL, platform.Account)
+			if _, fetched := usageByLane[lane]; !fetched && platformHasRemoteKey(&platform) {
+				usage, err := platformKeyUsage(context.Background(), &persona.Platforms[platformIdx], refreshRemote)
+				if err != nil {
+					logger.Debug("Key usage of %s@%s not available: %v", platform.Account, platform.Type, err)
+				}
+				usageByLane[lane] = usage
+			}
+
 			for _, key := range platform.Keys {
+				// Keys GitLab has not seen authenticate for a while are prune candidates
+				if usage, ok := usageByLane[lane][strings.TrimPrefix(key.Fingerprint, "SHA256:")]; ok && key.InUse() && usage.Unused(unusedDays) {
+					unusedKeys++
+					if statusVerbose {
+						warnings = append(warnings, fmt.Sprintf("Key unused for over %d days: %s/%s (%s)",
+							unusedDays, persona.Name, platform.Type, usage))
+					}
+				}
+
 				// Check key file exists
 				if key.LocalPath != "" {
 					if !keyMgr.KeyExists(key.LocalPath) {
@@ -134,6 +217,16 @@ func runStatus(cmd *cobra.Command, args []string) error {
 					}
 				}
 
+				// Keys expiring within the due window are rotated by 'rotate --due'
+				if key.Status == config.KeyStatusActive && keyDue(cfg, &persona, &persona.Platforms[platformIdx], &key, dueWindow) {
+					keysExpiringSoon++
+					if statusVerbose {
+						expiresAt, _ := cfg.KeyExpiry(&persona, &persona.Platforms[platformIdx], &key)
+						warnings = append(warnings, fmt.Sprintf("Key expires soon: %s/%s (%s)",
+							persona.Name, platform.Type, expiresAt.Format("2006-01-02")))
+					}
+				}
+
 				// Check key age for rotation
 				if key.Status == config.KeyStatusActive && !key.CreatedAt.IsZero() {
 					age := time.Since(key.CreatedAt)
@@ -156,37 +249,51 @@ func runStatus(cmd *cobra.Command, args []string) error {
 	healthOK := true
 	if missingKeyFiles > 0 {
 		healthOK = false
-		fmt.Printf("❌ Missing key files: %d\n", missingKeyFiles)
+		printCheck(checkFail, "Missing key files: %d", missingKeyFiles)
 	}
 	if expiredKeys > 0 {
 		healthOK = false
-		fmt.Printf("❌ Expired keys: %d\n", expiredKeys)
+		printCheck(checkFail, "Expired keys: %d", expiredKeys)
+	}
+	if keysExpiringSoon > 0 {
+		printCheck(checkWarn, "Keys expiring within %s: %d", formatDueWindow(dueWindow), keysExpiringSoon)
 	}
 	if keysNeedingRotation > 0 {
-		fmt.Printf("⚠️  Keys needing rotation (>90 days): %d\n", keysNeedingRotation)
+		printCheck(checkWarn, "Keys needing rotation (>90 days): %d", keysNeedingRotation)
+	}
+	if unusedKeys > 0 {
+		printCheck(checkWarn, "Keys unused on GitLab (>%d days): %d", unusedDays, unusedKeys)
+	}
+	if staleBindings > 0 {
+		printCheck(checkWarn, "Stale repository bindings: %d", staleBindings)
+	}
+
+	// Check that the recorded machine name still matches the system
+	currentMachineName, err := detectMachineName()
+	machineNameStale := err == nil && currentMachineName != "" && currentMachineName != cfg.Machine.Name
+	if machineNameStale {
+		printCheck(checkWarn, "Machine name changed: config has '%s', system reports '%s'", cfg.Machine.Name, currentMachineName)
 	}
 
-	if healthOK && keysNeedingRotation == 0 {
-		fmt.Println("✓ All checks passed")
+	if healthOK && keysNeedingRotation == 0 && keysExpiringSoon == 0 && unusedKeys == 0 && staleBindings == 0 && !machineNameStale {
+		printCheck(checkOK, "All checks passed")
 	}
 	fmt.Println()
 
 	// Show warnings and errors in verbose mode
 	if statusVerbose {
 		if len(errors) > 0 {
-			fmt.Println("❌ Errors")
-			fmt.Println("=========")
+			printHeader("❌ Errors")
 			for _, err := range errors {
-				fmt.Printf("  • %s\n", err)
+				printWrapped("  • ", err)
 			}
 			fmt.Println()
 		}
 
 		if len(warnings) > 0 {
-			fmt.Println("⚠️  Warnings")
-			fmt.Println("===========")
+			printHeader("⚠️  Warnings")
 			for _, warn := range warnings {
-				fmt.Printf("  • %s\n", warn)
+				printWrapped("  • ", warn)
 			}
 			fmt.Println()
 		}
@@ -194,13 +301,16 @@ func runStatus(cmd *cobra.Command, args []string) error {
 
 	// Detailed persona/platform view
 	if statusVerbose {
-		fmt.Println("👤 Personas & Platforms")
-		fmt.Println("=======================")
+		printHeader("👤 Personas & Platforms")
 		fmt.Println()
 
 		for _, persona := range cfg.Personas {
+			if persona.Archived {
+				fmt.Printf("📦 %s <%s> (archived %s)\n\n", persona.Name, persona.Email, persona.ArchivedAt.Format("2006-01-02"))
+				continue
+			}
 			fmt.Printf("📋 %s <%s>\n", persona.Name, persona.Email)
-			for _, platform := range persona.Platforms {
+			for platformIdx, platform := range persona.Platforms {
 				platformLabel := string(platform.Type)
 				if platform.BaseURL != "" {
 					platformLabel = fmt.Sprintf("%s (%s)", platform.Type, platform.BaseURL)
@@ -214,33 +324,106 @@ func runStatus(cmd *cobra.Command, args []string) error {
 						daysSinceCreation := int(time.Since(key.CreatedAt).Hours() / 24)
 						age = fmt.Sprintf(" (age: %dd)", daysSinceCreation)
 					}
-					fmt.Printf("     └─ %s %s%s\n", status, key.Fingerprint, age)
+					lastUsed := ""
+					lane := platformLane(platform.Type, platform.BaseURL, platform.Account)
+					if usage, ok := usageByLane[lane][strings.TrimPrefix(key.Fingerprint, "SHA256:")]; ok {
+						lastUsed = fmt.Sprintf(" (%s)", usage)
+					}
+					missing := key.LocalPath != "" && !keyMgr.KeyExists(key.LocalPath)
+					expiring := key.Status == config.KeyStatusActive && keyDue(cfg, &persona, &persona.Platforms[platformIdx], &key, dueWindow)
+					fingerprint := colorize(keyColor(key.Status, missing, expiring), key.Fingerprint)
+					fmt.Printf("     └─ %s %s%s%s\n", status, fingerprint, age, lastUsed)
+				}
+				for _, repo := range platform.Repos {
+					fmt.Printf("     └─ 📌 %s\n", repo)
 				}
 			}
 			fmt.Println()
 		}
 	}
 
+	if structuredOutput() {
+		report := newStatusReport(configPath, cfg)
+		report.Overview = statusOverview{
+			Personas:         totalPersonas,
+			ArchivedPersonas: archivedPersonas,
+			Platforms:        totalPlatforms,
+			Keys:             totalKeys,
+			ActiveKeys:       activeKeys,
+			RevokedKeys:      revokedKeys,
+			ExpiredKeys:      expiredKeys,
+		}
+		report.Health = statusHealth{
+			OK:               healthOK && keysNeedingRotation == 0 && keysExpiringSoon == 0 && unusedKeys == 0 && staleBindings == 0 && !machineNameStale,
+			MissingKeyFiles:  missingKeyFiles,
+			ExpiredKeys:      expiredKeys,
+			ExpiringSoon:     keysExpiringSoon,
+			DueWindow:        formatDueWindow(dueWindow),
+			NeedingRotation:  keysNeedingRotation,
+			UnusedKeys:       unusedKeys,
+			StaleBindings:    staleBindings,
+			MachineNameStale: machineNameStale,
+		}
+		if err := printStructured("Status", report); err != nil {
+			return err
+		}
+	}
+
 	// Recommendations
-	if missingKeyFiles > 0 || expiredKeys > 0 || keysNeedingRotation > 0 {
-		fmt.Println("💡 Recommendations")
-		fmt.Println("==================")
+	if missingKeyFiles > 0 || expiredKeys > 0 || keysExpiringSoon > 0 || keysNeedingRotation > 0 || unusedKeys > 0 || staleBindings > 0 || machineNameStale {
+		printHeader("💡 Recommendations")
 
 		if missingKeyFiles > 0 {
-			fmt.Println("• Missing key files detected. Run 'git-keys apply' to regenerate keys.")
+			printWrapped("• ", "Missing key files detected. Run 'git-keys apply' to regenerate keys.")
 		}
 		if expiredKeys > 0 {
-			fmt.Println("• Expired keys found. Run 'git-keys rotate' to rotate them.")
+			printWrapped("• ", "Expired keys found. Run 'git-keys rotate --due' to rotate them.")
+		}
+		if keysExpiringSoon > 0 {
+			printWrapped("• ", "Some keys expire soon. Run 'git-keys rotate --due', or 'git-keys schedule install' to rotate them automatically.")
 		}
 		if keysNeedingRotation > 0 {
-			fmt.Println("• Some keys are >90 days old. Consider rotating with 'git-keys rotate'.")
+			printWrapped("• ", "Some keys are >90 days old. Consider rotating with 'git-keys rotate'.")
+		}
+		if unusedKeys > 0 {
+			printWrapped("• ", "Some keys have not been used on GitLab for a while. Run 'git-keys audit' to list them, and 'git-keys revoke' those no longer needed.")
+		}
+		if machineNameStale {
+			printWrapped("• ", "Machine name is stale. Run 'git-keys machine rename' to update key comments and titles.")
+		}
+		if staleBindings > 0 {
+			printWrapped("• ", "Some repository bindings are stale. Run 'git-keys use <persona>' in the repository again, or 'git-keys use --clear'.")
 		}
 		fmt.Println()
 	}
 
+	switch {
+	case missingKeyFiles > 0:
+		return exitWithStatus(cmd, ExitError)
+	case expiredKeys > 0 || keysExpiringSoon > 0:
+		return exitWithStatus(cmd, ExitExpiring)
+	case keysNeedingRotation > 0 || unusedKeys > 0 || staleBindings > 0 || machineNameStale:
+		return exitWithStatus(cmd, ExitWarnings)
+	}
 	return nil
 }
 
+// repoBindingProblem checks a repository bound with 'git-keys use'. Returns
+// "" when it exists and its email is the persona's.
+func repoBindingProblem(repo, email string) string {
+	if _, err := os.Stat(repo); err != nil {
+		return "repository no longer exists"
+	}
+	local, err := gitOutput(repo, "config", "--local", "--get", "user.email")
+	if err != nil {
+		return "local user.email is not set"
+	}
+	if !strings.EqualFold(local, email) {
+		return fmt.Sprintf("local user.email is %s", local)
+	}
+	return ""
+}
+
 func getKeyStatusIcon(status config.KeyStatus) string {
 	switch status {
 	case config.KeyStatusActive:
@@ -255,3 +438,154 @@ func getKeyStatusIcon(status config.KeyStatus) string {
 		return "?"
 	}
 }
+
+// printMachineKeys lists the machines sharing the configuration and the
+// platforms each has an active key for
+func printMachineKeys(cfg *config.Config) {
+	printHeader("💻 Machines")
+
+	machines := cfg.Machines
+	if cfg.FindMachine(cfg.Machine.ID) == nil {
+		machines = append(machines, cfg.Machine)
+	}
+
+	for _, machine := range machines {
+		var lanes []string
+		for _, persona := range cfg.Personas {
+			if persona.Archived {
+				continue
+			}
+			for _, platform := range persona.Platforms {
+				// The keys of this machine need not be marked with its ID yet
+				keys, id := platform.OtherKeys, machine.ID
+				if machine.ID == cfg.Machine.ID {
+					keys, id = platform.Keys, ""
+				}
+				for _, key := range keys {
+					if key.Status == config.KeyStatusActive && (id == "" || key.Machine == id) {
+						lanes = append(lanes, fmt.Sprintf("%s/%s@%s", persona.Name, platform.Account, platform.Type))
+						break
+					}
+				}
+			}
+		}
+
+		label := machine.Name
+		if machine.ID == cfg.Machine.ID {
+			label += " (this machine)"
+		}
+		if len(lanes) == 0 {
+			fmt.Printf("%s: no active keys\n", label)
+			continue
+		}
+		printWrapped(label+": ", strings.Join(lanes, ", "))
+	}
+	fmt.Println()
+}
+
+// statusReport is the Status document of 'status --output json|yaml'
+type statusReport struct {
+	Initialized bool             `json:"initialized"`
+	Config      string           `json:"config"`
+	Profile     string           `json:"profile"`
+	Machine     reportMachine    `json:"machine"`
+	Overview    statusOverview   `json:"overview"`
+	Health      statusHealth     `json:"health"`
+	Personas    []statusPersona  `json:"personas"`
+	Overrides   []reportOverride `json:"overrides"`
+}
+
+type reportMachine struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type statusOverview struct {
+	Personas         int `json:"personas"`
+	ArchivedPersonas int `json:"archived_personas"`
+	Platforms        int `json:"platforms"`
+	Keys             int `json:"keys"`
+	ActiveKeys       int `json:"active_keys"`
+	RevokedKeys      int `json:"revoked_keys"`
+	ExpiredKeys      int `json:"expired_keys"`
+}
+
+type statusHealth struct {
+	OK               bool   `json:"ok"`
+	MissingKeyFiles  int    `json:"missing_key_files"`
+	ExpiredKeys      int    `json:"expired_keys"`
+	ExpiringSoon     int    `json:"expiring_soon"` // Within due_window
+	DueWindow        string `json:"due_window"`
+	NeedingRotation  int    `json:"needing_rotation"` // Older than 90 days
+	UnusedKeys       int    `json:"unused_keys"`
+	StaleBindings    int    `json:"stale_bindings"`
+	MachineNameStale bool   `json:"machine_name_stale"`
+}
+
+type statusPersona struct {
+	Name      string           `json:"name"`
+	Email     string           `json:"email"`
+	Archived  bool             `json:"archived"`
+	Platforms []statusPlatform `json:"platforms"`
+}
+
+type statusPlatform struct {
+	Type    string      `json:"type"`
+	BaseURL string      `json:"base_url,omitempty"`
+	Account string      `json:"account"`
+	Keys    []statusKey `json:"keys"`
+	Repos   []string    `json:"repos"`
+}
+
+type statusKey struct {
+	Fingerprint string    `json:"fingerprint"`
+	Type        string    `json:"type"`
+	Status      string    `json:"status"`
+	CreatedAt   time.Time `json:"created_at,omitzero"`
+	ExpiresAt   time.Time `json:"expires_at,omitzero"`
+}
+
+// newStatusReport describes the personas of cfg; the caller fills in the
+// overview and health
+func newStatusReport(configPath string, cfg *config.Config) statusReport {
+	report := statusReport{
+		Initialized: true,
+		Config:      configPath,
+		Profile:     profile.Name(),
+		Machine:     reportMachine{ID: cfg.Machine.ID, Name: cfg.Machine.Name},
+		Personas:    []statusPersona{},
+		Overrides:   []reportOverride{},
+	}
+	for _, o := range cfg.EnvOverrides() {
+		report.Overrides = append(report.Overrides, reportOverride{Key: o.Key, Value: o.Value, Var: o.Var})
+	}
+
+	for i := range cfg.Personas {
+		persona := &cfg.Personas[i]
+		sp := statusPersona{Name: persona.Name, Email: persona.Email, Archived: persona.Archived, Platforms: []statusPlatform{}}
+		for j := range persona.Platforms {
+			plat := &persona.Platforms[j]
+			pp := statusPlatform{
+				Type:    string(plat.Type),
+				BaseURL: plat.BaseURL,
+				Account: plat.Account,
+				Keys:    []statusKey{},
+				Repos:   append([]string{}, plat.Repos...),
+			}
+			for k := range plat.Keys {
+				key := &plat.Keys[k]
+				expiresAt, _ := cfg.KeyExpiry(persona, plat, key)
+				pp.Keys = append(pp.Keys, statusKey{
+					Fingerprint: key.Fingerprint,
+					Type:        string(key.Type),
+					Status:      string(key.Status),
+					CreatedAt:   key.CreatedAt,
+					ExpiresAt:   expiresAt,
+				})
+			}
+			sp.Platforms = append(sp.Platforms, pp)
+		}
+		report.Personas = append(report.Personas, sp)
+	}
+	return report
+}
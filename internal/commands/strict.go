@@ -0,0 +1,121 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/kunlu/git-keys/internal/config"
+	"github.com/kunlu/git-keys/internal/sshkey"
+	"github.com/spf13/cobra"
+)
+
+// minRSABits is the smallest RSA key size that is not reported as weak
+const minRSABits = 2048
+
+// recommendedRSABits is the smallest RSA key size recommended for keys that
+// stay in use past 2030. Audits of keys outside git-keys' control grade
+// smaller RSA keys as weak.
+const recommendedRSABits = 3072
+
+// strictMode reports whether warnings are treated as errors: the --strict
+// flag when given (including --strict=false), otherwise defaults.strict
+func strictMode(cmd *cobra.Command, cfg *config.Config) bool {
+	if flag := cmd.Flags().Lookup("strict"); flag != nil && flag.Changed {
+		strict, _ := cmd.Flags().GetBool("strict")
+		return strict
+	}
+	return cfg.Defaults.Strict
+}
+
+// noGitDirWarning returns a warning when no directory selects the platform's
+// identity, so commits never use it
+func noGitDirWarning(persona *config.Persona, plat *config.Platform) string {
+	if plat.HasIncludeConditions() || len(plat.Repos) > 0 {
+		return ""
+	}
+	return fmt.Sprintf("Platform %s/%s@%s has no gitdir or bound repositories", persona.Name, plat.Type, plat.Account)
+}
+
+// weakKeyWarning returns a warning when the public key of key is DSA or RSA
+// shorter than minRSABits. Unreadable keys are reported by other checks.
+func weakKeyWarning(keyMgr *sshkey.Manager, persona *config.Persona, plat *config.Platform, key *config.KeyConfig) string {
+	publicKey, err := keyMgr.GetPublicKey(key.LocalPath)
+	if err != nil {
+		return ""
+	}
+	info, err := sshkey.ParsePublicKey(publicKey)
+	if err != nil {
+		return ""
+	}
+
+	switch {
+	case info.Type == "ssh-dss":
+		return fmt.Sprintf("Weak key in %s/%s@%s: %s is DSA", persona.Name, plat.Type, plat.Account, key.LocalPath)
+	case info.Type == "ssh-rsa" && info.Bits < minRSABits:
+		return fmt.Sprintf("Weak key in %s/%s@%s: %s is RSA %d bits (minimum %d)",
+			persona.Name, plat.Type, plat.Account, key.LocalPath, info.Bits, minRSABits)
+	}
+	return ""
+}
+
+// hygieneWarnings collects the warnings strict mode refuses to run with:
+// personas without an email, platforms without a gitdir, weak active keys and
+// insecure permissions. Platforms outside target are skipped.
+func hygieneWarnings(cfg *config.Config, keyMgr *sshkey.Manager, target *platformTarget) []string {
+	var warnings []string
+
+	for personaIdx := range cfg.Personas {
+		persona := &cfg.Personas[personaIdx]
+		if persona.Archived {
+			continue
+		}
+
+		targeted := false
+		for platformIdx := range persona.Platforms {
+			plat := &persona.Platforms[platformIdx]
+			if !target.includes(persona, plat) {
+				continue
+			}
+			targeted = true
+
+			if warning := noGitDirWarning(persona, plat); warning != "" {
+				warnings = append(warnings, warning)
+			}
+			if key := plat.GetActiveKey(); key != nil && key.LocalPath != "" {
+				if warning := weakKeyWarning(keyMgr, persona, plat, key); warning != "" {
+					warnings = append(warnings, warning)
+				}
+			}
+		}
+
+		if persona.Email == "" && (targeted || target == nil) {
+			warnings = append(warnings, fmt.Sprintf("Persona '%s' has no email", persona.Name))
+		}
+	}
+
+	issues, err := sshkey.CheckPermissions(filepath.Join(os.Getenv("HOME"), ".ssh"), keyMgr.KeysDir())
+	if err != nil {
+		warnings = append(warnings, fmt.Sprintf("Permission check incomplete: %v", err))
+	}
+	for _, issue := range issues {
+		warnings = append(warnings, fmt.Sprintf("Unexpected permissions on %s %s: %o (expected: %o)", issue.Kind, issue.Path, issue.Mode, issue.Expected))
+	}
+
+	return warnings
+}
+
+// checkStrict prints the hygiene warnings and fails when there are any
+func checkStrict(cfg *config.Config, keyMgr *sshkey.Manager, target *platformTarget) error {
+	warnings := hygieneWarnings(cfg, keyMgr, target)
+	if len(warnings) == 0 {
+		return nil
+	}
+
+	fmt.Printf("\n❌ Strict mode: %d warning(s) treated as errors\n", len(warnings))
+	for _, warning := range warnings {
+		printWrapped("   • ", warning)
+	}
+	fmt.Println("\nFix these ('git-keys validate --fix' repairs permissions), or run with --strict=false.")
+	return fmt.Errorf("strict mode: %d warning(s)", len(warnings))
+}
@@ -0,0 +1,124 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// Formats of --output
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+	formatYAML  = "yaml"
+)
+
+// OutputAPIVersion versions the documents printed by --output json|yaml.
+// Fields are only added within a version; renaming or removing one raises it.
+const OutputAPIVersion = "git-keys/v1"
+
+// structuredOutputAnnotation marks the commands that support --output
+// json|yaml. Its value says where the human-readable output goes meanwhile:
+// structuredQuiet drops it, structuredProgress sends it to stderr, for
+// commands that make changes or ask for confirmation.
+const (
+	structuredOutputAnnotation = "structured-output"
+	structuredQuiet            = "quiet"
+	structuredProgress         = "progress"
+)
+
+var (
+	// outputFormat is the value of --output
+	outputFormat string
+
+	// structuredStdout is the real stdout while --output json|yaml diverts
+	// the human-readable output; nil for table output
+	structuredStdout *os.File
+)
+
+// outputDocument wraps the data of every structured document, so consumers
+// can check what they read
+type outputDocument struct {
+	APIVersion string      `json:"api_version"`
+	Kind       string      `json:"kind"`
+	Data       interface{} `json:"data"`
+}
+
+// setupStructuredOutput checks --output for cmd and, for json and yaml,
+// diverts the human-readable output so stdout carries only the document
+func setupStructuredOutput(cmd *cobra.Command) error {
+	format := strings.ToLower(outputFormat)
+	switch format {
+	case "", formatTable:
+		return nil
+	case formatJSON, formatYAML:
+	default:
+		return fmt.Errorf("invalid --output %q (use table, json or yaml)", outputFormat)
+	}
+
+	mode := cmd.Annotations[structuredOutputAnnotation]
+	if mode == "" {
+		return fmt.Errorf("'%s' does not support --output %s", cmd.CommandPath(), format)
+	}
+	outputFormat = format
+
+	structuredStdout = os.Stdout
+	if mode == structuredProgress {
+		os.Stdout = os.Stderr
+		return nil
+	}
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", os.DevNull, err)
+	}
+	os.Stdout = devNull
+	return nil
+}
+
+// structuredOutput reports whether --output json or yaml is in effect
+func structuredOutput() bool {
+	return structuredStdout != nil
+}
+
+// printStructured prints data as a document of the given kind in the
+// --output format. YAML documents use the same keys as JSON ones.
+func printStructured(kind string, data interface{}) error {
+	doc := outputDocument{APIVersion: OutputAPIVersion, Kind: kind, Data: data}
+	out, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", kind, err)
+	}
+
+	if outputFormat == formatYAML {
+		// JSON is YAML; re-encoding a node keeps the order of the keys
+		var node yaml.Node
+		if err := yaml.Unmarshal(out, &node); err != nil {
+			return fmt.Errorf("failed to encode %s: %w", kind, err)
+		}
+		blockStyle(&node)
+		enc := yaml.NewEncoder(structuredStdout)
+		enc.SetIndent(2)
+		if err := enc.Encode(&node); err != nil {
+			return fmt.Errorf("failed to encode %s: %w", kind, err)
+		}
+		return enc.Close()
+	}
+
+	_, err = fmt.Fprintln(structuredStdout, string(out))
+	return err
+}
+
+// blockStyle resets the flow style of nodes parsed from JSON
+func blockStyle(node *yaml.Node) {
+	node.Style &^= yaml.FlowStyle
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!str" {
+		node.Style &^= yaml.DoubleQuotedStyle
+	}
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
@@ -0,0 +1,223 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/kunlu/git-keys/internal/config"
+	"github.com/kunlu/git-keys/internal/logger"
+	"github.com/kunlu/git-keys/internal/profile"
+	"github.com/kunlu/git-keys/internal/sshkey"
+)
+
+const (
+	// summaryFileName is the editor integration summary in ~/.git-keys
+	summaryFileName = "summary.json"
+
+	// summaryVersion is bumped only for changes that break readers; fields
+	// may be added without a bump
+	summaryVersion = 1
+)
+
+var (
+	// Config file state before the command ran; summaryConfigPath is empty
+	// when no command ran (e.g. help)
+	summaryConfigPath    string
+	summaryConfigBefore  configState
+	summaryConfigExisted bool
+)
+
+// editorSummary is the machine-readable description of the setup written to
+// ~/.git-keys/summary.json for editor extensions. It is a stable contract:
+// extensions read it instead of running git-keys on every editor event.
+type editorSummary struct {
+	Version     int                `json:"version"`
+	GeneratedAt time.Time          `json:"generated_at"`
+	Config      string             `json:"config"`
+	Machine     string             `json:"machine"`
+	Personas    []summaryPersona   `json:"personas"`
+	Directories []summaryDirectory `json:"directories"` // Most specific first
+}
+
+type summaryPersona struct {
+	Name      string            `json:"name"`
+	Email     string            `json:"email"`
+	Archived  bool              `json:"archived,omitempty"`
+	Platforms []summaryPlatform `json:"platforms"`
+}
+
+type summaryPlatform struct {
+	Type         string     `json:"type"`
+	Account      string     `json:"account"`
+	Host         string     `json:"host"`      // e.g. github.com
+	SSHAlias     string     `json:"ssh_alias"` // e.g. github.com.work, used in remote URLs
+	GitDir       string     `json:"gitdir,omitempty"`
+	Repos        []string   `json:"repos,omitempty"`
+	IdentityFile string     `json:"identity_file,omitempty"`
+	Fingerprint  string     `json:"fingerprint,omitempty"`
+	KeyExpiresAt *time.Time `json:"key_expires_at,omitempty"`
+}
+
+// summaryDirectory maps a directory to the persona platform that applies in
+// it. A "repo" binding applies to exactly that repository and overrides any
+// "gitdir", which applies to everything below it.
+type summaryDirectory struct {
+	Path     string `json:"path"`
+	Kind     string `json:"kind"` // "repo" or "gitdir"
+	Persona  string `json:"persona"`
+	Platform string `json:"platform"`
+	Account  string `json:"account"`
+	SSHAlias string `json:"ssh_alias"`
+}
+
+// getSummaryPath returns the path of the editor integration summary
+func getSummaryPath() string {
+	return filepath.Join(profile.Dir(), summaryFileName)
+}
+
+// buildEditorSummary describes the personas, SSH aliases and directory
+// mappings of cfg
+func buildEditorSummary(cfg *config.Config, configPath string) *editorSummary {
+	keyMgr := sshkey.NewManager(cfg.Defaults.GetKeysDir())
+	summary := &editorSummary{
+		Version:     summaryVersion,
+		GeneratedAt: time.Now().UTC(),
+		Config:      configPath,
+		Machine:     cfg.Machine.Name,
+		Personas:    []summaryPersona{},
+		Directories: []summaryDirectory{},
+	}
+
+	for personaIdx := range cfg.Personas {
+		persona := &cfg.Personas[personaIdx]
+		sp := summaryPersona{Name: persona.Name, Email: persona.Email, Archived: persona.Archived, Platforms: []summaryPlatform{}}
+
+		for platformIdx := range persona.Platforms {
+			plat := &persona.Platforms[platformIdx]
+			alias, host := sshHostAlias(persona, plat)
+			platform := summaryPlatform{
+				Type:     string(plat.Type),
+				Account:  plat.Account,
+				Host:     host,
+				SSHAlias: alias,
+				GitDir:   plat.GitDir,
+				Repos:    plat.Repos,
+			}
+			if key := plat.GetActiveKey(); key != nil {
+				if !key.PublicOnly {
+					platform.IdentityFile = sshkey.ExpandHome(keyMgr.IdentityFilePath(key.LocalPath))
+				}
+				platform.Fingerprint = key.Fingerprint
+				if !key.ExpiresAt.IsZero() {
+					expires := key.ExpiresAt.UTC()
+					platform.KeyExpiresAt = &expires
+				}
+			}
+			sp.Platforms = append(sp.Platforms, platform)
+
+			// Archived personas no longer apply anywhere
+			if persona.Archived {
+				continue
+			}
+			directory := summaryDirectory{Persona: persona.Name, Platform: string(plat.Type), Account: plat.Account, SSHAlias: alias}
+			for _, repo := range plat.Repos {
+				directory.Path, directory.Kind = repo, "repo"
+				summary.Directories = append(summary.Directories, directory)
+			}
+			for _, gitdir := range plat.AllGitDirs() {
+				directory.Path, directory.Kind = sshkey.ExpandHome(gitdir), "gitdir"
+				summary.Directories = append(summary.Directories, directory)
+			}
+		}
+		summary.Personas = append(summary.Personas, sp)
+	}
+
+	// Repository bindings first, then the longest gitdir, so the first entry
+	// containing a directory is the one that applies
+	sort.SliceStable(summary.Directories, func(i, j int) bool {
+		a, b := summary.Directories[i], summary.Directories[j]
+		if a.Kind != b.Kind {
+			return a.Kind == "repo"
+		}
+		return len(a.Path) > len(b.Path)
+	})
+
+	return summary
+}
+
+// writeEditorSummary writes the summary of cfg to path. The file is replaced
+// atomically so editors never read a partial file.
+func writeEditorSummary(cfg *config.Config, configPath, path string) error {
+	data, err := json.MarshalIndent(buildEditorSummary(cfg, configPath), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode summary: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create summary directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), summaryFileName+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write summary: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write summary: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write summary: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0600); err != nil {
+		return fmt.Errorf("failed to write summary: %w", err)
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// configState identifies a version of the config file, so a command that
+// changed it can be told apart from one that only read it
+type configState struct {
+	ModTime time.Time
+	Size    int64
+}
+
+func statConfig(configPath string) (configState, bool) {
+	info, err := os.Stat(configPath)
+	if err != nil {
+		return configState{}, false
+	}
+	return configState{ModTime: info.ModTime(), Size: info.Size()}, true
+}
+
+// refreshEditorSummary rewrites the editor summary after a command that
+// changed the config, or when there is no summary yet
+func refreshEditorSummary(configPath string, before configState, existedBefore bool) {
+	after, exists := statConfig(configPath)
+	if !exists {
+		return
+	}
+
+	summaryPath := getSummaryPath()
+	if summaryPath == "" {
+		return
+	}
+	_, err := os.Stat(summaryPath)
+	if existedBefore && after.ModTime.Equal(before.ModTime) && after.Size == before.Size && err == nil {
+		return // Nothing changed
+	}
+
+	cfg, err := config.NewManager(configPath).Load()
+	if err != nil {
+		logger.Debug("Not refreshing %s: %v", summaryPath, err)
+		return
+	}
+	if err := writeEditorSummary(cfg, configPath, summaryPath); err != nil {
+		logger.Warn("Failed to refresh editor summary: %v", err)
+	}
+}
@@ -0,0 +1,583 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kunlu/git-keys/internal/api"
+	"github.com/kunlu/git-keys/internal/config"
+	"github.com/kunlu/git-keys/internal/history"
+	"github.com/kunlu/git-keys/internal/logger"
+	"github.com/kunlu/git-keys/internal/prompt"
+	"github.com/kunlu/git-keys/internal/sshconfig"
+	"github.com/kunlu/git-keys/internal/sshkey"
+	"github.com/spf13/cobra"
+)
+
+var (
+	syncDryRun  bool
+	syncYes     bool
+	syncOffline bool
+)
+
+var syncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Reconcile the actual setup with the configuration",
+	Long: `Read the actual state of everything git-keys manages, compare it with the
+configuration, and change only what differs.
+
+The actual state is read from:
+  - local key files and their permissions
+  - the git-keys managed blocks in the SSH config
+  - the per-platform git config files and the includeIf section of ~/.gitconfig
+  - the SSH keys registered on each platform
+
+Changes are shown as a plan before anything is written:
+  +  something is created (a key, an SSH config block, an upload)
+  ~  something is updated to match the configuration
+  -  something is removed: SSH config blocks of no configured platform, and
+     revoked or archived keys still registered on a platform
+  !  a difference sync cannot reconcile, with the command that does
+
+'git-keys plan' shows the same plan without applying it.
+
+Running sync again right after it succeeds finds nothing to change. Unlike
+apply, sync never prompts for directory patterns or tokens; platforms without
+a gitdir or token are reported instead. Use --offline to skip the platforms.
+
+Examples:
+  # Show the plan without changing anything
+  git-keys sync --dry-run
+
+  # Reconcile without prompting
+  git-keys sync --yes
+
+  # Reconcile local files only
+  git-keys sync --offline
+`,
+	RunE:         runSync,
+	SilenceUsage: true,
+}
+
+func init() {
+	syncCmd.Flags().BoolVar(&syncDryRun, "dry-run", false, "Show the plan without making changes")
+	syncCmd.Flags().BoolVarP(&syncYes, "yes", "y", false, "Skip confirmation prompt")
+	syncCmd.Flags().BoolVar(&syncOffline, "offline", false, "Skip the remote keys on the platforms")
+	rootCmd.AddCommand(syncCmd)
+}
+
+const (
+	syncCreate = "+"
+	syncUpdate = "~"
+	syncDelete = "-"
+	syncManual = "!"
+)
+
+// syncChange is a difference between the configuration and the actual state,
+// with the action that reconciles it
+type syncChange struct {
+	Op            string // syncCreate, syncUpdate, syncDelete or syncManual
+	Resource      string // e.g. "ssh_config github.com.work"
+	Detail        string
+	UpdatesConfig bool         // Apply changes the configuration, which is saved afterwards
+	Apply         func() error // nil for syncManual
+}
+
+// syncPlan collects changes while the actual state is read
+type syncPlan struct {
+	changes []syncChange
+}
+
+func (p *syncPlan) add(op, resource, detail string, updatesConfig bool, apply func() error) {
+	p.changes = append(p.changes, syncChange{Op: op, Resource: resource, Detail: detail, UpdatesConfig: updatesConfig, Apply: apply})
+}
+
+func (p *syncPlan) manual(resource, detail string) {
+	p.add(syncManual, resource, detail, false, nil)
+}
+
+func runSync(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	// Load configuration
+	configPath := cfgFile
+	if configPath == "" {
+		configPath = config.GetDefaultConfigPath()
+	}
+
+	mgr := config.NewManager(configPath)
+	if !mgr.Exists() {
+		return fmt.Errorf("configuration file not found at %s\nRun 'git-keys init' first", configPath)
+	}
+
+	cfg, err := mgr.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	keyMgr := sshkey.NewManager(cfg.Defaults.GetKeysDir())
+	sshMgr := sshConfigManager(cfg)
+
+	printHeader("\n🔄 Sync")
+	fmt.Printf("\n  Config: %s\n\n", configPath)
+
+	plan, failures, err := buildSyncPlan(ctx, cfg, keyMgr, sshMgr, syncOffline)
+	if err != nil {
+		return err
+	}
+
+	if len(plan.changes) == 0 {
+		fmt.Println("✓ Actual state matches the configuration. Nothing to change.")
+		printRelinkFailures(failures)
+		return nil
+	}
+
+	counts := plan.print()
+	printRelinkFailures(failures)
+
+	if counts[syncManual] == len(plan.changes) {
+		return nil
+	}
+
+	if syncDryRun {
+		fmt.Println("\n🔍 DRY RUN: no changes made. Run without --dry-run to apply the plan.")
+		return nil
+	}
+
+	if !syncYes {
+		if !prompt.Confirm("\nApply this plan?") {
+			fmt.Println("Sync cancelled.")
+			return nil
+		}
+	}
+	fmt.Println()
+
+	// Changes run in plan order, so a key is generated before its SSH config
+	// block is written and it is uploaded
+	details := make(map[string]string)
+	configChanged := false
+	failed := 0
+	for _, c := range plan.changes {
+		if c.Apply == nil {
+			continue
+		}
+		if err := c.Apply(); err != nil {
+			failed++
+			logger.Warn("Sync of %s failed: %v", c.Resource, err)
+			fmt.Printf("❌ %s: %v\n", c.Resource, err)
+			continue
+		}
+		configChanged = configChanged || c.UpdatesConfig
+		details[c.Op+" "+c.Resource] = c.Detail
+		fmt.Printf("✓ %s\n", c.Resource)
+	}
+
+	if configChanged {
+		if err := mgr.Save(cfg); err != nil {
+			return fmt.Errorf("failed to save configuration: %w", err)
+		}
+	}
+
+	if len(details) > 0 {
+		histMgr := history.NewManager("")
+		if err := histMgr.Record(history.Entry{
+			Action:  "sync",
+			Summary: fmt.Sprintf("Reconciled %d difference(s) with the configuration", len(details)),
+			Details: details,
+		}); err != nil {
+			logger.Warn("Failed to record history: %v", err)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d change(s) failed", failed)
+	}
+	fmt.Printf("\n✅ Applied %d change(s).\n", len(details))
+	return nil
+}
+
+// buildSyncPlan reads the actual state and returns the changes that make it
+// match the configuration, and the platforms that could not be checked
+func buildSyncPlan(ctx context.Context, cfg *config.Config, keyMgr *sshkey.Manager, sshMgr *sshconfig.Manager, offline bool) (*syncPlan, []string, error) {
+	// New keys and titles use the recorded machine name, as fix does
+	machineName := cfg.Machine.Name
+	if machineName == "" {
+		machineName = "unknown"
+	}
+
+	plan := &syncPlan{}
+	generated := plan.diffKeys(cfg, keyMgr, machineName)
+	if err := plan.diffSSHConfig(cfg, sshMgr, keyMgr, generated); err != nil {
+		return nil, nil, err
+	}
+	if err := plan.diffGitConfig(cfg); err != nil {
+		return nil, nil, err
+	}
+	if offline {
+		return plan, nil, nil
+	}
+	return plan, plan.diffRemote(ctx, cfg, keyMgr, machineName, generated), nil
+}
+
+// syncOpColors are the ANSI colors of each kind of change
+var syncOpColors = map[string]string{
+	syncCreate: ansiGreen,
+	syncUpdate: ansiYellow,
+	syncDelete: ansiRed,
+	syncManual: ansiMagenta,
+}
+
+// print lists the changes and a summary line, and returns the number of
+// changes of each kind
+func (p *syncPlan) print() map[string]int {
+	counts := make(map[string]int)
+	for _, c := range p.changes {
+		counts[c.Op]++
+		fmt.Printf("  %s %s\n", colorize(syncOpColors[c.Op], c.Op), c.Resource)
+		printWrapped("      ", c.Detail)
+	}
+	fmt.Printf("\nPlan: %s to create, %s to update, %s to delete, %d needing manual action.\n",
+		colorize(ansiGreen, fmt.Sprint(counts[syncCreate])),
+		colorize(ansiYellow, fmt.Sprint(counts[syncUpdate])),
+		colorize(ansiRed, fmt.Sprint(counts[syncDelete])),
+		counts[syncManual])
+	return counts
+}
+
+// diffKeys compares the active keys with the key files. It returns the
+// platforms that get a new key, whose other resources cannot be read yet.
+func (p *syncPlan) diffKeys(cfg *config.Config, keyMgr *sshkey.Manager, machineName string) map[*config.Platform]bool {
+	generated := make(map[*config.Platform]bool)
+
+	doctorPlatforms(cfg, func(persona *config.Persona, plat *config.Platform, label string) {
+		key := plat.GetActiveKey()
+		switch {
+		case key == nil && plat.UsesExternalAgent():
+			generated[plat] = true
+			p.add(syncCreate, "key "+label, "record the public key held by "+plat.IdentityAgent, true, func() error {
+				agentKey, err := adoptAgentKey(keyMgr, cfg, persona, plat, machineName)
+				if err != nil {
+					return err
+				}
+				plat.Keys = append(plat.Keys, *agentKey)
+				return nil
+			})
+		case key == nil:
+			generated[plat] = true
+			settings := cfg.ResolveKeySettings(persona, plat)
+			p.add(syncCreate, "key "+label, fmt.Sprintf("generate a new %s key", settings.Type), true, func() error {
+				newKey, err := generatePlatformKey(cfg, keyMgr, persona, plat, machineName)
+				if err != nil {
+					return err
+				}
+				plat.Keys = append(plat.Keys, *newKey)
+				return nil
+			})
+		case key.PublicOnly && key.Fingerprint == "":
+			p.add(syncUpdate, "key "+label, "record the type and fingerprint of "+key.LocalPath, true, func() error {
+				return fillPublicOnlyKey(keyMgr, plat.GetActiveKey())
+			})
+		case !keyMgr.KeyExists(key.LocalPath):
+			p.manual("key "+label, fmt.Sprintf("key file %s is missing; restore it with 'git-keys trash restore' or run 'git-keys rotate'", key.LocalPath))
+		default:
+			pubKeyPath := strings.TrimSuffix(key.LocalPath, ".pub") + ".pub"
+			if actual, err := keyMgr.GetFingerprint(pubKeyPath); err == nil && key.Fingerprint != "" && actual != key.Fingerprint {
+				p.manual("key "+label, fmt.Sprintf("%s has fingerprint %s, not %s; run 'git-keys validate'", key.LocalPath, actual, key.Fingerprint))
+			}
+		}
+	})
+
+	sshDir := filepath.Join(os.Getenv("HOME"), ".ssh")
+	issues, err := sshkey.CheckPermissions(sshDir, cfg.Defaults.GetKeysDir())
+	if err != nil {
+		logger.Warn("Permission check incomplete: %v", err)
+	}
+	for _, issue := range issues {
+		p.add(syncUpdate, "permissions "+issue.Path, fmt.Sprintf("%s mode %o → %o", issue.Kind, issue.Mode, issue.Expected), false, issue.Fix)
+	}
+
+	return generated
+}
+
+// diffSSHConfig compares the managed SSH config blocks with the blocks apply
+// writes for each platform's active key
+func (p *syncPlan) diffSSHConfig(cfg *config.Config, sshMgr *sshconfig.Manager, keyMgr *sshkey.Manager, generated map[*config.Platform]bool) error {
+	blocks, err := sshMgr.ManagedBlocks()
+	if err != nil {
+		return fmt.Errorf("failed to read SSH config: %w", err)
+	}
+	byID := make(map[string]sshconfig.ManagedBlock)
+	for _, block := range blocks {
+		byID[block.ID] = block
+	}
+
+	desired := make(map[string]bool)
+	renamed := make(map[string]bool)
+	doctorPlatforms(cfg, func(persona *config.Persona, plat *config.Platform, label string) {
+		blockID := sshconfig.GetManagedBlockID(persona.Name, plat.Type, plat.Account)
+		desired[blockID] = true
+
+		key := plat.GetActiveKey()
+		if key != nil && (key.PublicOnly || !keyMgr.KeyExists(key.LocalPath)) {
+			return // Public-only keys get no block; missing keys are reported already
+		}
+
+		alias, _ := sshHostAlias(persona, plat)
+		update := func() error {
+			key := plat.GetActiveKey()
+			if key == nil {
+				return fmt.Errorf("no active key")
+			}
+			return updateSSHConfig(cfg, sshMgr, keyMgr, persona, plat, key)
+		}
+
+		block, exists := byID[blockID]
+		if !exists {
+			// A persona or account rename leaves the block under its old ID,
+			// which apply moves instead of adding a new one
+			entry := sshconfig.Entry{Host: alias}
+			if key != nil {
+				entry.IdentityFile = keyMgr.IdentityFilePath(key.LocalPath)
+			}
+			if oldID, err := findRenamedBlock(cfg, sshMgr, blockID, entry); err == nil && oldID != "" {
+				renamed[oldID] = true
+				p.add(syncUpdate, "ssh_config "+alias, fmt.Sprintf("rename managed block %s → %s", oldID, blockID), false, update)
+				return
+			}
+		}
+
+		switch {
+		case generated[plat] && exists:
+			p.add(syncUpdate, "ssh_config "+alias, "use the new key", false, update)
+		case generated[plat] || !exists:
+			p.add(syncCreate, "ssh_config "+alias, fmt.Sprintf("add managed block %s", blockID), false, update)
+		case !contains(block.Hosts, alias):
+			p.add(syncUpdate, "ssh_config "+alias, fmt.Sprintf("block %s declares Host %s", blockID, strings.Join(block.Hosts, " ")), false, update)
+		default:
+			identityFile := keyMgr.IdentityFilePath(key.LocalPath)
+			if !contains(block.IdentityFiles, identityFile) {
+				p.add(syncUpdate, "ssh_config "+alias, fmt.Sprintf("IdentityFile %s → %s", strings.Join(block.IdentityFiles, ", "), identityFile), false, update)
+			}
+		}
+	})
+
+	for _, block := range blocks {
+		// Blocks written by import and rotate are not per-persona blocks
+		if desired[block.ID] || renamed[block.ID] || strings.HasPrefix(block.ID, "git-keys-") {
+			continue
+		}
+		blockID := block.ID
+		p.add(syncDelete, "ssh_config "+strings.Join(block.Hosts, " "), fmt.Sprintf("remove managed block %s, which belongs to no configured platform", blockID), false, func() error {
+			return sshMgr.RemoveEntry(blockID)
+		})
+	}
+
+	return nil
+}
+
+// diffGitConfig compares the per-platform git config files and the managed
+// includeIf section of ~/.gitconfig with what apply writes for each gitdir
+func (p *syncPlan) diffGitConfig(cfg *config.Config) error {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return fmt.Errorf("failed to get home directory: %w", err)
+	}
+
+	var includeEntries []string
+	doctorPlatforms(cfg, func(persona *config.Persona, plat *config.Platform, label string) {
+		if !plat.HasIncludeConditions() {
+			return // Nothing is desired until setup-git records a gitdir
+		}
+
+		platformID := fmt.Sprintf("%s-%s", string(plat.Type), plat.Account)
+		configPath := filepath.Join(home, platformGitConfigName(persona.Name, platformID))
+		includeEntries = append(includeEntries, platformIncludeEntries(plat, configPath)...)
+
+		write := func() error {
+			return createPlatformGitConfigFile(cfg, persona, plat, configPath)
+		}
+		data, err := os.ReadFile(configPath)
+		switch {
+		case os.IsNotExist(err):
+			p.add(syncCreate, "gitconfig "+configPath, fmt.Sprintf("identity %s <%s> for %s", persona.Name, persona.Email, strings.Join(platformIncludeConditions(plat), ", ")), false, write)
+		case err != nil:
+			p.manual("gitconfig "+configPath, err.Error())
+		case string(data) != platformGitConfigContent(cfg, persona, plat):
+			p.add(syncUpdate, "gitconfig "+configPath, "rewrite to match the persona's identity and SSH host", false, write)
+		}
+	})
+
+	if len(includeEntries) == 0 {
+		return nil
+	}
+
+	globalGitConfig := filepath.Join(home, ".gitconfig")
+	var existing string
+	if data, err := os.ReadFile(globalGitConfig); err == nil {
+		existing = string(data)
+	}
+	write := func() error {
+		return addGitConfigIncludes(globalGitConfig, includeEntries)
+	}
+
+	startIdx := strings.Index(existing, gitConfigManagedStart())
+	endIdx := strings.Index(existing, gitConfigManagedEnd())
+	switch {
+	case startIdx < 0:
+		p.add(syncCreate, "gitconfig "+globalGitConfig, fmt.Sprintf("add %d includeIf entries", len(includeEntries)), false, write)
+	case endIdx < startIdx:
+		p.manual("gitconfig "+globalGitConfig, "the managed includeIf section has no end marker; fix it by hand, then run sync again")
+	case existing[startIdx+len(gitConfigManagedStart())+1:endIdx] != strings.Join(includeEntries, "\n"):
+		p.add(syncUpdate, "gitconfig "+globalGitConfig, fmt.Sprintf("rewrite the managed section with %d includeIf entries", len(includeEntries)), false, write)
+	}
+
+	return nil
+}
+
+// diffRemote compares the active keys with the keys registered on each
+// platform. It returns the platforms that could not be checked.
+func (p *syncPlan) diffRemote(ctx context.Context, cfg *config.Config, keyMgr *sshkey.Manager, machineName string, generated map[*config.Platform]bool) []string {
+	links, failures := findRemoteDrift(ctx, cfg)
+
+	registered := make(map[*config.KeyConfig]bool)
+	for _, link := range links {
+		if link.NewID == "" {
+			continue // Cleared below when the active key is uploaded again
+		}
+		registered[link.Key] = true
+
+		plat, fingerprint, newID := link.Platform, link.Key.Fingerprint, link.NewID
+		p.add(syncUpdate, "remote_id "+link.label(), fmt.Sprintf("%s → %s (%s)", relinkID(link.OldID), newID, link.Title), true, func() error {
+			// Looked up again since generating a key may move the platform's keys
+			for keyIdx := range plat.Keys {
+				if plat.Keys[keyIdx].Fingerprint == fingerprint {
+					plat.Keys[keyIdx].RemoteID = newID
+					return nil
+				}
+			}
+			return fmt.Errorf("key %s is no longer configured", fingerprint)
+		})
+	}
+
+	envTokens := loadTokensFromEnv()
+	doctorPlatforms(cfg, func(persona *config.Persona, plat *config.Platform, label string) {
+		if !generated[plat] {
+			key := plat.GetActiveKey()
+			if key == nil || registered[key] || key.RemoteID != "" && !syncKeyGone(links, key) {
+				return // Registered, or registered by hand and re-linked above
+			}
+		}
+
+		token, _, err := lookupToken(plat, envTokens)
+		if err != nil {
+			p.manual("remote_key "+label, fmt.Sprintf("active key is not registered and cannot be uploaded: %v; run 'git-keys token set %s %s'", err, plat.Type, plat.Account))
+			return
+		}
+
+		p.add(syncCreate, "remote_key "+label, fmt.Sprintf("upload the active key to %s@%s", plat.Account, plat.Type), true, func() error {
+			key := plat.GetActiveKey()
+			if key == nil {
+				return fmt.Errorf("no active key")
+			}
+			settings := cfg.ResolveKeySettings(persona, plat)
+			title, err := sshkey.RenderTitle(settings, sshkey.DefaultTitleTemplate,
+				sshkey.NewNameData(persona, plat, key.Type, machineName, time.Now()), key.ExpiresAt)
+			if err != nil {
+				return err
+			}
+			return uploadKeyWithToken(ctx, keyMgr, plat, key, title, token)
+		})
+	})
+
+	return append(failures, p.diffRetiredKeys(ctx, cfg, failures)...)
+}
+
+// diffRetiredKeys finds revoked keys, and keys of archived personas, that
+// are still registered on their platform. failures lists the platforms
+// that could not be checked already.
+func (p *syncPlan) diffRetiredKeys(ctx context.Context, cfg *config.Config, failures []string) []string {
+	var newFailures []string
+
+	for personaIdx := range cfg.Personas {
+		persona := &cfg.Personas[personaIdx]
+		for platformIdx := range persona.Platforms {
+			plat := &persona.Platforms[platformIdx]
+			label := fmt.Sprintf("%s/%s@%s", persona.Name, plat.Type, plat.Account)
+
+			var retired []string // Fingerprints
+			for _, key := range plat.Keys {
+				if key.Fingerprint != "" && (persona.Archived || key.Status == config.KeyStatusRevoked) {
+					retired = append(retired, key.Fingerprint)
+				}
+			}
+			if len(retired) == 0 {
+				continue
+			}
+
+			client, err := newPlatformClient(plat)
+			if err != nil {
+				reported := false
+				for _, f := range failures {
+					reported = reported || strings.HasPrefix(f, label+":")
+				}
+				if !reported {
+					newFailures = append(newFailures, fmt.Sprintf("%s: %v", label, err))
+				}
+				continue
+			}
+
+			// The listing was just refreshed when checking the active keys
+			remoteKeys, _, err := api.ListKeysCached(ctx, client, false)
+			if err != nil {
+				newFailures = append(newFailures, fmt.Sprintf("%s: failed to list remote keys: %v", label, err))
+				continue
+			}
+			byFingerprint := make(map[string]api.SSHKey)
+			for _, remote := range remoteKeys {
+				if info, err := sshkey.ParsePublicKey(remote.Key); err == nil {
+					byFingerprint[strings.TrimPrefix(info.Fingerprint, "SHA256:")] = remote
+				}
+			}
+
+			reason := "revoked key"
+			if persona.Archived {
+				reason = "key of archived persona"
+			}
+			for _, fingerprint := range retired {
+				remote, registered := byFingerprint[strings.TrimPrefix(fingerprint, "SHA256:")]
+				if !registered {
+					continue
+				}
+
+				fingerprint, remoteID := fingerprint, remote.ID
+				p.add(syncDelete, "remote_key "+label, fmt.Sprintf("delete %q, a %s still registered (%s)", remote.Title, reason, fingerprint), true, func() error {
+					if err := deleteCheckedKey(ctx, client, checkRemoteKey(ctx, client, remoteID, fingerprint)); err != nil {
+						return err
+					}
+					for keyIdx := range plat.Keys {
+						if plat.Keys[keyIdx].RemoteID == remoteID {
+							plat.Keys[keyIdx].RemoteID = ""
+						}
+					}
+					return nil
+				})
+			}
+		}
+	}
+
+	return newFailures
+}
+
+// syncKeyGone reports whether the remote entry recorded for key no longer exists
+func syncKeyGone(links []remoteLink, key *config.KeyConfig) bool {
+	for _, link := range links {
+		if link.Key == key && link.NewID == "" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,474 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kunlu/git-keys/internal/api"
+	"github.com/kunlu/git-keys/internal/config"
+	"github.com/kunlu/git-keys/internal/prompt"
+	"github.com/spf13/cobra"
+)
+
+// tokenExpiryWarning is how far ahead token check warns about expiring tokens
+const tokenExpiryWarning = 14 * 24 * time.Hour
+
+var tokenCmd = &cobra.Command{
+	Use:   "token",
+	Short: "Manage platform API tokens",
+	Long: `Manage the GitHub and GitLab API tokens git-keys uses to register keys.
+
+Tokens are stored in the keychain (services git-keys-github and
+git-keys-gitlab), keyed by account name. A token stored for the account
+"default" is used for accounts without their own token. With --profile, the
+services end in the profile name (git-keys-github-<profile>).
+
+Subcommands:
+  set      - Store a token for a platform account
+  get      - Show a stored token (masked unless --reveal)
+  delete   - Remove a stored token
+  list     - Show which configured accounts have tokens
+  migrate  - Move tokens from .env into the keychain
+  check    - Verify stored tokens are valid, unexpired and have the needed scopes
+`,
+}
+
+var tokenSetCmd = &cobra.Command{
+	Use:   "set <github|gitlab> <account>",
+	Short: "Store an API token in the keychain",
+	Long: `Store the API token for a platform account in the keychain.
+
+The token is read from a hidden prompt, or from standard input with --stdin so
+it does not end up in shell history.
+
+Examples:
+  git-keys token set github myusername
+  echo "$GITLAB_TOKEN" | git-keys token set gitlab workuser --stdin
+`,
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: completeTokenArgs,
+	RunE:              runTokenSet,
+}
+
+var tokenGetCmd = &cobra.Command{
+	Use:               "get <github|gitlab> <account>",
+	Short:             "Show a stored API token",
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: completeTokenArgs,
+	RunE:              runTokenGet,
+}
+
+var tokenDeleteCmd = &cobra.Command{
+	Use:               "delete <github|gitlab> <account>",
+	Short:             "Remove a stored API token",
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: completeTokenArgs,
+	RunE:              runTokenDelete,
+}
+
+var tokenListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Show which configured accounts have tokens",
+	Args:  cobra.NoArgs,
+	RunE:  runTokenList,
+}
+
+var tokenMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Move tokens from .env into the keychain",
+	Long: `Store every GITHUB_API_TOKEN_<account> and GITLAB_TOKEN_<account> entry
+from .env in the keychain. The .env file is not modified; delete the token
+lines yourself once the migration succeeded.
+`,
+	Args: cobra.NoArgs,
+	RunE: runTokenMigrate,
+}
+
+var (
+	tokenSetStdin  bool
+	tokenGetReveal bool
+)
+
+var tokenCheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Verify API tokens before running apply",
+	Long: `Verify the API token of each configured platform account.
+
+Tokens are looked up like 'git-keys apply' does: in .env first, then in the
+keychain. For each token this command checks that:
+  - The platform accepts it
+  - It has not expired (and warns if it expires within 14 days)
+  - It has the scopes git-keys needs:
+      GitHub: admin:public_key (write:public_key cannot delete keys)
+      GitLab: api
+
+GitHub fine-grained tokens do not report scopes; they are checked for
+validity and expiry only.
+
+Exits with an error if any token is missing, invalid or lacks scopes.
+`,
+	RunE: runTokenCheck,
+}
+
+func init() {
+	tokenSetCmd.Flags().BoolVar(&tokenSetStdin, "stdin", false, "Read the token from standard input")
+	tokenGetCmd.Flags().BoolVar(&tokenGetReveal, "reveal", false, "Print the full token")
+
+	tokenCmd.AddCommand(tokenSetCmd)
+	tokenCmd.AddCommand(tokenGetCmd)
+	tokenCmd.AddCommand(tokenDeleteCmd)
+	tokenCmd.AddCommand(tokenListCmd)
+	tokenCmd.AddCommand(tokenMigrateCmd)
+	tokenCmd.AddCommand(tokenCheckCmd)
+	rootCmd.AddCommand(tokenCmd)
+}
+
+// tokenManagerFor parses a platform argument and returns its token manager
+func tokenManagerFor(platformArg string) (*api.TokenManager, config.PlatformType, error) {
+	platformType := config.PlatformType(strings.ToLower(platformArg))
+	service, err := tokenServiceName(platformType)
+	if err != nil {
+		return nil, "", fmt.Errorf("unknown platform %q (use github or gitlab)", platformArg)
+	}
+	return api.NewTokenManager(service), platformType, nil
+}
+
+func runTokenSet(cmd *cobra.Command, args []string) error {
+	tokenMgr, platformType, err := tokenManagerFor(args[0])
+	if err != nil {
+		return err
+	}
+	account := args[1]
+
+	if nonInteractive && !tokenSetStdin {
+		return errNonInteractive("'token set'", "pass the token with --stdin")
+	}
+
+	var token string
+	if tokenSetStdin {
+		token, _ = prompt.Line()
+	} else {
+		token = prompt.Secret(fmt.Sprintf("API token for %s@%s", account, platformType))
+	}
+
+	if token == "" {
+		return fmt.Errorf("no token provided")
+	}
+
+	if err := tokenMgr.SetToken(account, token); err != nil {
+		return err
+	}
+
+	fmt.Printf("✓ Stored token for %s@%s\n", account, platformType)
+	fmt.Println("  Run 'git-keys token check' to verify its scopes.")
+	return nil
+}
+
+func runTokenGet(cmd *cobra.Command, args []string) error {
+	tokenMgr, platformType, err := tokenManagerFor(args[0])
+	if err != nil {
+		return err
+	}
+	account := args[1]
+
+	token, err := tokenMgr.GetToken(account)
+	if err != nil {
+		return fmt.Errorf("no token stored for %s@%s", account, platformType)
+	}
+
+	if tokenGetReveal {
+		fmt.Println(token)
+	} else {
+		fmt.Printf("%s@%s: %s\n", account, platformType, maskToken(token))
+	}
+	return nil
+}
+
+func runTokenDelete(cmd *cobra.Command, args []string) error {
+	tokenMgr, platformType, err := tokenManagerFor(args[0])
+	if err != nil {
+		return err
+	}
+	account := args[1]
+
+	if _, err := tokenMgr.GetToken(account); err != nil {
+		fmt.Printf("No token stored for %s@%s\n", account, platformType)
+		return nil
+	}
+
+	if err := tokenMgr.DeleteToken(account); err != nil {
+		return err
+	}
+
+	fmt.Printf("✓ Deleted token for %s@%s\n", account, platformType)
+	return nil
+}
+
+func runTokenList(cmd *cobra.Command, args []string) error {
+	// Load configuration
+	configPath := cfgFile
+	if configPath == "" {
+		configPath = config.GetDefaultConfigPath()
+	}
+
+	mgr := config.NewManager(configPath)
+	if !mgr.Exists() {
+		return fmt.Errorf("configuration file not found at %s\nRun 'git-keys init' first", configPath)
+	}
+
+	cfg, err := mgr.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	printHeader("\n🔐 API Tokens")
+	fmt.Println()
+
+	envTokens := loadTokensFromEnv()
+	listed := make(map[string]bool)
+
+	for _, persona := range cfg.Personas {
+		if persona.Archived {
+			continue
+		}
+		for _, plat := range persona.Platforms {
+			id := fmt.Sprintf("%s|%s", plat.Type, plat.Account)
+			if listed[id] {
+				continue
+			}
+			listed[id] = true
+
+			source := "❌ no token"
+			if tokenKey, err := envTokenKey(plat.Type, plat.Account); err == nil && envTokens[tokenKey] != "" {
+				source = "✓ .env"
+			} else if service, err := tokenServiceName(plat.Type); err == nil {
+				tokenMgr := api.NewTokenManager(service)
+				if _, err := tokenMgr.GetToken(plat.Account); err == nil {
+					source = "✓ keychain"
+				} else if _, err := tokenMgr.GetToken("default"); err == nil {
+					source = "✓ keychain (default)"
+				}
+			}
+
+			fmt.Printf("  %-30s %s\n", fmt.Sprintf("%s@%s", plat.Account, plat.Type), source)
+		}
+	}
+
+	fmt.Println()
+	return nil
+}
+
+func runTokenMigrate(cmd *cobra.Command, args []string) error {
+	envTokens := loadTokensFromEnv()
+
+	prefixes := map[string]config.PlatformType{
+		"GITHUB_API_TOKEN_": config.PlatformGitHub,
+		"GITLAB_TOKEN_":     config.PlatformGitLab,
+	}
+
+	keys := make([]string, 0, len(envTokens))
+	for key := range envTokens {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var migrated []string
+	for _, key := range keys {
+		token := envTokens[key]
+		for prefix, platformType := range prefixes {
+			account, ok := strings.CutPrefix(key, prefix)
+			if !ok || account == "" || token == "" {
+				continue
+			}
+
+			tokenMgr, _, err := tokenManagerFor(string(platformType))
+			if err != nil {
+				return err
+			}
+			if err := tokenMgr.SetToken(account, token); err != nil {
+				return fmt.Errorf("failed to migrate %s: %w", key, err)
+			}
+
+			fmt.Printf("✓ Migrated %s to the keychain (%s@%s)\n", key, account, platformType)
+			migrated = append(migrated, key)
+		}
+	}
+
+	if len(migrated) == 0 {
+		fmt.Println("No tokens found in .env.")
+		return nil
+	}
+
+	fmt.Printf("\n✅ Migrated %d token(s). You can now remove these lines from .env:\n", len(migrated))
+	for _, key := range migrated {
+		fmt.Printf("   %s\n", key)
+	}
+	return nil
+}
+
+// maskToken shows only the first and last few characters of a token
+func maskToken(token string) string {
+	if len(token) <= 8 {
+		return strings.Repeat("*", len(token))
+	}
+	return token[:4] + strings.Repeat("*", len(token)-8) + token[len(token)-4:]
+}
+
+func runTokenCheck(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	// Load configuration
+	configPath := cfgFile
+	if configPath == "" {
+		configPath = config.GetDefaultConfigPath()
+	}
+
+	mgr := config.NewManager(configPath)
+	if !mgr.Exists() {
+		return fmt.Errorf("configuration file not found at %s\nRun 'git-keys init' first", configPath)
+	}
+
+	cfg, err := mgr.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	printHeader("\n🔐 API Token Check")
+	fmt.Println()
+
+	envTokens := loadTokensFromEnv()
+	checked := make(map[string]bool)
+	failed := 0
+
+	for personaIdx := range cfg.Personas {
+		persona := &cfg.Personas[personaIdx]
+		if persona.Archived {
+			continue
+		}
+		for platformIdx := range persona.Platforms {
+			plat := &persona.Platforms[platformIdx]
+
+			// Accounts shared by several personas use the same token
+			id := fmt.Sprintf("%s|%s|%s", plat.Type, plat.BaseURL, plat.Account)
+			if checked[id] {
+				continue
+			}
+			checked[id] = true
+
+			fmt.Printf("%s@%s (%s)\n", plat.Account, plat.Type, persona.Name)
+			if !checkPlatformToken(ctx, plat, envTokens) {
+				failed++
+			}
+			fmt.Println()
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d token(s) failed the check", failed)
+	}
+
+	fmt.Println("✅ All tokens are ready.")
+	return nil
+}
+
+// checkPlatformToken prints the check results for one account and reports
+// whether its token is usable
+func checkPlatformToken(ctx context.Context, plat *config.Platform, envTokens map[string]string) bool {
+	token, source, err := lookupToken(plat, envTokens)
+	if err != nil {
+		fmt.Printf("  ❌ %v\n", err)
+		return false
+	}
+
+	client, err := newPlatformClientWithToken(plat, token)
+	if err != nil {
+		fmt.Printf("  ❌ %v\n", err)
+		return false
+	}
+
+	checker, ok := client.(api.TokenChecker)
+	if !ok {
+		fmt.Printf("  ⚠️  Token checks are not supported for %s\n", plat.Type)
+		return true
+	}
+
+	info, err := checker.CheckToken(ctx)
+	if err != nil {
+		fmt.Printf("  ❌ %v (from %s)\n", err, source)
+		return false
+	}
+
+	usable := true
+	fmt.Printf("  ✓ Valid token for '%s' (from %s)\n", info.User, source)
+	if info.User != "" && !strings.EqualFold(info.User, plat.Account) {
+		fmt.Printf("  ⚠️  Token belongs to '%s', not the configured account '%s'\n", info.User, plat.Account)
+	}
+
+	if info.ExpiresAt != nil {
+		until := time.Until(*info.ExpiresAt)
+		switch {
+		case until <= 0:
+			fmt.Printf("  ❌ Expired on %s\n", info.ExpiresAt.Format("2006-01-02"))
+			usable = false
+		case until < tokenExpiryWarning:
+			fmt.Printf("  ⚠️  Expires in %d day(s) (%s)\n", int(until.Hours()/24), info.ExpiresAt.Format("2006-01-02"))
+		default:
+			fmt.Printf("  ✓ Expires %s\n", info.ExpiresAt.Format("2006-01-02"))
+		}
+	}
+
+	if info.Scopes == nil {
+		fmt.Println("  ⚠️  Scopes not reported (fine-grained or OAuth token); make sure it can manage SSH keys")
+		return usable
+	}
+
+	switch plat.Type {
+	case config.PlatformGitHub:
+		switch {
+		case info.HasScope("admin:public_key"):
+			fmt.Println("  ✓ Has admin:public_key scope")
+		case info.HasScope("write:public_key"):
+			fmt.Println("  ⚠️  Has write:public_key only; rotate and revoke need admin:public_key to delete keys")
+		default:
+			fmt.Printf("  ❌ Missing admin:public_key scope (has: %s)\n", formatScopes(info.Scopes))
+			usable = false
+		}
+	case config.PlatformGitLab:
+		if info.HasScope("api") {
+			fmt.Println("  ✓ Has api scope")
+		} else {
+			fmt.Printf("  ❌ Missing api scope, needed to manage SSH keys (has: %s)\n", formatScopes(info.Scopes))
+			usable = false
+		}
+	}
+
+	return usable
+}
+
+// lookupToken finds the token for a platform account in .env or the keychain
+// and reports where it came from
+func lookupToken(plat *config.Platform, envTokens map[string]string) (string, string, error) {
+	tokenKey, err := envTokenKey(plat.Type, plat.Account)
+	if err != nil {
+		return "", "", err
+	}
+	if token := envTokens[tokenKey]; token != "" {
+		return token, ".env " + tokenKey, nil
+	}
+
+	token, err := getKeychainToken(plat)
+	if err != nil {
+		return "", "", fmt.Errorf("no token in .env (%s) or keychain", tokenKey)
+	}
+	return token, "keychain", nil
+}
+
+func formatScopes(scopes []string) string {
+	if len(scopes) == 0 {
+		return "none"
+	}
+	return strings.Join(scopes, ", ")
+}
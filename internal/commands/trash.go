@@ -0,0 +1,196 @@
+package commands
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/kunlu/git-keys/internal/config"
+	"github.com/kunlu/git-keys/internal/logger"
+	"github.com/kunlu/git-keys/internal/prompt"
+	"github.com/kunlu/git-keys/internal/trash"
+	"github.com/spf13/cobra"
+)
+
+var (
+	trashRestoreForce bool
+	trashEmptyExpired bool
+	trashEmptyYes     bool
+)
+
+var trashCmd = &cobra.Command{
+	Use:   "trash",
+	Short: "Manage locally deleted SSH keys",
+	Long: `Manage key pairs deleted by git-keys.
+
+Keys deleted by 'revoke --local' and 'rebuild' are moved to ~/.git-keys/trash
+instead of being removed immediately. They are kept for the retention window
+(defaults.trash_retention, 30 days by default) and can be restored until then;
+'trash list' and 'trash empty' purge the keys past it.
+
+Subcommands:
+  list     - Show trashed key pairs
+  restore  - Move a key pair back to its original location
+  empty    - Permanently delete trashed key pairs
+
+Examples:
+  # Show trashed keys
+  git-keys trash list
+
+  # Restore a key by ID (or unique ID prefix / key file name)
+  git-keys trash restore git-keys-github-myusername-ed25519
+
+  # Remove only keys past the retention window
+  git-keys trash empty --expired
+`,
+}
+
+var trashListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List trashed key pairs",
+	RunE:  runTrashList,
+}
+
+var trashRestoreCmd = &cobra.Command{
+	Use:   "restore <id>",
+	Short: "Restore a trashed key pair",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runTrashRestore,
+}
+
+var trashEmptyCmd = &cobra.Command{
+	Use:   "empty",
+	Short: "Permanently delete trashed key pairs",
+	RunE:  runTrashEmpty,
+}
+
+func init() {
+	trashRestoreCmd.Flags().BoolVarP(&trashRestoreForce, "force", "f", false, "Overwrite existing files at the original location")
+	trashEmptyCmd.Flags().BoolVar(&trashEmptyExpired, "expired", false, "Only delete keys past the retention window (without asking)")
+	trashEmptyCmd.Flags().BoolVarP(&trashEmptyYes, "yes", "y", false, "Skip confirmation prompt")
+
+	trashCmd.AddCommand(trashListCmd)
+	trashCmd.AddCommand(trashRestoreCmd)
+	trashCmd.AddCommand(trashEmptyCmd)
+	rootCmd.AddCommand(trashCmd)
+}
+
+// newTrashManager creates a trash manager honoring the configured retention
+func newTrashManager() *trash.Manager {
+	configPath := cfgFile
+	if configPath == "" {
+		configPath = config.GetDefaultConfigPath()
+	}
+
+	var retention time.Duration
+	mgr := config.NewManager(configPath)
+	if mgr.Exists() {
+		if cfg, err := mgr.Load(); err == nil {
+			retention = cfg.Defaults.TrashRetention
+		}
+	}
+
+	return trash.NewManager("", retention)
+}
+
+func runTrashList(cmd *cobra.Command, args []string) error {
+	trashMgr := newTrashManager()
+
+	// Drop anything that has outlived the retention window
+	purged, err := trashMgr.Empty(true)
+	if err != nil {
+		logger.Warn("Failed to purge expired trash: %v", err)
+	}
+
+	items, err := trashMgr.List()
+	if err != nil {
+		return err
+	}
+
+	printHeader("\n🗑️  Trashed Keys")
+	fmt.Println()
+	if purged > 0 {
+		fmt.Printf("Purged %d key pair(s) past the retention window.\n\n", purged)
+	}
+
+	if len(items) == 0 {
+		fmt.Println("Trash is empty.")
+		fmt.Println()
+		return nil
+	}
+
+	now := time.Now()
+	for _, item := range items {
+		fmt.Printf("• %s\n", item.ID)
+		fmt.Printf("  Original: %s\n", item.OriginalPath)
+		fmt.Printf("  Deleted:  %s\n", item.DeletedAt.Format("2006-01-02 15:04:05"))
+
+		expires := item.ExpiresAt(trashMgr.Retention())
+		if now.After(expires) {
+			fmt.Println("  Expires:  expired (will be purged)")
+		} else {
+			days := int(expires.Sub(now).Hours() / 24)
+			fmt.Printf("  Expires:  %s (%d days)\n", expires.Format("2006-01-02"), days)
+		}
+		fmt.Println()
+	}
+
+	fmt.Println("Restore with: git-keys trash restore <id>")
+	fmt.Println()
+	return nil
+}
+
+func runTrashRestore(cmd *cobra.Command, args []string) error {
+	trashMgr := newTrashManager()
+
+	item, err := trashMgr.Restore(args[0], trashRestoreForce)
+	if err != nil {
+		return fmt.Errorf("failed to restore: %w", err)
+	}
+
+	fmt.Printf("✓ Restored %s\n", item.OriginalPath)
+	fmt.Println("\nIf the key was revoked remotely, run 'git-keys apply' or re-register it manually.")
+	return nil
+}
+
+func runTrashEmpty(cmd *cobra.Command, args []string) error {
+	trashMgr := newTrashManager()
+
+	// Keys past the retention window go without asking
+	purged, err := trashMgr.Empty(true)
+	if err != nil {
+		return err
+	}
+	if purged > 0 {
+		fmt.Printf("✓ Purged %d key pair(s) past the retention window\n", purged)
+	}
+	if trashEmptyExpired {
+		if purged == 0 {
+			fmt.Println("No trashed keys are past the retention window.")
+		}
+		return nil
+	}
+
+	items, err := trashMgr.List()
+	if err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		fmt.Println("Trash is empty.")
+		return nil
+	}
+
+	if !trashEmptyYes {
+		if !prompt.Confirm(fmt.Sprintf("Permanently delete all %d trashed key(s)?", len(items))) {
+			fmt.Println("Cancelled.")
+			return nil
+		}
+	}
+
+	removed, err := trashMgr.Empty(false)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("✓ Permanently deleted %d key pair(s)\n", removed)
+	return nil
+}
@@ -0,0 +1,470 @@
+package commands
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/kunlu/git-keys/internal/api"
+	"github.com/kunlu/git-keys/internal/config"
+	"github.com/kunlu/git-keys/internal/profile"
+	"github.com/kunlu/git-keys/internal/sshkey"
+	"github.com/spf13/cobra"
+)
+
+var uiCmd = &cobra.Command{
+	Use:   "ui",
+	Short: "Interactive dashboard of personas, keys and their health",
+	Long: `Show every persona, platform and key in one screen, with each key's
+expiry countdown, whether its file exists, whether it is loaded in the SSH
+agent, and whether the platform still has it registered.
+
+Keys:
+  ↑/↓, j/k   Select a key
+  R          Rotate the keys of the selected platform ('git-keys rotate')
+  x          Revoke the selected key ('git-keys revoke --fingerprint')
+  a          Add the selected key to the SSH agent and Keychain
+  t          Test the SSH connection of the selected platform
+  r          Reload the configuration and re-fetch remote keys
+  q          Quit
+
+Rotate and revoke run the regular commands, with their confirmation prompts,
+and return to the dashboard when they finish. Remote key listings come from
+the cache unless --refresh is given or r is pressed.`,
+	RunE:         runUI,
+	SilenceUsage: true,
+}
+
+func init() {
+	rootCmd.AddCommand(uiCmd)
+}
+
+func runUI(cmd *cobra.Command, args []string) error {
+	if nonInteractive {
+		return errNonInteractive("'ui'", "use 'git-keys status'")
+	}
+	if info, err := os.Stdin.Stat(); err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return fmt.Errorf("'ui' needs a terminal; use 'git-keys status' instead")
+	}
+
+	configPath := cfgFile
+	if configPath == "" {
+		configPath = config.GetDefaultConfigPath()
+	}
+	mgr := config.NewManager(configPath)
+	if !mgr.Exists() {
+		return fmt.Errorf("configuration file not found at %s\nRun 'git-keys init' first", configPath)
+	}
+
+	m := &uiModel{mgr: mgr, refresh: refreshRemote}
+	if err := m.load(); err != nil {
+		return err
+	}
+
+	_, err := tea.NewProgram(m, tea.WithAltScreen()).Run()
+	return err
+}
+
+// uiRow is a key shown on the dashboard
+type uiRow struct {
+	Persona  *config.Persona
+	Platform *config.Platform
+	Key      *config.KeyConfig
+	Lane     string // platformLane of the platform, the key of remote listings
+	KeyPath  string // Full path of the private key; "" without one
+}
+
+// uiRemote is the listing of a platform's remote keys
+type uiRemote struct {
+	Fingerprints map[string]bool // Without the SHA256: prefix
+	Err          error
+}
+
+// uiModel is the bubbletea model of the dashboard
+type uiModel struct {
+	mgr     *config.Manager
+	cfg     *config.Config
+	rows    []uiRow
+	cursor  int
+	refresh bool // Re-fetch remote listings on the next load
+
+	dueWindow time.Duration
+	remote    map[string]*uiRemote // By lane; nil entries are being fetched
+	agent     map[string]bool      // Fingerprints loaded in the SSH agent
+	message   string
+}
+
+// Messages of the dashboard
+type (
+	uiRemoteMsg struct {
+		Lane   string
+		Remote *uiRemote
+	}
+	uiAgentMsg    map[string]bool
+	uiMessageMsg  string
+	uiFinishedMsg struct {
+		Action string
+		Err    error
+	}
+)
+
+var (
+	uiTitleStyle    = lipgloss.NewStyle().Bold(true)
+	uiPersonaStyle  = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("12"))
+	uiSelectedStyle = lipgloss.NewStyle().Reverse(true)
+	uiOKStyle       = lipgloss.NewStyle().Foreground(lipgloss.Color("10"))
+	uiWarnStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("11"))
+	uiBadStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+	uiDimStyle      = lipgloss.NewStyle().Faint(true)
+)
+
+// load reads the configuration and lists its keys, keeping the selection
+// on the same key when it still exists
+func (m *uiModel) load() error {
+	cfg, err := m.mgr.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	dueWindow, err := rotateDueWindow(cfg, "")
+	if err != nil {
+		return err
+	}
+
+	var selected string
+	if m.cursor < len(m.rows) {
+		selected = m.rows[m.cursor].Key.Fingerprint
+	}
+
+	m.cfg = cfg
+	m.dueWindow = dueWindow
+	m.rows = nil
+	m.cursor = 0
+	keysDir := cfg.Defaults.GetKeysDir()
+	for i := range cfg.Personas {
+		persona := &cfg.Personas[i]
+		if persona.Archived {
+			continue
+		}
+		for j := range persona.Platforms {
+			plat := &persona.Platforms[j]
+			for k := range plat.Keys {
+				key := &plat.Keys[k]
+				if key.Status == config.KeyStatusRevoked {
+					continue
+				}
+				row := uiRow{
+					Persona:  persona,
+					Platform: plat,
+					Key:      key,
+					Lane:     platformLane(plat.Type, plat.BaseURL, plat.Account),
+				}
+				if key.HasPrivateKey() && key.LocalPath != "" {
+					row.KeyPath = expandKeyPath(keysDir, key.LocalPath)
+				}
+				if key.Fingerprint == selected {
+					m.cursor = len(m.rows)
+				}
+				m.rows = append(m.rows, row)
+			}
+		}
+	}
+	m.remote = make(map[string]*uiRemote)
+	return nil
+}
+
+func (m *uiModel) Init() tea.Cmd {
+	return m.fetch()
+}
+
+// fetch lists the keys in the SSH agent and on each platform
+func (m *uiModel) fetch() tea.Cmd {
+	cmds := []tea.Cmd{uiFetchAgent}
+	refresh := m.refresh
+	m.refresh = false
+	for _, row := range m.rows {
+		if _, fetching := m.remote[row.Lane]; fetching || row.Key.RemoteID == "" {
+			continue
+		}
+		m.remote[row.Lane] = nil
+		lane, plat := row.Lane, *row.Platform
+		cmds = append(cmds, func() tea.Msg {
+			return uiRemoteMsg{Lane: lane, Remote: uiFetchRemote(&plat, refresh)}
+		})
+	}
+	return tea.Batch(cmds...)
+}
+
+// uiFetchRemote lists the fingerprints of a platform's remote keys
+func uiFetchRemote(plat *config.Platform, refresh bool) *uiRemote {
+	client, err := newPlatformClient(plat)
+	if err != nil {
+		return &uiRemote{Err: err}
+	}
+	remoteKeys, _, err := api.ListKeysCached(context.Background(), client, refresh)
+	if err != nil {
+		return &uiRemote{Err: err}
+	}
+	remote := &uiRemote{Fingerprints: make(map[string]bool)}
+	for _, key := range remoteKeys {
+		remote.Fingerprints[remoteFingerprint(key)] = true
+	}
+	return remote
+}
+
+// uiFetchAgent lists the fingerprints of the keys in the SSH agent
+func uiFetchAgent() tea.Msg {
+	loaded := make(uiAgentMsg)
+	agent, err := sshkey.ConnectAgent("")
+	if err != nil {
+		return loaded
+	}
+	defer agent.Close()
+
+	fingerprints, _ := agent.Fingerprints()
+	for fingerprint := range fingerprints {
+		loaded[strings.TrimPrefix(fingerprint, "SHA256:")] = true
+	}
+	return loaded
+}
+
+func (m *uiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case uiRemoteMsg:
+		m.remote[msg.Lane] = msg.Remote
+
+	case uiAgentMsg:
+		m.agent = msg
+
+	case uiMessageMsg:
+		m.message = string(msg)
+		return m, uiFetchAgent
+
+	case uiFinishedMsg:
+		m.message = fmt.Sprintf("%s finished", msg.Action)
+		if msg.Err != nil {
+			m.message = fmt.Sprintf("%s failed: %v", msg.Action, msg.Err)
+		}
+		if err := m.load(); err != nil {
+			m.message = err.Error()
+			return m, nil
+		}
+		m.refresh = true
+		return m, m.fetch()
+
+	case tea.KeyMsg:
+		return m.handleKey(msg)
+	}
+	return m, nil
+}
+
+// handleKey moves the selection or starts the action bound to a key
+func (m *uiModel) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "q", "ctrl+c", "esc":
+		return m, tea.Quit
+	case "up", "k":
+		if m.cursor > 0 {
+			m.cursor--
+		}
+		return m, nil
+	case "down", "j":
+		if m.cursor < len(m.rows)-1 {
+			m.cursor++
+		}
+		return m, nil
+	case "r":
+		if err := m.load(); err != nil {
+			m.message = err.Error()
+			return m, nil
+		}
+		m.message = "Reloaded"
+		m.refresh = true
+		return m, m.fetch()
+	}
+
+	if len(m.rows) == 0 {
+		return m, nil
+	}
+	row := m.rows[m.cursor]
+
+	switch msg.String() {
+	case "R":
+		target := fmt.Sprintf("%s/%s", row.Persona.Name, row.Platform.Type)
+		return m, uiRunCommand("Rotation of "+target, "rotate", target)
+	case "x":
+		return m, uiRunCommand("Revocation of "+row.Key.Fingerprint, "revoke", "--fingerprint", row.Key.Fingerprint)
+	case "a":
+		if row.KeyPath == "" {
+			m.message = "The key has no private key file to add"
+			return m, nil
+		}
+		m.message = "Adding key to the SSH agent..."
+		keyPath, defaults := row.KeyPath, m.cfg.Defaults
+		return m, func() tea.Msg {
+			if err := addKeyToKeychain(defaults, keyPath); err != nil {
+				return uiMessageMsg(fmt.Sprintf("Failed to add key: %v", err))
+			}
+			return uiMessageMsg("Added key to the SSH agent")
+		}
+	case "t":
+		alias, _ := sshHostAlias(row.Persona, row.Platform)
+		m.message = fmt.Sprintf("Testing git@%s...", alias)
+		return m, func() tea.Msg {
+			return uiMessageMsg(uiTestConnection(alias))
+		}
+	}
+	return m, nil
+}
+
+// uiTestConnection runs 'ssh -T' against a host alias and describes the result
+func uiTestConnection(alias string) string {
+	output, _ := exec.Command("ssh", "-T", "-o", "BatchMode=yes", "git@"+alias).CombinedOutput()
+	out := strings.TrimSpace(string(output))
+	if sshAuthenticated(out) {
+		return fmt.Sprintf("git@%s: %s", alias, extractAuthMessage(out))
+	}
+	if out == "" {
+		out = "no response"
+	}
+	return fmt.Sprintf("git@%s: authentication failed: %s", alias, strings.Split(out, "\n")[0])
+}
+
+// uiRunCommand runs a git-keys command on the terminal, with the config and
+// profile of this run, and returns to the dashboard once it is confirmed
+func uiRunCommand(action string, args ...string) tea.Cmd {
+	self, err := os.Executable()
+	if err != nil {
+		return func() tea.Msg { return uiFinishedMsg{Action: action, Err: err} }
+	}
+	if cfgFile != "" {
+		args = append(args, "--config", cfgFile)
+	}
+	if !profile.IsDefault() {
+		args = append(args, "--profile", profile.Name())
+	}
+	return tea.Exec(&uiExec{cmd: exec.Command(self, args...)}, func(err error) tea.Msg {
+		return uiFinishedMsg{Action: action, Err: err}
+	})
+}
+
+// uiExec runs a command outside the dashboard and waits for Enter before
+// returning, so its output can be read
+type uiExec struct {
+	cmd *exec.Cmd
+}
+
+func (e *uiExec) SetStdin(r io.Reader)  { e.cmd.Stdin = r }
+func (e *uiExec) SetStdout(w io.Writer) { e.cmd.Stdout = w }
+func (e *uiExec) SetStderr(w io.Writer) { e.cmd.Stderr = w }
+
+func (e *uiExec) Run() error {
+	err := e.cmd.Run()
+	fmt.Fprint(e.cmd.Stdout, "\nPress Enter to return to the dashboard...")
+	bufio.NewReader(e.cmd.Stdin).ReadString('\n')
+	return err
+}
+
+func (m *uiModel) View() string {
+	var b strings.Builder
+	title := fmt.Sprintf("git-keys — %s", m.mgr.GetPath())
+	if !profile.IsDefault() {
+		title += fmt.Sprintf(" (profile %s)", profile.Name())
+	}
+	b.WriteString(uiTitleStyle.Render(title) + "\n\n")
+
+	if len(m.rows) == 0 {
+		b.WriteString("No keys yet. Run 'git-keys apply' to generate them.\n")
+	}
+
+	var lastPersona *config.Persona
+	for i, row := range m.rows {
+		if row.Persona != lastPersona {
+			if lastPersona != nil {
+				b.WriteString("\n")
+			}
+			b.WriteString(uiPersonaStyle.Render(fmt.Sprintf("%s <%s>", row.Persona.Name, row.Persona.Email)) + "\n")
+			lastPersona = row.Persona
+		}
+
+		line := fmt.Sprintf("  %-28s %-20s %-9s", uiTruncate(fmt.Sprintf("%s@%s", row.Platform.Account, row.Platform.Type), 28),
+			uiTruncate(strings.TrimPrefix(row.Key.Fingerprint, "SHA256:"), 20), row.Key.Status)
+		if i == m.cursor {
+			line = uiSelectedStyle.Render(line)
+		}
+		b.WriteString(line + " " + m.expiryCell(row) + " " + m.fileCell(row) + " " + m.agentCell(row) + " " + m.remoteCell(row) + "\n")
+	}
+
+	b.WriteString("\n" + uiDimStyle.Render("↑/↓ select • R rotate • x revoke • a add to agent • t test connection • r reload • q quit") + "\n")
+	if m.message != "" {
+		b.WriteString(m.message + "\n")
+	}
+	return b.String()
+}
+
+// expiryCell counts down to the key's expiry
+func (m *uiModel) expiryCell(row uiRow) string {
+	expiresAt, known := m.cfg.KeyExpiry(row.Persona, row.Platform, row.Key)
+	if !known {
+		return uiDimStyle.Render(fmt.Sprintf("%-18s", "no expiry"))
+	}
+	days := int(time.Until(expiresAt).Hours() / 24)
+	switch {
+	case !expiresAt.After(time.Now()):
+		return uiBadStyle.Render(fmt.Sprintf("%-18s", fmt.Sprintf("expired %dd ago", -days)))
+	case keyDue(m.cfg, row.Persona, row.Platform, row.Key, m.dueWindow):
+		return uiWarnStyle.Render(fmt.Sprintf("%-18s", fmt.Sprintf("expires in %dd", days)))
+	}
+	return uiOKStyle.Render(fmt.Sprintf("%-18s", fmt.Sprintf("expires in %dd", days)))
+}
+
+// fileCell shows whether the private key file exists
+func (m *uiModel) fileCell(row uiRow) string {
+	switch {
+	case row.KeyPath == "":
+		return uiDimStyle.Render("– file ")
+	case fileExists(row.KeyPath):
+		return uiOKStyle.Render("✓ file ")
+	}
+	return uiBadStyle.Render("✗ file ")
+}
+
+// agentCell shows whether the key is loaded in the SSH agent
+func (m *uiModel) agentCell(row uiRow) string {
+	if m.agent[strings.TrimPrefix(row.Key.Fingerprint, "SHA256:")] {
+		return uiOKStyle.Render("✓ agent ")
+	}
+	return uiDimStyle.Render("– agent ")
+}
+
+// remoteCell shows whether the platform has the key registered
+func (m *uiModel) remoteCell(row uiRow) string {
+	if row.Key.RemoteID == "" {
+		return uiDimStyle.Render("– not uploaded")
+	}
+	remote, fetched := m.remote[row.Lane]
+	switch {
+	case !fetched || remote == nil:
+		return uiDimStyle.Render("… checking")
+	case remote.Err != nil:
+		return uiWarnStyle.Render("? remote unknown")
+	case remote.Fingerprints[strings.TrimPrefix(row.Key.Fingerprint, "SHA256:")]:
+		return uiOKStyle.Render("✓ registered")
+	}
+	return uiBadStyle.Render("✗ not on platform")
+}
+
+// uiTruncate shortens s to n runes, marking the cut with …
+func uiTruncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
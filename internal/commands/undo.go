@@ -0,0 +1,428 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kunlu/git-keys/internal/config"
+	"github.com/kunlu/git-keys/internal/gpgkey"
+	"github.com/kunlu/git-keys/internal/history"
+	"github.com/kunlu/git-keys/internal/journal"
+	"github.com/kunlu/git-keys/internal/logger"
+	"github.com/kunlu/git-keys/internal/prompt"
+	"github.com/kunlu/git-keys/internal/sshconfig"
+	"github.com/kunlu/git-keys/internal/sshkey"
+	"github.com/kunlu/git-keys/internal/trash"
+	"github.com/spf13/cobra"
+)
+
+var (
+	undoYes  bool
+	undoKeep bool
+)
+
+var undoCmd = &cobra.Command{
+	Use:     "undo",
+	Aliases: []string{"rollback"},
+	Short:   "Undo the last apply, rotate, revoke or rebuild",
+	Long: `Undo the most recent apply, rotate, revoke or rebuild, most recent change first.
+
+These commands record each step in the operation history (~/.git-keys/history)
+as they go, with what is needed to take it back: copies of the files they
+write, where keys were archived or trashed, and the public key and title of
+each remote key they delete. Apply also rolls itself back when it fails.
+
+Undo takes back:
+  - keys uploaded to a platform are deleted (only if the remote key still
+    matches the local fingerprint)
+  - remote keys that were deleted are uploaded again, and their new remote ID
+    recorded in the config
+  - generated keys are moved to the trash ('git-keys trash restore')
+  - archived and trashed keys are moved back
+  - the SSH config, ~/.gitconfig, per-platform git config files and the
+    git-keys config get back their content from before the operation
+
+Running undo again takes back the operation before that. API tokens removed
+by rebuild are not stored and have to be set again ('git-keys token set').
+
+After an interrupted operation, apply refuses to run until it is undone, or
+kept with --keep.
+
+Examples:
+  git-keys undo
+  git-keys undo --keep
+`,
+	RunE:         runUndo,
+	SilenceUsage: true,
+}
+
+func init() {
+	undoCmd.Flags().BoolVarP(&undoYes, "yes", "y", false, "Skip confirmation prompt")
+	undoCmd.Flags().BoolVar(&undoKeep, "keep", false, "Keep the changes of an interrupted or failed operation instead of undoing them")
+	rootCmd.AddCommand(undoCmd)
+}
+
+func runUndo(cmd *cobra.Command, args []string) error {
+	if undoKeep {
+		last, err := journal.Latest("")
+		if err != nil {
+			return err
+		}
+		if last == nil || (last.Status != journal.StatusRunning && last.Status != journal.StatusFailed) {
+			fmt.Println("No interrupted or failed operation.")
+			return nil
+		}
+		if err := last.Finish(journal.StatusDone, nil); err != nil {
+			return err
+		}
+		fmt.Printf("✓ Kept the changes of the %s started %s\n", last.Operation, last.StartedAt.Local().Format("2006-01-02 15:04"))
+		return nil
+	}
+
+	j, err := journal.LatestUndoable("")
+	if err != nil {
+		return err
+	}
+	if j == nil {
+		fmt.Println("Nothing to undo.")
+		return nil
+	}
+
+	configPath := cfgFile
+	if configPath == "" {
+		configPath = config.GetDefaultConfigPath()
+	}
+
+	printHeader("\n↩️  Undo")
+	fmt.Printf("\n  Operation: %s, started %s (%s)\n", j.Operation, j.StartedAt.Local().Format("2006-01-02 15:04"), j.Status)
+	if j.Error != "" {
+		printWrapped("  Error:     ", j.Error)
+	}
+	fmt.Println()
+	for _, i := range j.Pending() {
+		printWrapped("  • ", describeJournalStep(j.Steps[i]))
+	}
+
+	if !undoYes {
+		if !prompt.Confirm("\nUndo these changes?") {
+			fmt.Println("Cancelled.")
+			return nil
+		}
+	}
+	fmt.Println()
+
+	failures := rollbackJournal(context.Background(), j, configPath)
+
+	histMgr := history.NewManager("")
+	if err := histMgr.Record(history.Entry{
+		Action:  "undo",
+		Summary: fmt.Sprintf("Undid %s started %s", j.Operation, j.StartedAt.Local().Format("2006-01-02 15:04")),
+		Details: map[string]string{"journal": j.ID, "failures": fmt.Sprintf("%d", len(failures))},
+	}); err != nil {
+		logger.Warn("Failed to record history: %v", err)
+	}
+
+	if len(failures) > 0 {
+		printRollbackFailures(failures)
+		return fmt.Errorf("undo incomplete: %d change(s) could not be undone", len(failures))
+	}
+
+	fmt.Printf("\n✅ Undid %s\n", j.Operation)
+	return nil
+}
+
+// describeJournalStep says what undoing a step will do
+func describeJournalStep(step journal.Step) string {
+	switch step.Kind {
+	case journal.StepFile:
+		if step.Backup == "" {
+			return fmt.Sprintf("Remove %s (created)", step.Path)
+		}
+		return fmt.Sprintf("Restore %s", step.Path)
+	case journal.StepKey:
+		return fmt.Sprintf("Move key %s to the trash", step.Path)
+	case journal.StepMove:
+		return fmt.Sprintf("Move key %s back to %s", step.To, step.Path)
+	case journal.StepTrash:
+		return fmt.Sprintf("Restore key %s from the trash", step.Path)
+	case journal.StepUpload:
+		return fmt.Sprintf("Delete remote key %s from %s/%s@%s", step.RemoteID, step.Persona, step.Platform, step.Account)
+	case journal.StepSigningKey:
+		return fmt.Sprintf("Delete signing key %s from %s/%s@%s", step.RemoteID, step.Persona, step.Platform, step.Account)
+	case journal.StepGPGKey:
+		return fmt.Sprintf("Delete GPG key %s from the keyring", step.Fingerprint)
+	case journal.StepGPGUpload:
+		return fmt.Sprintf("Delete GPG key %s from %s/%s@%s", step.RemoteID, step.Persona, step.Platform, step.Account)
+	case journal.StepDeployUpload:
+		return fmt.Sprintf("Delete deploy key %s from %s", step.RemoteID, step.Repo)
+	case journal.StepDeployDelete:
+		return fmt.Sprintf("Add deploy key %s to %s again", step.Fingerprint, step.Repo)
+	case journal.StepRemoteDelete:
+		return fmt.Sprintf("Upload %s to %s/%s@%s again", step.Fingerprint, step.Persona, step.Platform, step.Account)
+	}
+	return fmt.Sprintf("Unknown step %q", step.Kind)
+}
+
+// rollbackJournal undoes the steps of j that are not rolled back yet, most
+// recent first, and returns the ones that could not be undone. Remote steps
+// look up their platform in the config at configPath as it is when they are
+// undone.
+func rollbackJournal(ctx context.Context, j *journal.Journal, configPath string) []string {
+	var failures []string
+	envTokens := loadTokensFromEnv()
+
+	for _, i := range j.Pending() {
+		step := j.Steps[i]
+
+		var err error
+		switch step.Kind {
+		case journal.StepFile:
+			err = journal.RestoreFile(step)
+		case journal.StepKey:
+			err = sshkey.NewManager(filepath.Dir(step.Path)).DeleteKey(filepath.Base(step.Path))
+		case journal.StepMove:
+			err = journal.MoveBack(step)
+		case journal.StepTrash:
+			_, err = trash.NewManager("", 0).Restore(step.TrashID, false)
+		case journal.StepUpload:
+			err = rollbackUpload(ctx, configPath, step, envTokens)
+		case journal.StepSigningKey, journal.StepGPGUpload:
+			err = rollbackSigningKey(ctx, configPath, step, envTokens)
+		case journal.StepGPGKey:
+			err = gpgkey.Delete(step.Fingerprint)
+		case journal.StepDeployUpload:
+			err = rollbackDeployKey(ctx, configPath, step, envTokens)
+		case journal.StepDeployDelete:
+			err = readdDeletedDeployKey(ctx, configPath, step, envTokens)
+		case journal.StepRemoteDelete:
+			err = reuploadDeletedKey(ctx, configPath, step, envTokens)
+		default:
+			err = fmt.Errorf("unknown step %q", step.Kind)
+		}
+
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", describeJournalStep(step), err))
+			continue
+		}
+		fmt.Printf("✓ %s\n", describeJournalStep(step))
+		if err := j.MarkRolledBack(i); err != nil {
+			logger.Warn("Failed to update journal: %v", err)
+		}
+	}
+
+	status := journal.StatusRolledBack
+	if len(failures) > 0 {
+		status = journal.StatusFailed
+	}
+	if err := j.Finish(status, nil); err != nil {
+		logger.Warn("Failed to update journal: %v", err)
+	}
+	return failures
+}
+
+// journalStepPlatform finds the platform a remote step worked on in the
+// config at configPath
+func journalStepPlatform(configPath string, step journal.Step) (*config.Config, *config.Platform, error) {
+	cfg, err := config.NewManager(configPath).Load()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	persona := cfg.FindPersona(step.Persona)
+	if persona == nil {
+		return nil, nil, fmt.Errorf("persona '%s' not found", step.Persona)
+	}
+	for platformIdx := range persona.Platforms {
+		plat := &persona.Platforms[platformIdx]
+		if string(plat.Type) == step.Platform && strings.EqualFold(plat.Account, step.Account) {
+			return cfg, plat, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("platform %s@%s not found in persona '%s'", step.Platform, step.Account, step.Persona)
+}
+
+// rollbackUpload deletes a key an operation uploaded, if it is still the
+// same key on the platform
+func rollbackUpload(ctx context.Context, configPath string, step journal.Step, envTokens map[string]string) error {
+	_, plat, err := journalStepPlatform(configPath, step)
+	if err != nil {
+		return err
+	}
+
+	token, _, err := lookupToken(plat, envTokens)
+	if err != nil {
+		return err
+	}
+	client, err := newPlatformClientWithToken(plat, token)
+	if err != nil {
+		return err
+	}
+	return deleteCheckedKey(ctx, client, checkRemoteKey(ctx, client, step.RemoteID, step.Fingerprint))
+}
+
+// rollbackSigningKey deletes a signing key or GPG key an operation uploaded
+func rollbackSigningKey(ctx context.Context, configPath string, step journal.Step, envTokens map[string]string) error {
+	_, plat, err := journalStepPlatform(configPath, step)
+	if err != nil {
+		return err
+	}
+
+	token, _, err := lookupToken(plat, envTokens)
+	if err != nil {
+		return err
+	}
+	client, err := newPlatformClientWithToken(plat, token)
+	if err != nil {
+		return err
+	}
+	if step.Kind == journal.StepGPGUpload {
+		return client.DeleteGPGKey(ctx, step.RemoteID)
+	}
+	return client.DeleteSigningKey(ctx, step.RemoteID)
+}
+
+// reuploadDeletedKey uploads a key an operation deleted from a platform, and
+// records its new remote ID on the key with the same fingerprint
+func reuploadDeletedKey(ctx context.Context, configPath string, step journal.Step, envTokens map[string]string) error {
+	cfg, plat, err := journalStepPlatform(configPath, step)
+	if err != nil {
+		return err
+	}
+
+	token, _, err := lookupToken(plat, envTokens)
+	if err != nil {
+		return err
+	}
+	client, err := newPlatformClientWithToken(plat, token)
+	if err != nil {
+		return err
+	}
+
+	var key *config.KeyConfig
+	var expiresAt time.Time
+	for keyIdx := range plat.Keys {
+		if plat.Keys[keyIdx].Fingerprint == step.Fingerprint {
+			key = &plat.Keys[keyIdx]
+			expiresAt = key.ExpiresAt
+		}
+	}
+
+	remoteID, err := client.AddKey(ctx, step.Title, step.PublicKey, expiresAt)
+	if err != nil {
+		return fmt.Errorf("API error: %w", err)
+	}
+	if key == nil {
+		return nil
+	}
+	key.RemoteID = remoteID
+	if err := config.NewManager(configPath).Save(cfg); err != nil {
+		return fmt.Errorf("uploaded as %s, but failed to save config: %w", remoteID, err)
+	}
+	return nil
+}
+
+// printRollbackFailures lists the changes that are still in place
+func printRollbackFailures(failures []string) {
+	fmt.Printf("\n⚠️  %d change(s) could not be undone:\n", len(failures))
+	for _, failure := range failures {
+		printWrapped("   • ", failure)
+	}
+	fmt.Println("   Fix the cause and run 'git-keys undo' again.")
+}
+
+// finishApplyJournal marks the journal of a successful apply done, or rolls
+// back everything a failed apply changed. An apply that only failed
+// verification is done.
+func finishApplyJournal(j *journal.Journal, configPath string, applyErr error) {
+	if errors.Is(applyErr, errApplyUnverified) {
+		applyErr = nil
+	}
+	finishAtomicJournal(j, configPath, "Apply", applyErr)
+}
+
+// finishAtomicJournal marks the journal of a successful operation done, or
+// rolls back everything a failed one changed
+func finishAtomicJournal(j *journal.Journal, configPath, operation string, opErr error) {
+	if opErr == nil {
+		if err := j.Finish(journal.StatusDone, nil); err != nil {
+			logger.Warn("Failed to update journal: %v", err)
+		}
+		return
+	}
+
+	if err := j.Finish(journal.StatusFailed, opErr); err != nil {
+		logger.Warn("Failed to update journal: %v", err)
+	}
+	if len(j.Pending()) == 0 {
+		return
+	}
+
+	fmt.Printf("\n↩️  %s failed; rolling back %d change(s)...\n", operation, len(j.Pending()))
+	if failures := rollbackJournal(context.Background(), j, configPath); len(failures) > 0 {
+		printRollbackFailures(failures)
+		return
+	}
+	fmt.Println("✓ Rolled back every change; nothing was left half applied")
+}
+
+// finishJournal records the outcome of an operation other than apply. Its
+// changes are kept, also when it failed part of the way, and can be undone.
+func finishJournal(j *journal.Journal, opErr error) {
+	if err := j.Finish(journal.StatusDone, opErr); err != nil {
+		logger.Warn("Failed to update journal: %v", err)
+	}
+}
+
+// journalWarn logs a step the journal failed to record; undo will not be
+// able to take it back
+func journalWarn(err error) {
+	if err != nil {
+		logger.Warn("Failed to record step for 'git-keys undo': %v", err)
+	}
+}
+
+// journalSSHConfig backs up the SSH config and the included file of its
+// managed blocks
+func journalSSHConfig(j *journal.Journal, sshMgr *sshconfig.Manager) {
+	for _, path := range sshMgr.Files() {
+		journalWarn(j.BackupFile(path))
+	}
+}
+
+// journalApplyFiles backs up every file apply may write: the git-keys config,
+// the SSH config, ~/.gitconfig and the git config files of targeted platforms
+func journalApplyFiles(j *journal.Journal, cfg *config.Config, configPath string, sshMgr *sshconfig.Manager, target *platformTarget) error {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return fmt.Errorf("failed to get home directory: %w", err)
+	}
+
+	paths := append([]string{config.StoragePath(configPath), filepath.Join(home, ".gitconfig")}, sshMgr.Files()...)
+	if !cfg.Defaults.DisableKnownHosts {
+		paths = append(paths, cfg.Defaults.GetKnownHostsFile())
+	}
+	for personaIdx := range cfg.Personas {
+		persona := &cfg.Personas[personaIdx]
+		if persona.Archived {
+			continue
+		}
+		for platformIdx := range persona.Platforms {
+			platform := &persona.Platforms[platformIdx]
+			if target.includes(persona, platform) {
+				paths = append(paths, filepath.Join(home, platformGitConfigName(persona.Name, fmt.Sprintf("%s-%s", platform.Type, platform.Account))))
+			}
+		}
+	}
+
+	for _, path := range paths {
+		if err := j.BackupFile(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kunlu/git-keys/internal/api"
+	"github.com/kunlu/git-keys/internal/config"
+)
+
+// defaultUnusedKeyDays is how long a GitLab key may go unused before it is
+// flagged as a prune candidate when defaults.unused_key_days is not set
+const defaultUnusedKeyDays = 90
+
+// keyUsage is when a key registered on GitLab last authenticated
+type keyUsage struct {
+	LastUsed time.Time // Zero when the key was never used
+	Created  time.Time // Upload time, zero when unknown
+}
+
+// remoteKeyUsage reads the usage GitLab reports for a remote key
+func remoteKeyUsage(remote api.SSHKey) keyUsage {
+	var usage keyUsage
+	if t, err := time.Parse(time.RFC3339, remote.LastUsedAt); err == nil {
+		usage.LastUsed = t
+	}
+	if t, err := time.Parse(time.RFC3339, remote.CreatedAt); err == nil {
+		usage.Created = t
+	}
+	return usage
+}
+
+// String returns "last used <date>" or "never used"
+func (u keyUsage) String() string {
+	if u.LastUsed.IsZero() {
+		return "never used"
+	}
+	return "last used " + u.LastUsed.Local().Format("2006-01-02")
+}
+
+// Unused reports whether the key has not authenticated for days: its last
+// use, or its upload if it was never used, is longer ago than that
+func (u keyUsage) Unused(days int) bool {
+	since := u.LastUsed
+	if since.IsZero() {
+		since = u.Created
+	}
+	return !since.IsZero() && time.Since(since) > time.Duration(days)*24*time.Hour
+}
+
+// unusedKeyDays returns defaults.unused_key_days, or defaultUnusedKeyDays
+func unusedKeyDays(cfg *config.Config) int {
+	if cfg.Defaults.UnusedKeyDays > 0 {
+		return cfg.Defaults.UnusedKeyDays
+	}
+	return defaultUnusedKeyDays
+}
+
+// platformKeyUsage lists the keys of a GitLab platform and returns their
+// usage by fingerprint (without the SHA256: prefix). Other platforms do not
+// report usage and return nil.
+func platformKeyUsage(ctx context.Context, plat *config.Platform, refresh bool) (map[string]keyUsage, error) {
+	if plat.Type != config.PlatformGitLab {
+		return nil, nil
+	}
+
+	client, err := newPlatformClient(plat)
+	if err != nil {
+		return nil, err
+	}
+	remoteKeys, _, err := api.ListKeysCached(ctx, client, refresh)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list remote keys: %w", err)
+	}
+
+	usage := make(map[string]keyUsage)
+	for _, remote := range remoteKeys {
+		usage[remoteFingerprint(remote)] = remoteKeyUsage(remote)
+	}
+	return usage, nil
+}
+
+// platformHasRemoteKey reports whether a GitLab platform has an in-use key
+// registered, i.e. whether listing its keys can report usage
+func platformHasRemoteKey(plat *config.Platform) bool {
+	if plat.Type != config.PlatformGitLab {
+		return false
+	}
+	for _, key := range plat.Keys {
+		if key.InUse() && key.RemoteID != "" {
+			return true
+		}
+	}
+	return false
+}
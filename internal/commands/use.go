@@ -0,0 +1,284 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/kunlu/git-keys/internal/config"
+	"github.com/kunlu/git-keys/internal/history"
+	"github.com/kunlu/git-keys/internal/logger"
+	"github.com/kunlu/git-keys/internal/sshkey"
+	"github.com/spf13/cobra"
+)
+
+var (
+	usePlatform string
+	useAccount  string
+	useClear    bool
+)
+
+var useCmd = &cobra.Command{
+	Use:   "use <persona>",
+	Short: "Bind the current repository to a persona",
+	Long: `Bind the repository in the current directory to a persona.
+
+Repositories under a platform's gitdir get their identity from an includeIf.
+For repositories that live elsewhere, or in a directory that mixes
+repositories of several personas, this command sets the repository's local
+git config, which takes precedence over the includeIf:
+  - user.name and user.email from the persona
+  - core.sshCommand using the persona's key with IdentitiesOnly=yes
+
+The binding is recorded under the platform's repos in the configuration. It
+overrides gitdir wherever git-keys maps a directory to a persona (whoami,
+fix, persona suggest), and 'git-keys status' reports bindings that went
+stale. Use --clear to remove a binding.
+
+When the persona has several platforms, the one matching the repository's
+origin remote is used; pass --platform (and --account) to choose.
+
+Examples:
+  git-keys use work
+  git-keys use personal --platform gitlab
+  git-keys use --clear
+`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completePersonas,
+	RunE:              runUse,
+}
+
+func init() {
+	useCmd.Flags().StringVar(&usePlatform, "platform", "", "Platform type when the persona has several (github, gitlab)")
+	useCmd.RegisterFlagCompletionFunc("platform", completePlatformTypes)
+	useCmd.Flags().StringVar(&useAccount, "account", "", "Account when the persona has several on the platform")
+	useCmd.Flags().BoolVar(&useClear, "clear", false, "Remove the repository's binding")
+	rootCmd.AddCommand(useCmd)
+}
+
+func runUse(cmd *cobra.Command, args []string) error {
+	if useClear == (len(args) == 1) {
+		return fmt.Errorf("specify a persona, or --clear to remove the binding")
+	}
+
+	// Load configuration
+	configPath := cfgFile
+	if configPath == "" {
+		configPath = config.GetDefaultConfigPath()
+	}
+
+	mgr := config.NewManager(configPath)
+	if !mgr.Exists() {
+		return fmt.Errorf("configuration file not found at %s\nRun 'git-keys init' first", configPath)
+	}
+
+	cfg, err := mgr.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get current directory: %w", err)
+	}
+	repo, err := gitOutput(cwd, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return fmt.Errorf("%s is not inside a git repository", cwd)
+	}
+
+	if useClear {
+		return clearRepoBinding(mgr, cfg, repo)
+	}
+
+	persona := cfg.FindPersona(args[0])
+	if persona == nil {
+		return fmt.Errorf("persona not found: %s", args[0])
+	}
+	if persona.Archived {
+		return fmt.Errorf("persona '%s' is archived; run 'git-keys persona unarchive %s' first", persona.Name, persona.Name)
+	}
+
+	plat, err := choosePlatformForRepo(persona, repo)
+	if err != nil {
+		return err
+	}
+
+	printHeader("\n📌 Bind Repository")
+	fmt.Printf("\n  Repository: %s\n", repo)
+	fmt.Printf("  Persona:    %s <%s>\n", persona.Name, persona.Email)
+	fmt.Printf("  Platform:   %s@%s\n\n", plat.Account, plat.Type)
+
+	if err := bindRepository(mgr, cfg, repo, persona, plat); err != nil {
+		return err
+	}
+
+	fmt.Printf("\n✅ %s now commits and pushes as %s.\n", repo, persona.Name)
+	return nil
+}
+
+// bindRepository sets the repository's local identity and SSH command to the
+// persona platform's and records the binding in the configuration
+func bindRepository(mgr *config.Manager, cfg *config.Config, repo string, persona *config.Persona, plat *config.Platform) error {
+	key := plat.GetActiveKey()
+	if key == nil {
+		return fmt.Errorf("%s@%s has no active key; run 'git-keys apply' first", plat.Account, plat.Type)
+	}
+
+	keyMgr := sshkey.NewManager(cfg.Defaults.GetKeysDir())
+	sshCommand := personaSSHCommand(plat, keyMgr.IdentityFilePath(key.LocalPath))
+
+	settings := [][2]string{
+		{"user.name", persona.Name},
+		{"user.email", persona.Email},
+		{"core.sshCommand", sshCommand},
+	}
+	for _, setting := range settings {
+		if err := exec.Command("git", "-C", repo, "config", "--local", setting[0], setting[1]).Run(); err != nil {
+			return fmt.Errorf("failed to set %s: %w", setting[0], err)
+		}
+		fmt.Printf("✓ %s = %s\n", setting[0], setting[1])
+	}
+
+	// Record the binding on this platform only
+	removeRepoBinding(cfg, repo)
+	plat.Repos = append(plat.Repos, repo)
+	if err := mgr.Save(cfg); err != nil {
+		return fmt.Errorf("failed to save configuration: %w", err)
+	}
+
+	histMgr := history.NewManager("")
+	if err := histMgr.Record(history.Entry{
+		Action:  "use",
+		Summary: fmt.Sprintf("Bound %s to persona '%s'", repo, persona.Name),
+		Details: map[string]string{
+			"repository": repo,
+			"persona":    persona.Name,
+			"platform":   fmt.Sprintf("%s@%s", plat.Account, plat.Type),
+		},
+	}); err != nil {
+		logger.Warn("Failed to record history: %v", err)
+	}
+
+	return nil
+}
+
+// choosePlatformForRepo picks the persona platform for a repository: the one
+// selected by --platform/--account, the only one, or the one whose host the
+// origin remote uses
+func choosePlatformForRepo(persona *config.Persona, repo string) (*config.Platform, error) {
+	var candidates []*config.Platform
+	for i := range persona.Platforms {
+		plat := &persona.Platforms[i]
+		if usePlatform != "" && string(plat.Type) != usePlatform {
+			continue
+		}
+		if useAccount != "" && plat.Account != useAccount {
+			continue
+		}
+		candidates = append(candidates, plat)
+	}
+
+	switch len(candidates) {
+	case 0:
+		return nil, fmt.Errorf("persona '%s' has no matching platform", persona.Name)
+	case 1:
+		return candidates[0], nil
+	}
+
+	if remote, err := gitOutput(repo, "config", "--get", "remote.origin.url"); err == nil {
+		host := remoteHost(remote)
+		var matched []*config.Platform
+		for _, plat := range candidates {
+			alias, hostname := sshHostAlias(persona, plat)
+			if strings.EqualFold(host, alias) || strings.EqualFold(host, hostname) {
+				matched = append(matched, plat)
+			}
+		}
+		if len(matched) == 1 {
+			return matched[0], nil
+		}
+	}
+
+	return nil, fmt.Errorf("persona '%s' has several platforms; choose one with --platform and --account", persona.Name)
+}
+
+// personaSSHCommand returns a core.sshCommand that offers only the given key
+func personaSSHCommand(plat *config.Platform, identityFile string) string {
+	quote := func(s string) string {
+		if strings.ContainsAny(s, " \t") {
+			return fmt.Sprintf("%q", s)
+		}
+		return s
+	}
+
+	command := fmt.Sprintf("ssh -i %s -o IdentitiesOnly=yes", quote(identityFile))
+	if plat.UsesExternalAgent() {
+		command += " -o IdentityAgent=" + quote(sshkey.ExpandHome(plat.IdentityAgent))
+	}
+
+	// The options of the managed Host block, which connections to the
+	// platform's own hostname do not read
+	options := plat.ExtraSSHOptions()
+	names := make([]string, 0, len(options))
+	for name := range options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		command += " -o " + quote(name+"="+options[name])
+	}
+	return command
+}
+
+// removeRepoBinding removes repo from every platform's repos and reports
+// whether it was bound
+func removeRepoBinding(cfg *config.Config, repo string) bool {
+	removed := false
+	for personaIdx := range cfg.Personas {
+		persona := &cfg.Personas[personaIdx]
+		for platformIdx := range persona.Platforms {
+			plat := &persona.Platforms[platformIdx]
+			kept := plat.Repos[:0]
+			for _, bound := range plat.Repos {
+				if bound == repo {
+					removed = true
+					continue
+				}
+				kept = append(kept, bound)
+			}
+			plat.Repos = kept
+			if len(plat.Repos) == 0 {
+				plat.Repos = nil
+			}
+		}
+	}
+	return removed
+}
+
+func clearRepoBinding(mgr *config.Manager, cfg *config.Config, repo string) error {
+	for _, key := range []string{"user.name", "user.email", "core.sshCommand"} {
+		// Exit status 5 means the key was not set
+		exec.Command("git", "-C", repo, "config", "--local", "--unset", key).Run()
+	}
+	fmt.Printf("✓ Removed local user.name, user.email and core.sshCommand from %s\n", repo)
+
+	if removeRepoBinding(cfg, repo) {
+		if err := mgr.Save(cfg); err != nil {
+			return fmt.Errorf("failed to save configuration: %w", err)
+		}
+
+		histMgr := history.NewManager("")
+		if err := histMgr.Record(history.Entry{
+			Action:  "use",
+			Summary: fmt.Sprintf("Removed the persona binding of %s", repo),
+			Details: map[string]string{"repository": repo},
+		}); err != nil {
+			logger.Warn("Failed to record history: %v", err)
+		}
+		fmt.Println("✓ Removed the binding from the configuration")
+	}
+
+	return nil
+}
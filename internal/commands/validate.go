@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/kunlu/git-keys/internal/config"
 	"github.com/kunlu/git-keys/internal/sshkey"
@@ -11,7 +12,9 @@ import (
 )
 
 var (
-	validateFix bool
+	validateFix              bool
+	validateStrict           bool
+	validateDetailedExitCode bool
 )
 
 var validateCmd = &cobra.Command{
@@ -25,40 +28,67 @@ This command performs comprehensive validation checks:
   • Valid platform types and URLs
   • Email format validation
   • SSH key file paths exist
-  • SSH key permissions (600 for private keys)
+  • Permissions across ~/.ssh (directories 700, private keys and
+    config 600, public keys 644, including the archive directory)
   • No duplicate personas/platforms
+  • Unknown (misspelled) keys, which are otherwise ignored; with
+    defaults.strict the configuration does not load at all
   • Fingerprint consistency
+  • Private and public key files match each other
+  • Platforms without a gitdir, and weak (DSA or short RSA) keys
 
 Use this after manually editing the configuration file to ensure
 everything is correct before running 'git-keys apply'.
 
+With --detailed-exitcode, or --quiet, the exit status also tells warnings
+apart:
+  0  the configuration is valid
+  1  validation failed with errors (or warnings, with --strict)
+  2  the configuration is valid with warnings
+
 Examples:
   # Validate configuration
   git-keys validate
 
-  # Validate and attempt to fix common issues
+  # Validate and repair permissions and other common issues
   git-keys validate --fix
+
+  # Fail on warnings too (or set strict: true under defaults)
+  git-keys validate --strict
+
+  # Check in CI without output
+  git-keys validate --quiet
 `,
-	RunE: runValidate,
+	Annotations: map[string]string{structuredOutputAnnotation: structuredQuiet},
+	RunE:        runValidate,
 }
 
 func init() {
 	validateCmd.Flags().BoolVar(&validateFix, "fix", false, "Attempt to fix common issues (e.g., file permissions)")
+	validateCmd.Flags().BoolVar(&validateStrict, "strict", false, "Treat warnings as errors")
+	validateCmd.Flags().BoolVar(&validateDetailedExitCode, "detailed-exitcode", false, "Exit with status 2 when there are warnings")
 	rootCmd.AddCommand(validateCmd)
 }
 
 func runValidate(cmd *cobra.Command, args []string) error {
-	fmt.Println("\n🔍 Validating Configuration")
-	fmt.Println("============================")
+	printHeader("\n🔍 Validating Configuration")
 	fmt.Println()
 
 	// Check if config file exists
-	configPath := config.GetDefaultConfigPath()
-	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		fmt.Println("❌ Configuration file not found")
+	configPath := cfgFile
+	if configPath == "" {
+		configPath = config.GetDefaultConfigPath()
+	}
+	if !config.NewManager(configPath).Exists() {
+		if structuredOutput() {
+			if err := printStructured("ValidationResult", newValidationReport(configPath, nil, false, []string{"configuration file not found"}, nil, nil)); err != nil {
+				return err
+			}
+		}
+		printCheck(checkFail, "Configuration file not found")
 		fmt.Printf("   Expected: %s\n\n", configPath)
 		fmt.Println("Run 'git-keys init' to create configuration")
-		return nil
+		return exitWithStatus(cmd, ExitError)
 	}
 
 	fmt.Printf("Config file: %s\n", configPath)
@@ -68,24 +98,97 @@ func runValidate(cmd *cobra.Command, args []string) error {
 	configMgr := config.NewManager(configPath)
 	cfg, err := configMgr.Load()
 	if err != nil {
-		fmt.Println("❌ Configuration validation failed")
+		if structuredOutput() {
+			if err := printStructured("ValidationResult", newValidationReport(configPath, nil, false, []string{err.Error()}, nil, nil)); err != nil {
+				return err
+			}
+		}
+		printCheck(checkFail, "Configuration validation failed")
 		fmt.Printf("   Error: %v\n\n", err)
 		return fmt.Errorf("invalid configuration")
 	}
 
-	fmt.Println("✓ YAML syntax valid")
+	printCheck(checkOK, "YAML syntax valid")
+	printEnvOverrides(cfg)
 	fmt.Println()
 
-	// Perform detailed validation
-	errors := []string{}
-	warnings := []string{}
-	fixedIssues := []string{}
+	errors, warnings, fixedIssues := checkConfig(cfg, validateFix)
+	warnings = append(unknownKeyWarnings(configPath), warnings...)
+
+	// Display results
+	printHeader("📋 Validation Results")
+	fmt.Println()
 
+	if len(errors) > 0 {
+		printCheck(checkFail, "Errors: %d", len(errors))
+		for _, err := range errors {
+			printWrapped("   • ", err)
+		}
+		fmt.Println()
+	}
+
+	if len(warnings) > 0 {
+		printCheck(checkWarn, "Warnings: %d", len(warnings))
+		for _, warn := range warnings {
+			printWrapped("   • ", warn)
+		}
+		fmt.Println()
+	}
+
+	if len(fixedIssues) > 0 {
+		fmt.Printf("🔧 Fixed: %d\n", len(fixedIssues))
+		for _, fix := range fixedIssues {
+			printWrapped("   • ", fix)
+		}
+		fmt.Println()
+	}
+
+	// Summary
+	strict := strictMode(cmd, cfg)
+	if structuredOutput() {
+		if err := printStructured("ValidationResult", newValidationReport(configPath, cfg, strict, errors, warnings, fixedIssues)); err != nil {
+			return err
+		}
+	}
+	if len(errors) == 0 && len(warnings) == 0 {
+		printCheck(checkOK, "Configuration is valid!")
+		fmt.Println("   No issues found.")
+	} else if len(errors) == 0 && strict {
+		printCheck(checkFail, "Strict mode: %d warning(s) treated as errors", len(warnings))
+		fmt.Println("   Please fix the warnings before running 'git-keys apply'")
+	} else if len(errors) == 0 {
+		printCheck(checkOK, "Configuration is valid with %d warning(s)", len(warnings))
+	} else {
+		printCheck(checkFail, "Configuration has %d error(s)", len(errors))
+		fmt.Println("   Please fix the errors before running 'git-keys apply'")
+	}
+	fmt.Println()
+
+	if len(errors) > 0 {
+		return fmt.Errorf("validation failed with %d error(s)", len(errors))
+	}
+	if strict && len(warnings) > 0 {
+		return fmt.Errorf("strict mode: validation failed with %d warning(s)", len(warnings))
+	}
+	if len(warnings) > 0 {
+		return exitWithStatus(cmd, ExitWarnings)
+	}
+
+	return nil
+}
+
+// checkConfig runs the validation checks on a loaded configuration and the
+// key files and SSH config it refers to. With fix, permission problems are
+// repaired instead of reported.
+func checkConfig(cfg *config.Config, fix bool) (errors, warnings, fixed []string) {
 	// Check personas
 	if len(cfg.Personas) == 0 {
 		errors = append(errors, "No personas defined")
 	}
 
+	sshDir := filepath.Join(os.Getenv("HOME"), ".ssh")
+	keysDir := cfg.Defaults.GetKeysDir()
+
 	seenPersonas := make(map[string]bool)
 	for _, persona := range cfg.Personas {
 		// Check for duplicate persona names
@@ -127,13 +230,21 @@ func runValidate(cmd *cobra.Command, args []string) error {
 				errors = append(errors, fmt.Sprintf("Platform %s in persona '%s' has no account", platform.Type, persona.Name))
 			}
 
+			// Keys of archived personas stay in the archive until unarchived
+			if persona.Archived {
+				continue
+			}
+
+			if warning := noGitDirWarning(&persona, &platform); warning != "" {
+				warnings = append(warnings, warning)
+			}
+
 			// Check keys
 			if len(platform.Keys) == 0 {
 				warnings = append(warnings, fmt.Sprintf("Platform %s/%s has no keys", persona.Name, platform.Type))
 			}
 
-			sshDir := filepath.Join(os.Getenv("HOME"), ".ssh")
-			keyMgr := sshkey.NewManager(sshDir)
+			keyMgr := sshkey.NewManager(keysDir)
 
 			for i, key := range platform.Keys {
 				// Validate key path
@@ -148,24 +259,17 @@ func runValidate(cmd *cobra.Command, args []string) error {
 					continue
 				}
 
-				// Check key permissions
-				info, err := os.Stat(key.LocalPath)
-				if err != nil {
-					errors = append(errors, fmt.Sprintf("Cannot stat key file: %s", key.LocalPath))
-					continue
+				// Agent-held keys only have a public key on disk
+				if key.Agent && !platform.UsesExternalAgent() {
+					warnings = append(warnings, fmt.Sprintf("Agent key %s in %s/%s has no identity_agent configured",
+						key.LocalPath, persona.Name, platform.Type))
 				}
 
-				mode := info.Mode().Perm()
-				expectedMode := os.FileMode(0600)
-				if mode != expectedMode {
-					if validateFix {
-						if err := os.Chmod(key.LocalPath, expectedMode); err != nil {
-							errors = append(errors, fmt.Sprintf("Failed to fix permissions for %s: %v", key.LocalPath, err))
-						} else {
-							fixedIssues = append(fixedIssues, fmt.Sprintf("Fixed permissions for %s (%o -> %o)", key.LocalPath, mode, expectedMode))
-						}
-					} else {
-						warnings = append(warnings, fmt.Sprintf("Insecure permissions on %s: %o (expected: %o)", key.LocalPath, mode, expectedMode))
+				// Key permissions are covered by the permissions walk below
+
+				if key.InUse() {
+					if warning := weakKeyWarning(keyMgr, &persona, &platform, &key); warning != "" {
+						warnings = append(warnings, warning)
 					}
 				}
 
@@ -174,7 +278,7 @@ func runValidate(cmd *cobra.Command, args []string) error {
 					warnings = append(warnings, fmt.Sprintf("Key at %s has no fingerprint", key.LocalPath))
 				} else {
 					// Verify fingerprint matches actual key file
-					pubKeyPath := key.LocalPath + ".pub"
+					pubKeyPath := strings.TrimSuffix(key.LocalPath, ".pub") + ".pub"
 					actualFingerprint, err := keyMgr.GetFingerprint(pubKeyPath)
 					if err != nil {
 						warnings = append(warnings, fmt.Sprintf("Cannot read fingerprint from %s: %v", pubKeyPath, err))
@@ -184,6 +288,19 @@ func runValidate(cmd *cobra.Command, args []string) error {
 					}
 				}
 
+				// Check that the private key matches the public key file
+				if key.HasPrivateKey() {
+					if err := keyMgr.VerifyKeyPair(key.LocalPath); err != nil {
+						if sshkey.IsKeyPairMismatch(err) {
+							errors = append(errors, fmt.Sprintf("Key pair mismatch in %s/%s@%s: %s (%v)",
+								persona.Name, platform.Type, platform.Account, key.LocalPath, err))
+						} else {
+							warnings = append(warnings, fmt.Sprintf("Cannot verify key pair for %s/%s@%s: %v",
+								persona.Name, platform.Type, platform.Account, err))
+						}
+					}
+				}
+
 				// Validate key status
 				validStatuses := map[config.KeyStatus]bool{
 					config.KeyStatusActive:  true,
@@ -199,50 +316,97 @@ func runValidate(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	// Display results
-	fmt.Println("📋 Validation Results")
-	fmt.Println("=====================")
-	fmt.Println()
-
-	if len(errors) > 0 {
-		fmt.Printf("❌ Errors: %d\n", len(errors))
-		for _, err := range errors {
-			fmt.Printf("   • %s\n", err)
-		}
-		fmt.Println()
+	// Check permissions across the whole ~/.ssh tree and the keys directory,
+	// not only managed keys
+	issues, err := sshkey.CheckPermissions(sshDir, keysDir)
+	if err != nil {
+		warnings = append(warnings, fmt.Sprintf("Permission check incomplete: %v", err))
 	}
-
-	if len(warnings) > 0 {
-		fmt.Printf("⚠️  Warnings: %d\n", len(warnings))
-		for _, warn := range warnings {
-			fmt.Printf("   • %s\n", warn)
+	for _, issue := range issues {
+		if fix {
+			if err := issue.Fix(); err != nil {
+				errors = append(errors, fmt.Sprintf("Failed to fix permissions for %s: %v", issue.Path, err))
+			} else {
+				fixed = append(fixed, fmt.Sprintf("Fixed permissions for %s %s (%o -> %o)", issue.Kind, issue.Path, issue.Mode, issue.Expected))
+			}
+		} else {
+			warnings = append(warnings, fmt.Sprintf("Unexpected permissions on %s %s: %o (expected: %o)", issue.Kind, issue.Path, issue.Mode, issue.Expected))
 		}
-		fmt.Println()
 	}
 
-	if len(fixedIssues) > 0 {
-		fmt.Printf("🔧 Fixed: %d\n", len(fixedIssues))
-		for _, fix := range fixedIssues {
-			fmt.Printf("   • %s\n", fix)
-		}
-		fmt.Println()
+	// Check for Host sections that conflict with managed SSH config hosts
+	conflicts, err := findSSHHostConflicts(cfg, sshConfigManager(cfg), sshkey.NewManager(keysDir))
+	if err != nil {
+		warnings = append(warnings, fmt.Sprintf("SSH config conflict check incomplete: %v", err))
+	}
+	for _, c := range conflicts {
+		warnings = append(warnings, fmt.Sprintf("SSH config conflict: %s", c.String()))
 	}
 
-	// Summary
-	if len(errors) == 0 && len(warnings) == 0 {
-		fmt.Println("✅ Configuration is valid!")
-		fmt.Println("   No issues found.")
-	} else if len(errors) == 0 {
-		fmt.Printf("✓ Configuration is valid with %d warning(s)\n", len(warnings))
-	} else {
-		fmt.Printf("❌ Configuration has %d error(s)\n", len(errors))
-		fmt.Println("   Please fix the errors before running 'git-keys apply'")
+	errors = append(errors, policyViolations(cfg)...)
+
+	return errors, warnings, fixed
+}
+
+// unknownKeyWarnings reports keys in the configuration file that git-keys
+// does not read, such as misspelled keys
+func unknownKeyWarnings(configPath string) []string {
+	data, err := config.ReadFile(configPath)
+	if err != nil {
+		return nil
 	}
-	fmt.Println()
+	unknown, err := config.UnknownFields(data)
+	if err != nil {
+		return nil
+	}
+	var warnings []string
+	for _, u := range unknown {
+		warnings = append(warnings, fmt.Sprintf("Ignored %s (misspelled?)", u))
+	}
+	return warnings
+}
 
-	if len(errors) > 0 {
-		return fmt.Errorf("validation failed with %d error(s)", len(errors))
+// printEnvOverrides lists the defaults overridden by GIT_KEYS_* environment
+// variables for this run
+func printEnvOverrides(cfg *config.Config) {
+	for _, o := range cfg.EnvOverrides() {
+		fmt.Printf("  Override: %s = %s (from %s)\n", o.Key, o.Value, o.Var)
 	}
+}
 
-	return nil
+// validationReport is the ValidationResult document of
+// 'validate --output json|yaml'
+type validationReport struct {
+	Config    string           `json:"config"`
+	Valid     bool             `json:"valid"` // No errors, and no warnings in strict mode
+	Strict    bool             `json:"strict"`
+	Errors    []string         `json:"errors"`
+	Warnings  []string         `json:"warnings"`
+	Fixed     []string         `json:"fixed"`
+	Overrides []reportOverride `json:"overrides"`
+}
+
+// reportOverride is a defaults value overridden by the environment
+type reportOverride struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+	Var   string `json:"var"`
+}
+
+func newValidationReport(configPath string, cfg *config.Config, strict bool, errors, warnings, fixed []string) validationReport {
+	report := validationReport{
+		Config:    configPath,
+		Valid:     len(errors) == 0 && !(strict && len(warnings) > 0),
+		Strict:    strict,
+		Errors:    append([]string{}, errors...),
+		Warnings:  append([]string{}, warnings...),
+		Fixed:     append([]string{}, fixed...),
+		Overrides: []reportOverride{},
+	}
+	if cfg != nil {
+		for _, o := range cfg.EnvOverrides() {
+			report.Overrides = append(report.Overrides, reportOverride{Key: o.Key, Value: o.Value, Var: o.Var})
+		}
+	}
+	return report
 }
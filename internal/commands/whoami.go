@@ -0,0 +1,254 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/kunlu/git-keys/internal/config"
+	"github.com/kunlu/git-keys/internal/sshkey"
+	"github.com/spf13/cobra"
+)
+
+var whoamiRemote string
+
+var whoamiCmd = &cobra.Command{
+	Use:   "whoami [path]",
+	Short: "Show the identity git and ssh use in a directory",
+	Long: `Show which persona, git identity and SSH key apply in a directory
+(default: the current directory).
+
+The identity is resolved the way git and ssh resolve it, not just from the
+git-keys configuration:
+  - user.name and user.email come from 'git config', with the file that set
+    them (e.g. the ~/.gitconfig-<persona>-... file of an includeIf)
+  - the remote URL is shown after url.<base>.insteadOf rewrites
+  - the SSH host and key come from 'ssh -G'
+
+Mismatches, such as an email that differs from the persona's or a remote that
+bypasses the persona's SSH alias, are flagged. Useful when a commit went out
+with the wrong email or a push used the wrong key.
+
+Examples:
+  git-keys whoami
+  git-keys whoami ~/work/api --remote upstream
+`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runWhoami,
+}
+
+func init() {
+	whoamiCmd.Flags().StringVar(&whoamiRemote, "remote", "origin", "Remote to resolve")
+	rootCmd.AddCommand(whoamiCmd)
+}
+
+// personaMatch is a persona platform that applies to a directory or remote
+type personaMatch struct {
+	Persona  *config.Persona
+	Platform *config.Platform
+	Reason   string
+}
+
+func runWhoami(cmd *cobra.Command, args []string) error {
+	// Load configuration
+	configPath := cfgFile
+	if configPath == "" {
+		configPath = config.GetDefaultConfigPath()
+	}
+
+	mgr := config.NewManager(configPath)
+	if !mgr.Exists() {
+		return fmt.Errorf("configuration file not found at %s\nRun 'git-keys init' first", configPath)
+	}
+
+	cfg, err := mgr.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	dir, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get current directory: %w", err)
+	}
+	if len(args) > 0 {
+		if dir, err = filepath.Abs(sshkey.ExpandHome(args[0])); err != nil {
+			return fmt.Errorf("invalid path: %w", err)
+		}
+	}
+
+	printHeader("\n🪪 Who Am I")
+	fmt.Printf("\n  Directory:  %s\n", dir)
+
+	repoRoot, err := gitOutput(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		fmt.Println("  Repository: not a git repository")
+	} else {
+		fmt.Printf("  Repository: %s\n", repoRoot)
+	}
+
+	var warnings []string
+
+	// Persona by repository binding, which overrides gitdir, then by gitdir
+	// as the includeIf in ~/.gitconfig selects it
+	var match *personaMatch
+	gitdirPersona, gitdirPlat, gitdir := personaByGitDir(cfg, dir)
+	bindingDir := dir
+	if repoRoot != "" {
+		bindingDir = repoRoot // Bindings are recorded by repository root
+	}
+	if persona, plat, repo := cfg.FindRepoBinding(bindingDir); persona != nil {
+		match = &personaMatch{Persona: persona, Platform: plat, Reason: "binding of " + repo}
+		if gitdirPersona != nil && gitdirPersona != persona {
+			match.Reason += fmt.Sprintf(" (overrides gitdir %s of '%s')", gitdir, gitdirPersona.Name)
+		}
+	} else if gitdirPersona != nil {
+		match = &personaMatch{Persona: gitdirPersona, Platform: gitdirPlat, Reason: "gitdir " + gitdir}
+	}
+
+	// Git identity as git resolves it
+	name, nameOrigin := gitConfigWithOrigin(dir, "user.name")
+	email, emailOrigin := gitConfigWithOrigin(dir, "user.email")
+
+	// Remote URL before and after insteadOf rewrites
+	var remoteURL, effectiveURL string
+	if repoRoot != "" {
+		remoteURL, _ = gitOutput(dir, "config", "--get", "remote."+whoamiRemote+".url")
+		effectiveURL, _ = gitOutput(dir, "ls-remote", "--get-url", whoamiRemote)
+	}
+	host := ""
+	if effectiveURL != "" {
+		host = remoteHost(effectiveURL)
+	}
+
+	// Persona by the SSH alias the remote resolves to
+	var remoteMatch *personaMatch
+	for personaIdx := range cfg.Personas {
+		persona := &cfg.Personas[personaIdx]
+		for platformIdx := range persona.Platforms {
+			plat := &persona.Platforms[platformIdx]
+			if alias, _ := sshHostAlias(persona, plat); host != "" && strings.EqualFold(alias, host) {
+				remoteMatch = &personaMatch{Persona: persona, Platform: plat, Reason: "SSH host " + alias}
+			}
+		}
+	}
+	if match == nil {
+		match = remoteMatch
+	}
+
+	fmt.Println()
+	if match != nil {
+		fmt.Printf("  Persona:    %s <%s>\n", match.Persona.Name, match.Persona.Email)
+		fmt.Printf("  Platform:   %s@%s\n", match.Platform.Account, match.Platform.Type)
+		fmt.Printf("  Matched by: %s\n", match.Reason)
+		if match.Persona.Archived {
+			warnings = append(warnings, fmt.Sprintf("persona '%s' is archived", match.Persona.Name))
+		}
+	} else {
+		fmt.Println("  Persona:    none (no binding or persona gitdir contains this directory)")
+	}
+
+	printHeader("\nGit Identity")
+	fmt.Printf("  user.name:  %s\n", whoamiValue(name, nameOrigin))
+	fmt.Printf("  user.email: %s\n", whoamiValue(email, emailOrigin))
+	if match != nil && email != "" && !strings.EqualFold(email, match.Persona.Email) {
+		warnings = append(warnings, fmt.Sprintf("commits use %s, but persona '%s' uses %s", email, match.Persona.Name, match.Persona.Email))
+	}
+	if email == "" {
+		warnings = append(warnings, "user.email is not set; git will refuse to commit or guess an address")
+	}
+
+	if remoteURL != "" {
+		printHeader(fmt.Sprintf("\nRemote (%s)", whoamiRemote))
+		fmt.Printf("  URL:        %s\n", remoteURL)
+		if effectiveURL != remoteURL {
+			fmt.Printf("  Rewritten:  %s\n", effectiveURL)
+		}
+
+		if host != "" && !strings.HasPrefix(effectiveURL, "http") {
+			sshMgr := sshConfigManager(cfg)
+			effective, err := sshMgr.EffectiveSettings(host)
+			if err != nil {
+				warnings = append(warnings, err.Error())
+			} else {
+				fmt.Printf("  SSH host:   %s → %s\n", host, effective.HostName)
+				fmt.Printf("  Key:        %s (IdentitiesOnly %s)\n", effective.IdentityFile, effective.IdentitiesOnly)
+			}
+		} else if host != "" {
+			warnings = append(warnings, "the remote uses HTTPS, so no SSH key is used")
+		}
+
+		switch {
+		case remoteMatch == nil && match != nil:
+			alias, _ := sshHostAlias(match.Persona, match.Platform)
+			warnings = append(warnings, fmt.Sprintf("the remote does not use %s; pushes will not use the persona's key", alias))
+		case remoteMatch != nil && match != nil && remoteMatch.Persona != match.Persona:
+			warnings = append(warnings, fmt.Sprintf("the remote uses the SSH key of persona '%s', but this directory belongs to '%s'",
+				remoteMatch.Persona.Name, match.Persona.Name))
+		}
+	}
+
+	fmt.Println()
+	if len(warnings) > 0 {
+		fmt.Printf("⚠️  Warnings: %d\n", len(warnings))
+		for _, w := range warnings {
+			printWrapped("   • ", w)
+		}
+	} else {
+		fmt.Println("✓ No identity mismatches found")
+	}
+
+	return nil
+}
+
+// personaByGitDir returns the first non-archived persona platform with a
+// gitdir pattern containing dir, and the pattern
+func personaByGitDir(cfg *config.Config, dir string) (*config.Persona, *config.Platform, string) {
+	for personaIdx := range cfg.Personas {
+		persona := &cfg.Personas[personaIdx]
+		if persona.Archived {
+			continue
+		}
+		for platformIdx := range persona.Platforms {
+			plat := &persona.Platforms[platformIdx]
+			if gitdir := platformGitDirFor(plat, dir); gitdir != "" {
+				return persona, plat, gitdir
+			}
+		}
+	}
+	return nil, nil, ""
+}
+
+// gitOutput runs git in dir and returns its trimmed output
+func gitOutput(dir string, args ...string) (string, error) {
+	output, err := exec.Command("git", append([]string{"-C", dir}, args...)...).Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// gitConfigWithOrigin returns the value git resolves for key in dir and the
+// file that set it
+func gitConfigWithOrigin(dir, key string) (string, string) {
+	output, err := gitOutput(dir, "config", "--show-origin", "--get", key)
+	if err != nil {
+		return "", ""
+	}
+	origin, value, ok := strings.Cut(output, "\t")
+	if !ok {
+		return output, ""
+	}
+	return value, strings.TrimPrefix(origin, "file:")
+}
+
+func whoamiValue(value, origin string) string {
+	if value == "" {
+		return "(not set)"
+	}
+	if origin == "" {
+		return value
+	}
+	return fmt.Sprintf("%s  (from %s)", value, origin)
+}
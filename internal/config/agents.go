@@ -0,0 +1,44 @@
+package config
+
+import "runtime"
+
+// knownAgents are the sockets of agents that identity_agent may name
+// instead of giving a path, by OS
+var knownAgents = map[string]map[string]string{
+	"1password": {
+		"darwin": "~/Library/Group Containers/2BUA8C4S2C.com.1password/t/agent.sock",
+		"linux":  "~/.1password/agent.sock",
+	},
+	"secretive": {
+		"darwin": "~/Library/Containers/com.maxgoedjen.Secretive.SecretAgent/Data/socket.ssh",
+	},
+}
+
+// KnownAgentSocket returns the socket of an agent named in identity_agent on
+// this OS, or "" when name is not a known agent
+func KnownAgentSocket(name string) string {
+	return knownAgents[name][runtime.GOOS]
+}
+
+// resolveIdentityAgents gives each platform the agent socket it uses: its
+// own identity_agent or else its persona's, with known agent names replaced
+// by their socket. withMachineKeys writes the settings back as they were.
+func (c *Config) resolveIdentityAgents() {
+	for i := range c.Personas {
+		persona := &c.Personas[i]
+		for j := range persona.Platforms {
+			plat := &persona.Platforms[j]
+			plat.agentSetting = plat.IdentityAgent
+
+			agent := plat.IdentityAgent
+			if agent == "" {
+				agent = persona.IdentityAgent
+			}
+			if socket := KnownAgentSocket(agent); socket != "" {
+				agent = socket
+			}
+			plat.IdentityAgent = agent
+			plat.resolvedAgent = agent
+		}
+	}
+}
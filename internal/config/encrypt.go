@@ -0,0 +1,310 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/kunlu/git-keys/internal/logger"
+)
+
+const (
+	// EncryptedSuffix is appended to the config path for the age-encrypted
+	// configuration
+	EncryptedSuffix = ".age"
+
+	// recipientsSuffix is appended to the encrypted path for the file with
+	// the age recipients the configuration is encrypted to. Without it, the
+	// configuration was encrypted with a passphrase by an earlier version.
+	recipientsSuffix = ".recipients"
+
+	// identityPrefix marks a comment in the recipients file naming an age
+	// identity file that decrypts the configuration
+	identityPrefix = "# identity: "
+
+	// IdentityEnv names an age identity file that decrypts the configuration,
+	// in addition to the ones in the recipients file
+	IdentityEnv = "GIT_KEYS_AGE_IDENTITY"
+)
+
+// decrypted caches decrypted configurations by path, so one command asks
+// for a passphrase at most once
+var (
+	decryptedMu sync.Mutex
+	decrypted   = make(map[string][]byte)
+)
+
+// EncryptedPath returns the path of the encrypted form of a configuration
+func EncryptedPath(path string) string {
+	return path + EncryptedSuffix
+}
+
+// RecipientsPath returns the path of the recipients file of an encrypted
+// configuration
+func RecipientsPath(path string) string {
+	return EncryptedPath(path) + recipientsSuffix
+}
+
+// IsEncrypted reports whether the configuration at path is stored encrypted,
+// i.e. there is an encrypted file and no plaintext one
+func IsEncrypted(path string) bool {
+	if _, err := os.Stat(path); err == nil {
+		return false
+	}
+	_, err := os.Stat(EncryptedPath(path))
+	return err == nil
+}
+
+// StoragePath returns the file a configuration is actually stored in: the
+// encrypted file for encrypted configurations, path otherwise
+func StoragePath(path string) string {
+	if IsEncrypted(path) {
+		return EncryptedPath(path)
+	}
+	return path
+}
+
+// ReadFile returns the contents of a configuration file, decrypting it if
+// it is stored encrypted
+func ReadFile(path string) ([]byte, error) {
+	if !IsEncrypted(path) {
+		return os.ReadFile(path)
+	}
+
+	decryptedMu.Lock()
+	defer decryptedMu.Unlock()
+	if data, ok := decrypted[path]; ok {
+		return data, nil
+	}
+
+	data, err := decryptFile(path)
+	if err != nil {
+		return nil, err
+	}
+	decrypted[path] = data
+	return data, nil
+}
+
+// AgeAvailable reports whether the age command is installed
+func AgeAvailable() bool {
+	_, err := exec.LookPath("age")
+	return err == nil
+}
+
+// ErrPassphraseEncryption is returned when a configuration encrypted with a
+// passphrase would be written. age asks for a new passphrase on every
+// encryption, so every save would re-key the file, and commands without a
+// terminal could not save at all.
+var ErrPassphraseEncryption = errors.New("the configuration is encrypted with a passphrase, which git-keys cannot reuse to save changes; " +
+	"re-encrypt it to a key with 'git-keys config decrypt' and 'git-keys config encrypt --recipient <key>'")
+
+// Encrypt replaces the plaintext configuration at path with an age-encrypted
+// copy, encrypted to recipients (age or SSH public keys, or files of them);
+// identityFiles are the files that decrypt it. The encrypted copy is
+// decrypted and compared before the plaintext file is removed.
+func Encrypt(path string, recipients, identityFiles []string) error {
+	if len(recipients) == 0 {
+		return fmt.Errorf("at least one recipient is required")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	recipientsPath := RecipientsPath(path)
+	if err := writeRecipients(recipientsPath, recipients, identityFiles); err != nil {
+		return err
+	}
+
+	if err := encryptTo(EncryptedPath(path), recipientsPath, data); err != nil {
+		return err
+	}
+
+	// Only give up the plaintext once the encrypted copy is known to work
+	check, err := decryptFile(path)
+	if err != nil {
+		os.Remove(EncryptedPath(path))
+		return fmt.Errorf("failed to decrypt the encrypted config, plaintext kept: %w", err)
+	}
+	if !bytes.Equal(check, data) {
+		os.Remove(EncryptedPath(path))
+		return fmt.Errorf("encrypted config does not decrypt to the original, plaintext kept")
+	}
+
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("failed to remove plaintext config: %w", err)
+	}
+
+	decryptedMu.Lock()
+	decrypted[path] = data
+	decryptedMu.Unlock()
+
+	logger.Info("Encrypted config %s", EncryptedPath(path))
+	return nil
+}
+
+// Decrypt replaces an encrypted configuration with its plaintext
+func Decrypt(path string) error {
+	if !IsEncrypted(path) {
+		return fmt.Errorf("config %s is not encrypted", path)
+	}
+
+	data, err := ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	for _, p := range []string{EncryptedPath(path), RecipientsPath(path)} {
+		if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+			logger.Warn("Failed to remove %s: %v", p, err)
+		}
+	}
+
+	logger.Info("Decrypted config %s", path)
+	return nil
+}
+
+// WriteEncrypted replaces the encrypted configuration of path with data,
+// encrypted with the same recipients as before. Configurations encrypted
+// with a passphrase are not written; see ErrPassphraseEncryption.
+func WriteEncrypted(path string, data []byte) error {
+	if err := encryptTo(EncryptedPath(path), RecipientsPath(path), data); err != nil {
+		return err
+	}
+	decryptedMu.Lock()
+	decrypted[path] = data
+	decryptedMu.Unlock()
+	return nil
+}
+
+// encryptTo encrypts data to target with age, to the recipients in
+// recipientsPath. Without that file the configuration is encrypted with a
+// passphrase, and ErrPassphraseEncryption is returned. The file is replaced
+// atomically.
+func encryptTo(target, recipientsPath string, data []byte) error {
+	if _, err := os.Stat(recipientsPath); err != nil {
+		if os.IsNotExist(err) {
+			return ErrPassphraseEncryption
+		}
+		return fmt.Errorf("failed to read %s: %w", recipientsPath, err)
+	}
+	args := []string{"--encrypt", "--recipients-file", recipientsPath}
+
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".git-keys-*.age")
+	if err != nil {
+		return fmt.Errorf("failed to create encrypted config: %w", err)
+	}
+	tmpPath := tmp.Name()
+	tmp.Close()
+	defer os.Remove(tmpPath)
+
+	cmd := exec.Command("age", append(args, "--output", tmpPath)...)
+	cmd.Stdin = bytes.NewReader(data)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	logger.Debug("Encrypting config to %s", target)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to encrypt config with age: %w\nOutput: %s", err, stderr.String())
+	}
+
+	if err := os.Chmod(tmpPath, 0600); err != nil {
+		return fmt.Errorf("failed to set permissions: %w", err)
+	}
+	if err := os.Rename(tmpPath, target); err != nil {
+		return fmt.Errorf("failed to write encrypted config: %w", err)
+	}
+	return nil
+}
+
+// decryptFile decrypts the encrypted configuration of path with the
+// identities from the recipients file and $GIT_KEYS_AGE_IDENTITY. Without
+// identities, age asks for the passphrase on the terminal.
+func decryptFile(path string) ([]byte, error) {
+	args := []string{"--decrypt"}
+	for _, identity := range identities(path) {
+		args = append(args, "--identity", identity)
+	}
+
+	cmd := exec.Command("age", append(args, EncryptedPath(path))...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	logger.Debug("Decrypting config %s", EncryptedPath(path))
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("failed to decrypt config with age: %w\nOutput: %s", err, stderr.String())
+	}
+	return stdout.Bytes(), nil
+}
+
+// identities returns the identity files that decrypt the configuration of
+// path
+func identities(path string) []string {
+	var ids []string
+	if env := os.Getenv(IdentityEnv); env != "" {
+		ids = append(ids, expandHome(env))
+	}
+
+	f, err := os.Open(RecipientsPath(path))
+	if err != nil {
+		return ids
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if id, ok := strings.CutPrefix(strings.TrimSpace(scanner.Text()), identityPrefix); ok && id != "" {
+			ids = append(ids, expandHome(id))
+		}
+	}
+	return ids
+}
+
+// writeRecipients writes the recipients file. Recipients that name a file
+// are replaced by the keys in it; identities are kept as comments.
+func writeRecipients(path string, recipients, identities []string) error {
+	var b strings.Builder
+	b.WriteString("# Recipients of the encrypted git-keys configuration\n")
+	for _, id := range identities {
+		abs, err := filepath.Abs(expandHome(id))
+		if err != nil {
+			return fmt.Errorf("invalid identity %s: %w", id, err)
+		}
+		if _, err := os.Stat(abs); err != nil {
+			return fmt.Errorf("identity file not found: %s", id)
+		}
+		b.WriteString(identityPrefix + abs + "\n")
+	}
+
+	for _, r := range recipients {
+		if data, err := os.ReadFile(expandHome(r)); err == nil {
+			b.Write(bytes.TrimSpace(data))
+			b.WriteString("\n")
+			continue
+		}
+		b.WriteString(strings.TrimSpace(r) + "\n")
+	}
+
+	if err := os.WriteFile(path, []byte(b.String()), 0600); err != nil {
+		return fmt.Errorf("failed to write recipients file: %w", err)
+	}
+	return nil
+}
+
+// expandHome expands a leading ~/ to the home directory
+func expandHome(path string) string {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, rest)
+		}
+	}
+	return path
+}
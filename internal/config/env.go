@@ -0,0 +1,129 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kunlu/git-keys/internal/logger"
+)
+
+// EnvPrefix starts the environment variables that override configuration
+// values, e.g. GIT_KEYS_DEFAULTS_KEY_TYPE for defaults.key_type
+const EnvPrefix = "GIT_KEYS_"
+
+// EnvOverride is a defaults value replaced by an environment variable for
+// the current run
+type EnvOverride struct {
+	Var   string // Environment variable, e.g. GIT_KEYS_DEFAULTS_KEY_TYPE
+	Key   string // Configuration key, e.g. defaults.key_type
+	Value string // Value from the environment
+
+	field    int           // Index of the field in Defaults
+	original reflect.Value // Value from the configuration file
+	applied  reflect.Value // Value after the override
+}
+
+// EnvOverrides returns the values the environment overrode when the
+// configuration was loaded
+func (c *Config) EnvOverrides() []EnvOverride {
+	return c.envOverrides
+}
+
+// applyEnvOverrides replaces defaults values with the environment variables
+// GIT_KEYS_DEFAULTS_<KEY>, or the shorter GIT_KEYS_<KEY>, where <KEY> is the
+// upper-case YAML key. The overrides last for the run; Save writes the values
+// from the file back unless a command changed them.
+func (c *Config) applyEnvOverrides() error {
+	defaults := reflect.ValueOf(&c.Defaults).Elem()
+	typ := defaults.Type()
+
+	for i := 0; i < typ.NumField(); i++ {
+		key := strings.Split(typ.Field(i).Tag.Get("yaml"), ",")[0]
+		if key == "" || key == "-" {
+			continue
+		}
+
+		name := strings.ToUpper(key)
+		envVar := EnvPrefix + "DEFAULTS_" + name
+		value, ok := os.LookupEnv(envVar)
+		if !ok {
+			envVar = EnvPrefix + name
+			if value, ok = os.LookupEnv(envVar); !ok {
+				continue
+			}
+		}
+
+		field := defaults.Field(i)
+		original := reflect.New(field.Type()).Elem()
+		original.Set(field)
+		if err := setFromEnv(field, value); err != nil {
+			return fmt.Errorf("invalid %s: %w", envVar, err)
+		}
+
+		applied := reflect.New(field.Type()).Elem()
+		applied.Set(field)
+		c.envOverrides = append(c.envOverrides, EnvOverride{
+			Var:      envVar,
+			Key:      "defaults." + key,
+			Value:    value,
+			field:    i,
+			original: original,
+			applied:  applied,
+		})
+		logger.Debug("Overriding defaults.%s with %s", key, envVar)
+	}
+
+	sort.Slice(c.envOverrides, func(i, j int) bool { return c.envOverrides[i].Key < c.envOverrides[j].Key })
+	return nil
+}
+
+// restoreFileDefaults puts back the values overridden by the environment,
+// keeping those a command has changed since
+func (c *Config) restoreFileDefaults() {
+	defaults := reflect.ValueOf(&c.Defaults).Elem()
+	for _, o := range c.envOverrides {
+		field := defaults.Field(o.field)
+		if reflect.DeepEqual(field.Interface(), o.applied.Interface()) {
+			field.Set(o.original)
+		}
+	}
+}
+
+// setFromEnv parses value into a defaults field
+func setFromEnv(field reflect.Value, value string) error {
+	value = strings.TrimSpace(value)
+
+	if field.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%q is not true or false", value)
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", value)
+		}
+		field.SetInt(n)
+	default:
+		return fmt.Errorf("cannot be set from the environment")
+	}
+	return nil
+}
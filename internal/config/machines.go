@@ -0,0 +1,159 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/kunlu/git-keys/internal/logger"
+	"github.com/kunlu/git-keys/internal/platform"
+)
+
+// MachineIDEnv overrides the detected ID of the machine git-keys runs on,
+// for systems where it cannot be detected
+const MachineIDEnv = "GIT_KEYS_MACHINE_ID"
+
+// FindMachine finds a machine sharing the configuration by ID
+func (c *Config) FindMachine(id string) *Machine {
+	for i := range c.Machines {
+		if c.Machines[i].ID == id {
+			return &c.Machines[i]
+		}
+	}
+	return nil
+}
+
+// MachineName returns the name of the machine with the given ID, or the ID
+// itself for machines the configuration does not list
+func (c *Config) MachineName(id string) string {
+	if m := c.FindMachine(id); m != nil && m.Name != "" {
+		return m.Name
+	}
+	return id
+}
+
+// currentMachine returns the machine git-keys runs on. A machine that is not
+// listed yet is detected; it is added to the configuration when it is saved.
+func currentMachine(machines []Machine) (Machine, error) {
+	id := os.Getenv(MachineIDEnv)
+
+	plat, platErr := platform.NewPlatform()
+	if id == "" && platErr == nil {
+		detected, err := plat.GetMachineID()
+		if err != nil {
+			logger.Debug("Failed to detect machine ID: %v", err)
+		}
+		id = detected
+	}
+
+	if id == "" {
+		// Without an ID, a file used on one machine can only be this one's
+		if len(machines) == 1 {
+			return machines[0], nil
+		}
+		var ids []string
+		for _, m := range machines {
+			ids = append(ids, fmt.Sprintf("%s (%s)", m.ID, m.Name))
+		}
+		return Machine{}, fmt.Errorf("cannot detect which machine this is; set %s to one of: %s", MachineIDEnv, strings.Join(ids, ", "))
+	}
+
+	for _, m := range machines {
+		if m.ID == id {
+			return m, nil
+		}
+	}
+
+	machine := Machine{ID: id, Name: "unknown"}
+	if platErr == nil {
+		if name, err := plat.GetMachineName(); err == nil {
+			machine.Name = name
+		}
+		machine.OS = plat.GetOS()
+		machine.OSVersion, _ = plat.GetOSVersion()
+	}
+	logger.Info("Machine %s (%s) is new to this configuration", machine.Name, machine.ID)
+	return machine, nil
+}
+
+// splitMachineKeys sets aside the keys of other machines, so the key lists
+// only have the keys of c.Machine. Keys without a machine belong to it.
+func (c *Config) splitMachineKeys() {
+	for i := range c.Personas {
+		for j := range c.Personas[i].Platforms {
+			plat := &c.Personas[i].Platforms[j]
+			plat.Keys, plat.OtherKeys = splitKeys(plat.Keys, c.Machine.ID)
+			plat.SigningKeys, plat.OtherSigningKeys = splitKeys(plat.SigningKeys, c.Machine.ID)
+		}
+	}
+	for i := range c.DeployKeys {
+		d := &c.DeployKeys[i]
+		d.Keys, d.OtherKeys = splitKeys(d.Keys, c.Machine.ID)
+	}
+}
+
+// splitKeys returns the keys of the machine with the given ID and the keys
+// of other machines
+func splitKeys(keys []KeyConfig, machineID string) (mine, others []KeyConfig) {
+	for _, key := range keys {
+		if key.Machine == "" || key.Machine == machineID {
+			mine = append(mine, key)
+		} else {
+			others = append(others, key)
+		}
+	}
+	return mine, others
+}
+
+// withMachineKeys returns a copy of c as it is written to disk: the keys of
+// this machine are marked with its ID and merged with the keys of other
+// machines, this machine is listed in Machines, and identity agents that
+// were not changed are written as they were set. c is not changed.
+func (c *Config) withMachineKeys() *Config {
+	out := *c
+
+	out.Machines = append([]Machine(nil), c.Machines...)
+	if c.Machine.ID != "" {
+		if m := out.FindMachine(c.Machine.ID); m != nil {
+			*m = c.Machine
+		} else {
+			out.Machines = append(out.Machines, c.Machine)
+		}
+	}
+
+	out.Personas = make([]Persona, len(c.Personas))
+	for i, persona := range c.Personas {
+		persona.Platforms = append([]Platform(nil), persona.Platforms...)
+		for j := range persona.Platforms {
+			plat := &persona.Platforms[j]
+			plat.Keys = mergeKeys(plat.Keys, plat.OtherKeys, c.Machine.ID)
+			plat.SigningKeys = mergeKeys(plat.SigningKeys, plat.OtherSigningKeys, c.Machine.ID)
+			if plat.IdentityAgent == plat.resolvedAgent {
+				plat.IdentityAgent = plat.agentSetting
+			}
+		}
+		out.Personas[i] = persona
+	}
+
+	out.DeployKeys = append([]DeployKey(nil), c.DeployKeys...)
+	for i := range out.DeployKeys {
+		d := &out.DeployKeys[i]
+		d.Keys = mergeKeys(d.Keys, d.OtherKeys, c.Machine.ID)
+	}
+	return &out
+}
+
+// mergeKeys marks mine with machineID and appends the keys of other machines
+func mergeKeys(mine, others []KeyConfig, machineID string) []KeyConfig {
+	if len(mine) == 0 && len(others) == 0 {
+		return nil
+	}
+	merged := make([]KeyConfig, 0, len(mine)+len(others))
+	for _, key := range mine {
+		if machineID != "" {
+			key.Machine = machineID
+		}
+		merged = append(merged, key)
+	}
+	return append(merged, others...)
+}
@@ -4,13 +4,17 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
+	"github.com/kunlu/git-keys/internal/logger"
+	"github.com/kunlu/git-keys/internal/profile"
 	"gopkg.in/yaml.v3"
 )
 
 const (
 	DefaultConfigFileName = ".git-keys.yaml"
-	ConfigVersion         = "1.0"
+	ProfileConfigFileName = "config.yaml" // In the directory of a profile
+	ConfigVersion         = "1.2"
 )
 
 // Manager handles configuration file operations
@@ -26,8 +30,12 @@ func NewManager(configPath string) *Manager {
 	return &Manager{configPath: configPath}
 }
 
-// GetDefaultConfigPath returns the default config file path
+// GetDefaultConfigPath returns the default config file path: ~/.git-keys.yaml,
+// or config.yaml in the directory of the selected profile
 func GetDefaultConfigPath() string {
+	if !profile.IsDefault() {
+		return filepath.Join(profile.Dir(), ProfileConfigFileName)
+	}
 	home, err := os.UserHomeDir()
 	if err != nil {
 		return ""
@@ -35,32 +43,122 @@ func GetDefaultConfigPath() string {
 	return filepath.Join(home, DefaultConfigFileName)
 }
 
-// Load reads the configuration from disk
+// Load reads the configuration from disk, decrypting it if it is stored
+// encrypted
 func (m *Manager) Load() (*Config, error) {
-	data, err := os.ReadFile(m.configPath)
+	data, err := ReadFile(m.configPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
 
+	// Upgrade files written by older versions before they are parsed
+	original := data
+	migrated, applied, err := migrate(data)
+	if err != nil {
+		return nil, err
+	}
+	if migrated != nil {
+		data = migrated
+	}
+
 	var config Config
 	if err := yaml.Unmarshal(data, &config); err != nil {
 		return nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
 
+	// CI runs and experiments override values without editing the file
+	if err := config.applyEnvOverrides(); err != nil {
+		return nil, err
+	}
+
+	// Strict mode rejects misspelled keys instead of dropping them
+	if config.Defaults.Strict {
+		unknown, err := UnknownFields(data)
+		if err != nil {
+			return nil, err
+		}
+		if len(unknown) > 0 {
+			return nil, fmt.Errorf("invalid config (defaults.strict): %s", strings.Join(unknown, "; "))
+		}
+	}
+
 	if err := config.Validate(); err != nil {
 		return nil, fmt.Errorf("invalid config: %w", err)
 	}
 
+	// Commands work on the keys of this machine only
+	machine, err := currentMachine(config.Machines)
+	if err != nil {
+		return nil, err
+	}
+	config.Machine = machine
+	config.splitMachineKeys()
+	config.resolveIdentityAgents()
+
+	if len(applied) > 0 {
+		if err := m.saveMigrated(&config, original, applied); err != nil {
+			return nil, err
+		}
+	}
+
 	return &config, nil
 }
 
-// Save writes the configuration to disk
+// migrate upgrades the data of an older configuration file to
+// ConfigVersion. Returns nil data when no migration applies.
+func migrate(data []byte) ([]byte, []Migration, error) {
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil || doc == nil {
+		return nil, nil, nil // Reported when the file is parsed
+	}
+
+	applied, err := migrateDocument(doc)
+	if err != nil || len(applied) == 0 {
+		return nil, nil, err
+	}
+
+	migrated, err := yaml.Marshal(doc)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal migrated config: %w", err)
+	}
+	return migrated, applied, nil
+}
+
+// saveMigrated writes a migrated configuration, keeping the original file
+// next to it as <config>.v<version>.bak (encrypted like the config)
+func (m *Manager) saveMigrated(config *Config, original []byte, applied []Migration) error {
+	backupPath := fmt.Sprintf("%s.v%s.bak", m.configPath, applied[0].From)
+	if IsEncrypted(m.configPath) {
+		backupPath = EncryptedPath(backupPath)
+		if err := encryptTo(backupPath, RecipientsPath(m.configPath), original); err != nil {
+			return fmt.Errorf("failed to back up config before migration: %w", err)
+		}
+	} else if err := os.WriteFile(backupPath, original, 0600); err != nil {
+		return fmt.Errorf("failed to back up config before migration: %w", err)
+	}
+	if err := m.Save(config); err != nil {
+		return fmt.Errorf("failed to save migrated config: %w", err)
+	}
+
+	for _, mig := range applied {
+		logger.Info("Migrated config %s -> %s: %s", mig.From, mig.To, mig.Description)
+	}
+	logger.Info("Previous config saved to %s", backupPath)
+	return nil
+}
+
+// Save writes the configuration to disk. The keys of other machines are
+// written back unchanged, and so are defaults overridden by the environment.
+// An encrypted configuration is encrypted again with the same recipients;
+// one encrypted with a passphrase is not written (ErrPassphraseEncryption).
 func (m *Manager) Save(config *Config) error {
-	if err := config.Validate(); err != nil {
+	out := config.withMachineKeys()
+	out.restoreFileDefaults()
+	if err := out.Validate(); err != nil {
 		return fmt.Errorf("invalid config: %w", err)
 	}
 
-	data, err := yaml.Marshal(config)
+	data, err := yaml.Marshal(out)
 	if err != nil {
 		return fmt.Errorf("failed to marshal config: %w", err)
 	}
@@ -71,6 +169,10 @@ func (m *Manager) Save(config *Config) error {
 		return fmt.Errorf("failed to create config directory: %w", err)
 	}
 
+	if IsEncrypted(m.configPath) {
+		return WriteEncrypted(m.configPath, data)
+	}
+
 	// Write with restrictive permissions
 	if err := os.WriteFile(m.configPath, data, 0600); err != nil {
 		return fmt.Errorf("failed to write config file: %w", err)
@@ -79,9 +181,9 @@ func (m *Manager) Save(config *Config) error {
 	return nil
 }
 
-// Exists checks if the config file exists
+// Exists checks if the config file exists, in plaintext or encrypted
 func (m *Manager) Exists() bool {
-	_, err := os.Stat(m.configPath)
+	_, err := os.Stat(StoragePath(m.configPath))
 	return err == nil
 }
 
@@ -95,6 +197,7 @@ func (m *Manager) CreateDefault(machine Machine) *Config {
 	return &Config{
 		Version:  ConfigVersion,
 		Machine:  machine,
+		Machines: []Machine{machine},
 		Personas: []Persona{},
 		Defaults: Defaults{
 			KeyType:       KeyTypeED25519,
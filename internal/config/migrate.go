@@ -0,0 +1,161 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Migration upgrades a configuration file from one version to the next. It
+// works on the decoded YAML document, so it can read keys that the current
+// Config struct no longer has.
+type Migration struct {
+	From        string
+	To          string
+	Description string
+	Migrate     func(doc map[string]interface{}) error
+}
+
+// migrations are applied in order to files older than ConfigVersion. Add new
+// migrations at the end and bump ConfigVersion to the last To.
+var migrations = []Migration{
+	{
+		From:        "1.0",
+		To:          "1.1",
+		Description: "move signing keys listed under keys to signing_keys",
+		Migrate:     migrateSigningKeys,
+	},
+	{
+		From:        "1.1",
+		To:          "1.2",
+		Description: "list the machine under machines and assign the keys to it",
+		Migrate:     migrateMachines,
+	},
+}
+
+// documentVersion returns the version of a decoded configuration. A bare
+// "1" (or 1 without quotes) is the same version as "1.0".
+func documentVersion(doc map[string]interface{}) string {
+	var version string
+	switch v := doc["version"].(type) {
+	case string:
+		version = v
+	case int:
+		version = strconv.Itoa(v)
+	case float64:
+		version = strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	if version != "" && !strings.Contains(version, ".") {
+		version += ".0"
+	}
+	return version
+}
+
+// migrateDocument applies the migrations that upgrade doc to ConfigVersion
+// and returns the ones it applied. Files without a version or from an
+// unknown version are left alone.
+func migrateDocument(doc map[string]interface{}) ([]Migration, error) {
+	version := documentVersion(doc)
+	if version == "" || version == ConfigVersion {
+		return nil, nil
+	}
+
+	var applied []Migration
+	for _, m := range migrations {
+		if m.From != version {
+			continue
+		}
+		if err := m.Migrate(doc); err != nil {
+			return applied, fmt.Errorf("migration %s -> %s (%s) failed: %w", m.From, m.To, m.Description, err)
+		}
+		doc["version"] = m.To
+		version = m.To
+		applied = append(applied, m)
+	}
+	return applied, nil
+}
+
+// migrateSigningKeys moves keys with purpose signing from a platform's keys
+// to its signing_keys
+func migrateSigningKeys(doc map[string]interface{}) error {
+	personas, _ := doc["personas"].([]interface{})
+	for _, p := range personas {
+		persona, ok := p.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		platforms, _ := persona["platforms"].([]interface{})
+		for _, pl := range platforms {
+			platform, ok := pl.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			keys, _ := platform["keys"].([]interface{})
+			signing, _ := platform["signing_keys"].([]interface{})
+
+			var auth []interface{}
+			for _, k := range keys {
+				if key, ok := k.(map[string]interface{}); ok && key["purpose"] == string(KeyPurposeSigning) {
+					signing = append(signing, key)
+					continue
+				}
+				auth = append(auth, k)
+			}
+			if len(auth) == len(keys) {
+				continue
+			}
+
+			if len(auth) > 0 {
+				platform["keys"] = auth
+			} else {
+				delete(platform, "keys")
+			}
+			platform["signing_keys"] = signing
+		}
+	}
+	return nil
+}
+
+// migrateMachines turns the single machine into the list of machines sharing
+// the file and marks every key as belonging to it. Files without a machine,
+// such as templates, are left alone.
+func migrateMachines(doc map[string]interface{}) error {
+	machine, ok := doc["machine"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	id, _ := machine["id"].(string)
+	doc["machines"] = []interface{}{machine}
+	delete(doc, "machine")
+
+	markKeys := func(keys interface{}) {
+		list, _ := keys.([]interface{})
+		for _, k := range list {
+			if key, ok := k.(map[string]interface{}); ok && id != "" {
+				key["machine"] = id
+			}
+		}
+	}
+
+	personas, _ := doc["personas"].([]interface{})
+	for _, p := range personas {
+		persona, ok := p.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		platforms, _ := persona["platforms"].([]interface{})
+		for _, pl := range platforms {
+			if platform, ok := pl.(map[string]interface{}); ok {
+				markKeys(platform["keys"])
+				markKeys(platform["signing_keys"])
+			}
+		}
+	}
+	deployKeys, _ := doc["deploy_keys"].([]interface{})
+	for _, d := range deployKeys {
+		if deployKey, ok := d.(map[string]interface{}); ok {
+			markKeys(deployKey["keys"])
+		}
+	}
+	return nil
+}
@@ -2,18 +2,50 @@ package config
 
 import (
 	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
 	"time"
+
+	"github.com/kunlu/git-keys/internal/profile"
+	"golang.org/x/crypto/ssh"
 )
 
 // Config represents the git-keys configuration file
 type Config struct {
 	Version  string    `yaml:"version"`
-	Machine  Machine   `yaml:"machine"`
+	Machines []Machine `yaml:"machines"` // Machines sharing this file; each key belongs to one
 	Personas []Persona `yaml:"personas"`
 	Defaults Defaults  `yaml:"defaults,omitempty"`
+
+	// Machine is the machine git-keys runs on, picked from Machines when the
+	// configuration is loaded
+	Machine Machine `yaml:"-"`
+
+	// Deploy keys give this machine access to single repositories
+	DeployKeys []DeployKey `yaml:"deploy_keys,omitempty"`
+
+	// Defaults values replaced by GIT_KEYS_* environment variables
+	envOverrides []EnvOverride
+}
+
+// DeployKey is an SSH key registered as a repository's deploy key. The API
+// token of a persona's platform manages it.
+type DeployKey struct {
+	Repo      string       `yaml:"repo"`                 // owner/name on GitHub, group/project path on GitLab
+	Persona   string       `yaml:"persona"`              // Persona whose platform token manages the key
+	Platform  PlatformType `yaml:"platform"`             // "github" or "gitlab"
+	Account   string       `yaml:"account,omitempty"`    // Needed when the persona has several accounts on the platform
+	ReadWrite bool         `yaml:"read_write,omitempty"` // Allow pushes; deploy keys are read-only by default
+	Keys      []KeyConfig  `yaml:"keys,omitempty"`
+
+	// Keys of other machines sharing the configuration
+	OtherKeys []KeyConfig `yaml:"-"`
 }
 
-// Machine represents the local machine identity
+// Machine represents the identity of a machine
 type Machine struct {
 	ID        string `yaml:"id"`   // Hardware UUID
 	Name      string `yaml:"name"` // Human-readable name
@@ -26,6 +58,34 @@ type Persona struct {
 	Name      string     `yaml:"name"`  // e.g., "personal", "work"
 	Email     string     `yaml:"email"` // Git commit email
 	Platforms []Platform `yaml:"platforms"`
+
+	// Key overrides for all platforms of this persona (fall back to defaults)
+	KeyType       KeyType       `yaml:"key_type,omitempty"`
+	KeyExpiration time.Duration `yaml:"key_expiration,omitempty"`
+	KeyName       string        `yaml:"key_name,omitempty"` // Key file name template
+
+	// Agent holding the keys of all platforms of this persona, such as
+	// 1Password's; a platform's own identity_agent wins
+	IdentityAgent string `yaml:"identity_agent,omitempty"`
+
+	// Sign commits with a dedicated signing key shared by all platforms of
+	// the persona: an SSH key (gpg.format=ssh, the default) or a GPG key
+	SignCommits   bool          `yaml:"sign_commits,omitempty"`
+	SigningFormat SigningFormat `yaml:"signing_format,omitempty"`
+	GPGKey        *GPGKey       `yaml:"gpg_key,omitempty"` // Set by apply, or by hand to use an existing key
+
+	// How the persona's git config makes git use its keys; defaults to
+	// defaults.switch_strategy
+	SwitchStrategy SwitchStrategy `yaml:"switch_strategy,omitempty"`
+
+	// Names the persona had before 'git-keys persona rename'. Remotes that
+	// still use their SSH host aliases are rewritten to the current alias.
+	PreviousNames []string `yaml:"previous_names,omitempty"`
+
+	// Archived personas keep their definition but have no registered keys,
+	// SSH config entries or git identity until unarchived
+	Archived   bool      `yaml:"archived,omitempty"`
+	ArchivedAt time.Time `yaml:"archived_at,omitempty"`
 }
 
 // Platform represents a git hosting platform configuration
@@ -35,6 +95,222 @@ type Platform struct {
 	BaseURL string       `yaml:"base_url,omitempty"` // For self-hosted GitLab
 	GitDir  string       `yaml:"gitdir,omitempty"`   // Directory pattern for git config includeIf
 	Keys    []KeyConfig  `yaml:"keys,omitempty"`     // Managed keys
+	// The persona's signing keys as registered on this account
+	SigningKeys []KeyConfig `yaml:"signing_keys,omitempty"`
+	GPGKeyID    string      `yaml:"gpg_key_id,omitempty"` // Remote ID of the persona's GPG key
+	Repos       []string    `yaml:"repos,omitempty"`      // Repositories bound with 'git-keys use'; they override gitdir
+
+	// Keys and signing keys of other machines sharing the configuration.
+	// They are set aside when the configuration is loaded, so commands only
+	// see and change the keys of this machine.
+	OtherKeys        []KeyConfig `yaml:"-"`
+	OtherSigningKeys []KeyConfig `yaml:"-"`
+
+	// External agent support (e.g., Secretive / Secure Enclave). When set, no
+	// key file is generated; the public key is taken from the agent instead.
+	IdentityAgent string `yaml:"identity_agent,omitempty"` // Agent socket path, or a known agent such as 1password
+	AgentKey      string `yaml:"agent_key,omitempty"`      // Public key file, or comment of the key in the agent
+
+	// identity_agent as written in the file, and the socket it resolved to
+	// when loaded; see resolveIdentityAgents
+	agentSetting  string
+	resolvedAgent string
+
+	// Key overrides for this platform (fall back to persona, then defaults)
+	KeyType       KeyType       `yaml:"key_type,omitempty"`
+	KeyExpiration time.Duration `yaml:"key_expiration,omitempty"`
+	KeyName       string        `yaml:"key_name,omitempty"` // Key file name template
+
+	// Connection settings for self-hosted GitLab behind a proxy or an
+	// internal CA. Without a proxy, HTTPS_PROXY/NO_PROXY are honored.
+	CACertPath         string `yaml:"ca_cert_path,omitempty"`         // Extra PEM CA bundle to trust
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify,omitempty"` // Disable TLS verification
+	Proxy              string `yaml:"proxy,omitempty"`                // Proxy URL, e.g. http://proxy:3128
+	OAuthClientID      string `yaml:"oauth_client_id,omitempty"`      // OAuth application ID for 'git-keys login gitlab'
+
+	// SSH options of the platform's managed Host block, e.g. for a host only
+	// reachable through a bastion
+	ProxyJump    string            `yaml:"proxy_jump,omitempty"`     // Jump host, e.g. bastion.example.com or user@bastion:2222
+	ProxyCommand string            `yaml:"proxy_command,omitempty"`  // Command connecting to the host, e.g. ssh -W %h:%p bastion
+	SSHOptions   map[string]string `yaml:"ssh_options,omitempty"`    // Further options, e.g. Port: "2222"
+	SSHOverHTTPS bool              `yaml:"ssh_over_https,omitempty"` // Also write a fallback host using SSH over port 443
+	HostKeys     []string          `yaml:"host_keys,omitempty"`      // Pinned SSH host keys ("type base64"), e.g. of self-hosted GitLab
+
+	// Remote URL patterns whose repositories get the platform's identity
+	// wherever they are cloned, e.g. git@github.com:acme/** (includeIf
+	// hasconfig:remote.*.url, git 2.36 or later)
+	RemoteURLs []string `yaml:"remote_urls,omitempty"`
+
+	// More conditions applying the identity, each an includeIf entry of
+	// its own: directory patterns besides gitdir, and raw conditions
+	GitDirs   []string `yaml:"gitdirs,omitempty"`    // e.g. ~/src/corp/
+	IncludeIf []string `yaml:"include_if,omitempty"` // e.g. onbranch:corp/** or gitdir/i:~/Clients/
+
+	// Further hosts the platform's key applies to: patterns added to the
+	// alias's Host line, and Match blocks written after it
+	SSHHostPatterns []string `yaml:"ssh_host_patterns,omitempty"` // e.g. "gh-work" or "*.github.com.work"
+	SSHMatch        []string `yaml:"ssh_match,omitempty"`         // Match criteria, e.g. canonical host github.com exec "test -d .work"
+}
+
+// sshOverHTTPSHosts are the hosts that accept SSH on port 443, for networks
+// that block port 22, keyed by the SSH host they stand in for
+var sshOverHTTPSHosts = map[string]string{
+	"github.com": "ssh.github.com",
+	"gitlab.com": "altssh.gitlab.com",
+}
+
+// SSHOverHTTPSHost returns the host accepting SSH on port 443 in place of
+// hostname, or "" when it has none (e.g. self-hosted GitLab)
+func SSHOverHTTPSHost(hostname string) string {
+	return sshOverHTTPSHosts[strings.ToLower(hostname)]
+}
+
+// UsesSSHOverHTTPS reports whether a platform gets a port 443 fallback host,
+// set on the platform or for all platforms in defaults
+func (c *Config) UsesSSHOverHTTPS(p *Platform) bool {
+	return p.SSHOverHTTPS || c.Defaults.SSHOverHTTPS
+}
+
+// managedSSHOptions are the options of a managed Host block that git-keys
+// writes itself, which ssh_options may not set
+var managedSSHOptions = []string{"Host", "Match", "Include", "HostName", "User", "IdentityFile", "IdentitiesOnly", "IdentityAgent"}
+
+// ExtraSSHOptions returns the options the platform adds to its managed Host
+// block: proxy_jump, proxy_command and ssh_options
+func (p *Platform) ExtraSSHOptions() map[string]string {
+	options := make(map[string]string, len(p.SSHOptions)+2)
+	for key, value := range p.SSHOptions {
+		options[key] = value
+	}
+	if p.ProxyJump != "" {
+		options["ProxyJump"] = p.ProxyJump
+	}
+	if p.ProxyCommand != "" {
+		options["ProxyCommand"] = p.ProxyCommand
+	}
+	return options
+}
+
+// validateSSHOptions checks the SSH options of a platform: each must be one
+// line, a single keyword, and not one git-keys manages
+func (p *Platform) validateSSHOptions() error {
+	if p.ProxyJump != "" && p.ProxyCommand != "" {
+		return fmt.Errorf("proxy_jump and proxy_command cannot both be set")
+	}
+	if strings.ContainsAny(p.ProxyJump, "\r\n") || strings.ContainsAny(p.ProxyCommand, "\r\n") {
+		return fmt.Errorf("proxy_jump and proxy_command must be one line")
+	}
+	for key, value := range p.SSHOptions {
+		if key == "" || strings.ContainsAny(key, " \t=\r\n") {
+			return fmt.Errorf("ssh_options has an invalid option name %q", key)
+		}
+		if strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("ssh_options.%s must be one line", key)
+		}
+		for _, managed := range managedSSHOptions {
+			if strings.EqualFold(key, managed) {
+				return fmt.Errorf("ssh_options cannot set %s, which git-keys writes itself", managed)
+			}
+		}
+		isProxy := strings.EqualFold(key, "ProxyJump") || strings.EqualFold(key, "ProxyCommand")
+		if isProxy && (p.ProxyJump != "" || p.ProxyCommand != "") {
+			return fmt.Errorf("ssh_options.%s conflicts with proxy_jump/proxy_command", key)
+		}
+	}
+	return nil
+}
+
+// sshMatchCriteria are the criteria keywords a Match line may use
+var sshMatchCriteria = []string{"all", "canonical", "final", "exec", "localnetwork", "host", "originalhost", "tagged", "command", "user", "localuser", "address", "localaddress", "localport", "rdomain", "version", "sessiontype"}
+
+// validateSSHHosts checks ssh_host_patterns and ssh_match: each pattern a
+// single word, and each Match one line starting with a known criterion
+func (p *Platform) validateSSHHosts() error {
+	for _, pattern := range p.SSHHostPatterns {
+		if pattern == "" || strings.ContainsAny(pattern, " \t\r\n\",") {
+			return fmt.Errorf("ssh_host_patterns has an invalid pattern %q; list each pattern separately", pattern)
+		}
+	}
+	for _, match := range p.SSHMatch {
+		if strings.ContainsAny(match, "\r\n") {
+			return fmt.Errorf("ssh_match %q must be one line", match)
+		}
+		fields := strings.Fields(match)
+		if len(fields) == 0 {
+			return fmt.Errorf("ssh_match has an empty entry")
+		}
+		if !containsFold(sshMatchCriteria, fields[0]) {
+			return fmt.Errorf("ssh_match %q starts with an unknown criterion %q", match, fields[0])
+		}
+		if strings.Count(match, `"`)%2 != 0 {
+			return fmt.Errorf("ssh_match %q has unbalanced quotes", match)
+		}
+	}
+	return nil
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasIncludeConditions reports whether the platform's identity is applied
+// by an includeIf: for its gitdirs, remote_urls or include_if conditions
+func (p *Platform) HasIncludeConditions() bool {
+	return len(p.AllGitDirs()) > 0 || len(p.RemoteURLs) > 0 || len(p.IncludeIf) > 0
+}
+
+// AllGitDirs returns the platform's directory patterns: gitdir, then
+// gitdirs, without duplicates
+func (p *Platform) AllGitDirs() []string {
+	var dirs []string
+	for _, dir := range append([]string{p.GitDir}, p.GitDirs...) {
+		if dir != "" && !containsFold(dirs, dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// includeIfConditions are the includeIf condition keywords git knows
+var includeIfConditions = []string{"gitdir:", "gitdir/i:", "onbranch:", "hasconfig:remote.*.url:"}
+
+// validateIncludeConditions checks gitdirs and include_if: one line
+// without quotes each, include_if starting with a condition git knows
+func (p *Platform) validateIncludeConditions() error {
+	for _, dir := range p.GitDirs {
+		if dir == "" || strings.ContainsAny(dir, "\"\r\n") {
+			return fmt.Errorf("gitdirs has an invalid pattern %q", dir)
+		}
+	}
+	for _, condition := range p.IncludeIf {
+		if strings.ContainsAny(condition, "\"\r\n") {
+			return fmt.Errorf("include_if %q must be one line without quotes", condition)
+		}
+		known := false
+		for _, prefix := range includeIfConditions {
+			if strings.HasPrefix(condition, prefix) && len(condition) > len(prefix) {
+				known = true
+			}
+		}
+		if !known {
+			return fmt.Errorf("include_if %q must start with %s", condition, strings.Join(includeIfConditions, ", "))
+		}
+	}
+	return nil
+}
+
+// GetCACertPath returns ca_cert_path with ~/ expanded
+func (p *Platform) GetCACertPath() string {
+	if strings.HasPrefix(p.CACertPath, "~/") {
+		home, _ := os.UserHomeDir()
+		return filepath.Join(home, p.CACertPath[2:])
+	}
+	return p.CACertPath
 }
 
 // PlatformType is the type of git hosting platform
@@ -47,21 +323,76 @@ const (
 
 // KeyConfig represents a managed SSH key
 type KeyConfig struct {
-	Type        KeyType   `yaml:"type"` // "ed25519" or "rsa"
-	CreatedAt   time.Time `yaml:"created_at"`
-	ExpiresAt   time.Time `yaml:"expires_at"`
+	Type        KeyType    `yaml:"type"` // "ed25519" or "rsa"
+	CreatedAt   time.Time  `yaml:"created_at"`
+	ExpiresAt   time.Time  `yaml:"expires_at"`
+	Fingerprint string     `yaml:"fingerprint"`
+	LocalPath   string     `yaml:"local_path"`          // Path to private key
+	RemoteID    string     `yaml:"remote_id,omitempty"` // Platform's key ID
+	Status      KeyStatus  `yaml:"status"`
+	Agent       bool       `yaml:"agent,omitempty"`       // Private key lives in an external agent; LocalPath is the public key
+	PublicOnly  bool       `yaml:"public_only,omitempty"` // Private key lives elsewhere (HSM, another machine); LocalPath is the public key
+	Purpose     KeyPurpose `yaml:"purpose,omitempty"`     // "" means auth
+	Machine     string     `yaml:"machine,omitempty"`     // ID of the machine with the private key; set when saved
+}
+
+// SigningFormat is how a persona's commits are signed
+type SigningFormat string
+
+const (
+	SigningFormatSSH SigningFormat = "ssh"
+	SigningFormatGPG SigningFormat = "gpg"
+)
+
+// SwitchStrategy is how the git config of a persona's gitdir makes git
+// connect with the persona's key
+type SwitchStrategy string
+
+const (
+	SwitchURLRewrite SwitchStrategy = "url-rewrite" // url.insteadOf rewrites platform URLs to the SSH host alias (default)
+	SwitchSSHCommand SwitchStrategy = "ssh-command" // core.sshCommand offers only the persona's key; URLs are left alone
+	SwitchHostAlias  SwitchStrategy = "host-alias"  // Neither; remotes use the SSH host alias themselves
+)
+
+// validSwitchStrategy reports whether s is a known strategy, or empty
+func validSwitchStrategy(s SwitchStrategy) bool {
+	return s == "" || s == SwitchURLRewrite || s == SwitchSSHCommand || s == SwitchHostAlias
+}
+
+// GetSwitchStrategy returns the persona's switch_strategy, falling back to
+// defaults.switch_strategy and then url-rewrite
+func (c *Config) GetSwitchStrategy(p *Persona) SwitchStrategy {
+	if p.SwitchStrategy != "" {
+		return p.SwitchStrategy
+	}
+	if c.Defaults.SwitchStrategy != "" {
+		return c.Defaults.SwitchStrategy
+	}
+	return SwitchURLRewrite
+}
+
+// GPGKey is a persona's GPG signing key, kept in the GnuPG keyring
+type GPGKey struct {
 	Fingerprint string    `yaml:"fingerprint"`
-	LocalPath   string    `yaml:"local_path"`          // Path to private key
-	RemoteID    string    `yaml:"remote_id,omitempty"` // Platform's key ID
-	Status      KeyStatus `yaml:"status"`
+	CreatedAt   time.Time `yaml:"created_at,omitempty"`
+	Generated   bool      `yaml:"generated,omitempty"` // Generated by git-keys rather than an existing key
 }
 
+// KeyPurpose is what a key is used for
+type KeyPurpose string
+
+const (
+	KeyPurposeAuth    KeyPurpose = "auth"    // SSH authentication
+	KeyPurposeSigning KeyPurpose = "signing" // Commit signing (gpg.format=ssh)
+)
+
 // KeyType represents the SSH key algorithm
 type KeyType string
 
 const (
 	KeyTypeED25519 KeyType = "ed25519"
 	KeyTypeRSA     KeyType = "rsa"
+	KeyTypeECDSA   KeyType = "ecdsa" // Secure Enclave keys; not generated by git-keys
 )
 
 // KeyStatus represents the state of a key
@@ -76,10 +407,203 @@ const (
 
 // Defaults represents default configuration values
 type Defaults struct {
-	KeyType       KeyType       `yaml:"key_type,omitempty"`
-	KeyExpiration time.Duration `yaml:"key_expiration,omitempty"`
-	AutoRotate    bool          `yaml:"auto_rotate,omitempty"`
-	SSHConfigPath string        `yaml:"ssh_config_path,omitempty"`
+	KeyType        KeyType       `yaml:"key_type,omitempty"`
+	KeyExpiration  time.Duration `yaml:"key_expiration,omitempty"`
+	AutoRotate     bool          `yaml:"auto_rotate,omitempty"`
+	RotateWithin   time.Duration `yaml:"rotate_within,omitempty"`   // Keys expiring this soon are due for 'rotate --due' (default 14 days)
+	UnusedKeyDays  int           `yaml:"unused_key_days,omitempty"` // GitLab keys unused this long are prune candidates (default 90)
+	SSHConfigPath  string        `yaml:"ssh_config_path,omitempty"`
+	SSHInclude     string        `yaml:"ssh_include,omitempty"`      // Keep managed hosts in this file, included from ssh_config_path (e.g. git-keys.conf)
+	KeysDir        string        `yaml:"keys_dir,omitempty"`         // Directory for managed keys (default ~/.ssh)
+	TrashRetention time.Duration `yaml:"trash_retention,omitempty"`  // How long deleted keys stay in the trash
+	APIRetries     int           `yaml:"api_retries,omitempty"`      // Retries for transient API failures (default 3, -1 disables)
+	APITimeout     time.Duration `yaml:"api_timeout,omitempty"`      // Timeout per API request attempt (default 30s)
+	PlainOutput    bool          `yaml:"plain_output,omitempty"`     // Headers without emoji or underlines
+	OutputWidth    int           `yaml:"output_width,omitempty"`     // Wrap width (default: terminal width)
+	SafeApply      bool          `yaml:"safe_apply,omitempty"`       // Always run apply in --safe mode
+	Strict         bool          `yaml:"strict,omitempty"`           // Treat warnings as errors in validate, plan and apply; reject unknown keys
+	VerifyApply    bool          `yaml:"verify_apply,omitempty"`     // Always run apply with --verify
+	RemoteCacheTTL time.Duration `yaml:"remote_cache_ttl,omitempty"` // How long remote key listings are cached (default 15m, negative disables)
+	PolicyFile     string        `yaml:"policy_file,omitempty"`      // Organization policy enforced by validate, plan and apply (default ~/.git-keys/policy.yaml)
+	LogFormat      string        `yaml:"log_format,omitempty"`       // Log line format: text (default) or json
+	LogFile        string        `yaml:"log_file,omitempty"`         // Also log to this file, e.g. ~/.git-keys/logs/git-keys.log
+	LogMaxSize     int           `yaml:"log_max_size,omitempty"`     // Megabytes before the log file is rotated (default 10)
+	LogMaxFiles    int           `yaml:"log_max_files,omitempty"`    // Rotated log files kept (default 5)
+	AgentLifetime  time.Duration `yaml:"agent_lifetime,omitempty"`   // Keys git-keys adds to the SSH agent are dropped after this long (ssh-add -t)
+	AgentConfirm   bool          `yaml:"agent_confirm,omitempty"`    // Keys git-keys adds to the SSH agent ask before each use (ssh-add -c)
+	SSHOverHTTPS   bool          `yaml:"ssh_over_https,omitempty"`   // Write port 443 fallback hosts for every platform that has one
+	KnownHostsFile string        `yaml:"known_hosts_file,omitempty"` // File apply writes host keys to (default ~/.ssh/known_hosts); others are referenced with UserKnownHostsFile
+
+	// apply writes published and pinned host keys to known_hosts unless disabled
+	DisableKnownHosts bool `yaml:"disable_known_hosts,omitempty"`
+
+	// How personas' git config makes git use their keys: url-rewrite
+	// (default), ssh-command or host-alias
+	SwitchStrategy SwitchStrategy `yaml:"switch_strategy,omitempty"`
+
+	// Escrow export of public keys for team admins (opt-in)
+	EscrowEnabled    bool   `yaml:"escrow_enabled,omitempty"`
+	EscrowSigningKey string `yaml:"escrow_signing_key,omitempty"` // SSH private key that signs manifests
+
+	// Naming templates (Go text/template). Fields: .Platform, .Account,
+	// .Persona, .Machine, .Type, .Date. Empty means the built-in format.
+	KeyNameTemplate    string `yaml:"key_name_template,omitempty"`
+	KeyCommentTemplate string `yaml:"key_comment_template,omitempty"`
+	KeyTitleTemplate   string `yaml:"key_title_template,omitempty"`
+
+	// Remote key titles end with [gk m=<machine> exp=<date>] so audits on
+	// any machine can tell where a key lives and when it expires
+	DisableTitleMetadata bool `yaml:"disable_title_metadata,omitempty"`
+}
+
+// GetKeysDir returns the directory for managed keys with ~/ expanded.
+// Defaults to ~/.ssh when keys_dir is not set, or ~/.ssh/git-keys-<profile>
+// for a profile other than the default so profiles never share key files.
+func (d *Defaults) GetKeysDir() string {
+	home, _ := os.UserHomeDir()
+	switch {
+	case d.KeysDir == "" && !profile.IsDefault():
+		return filepath.Join(home, ".ssh", profile.Qualify("git-keys"))
+	case d.KeysDir == "":
+		return filepath.Join(home, ".ssh")
+	case strings.HasPrefix(d.KeysDir, "~/"):
+		return filepath.Join(home, d.KeysDir[2:])
+	default:
+		return d.KeysDir
+	}
+}
+
+// DefaultKnownHostsFile is the known_hosts file ssh reads by default
+const DefaultKnownHostsFile = "~/.ssh/known_hosts"
+
+// GetKnownHostsFile returns the file apply writes host keys to with ~/
+// expanded, ~/.ssh/known_hosts unless known_hosts_file is set
+func (d *Defaults) GetKnownHostsFile() string {
+	path := d.KnownHostsFile
+	if path == "" {
+		path = DefaultKnownHostsFile
+	}
+	if strings.HasPrefix(path, "~/") {
+		home, _ := os.UserHomeDir()
+		return filepath.Join(home, path[2:])
+	}
+	return path
+}
+
+// DefaultKeyExpiration is used when no key_expiration is configured
+const DefaultKeyExpiration = 180 * 24 * time.Hour // ~6 months
+
+// KeySettings holds the key settings that apply to one platform after
+// resolving platform, persona and default values
+type KeySettings struct {
+	Type       KeyType
+	Expiration time.Duration
+
+	// Naming templates; empty means the built-in format
+	FileNameTemplate string
+	CommentTemplate  string
+	TitleTemplate    string
+
+	// TitleMetadata appends the machine and expiry to remote key titles
+	TitleMetadata bool
+}
+
+// ExpiresAt returns the expiry time for a key created at createdAt
+func (s KeySettings) ExpiresAt(createdAt time.Time) time.Time {
+	return createdAt.Add(s.Expiration)
+}
+
+// ResolveKeySettings returns the most specific key settings for a platform:
+// platform values win over persona values, which win over defaults.
+func (c *Config) ResolveKeySettings(persona *Persona, platform *Platform) KeySettings {
+	settings := KeySettings{
+		Type:             c.Defaults.KeyType,
+		Expiration:       c.Defaults.KeyExpiration,
+		FileNameTemplate: c.Defaults.KeyNameTemplate,
+		CommentTemplate:  c.Defaults.KeyCommentTemplate,
+		TitleTemplate:    c.Defaults.KeyTitleTemplate,
+		TitleMetadata:    !c.Defaults.DisableTitleMetadata,
+	}
+
+	if persona != nil {
+		if persona.KeyType != "" {
+			settings.Type = persona.KeyType
+		}
+		if persona.KeyExpiration > 0 {
+			settings.Expiration = persona.KeyExpiration
+		}
+		if persona.KeyName != "" {
+			settings.FileNameTemplate = persona.KeyName
+		}
+	}
+
+	if platform != nil {
+		if platform.KeyType != "" {
+			settings.Type = platform.KeyType
+		}
+		if platform.KeyExpiration > 0 {
+			settings.Expiration = platform.KeyExpiration
+		}
+		if platform.KeyName != "" {
+			settings.FileNameTemplate = platform.KeyName
+		}
+	}
+
+	if settings.Type == "" {
+		settings.Type = KeyTypeED25519
+	}
+	if settings.Expiration <= 0 {
+		settings.Expiration = DefaultKeyExpiration
+	}
+
+	return settings
+}
+
+// KeyExpiry returns when a key expires: its expires_at, or its creation time
+// plus the key_expiration that applies to the platform. known is false when
+// the key has neither date.
+func (c *Config) KeyExpiry(persona *Persona, platform *Platform, key *KeyConfig) (expiresAt time.Time, known bool) {
+	if !key.ExpiresAt.IsZero() {
+		return key.ExpiresAt, true
+	}
+	if key.CreatedAt.IsZero() {
+		return time.Time{}, false
+	}
+	return c.ResolveKeySettings(persona, platform).ExpiresAt(key.CreatedAt), true
+}
+
+// ExpiredKey is a key MarkExpiredKeys changed to expired
+type ExpiredKey struct {
+	Persona   *Persona
+	Platform  *Platform
+	Key       *KeyConfig
+	ExpiresAt time.Time
+}
+
+// MarkExpiredKeys sets active keys whose expiry is before now to expired
+// and returns them. Keys of archived personas are left alone.
+func (c *Config) MarkExpiredKeys(now time.Time) []ExpiredKey {
+	var expired []ExpiredKey
+	for personaIdx := range c.Personas {
+		persona := &c.Personas[personaIdx]
+		if persona.Archived {
+			continue
+		}
+		for platformIdx := range persona.Platforms {
+			platform := &persona.Platforms[platformIdx]
+			for keyIdx := range platform.Keys {
+				key := &platform.Keys[keyIdx]
+				if key.Status != KeyStatusActive {
+					continue
+				}
+				if expiresAt, known := c.KeyExpiry(persona, platform, key); known && expiresAt.Before(now) {
+					key.Status = KeyStatusExpired
+					expired = append(expired, ExpiredKey{Persona: persona, Platform: platform, Key: key, ExpiresAt: expiresAt})
+				}
+			}
+		}
+	}
+	return expired
 }
 
 // Validate validates the configuration
@@ -87,13 +611,37 @@ func (c *Config) Validate() error {
 	if c.Version == "" {
 		return fmt.Errorf("version is required")
 	}
-	if c.Machine.ID == "" {
-		return fmt.Errorf("machine.id is required")
+	if len(c.Machines) == 0 {
+		return fmt.Errorf("at least one machine is required")
+	}
+	machineIDs := make(map[string]bool)
+	for i, machine := range c.Machines {
+		if machine.ID == "" {
+			return fmt.Errorf("machines[%d].id is required", i)
+		}
+		if machineIDs[machine.ID] {
+			return fmt.Errorf("machines[%d].id %s is listed twice", i, machine.ID)
+		}
+		machineIDs[machine.ID] = true
 	}
 	if len(c.Personas) == 0 {
 		return fmt.Errorf("at least one persona is required")
 	}
 
+	templates := map[string]string{
+		"defaults.key_name_template":    c.Defaults.KeyNameTemplate,
+		"defaults.key_comment_template": c.Defaults.KeyCommentTemplate,
+		"defaults.key_title_template":   c.Defaults.KeyTitleTemplate,
+	}
+	for field, tmpl := range templates {
+		if _, err := template.New(field).Parse(tmpl); err != nil {
+			return fmt.Errorf("%s is not a valid template: %w", field, err)
+		}
+	}
+	if !validSwitchStrategy(c.Defaults.SwitchStrategy) {
+		return fmt.Errorf("defaults.switch_strategy must be url-rewrite, ssh-command or host-alias")
+	}
+
 	for i, persona := range c.Personas {
 		if persona.Name == "" {
 			return fmt.Errorf("persona[%d].name is required", i)
@@ -104,11 +652,89 @@ func (c *Config) Validate() error {
 		if len(persona.Platforms) == 0 {
 			return fmt.Errorf("persona[%d] must have at least one platform", i)
 		}
+		if persona.SigningFormat != "" && persona.SigningFormat != SigningFormatSSH && persona.SigningFormat != SigningFormatGPG {
+			return fmt.Errorf("persona[%d].signing_format must be ssh or gpg", i)
+		}
+		if !validSwitchStrategy(persona.SwitchStrategy) {
+			return fmt.Errorf("persona[%d].switch_strategy must be url-rewrite, ssh-command or host-alias", i)
+		}
+		if persona.GPGKey != nil && persona.GPGKey.Fingerprint == "" {
+			return fmt.Errorf("persona[%d].gpg_key.fingerprint is required", i)
+		}
+		if !isGeneratableKeyType(persona.KeyType) {
+			return fmt.Errorf("persona[%d].key_type must be ed25519 or rsa", i)
+		}
+		if _, err := template.New("key_name").Parse(persona.KeyName); err != nil {
+			return fmt.Errorf("persona[%d].key_name is not a valid template: %w", i, err)
+		}
+		for j, platform := range persona.Platforms {
+			if !isGeneratableKeyType(platform.KeyType) {
+				return fmt.Errorf("persona[%d].platforms[%d].key_type must be ed25519 or rsa", i, j)
+			}
+			if _, err := template.New("key_name").Parse(platform.KeyName); err != nil {
+				return fmt.Errorf("persona[%d].platforms[%d].key_name is not a valid template: %w", i, j, err)
+			}
+			for k, key := range platform.Keys {
+				if key.Purpose != "" && key.Purpose != KeyPurposeAuth {
+					return fmt.Errorf("persona[%d].platforms[%d].keys[%d].purpose must be auth; signing keys belong in signing_keys", i, j, k)
+				}
+			}
+			for k, key := range platform.SigningKeys {
+				if key.Purpose != KeyPurposeSigning {
+					return fmt.Errorf("persona[%d].platforms[%d].signing_keys[%d].purpose must be signing", i, j, k)
+				}
+			}
+			if platform.Proxy != "" {
+				if u, err := url.Parse(platform.Proxy); err != nil || u.Scheme == "" || u.Host == "" {
+					return fmt.Errorf("persona[%d].platforms[%d].proxy must be a URL such as http://proxy:3128", i, j)
+				}
+			}
+			if platform.SSHOverHTTPS && platform.Type == PlatformGitLab && platform.BaseURL != "" && platform.BaseURL != "https://gitlab.com" {
+				return fmt.Errorf("persona[%d].platforms[%d].ssh_over_https is only available for github.com and gitlab.com", i, j)
+			}
+			if err := platform.validateSSHOptions(); err != nil {
+				return fmt.Errorf("persona[%d].platforms[%d]: %w", i, j, err)
+			}
+			if err := platform.validateSSHHosts(); err != nil {
+				return fmt.Errorf("persona[%d].platforms[%d]: %w", i, j, err)
+			}
+			if err := platform.validateIncludeConditions(); err != nil {
+				return fmt.Errorf("persona[%d].platforms[%d]: %w", i, j, err)
+			}
+			for k, pattern := range platform.RemoteURLs {
+				if pattern == "" || strings.ContainsAny(pattern, "\"\r\n") {
+					return fmt.Errorf("persona[%d].platforms[%d].remote_urls[%d] must be a URL pattern without quotes, e.g. git@github.com:acme/**", i, j, k)
+				}
+			}
+			for k, hostKey := range platform.HostKeys {
+				if _, _, _, _, err := ssh.ParseAuthorizedKey([]byte(hostKey)); err != nil {
+					return fmt.Errorf("persona[%d].platforms[%d].host_keys[%d] must be a public key such as \"ssh-ed25519 AAAA...\": %w", i, j, k, err)
+				}
+			}
+		}
+	}
+
+	for i := range c.DeployKeys {
+		d := &c.DeployKeys[i]
+		if d.Repo == "" || !strings.Contains(d.Repo, "/") {
+			return fmt.Errorf("deploy_keys[%d].repo must be owner/name", i)
+		}
+		if d.Platform != PlatformGitHub && d.Platform != PlatformGitLab {
+			return fmt.Errorf("deploy_keys[%d].platform must be github or gitlab", i)
+		}
+		if _, _, err := c.DeployKeyPlatform(d); err != nil {
+			return fmt.Errorf("deploy_keys[%d]: %w", i, err)
+		}
 	}
 
 	return nil
 }
 
+// isGeneratableKeyType reports whether t is empty or a key type git-keys can generate
+func isGeneratableKeyType(t KeyType) bool {
+	return t == "" || t == KeyTypeED25519 || t == KeyTypeRSA
+}
+
 // FindPersona finds a persona by name
 func (c *Config) FindPersona(name string) *Persona {
 	for i := range c.Personas {
@@ -119,6 +745,25 @@ func (c *Config) FindPersona(name string) *Persona {
 	return nil
 }
 
+// FindRepoBinding finds the persona platform whose repos contain dir. A
+// binding takes precedence over gitdir, so a directory that mixes repositories
+// of several personas can be mapped repository by repository.
+func (c *Config) FindRepoBinding(dir string) (*Persona, *Platform, string) {
+	for i := range c.Personas {
+		persona := &c.Personas[i]
+		for j := range persona.Platforms {
+			plat := &persona.Platforms[j]
+			for _, repo := range plat.Repos {
+				rel, err := filepath.Rel(repo, dir)
+				if err == nil && rel != ".." && !strings.HasPrefix(rel, "../") {
+					return persona, plat, repo
+				}
+			}
+		}
+	}
+	return nil, nil, ""
+}
+
 // FindPlatform finds a platform within a persona
 func (p *Persona) FindPlatform(platformType PlatformType, account string) *Platform {
 	for i := range p.Platforms {
@@ -136,9 +781,107 @@ func (p *Platform) GetActiveKey() *KeyConfig {
 			return &p.Keys[i]
 		}
 	}
+	// An expired key stays in use until it is rotated
+	for i := range p.Keys {
+		if p.Keys[i].Status == KeyStatusExpired {
+			return &p.Keys[i]
+		}
+	}
+	return nil
+}
+
+// GetSigningKey returns the persona's signing key registered on this platform
+func (p *Platform) GetSigningKey() *KeyConfig {
+	for i := range p.SigningKeys {
+		if p.SigningKeys[i].InUse() {
+			return &p.SigningKeys[i]
+		}
+	}
 	return nil
 }
 
+// SignsWithSSH reports whether the persona signs commits with an SSH key
+func (p *Persona) SignsWithSSH() bool {
+	return p.SignCommits && (p.SigningFormat == "" || p.SigningFormat == SigningFormatSSH)
+}
+
+// SignsWithGPG reports whether the persona signs commits with a GPG key
+func (p *Persona) SignsWithGPG() bool {
+	return p.SignCommits && p.SigningFormat == SigningFormatGPG
+}
+
+// GetSigningKey returns the persona's signing key, as recorded on any of its
+// platforms
+func (p *Persona) GetSigningKey() *KeyConfig {
+	for i := range p.Platforms {
+		if key := p.Platforms[i].GetSigningKey(); key != nil {
+			return key
+		}
+	}
+	return nil
+}
+
+// GetActiveKey returns the deploy key currently in use
+func (d *DeployKey) GetActiveKey() *KeyConfig {
+	for i := range d.Keys {
+		if d.Keys[i].InUse() {
+			return &d.Keys[i]
+		}
+	}
+	return nil
+}
+
+// FindDeployKey finds a deploy key by repository, and platform when given
+func (c *Config) FindDeployKey(repo string, platform PlatformType) *DeployKey {
+	for i := range c.DeployKeys {
+		if strings.EqualFold(c.DeployKeys[i].Repo, repo) && (platform == "" || c.DeployKeys[i].Platform == platform) {
+			return &c.DeployKeys[i]
+		}
+	}
+	return nil
+}
+
+// DeployKeyPlatform returns the persona platform whose token manages a
+// deploy key
+func (c *Config) DeployKeyPlatform(d *DeployKey) (*Persona, *Platform, error) {
+	persona := c.FindPersona(d.Persona)
+	if persona == nil {
+		return nil, nil, fmt.Errorf("persona '%s' not found", d.Persona)
+	}
+
+	var found *Platform
+	for i := range persona.Platforms {
+		plat := &persona.Platforms[i]
+		if plat.Type != d.Platform || (d.Account != "" && !strings.EqualFold(plat.Account, d.Account)) {
+			continue
+		}
+		if found != nil {
+			return nil, nil, fmt.Errorf("persona '%s' has several %s accounts; set account for deploy key %s", d.Persona, d.Platform, d.Repo)
+		}
+		found = plat
+	}
+	if found == nil {
+		return nil, nil, fmt.Errorf("persona '%s' has no %s platform for deploy key %s", d.Persona, d.Platform, d.Repo)
+	}
+	return persona, found, nil
+}
+
+// InUse reports whether the key is the platform's current key: active, or
+// expired and not rotated yet
+func (k *KeyConfig) InUse() bool {
+	return k.Status == KeyStatusActive || k.Status == KeyStatusExpired
+}
+
+// HasPrivateKey reports whether this machine holds the key's private key file
+func (k *KeyConfig) HasPrivateKey() bool {
+	return !k.Agent && !k.PublicOnly
+}
+
+// UsesExternalAgent reports whether this platform's keys live in an external agent
+func (p *Platform) UsesExternalAgent() bool {
+	return p.IdentityAgent != ""
+}
+
 // GetExpiredKeys returns all expired keys
 func (p *Platform) GetExpiredKeys() []KeyConfig {
 	var expired []KeyConfig
@@ -0,0 +1,124 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SchemaID identifies the JSON Schema of the configuration file
+const SchemaID = "https://github.com/kunlu/git-keys/schema/git-keys.schema.json"
+
+// durationPattern matches the durations yaml accepts for time.Duration fields
+// (Go duration strings such as 720h or 1h30m)
+const durationPattern = `^-?([0-9]+(\.[0-9]*)?(ns|us|µs|ms|s|m|h))+$|^0$`
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	timeType     = reflect.TypeOf(time.Time{})
+)
+
+// enumValues lists the allowed values of the string types with a fixed set
+// of values
+var enumValues = map[reflect.Type][]string{
+	reflect.TypeOf(PlatformType("")):  {string(PlatformGitHub), string(PlatformGitLab)},
+	reflect.TypeOf(KeyType("")):       {string(KeyTypeED25519), string(KeyTypeRSA), string(KeyTypeECDSA)},
+	reflect.TypeOf(KeyStatus("")):     {string(KeyStatusActive), string(KeyStatusExpired), string(KeyStatusRevoked), string(KeyStatusPending)},
+	reflect.TypeOf(KeyPurpose("")):    {string(KeyPurposeAuth), string(KeyPurposeSigning)},
+	reflect.TypeOf(SigningFormat("")): {string(SigningFormatSSH), string(SigningFormatGPG)},
+}
+
+// Schema returns a JSON Schema (draft 2020-12) for the configuration file,
+// derived from the yaml tags of Config. Unknown keys are not allowed.
+func Schema() map[string]interface{} {
+	schema := schemaFor(reflect.TypeOf(Config{}))
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["$id"] = SchemaID
+	schema["title"] = "git-keys configuration"
+	schema["required"] = []string{"version", "machines", "personas"}
+	return schema
+}
+
+// schemaFor returns the schema of a Go type as yaml encodes it
+func schemaFor(t reflect.Type) map[string]interface{} {
+	if values, ok := enumValues[t]; ok {
+		return map[string]interface{}{"type": "string", "enum": values}
+	}
+
+	switch t {
+	case durationType:
+		return map[string]interface{}{"type": "string", "pattern": durationPattern}
+	case timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return schemaFor(t.Elem())
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": schemaFor(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": schemaFor(t.Elem())}
+	case reflect.Struct:
+		properties := make(map[string]interface{})
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+			if !field.IsExported() || name == "-" {
+				continue
+			}
+			if name == "" {
+				name = strings.ToLower(field.Name)
+			}
+			properties[name] = schemaFor(field.Type)
+		}
+		return map[string]interface{}{
+			"type":                 "object",
+			"properties":           properties,
+			"additionalProperties": false,
+		}
+	}
+	return map[string]interface{}{}
+}
+
+// UnknownFields returns the keys in a configuration file that no field
+// reads, e.g. misspelled keys that would otherwise be silently dropped. Each
+// entry names the line and the key.
+func UnknownFields(data []byte) ([]string, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+
+	var cfg Config
+	err := dec.Decode(&cfg)
+	var typeErr *yaml.TypeError
+	switch {
+	case err == nil, errors.Is(err, io.EOF):
+		return nil, nil
+	case errors.As(err, &typeErr):
+		var unknown []string
+		for _, msg := range typeErr.Errors {
+			if strings.Contains(msg, "not found in type") {
+				msg = strings.Replace(msg, "field ", "unknown key ", 1)
+				unknown = append(unknown, strings.Replace(msg, " not found in type ", " in ", 1))
+			}
+		}
+		return unknown, nil
+	default:
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+}
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kunlu/git-keys/internal/logger"
+	"gopkg.in/yaml.v3"
+)
+
+// FromTemplate parses a configuration template, such as a configuration a
+// team hands to new hires, for a new machine. Machine-specific state (the
+// machines, keys and bound repositories) is dropped and the version is set
+// to ConfigVersion. Accounts and emails may be left empty in a template; the
+// caller fills them in before the configuration is saved.
+func FromTemplate(data []byte) (*Config, error) {
+	migrated, _, err := migrate(data)
+	if err != nil {
+		return nil, err
+	}
+	if migrated != nil {
+		data = migrated
+	}
+
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+	if len(cfg.Personas) == 0 {
+		return nil, fmt.Errorf("template has no personas")
+	}
+	if unknown, err := UnknownFields(data); err == nil && len(unknown) > 0 {
+		logger.Warn("Template has unknown keys, which are ignored: %s", strings.Join(unknown, "; "))
+	}
+
+	cfg.Version = ConfigVersion
+	cfg.Machines = nil
+	for i := range cfg.DeployKeys {
+		cfg.DeployKeys[i].Keys = nil
+	}
+	for i := range cfg.Personas {
+		persona := &cfg.Personas[i]
+		persona.GPGKey = nil
+		persona.PreviousNames = nil
+		persona.Archived = false
+		for j := range persona.Platforms {
+			plat := &persona.Platforms[j]
+			plat.Keys = nil
+			plat.SigningKeys = nil
+			plat.GPGKeyID = ""
+			plat.Repos = nil
+		}
+	}
+	return &cfg, nil
+}
@@ -0,0 +1,235 @@
+package escrow
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/kunlu/git-keys/internal/config"
+	"github.com/kunlu/git-keys/internal/sshkey"
+)
+
+// ManifestVersion is the format version written to new manifests
+const ManifestVersion = 1
+
+// Namespace is the ssh-keygen signature namespace for escrow manifests, so a
+// manifest signature cannot be reused as a git commit or file signature
+const Namespace = "git-keys-escrow"
+
+// Manifest lists the public keys of a machine for escrow. It never contains
+// private key material.
+type Manifest struct {
+	Version     int       `json:"version"`
+	GeneratedAt time.Time `json:"generated_at"`
+	Machine     Machine   `json:"machine"`
+	Signer      string    `json:"signer,omitempty"` // Fingerprint of the signing key
+	Keys        []Entry   `json:"keys"`
+}
+
+// Machine identifies the machine the manifest was exported from
+type Machine struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	OS   string `json:"os"`
+}
+
+// Entry is one public key and the persona/platform it belongs to
+type Entry struct {
+	Persona     string    `json:"persona"`
+	Email       string    `json:"email"`
+	Platform    string    `json:"platform"`
+	Account     string    `json:"account"`
+	BaseURL     string    `json:"base_url,omitempty"`
+	KeyType     string    `json:"key_type"`
+	Fingerprint string    `json:"fingerprint"`
+	PublicKey   string    `json:"public_key"`
+	Status      string    `json:"status"`
+	RemoteID    string    `json:"remote_id,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	ExpiresAt   time.Time `json:"expires_at,omitempty"`
+}
+
+// BuildManifest collects the public keys of all non-archived personas.
+// Keys whose public key cannot be read are returned as warnings.
+func BuildManifest(cfg *config.Config, keyMgr *sshkey.Manager, includeRevoked bool) (*Manifest, []string) {
+	manifest := &Manifest{
+		Version:     ManifestVersion,
+		GeneratedAt: time.Now().UTC(),
+		Machine: Machine{
+			ID:   cfg.Machine.ID,
+			Name: cfg.Machine.Name,
+			OS:   cfg.Machine.OS,
+		},
+	}
+	var warnings []string
+
+	for _, persona := range cfg.Personas {
+		if persona.Archived {
+			continue
+		}
+		for _, plat := range persona.Platforms {
+			for _, key := range plat.Keys {
+				if key.LocalPath == "" {
+					continue
+				}
+				if key.Status == config.KeyStatusRevoked && !includeRevoked {
+					continue
+				}
+
+				publicKey, err := keyMgr.GetPublicKey(key.LocalPath)
+				if err != nil {
+					warnings = append(warnings, fmt.Sprintf("%s/%s@%s: %v", persona.Name, plat.Type, plat.Account, err))
+					continue
+				}
+
+				fingerprint := key.Fingerprint
+				if info, err := sshkey.ParsePublicKey(publicKey); err == nil {
+					fingerprint = info.Fingerprint
+				}
+
+				manifest.Keys = append(manifest.Keys, Entry{
+					Persona:     persona.Name,
+					Email:       persona.Email,
+					Platform:    string(plat.Type),
+					Account:     plat.Account,
+					BaseURL:     plat.BaseURL,
+					KeyType:     string(key.Type),
+					Fingerprint: fingerprint,
+					PublicKey:   publicKey,
+					Status:      string(key.Status),
+					RemoteID:    key.RemoteID,
+					CreatedAt:   key.CreatedAt,
+					ExpiresAt:   key.ExpiresAt,
+				})
+			}
+		}
+	}
+
+	return manifest, warnings
+}
+
+// Marshal encodes the manifest as indented JSON
+func (m *Manifest) Marshal() ([]byte, error) {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	return append(data, '\n'), nil
+}
+
+// ParseManifest decodes a manifest and checks that every entry holds a valid
+// public key matching its fingerprint
+func ParseManifest(data []byte) (*Manifest, error) {
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	if manifest.Version == 0 || manifest.Version > ManifestVersion {
+		return nil, fmt.Errorf("unsupported manifest version %d", manifest.Version)
+	}
+
+	for i, entry := range manifest.Keys {
+		label := fmt.Sprintf("entry %d (%s/%s@%s)", i+1, entry.Persona, entry.Platform, entry.Account)
+		if strings.Contains(entry.PublicKey, "PRIVATE KEY") {
+			return nil, fmt.Errorf("%s contains private key material", label)
+		}
+		info, err := sshkey.ParsePublicKey(entry.PublicKey)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", label, err)
+		}
+		if info.Fingerprint != entry.Fingerprint {
+			return nil, fmt.Errorf("%s: fingerprint %s does not match public key (%s)", label, entry.Fingerprint, info.Fingerprint)
+		}
+	}
+
+	return &manifest, nil
+}
+
+// SignatureFingerprint returns the fingerprint of the public half of a
+// signing key
+func SignatureFingerprint(signingKey string) (string, error) {
+	pubPath := signingKey
+	if !strings.HasSuffix(pubPath, ".pub") {
+		pubPath += ".pub"
+	}
+	data, err := os.ReadFile(pubPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read signing public key: %w", err)
+	}
+	info, err := sshkey.ParsePublicKey(string(data))
+	if err != nil {
+		return "", fmt.Errorf("invalid signing public key %s: %w", pubPath, err)
+	}
+	return info.Fingerprint, nil
+}
+
+// Sign signs manifestPath with an SSH key using ssh-keygen, writing the
+// signature to manifestPath + ".sig"
+func Sign(manifestPath, signingKey string) (string, error) {
+	sigPath := manifestPath + ".sig"
+
+	// ssh-keygen refuses to overwrite an existing signature
+	if err := os.Remove(sigPath); err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("failed to remove old signature: %w", err)
+	}
+
+	cmd := exec.Command("ssh-keygen", "-Y", "sign", "-f", signingKey, "-n", Namespace, manifestPath)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("failed to sign manifest: %w\nOutput: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	return sigPath, nil
+}
+
+// Verify checks a manifest signature. The signer must be listed for
+// principal in allowedSigners (ssh-keygen allowed_signers format).
+func Verify(manifestPath, sigPath, allowedSigners, principal string) error {
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	cmd := exec.Command("ssh-keygen", "-Y", "verify",
+		"-f", allowedSigners,
+		"-I", principal,
+		"-n", Namespace,
+		"-s", sigPath)
+	cmd.Stdin = bytes.NewReader(data)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("ssh-keygen -Y verify: %s", strings.TrimSpace(string(output)))
+	}
+
+	return nil
+}
+
+// WriteAllowedSigners writes a temporary allowed_signers file trusting a
+// single public key for principal. The caller removes the returned file.
+func WriteAllowedSigners(publicKeyPath, principal string) (string, error) {
+	data, err := os.ReadFile(publicKeyPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read signer public key: %w", err)
+	}
+	info, err := sshkey.ParsePublicKey(string(data))
+	if err != nil {
+		return "", fmt.Errorf("invalid signer public key %s: %w", publicKeyPath, err)
+	}
+	fields := strings.Fields(string(data))
+
+	file, err := os.CreateTemp("", "git-keys-allowed-signers-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create allowed signers file: %w", err)
+	}
+	defer file.Close()
+
+	line := fmt.Sprintf("%s namespaces=\"%s\" %s %s\n", principal, Namespace, info.Type, fields[1])
+	if _, err := file.WriteString(line); err != nil {
+		os.Remove(file.Name())
+		return "", fmt.Errorf("failed to write allowed signers file: %w", err)
+	}
+
+	return file.Name(), nil
+}
@@ -0,0 +1,152 @@
+package gpgkey
+
+import (
+	"bufio"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/kunlu/git-keys/internal/logger"
+)
+
+// Key is a secret key in the user's GnuPG keyring
+type Key struct {
+	Fingerprint string
+	UserID      string // e.g. "work <me@company.com>"
+	CreatedAt   time.Time
+}
+
+// Available reports whether gpg is installed
+func Available() bool {
+	_, err := exec.LookPath("gpg")
+	return err == nil
+}
+
+// Generate creates an ed25519 signing key without a passphrase or expiry
+// for name <email> and returns it. Like SSH keys generated by git-keys, the
+// key is protected by the keyring's file permissions only.
+func Generate(name, email string) (*Key, error) {
+	userID := fmt.Sprintf("%s <%s>", name, email)
+	logger.Debug("Generating GPG key for %s", userID)
+
+	cmd := exec.Command("gpg", "--batch", "--pinentry-mode", "loopback", "--passphrase", "",
+		"--quick-gen-key", userID, "ed25519", "sign", "never")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("failed to generate GPG key: %w\nOutput: %s", err, string(output))
+	}
+
+	// The newest secret key with the user ID is the one just generated
+	keys, err := ListSecretKeys(userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("generated GPG key for %s not found in the keyring", userID)
+	}
+	newest := keys[0]
+	for _, key := range keys[1:] {
+		if key.CreatedAt.After(newest.CreatedAt) {
+			newest = key
+		}
+	}
+
+	logger.Info("Generated GPG key %s for %s", newest.Fingerprint, userID)
+	return &newest, nil
+}
+
+// Find returns the secret key with the given fingerprint or key ID, or nil
+// if the keyring has no such secret key
+func Find(id string) (*Key, error) {
+	keys, err := ListSecretKeys(id)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		if strings.HasSuffix(key.Fingerprint, strings.ToUpper(id)) {
+			return &key, nil
+		}
+	}
+	return nil, nil
+}
+
+// ListSecretKeys lists the secret keys matching query (a user ID,
+// fingerprint or key ID)
+func ListSecretKeys(query string) ([]Key, error) {
+	cmd := exec.Command("gpg", "--batch", "--with-colons", "--fixed-list-mode", "--list-secret-keys", query)
+	output, err := cmd.Output()
+	if err != nil {
+		// gpg exits with 2 when nothing matches
+		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 2 {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to list GPG keys: %w", err)
+	}
+	return parseColons(string(output)), nil
+}
+
+// parseColons reads the primary keys from gpg --with-colons output
+func parseColons(output string) []Key {
+	var keys []Key
+	var current *Key
+	inPrimary := false
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), ":")
+		switch fields[0] {
+		case "sec":
+			keys = append(keys, Key{})
+			current = &keys[len(keys)-1]
+			inPrimary = true
+			if len(fields) > 5 {
+				if secs, err := parseUnix(fields[5]); err == nil {
+					current.CreatedAt = secs
+				}
+			}
+		case "ssb":
+			inPrimary = false
+		case "fpr":
+			if current != nil && inPrimary && current.Fingerprint == "" && len(fields) > 9 {
+				current.Fingerprint = fields[9]
+			}
+		case "uid":
+			if current != nil && current.UserID == "" && len(fields) > 9 {
+				current.UserID = fields[9]
+			}
+		}
+	}
+	return keys
+}
+
+// parseUnix parses a Unix timestamp from gpg output
+func parseUnix(s string) (time.Time, error) {
+	var secs int64
+	if _, err := fmt.Sscanf(s, "%d", &secs); err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(secs, 0), nil
+}
+
+// ExportPublicKey returns the ASCII-armored public key for a fingerprint
+func ExportPublicKey(fingerprint string) (string, error) {
+	output, err := exec.Command("gpg", "--batch", "--armor", "--export", fingerprint).Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to export GPG key %s: %w", fingerprint, err)
+	}
+	if len(output) == 0 {
+		return "", fmt.Errorf("GPG key %s not found in the keyring", fingerprint)
+	}
+	return string(output), nil
+}
+
+// Delete removes a secret and public key from the keyring
+func Delete(fingerprint string) error {
+	logger.Debug("Deleting GPG key %s", fingerprint)
+
+	cmd := exec.Command("gpg", "--batch", "--yes", "--delete-secret-and-public-key", fingerprint)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to delete GPG key %s: %w\nOutput: %s", fingerprint, err, string(output))
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/kunlu/git-keys/internal/profile"
+)
+
+const (
+	DefaultHistoryFileName = "history.jsonl"
+)
+
+// Entry represents a single recorded operation
+type Entry struct {
+	Timestamp time.Time         `json:"timestamp"`
+	Action    string            `json:"action"`  // e.g., "machine-rename"
+	Summary   string            `json:"summary"` // Human-readable description
+	Details   map[string]string `json:"details,omitempty"`
+}
+
+// Manager handles the append-only operation history
+type Manager struct {
+	historyPath string
+}
+
+// NewManager creates a new history manager
+func NewManager(historyPath string) *Manager {
+	if historyPath == "" {
+		historyPath = GetDefaultHistoryPath()
+	}
+	return &Manager{historyPath: historyPath}
+}
+
+// GetDefaultHistoryPath returns the default history file path
+func GetDefaultHistoryPath() string {
+	return filepath.Join(profile.Dir(), DefaultHistoryFileName)
+}
+
+// Record appends an entry to the history file
+func (m *Manager) Record(entry Entry) error {
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now()
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal history entry: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(m.historyPath), 0700); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	f, err := os.OpenFile(m.historyPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open history file: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write history entry: %w", err)
+	}
+
+	return nil
+}
+
+// Entries returns all recorded entries, oldest first
+func (m *Manager) Entries() ([]Entry, error) {
+	f, err := os.Open(m.historyPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open history file: %w", err)
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue // Skip corrupt lines rather than failing the whole read
+		}
+		entries = append(entries, entry)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history file: %w", err)
+	}
+
+	return entries, nil
+}
+
+// GetPath returns the history file path
+func (m *Manager) GetPath() string {
+	return m.historyPath
+}
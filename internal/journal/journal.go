@@ -0,0 +1,406 @@
+package journal
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/kunlu/git-keys/internal/atomicfile"
+	"github.com/kunlu/git-keys/internal/profile"
+)
+
+const (
+	DefaultJournalDirName = "history"
+	journalFileName       = "journal.json"
+	filesDirName          = "files"
+	idTimestampFormat     = "20060102-150405.000"
+
+	// maxJournals is how many operations are kept; older ones are removed
+	// when a new operation begins
+	maxJournals = 10
+)
+
+// Step kinds
+const (
+	StepFile         = "file"          // A file about to be written; Backup holds its previous content
+	StepKey          = "key"           // A key pair was generated, or an agent's public key saved
+	StepMove         = "move"          // A key pair was moved from Path to To, e.g. archived
+	StepTrash        = "trash"         // A key pair was moved to the trash as TrashID
+	StepUpload       = "upload"        // A key was uploaded to a platform
+	StepSigningKey   = "signing-key"   // A signing key was uploaded to a platform
+	StepGPGKey       = "gpg-key"       // A GPG key was generated in the keyring
+	StepGPGUpload    = "gpg-upload"    // A GPG key was uploaded to a platform
+	StepDeployUpload = "deploy-upload" // A deploy key was added to Repo
+	StepDeployDelete = "deploy-delete" // A deploy key was deleted from Repo; PublicKey and Title re-add it
+	StepRemoteDelete = "remote-delete" // A key was deleted from a platform; PublicKey and Title re-upload it
+)
+
+// Journal statuses
+const (
+	StatusRunning    = "running"     // In progress, or interrupted
+	StatusDone       = "done"        // Finished; can still be undone
+	StatusFailed     = "failed"      // Failed and not (completely) rolled back
+	StatusRolledBack = "rolled-back" // Every step was undone
+)
+
+// Step is a single recorded change that a rollback can undo
+type Step struct {
+	Kind string    `json:"kind"`
+	Time time.Time `json:"time"`
+
+	// StepFile: the file, a copy of its previous content ("" when the file
+	// did not exist) and its mode. StepKey, StepMove and StepTrash: the
+	// absolute path of the private key.
+	Path    string      `json:"path,omitempty"`
+	Backup  string      `json:"backup,omitempty"`
+	Mode    os.FileMode `json:"mode,omitempty"`
+	To      string      `json:"to,omitempty"`       // StepMove
+	TrashID string      `json:"trash_id,omitempty"` // StepTrash
+
+	// StepUpload, StepSigningKey, StepGPGUpload and StepRemoteDelete: the
+	// remote key and where it is. StepGPGKey: the key's Fingerprint.
+	Persona     string `json:"persona,omitempty"`
+	Platform    string `json:"platform,omitempty"`
+	Account     string `json:"account,omitempty"`
+	RemoteID    string `json:"remote_id,omitempty"`
+	Fingerprint string `json:"fingerprint,omitempty"`
+	PublicKey   string `json:"public_key,omitempty"` // StepRemoteDelete
+	Title       string `json:"title,omitempty"`      // StepRemoteDelete
+	Repo        string `json:"repo,omitempty"`       // StepDeployUpload and StepDeployDelete
+
+	RolledBack bool `json:"rolled_back,omitempty"`
+}
+
+// Journal records the steps of an operation as they happen, so a failed,
+// interrupted or mistaken operation can be undone
+type Journal struct {
+	ID        string    `json:"id"`
+	Operation string    `json:"operation"` // e.g., "apply", "rotate"
+	StartedAt time.Time `json:"started_at"`
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	Steps     []Step    `json:"steps"`
+
+	dir string
+	mu  sync.Mutex // Concurrent platform tasks record steps
+}
+
+// GetDefaultJournalDir returns the default directory of the operation history
+func GetDefaultJournalDir() string {
+	return filepath.Join(profile.Dir(), DefaultJournalDirName)
+}
+
+// Begin starts the journal of a new operation in historyDir, removing the
+// oldest operations beyond maxJournals
+func Begin(historyDir, operation string) (*Journal, error) {
+	if historyDir == "" {
+		historyDir = GetDefaultJournalDir()
+	}
+
+	now := time.Now()
+	id := fmt.Sprintf("%s-%s", now.Format(idTimestampFormat), operation)
+	dir := filepath.Join(historyDir, id)
+	if err := os.MkdirAll(filepath.Join(dir, filesDirName), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create journal directory: %w", err)
+	}
+
+	j := &Journal{ID: id, Operation: operation, StartedAt: now, Status: StatusRunning, Steps: []Step{}, dir: dir}
+	if err := j.save(); err != nil {
+		return nil, err
+	}
+
+	if ids, err := journalIDs(historyDir); err == nil && len(ids) > maxJournals {
+		for _, old := range ids[maxJournals:] {
+			os.RemoveAll(filepath.Join(historyDir, old))
+		}
+	}
+	return j, nil
+}
+
+// List returns the journals in historyDir, most recent first
+func List(historyDir string) ([]*Journal, error) {
+	if historyDir == "" {
+		historyDir = GetDefaultJournalDir()
+	}
+	ids, err := journalIDs(historyDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var journals []*Journal
+	for _, id := range ids {
+		j, err := load(filepath.Join(historyDir, id))
+		if err != nil {
+			continue // Skip unreadable journals rather than failing the whole list
+		}
+		journals = append(journals, j)
+	}
+	return journals, nil
+}
+
+// Latest returns the most recent journal, or nil if there is none
+func Latest(historyDir string) (*Journal, error) {
+	journals, err := List(historyDir)
+	if err != nil || len(journals) == 0 {
+		return nil, err
+	}
+	return journals[0], nil
+}
+
+// LatestUndoable returns the most recent journal with changes that were not
+// rolled back, or nil if there is none. Operations undone before it are
+// skipped, so repeated undos walk back through the history.
+func LatestUndoable(historyDir string) (*Journal, error) {
+	journals, err := List(historyDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, j := range journals {
+		if j.Status != StatusRolledBack && len(j.Pending()) > 0 {
+			return j, nil
+		}
+	}
+	return nil, nil
+}
+
+// journalIDs returns the journal directories in historyDir, most recent first
+func journalIDs(historyDir string) ([]string, error) {
+	entries, err := os.ReadDir(historyDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read operation history: %w", err)
+	}
+
+	var ids []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			ids = append(ids, entry.Name())
+		}
+	}
+	// IDs start with a sortable timestamp
+	sort.Sort(sort.Reverse(sort.StringSlice(ids)))
+	return ids, nil
+}
+
+func load(dir string) (*Journal, error) {
+	data, err := os.ReadFile(filepath.Join(dir, journalFileName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read journal: %w", err)
+	}
+
+	j := &Journal{dir: dir}
+	if err := json.Unmarshal(data, j); err != nil {
+		return nil, fmt.Errorf("failed to parse journal: %w", err)
+	}
+	return j, nil
+}
+
+// BackupFile copies a file before it is written for the first time in this
+// operation. A file that does not exist yet is removed on rollback.
+func (j *Journal) BackupFile(path string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for _, step := range j.Steps {
+		if step.Kind == StepFile && step.Path == path {
+			return nil // Keep the content from before the operation
+		}
+	}
+
+	step := Step{Kind: StepFile, Time: time.Now(), Path: path}
+	content, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if info, err := os.Stat(path); err == nil {
+			step.Mode = info.Mode().Perm()
+		}
+		step.Backup = filepath.Join(j.dir, filesDirName, strconv.Itoa(len(j.Steps)))
+		if err := os.WriteFile(step.Backup, content, 0600); err != nil {
+			return fmt.Errorf("failed to back up %s: %w", path, err)
+		}
+	case !os.IsNotExist(err):
+		return fmt.Errorf("failed to back up %s: %w", path, err)
+	}
+
+	return j.add(step)
+}
+
+// KeyCreated records a key pair created at keyPath
+func (j *Journal) KeyCreated(keyPath string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.add(Step{Kind: StepKey, Time: time.Now(), Path: keyPath})
+}
+
+// KeyMoved records a key pair moved from keyPath to newPath
+func (j *Journal) KeyMoved(keyPath, newPath string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.add(Step{Kind: StepMove, Time: time.Now(), Path: keyPath, To: newPath})
+}
+
+// KeyTrashed records a key pair moved to the trash
+func (j *Journal) KeyTrashed(keyPath, trashID string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.add(Step{Kind: StepTrash, Time: time.Now(), Path: keyPath, TrashID: trashID})
+}
+
+// KeyUploaded records a key uploaded to a persona's platform account
+func (j *Journal) KeyUploaded(persona, platform, account, remoteID, fingerprint string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.add(Step{Kind: StepUpload, Time: time.Now(), Persona: persona, Platform: platform,
+		Account: account, RemoteID: remoteID, Fingerprint: fingerprint})
+}
+
+// SigningKeyUploaded records a signing key uploaded to a persona's platform
+// account
+func (j *Journal) SigningKeyUploaded(persona, platform, account, remoteID, fingerprint string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.add(Step{Kind: StepSigningKey, Time: time.Now(), Persona: persona, Platform: platform,
+		Account: account, RemoteID: remoteID, Fingerprint: fingerprint})
+}
+
+// GPGKeyCreated records a GPG key generated in the keyring
+func (j *Journal) GPGKeyCreated(fingerprint string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.add(Step{Kind: StepGPGKey, Time: time.Now(), Fingerprint: fingerprint})
+}
+
+// GPGKeyUploaded records a GPG key uploaded to a persona's platform account
+func (j *Journal) GPGKeyUploaded(persona, platform, account, remoteID, fingerprint string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.add(Step{Kind: StepGPGUpload, Time: time.Now(), Persona: persona, Platform: platform,
+		Account: account, RemoteID: remoteID, Fingerprint: fingerprint})
+}
+
+// DeployKeyUploaded records a deploy key added to a repository with the
+// token of a persona's platform account
+func (j *Journal) DeployKeyUploaded(persona, platform, account, repo, remoteID, fingerprint string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.add(Step{Kind: StepDeployUpload, Time: time.Now(), Persona: persona, Platform: platform,
+		Account: account, Repo: repo, RemoteID: remoteID, Fingerprint: fingerprint})
+}
+
+// DeployKeyDeleted records a deploy key deleted from a repository, with the
+// public key and title needed to add it again
+func (j *Journal) DeployKeyDeleted(persona, platform, account, repo, remoteID, fingerprint, publicKey, title string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.add(Step{Kind: StepDeployDelete, Time: time.Now(), Persona: persona, Platform: platform,
+		Account: account, Repo: repo, RemoteID: remoteID, Fingerprint: fingerprint, PublicKey: publicKey, Title: title})
+}
+
+// RemoteKeyDeleted records a key deleted from a persona's platform account,
+// with the public key and title needed to upload it again
+func (j *Journal) RemoteKeyDeleted(persona, platform, account, remoteID, fingerprint, publicKey, title string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.add(Step{Kind: StepRemoteDelete, Time: time.Now(), Persona: persona, Platform: platform,
+		Account: account, RemoteID: remoteID, Fingerprint: fingerprint, PublicKey: publicKey, Title: title})
+}
+
+// MarkRolledBack records that the step at index was undone
+func (j *Journal) MarkRolledBack(index int) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.Steps[index].RolledBack = true
+	return j.save()
+}
+
+// Finish sets the final status, and the error the operation failed with
+func (j *Journal) Finish(status string, opErr error) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.Status = status
+	if opErr != nil {
+		j.Error = opErr.Error()
+	}
+	return j.save()
+}
+
+// Pending returns the indexes of the steps not rolled back yet, most recent first
+func (j *Journal) Pending() []int {
+	var pending []int
+	for i := len(j.Steps) - 1; i >= 0; i-- {
+		if !j.Steps[i].RolledBack {
+			pending = append(pending, i)
+		}
+	}
+	return pending
+}
+
+// RestoreFile puts back the content a file step backed up, or removes the
+// file if it did not exist before
+func RestoreFile(step Step) error {
+	if step.Backup == "" {
+		if err := os.Remove(step.Path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %w", step.Path, err)
+		}
+		return nil
+	}
+
+	content, err := os.ReadFile(step.Backup)
+	if err != nil {
+		return fmt.Errorf("failed to read backup of %s: %w", step.Path, err)
+	}
+	mode := step.Mode
+	if mode == 0 {
+		mode = 0600
+	}
+	if err := atomicfile.WriteFile(step.Path, content, mode); err != nil {
+		return fmt.Errorf("failed to restore %s: %w", step.Path, err)
+	}
+	return os.Chmod(step.Path, mode)
+}
+
+// MoveBack moves a key pair of a move step back to where it was
+func MoveBack(step Step) error {
+	if _, err := os.Stat(step.Path); err == nil {
+		return fmt.Errorf("%s already exists", step.Path)
+	}
+	if err := os.Rename(step.To, step.Path); err != nil {
+		return fmt.Errorf("failed to move %s back: %w", step.To, err)
+	}
+	if err := os.Rename(step.To+".pub", step.Path+".pub"); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to move %s.pub back: %w", step.To, err)
+	}
+	return nil
+}
+
+// add appends a step and saves the journal; the caller holds mu
+func (j *Journal) add(step Step) error {
+	j.Steps = append(j.Steps, step)
+	return j.save()
+}
+
+// save writes the journal atomically so an interrupted write never loses
+// the steps recorded so far
+func (j *Journal) save() error {
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode journal: %w", err)
+	}
+
+	path := filepath.Join(j.dir, journalFileName)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0600); err != nil {
+		return fmt.Errorf("failed to write journal: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write journal: %w", err)
+	}
+	return nil
+}
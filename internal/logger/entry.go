@@ -0,0 +1,36 @@
+package logger
+
+// Fields are key/value pairs logged with a message, e.g. the persona and
+// platform a rotation failed for. Text lines append them as key=value; JSON
+// lines add them as keys.
+type Fields map[string]interface{}
+
+// Entry logs messages with fields
+type Entry struct {
+	fields Fields
+}
+
+// WithFields returns an entry that logs its messages with fields
+func WithFields(fields Fields) *Entry {
+	return &Entry{fields: fields}
+}
+
+// Error logs an error-level message with the entry's fields
+func (e *Entry) Error(format string, args ...interface{}) {
+	logf(ERROR, e.fields, format, args...)
+}
+
+// Warn logs a warning-level message with the entry's fields
+func (e *Entry) Warn(format string, args ...interface{}) {
+	logf(WARN, e.fields, format, args...)
+}
+
+// Info logs an info-level message with the entry's fields
+func (e *Entry) Info(format string, args ...interface{}) {
+	logf(INFO, e.fields, format, args...)
+}
+
+// Debug logs a debug-level message with the entry's fields
+func (e *Entry) Debug(format string, args ...interface{}) {
+	logf(DEBUG, e.fields, format, args...)
+}
@@ -0,0 +1,119 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Defaults of RotatingFile
+const (
+	DefaultMaxSize  = 10 << 20 // Bytes before a log file is rotated
+	DefaultMaxFiles = 5        // Rotated files kept besides the current one
+)
+
+// RotatingFile is a log file that is moved aside when it would grow past
+// MaxSize or was last written on an earlier day. The rotated files are
+// named <path>.1 (the newest) to <path>.<MaxFiles>; older ones are removed.
+type RotatingFile struct {
+	Path     string
+	MaxSize  int64
+	MaxFiles int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+	day  string // Day of the last write, 2006-01-02
+}
+
+// OpenRotatingFile opens path for appending, creating its directory. Zero
+// maxSize or maxFiles use the defaults.
+func OpenRotatingFile(path string, maxSize int64, maxFiles int) (*RotatingFile, error) {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxSize
+	}
+	if maxFiles <= 0 {
+		maxFiles = DefaultMaxFiles
+	}
+	r := &RotatingFile{Path: path, MaxSize: maxSize, MaxFiles: maxFiles}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// open opens the current file and reads its size and day
+func (r *RotatingFile) open() error {
+	if err := os.MkdirAll(filepath.Dir(r.Path), 0700); err != nil {
+		return fmt.Errorf("failed to create log directory: %w", err)
+	}
+	file, err := os.OpenFile(r.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat log file: %w", err)
+	}
+	r.file = file
+	r.size = info.Size()
+	r.day = info.ModTime().Format("2006-01-02")
+	return nil
+}
+
+// Write appends p, rotating the file first when needed
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return 0, os.ErrClosed
+	}
+	today := time.Now().Format("2006-01-02")
+	if r.size > 0 && (r.size+int64(len(p)) > r.MaxSize || r.day != today) {
+		if err := r.rotate(); err != nil && r.file == nil {
+			return 0, err
+		}
+	}
+
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	r.day = today
+	return n, err
+}
+
+// rotate shifts the rotated files up by one, dropping the oldest, and
+// starts a new file
+func (r *RotatingFile) rotate() error {
+	r.file.Close()
+	r.file = nil
+
+	os.Remove(fmt.Sprintf("%s.%d", r.Path, r.MaxFiles))
+	for i := r.MaxFiles - 1; i >= 1; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", r.Path, i), fmt.Sprintf("%s.%d", r.Path, i+1))
+	}
+	if err := os.Rename(r.Path, r.Path+".1"); err != nil {
+		// Keep writing to the file that could not be moved
+		if openErr := r.open(); openErr != nil {
+			return openErr
+		}
+		return fmt.Errorf("failed to rotate log file: %w", err)
+	}
+	return r.open()
+}
+
+// Close closes the current file
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
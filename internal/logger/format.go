@@ -0,0 +1,121 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Format is how log lines are written
+type Format string
+
+const (
+	FormatText Format = "text" // [LEVEL] date time message key=value...
+	FormatJSON Format = "json" // One JSON object per line, for log aggregators
+)
+
+// SetFormat sets the format of log lines, on stderr and in the log file
+func SetFormat(format Format) {
+	currentFormat = format
+}
+
+// SetFormatFromString sets the format of log lines from a string
+func SetFormatFromString(formatStr string) error {
+	switch Format(strings.ToLower(formatStr)) {
+	case FormatText:
+		currentFormat = FormatText
+	case FormatJSON:
+		currentFormat = FormatJSON
+	default:
+		return fmt.Errorf("invalid log format: %s", formatStr)
+	}
+	return nil
+}
+
+// formatLine formats a message with its fields as one line in the current
+// format. JSON lines carry time, level, component (the package that logged,
+// unless a "component" field names it), msg, and the fields.
+func formatLine(level Level, fields Fields, msg string) string {
+	now := time.Now()
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		if k != "component" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	if currentFormat != FormatJSON {
+		var b strings.Builder
+		fmt.Fprintf(&b, "[%s] %s %s", levelNames[level], now.Format("2006/01/02 15:04:05"), msg)
+		for _, k := range keys {
+			value := fmt.Sprint(fields[k])
+			if value == "" || strings.ContainsAny(value, " \t\"=") {
+				value = fmt.Sprintf("%q", value)
+			}
+			fmt.Fprintf(&b, " %s=%s", k, value)
+		}
+		return b.String() + "\n"
+	}
+
+	component, _ := fields["component"].(string)
+	if component == "" {
+		component = callerComponent()
+	}
+
+	// Written by hand to keep the fixed keys first
+	var b strings.Builder
+	b.WriteString(`{"time":`)
+	writeJSON(&b, now.Format(time.RFC3339Nano))
+	b.WriteString(`,"level":`)
+	writeJSON(&b, strings.ToLower(levelNames[level]))
+	b.WriteString(`,"component":`)
+	writeJSON(&b, component)
+	b.WriteString(`,"msg":`)
+	writeJSON(&b, msg)
+	for _, k := range keys {
+		b.WriteString(",")
+		writeJSON(&b, k)
+		b.WriteString(":")
+		value := fields[k]
+		if err, ok := value.(error); ok {
+			value = err.Error()
+		}
+		writeJSON(&b, value)
+	}
+	return b.String() + "}\n"
+}
+
+// writeJSON writes v as JSON, or as a string when it cannot be encoded
+func writeJSON(b *strings.Builder, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		data, _ = json.Marshal(fmt.Sprint(v))
+	}
+	b.Write(data)
+}
+
+// callerComponent returns the last element of the package that called the
+// logger, such as "commands" or "api"
+func callerComponent() string {
+	pcs := make([]uintptr, 16)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs)])
+	for {
+		frame, more := frames.Next()
+		// e.g. github.com/kunlu/git-keys/internal/commands.runRotate.func1
+		pkg := frame.Function
+		if slash := strings.LastIndex(pkg, "/"); slash >= 0 {
+			pkg = pkg[slash+1:]
+		}
+		pkg, _, _ = strings.Cut(pkg, ".")
+		if pkg != "logger" && pkg != "" {
+			return pkg
+		}
+		if !more {
+			return ""
+		}
+	}
+}
@@ -3,7 +3,6 @@ package logger
 import (
 	"fmt"
 	"io"
-	"log"
 	"os"
 	"strings"
 )
@@ -28,8 +27,10 @@ var (
 		TRACE: "TRACE",
 	}
 
-	currentLevel           = INFO
-	output       io.Writer = os.Stderr
+	currentLevel            = INFO
+	currentFormat           = FormatText
+	output        io.Writer = os.Stderr
+	fileOutput    io.Writer // Log file that also receives every message, or nil
 )
 
 // SetLevel sets the global logging level
@@ -61,35 +62,58 @@ func SetOutput(w io.Writer) {
 	output = w
 }
 
-func logf(level Level, format string, args ...interface{}) {
-	if level <= currentLevel {
-		levelName := levelNames[level]
-		msg := fmt.Sprintf(format, args...)
-		log.New(output, fmt.Sprintf("[%s] ", levelName), log.LstdFlags).Println(msg)
+// SetFile tees the log to w, such as a RotatingFile, or stops with nil. The
+// file receives messages up to the current level, and at least info ones,
+// so a quieter terminal still leaves a record.
+func SetFile(w io.Writer) {
+	fileOutput = w
+}
+
+func logf(level Level, fields Fields, format string, args ...interface{}) {
+	toOutput := level <= currentLevel
+	toFile := fileOutput != nil && (level <= currentLevel || level <= INFO)
+	if !toOutput && !toFile {
+		return
+	}
+
+	line := formatLine(level, fields, fmt.Sprintf(format, args...))
+	if toOutput {
+		io.WriteString(output, line)
+	}
+	if toFile {
+		io.WriteString(fileOutput, line)
+	}
+}
+
+// Record writes a message to the log file only, such as the command a run
+// starts with or the error it ends with
+func Record(level Level, fields Fields, format string, args ...interface{}) {
+	if fileOutput != nil {
+		io.WriteString(fileOutput, formatLine(level, fields, fmt.Sprintf(format, args...)))
 	}
 }
 
 // Error logs an error-level message
 func Error(format string, args ...interface{}) {
-	logf(ERROR, format, args...)
+	logf(ERROR, nil, format, args...)
 }
 
 // Warn logs a warning-level message
 func Warn(format string, args ...interface{}) {
-	logf(WARN, format, args...)
+	logf(WARN, nil, format, args...)
 }
 
 // Info logs an info-level message
 func Info(format string, args ...interface{}) {
-	logf(INFO, format, args...)
+	logf(INFO, nil, format, args...)
 }
 
 // Debug logs a debug-level message
 func Debug(format string, args ...interface{}) {
-	logf(DEBUG, format, args...)
+	logf(DEBUG, nil, format, args...)
 }
 
 // Trace logs a trace-level message
 func Trace(format string, args ...interface{}) {
-	logf(TRACE, format, args...)
+	logf(TRACE, nil, format, args...)
 }
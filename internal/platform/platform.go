@@ -13,6 +13,7 @@ type Platform interface {
 	GetMachineName() (string, error)
 	GetOS() string
 	GetOSVersion() (string, error)
+	OpenURL(url string) error
 }
 
 // macOSPlatform implements Platform for macOS
@@ -92,3 +93,11 @@ func (p *macOSPlatform) GetOSVersion() (string, error) {
 	}
 	return strings.TrimSpace(string(output)), nil
 }
+
+// OpenURL opens a URL in the default browser
+func (p *macOSPlatform) OpenURL(url string) error {
+	if err := exec.Command("open", url).Run(); err != nil {
+		return fmt.Errorf("failed to open browser: %w", err)
+	}
+	return nil
+}
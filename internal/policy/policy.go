@@ -0,0 +1,178 @@
+package policy
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kunlu/git-keys/internal/config"
+	"github.com/kunlu/git-keys/internal/profile"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultFileName is the policy file looked up in ~/.git-keys when the
+// configuration names none
+const DefaultFileName = "policy.yaml"
+
+// Policy is an organization's rules for the keys and platforms of a
+// configuration, shipped by a security team next to the config template
+type Policy struct {
+	MinKeyType         config.KeyType `yaml:"min_key_type,omitempty"`        // Weakest allowed key type: rsa, ecdsa or ed25519
+	MaxKeyAge          time.Duration  `yaml:"max_key_age,omitempty"`         // Active keys must be younger, and expire within it
+	RequireExpiry      bool           `yaml:"require_expiry,omitempty"`      // Every active key must have an expiry date
+	ForbiddenPlatforms []string       `yaml:"forbidden_platforms,omitempty"` // Platform types (github, gitlab) or hosts (gitlab.example.com)
+	RequireSigning     bool           `yaml:"require_signing,omitempty"`     // Every persona must sign commits
+}
+
+// Violation is a part of the configuration that breaks a rule
+type Violation struct {
+	Rule    string // The policy key, e.g. max_key_age
+	Subject string // persona or persona/account@platform
+	Message string
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s: %s (policy %s)", v.Subject, v.Message, v.Rule)
+}
+
+// keyTypeStrength orders key types from weakest to strongest
+var keyTypeStrength = map[config.KeyType]int{
+	config.KeyTypeRSA:     1,
+	config.KeyTypeECDSA:   2,
+	config.KeyTypeED25519: 3,
+}
+
+// Path returns the policy file of cfg: defaults.policy_file, or
+// ~/.git-keys/policy.yaml
+func Path(cfg *config.Config) string {
+	path := cfg.Defaults.PolicyFile
+	switch {
+	case path == "":
+		return filepath.Join(profile.Dir(), DefaultFileName)
+	case strings.HasPrefix(path, "~/"):
+		home, _ := os.UserHomeDir()
+		return filepath.Join(home, path[2:])
+	}
+	return path
+}
+
+// Load reads the policy of cfg. Returns nil without an error when there is
+// no policy file at the default location; a policy_file that is named but
+// missing is an error, so a policy cannot be skipped by accident.
+func Load(cfg *config.Config) (*Policy, error) {
+	path := Path(cfg)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) && cfg.Defaults.PolicyFile == "" {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy: %w", err)
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	var p Policy
+	if err := dec.Decode(&p); err != nil {
+		return nil, fmt.Errorf("failed to parse policy %s: %w", path, err)
+	}
+	if p.MinKeyType != "" && keyTypeStrength[p.MinKeyType] == 0 {
+		return nil, fmt.Errorf("policy %s: min_key_type must be rsa, ecdsa or ed25519", path)
+	}
+	return &p, nil
+}
+
+// Check returns the parts of cfg that break the policy. Archived personas
+// are skipped: they have no keys in use.
+func (p *Policy) Check(cfg *config.Config, now time.Time) []Violation {
+	var violations []Violation
+	for personaIdx := range cfg.Personas {
+		persona := &cfg.Personas[personaIdx]
+		if persona.Archived {
+			continue
+		}
+
+		if p.RequireSigning && !persona.SignCommits {
+			violations = append(violations, Violation{Rule: "require_signing", Subject: persona.Name,
+				Message: "commits are not signed; set sign_commits: true"})
+		}
+
+		for platformIdx := range persona.Platforms {
+			plat := &persona.Platforms[platformIdx]
+			subject := fmt.Sprintf("%s/%s@%s", persona.Name, plat.Account, plat.Type)
+			settings := cfg.ResolveKeySettings(persona, plat)
+
+			if forbidden := p.forbidden(plat); forbidden != "" {
+				violations = append(violations, Violation{Rule: "forbidden_platforms", Subject: subject,
+					Message: forbidden + " is not allowed"})
+			}
+
+			if p.MinKeyType != "" && !p.keyTypeAllowed(settings.Type) {
+				violations = append(violations, Violation{Rule: "min_key_type", Subject: subject,
+					Message: fmt.Sprintf("new keys would be %s; use %s or stronger", settings.Type, p.MinKeyType)})
+			}
+
+			if p.MaxKeyAge > 0 && settings.Expiration > p.MaxKeyAge {
+				violations = append(violations, Violation{Rule: "max_key_age", Subject: subject,
+					Message: fmt.Sprintf("key_expiration %s is longer than %s", formatDuration(settings.Expiration), formatDuration(p.MaxKeyAge))})
+			}
+
+			for _, key := range plat.Keys {
+				if key.Status != config.KeyStatusActive {
+					continue
+				}
+				// Imported keys may have no expiry date
+				if p.RequireExpiry && key.ExpiresAt.IsZero() {
+					violations = append(violations, Violation{Rule: "require_expiry", Subject: subject,
+						Message: fmt.Sprintf("active key %s has no expiry date; rotate it", key.Fingerprint)})
+				}
+				if p.MinKeyType != "" && key.Type != "" && !p.keyTypeAllowed(key.Type) {
+					violations = append(violations, Violation{Rule: "min_key_type", Subject: subject,
+						Message: fmt.Sprintf("active key %s is %s; rotate it to %s or stronger", key.Fingerprint, key.Type, p.MinKeyType)})
+				}
+				if p.MaxKeyAge > 0 && !key.CreatedAt.IsZero() && now.Sub(key.CreatedAt) > p.MaxKeyAge {
+					violations = append(violations, Violation{Rule: "max_key_age", Subject: subject,
+						Message: fmt.Sprintf("active key %s was created %s; rotate it", key.Fingerprint, key.CreatedAt.Format("2006-01-02"))})
+				}
+			}
+		}
+	}
+	return violations
+}
+
+// keyTypeAllowed reports whether t is at least as strong as MinKeyType
+func (p *Policy) keyTypeAllowed(t config.KeyType) bool {
+	return keyTypeStrength[t] >= keyTypeStrength[p.MinKeyType]
+}
+
+// forbidden returns the forbidden platform type or host plat is on, or ""
+func (p *Policy) forbidden(plat *config.Platform) string {
+	host := "github.com"
+	if plat.Type == config.PlatformGitLab {
+		host = "gitlab.com"
+	}
+	if plat.BaseURL != "" {
+		if u, err := url.Parse(plat.BaseURL); err == nil && u.Host != "" {
+			host = u.Hostname()
+		}
+	}
+
+	for _, f := range p.ForbiddenPlatforms {
+		f = strings.ToLower(strings.TrimSpace(f))
+		if f == string(plat.Type) || f == host {
+			return f
+		}
+	}
+	return ""
+}
+
+// formatDuration shows whole days as days
+func formatDuration(d time.Duration) string {
+	if d%(24*time.Hour) == 0 {
+		return fmt.Sprintf("%d days", d/(24*time.Hour))
+	}
+	return d.String()
+}
@@ -0,0 +1,103 @@
+package profile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// Env selects the profile when --profile is not given
+const Env = "GIT_KEYS_PROFILE"
+
+// Default is the profile used when none is selected. Its files keep the
+// locations git-keys used before profiles existed.
+const Default = "default"
+
+// namePattern restricts profile names to what is safe in file names,
+// keychain services and scheduler labels
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+// current is the selected profile; "" is the default profile
+var current string
+
+// Set selects a profile for the rest of the process. An empty name falls
+// back to $GIT_KEYS_PROFILE, then to the default profile.
+func Set(name string) error {
+	if name == "" {
+		name = os.Getenv(Env)
+	}
+	if name == "" || name == Default {
+		current = ""
+		return nil
+	}
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("invalid profile name %q (use letters, digits, '-' and '_')", name)
+	}
+	current = name
+	return nil
+}
+
+// Name returns the selected profile
+func Name() string {
+	if current == "" {
+		return Default
+	}
+	return current
+}
+
+// IsDefault reports whether the default profile is selected
+func IsDefault() bool {
+	return current == ""
+}
+
+// Dir returns the directory of the selected profile's state (history,
+// backups, caches, trash and logs): ~/.git-keys, or
+// ~/.git-keys/profiles/<name>
+func Dir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	if current == "" {
+		return filepath.Join(home, ".git-keys")
+	}
+	return filepath.Join(home, ".git-keys", "profiles", current)
+}
+
+// List returns the profiles other than the default that have a directory
+func List() []string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+	entries, err := os.ReadDir(filepath.Join(home, ".git-keys", "profiles"))
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() && namePattern.MatchString(entry.Name()) {
+			names = append(names, entry.Name())
+		}
+	}
+	return names
+}
+
+// Qualify appends the profile to a name shared by all profiles, such as a
+// keychain service or scheduler label. The default profile keeps the name.
+func Qualify(name string) string {
+	if current == "" {
+		return name
+	}
+	return name + "-" + current
+}
+
+// Tag returns the marker that sets the selected profile's managed regions of
+// shared files apart, e.g. "[work] "; "" for the default profile
+func Tag() string {
+	if current == "" {
+		return ""
+	}
+	return "[" + current + "] "
+}
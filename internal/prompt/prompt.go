@@ -0,0 +1,281 @@
+// Package prompt asks the user questions. All prompts of a run read from one
+// buffered input, so answers can be piped one per line, and a Prompter on
+// other readers and writers makes commands testable.
+package prompt
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Prompter asks questions on an input and an output
+type Prompter struct {
+	in        *bufio.Reader
+	terminal  *os.File  // Input, when it is a terminal whose echo can be turned off
+	out       io.Writer // nil writes to os.Stdout as it is when asking
+	assumeYes bool
+	ended     bool // The input has ended; later questions get their defaults
+}
+
+// New returns a Prompter reading answers from in and asking on out
+func New(in io.Reader, out io.Writer) *Prompter {
+	p := &Prompter{in: bufio.NewReader(in), out: out}
+	if f, ok := in.(*os.File); ok {
+		if info, err := f.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+			p.terminal = f
+		}
+	}
+	return p
+}
+
+// std reads stdin and asks on stdout, which commands may redirect after it
+// is created
+var std = New(os.Stdin, nil)
+
+// Default returns the Prompter of the package-level functions
+func Default() *Prompter {
+	return std
+}
+
+// SetDefault replaces the Prompter of the package-level functions, e.g. in
+// tests
+func SetDefault(p *Prompter) {
+	std = p
+}
+
+// SetAssumeYes makes confirmations of the default Prompter succeed without
+// reading an answer, for --yes
+func SetAssumeYes(yes bool) {
+	std.assumeYes = yes
+}
+
+// SetAssumeYes makes confirmations succeed without reading an answer
+func (p *Prompter) SetAssumeYes(yes bool) {
+	p.assumeYes = yes
+}
+
+func (p *Prompter) output() io.Writer {
+	if p.out == nil {
+		return os.Stdout
+	}
+	return p.out
+}
+
+// readLine reads one answer, trimmed. ok is false when the input has
+// ended without one.
+func (p *Prompter) readLine() (answer string, ok bool) {
+	if p.ended {
+		fmt.Fprintln(p.output())
+		return "", false
+	}
+	line, err := p.in.ReadString('\n')
+	if err != nil && line == "" {
+		// A closed pipe gets no more answers; end the prompt's line
+		p.ended = true
+		fmt.Fprintln(p.output())
+		return "", false
+	}
+	return strings.TrimSpace(line), true
+}
+
+// Line reads the answer to a question the caller has printed itself. ok is
+// false when the input has ended.
+func (p *Prompter) Line() (answer string, ok bool) {
+	return p.readLine()
+}
+
+// Confirm asks a y/n question until it gets an answer. It is false when the
+// input ends, and true without asking with assume-yes.
+func (p *Prompter) Confirm(question string) bool {
+	for {
+		fmt.Fprintf(p.output(), "%s (y/n): ", question)
+		if p.assumeYes {
+			fmt.Fprintln(p.output(), "y")
+			return true
+		}
+		answer, ok := p.readLine()
+		if !ok {
+			return false
+		}
+		switch strings.ToLower(answer) {
+		case "y", "yes":
+			return true
+		case "n", "no":
+			return false
+		}
+		fmt.Fprintln(p.output(), "Please enter 'y' or 'n'")
+	}
+}
+
+// ConfirmDefault asks a [Y/n] or [y/N] question. An empty answer, or the end
+// of the input, gives def.
+func (p *Prompter) ConfirmDefault(question string, def bool) bool {
+	hint := "[y/N]"
+	if def {
+		hint = "[Y/n]"
+	}
+	for {
+		fmt.Fprintf(p.output(), "%s %s: ", question, hint)
+		if p.assumeYes {
+			fmt.Fprintln(p.output(), "y")
+			return true
+		}
+		answer, ok := p.readLine()
+		if !ok {
+			return def
+		}
+		switch strings.ToLower(answer) {
+		case "":
+			return def
+		case "y", "yes":
+			return true
+		case "n", "no":
+			return false
+		}
+		fmt.Fprintln(p.output(), "Please enter 'y' or 'n'")
+	}
+}
+
+// ConfirmWord asks to type word, such as "yes", before something that cannot
+// be undone. Any other answer declines.
+func (p *Prompter) ConfirmWord(question, word string) bool {
+	fmt.Fprintf(p.output(), "%s (type '%s' to confirm): ", question, word)
+	if p.assumeYes {
+		fmt.Fprintln(p.output(), word)
+		return true
+	}
+	answer, ok := p.readLine()
+	return ok && strings.EqualFold(answer, word)
+}
+
+// Input asks for a line of text. An empty answer, or the end of the input,
+// gives def, which is shown in brackets when not empty.
+func (p *Prompter) Input(question, def string) string {
+	if def != "" {
+		fmt.Fprintf(p.output(), "%s [%s]: ", question, def)
+	} else {
+		fmt.Fprintf(p.output(), "%s: ", question)
+	}
+	answer, ok := p.readLine()
+	if !ok || answer == "" {
+		return def
+	}
+	return answer
+}
+
+// Secret asks for a line without echoing it when the input is a terminal
+func (p *Prompter) Secret(question string) string {
+	fmt.Fprintf(p.output(), "%s: ", question)
+	if p.terminal != nil && setEcho(p.terminal, false) == nil {
+		defer func() {
+			setEcho(p.terminal, true)
+			fmt.Fprintln(p.output())
+		}()
+	}
+	answer, _ := p.readLine()
+	return answer
+}
+
+// Choice asks for one of choices until it gets one. An empty answer, or the
+// end of the input, gives def.
+func (p *Prompter) Choice(question string, choices []string, def string) string {
+	list := strings.Join(choices, "/")
+	for {
+		if def != "" {
+			fmt.Fprintf(p.output(), "%s [%s] (default: %s): ", question, list, def)
+		} else {
+			fmt.Fprintf(p.output(), "%s [%s]: ", question, list)
+		}
+		answer, ok := p.readLine()
+		if !ok || (answer == "" && def != "") {
+			return def
+		}
+		for _, choice := range choices {
+			if strings.EqualFold(answer, choice) {
+				return choice
+			}
+		}
+		fmt.Fprintf(p.output(), "Please enter one of: %s\n", list)
+	}
+}
+
+// Select lists options numbered from 1 and asks for one until it gets a
+// valid number. Returns the index of the option; an empty answer, or the
+// end of the input, gives def, which may be -1 for none.
+func (p *Prompter) Select(question string, options []string, def int) int {
+	for i, option := range options {
+		fmt.Fprintf(p.output(), "  %d. %s\n", i+1, option)
+	}
+	for {
+		if def >= 0 && def < len(options) {
+			fmt.Fprintf(p.output(), "%s [%d]: ", question, def+1)
+		} else {
+			fmt.Fprintf(p.output(), "%s: ", question)
+		}
+		answer, ok := p.readLine()
+		if !ok || answer == "" {
+			return def
+		}
+		if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(options) {
+			return n - 1
+		}
+		fmt.Fprintf(p.output(), "Please enter a number from 1 to %d\n", len(options))
+	}
+}
+
+// Line reads an answer from the default Prompter
+func Line() (string, bool) {
+	return std.readLine()
+}
+
+// Confirm asks a y/n question on the default Prompter
+func Confirm(question string) bool {
+	return std.Confirm(question)
+}
+
+// ConfirmDefault asks a question with a default answer on the default
+// Prompter
+func ConfirmDefault(question string, def bool) bool {
+	return std.ConfirmDefault(question, def)
+}
+
+// ConfirmWord asks to type word on the default Prompter
+func ConfirmWord(question, word string) bool {
+	return std.ConfirmWord(question, word)
+}
+
+// Input asks for a line of text on the default Prompter
+func Input(question, def string) string {
+	return std.Input(question, def)
+}
+
+// Secret asks for a line without echo on the default Prompter
+func Secret(question string) string {
+	return std.Secret(question)
+}
+
+// Choice asks for one of choices on the default Prompter
+func Choice(question string, choices []string, def string) string {
+	return std.Choice(question, choices, def)
+}
+
+// Select asks for one of numbered options on the default Prompter
+func Select(question string, options []string, def int) int {
+	return std.Select(question, options, def)
+}
+
+// setEcho turns echo of typed characters on or off via stty
+func setEcho(terminal *os.File, on bool) error {
+	mode := "-echo"
+	if on {
+		mode = "echo"
+	}
+	cmd := exec.Command("stty", mode)
+	cmd.Stdin = terminal
+	return cmd.Run()
+}
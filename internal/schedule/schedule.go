@@ -0,0 +1,241 @@
+package schedule
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/kunlu/git-keys/internal/profile"
+)
+
+const (
+	// LaunchdLabel identifies the LaunchAgent on macOS; a profile other
+	// than the default appends its name
+	LaunchdLabel = "com.git-keys.rotate"
+
+	// SystemdUnit is the name of the systemd user service and timer on
+	// Linux; a profile other than the default appends its name
+	SystemdUnit = "git-keys-rotate"
+
+	// DefaultLogFileName is the log of scheduled runs in ~/.git-keys/logs
+	DefaultLogFileName = "rotate.log"
+)
+
+// Job is a command run periodically by the OS scheduler
+type Job struct {
+	Command  []string      // Executable and arguments
+	Interval time.Duration // Time between runs
+	LogPath  string        // File that receives stdout and stderr
+}
+
+// GetDefaultLogPath returns the default log file of scheduled runs
+func GetDefaultLogPath() string {
+	return filepath.Join(profile.Dir(), "logs", DefaultLogFileName)
+}
+
+// Scheduler returns the name of the OS scheduler, or an error on systems
+// without a supported one
+func Scheduler() (string, error) {
+	switch runtime.GOOS {
+	case "darwin":
+		return "launchd", nil
+	case "linux":
+		return "systemd", nil
+	}
+	return "", fmt.Errorf("scheduling is not supported on %s (only macOS launchd and Linux systemd)", runtime.GOOS)
+}
+
+// Files returns the files Install writes on this system
+func Files() ([]string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get home directory: %w", err)
+	}
+
+	switch runtime.GOOS {
+	case "darwin":
+		return []string{filepath.Join(home, "Library", "LaunchAgents", profile.Qualify(LaunchdLabel)+".plist")}, nil
+	case "linux":
+		unitDir := filepath.Join(home, ".config", "systemd", "user")
+		return []string{
+			filepath.Join(unitDir, profile.Qualify(SystemdUnit)+".service"),
+			filepath.Join(unitDir, profile.Qualify(SystemdUnit)+".timer"),
+		}, nil
+	}
+	_, err = Scheduler()
+	return nil, err
+}
+
+// Installed reports whether the job files exist
+func Installed() (bool, error) {
+	files, err := Files()
+	if err != nil {
+		return false, err
+	}
+	for _, path := range files {
+		if _, err := os.Stat(path); err != nil {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// Install writes the job files and loads them into the OS scheduler,
+// replacing a previously installed job
+func Install(job *Job) error {
+	if len(job.Command) == 0 {
+		return fmt.Errorf("no command to schedule")
+	}
+	if job.Interval < time.Minute {
+		return fmt.Errorf("interval %s is too short", job.Interval)
+	}
+	if err := os.MkdirAll(filepath.Dir(job.LogPath), 0700); err != nil {
+		return fmt.Errorf("failed to create log directory: %w", err)
+	}
+
+	files, err := Files()
+	if err != nil {
+		return err
+	}
+
+	var contents []string
+	switch runtime.GOOS {
+	case "darwin":
+		contents = []string{renderLaunchdPlist(job)}
+	case "linux":
+		contents = []string{renderSystemdService(job), renderSystemdTimer(job)}
+	}
+
+	for i, path := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+		}
+		if err := os.WriteFile(path, []byte(contents[i]), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+	}
+
+	switch runtime.GOOS {
+	case "darwin":
+		// Unload first so a changed interval takes effect
+		exec.Command("launchctl", "unload", files[0]).Run()
+		return run("launchctl", "load", "-w", files[0])
+	default:
+		if err := run("systemctl", "--user", "daemon-reload"); err != nil {
+			return err
+		}
+		return run("systemctl", "--user", "enable", "--now", profile.Qualify(SystemdUnit)+".timer")
+	}
+}
+
+// Uninstall unloads the job and removes its files. It is not an error if
+// the job is not installed.
+func Uninstall() error {
+	files, err := Files()
+	if err != nil {
+		return err
+	}
+
+	switch runtime.GOOS {
+	case "darwin":
+		if _, err := os.Stat(files[0]); err == nil {
+			exec.Command("launchctl", "unload", "-w", files[0]).Run()
+		}
+	case "linux":
+		exec.Command("systemctl", "--user", "disable", "--now", profile.Qualify(SystemdUnit)+".timer").Run()
+	}
+
+	for _, path := range files {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %w", path, err)
+		}
+	}
+
+	if runtime.GOOS == "linux" {
+		exec.Command("systemctl", "--user", "daemon-reload").Run()
+	}
+	return nil
+}
+
+// run executes a scheduler command, including its output in the error
+func run(name string, args ...string) error {
+	output, err := exec.Command(name, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s %s failed: %w: %s", name, strings.Join(args, " "), err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// renderLaunchdPlist returns a LaunchAgent that runs the job every interval
+func renderLaunchdPlist(job *Job) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+`)
+	fmt.Fprintf(&b, "\t<key>Label</key>\n\t<string>%s</string>\n", xmlEscape(profile.Qualify(LaunchdLabel)))
+	b.WriteString("\t<key>ProgramArguments</key>\n\t<array>\n")
+	for _, arg := range job.Command {
+		fmt.Fprintf(&b, "\t\t<string>%s</string>\n", xmlEscape(arg))
+	}
+	b.WriteString("\t</array>\n")
+	fmt.Fprintf(&b, "\t<key>StartInterval</key>\n\t<integer>%d</integer>\n", int(job.Interval.Seconds()))
+	fmt.Fprintf(&b, "\t<key>StandardOutPath</key>\n\t<string>%s</string>\n", xmlEscape(job.LogPath))
+	fmt.Fprintf(&b, "\t<key>StandardErrorPath</key>\n\t<string>%s</string>\n", xmlEscape(job.LogPath))
+	b.WriteString("</dict>\n</plist>\n")
+	return b.String()
+}
+
+// renderSystemdService returns the oneshot user service that runs the job
+func renderSystemdService(job *Job) string {
+	quoted := make([]string, len(job.Command))
+	for i, arg := range job.Command {
+		quoted[i] = systemdQuote(arg)
+	}
+
+	return fmt.Sprintf(`[Unit]
+Description=git-keys scheduled key rotation
+
+[Service]
+Type=oneshot
+ExecStart=%s
+StandardOutput=append:%s
+StandardError=append:%s
+`, strings.Join(quoted, " "), job.LogPath, job.LogPath)
+}
+
+// renderSystemdTimer returns the user timer that starts the service every
+// interval, and shortly after login
+func renderSystemdTimer(job *Job) string {
+	return fmt.Sprintf(`[Unit]
+Description=Run git-keys scheduled key rotation every %s
+
+[Timer]
+OnStartupSec=15min
+OnUnitActiveSec=%ds
+
+[Install]
+WantedBy=timers.target
+`, job.Interval, int(job.Interval.Seconds()))
+}
+
+func xmlEscape(s string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
+
+// systemdQuote quotes an ExecStart argument; % starts a specifier in unit files
+func systemdQuote(arg string) string {
+	arg = strings.ReplaceAll(arg, "%", "%%")
+	arg = strings.ReplaceAll(arg, `\`, `\\`)
+	arg = strings.ReplaceAll(arg, `"`, `\"`)
+	return `"` + arg + `"`
+}
@@ -0,0 +1,184 @@
+package sshconfig
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// conflictComment precedes Host sections that CommentOutSections disabled
+const conflictComment = "# Commented out by git-keys: conflicted with its managed hosts"
+
+// FindHostConflicts reports the Host sections of the SSH config, outside the
+// managed blocks, that overlap the managed hosts: the overrides FindOverrides
+// reports, sections declaring a managed alias again, and sections for the
+// real host behind an alias (such as "Host github.com") that offer another
+// key. Git commands that do not use the alias, like a clone outside the
+// persona's gitdir, authenticate with that key instead.
+func (m *Manager) FindHostConflicts(expected []ExpectedHost) ([]Conflict, error) {
+	conflicts, err := m.FindOverrides(expected)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := os.ReadFile(m.configPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return conflicts, nil
+		}
+		return nil, fmt.Errorf("failed to read SSH config: %w", err)
+	}
+	sections, _ := scanHostSections(string(content))
+
+	// Keys git-keys manages for each real host
+	managedKeys := make(map[string][]string)
+	for _, want := range expected {
+		if want.HostName != "" {
+			host := strings.ToLower(want.HostName)
+			managedKeys[host] = append(managedKeys[host], want.IdentityFile)
+		}
+	}
+
+	reported := make(map[int]bool)
+	for _, c := range conflicts {
+		reported[c.Line] = true
+	}
+	add := func(section hostSection, reason string) {
+		if reported[section.line] {
+			return
+		}
+		reported[section.line] = true
+		conflicts = append(conflicts, Conflict{Path: m.configPath, Line: section.line, Text: section.text, Reason: reason})
+	}
+
+	for _, section := range sections {
+		for _, want := range expected {
+			if containsPattern(section.patterns, want.Alias) {
+				add(section, fmt.Sprintf("unmanaged entry for %s", want.Alias))
+			}
+		}
+
+		for host, keys := range managedKeys {
+			if !containsPattern(section.patterns, host) {
+				continue
+			}
+			for _, identityFile := range section.identityFiles {
+				if !containsIdentityFile(keys, identityFile) {
+					add(section, fmt.Sprintf("offers %s for %s; git commands not using a git-keys alias authenticate with it", identityFile, host))
+					break
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(conflicts, func(i, j int) bool { return conflicts[i].Line < conflicts[j].Line })
+	return conflicts, nil
+}
+
+// containsPattern reports whether a Host line names host itself, not just a
+// wildcard matching it
+func containsPattern(patterns []string, host string) bool {
+	for _, pattern := range patterns {
+		if strings.EqualFold(pattern, host) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsIdentityFile(files []string, file string) bool {
+	for _, f := range files {
+		if sameIdentityFile(f, file) {
+			return true
+		}
+	}
+	return false
+}
+
+// CommentOutSections disables the Host sections of the SSH config starting at
+// lines, as reported in conflicts, by commenting out each of their lines
+// under a note saying why
+func (m *Manager) CommentOutSections(lines []int) error {
+	return m.rewriteSections(lines, func(config []string, sections [][2]int) []string {
+		var result []string
+		next := 0
+		for _, span := range sections {
+			result = append(result, config[next:span[0]]...)
+			result = append(result, fmt.Sprintf("%s (%s)", conflictComment, time.Now().Format("2006-01-02")))
+			for _, line := range config[span[0]:span[1]] {
+				if strings.TrimSpace(line) == "" {
+					result = append(result, line)
+				} else {
+					result = append(result, "# "+line)
+				}
+			}
+			next = span[1]
+		}
+		return append(result, config[next:]...)
+	})
+}
+
+// MoveSectionsToEnd moves the Host sections of the SSH config starting at
+// lines, in their order, to the end of the file, below the managed blocks,
+// where the managed settings take precedence over them
+func (m *Manager) MoveSectionsToEnd(lines []int) error {
+	return m.rewriteSections(lines, func(config []string, sections [][2]int) []string {
+		var result, moved []string
+		next := 0
+		for _, span := range sections {
+			result = append(result, config[next:span[0]]...)
+			moved = append(moved, "")
+			moved = append(moved, config[span[0]:span[1]]...)
+			// Take the blank lines after the section along
+			next = span[1]
+			for next < len(config) && strings.TrimSpace(config[next]) == "" {
+				next++
+			}
+		}
+		result = append(result, config[next:]...)
+		for len(result) > 0 && strings.TrimSpace(result[len(result)-1]) == "" {
+			result = result[:len(result)-1]
+		}
+		return append(append(result, moved...), "")
+	})
+}
+
+// rewriteSections locates the unmanaged Host sections starting at lines and
+// writes the SSH config edit returns for them. Sections are passed in file
+// order as [start, end) line indexes.
+func (m *Manager) rewriteSections(lines []int, edit func(config []string, sections [][2]int) []string) error {
+	unlock, err := m.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	content, err := os.ReadFile(m.configPath)
+	if err != nil {
+		return fmt.Errorf("failed to read SSH config: %w", err)
+	}
+	sections, _ := scanHostSections(string(content))
+
+	wanted := make(map[int]bool, len(lines))
+	for _, line := range lines {
+		wanted[line] = true
+	}
+	var spans [][2]int
+	for _, section := range sections {
+		if wanted[section.line] {
+			spans = append(spans, [2]int{section.line - 1, section.end})
+			delete(wanted, section.line)
+		}
+	}
+	if len(wanted) > 0 {
+		return fmt.Errorf("the SSH config changed; run the check again")
+	}
+	if len(spans) == 0 {
+		return nil
+	}
+
+	newContent := strings.Join(edit(strings.Split(string(content), "\n"), spans), "\n")
+	return m.writeVerified(m.configPath, content, newContent, "")
+}
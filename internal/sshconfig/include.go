@@ -0,0 +1,122 @@
+package sshconfig
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kunlu/git-keys/internal/logger"
+)
+
+const (
+	// includeComment precedes the Include line git-keys adds to the SSH config
+	includeComment = "# Hosts managed by git-keys"
+
+	// includeFileHeader starts a new included file of managed blocks
+	includeFileHeader = "# Managed by git-keys; changes are overwritten by 'git-keys apply'\n"
+)
+
+// includeValue returns how the SSH config names the included file: by its
+// name when both are in ~/.ssh, where ssh resolves relative includes, and
+// by its full path otherwise
+func (m *Manager) includeValue() string {
+	home, _ := os.UserHomeDir()
+	if filepath.Dir(m.includePath) == filepath.Join(home, ".ssh") {
+		return filepath.Base(m.includePath)
+	}
+	if strings.ContainsAny(m.includePath, " \t") {
+		return fmt.Sprintf("%q", m.includePath)
+	}
+	return m.includePath
+}
+
+// includeLine returns the 1-based line of the SSH config's Include of the
+// managed blocks file, or 0 when there is none. Only Include lines outside
+// Host and Match sections count, as ssh applies the others conditionally.
+func (m *Manager) includeLine(content string) int {
+	for i, line := range strings.Split(content, "\n") {
+		keyword, value := splitDirective(strings.TrimSpace(line))
+		switch strings.ToLower(keyword) {
+		case "host", "match":
+			return 0
+		case "include":
+			for _, name := range strings.Fields(value) {
+				if m.namesIncludeFile(strings.Trim(name, `"`)) {
+					return i + 1
+				}
+			}
+		}
+	}
+	return 0
+}
+
+// namesIncludeFile reports whether an Include argument is the managed
+// blocks file
+func (m *Manager) namesIncludeFile(name string) bool {
+	name = expandTilde(name)
+	if !filepath.IsAbs(name) {
+		home, _ := os.UserHomeDir()
+		name = filepath.Join(home, ".ssh", name)
+	}
+	return filepath.Clean(name) == filepath.Clean(m.includePath)
+}
+
+// ensureInclude adds the Include line of the managed blocks file to the top
+// of the SSH config, where it applies to every host and its blocks are read
+// before the user's own sections
+func (m *Manager) ensureInclude() error {
+	content, err := os.ReadFile(m.configPath)
+	if err != nil {
+		return fmt.Errorf("failed to read SSH config: %w", err)
+	}
+	if m.includeLine(string(content)) > 0 {
+		return nil
+	}
+
+	header := fmt.Sprintf("%s\nInclude %s\n\n", includeComment, m.includeValue())
+	if err := m.writeVerified(m.configPath, content, header+string(content), ""); err != nil {
+		return err
+	}
+	logger.Info("Added 'Include %s' to %s", m.includeValue(), m.configPath)
+	return nil
+}
+
+// removeIncludeIfUnused deletes the managed blocks file and its Include line
+// when the file holds nothing but comments
+func (m *Manager) removeIncludeIfUnused() error {
+	content, err := os.ReadFile(m.includePath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %s: %w", m.includePath, err)
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		if trimmed := strings.TrimSpace(line); trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+			return nil
+		}
+	}
+
+	config, err := os.ReadFile(m.configPath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read SSH config: %w", err)
+	}
+	if n := m.includeLine(string(config)); n > 0 {
+		lines := strings.Split(string(config), "\n")
+		start, end := n-1, n
+		if start > 0 && strings.TrimSpace(lines[start-1]) == includeComment {
+			start--
+		}
+		if end < len(lines) && strings.TrimSpace(lines[end]) == "" {
+			end++
+		}
+		lines = append(lines[:start], lines[end:]...)
+		if err := m.writeVerified(m.configPath, config, strings.Join(lines, "\n"), ""); err != nil {
+			return err
+		}
+	}
+
+	if err := os.Remove(m.includePath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove %s: %w", m.includePath, err)
+	}
+	logger.Info("Removed %s and its Include line", m.includePath)
+	return nil
+}
@@ -0,0 +1,108 @@
+package sshconfig
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/kunlu/git-keys/internal/atomicfile"
+	"github.com/kunlu/git-keys/internal/logger"
+	"github.com/kunlu/git-keys/internal/profile"
+)
+
+// KnownHost is a host and the host keys it publishes
+type KnownHost struct {
+	Host string   // known_hosts host pattern: github.com, or [host]:port off port 22
+	Keys []string // "type base64" public keys
+}
+
+// KnownHostPattern returns the known_hosts pattern of hostname on port,
+// which ssh writes as [host]:port unless the port is 22
+func KnownHostPattern(hostname string, port string) string {
+	if port == "" || port == "22" {
+		return hostname
+	}
+	return fmt.Sprintf("[%s]:%s", hostname, port)
+}
+
+// knownHostsStart and knownHostsEnd enclose the host keys git-keys writes.
+// Each profile manages a section of its own.
+func knownHostsStart() string {
+	return "# BEGIN git-keys " + profile.Tag() + "managed host keys"
+}
+
+func knownHostsEnd() string {
+	return "# END git-keys " + profile.Tag() + "managed host keys"
+}
+
+// WriteKnownHosts replaces the managed section of the known_hosts file at
+// path with the keys of hosts, creating the file if needed. Lines outside
+// the section are kept. Reports whether the file changed.
+func WriteKnownHosts(path string, hosts []KnownHost) (bool, error) {
+	path = expandTilde(path)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return false, fmt.Errorf("failed to create directory of %s: %w", path, err)
+	}
+
+	unlock, err := atomicfile.Lock(path)
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+
+	content, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return false, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	// The section is sorted so unchanged keys leave the file as it is
+	sorted := append([]KnownHost(nil), hosts...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Host < sorted[j].Host })
+	section := []string{knownHostsStart()}
+	for _, host := range sorted {
+		keys := append([]string(nil), host.Keys...)
+		sort.Strings(keys)
+		for _, key := range keys {
+			section = append(section, host.Host+" "+strings.TrimSpace(key))
+		}
+	}
+	section = append(section, knownHostsEnd())
+
+	var lines []string
+	inSection, replaced := false, false
+	for _, line := range strings.Split(strings.TrimRight(string(content), "\n"), "\n") {
+		switch strings.TrimSpace(line) {
+		case knownHostsStart():
+			inSection = true
+			if !replaced {
+				lines = append(lines, section...)
+				replaced = true
+			}
+			continue
+		case knownHostsEnd():
+			inSection = false
+			continue
+		}
+		if !inSection && (line != "" || len(lines) > 0) {
+			lines = append(lines, line)
+		}
+	}
+	if inSection {
+		return false, fmt.Errorf("%s has a git-keys section without an end marker; fix it by hand", path)
+	}
+	if !replaced {
+		lines = append(lines, section...)
+	}
+
+	newContent := strings.Join(lines, "\n") + "\n"
+	if newContent == string(content) {
+		return false, nil
+	}
+	if err := atomicfile.WriteFile(path, []byte(newContent), 0600); err != nil {
+		return false, err
+	}
+	logger.Info("Updated host keys in %s", path)
+	return true, nil
+}
@@ -0,0 +1,313 @@
+package sshconfig
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Severities of lint issues
+const (
+	LintError   = "error"
+	LintWarning = "warning"
+)
+
+// lintMaxIncludeDepth stops Include loops, as ssh does
+const lintMaxIncludeDepth = 16
+
+// accumulatingOptions are the options ssh collects from every matching
+// section instead of taking the first value, so they cannot be shadowed
+var accumulatingOptions = map[string]bool{
+	"identityfile":    true,
+	"certificatefile": true,
+	"localforward":    true,
+	"remoteforward":   true,
+	"dynamicforward":  true,
+	"sendenv":         true,
+	"setenv":          true,
+	"include":         true,
+}
+
+// LintIssue is a problem found in the SSH config or a file it includes
+type LintIssue struct {
+	Severity string `json:"severity"` // error or warning
+	Path     string `json:"path"`
+	Line     int    `json:"line,omitempty"`
+	Problem  string `json:"problem"`
+}
+
+// String formats the issue as path:line: problem
+func (i LintIssue) String() string {
+	if i.Line == 0 {
+		return fmt.Sprintf("%s: %s", i.Path, i.Problem)
+	}
+	return fmt.Sprintf("%s:%d: %s", i.Path, i.Line, i.Problem)
+}
+
+// lintSection is a Host or Match section, or the options before the first
+// one, in the order ssh reads them
+type lintSection struct {
+	path     string
+	line     int
+	text     string
+	patterns []string          // Host patterns; nil for Match sections
+	global   bool              // Options before the first Host or Match line
+	options  map[string]string // Options set, lowercased, to their spelling in the file
+}
+
+// lintBlock is a managed block start found while linting
+type lintBlock struct {
+	id   string
+	path string
+	line int
+}
+
+// linter collects what Lint finds while reading the files
+type linter struct {
+	issues   []LintIssue
+	sections []*lintSection
+	blocks   []lintBlock
+	keys     map[string]bool // Identity files already checked
+}
+
+func (l *linter) add(severity, path string, line int, format string, args ...interface{}) {
+	l.issues = append(l.issues, LintIssue{Severity: severity, Path: path, Line: line, Problem: fmt.Sprintf(format, args...)})
+}
+
+// Lint checks the SSH config and the files it includes: that ssh accepts
+// it, that each IdentityFile exists with permissions ssh accepts, Host
+// options shadowed by an earlier section matching the same hosts, and
+// managed blocks that are unterminated or appear twice. Managed blocks of
+// the selected profile for which known returns false are reported as
+// dangling; a nil known skips that check.
+func (m *Manager) Lint(known func(blockID string) bool) ([]LintIssue, error) {
+	l := &linter{keys: make(map[string]bool)}
+
+	if _, err := os.Stat(m.configPath); err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read SSH config: %w", err)
+	}
+	if err := m.Verify(""); err != nil {
+		l.add(LintError, m.configPath, 0, "%v", err)
+	}
+
+	global := &lintSection{path: m.configPath, line: 1, global: true, options: make(map[string]string)}
+	l.sections = append(l.sections, global)
+	if err := l.readFile(filepath.Dir(m.configPath), m.configPath, global, 0, make(map[string]bool)); err != nil {
+		return nil, err
+	}
+
+	l.checkShadowed()
+	l.checkBlocks(known)
+
+	sort.SliceStable(l.issues, func(i, j int) bool {
+		return l.issues[i].Severity == LintError && l.issues[j].Severity != LintError
+	})
+	return l.issues, nil
+}
+
+// readFile reads one file into the section list, following its Include
+// lines where they appear. current is the section the file's first lines
+// belong to.
+func (l *linter) readFile(sshDir, path string, current *lintSection, depth int, visited map[string]bool) error {
+	if depth > lintMaxIncludeDepth || visited[path] {
+		return nil
+	}
+	visited[path] = true
+	defer delete(visited, path)
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if depth > 0 && os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	block := lintBlock{}
+	for i, raw := range strings.Split(string(content), "\n") {
+		line := i + 1
+		trimmed := strings.TrimSpace(raw)
+
+		switch {
+		case strings.HasPrefix(trimmed, managedBlockStart):
+			if block.line > 0 {
+				l.add(LintError, path, block.line, "managed block %s has no end marker before the next block", block.id)
+			}
+			block = lintBlock{id: strings.TrimSpace(strings.TrimPrefix(trimmed, managedBlockStart)), path: path, line: line}
+			l.blocks = append(l.blocks, block)
+			continue
+		case strings.HasPrefix(trimmed, managedBlockEnd):
+			if block.line == 0 {
+				l.add(LintWarning, path, line, "end marker without a managed block")
+			}
+			block = lintBlock{}
+			continue
+		case trimmed == "" || strings.HasPrefix(trimmed, "#"):
+			continue
+		}
+
+		name, value := splitDirective(trimmed)
+		keyword := strings.ToLower(name)
+		switch keyword {
+		case "host":
+			current = &lintSection{path: path, line: line, text: trimmed, patterns: strings.Fields(value), options: make(map[string]string)}
+			l.sections = append(l.sections, current)
+			continue
+		case "match":
+			current = &lintSection{path: path, line: line, text: trimmed, options: make(map[string]string)}
+			l.sections = append(l.sections, current)
+			continue
+		case "include":
+			for _, pattern := range strings.Fields(value) {
+				matches, _ := filepath.Glob(resolveInclude(sshDir, strings.Trim(pattern, `"`)))
+				for _, match := range matches {
+					if err := l.readFile(sshDir, match, current, depth+1, visited); err != nil {
+						return err
+					}
+				}
+			}
+			continue
+		case "identityfile":
+			l.checkIdentityFile(path, line, strings.Trim(value, `"`))
+		}
+
+		if _, seen := current.options[keyword]; !seen {
+			current.options[keyword] = name
+		}
+	}
+
+	if block.line > 0 {
+		l.add(LintError, path, block.line, "managed block %s has no end marker", block.id)
+	}
+	return nil
+}
+
+// resolveInclude returns the path of an Include pattern: ~ is the home
+// directory, and relative paths are taken from the SSH config's directory
+func resolveInclude(sshDir, pattern string) string {
+	pattern = expandTilde(pattern)
+	if filepath.IsAbs(pattern) {
+		return pattern
+	}
+	return filepath.Join(sshDir, pattern)
+}
+
+// checkIdentityFile reports an IdentityFile that does not exist, or a
+// private key ssh refuses because others can read it. Paths with tokens
+// other than %d, %u and %% depend on the host and are not checked.
+func (l *linter) checkIdentityFile(path string, line int, file string) {
+	if strings.EqualFold(file, "none") {
+		return
+	}
+	home, _ := os.UserHomeDir()
+	file = strings.ReplaceAll(file, "%d", home)
+	if u, err := user.Current(); err == nil {
+		file = strings.ReplaceAll(file, "%u", u.Username)
+	}
+	if strings.Contains(strings.ReplaceAll(file, "%%", ""), "%") {
+		return
+	}
+	file = expandTilde(strings.ReplaceAll(file, "%%", "%"))
+	if !filepath.IsAbs(file) {
+		l.add(LintWarning, path, line, "IdentityFile %s is relative, so ssh looks for it in the current directory", file)
+		return
+	}
+
+	if l.keys[file] {
+		return
+	}
+	l.keys[file] = true
+
+	info, err := os.Stat(file)
+	if err != nil {
+		l.add(LintError, path, line, "IdentityFile %s does not exist", file)
+		return
+	}
+	if strings.HasSuffix(file, ".pub") {
+		return // Public keys select a key held by the agent
+	}
+	if mode := info.Mode().Perm(); mode&0077 != 0 {
+		l.add(LintError, path, line, "IdentityFile %s has mode %o; ssh ignores private keys others can read (chmod 600)", file, mode)
+	}
+}
+
+// checkShadowed reports options of a Host section that an earlier section
+// matching all of its hosts sets already: ssh takes the first value it
+// reads, so the later one never applies
+func (l *linter) checkShadowed() {
+	for j, later := range l.sections {
+		var hosts []string
+		for _, pattern := range later.patterns {
+			if !strings.ContainsAny(pattern, "*?!") {
+				hosts = append(hosts, pattern)
+			}
+		}
+		if len(hosts) == 0 {
+			continue
+		}
+
+		for _, earlier := range l.sections[:j] {
+			if earlier.patterns == nil && !earlier.global {
+				continue // Match criteria cannot be evaluated here
+			}
+			if !earlier.global && !matchesAll(earlier.patterns, hosts) {
+				continue
+			}
+
+			var shadowed []string
+			for option, name := range later.options {
+				if _, set := earlier.options[option]; set && !accumulatingOptions[option] {
+					shadowed = append(shadowed, name)
+				}
+			}
+			if len(shadowed) == 0 {
+				continue
+			}
+			sort.Strings(shadowed)
+
+			by := fmt.Sprintf("%q at %s:%d", earlier.text, earlier.path, earlier.line)
+			if earlier.global {
+				by = fmt.Sprintf("options before the first Host line of %s", earlier.path)
+			}
+			l.add(LintWarning, later.path, later.line, "%q sets %s, already set by %s, which ssh reads first",
+				later.text, strings.Join(shadowed, ", "), by)
+		}
+	}
+}
+
+// matchesAll reports whether a Host pattern list applies to every host
+func matchesAll(patterns []string, hosts []string) bool {
+	for _, host := range hosts {
+		if !matchesHost(patterns, host) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkBlocks reports managed blocks of the selected profile that appear
+// more than once, and those known does not recognize
+func (l *linter) checkBlocks(known func(blockID string) bool) {
+	first := make(map[string]lintBlock)
+	for _, block := range l.blocks {
+		id, ok := profileBlockID(block.id)
+		if !ok {
+			continue
+		}
+		if prev, seen := first[id]; seen {
+			l.add(LintError, block.path, block.line, "duplicate managed block %s, also at %s:%d; ssh uses the first one", id, prev.path, prev.line)
+			continue
+		}
+		first[id] = block
+
+		if known != nil && !known(id) {
+			l.add(LintWarning, block.path, block.line, "managed block %s belongs to no configured persona", id)
+		}
+	}
+}
@@ -4,10 +4,13 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
+	"github.com/kunlu/git-keys/internal/atomicfile"
 	"github.com/kunlu/git-keys/internal/config"
 	"github.com/kunlu/git-keys/internal/logger"
+	"github.com/kunlu/git-keys/internal/profile"
 )
 
 const (
@@ -17,7 +20,8 @@ const (
 
 // Manager handles SSH config file operations
 type Manager struct {
-	configPath string
+	configPath  string
+	includePath string // File holding the managed blocks; "" keeps them in configPath
 }
 
 // NewManager creates a new SSH config manager
@@ -29,9 +33,68 @@ func NewManager(configPath string) *Manager {
 	return &Manager{configPath: configPath}
 }
 
-// Entry represents a Host entry in SSH config
+// ConfigPath returns the path of the SSH config file
+func (m *Manager) ConfigPath() string {
+	return m.configPath
+}
+
+// UseIncludeFile makes the manager keep the managed blocks in a file of
+// their own, which the SSH config includes, instead of in the SSH config
+// itself. A relative path is taken from the SSH config's directory. Blocks
+// left in the SSH config by an earlier inline setup move to the file as they
+// are rewritten.
+func (m *Manager) UseIncludeFile(path string) {
+	if path != "" && !filepath.IsAbs(path) && !strings.HasPrefix(path, "~/") {
+		path = filepath.Join(filepath.Dir(m.configPath), path)
+	}
+	m.includePath = expandTilde(path)
+}
+
+// BlocksPath returns the file the managed blocks are written to
+func (m *Manager) BlocksPath() string {
+	if m.includePath != "" {
+		return m.includePath
+	}
+	return m.configPath
+}
+
+// Files returns the files the manager changes: the SSH config, and the
+// included file of the managed blocks if there is one
+func (m *Manager) Files() []string {
+	if m.includePath != "" {
+		return []string{m.configPath, m.includePath}
+	}
+	return []string{m.configPath}
+}
+
+// lock takes the write lock of the SSH config, and of the included file of
+// the managed blocks when it is in another directory, for the whole
+// read-modify-write of a change
+func (m *Manager) lock() (unlock func(), err error) {
+	unlockConfig, err := atomicfile.Lock(m.configPath)
+	if err != nil {
+		return nil, err
+	}
+	if m.includePath == "" || filepath.Dir(m.includePath) == filepath.Dir(m.configPath) {
+		return unlockConfig, nil
+	}
+	unlockInclude, err := atomicfile.Lock(m.includePath)
+	if err != nil {
+		unlockConfig()
+		return nil, err
+	}
+	return func() {
+		unlockInclude()
+		unlockConfig()
+	}, nil
+}
+
+// Entry represents a Host entry in SSH config, or a Match entry when Match
+// is set
 type Entry struct {
 	Host         string
+	Patterns     []string // Further patterns on the Host line, e.g. "*.work" or "!github.com.old"
+	Match        string   // Criteria of a Match entry, e.g. `host github.com exec "test -d ~/work"`
 	HostName     string
 	User         string
 	IdentityFile string
@@ -66,12 +129,22 @@ func GetManagedBlockID(persona string, platform config.PlatformType, account str
 
 // AddOrUpdateEntry adds or updates a managed block in SSH config
 func (m *Manager) AddOrUpdateEntry(blockID string, entries []Entry) error {
-	if err := m.EnsureConfigExists(); err != nil {
+	unlock, err := m.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return m.addOrUpdateEntry(blockID, entries)
+}
+
+// addOrUpdateEntry is AddOrUpdateEntry with the lock held
+func (m *Manager) addOrUpdateEntry(blockID string, entries []Entry) error {
+	if err := m.prepareBlocksFile(blockID); err != nil {
 		return err
 	}
 
 	// Read existing config
-	content, err := os.ReadFile(m.configPath)
+	content, err := os.ReadFile(m.BlocksPath())
 	if err != nil {
 		return fmt.Errorf("failed to read SSH config: %w", err)
 	}
@@ -83,26 +156,253 @@ func (m *Manager) AddOrUpdateEntry(blockID string, entries []Entry) error {
 	blockLines := m.buildManagedBlock(blockID, entries)
 	newLines = append(newLines, blockLines...)
 
-	// Write back
+	// Write back, rolling back if the result does not parse
+	host := ""
+	if len(entries) > 0 {
+		host = entries[0].Host
+	}
 	newContent := strings.Join(newLines, "\n")
-	if err := os.WriteFile(m.configPath, []byte(newContent), 0600); err != nil {
-		return fmt.Errorf("failed to write SSH config: %w", err)
+	if err := m.writeVerified(m.BlocksPath(), content, newContent, host); err != nil {
+		return err
 	}
 
 	logger.Info("Updated SSH config managed block: %s", blockID)
 	return nil
 }
 
+// RemoveEntry removes a managed block from the SSH config. Missing blocks are ignored.
+func (m *Manager) RemoveEntry(blockID string) error {
+	unlock, err := m.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	removed := false
+	for _, path := range m.Files() {
+		found, err := m.removeBlockFrom(path, blockID)
+		if err != nil {
+			return err
+		}
+		removed = removed || found
+	}
+
+	if removed {
+		logger.Info("Removed SSH config managed block: %s", blockID)
+	}
+	return nil
+}
+
+// removeBlockFrom removes a managed block from one file, reporting whether
+// it was there
+func (m *Manager) removeBlockFrom(path, blockID string) (bool, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to read SSH config: %w", err)
+	}
+
+	lines := strings.Split(string(content), "\n")
+	newLines := m.removeManagedBlock(lines, blockID)
+	if len(newLines) == len(lines) {
+		return false, nil
+	}
+
+	if err := m.writeVerified(path, content, strings.Join(newLines, "\n"), ""); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// prepareBlocksFile makes sure the file of the managed blocks exists. With
+// an included file, the SSH config gets its Include line, and a block with
+// blockID left in the SSH config itself is removed, as the file replaces it.
+func (m *Manager) prepareBlocksFile(blockID string) error {
+	if err := m.EnsureConfigExists(); err != nil {
+		return err
+	}
+	if m.includePath == "" {
+		return nil
+	}
+
+	if _, err := os.Stat(m.includePath); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(m.includePath), 0700); err != nil {
+			return fmt.Errorf("failed to create directory of %s: %w", m.includePath, err)
+		}
+		if err := atomicfile.WriteFile(m.includePath, []byte(includeFileHeader), 0600); err != nil {
+			return fmt.Errorf("failed to create %s: %w", m.includePath, err)
+		}
+		logger.Info("Created SSH config file for managed hosts: %s", m.includePath)
+	}
+
+	if err := m.ensureInclude(); err != nil {
+		return err
+	}
+	if _, err := m.removeBlockFrom(m.configPath, blockID); err != nil {
+		return err
+	}
+	return nil
+}
+
+// RenameEntry moves a managed block to a new block ID, e.g. after a persona
+// or platform account was renamed. The block is rewritten with entries in
+// place of the old one, so comments and hosts the user placed around it keep
+// their position. Any existing block with the new ID is dropped. Both changes
+// are made in a single write. If the old block is gone, this behaves like
+// AddOrUpdateEntry.
+func (m *Manager) RenameEntry(oldID, newID string, entries []Entry) error {
+	unlock, err := m.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if m.includePath != "" {
+		// Blocks in the SSH config itself move to the included file
+		if found, err := m.removeBlockFrom(m.configPath, oldID); err != nil {
+			return err
+		} else if found {
+			return m.addOrUpdateEntry(newID, entries)
+		}
+	}
+	if err := m.prepareBlocksFile(newID); err != nil {
+		return err
+	}
+
+	content, err := os.ReadFile(m.BlocksPath())
+	if err != nil {
+		return fmt.Errorf("failed to read SSH config: %w", err)
+	}
+
+	lines := m.removeManagedBlock(strings.Split(string(content), "\n"), newID)
+
+	block := m.buildManagedBlock(newID, entries)
+	// Drop the blank padding; the old block's surroundings are kept as they are
+	block = block[1 : len(block)-1]
+
+	newLines, found := replaceManagedBlock(lines, oldID, block)
+	if !found {
+		newLines = append(lines, m.buildManagedBlock(newID, entries)...)
+	}
+
+	host := ""
+	if len(entries) > 0 {
+		host = entries[0].Host
+	}
+	if err := m.writeVerified(m.BlocksPath(), content, strings.Join(newLines, "\n"), host); err != nil {
+		return err
+	}
+
+	if found {
+		logger.Info("Renamed SSH config managed block: %s -> %s", oldID, newID)
+	} else {
+		logger.Info("Updated SSH config managed block: %s", newID)
+	}
+	return nil
+}
+
+// ManagedBlock describes a git-keys managed block found in the SSH config
+type ManagedBlock struct {
+	ID            string
+	Hosts         []string
+	IdentityFiles []string
+}
+
+// ManagedBlocks lists the managed blocks in the SSH config, and in the
+// included file of the managed blocks, with the hosts and identity files
+// they declare
+func (m *Manager) ManagedBlocks() ([]ManagedBlock, error) {
+	var blocks []ManagedBlock
+	for _, path := range m.Files() {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to read SSH config: %w", err)
+		}
+		blocks = append(blocks, parseManagedBlocks(string(content))...)
+	}
+	return blocks, nil
+}
+
+// parseManagedBlocks lists the managed blocks of the selected profile in
+// content
+func parseManagedBlocks(content string) []ManagedBlock {
+	var blocks []ManagedBlock
+	var current *ManagedBlock
+
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case strings.HasPrefix(trimmed, managedBlockStart):
+			// Blocks of other profiles are left alone
+			id, ok := profileBlockID(strings.TrimSpace(strings.TrimPrefix(trimmed, managedBlockStart)))
+			if !ok {
+				current = nil
+				continue
+			}
+			blocks = append(blocks, ManagedBlock{ID: id})
+			current = &blocks[len(blocks)-1]
+		case strings.HasPrefix(trimmed, managedBlockEnd):
+			current = nil
+		case current != nil:
+			keyword, value := splitDirective(trimmed)
+			switch strings.ToLower(keyword) {
+			case "host":
+				current.Hosts = append(current.Hosts, strings.Fields(value)...)
+			case "identityfile":
+				current.IdentityFiles = append(current.IdentityFiles, strings.Trim(value, `"`))
+			}
+		}
+	}
+
+	return blocks
+}
+
+// replaceManagedBlock replaces the block with blockID, markers included, by
+// replacement. It reports whether the block was found.
+func replaceManagedBlock(lines []string, blockID string, replacement []string) ([]string, bool) {
+	startMarker := blockStartMarker(blockID)
+	var result []string
+	inBlock, found := false, false
+
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+
+		if !found && trimmed == startMarker {
+			inBlock, found = true, true
+			result = append(result, replacement...)
+			continue
+		}
+
+		if inBlock {
+			if strings.HasPrefix(trimmed, managedBlockEnd) {
+				inBlock = false
+			}
+			continue
+		}
+
+		result = append(result, line)
+	}
+
+	return result, found
+}
+
 // removeManagedBlock removes a specific managed block from lines
 func (m *Manager) removeManagedBlock(lines []string, blockID string) []string {
-	startMarker := fmt.Sprintf("%s %s", managedBlockStart, blockID)
+	startMarker := blockStartMarker(blockID)
 	var result []string
 	inBlock := false
 
 	for _, line := range lines {
 		trimmed := strings.TrimSpace(line)
 
-		if strings.HasPrefix(trimmed, startMarker) {
+		// Match the whole ID so "work-github-bob" does not remove "work-github-bobby"
+		if trimmed == startMarker {
 			inBlock = true
 			continue
 		}
@@ -120,15 +420,39 @@ func (m *Manager) removeManagedBlock(lines []string, blockID string) []string {
 	return result
 }
 
+// blockStartMarker returns the line that starts the block with blockID. Blocks
+// of a profile other than the default carry its tag, e.g.
+// "# BEGIN git-keys managed block - [work] client-github-alice".
+func blockStartMarker(blockID string) string {
+	return fmt.Sprintf("%s %s%s", managedBlockStart, profile.Tag(), blockID)
+}
+
+// profileBlockID returns the block ID of a start marker's raw ID without the
+// profile tag, and whether the block belongs to the selected profile
+func profileBlockID(rawID string) (string, bool) {
+	tag := strings.TrimSpace(profile.Tag())
+	if tag == "" {
+		return rawID, !strings.HasPrefix(rawID, "[")
+	}
+	if !strings.HasPrefix(rawID, tag) {
+		return "", false
+	}
+	return strings.TrimSpace(strings.TrimPrefix(rawID, tag)), true
+}
+
 // buildManagedBlock creates a managed block with entries
 func (m *Manager) buildManagedBlock(blockID string, entries []Entry) []string {
 	var lines []string
 
 	lines = append(lines, "")
-	lines = append(lines, fmt.Sprintf("%s %s", managedBlockStart, blockID))
+	lines = append(lines, blockStartMarker(blockID))
 
 	for _, entry := range entries {
-		lines = append(lines, fmt.Sprintf("Host %s", entry.Host))
+		if entry.Match != "" {
+			lines = append(lines, fmt.Sprintf("Match %s", entry.Match))
+		} else {
+			lines = append(lines, fmt.Sprintf("Host %s", strings.Join(append([]string{entry.Host}, entry.Patterns...), " ")))
+		}
 		if entry.HostName != "" {
 			lines = append(lines, fmt.Sprintf("  HostName %s", entry.HostName))
 		}
@@ -138,8 +462,14 @@ func (m *Manager) buildManagedBlock(blockID string, entries []Entry) []string {
 		if entry.IdentityFile != "" {
 			lines = append(lines, fmt.Sprintf("  IdentityFile %s", entry.IdentityFile))
 		}
-		for key, value := range entry.Extra {
-			lines = append(lines, fmt.Sprintf("  %s %s", key, value))
+		// Sorted, so rewriting a block does not reorder its lines
+		keys := make([]string, 0, len(entry.Extra))
+		for key := range entry.Extra {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			lines = append(lines, fmt.Sprintf("  %s %s", key, entry.Extra[key]))
 		}
 		lines = append(lines, "")
 	}
@@ -150,28 +480,62 @@ func (m *Manager) buildManagedBlock(blockID string, entries []Entry) []string {
 	return lines
 }
 
-// BackupConfig creates a backup of the SSH config file
+// BackupConfig creates a backup of the SSH config file, and of the included
+// file of the managed blocks. Returns the backup of the SSH config.
 func (m *Manager) BackupConfig() (string, error) {
-	content, err := os.ReadFile(m.configPath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return "", nil
+	var configBackup string
+	for _, path := range m.Files() {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return "", fmt.Errorf("failed to read SSH config: %w", err)
+		}
+
+		backupPath := path + ".backup"
+		if err := os.WriteFile(backupPath, content, 0600); err != nil {
+			return "", fmt.Errorf("failed to write backup: %w", err)
+		}
+		logger.Info("Created SSH config backup: %s", backupPath)
+
+		if path == m.configPath {
+			configBackup = backupPath
 		}
-		return "", fmt.Errorf("failed to read SSH config: %w", err)
 	}
+	return configBackup, nil
+}
 
-	backupPath := m.configPath + ".backup"
-	if err := os.WriteFile(backupPath, content, 0600); err != nil {
-		return "", fmt.Errorf("failed to write backup: %w", err)
+// RemoveAllManagedBlocks removes all git-keys managed blocks of the selected
+// profile from SSH config. An included file of managed blocks that is left
+// without any is deleted along with its Include line.
+func (m *Manager) RemoveAllManagedBlocks() error {
+	unlock, err := m.lock()
+	if err != nil {
+		return err
 	}
+	defer unlock()
 
-	logger.Info("Created SSH config backup: %s", backupPath)
-	return backupPath, nil
+	if m.includePath != "" {
+		if err := m.removeAllManagedBlocksFrom(m.includePath); err != nil {
+			return err
+		}
+		if err := m.removeIncludeIfUnused(); err != nil {
+			return err
+		}
+	}
+	if err := m.removeAllManagedBlocksFrom(m.configPath); err != nil {
+		return err
+	}
+
+	logger.Info("Removed all git-keys managed blocks from SSH config")
+	return nil
 }
 
-// RemoveAllManagedBlocks removes all git-keys managed blocks from SSH config
-func (m *Manager) RemoveAllManagedBlocks() error {
-	content, err := os.ReadFile(m.configPath)
+// removeAllManagedBlocksFrom removes the managed blocks of the selected
+// profile from one file
+func (m *Manager) removeAllManagedBlocksFrom(path string) error {
+	content, err := os.ReadFile(path)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return nil // No config file, nothing to clean
@@ -186,10 +550,12 @@ func (m *Manager) RemoveAllManagedBlocks() error {
 	for _, line := range lines {
 		trimmed := strings.TrimSpace(line)
 
-		// Check if entering a managed block
+		// Check if entering a managed block of this profile
 		if strings.HasPrefix(trimmed, managedBlockStart) {
-			inManagedBlock = true
-			continue
+			if _, ok := profileBlockID(strings.TrimSpace(strings.TrimPrefix(trimmed, managedBlockStart))); ok {
+				inManagedBlock = true
+				continue
+			}
 		}
 
 		// Check if exiting a managed block
@@ -215,10 +581,5 @@ func (m *Manager) RemoveAllManagedBlocks() error {
 		newContent += "\n" // Ensure file ends with newline
 	}
 
-	if err := os.WriteFile(m.configPath, []byte(newContent), 0600); err != nil {
-		return fmt.Errorf("failed to write SSH config: %w", err)
-	}
-
-	logger.Info("Removed all git-keys managed blocks from SSH config")
-	return nil
+	return m.writeVerified(path, content, newContent, "")
 }
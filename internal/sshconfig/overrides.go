@@ -0,0 +1,294 @@
+package sshconfig
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ExpectedHost is what a managed block sets for a host alias
+type ExpectedHost struct {
+	Alias        string
+	HostName     string // Real host behind the alias, e.g. github.com
+	IdentityFile string
+}
+
+// hostSection is a Host section outside the managed blocks
+type hostSection struct {
+	line           int // 1-based line of the Host directive
+	end            int // 1-based line of the section's last directive
+	text           string
+	patterns       []string
+	identityFiles  []string
+	identityFile   bool // Sets IdentityFile
+	identitiesOnly bool // Sets IdentitiesOnly to something other than yes
+}
+
+// FindOverrides reports Host sections outside the managed blocks, such as
+// "Host *" or "Host github.*", whose IdentityFile or IdentitiesOnly take
+// effect for a managed alias. SSH uses the first value it finds for most
+// settings and offers IdentityFiles in the order it reads them, so a wildcard
+// above a managed block wins over it.
+//
+// For aliases that already have a managed block the effective settings come
+// from 'ssh -G'. Without ssh, or before the block is written, the config is
+// evaluated in-process.
+func (m *Manager) FindOverrides(expected []ExpectedHost) ([]Conflict, error) {
+	content, err := os.ReadFile(m.configPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read SSH config: %w", err)
+	}
+
+	sections, blockLines := scanHostSections(string(content))
+	_, sshErr := exec.LookPath("ssh")
+
+	// Blocks in an included file are read where the Include line is; new
+	// blocks go there as well
+	includeLine := 0
+	if m.includePath != "" {
+		includeLine = m.includeLine(string(content))
+		included, err := os.ReadFile(m.includePath)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read %s: %w", m.includePath, err)
+		}
+		_, includedBlocks := scanHostSections(string(included))
+		for alias := range includedBlocks {
+			blockLines[alias] = includeLine
+		}
+	}
+
+	var conflicts []Conflict
+	for _, want := range expected {
+		// Sections read before the alias's own block take precedence over it;
+		// without a block, new blocks are appended so every section does
+		blockLine, hasBlock := blockLines[strings.ToLower(want.Alias)]
+		if !hasBlock && includeLine > 0 {
+			blockLine = includeLine
+		}
+
+		var before []hostSection
+		for _, section := range sections {
+			if (blockLine == 0 || section.line < blockLine) && matchesHost(section.patterns, want.Alias) {
+				before = append(before, section)
+			}
+		}
+
+		if !hasBlock || sshErr != nil {
+			for _, section := range before {
+				if section.identityFile {
+					conflicts = append(conflicts, m.overrideConflict(section, want.Alias, "IdentityFile"))
+				}
+				if section.identitiesOnly {
+					conflicts = append(conflicts, m.overrideConflict(section, want.Alias, "IdentitiesOnly"))
+				}
+			}
+			continue
+		}
+
+		effective, err := m.EffectiveSettings(want.Alias)
+		if err != nil {
+			return nil, err
+		}
+
+		if !sameIdentityFile(effective.IdentityFile, want.IdentityFile) {
+			conflicts = append(conflicts, m.attributeOverride(before, want.Alias, "IdentityFile",
+				fmt.Sprintf("effective IdentityFile for %s is %s, not %s", want.Alias, effective.IdentityFile, want.IdentityFile),
+				func(s hostSection) bool { return s.identityFile })...)
+		}
+		if effective.IdentitiesOnly != "yes" {
+			conflicts = append(conflicts, m.attributeOverride(before, want.Alias, "IdentitiesOnly",
+				fmt.Sprintf("effective IdentitiesOnly for %s is %s, not yes", want.Alias, effective.IdentitiesOnly),
+				func(s hostSection) bool { return s.identitiesOnly })...)
+		}
+	}
+
+	return conflicts, nil
+}
+
+func (m *Manager) overrideConflict(section hostSection, alias, setting string) Conflict {
+	return Conflict{
+		Path:   m.configPath,
+		Line:   section.line,
+		Text:   section.text,
+		Reason: fmt.Sprintf("sets %s for %s before its managed block", setting, alias),
+	}
+}
+
+// attributeOverride points an override found by 'ssh -G' at the sections
+// responsible, or at the alias itself when it comes from elsewhere (an
+// Include or Match block)
+func (m *Manager) attributeOverride(before []hostSection, alias, setting, detail string, sets func(hostSection) bool) []Conflict {
+	var conflicts []Conflict
+	for _, section := range before {
+		if sets(section) {
+			conflicts = append(conflicts, m.overrideConflict(section, alias, setting))
+		}
+	}
+	if len(conflicts) == 0 {
+		conflicts = append(conflicts, Conflict{
+			Path:   m.configPath,
+			Text:   "ssh -G " + alias,
+			Reason: detail,
+		})
+	}
+	return conflicts
+}
+
+// EffectiveHost is what ssh resolves a host to after reading the config
+type EffectiveHost struct {
+	HostName       string
+	User           string
+	IdentityFile   string // First IdentityFile offered
+	IdentitiesOnly string
+}
+
+// EffectiveSettings asks 'ssh -G' how ssh resolves alias with this config
+func (m *Manager) EffectiveSettings(alias string) (*EffectiveHost, error) {
+	args := []string{"-G", alias}
+	if _, err := os.Stat(m.configPath); err == nil {
+		args = []string{"-G", "-F", m.configPath, alias}
+	}
+	output, err := exec.Command("ssh", args...).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("ssh -G %s failed: %s", alias, firstLine(string(output)))
+	}
+
+	effective := &EffectiveHost{}
+	for _, line := range strings.Split(string(output), "\n") {
+		keyword, value := splitDirective(strings.TrimSpace(line))
+		switch strings.ToLower(keyword) {
+		case "hostname":
+			effective.HostName = value
+		case "user":
+			effective.User = value
+		case "identityfile":
+			if effective.IdentityFile == "" {
+				effective.IdentityFile = value
+			}
+		case "identitiesonly":
+			effective.IdentitiesOnly = value
+		}
+	}
+
+	return effective, nil
+}
+
+// scanHostSections returns the Host sections outside managed blocks, and the
+// line of each managed block start keyed by the aliases it declares
+func scanHostSections(content string) ([]hostSection, map[string]int) {
+	var sections []hostSection
+	blockLines := make(map[string]int)
+
+	var current *hostSection
+	blockStart := 0
+
+	flush := func() {
+		if current != nil {
+			sections = append(sections, *current)
+		}
+		current = nil
+	}
+
+	for i, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case strings.HasPrefix(trimmed, managedBlockStart):
+			flush()
+			blockStart = i + 1
+			continue
+		case strings.HasPrefix(trimmed, managedBlockEnd):
+			blockStart = 0
+			continue
+		case trimmed == "" || strings.HasPrefix(trimmed, "#"):
+			continue
+		}
+
+		keyword, value := splitDirective(trimmed)
+		keyword = strings.ToLower(keyword)
+
+		if blockStart > 0 {
+			if keyword == "host" {
+				for _, alias := range strings.Fields(value) {
+					if _, seen := blockLines[strings.ToLower(alias)]; !seen {
+						blockLines[strings.ToLower(alias)] = blockStart
+					}
+				}
+			}
+			continue
+		}
+
+		switch keyword {
+		case "host":
+			flush()
+			current = &hostSection{line: i + 1, text: trimmed, patterns: strings.Fields(value)}
+		case "match":
+			// Match criteria cannot be evaluated here; 'ssh -G' covers them
+			flush()
+			continue
+		case "identityfile":
+			if current != nil {
+				current.identityFile = true
+				current.identityFiles = append(current.identityFiles, value)
+			}
+		case "identitiesonly":
+			// Only a value other than the managed "yes" changes anything
+			if current != nil && !strings.EqualFold(value, "yes") {
+				current.identitiesOnly = true
+			}
+		}
+		if current != nil {
+			current.end = i + 1
+		}
+	}
+	flush()
+
+	return sections, blockLines
+}
+
+// matchesHost reports whether a Host pattern list applies to host, following
+// ssh_config rules: * and ? wildcards, and any matching !pattern excludes
+func matchesHost(patterns []string, host string) bool {
+	host = strings.ToLower(host)
+	matched := false
+	for _, pattern := range patterns {
+		negated := strings.HasPrefix(pattern, "!")
+		pattern = strings.ToLower(strings.TrimPrefix(pattern, "!"))
+		if !wildcardMatch(pattern, host) {
+			continue
+		}
+		if negated {
+			return false
+		}
+		matched = true
+	}
+	return matched
+}
+
+// wildcardMatch matches an ssh pattern with * and ? against s
+func wildcardMatch(pattern, s string) bool {
+	expr := regexp.QuoteMeta(pattern)
+	expr = strings.ReplaceAll(expr, `\*`, ".*")
+	expr = strings.ReplaceAll(expr, `\?`, ".")
+	ok, _ := regexp.MatchString("^"+expr+"$", s)
+	return ok
+}
+
+// sameIdentityFile compares identity file paths with ~ expanded
+func sameIdentityFile(a, b string) bool {
+	return filepath.Clean(expandTilde(strings.Trim(a, `"`))) == filepath.Clean(expandTilde(strings.Trim(b, `"`)))
+}
+
+func expandTilde(path string) string {
+	if strings.HasPrefix(path, "~/") {
+		home, _ := os.UserHomeDir()
+		return filepath.Join(home, path[2:])
+	}
+	return path
+}
@@ -0,0 +1,102 @@
+package sshconfig
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Conflict is a line outside the managed blocks that a change would affect
+type Conflict struct {
+	Path   string
+	Line   int // 1-based line number
+	Text   string
+	Reason string
+}
+
+// String formats the conflict as path:line
+func (c Conflict) String() string {
+	if c.Line == 0 {
+		return fmt.Sprintf("%s: %s (%s)", c.Path, c.Text, c.Reason)
+	}
+	return fmt.Sprintf("%s:%d: %s (%s)", c.Path, c.Line, c.Text, c.Reason)
+}
+
+// FindConflicts reports unmanaged content that writing managed blocks for
+// hosts would affect: Host entries outside managed blocks that declare one of
+// the aliases, and unbalanced markers that would make block removal consume
+// unmanaged lines. Both the SSH config and the included file of the managed
+// blocks are checked.
+func (m *Manager) FindConflicts(hosts []string) ([]Conflict, error) {
+	var conflicts []Conflict
+	for _, path := range m.Files() {
+		found, err := findConflictsIn(path, hosts)
+		if err != nil {
+			return nil, err
+		}
+		conflicts = append(conflicts, found...)
+	}
+	return conflicts, nil
+}
+
+// findConflictsIn reports the conflicts of one file
+func findConflictsIn(path string, hosts []string) ([]Conflict, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read SSH config: %w", err)
+	}
+
+	wanted := make(map[string]bool, len(hosts))
+	for _, host := range hosts {
+		wanted[strings.ToLower(host)] = true
+	}
+
+	var conflicts []Conflict
+	add := func(line int, text, reason string) {
+		conflicts = append(conflicts, Conflict{Path: path, Line: line, Text: text, Reason: reason})
+	}
+
+	blockStart, blockText := 0, ""
+	for i, line := range strings.Split(string(content), "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case strings.HasPrefix(trimmed, managedBlockStart):
+			if blockStart > 0 {
+				add(blockStart, blockText, "managed block is not closed before the next one starts")
+			}
+			blockStart, blockText = i+1, trimmed
+			continue
+		case strings.HasPrefix(trimmed, managedBlockEnd):
+			if blockStart == 0 {
+				add(i+1, trimmed, "end marker without a matching begin marker")
+			}
+			blockStart = 0
+			continue
+		}
+
+		if blockStart > 0 {
+			continue
+		}
+
+		keyword, value := splitDirective(trimmed)
+		if !strings.EqualFold(keyword, "Host") {
+			continue
+		}
+		for _, pattern := range strings.Fields(value) {
+			if wanted[strings.ToLower(pattern)] {
+				add(i+1, trimmed, fmt.Sprintf("unmanaged entry for %s", pattern))
+				break
+			}
+		}
+	}
+
+	if blockStart > 0 {
+		add(blockStart, blockText, "managed block has no end marker; removing it would delete the rest of the file")
+	}
+
+	return conflicts, nil
+}
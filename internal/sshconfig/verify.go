@@ -0,0 +1,115 @@
+package sshconfig
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/kunlu/git-keys/internal/atomicfile"
+	"github.com/kunlu/git-keys/internal/logger"
+)
+
+// verifyHost is resolved by 'ssh -G' when no managed alias is at hand
+const verifyHost = "git-keys-verify.invalid"
+
+// Verify checks that the SSH config parses. It asks ssh to evaluate the
+// config for host ('ssh -G') and falls back to a built-in syntax check when
+// ssh is not installed.
+func (m *Manager) Verify(host string) error {
+	if host == "" {
+		host = verifyHost
+	}
+
+	if _, err := exec.LookPath("ssh"); err != nil {
+		for _, path := range m.Files() {
+			content, err := os.ReadFile(path)
+			if err != nil {
+				if path != m.configPath && os.IsNotExist(err) {
+					continue
+				}
+				return fmt.Errorf("failed to read SSH config: %w", err)
+			}
+			if err := CheckSyntax(string(content)); err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+		}
+		return nil
+	}
+
+	cmd := exec.Command("ssh", "-G", "-F", m.configPath, host)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("ssh -G rejected %s: %s", m.configPath, firstLine(string(output)))
+	}
+
+	return nil
+}
+
+// CheckSyntax performs a basic syntax check of SSH config content: every
+// directive needs a keyword and a value, and quotes must be balanced.
+func CheckSyntax(content string) error {
+	for i, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		keyword, value := splitDirective(trimmed)
+		if keyword == "" || value == "" {
+			return fmt.Errorf("line %d: missing value for %q", i+1, trimmed)
+		}
+		if strings.Count(value, `"`)%2 != 0 {
+			return fmt.Errorf("line %d: unbalanced quotes in %q", i+1, trimmed)
+		}
+	}
+
+	return nil
+}
+
+// splitDirective splits "Keyword value" or "Keyword=value"
+func splitDirective(line string) (string, string) {
+	idx := strings.IndexAny(line, " \t=")
+	if idx < 0 {
+		return line, ""
+	}
+
+	keyword := line[:idx]
+	value := strings.TrimSpace(line[idx:])
+	value = strings.TrimSpace(strings.TrimPrefix(value, "="))
+	return keyword, value
+}
+
+// writeVerified atomically writes content to path, the SSH config or a file
+// it includes, and restores the previous content if the SSH config no longer
+// parses. Configs that were already invalid before the write are written
+// without verification.
+func (m *Manager) writeVerified(path string, previous []byte, content string, host string) error {
+	wasValid := m.Verify(host) == nil
+
+	if err := atomicfile.WriteFile(path, []byte(content), 0600); err != nil {
+		return fmt.Errorf("failed to write SSH config: %w", err)
+	}
+
+	if !wasValid {
+		logger.Warn("SSH config was already invalid before this change; skipping verification")
+		return nil
+	}
+
+	if verifyErr := m.Verify(host); verifyErr != nil {
+		if err := atomicfile.WriteFile(path, previous, 0600); err != nil {
+			return fmt.Errorf("SSH config is invalid after update (%v) and rollback failed: %w", verifyErr, err)
+		}
+		return fmt.Errorf("SSH config would be invalid, change rolled back: %w", verifyErr)
+	}
+
+	return nil
+}
+
+func firstLine(s string) string {
+	s = strings.TrimSpace(s)
+	if idx := strings.IndexByte(s, '\n'); idx >= 0 {
+		return s[:idx]
+	}
+	return s
+}
@@ -0,0 +1,253 @@
+package sshkey
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kunlu/git-keys/internal/config"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// ExpandHome expands a leading ~/ to the user's home directory
+func ExpandHome(path string) string {
+	if strings.HasPrefix(path, "~/") {
+		home, _ := os.UserHomeDir()
+		return filepath.Join(home, path[2:])
+	}
+	return path
+}
+
+// ErrNoAgent is returned when no SSH agent socket is set
+var ErrNoAgent = errors.New("no SSH agent is running (SSH_AUTH_SOCK is not set)")
+
+// Agent is a connection to an ssh-agent, spoken to directly over its socket
+// rather than through ssh-add
+type Agent struct {
+	Socket string
+	conn   net.Conn
+	client agent.ExtendedAgent
+}
+
+// AgentKeyOptions are the constraints of a key added to an agent, as
+// ssh-add -t and -c set them
+type AgentKeyOptions struct {
+	Lifetime time.Duration // Zero keeps the key until it is removed
+	Confirm  bool          // Ask for confirmation before each use
+
+	// Passphrase is asked for the passphrase of an encrypted key; adding an
+	// encrypted key fails when it is nil
+	Passphrase func() ([]byte, error)
+}
+
+// ConnectAgent connects to the agent listening on socketPath, or on
+// $SSH_AUTH_SOCK when socketPath is empty
+func ConnectAgent(socketPath string) (*Agent, error) {
+	if socketPath == "" {
+		socketPath = os.Getenv("SSH_AUTH_SOCK")
+	}
+	if socketPath == "" {
+		return nil, ErrNoAgent
+	}
+	socketPath = ExpandHome(socketPath)
+
+	conn, err := net.DialTimeout("unix", socketPath, 5*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to agent %s: %w", socketPath, err)
+	}
+	return &Agent{Socket: socketPath, conn: conn, client: agent.NewClient(conn)}, nil
+}
+
+// Close closes the connection to the agent
+func (a *Agent) Close() error {
+	return a.conn.Close()
+}
+
+// PublicKeys lists the keys the agent holds as public key lines with their
+// comments
+func (a *Agent) PublicKeys() ([]string, error) {
+	keys, err := a.client.List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list keys from agent %s: %w", a.Socket, err)
+	}
+	lines := make([]string, 0, len(keys))
+	for _, key := range keys {
+		lines = append(lines, strings.TrimSpace(key.String()))
+	}
+	return lines, nil
+}
+
+// Fingerprints returns the SHA256 fingerprints of the keys the agent holds
+func (a *Agent) Fingerprints() (map[string]bool, error) {
+	keys, err := a.client.List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list keys from agent %s: %w", a.Socket, err)
+	}
+	fingerprints := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		fingerprints[FingerprintFromBlob(key.Blob)] = true
+	}
+	return fingerprints, nil
+}
+
+// HasKey reports whether the agent holds the key whose private key file is
+// keyPath, identified by keyPath.pub
+func (a *Agent) HasKey(keyPath string) (bool, error) {
+	info, err := readPublicKeyFile(keyPath + ".pub")
+	if err != nil {
+		return false, err
+	}
+	fingerprints, err := a.Fingerprints()
+	if err != nil {
+		return false, err
+	}
+	return fingerprints[info.Fingerprint], nil
+}
+
+// AddKey loads the private key file keyPath into the agent with the
+// comment of keyPath.pub
+func (a *Agent) AddKey(keyPath string, opts AgentKeyOptions) error {
+	data, err := os.ReadFile(keyPath)
+	if err != nil {
+		return fmt.Errorf("failed to read private key: %w", err)
+	}
+
+	key, err := ssh.ParseRawPrivateKey(data)
+	var missing *ssh.PassphraseMissingError
+	if errors.As(err, &missing) {
+		if opts.Passphrase == nil {
+			return fmt.Errorf("%s is protected by a passphrase", keyPath)
+		}
+		passphrase, perr := opts.Passphrase()
+		if perr != nil {
+			return perr
+		}
+		key, err = ssh.ParseRawPrivateKeyWithPassphrase(data, passphrase)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse private key %s: %w", keyPath, err)
+	}
+
+	comment := keyPath
+	if info, err := readPublicKeyFile(keyPath + ".pub"); err == nil && info.Comment != "" {
+		comment = info.Comment
+	}
+
+	added := agent.AddedKey{
+		PrivateKey:       key,
+		Comment:          comment,
+		ConfirmBeforeUse: opts.Confirm,
+	}
+	if opts.Lifetime > 0 {
+		added.LifetimeSecs = uint32(opts.Lifetime / time.Second)
+	}
+	if err := a.client.Add(added); err != nil {
+		return fmt.Errorf("failed to add %s to agent: %w", keyPath, err)
+	}
+	return nil
+}
+
+// RemoveKey removes the key whose private key file is keyPath, identified
+// by keyPath.pub
+func (a *Agent) RemoveKey(keyPath string) error {
+	info, err := readPublicKeyFile(keyPath + ".pub")
+	if err != nil {
+		return err
+	}
+	pub, err := ssh.ParsePublicKey(info.Blob)
+	if err != nil {
+		return fmt.Errorf("failed to parse public key: %w", err)
+	}
+	if err := a.client.Remove(pub); err != nil {
+		return fmt.Errorf("failed to remove %s from agent: %w", keyPath, err)
+	}
+	return nil
+}
+
+// readPublicKeyFile parses a public key file
+func readPublicKeyFile(path string) (*PublicKeyInfo, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read public key: %w", err)
+	}
+	return ParsePublicKey(string(data))
+}
+
+// ExportAgentPublicKey returns a public key held by the ssh-agent listening on
+// socketPath. If match is non-empty, the key whose comment equals (or contains)
+// match is returned; otherwise the agent must hold exactly one key.
+func ExportAgentPublicKey(socketPath, match string) (string, error) {
+	conn, err := ConnectAgent(socketPath)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	lines, err := conn.PublicKeys()
+	if err != nil {
+		return "", err
+	}
+
+	var candidates []string
+	for _, line := range lines {
+		info, err := ParsePublicKey(line)
+		if err != nil {
+			continue
+		}
+		if match == "" || info.Comment == match {
+			candidates = append(candidates, line)
+		}
+	}
+
+	// Fall back to a substring match on the comment
+	if len(candidates) == 0 && match != "" {
+		for _, line := range lines {
+			if info, err := ParsePublicKey(line); err == nil && strings.Contains(info.Comment, match) {
+				candidates = append(candidates, line)
+			}
+		}
+	}
+
+	switch len(candidates) {
+	case 0:
+		if match == "" {
+			return "", fmt.Errorf("agent %s holds no keys", socketPath)
+		}
+		return "", fmt.Errorf("no key matching '%s' in agent %s", match, socketPath)
+	case 1:
+		return candidates[0], nil
+	default:
+		return "", fmt.Errorf("agent %s holds %d matching keys; set agent_key to choose one", socketPath, len(candidates))
+	}
+}
+
+// KeyTypeFromPublicKey maps an OpenSSH key type to the config key type
+func KeyTypeFromPublicKey(sshType string) config.KeyType {
+	switch {
+	case strings.Contains(sshType, "ed25519"):
+		return config.KeyTypeED25519
+	case strings.Contains(sshType, "ecdsa"):
+		return config.KeyTypeECDSA
+	default:
+		return config.KeyTypeRSA
+	}
+}
+
+// SavePublicKey writes a public key into the keys directory
+func (m *Manager) SavePublicKey(fileName, publicKey string) error {
+	if err := os.MkdirAll(m.keysDir, 0700); err != nil {
+		return fmt.Errorf("failed to create keys directory: %w", err)
+	}
+
+	fullPath := filepath.Join(m.keysDir, fileName)
+	if err := os.WriteFile(fullPath, []byte(strings.TrimSpace(publicKey)+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write public key: %w", err)
+	}
+
+	return nil
+}
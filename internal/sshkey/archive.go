@@ -0,0 +1,70 @@
+package sshkey
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// PersonaArchiveDir returns the directory holding the keys of an archived persona
+func (m *Manager) PersonaArchiveDir(persona string) string {
+	return filepath.Join(m.keysDir, "archive", "personas", persona)
+}
+
+// ArchiveKey moves a key pair, or a lone public key, into dir. Returns false
+// if none of the key's files exist.
+func (m *Manager) ArchiveKey(keyPath, dir string) (bool, error) {
+	if err := os.MkdirAll(dir, DirPerm); err != nil {
+		return false, fmt.Errorf("failed to create archive directory: %w", err)
+	}
+
+	moved := false
+	for _, src := range keyFiles(filepath.Join(m.keysDir, keyPath)) {
+		if _, err := os.Lstat(src); os.IsNotExist(err) {
+			continue
+		}
+		dst := filepath.Join(dir, filepath.Base(src))
+		if _, err := os.Lstat(dst); err == nil {
+			return moved, fmt.Errorf("archive already contains %s", dst)
+		}
+		if err := os.Rename(src, dst); err != nil {
+			return moved, fmt.Errorf("failed to archive %s: %w", src, err)
+		}
+		moved = true
+	}
+
+	return moved, nil
+}
+
+// RestoreKey moves a key archived with ArchiveKey back to keyPath. Existing
+// files at the original location are never overwritten. Returns false if the
+// archive does not contain the key.
+func (m *Manager) RestoreKey(keyPath, dir string) (bool, error) {
+	restored := false
+	for _, dst := range keyFiles(filepath.Join(m.keysDir, keyPath)) {
+		src := filepath.Join(dir, filepath.Base(dst))
+		if _, err := os.Lstat(src); os.IsNotExist(err) {
+			continue
+		}
+		if _, err := os.Lstat(dst); err == nil {
+			return restored, fmt.Errorf("%s already exists", dst)
+		}
+		if err := os.MkdirAll(filepath.Dir(dst), DirPerm); err != nil {
+			return restored, fmt.Errorf("failed to create %s: %w", filepath.Dir(dst), err)
+		}
+		if err := os.Rename(src, dst); err != nil {
+			return restored, fmt.Errorf("failed to restore %s: %w", dst, err)
+		}
+		restored = true
+	}
+
+	return restored, nil
+}
+
+// keyFiles returns the private and public key paths for a key path
+func keyFiles(fullPath string) []string {
+	if filepath.Ext(fullPath) == ".pub" {
+		return []string{fullPath}
+	}
+	return []string{fullPath, fullPath + ".pub"}
+}
@@ -1,19 +1,23 @@
 package sshkey
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/kunlu/git-keys/internal/config"
 	"github.com/kunlu/git-keys/internal/logger"
+	"github.com/kunlu/git-keys/internal/trash"
 )
 
 // Manager handles SSH key operations
 type Manager struct {
 	keysDir string
+	trash   *trash.Manager
 }
 
 // NewManager creates a new SSH key manager
@@ -22,7 +26,24 @@ func NewManager(keysDir string) *Manager {
 		home, _ := os.UserHomeDir()
 		keysDir = filepath.Join(home, ".ssh")
 	}
-	return &Manager{keysDir: keysDir}
+	return &Manager{keysDir: keysDir, trash: trash.NewManager("", 0)}
+}
+
+// KeysDir returns the directory keys are stored in
+func (m *Manager) KeysDir() string {
+	return m.keysDir
+}
+
+// IdentityFilePath returns the path of a key for use in SSH config and
+// messages, abbreviating the home directory as ~
+func (m *Manager) IdentityFilePath(keyPath string) string {
+	fullPath := filepath.Join(m.keysDir, keyPath)
+	if home, err := os.UserHomeDir(); err == nil {
+		if rel, err := filepath.Rel(home, fullPath); err == nil && !strings.HasPrefix(rel, "..") {
+			return "~/" + filepath.ToSlash(rel)
+		}
+	}
+	return fullPath
 }
 
 // GenerateKey generates a new SSH key pair
@@ -99,6 +120,66 @@ func (m *Manager) GetPublicKey(publicKeyPath string) (string, error) {
 	return strings.TrimSpace(string(data)), nil
 }
 
+// ErrKeyPairMismatch is returned when a private key does not match its public key file
+var ErrKeyPairMismatch = errors.New("private and public key do not match")
+
+// IsKeyPairMismatch reports whether err indicates a mismatched key pair
+func IsKeyPairMismatch(err error) bool {
+	return errors.Is(err, ErrKeyPairMismatch)
+}
+
+// VerifyKeyPair derives the public key from the private key and compares it
+// with the .pub file. For passphrase-protected keys the public key in the
+// header of the OpenSSH format is compared instead; encrypted PEM keys have
+// none and are not checked.
+func (m *Manager) VerifyKeyPair(keyPath string) error {
+	privateKey := filepath.Join(m.keysDir, keyPath)
+
+	if encrypted, err := PrivateKeyEncrypted(privateKey); err == nil && encrypted {
+		fingerprint, ok, err := PrivateKeyPublicFingerprint(privateKey)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			logger.Debug("Not verifying key pair %s: encrypted PEM key", keyPath)
+			return nil
+		}
+		return m.compareFingerprint(keyPath, fingerprint)
+	}
+
+	cmd := exec.Command("ssh-keygen", "-y", "-P", "", "-f", privateKey)
+	output, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("failed to derive public key (unreadable private key): %w", err)
+	}
+
+	derived, err := ParsePublicKey(string(output))
+	if err != nil {
+		return fmt.Errorf("failed to parse derived public key: %w", err)
+	}
+	return m.compareFingerprint(keyPath, derived.Fingerprint)
+}
+
+// compareFingerprint checks the fingerprint of a private key against its
+// .pub file
+func (m *Manager) compareFingerprint(keyPath, fingerprint string) error {
+	publicKey, err := m.GetPublicKey(keyPath)
+	if err != nil {
+		return err
+	}
+
+	stored, err := ParsePublicKey(publicKey)
+	if err != nil {
+		return fmt.Errorf("failed to parse public key file: %w", err)
+	}
+
+	if fingerprint != stored.Fingerprint {
+		return fmt.Errorf("%w (private: %s, public: %s)", ErrKeyPairMismatch, fingerprint, stored.Fingerprint)
+	}
+
+	return nil
+}
+
 // KeyExists checks if a key file exists
 func (m *Manager) KeyExists(keyPath string) bool {
 	fullPath := filepath.Join(m.keysDir, keyPath)
@@ -106,28 +187,52 @@ func (m *Manager) KeyExists(keyPath string) bool {
 	return err == nil
 }
 
-// DeleteKey removes a key pair
+// DeleteKey moves a key pair to the trash. Trashed keys can be restored with
+// 'git-keys trash restore' until 'git-keys trash' purges them after the
+// retention window.
 func (m *Manager) DeleteKey(keyPath string) error {
+	_, err := m.TrashKey(keyPath)
+	return err
+}
+
+// TrashKey moves a key pair to the trash like DeleteKey, and returns the
+// trash item (nil when there were no files)
+func (m *Manager) TrashKey(keyPath string) (*trash.Item, error) {
 	privateKey := filepath.Join(m.keysDir, keyPath)
-	publicKey := privateKey + ".pub"
 
-	// Remove private key
-	if err := os.Remove(privateKey); err != nil && !os.IsNotExist(err) {
-		return fmt.Errorf("failed to remove private key: %w", err)
+	item, err := m.trash.Add(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete key: %w", err)
 	}
 
-	// Remove public key
-	if err := os.Remove(publicKey); err != nil && !os.IsNotExist(err) {
-		return fmt.Errorf("failed to remove public key: %w", err)
+	if item != nil {
+		logger.Info("Deleted key: %s (moved to trash as %s)", keyPath, item.ID)
 	}
+	return item, nil
+}
 
-	logger.Info("Deleted key: %s", keyPath)
+// SetComment rewrites the comment of an existing key pair (passphrase-less keys only)
+func (m *Manager) SetComment(keyPath, comment string) error {
+	fullPath := filepath.Join(m.keysDir, keyPath)
+
+	cmd := exec.Command("ssh-keygen", "-c", "-C", comment, "-P", "", "-f", fullPath)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to update key comment: %w\nOutput: %s", err, string(output))
+	}
+
+	logger.Debug("Updated comment for %s: %s", keyPath, comment)
 	return nil
 }
 
-// BuildKeyComment creates a standardized key comment
-func BuildKeyComment(platform config.PlatformType, account, machineName string) string {
-	return fmt.Sprintf("git-keys:%s:%s:%s", platform, account, machineName)
+// BuildKeyComment creates a standardized key comment including the creation date
+func BuildKeyComment(platform config.PlatformType, account, machineName string, created time.Time) string {
+	return fmt.Sprintf("git-keys:%s:%s:%s:%s", platform, account, machineName, created.Format("2006-01-02"))
+}
+
+// BuildKeyTitle creates the title used when registering a key on a platform
+func BuildKeyTitle(account, machineName string, date time.Time) string {
+	return fmt.Sprintf("%s@%s (git-keys %s)", account, machineName, date.Format("2006-01-02"))
 }
 
 // BuildKeyFileName creates a standardized key file name
@@ -0,0 +1,197 @@
+package sshkey
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/kunlu/git-keys/internal/config"
+)
+
+// Default naming templates. They render the same values as BuildKeyFileName,
+// BuildKeyComment and BuildKeyTitle. The comment embeds the creation date so
+// it survives file copies that reset mtimes.
+const (
+	DefaultFileNameTemplate = "git-keys-{{.Platform}}-{{.Account}}-{{.Type}}"
+	DefaultCommentTemplate  = "git-keys:{{.Platform}}:{{.Account}}:{{.Machine}}:{{.Date}}"
+	DefaultTitleTemplate    = "{{.Account}}@{{.Machine}} (git-keys {{.Date}})"
+)
+
+// creationDatePattern matches a YYYY-MM-DD date in a key comment
+var creationDatePattern = regexp.MustCompile(`\b(\d{4}-\d{2}-\d{2})\b`)
+
+// ParseCreationHint extracts a creation date embedded in a key comment, such
+// as the date in git-keys comments. The last date in the comment wins.
+func ParseCreationHint(comment string) (time.Time, bool) {
+	matches := creationDatePattern.FindAllString(comment, -1)
+	for i := len(matches) - 1; i >= 0; i-- {
+		if date, err := time.ParseInLocation("2006-01-02", matches[i], time.Local); err == nil {
+			return date, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// NameData holds the values available to naming templates
+type NameData struct {
+	Platform string // "github" or "gitlab"
+	Account  string
+	Persona  string
+	Machine  string
+	Type     string // Key type, e.g. "ed25519"
+	Date     string // YYYY-MM-DD
+}
+
+// NewNameData builds template data for a persona's platform key
+func NewNameData(persona *config.Persona, platform *config.Platform, keyType config.KeyType, machineName string, date time.Time) NameData {
+	data := NameData{
+		Platform: string(platform.Type),
+		Account:  platform.Account,
+		Machine:  machineName,
+		Type:     string(keyType),
+		Date:     date.Format("2006-01-02"),
+	}
+	if persona != nil {
+		data.Persona = persona.Name
+	}
+	return data
+}
+
+// RenderName executes a naming template, using fallback when tmpl is empty
+func RenderName(tmpl, fallback string, data NameData) (string, error) {
+	if tmpl == "" {
+		tmpl = fallback
+	}
+
+	t, err := template.New("name").Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("invalid naming template %q: %w", tmpl, err)
+	}
+
+	var sb strings.Builder
+	if err := t.Execute(&sb, data); err != nil {
+		return "", fmt.Errorf("failed to render naming template %q: %w", tmpl, err)
+	}
+
+	name := strings.TrimSpace(sb.String())
+	if name == "" {
+		return "", fmt.Errorf("naming template %q rendered an empty name", tmpl)
+	}
+	return name, nil
+}
+
+// TitleMeta is the machine and expiry recorded in a remote key title, so that
+// other machines and later audits can tell where a key lives and when it is
+// due without a shared configuration
+type TitleMeta struct {
+	Machine string
+	Created time.Time // Only known for titles in the old default formats
+	Expires time.Time // Zero when unknown
+}
+
+// titleMetaPattern matches the suffix written by AppendTitleMeta, e.g.
+// [gk m=laptop exp=2025-06-30] or [gk m="Kim's Mac" exp=2025-06-30]
+var titleMetaPattern = regexp.MustCompile(`\s*\[gk(?: m=("(?:[^"\\]|\\.)*"|[^\s\]]+))?(?: exp=(\d{4}-\d{2}-\d{2}))?\]$`)
+
+// legacyTitlePattern matches titles rendered by the default title templates
+// before the metadata suffix existed: account@machine (git-keys|rotated date)
+var legacyTitlePattern = regexp.MustCompile(`^[^@\s]+@(.+) \((?:git-keys|rotated) (\d{4}-\d{2}-\d{2})\)$`)
+
+// AppendTitleMeta appends the metadata suffix to a remote key title,
+// replacing an existing one
+func AppendTitleMeta(title string, meta TitleMeta) string {
+	suffix := "[gk"
+	if meta.Machine != "" {
+		machine := meta.Machine
+		if strings.ContainsAny(machine, " \t\"]") {
+			machine = strconv.Quote(machine)
+		}
+		suffix += " m=" + machine
+	}
+	if !meta.Expires.IsZero() {
+		suffix += " exp=" + meta.Expires.Format("2006-01-02")
+	}
+	return StripTitleMeta(title) + " " + suffix + "]"
+}
+
+// StripTitleMeta removes the metadata suffix from a remote key title
+func StripTitleMeta(title string) string {
+	return titleMetaPattern.ReplaceAllString(title, "")
+}
+
+// ParseTitleMeta reads the metadata of a remote key title. Titles uploaded
+// before the suffix existed are recognized by the old default formats, which
+// carry the machine and upload date but no expiry.
+func ParseTitleMeta(title string) (TitleMeta, bool) {
+	var meta TitleMeta
+
+	if m := titleMetaPattern.FindStringSubmatch(title); m != nil {
+		meta.Machine = m[1]
+		if unquoted, err := strconv.Unquote(m[1]); err == nil {
+			meta.Machine = unquoted
+		}
+		if m[2] != "" {
+			meta.Expires, _ = time.ParseInLocation("2006-01-02", m[2], time.Local)
+		}
+		title = StripTitleMeta(title)
+		if meta.Machine != "" || !meta.Expires.IsZero() {
+			if legacy, ok := parseLegacyTitle(title); ok {
+				meta.Created = legacy.Created
+			}
+			return meta, true
+		}
+	}
+
+	return parseLegacyTitle(title)
+}
+
+func parseLegacyTitle(title string) (TitleMeta, bool) {
+	m := legacyTitlePattern.FindStringSubmatch(title)
+	if m == nil {
+		return TitleMeta{}, false
+	}
+	created, err := time.ParseInLocation("2006-01-02", m[2], time.Local)
+	if err != nil {
+		return TitleMeta{}, false
+	}
+	return TitleMeta{Machine: m[1], Created: created}, true
+}
+
+// RenderTitle renders a remote key title from the settings' title template
+// (or fallback) and appends the machine and expiry unless title metadata is
+// disabled
+func RenderTitle(settings config.KeySettings, fallback string, data NameData, expires time.Time) (string, error) {
+	title, err := RenderName(settings.TitleTemplate, fallback, data)
+	if err != nil {
+		return "", err
+	}
+	if !settings.TitleMetadata {
+		return title, nil
+	}
+	return AppendTitleMeta(title, TitleMeta{Machine: data.Machine, Expires: expires}), nil
+}
+
+// RenderFileName renders a key file name. Spaces and path separators are
+// replaced so the name is usable as an unquoted IdentityFile.
+func RenderFileName(tmpl string, data NameData) (string, error) {
+	name, err := RenderName(tmpl, DefaultFileNameTemplate, data)
+	if err != nil {
+		return "", err
+	}
+
+	name = strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '\t', '/', '\\':
+			return '-'
+		}
+		return r
+	}, name)
+
+	if strings.HasPrefix(name, ".") {
+		return "", fmt.Errorf("key file name %q must not start with '.'", name)
+	}
+	return name, nil
+}
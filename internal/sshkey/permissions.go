@@ -0,0 +1,136 @@
+package sshkey
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// DirPerm is the expected mode for ~/.ssh and its subdirectories
+	DirPerm os.FileMode = 0700
+	// PrivateKeyPerm is the expected mode for private keys and the SSH config
+	PrivateKeyPerm os.FileMode = 0600
+	// PublicKeyPerm is the expected mode for public keys
+	PublicKeyPerm os.FileMode = 0644
+)
+
+// PermissionIssue describes a file or directory whose mode differs from what SSH expects
+type PermissionIssue struct {
+	Path     string
+	Kind     string // "directory", "private key", "public key", or "ssh config"
+	Mode     os.FileMode
+	Expected os.FileMode
+}
+
+// Fix applies the expected mode
+func (i PermissionIssue) Fix() error {
+	if err := os.Chmod(i.Path, i.Expected); err != nil {
+		return fmt.Errorf("failed to chmod %s: %w", i.Path, err)
+	}
+	return nil
+}
+
+// CheckPermissions walks each directory tree and reports directories, private
+// keys, public keys and SSH config files with unexpected permissions. Files
+// that are none of these (and symlinks) are left alone. Missing roots are skipped.
+func CheckPermissions(roots ...string) ([]PermissionIssue, error) {
+	var issues []PermissionIssue
+	seen := make(map[string]bool)
+
+	for _, root := range roots {
+		if root == "" {
+			continue
+		}
+		if _, err := os.Lstat(root); os.IsNotExist(err) {
+			continue
+		}
+
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if seen[path] || d.Type()&fs.ModeSymlink != 0 {
+				return nil
+			}
+			seen[path] = true
+
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+
+			kind, expected, ok := expectedPermission(path, info)
+			if !ok {
+				return nil
+			}
+
+			mode := info.Mode().Perm()
+			// Read-only private keys are at least as strict as 0600
+			if mode == expected || (kind == "private key" && mode == 0400) {
+				return nil
+			}
+
+			issues = append(issues, PermissionIssue{Path: path, Kind: kind, Mode: mode, Expected: expected})
+			return nil
+		})
+		if err != nil {
+			return issues, fmt.Errorf("failed to walk %s: %w", root, err)
+		}
+	}
+
+	return issues, nil
+}
+
+// expectedPermission classifies a path and returns the mode SSH expects for it
+func expectedPermission(path string, info os.FileInfo) (string, os.FileMode, bool) {
+	if info.IsDir() {
+		return "directory", DirPerm, true
+	}
+	if !info.Mode().IsRegular() {
+		return "", 0, false
+	}
+
+	name := filepath.Base(path)
+	if name == "config" || strings.HasPrefix(name, "config.backup") {
+		return "ssh config", PrivateKeyPerm, true
+	}
+
+	// Archived keys lose their usual names, so classify by content
+	header := readHeader(path, 4096)
+	switch {
+	case bytes.HasPrefix(header, []byte("-----BEGIN")) && bytes.Contains(header, []byte("PRIVATE KEY-----")):
+		return "private key", PrivateKeyPerm, true
+	case isPublicKeyHeader(header):
+		return "public key", PublicKeyPerm, true
+	}
+
+	return "", 0, false
+}
+
+// isPublicKeyHeader reports whether the first line of a file is an OpenSSH public key
+func isPublicKeyHeader(header []byte) bool {
+	line := header
+	if idx := bytes.IndexByte(line, '\n'); idx >= 0 {
+		line = line[:idx]
+	}
+	_, err := ParsePublicKey(string(line))
+	return err == nil
+}
+
+// readHeader returns up to n bytes from the start of a file
+func readHeader(path string, n int) []byte {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	buf := make([]byte, n)
+	read, _ := io.ReadFull(f, buf)
+	return buf[:read]
+}
@@ -0,0 +1,84 @@
+package sshkey
+
+import (
+	"bytes"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// openSSHKeyMagic starts the body of an OpenSSH format private key
+const openSSHKeyMagic = "openssh-key-v1\x00"
+
+// PrivateKeyEncrypted reports whether a private key file is protected by a
+// passphrase, read from its header without asking for the passphrase.
+// OpenSSH keys name their cipher ("none" when unencrypted); PEM keys carry a
+// Proc-Type: 4,ENCRYPTED header or an ENCRYPTED PRIVATE KEY block.
+func PrivateKeyEncrypted(path string) (bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return false, fmt.Errorf("%s is not a PEM or OpenSSH private key", path)
+	}
+
+	switch {
+	case block.Type == "OPENSSH PRIVATE KEY":
+		if !bytes.HasPrefix(block.Bytes, []byte(openSSHKeyMagic)) {
+			return false, fmt.Errorf("%s has an invalid OpenSSH key header", path)
+		}
+		cipher, _, err := readWireString(block.Bytes[len(openSSHKeyMagic):])
+		if err != nil {
+			return false, fmt.Errorf("%s has an invalid OpenSSH key header: %w", path, err)
+		}
+		return string(cipher) != "none", nil
+	case block.Type == "ENCRYPTED PRIVATE KEY":
+		return true, nil
+	case strings.HasSuffix(block.Type, "PRIVATE KEY"):
+		return strings.Contains(block.Headers["Proc-Type"], "ENCRYPTED"), nil
+	}
+	return false, fmt.Errorf("%s is not a private key (%s)", path, block.Type)
+}
+
+// PrivateKeyPublicFingerprint returns the fingerprint of the public key an
+// OpenSSH format private key carries in its unencrypted header, so it can be
+// read from passphrase-protected keys too. ok is false for PEM keys, which
+// have no public part.
+func PrivateKeyPublicFingerprint(path string) (fingerprint string, ok bool, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return "", false, fmt.Errorf("%s is not a PEM or OpenSSH private key", path)
+	}
+	if block.Type != "OPENSSH PRIVATE KEY" {
+		return "", false, nil
+	}
+	if !bytes.HasPrefix(block.Bytes, []byte(openSSHKeyMagic)) {
+		return "", false, fmt.Errorf("%s has an invalid OpenSSH key header", path)
+	}
+
+	// cipher, KDF name and KDF options, then the key count and the first
+	// public key
+	rest := block.Bytes[len(openSSHKeyMagic):]
+	for i := 0; i < 3; i++ {
+		if _, rest, err = readWireString(rest); err != nil {
+			return "", false, fmt.Errorf("%s has an invalid OpenSSH key header: %w", path, err)
+		}
+	}
+	if len(rest) < 4 {
+		return "", false, fmt.Errorf("%s has an invalid OpenSSH key header: short data", path)
+	}
+	blob, _, err := readWireString(rest[4:])
+	if err != nil {
+		return "", false, fmt.Errorf("%s has an invalid OpenSSH key header: %w", path, err)
+	}
+	return FingerprintFromBlob(blob), true, nil
+}
@@ -0,0 +1,105 @@
+package sshkey
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// PublicKeyInfo holds the details parsed from an OpenSSH public key line
+type PublicKeyInfo struct {
+	Type        string // e.g., "ssh-ed25519", "ssh-rsa"
+	Bits        int
+	Fingerprint string // SHA256:... (same format as ssh-keygen -l)
+	Comment     string
+	Blob        []byte // Raw wire-format key
+}
+
+// ParsePublicKey parses an OpenSSH public key line ("type base64 comment")
+// without shelling out to ssh-keygen
+func ParsePublicKey(line string) (*PublicKeyInfo, error) {
+	fields := strings.Fields(strings.TrimSpace(line))
+	if len(fields) < 2 {
+		return nil, fmt.Errorf("invalid public key format")
+	}
+
+	blob, err := base64.StdEncoding.DecodeString(fields[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key encoding: %w", err)
+	}
+
+	// The first field in the blob repeats the key type
+	blobType, rest, err := readWireString(blob)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key data: %w", err)
+	}
+	if string(blobType) != fields[0] {
+		return nil, fmt.Errorf("key type mismatch: %s vs %s", fields[0], blobType)
+	}
+
+	info := &PublicKeyInfo{
+		Type:        fields[0],
+		Bits:        keyBits(fields[0], rest),
+		Fingerprint: FingerprintFromBlob(blob),
+		Blob:        blob,
+	}
+	if len(fields) >= 3 {
+		info.Comment = strings.Join(fields[2:], " ")
+	}
+
+	return info, nil
+}
+
+// FingerprintFromBlob computes the SHA256 fingerprint of a wire-format public key
+func FingerprintFromBlob(blob []byte) string {
+	sum := sha256.Sum256(blob)
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+}
+
+// keyBits derives the key size from the wire-format key data following the type
+func keyBits(keyType string, data []byte) int {
+	switch {
+	case strings.Contains(keyType, "ed25519"):
+		return 256
+	case keyType == "ssh-rsa":
+		// Format: string e, string n
+		_, rest, err := readWireString(data)
+		if err != nil {
+			return 0
+		}
+		n, _, err := readWireString(rest)
+		if err != nil {
+			return 0
+		}
+		return new(big.Int).SetBytes(n).BitLen()
+	case strings.Contains(keyType, "nistp256"):
+		return 256
+	case strings.Contains(keyType, "nistp384"):
+		return 384
+	case strings.Contains(keyType, "nistp521"):
+		return 521
+	case keyType == "ssh-dss":
+		// Format: string p, ...
+		p, _, err := readWireString(data)
+		if err != nil {
+			return 0
+		}
+		return new(big.Int).SetBytes(p).BitLen()
+	}
+	return 0
+}
+
+// readWireString reads a length-prefixed string from SSH wire format
+func readWireString(data []byte) ([]byte, []byte, error) {
+	if len(data) < 4 {
+		return nil, nil, fmt.Errorf("short data")
+	}
+	length := binary.BigEndian.Uint32(data[:4])
+	if uint32(len(data)-4) < length {
+		return nil, nil, fmt.Errorf("truncated data")
+	}
+	return data[4 : 4+length], data[4+length:], nil
+}
@@ -0,0 +1,245 @@
+package trash
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kunlu/git-keys/internal/logger"
+	"github.com/kunlu/git-keys/internal/profile"
+)
+
+const (
+	// DefaultRetention is how long trashed keys are kept before they may be purged
+	DefaultRetention = 30 * 24 * time.Hour
+
+	metadataFileName  = "trash.json"
+	idTimestampFormat = "20060102-150405"
+)
+
+// Item represents a trashed key pair
+type Item struct {
+	ID           string    `json:"id"`
+	OriginalPath string    `json:"original_path"` // Absolute path of the private key
+	DeletedAt    time.Time `json:"deleted_at"`
+	Files        []string  `json:"files"` // File names stored in the item directory
+}
+
+// ExpiresAt returns when the item becomes eligible for purging
+func (i *Item) ExpiresAt(retention time.Duration) time.Time {
+	return i.DeletedAt.Add(retention)
+}
+
+// Manager handles the key trash directory
+type Manager struct {
+	trashDir  string
+	retention time.Duration
+}
+
+// NewManager creates a new trash manager
+func NewManager(trashDir string, retention time.Duration) *Manager {
+	if trashDir == "" {
+		trashDir = GetDefaultTrashDir()
+	}
+	if retention <= 0 {
+		retention = DefaultRetention
+	}
+	return &Manager{trashDir: trashDir, retention: retention}
+}
+
+// GetDefaultTrashDir returns the default trash directory
+func GetDefaultTrashDir() string {
+	return filepath.Join(profile.Dir(), "trash")
+}
+
+// Retention returns the configured retention window
+func (m *Manager) Retention() time.Duration {
+	return m.retention
+}
+
+// Add moves a key pair (private key and .pub) into the trash
+func (m *Manager) Add(privateKeyPath string) (*Item, error) {
+	var present []string
+	for _, path := range []string{privateKeyPath, privateKeyPath + ".pub"} {
+		if _, err := os.Stat(path); err == nil {
+			present = append(present, path)
+		}
+	}
+
+	if len(present) == 0 {
+		return nil, nil // Nothing to trash
+	}
+
+	now := time.Now()
+	item := &Item{
+		ID:           fmt.Sprintf("%s-%s", now.Format(idTimestampFormat), filepath.Base(privateKeyPath)),
+		OriginalPath: privateKeyPath,
+		DeletedAt:    now,
+	}
+
+	itemDir := filepath.Join(m.trashDir, item.ID)
+	if err := os.MkdirAll(itemDir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create trash directory: %w", err)
+	}
+
+	for _, path := range present {
+		name := filepath.Base(path)
+		if err := os.Rename(path, filepath.Join(itemDir, name)); err != nil {
+			return nil, fmt.Errorf("failed to move %s to trash: %w", path, err)
+		}
+		item.Files = append(item.Files, name)
+	}
+
+	if err := writeMetadata(itemDir, item); err != nil {
+		return nil, err
+	}
+
+	logger.Debug("Moved %s to trash (%s)", privateKeyPath, item.ID)
+	return item, nil
+}
+
+// List returns all trashed items, newest first
+func (m *Manager) List() ([]Item, error) {
+	entries, err := os.ReadDir(m.trashDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read trash directory: %w", err)
+	}
+
+	var items []Item
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		item, err := readMetadata(filepath.Join(m.trashDir, entry.Name()))
+		if err != nil {
+			logger.Debug("Skipping trash entry %s: %v", entry.Name(), err)
+			continue
+		}
+		items = append(items, *item)
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].DeletedAt.After(items[j].DeletedAt)
+	})
+
+	return items, nil
+}
+
+// Find returns the item with the given ID, or a unique ID prefix/key name match
+func (m *Manager) Find(query string) (*Item, error) {
+	items, err := m.List()
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []Item
+	for _, item := range items {
+		if item.ID == query {
+			return &item, nil
+		}
+		if strings.HasPrefix(item.ID, query) || filepath.Base(item.OriginalPath) == query {
+			matches = append(matches, item)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("no trashed key matches: %s", query)
+	case 1:
+		return &matches[0], nil
+	default:
+		return nil, fmt.Errorf("%d trashed keys match '%s'; use the full ID", len(matches), query)
+	}
+}
+
+// Restore moves a trashed key pair back to its original location
+func (m *Manager) Restore(id string, overwrite bool) (*Item, error) {
+	item, err := m.Find(id)
+	if err != nil {
+		return nil, err
+	}
+
+	itemDir := filepath.Join(m.trashDir, item.ID)
+	targetDir := filepath.Dir(item.OriginalPath)
+
+	if !overwrite {
+		for _, name := range item.Files {
+			if _, err := os.Stat(filepath.Join(targetDir, name)); err == nil {
+				return nil, fmt.Errorf("%s already exists (use --force to overwrite)", filepath.Join(targetDir, name))
+			}
+		}
+	}
+
+	if err := os.MkdirAll(targetDir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", targetDir, err)
+	}
+
+	for _, name := range item.Files {
+		if err := os.Rename(filepath.Join(itemDir, name), filepath.Join(targetDir, name)); err != nil {
+			return nil, fmt.Errorf("failed to restore %s: %w", name, err)
+		}
+	}
+
+	if err := os.RemoveAll(itemDir); err != nil {
+		logger.Warn("Failed to remove trash entry %s: %v", item.ID, err)
+	}
+
+	logger.Info("Restored %s from trash", item.OriginalPath)
+	return item, nil
+}
+
+// Empty permanently deletes trashed items. If expiredOnly is set, only items
+// past the retention window are removed.
+func (m *Manager) Empty(expiredOnly bool) (int, error) {
+	items, err := m.List()
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	now := time.Now()
+	for _, item := range items {
+		if expiredOnly && now.Before(item.ExpiresAt(m.retention)) {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(m.trashDir, item.ID)); err != nil {
+			return removed, fmt.Errorf("failed to remove %s: %w", item.ID, err)
+		}
+		removed++
+	}
+
+	if removed > 0 {
+		logger.Debug("Permanently deleted %d trashed key(s)", removed)
+	}
+	return removed, nil
+}
+
+func writeMetadata(itemDir string, item *Item) error {
+	data, err := json.MarshalIndent(item, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal trash metadata: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(itemDir, metadataFileName), data, 0600); err != nil {
+		return fmt.Errorf("failed to write trash metadata: %w", err)
+	}
+	return nil
+}
+
+func readMetadata(itemDir string) (*Item, error) {
+	data, err := os.ReadFile(filepath.Join(itemDir, metadataFileName))
+	if err != nil {
+		return nil, err
+	}
+	var item Item
+	if err := json.Unmarshal(data, &item); err != nil {
+		return nil, err
+	}
+	return &item, nil
+}